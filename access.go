@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// accessAnyRepo in a rule's repo list allows every repo; as a channel ID it is
+// the rule for channels without their own.
+const accessAnyRepo = "*"
+
+// groupMembersTTL is how long a user group's member list is reused.
+const groupMembersTTL = 5 * time.Minute
+
+// accessPolicyFile is the BOB_ACCESS_FILE format:
+//
+//	channels:
+//	  C0123BILLING: [billing-service, billing-web]
+//	  "*": [docs]
+//	groups:
+//	  S0456BACKEND: ["*"]
+type accessPolicyFile struct {
+	Channels map[string][]string `yaml:"channels"` // chat channel ID → repos
+	Groups   map[string][]string `yaml:"groups"`   // Slack user group ID → repos
+}
+
+// groupMemberLister is implemented by chat platforms with user groups.
+type groupMemberLister interface {
+	UserGroupMembers(ctx context.Context, groupID string) ([]string, error)
+}
+
+// accessPolicy limits which repos a chat request may target, by the channel it
+// was made in and the user groups of the requester. A request is allowed if
+// any rule that applies to it lists the repo. Requests without a chat channel
+// (the REST API, GitHub webhooks) and issue assignments, which can only target
+// the issue's repo, are not restricted.
+type accessPolicy struct {
+	channels map[string][]string
+	groups   map[string][]string
+	members  groupMemberLister // nil: group rules never apply
+
+	mu           sync.Mutex
+	groupMembers map[string]cachedMembers
+}
+
+type cachedMembers struct {
+	users   []string
+	fetched time.Time
+}
+
+// loadAccessPolicy reads an access policy file.
+func loadAccessPolicy(path string, members groupMemberLister) (*accessPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f accessPolicyFile
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(f.Channels) == 0 && len(f.Groups) == 0 {
+		return nil, fmt.Errorf("%s has no channel or group rules", path)
+	}
+	return &accessPolicy{channels: f.Channels, groups: f.Groups, members: members, groupMembers: map[string]cachedMembers{}}, nil
+}
+
+// allowedRepos returns the repos the requester may target from channel, and
+// whether the policy applies at all.
+func (p *accessPolicy) allowedRepos(ctx context.Context, platform, channel, user string) ([]string, bool) {
+	if p == nil || channel == "" || platform == "github" {
+		return nil, false
+	}
+	repos, ok := p.channels[channel]
+	if !ok {
+		repos = p.channels[accessAnyRepo]
+	}
+	repos = slices.Clone(repos)
+	if platform == "slack" && user != "" && p.members != nil {
+		for group, groupRepos := range p.groups {
+			if p.isMember(ctx, group, user) {
+				repos = append(repos, groupRepos...)
+			}
+		}
+	}
+	return repos, true
+}
+
+// isMember reports whether user is in the user group, caching its members.
+func (p *accessPolicy) isMember(ctx context.Context, group, user string) bool {
+	p.mu.Lock()
+	cached, ok := p.groupMembers[group]
+	p.mu.Unlock()
+	if !ok || time.Since(cached.fetched) > groupMembersTTL {
+		users, err := p.members.UserGroupMembers(ctx, group)
+		if err != nil {
+			slog.WarnContext(ctx, "access: failed to list group members", "group", group, "err", err)
+			return slices.Contains(cached.users, user) // keep using a stale list
+		}
+		cached = cachedMembers{users: users, fetched: time.Now()}
+		p.mu.Lock()
+		p.groupMembers[group] = cached
+		p.mu.Unlock()
+	}
+	return slices.Contains(cached.users, user)
+}
+
+// checkAccess returns a user-facing denial if the request in ctx may not
+// target repo, recording the denial in the audit log.
+func (o *Orchestrator) checkAccess(ctx context.Context, repo string) string {
+	platform := PlatformFromCtx(ctx)
+	channel, _ := ctx.Value(ctxKeyChannel).(string)
+	user := UserFromCtx(ctx)
+	allowed, governed := o.access.allowedRepos(ctx, platform, channel, user)
+	if !governed || slices.Contains(allowed, accessAnyRepo) || slices.Contains(allowed, repo) {
+		return ""
+	}
+	o.hub.audit(auditEntry{Action: "access_denied", Platform: platform, Channel: channel, User: user, Repo: repo})
+	if len(allowed) == 0 {
+		return fmt.Sprintf("Requests from here can't target any repositories, so I can't work on %q. Ask an admin if you need access.", repo)
+	}
+	slices.Sort(allowed)
+	return fmt.Sprintf("You can't ask me to work on %q from here. Repositories allowed here: %s.", repo, strings.Join(slices.Compact(allowed), ", "))
+}
+
+// auditLogFile holds security-relevant decisions, one JSON object per line.
+// It shares the data dir with the JSONL event store, which takes every
+// *.jsonl file there for a job, hence the .log suffix.
+const auditLogFile = "audit.log"
+
+// legacyAuditLogFile is where the audit log was kept before auditLogFile.
+const legacyAuditLogFile = "audit.jsonl"
+
+// auditEntry is one line of the audit log.
+type auditEntry struct {
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`
+	Platform string    `json:"platform,omitempty"`
+	Channel  string    `json:"channel,omitempty"`
+	User     string    `json:"user,omitempty"`
+	Repo     string    `json:"repo,omitempty"`
+}
+
+// moveLegacyAuditLog renames an audit log written as legacyAuditLogFile, so it
+// stops showing up as a job.
+func (h *Hub) moveLegacyAuditLog() {
+	legacy := filepath.Join(h.dataDir, legacyAuditLogFile)
+	if _, err := os.Stat(legacy); err != nil {
+		return
+	}
+	path := filepath.Join(h.dataDir, auditLogFile)
+	if _, err := os.Stat(path); err == nil {
+		slog.Warn("audit: both audit logs exist, leaving the old one", "old", legacy, "new", path)
+		return
+	}
+	if err := os.Rename(legacy, path); err != nil {
+		slog.Error("audit: failed to move the audit log", "err", err)
+	}
+}
+
+// audit appends e to the audit log and logs it.
+func (h *Hub) audit(e auditEntry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	slog.Info("audit", "action", e.Action, "platform", e.Platform, "channel", e.Channel, "user", e.User, "repo", e.Repo)
+	metricAuditEvents.WithLabelValues(e.Action).Inc()
+	if h == nil || h.dataDir == "" {
+		return
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	h.auditMu.Lock()
+	defer h.auditMu.Unlock()
+	f, err := os.OpenFile(filepath.Join(h.dataDir, auditLogFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		slog.Error("audit: failed to open the audit log", "err", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		slog.Error("audit: failed to write the audit log", "err", err)
+	}
+}
+
+// UserGroupMembers implements groupMemberLister with Slack user groups
+// (needs the usergroups:read scope).
+func (n *SlackPlatform) UserGroupMembers(ctx context.Context, groupID string) ([]string, error) {
+	return n.client.GetUserGroupMembersContext(ctx, groupID)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type fakeGroups map[string][]string
+
+func (f fakeGroups) UserGroupMembers(_ context.Context, group string) ([]string, error) {
+	return f[group], nil
+}
+
+func TestCheckAccess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.yml")
+	os.WriteFile(path, []byte(`channels:
+  C-BILLING: [billing-service, billing-web]
+  "*": [docs]
+groups:
+  S-PLATFORM: ["*"]
+`), 0o644)
+	policy, err := loadAccessPolicy(path, fakeGroups{"S-PLATFORM": {"U-ADMIN"}})
+	if err != nil {
+		t.Fatalf("loadAccessPolicy: %v", err)
+	}
+	dir := t.TempDir()
+	drainHub(t)
+	o := &Orchestrator{hub: NewHub(dir), access: policy}
+
+	request := func(platform, channel, user string) context.Context {
+		ctx := context.Background()
+		if channel != "" {
+			ctx = WithSlackThread(ctx, channel, "ts")
+		}
+		return WithUser(WithPlatform(ctx, platform), user)
+	}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		repo    string
+		allowed bool
+	}{
+		{"listed repo", request("slack", "C-BILLING", "U1"), "billing-web", true},
+		{"unlisted repo", request("slack", "C-BILLING", "U1"), "api", false},
+		{"default channel rule", request("slack", "C-RANDOM", "U1"), "docs", true},
+		{"not in default rule", request("slack", "C-RANDOM", "U1"), "billing-service", false},
+		{"user group", request("slack", "C-RANDOM", "U-ADMIN"), "billing-service", true},
+		{"groups are Slack only", request("discord", "C-RANDOM", "U-ADMIN"), "billing-service", false},
+		{"no channel (API)", request("", "", ""), "billing-service", true},
+		{"issue assignment", request("github", "billing-service", "octocat"), "billing-service", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := o.checkAccess(tt.ctx, tt.repo)
+			if (got == "") != tt.allowed {
+				t.Errorf("checkAccess(%q) = %q, want allowed %v", tt.repo, got, tt.allowed)
+			}
+		})
+	}
+
+	if got := o.checkAccess(request("slack", "C-BILLING", "U1"), "api"); !strings.Contains(got, "billing-service, billing-web") {
+		t.Errorf("denial = %q", got)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, auditLogFile))
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var last auditEntry
+	json.Unmarshal([]byte(lines[len(lines)-1]), &last)
+	if len(lines) != 4 || last.Action != "access_denied" || last.Channel != "C-BILLING" || last.User != "U1" || last.Repo != "api" {
+		t.Errorf("audit log = %q", data)
+	}
+}
+
+func TestLoadAccessPolicy_Empty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.yml")
+	os.WriteFile(path, []byte("channels: {}\n"), 0o644)
+	if _, err := loadAccessPolicy(path, nil); err == nil {
+		t.Error("expected an error for a policy without rules")
+	}
+}
+
+func TestAudit_NotAJob(t *testing.T) {
+	drainHub(t)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, legacyAuditLogFile), []byte(`{"action":"access_denied"}`+"\n"), 0o600)
+	hub := NewHub(dir)
+	hub.audit(auditEntry{Action: "access_denied", Channel: "C1", User: "U1", Repo: "api"})
+
+	if _, err := os.Stat(filepath.Join(dir, legacyAuditLogFile)); !os.IsNotExist(err) {
+		t.Errorf("legacy audit log not moved (err %v)", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, auditLogFile))
+	if n := strings.Count(string(data), "\n"); n != 2 {
+		t.Errorf("audit log has %d lines, want the old one and the new one: %q", n, data)
+	}
+
+	page, err := hub.store.ListJobs(jobQuery{Limit: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Jobs) != 0 || page.Total != 0 {
+		t.Errorf("jobs = %+v, want none", page.Jobs)
+	}
+	stats, err := hub.store.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalJobs != 0 || stats.RunningJobs != 0 {
+		t.Errorf("stats = %+v, want no jobs", stats)
+	}
+}
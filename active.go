@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// jobActivity is what an open job is doing, followed from its events so
+// /api/active can answer from memory.
+type jobActivity struct {
+	started       time.Time // job_started; zero for a job restored after a restart
+	phaseSince    time.Time
+	requesterName string
+	tool          string // the tool call in progress or last made in this phase, e.g. "Read main.go"
+	toolSince     time.Time
+}
+
+// trackActivity folds e into its job's activity. It is called from Hub.Emit,
+// possibly with the job's state locked, so it only takes activityMu.
+func (h *Hub) trackActivity(e Event) {
+	h.activityMu.Lock()
+	defer h.activityMu.Unlock()
+	a := h.activity[e.JobID]
+	switch d := e.Data.(type) {
+	case JobStartedData:
+		h.activity[e.JobID] = &jobActivity{started: e.Timestamp, phaseSince: e.Timestamp, requesterName: d.RequestedByName}
+		return
+	case JobCompletedData, JobErrorData, JobCancelledData, JobExpiredData:
+		delete(h.activity, e.JobID)
+		return
+	case PhaseChangedData:
+		if a == nil {
+			a = &jobActivity{}
+			h.activity[e.JobID] = a
+		}
+		a.phaseSince, a.tool = e.Timestamp, ""
+		return
+	}
+	if a == nil {
+		return
+	}
+	switch d := e.Data.(type) {
+	case ToolStartedData:
+		a.tool, a.toolSince = d.ToolName, e.Timestamp
+		if in := firstLine(d.Input); in != "" {
+			a.tool += " " + truncate(in, 120)
+		}
+	case ToolCompletedData:
+		if a.tool == d.ToolName || strings.HasPrefix(a.tool, d.ToolName+" ") {
+			a.tool = ""
+		}
+	case ClaudeCodeLineData:
+		// A sub-agent's calls show as the main agent's Task call.
+		if d.ToolName != "" && d.AgentID == "" {
+			a.tool, a.toolSince = toolSource(d.ToolName, json.RawMessage(d.ToolInput)), e.Timestamp
+		}
+	}
+}
+
+// activeJob is one job in the /api/active response.
+type activeJob struct {
+	JobID           string     `json:"job_id"`
+	Repo            string     `json:"repo"`
+	Task            string     `json:"task"`
+	Phase           JobPhase   `json:"phase"`
+	Platform        string     `json:"platform,omitempty"`
+	RequestedBy     string     `json:"requested_by,omitempty"`
+	RequestedByName string     `json:"requested_by_name,omitempty"`
+	StartedAt       *time.Time `json:"started_at,omitempty"` // unset for jobs restored after a restart
+	ElapsedMs       int64      `json:"elapsed_ms,omitempty"`
+	PhaseElapsedMs  int64      `json:"phase_elapsed_ms,omitempty"`
+	CurrentTool     string     `json:"current_tool,omitempty"`
+	ToolElapsedMs   int64      `json:"tool_elapsed_ms,omitempty"`
+}
+
+// activeJobs is the /api/active response.
+type activeJobs struct {
+	Running []activeJob `json:"running"` // planning, implementing, reviewing or explaining
+	Queued  []activeJob `json:"queued"`  // waiting on a person: a question, approval or push
+}
+
+// ActiveJobs returns the open jobs, running and queued, oldest first, from
+// the Hub's in-memory state.
+func (h *Hub) ActiveJobs(now time.Time) activeJobs {
+	res := activeJobs{Running: []activeJob{}, Queued: []activeJob{}}
+	if h == nil {
+		return res
+	}
+	var jobs []activeJob
+	h.jobStates.Range(func(k, v any) bool {
+		s := v.(*JobState)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.closed {
+			return true
+		}
+		jobs = append(jobs, activeJob{
+			JobID:       k.(string),
+			Repo:        s.Repo,
+			Task:        truncate(s.Task, 200),
+			Phase:       s.Phase,
+			Platform:    s.Platform,
+			RequestedBy: s.RequestedBy,
+		})
+		return true
+	})
+
+	h.activityMu.Lock()
+	for i := range jobs {
+		j := &jobs[i]
+		a := h.activity[j.JobID]
+		if a == nil {
+			continue
+		}
+		j.RequestedByName = a.requesterName
+		if !a.started.IsZero() {
+			started := a.started
+			j.StartedAt = &started
+			j.ElapsedMs = now.Sub(a.started).Milliseconds()
+		}
+		if !a.phaseSince.IsZero() {
+			j.PhaseElapsedMs = now.Sub(a.phaseSince).Milliseconds()
+		}
+		if a.tool != "" {
+			j.CurrentTool = redactSecrets(a.tool)
+			j.ToolElapsedMs = now.Sub(a.toolSince).Milliseconds()
+		}
+	}
+	h.activityMu.Unlock()
+
+	// Jobs restored after a restart have no start time, and sort first.
+	startedAt := func(j activeJob) time.Time {
+		if j.StartedAt == nil {
+			return time.Time{}
+		}
+		return *j.StartedAt
+	}
+	slices.SortFunc(jobs, func(a, b activeJob) int {
+		if c := startedAt(a).Compare(startedAt(b)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.JobID, b.JobID)
+	})
+	for _, j := range jobs {
+		switch j.Phase {
+		case PhasePlanning, PhaseImplementing, PhaseReviewing, PhaseExplaining:
+			res.Running = append(res.Running, j)
+		case PhaseAwaitingQuestion, PhaseAwaitingApproval, PhaseAwaitingPush:
+			res.Queued = append(res.Queued, j)
+		}
+	}
+	return res
+}
+
+// ServeActive handles GET /api/active: the jobs running and waiting right
+// now, without reading the event store.
+func (h *Hub) ServeActive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(h.ActiveJobs(time.Now()))
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHub_ActiveJobs(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+
+	hub.Emit("job-a", JobStartedData{Repo: "api", Task: "fix the login bug", RequestedBy: "U1", RequestedByName: "Ada"})
+	hub.SetJobState("job-a", &JobState{Repo: "api", Task: "fix the login bug", Phase: PhasePlanning, Platform: "slack", RequestedBy: "U1"})
+	hub.Emit("job-a", ClaudeCodeLineData{ToolName: "Read", ToolInput: `{"file_path":"auth/login.go"}`})
+	hub.Emit("job-a", ClaudeCodeLineData{ToolName: "Grep", ToolInput: `{"pattern":"x"}`, AgentID: "task-1"})
+
+	hub.Emit("job-b", JobStartedData{Repo: "web", Task: "add a footer"})
+	hub.SetJobState("job-b", &JobState{Repo: "web", Task: "add a footer", Phase: PhaseImplementing})
+	hub.Emit("job-b", ToolStartedData{ToolName: "run_tests", Input: "go test ./..."})
+	hub.Emit("job-b", ToolCompletedData{ToolName: "run_tests"})
+
+	// Restored after a restart: no events since.
+	hub.SetJobState("job-c", &JobState{Repo: "web", Task: "bump deps", Phase: PhaseAwaitingApproval, RequestedBy: "U2"})
+
+	hub.Emit("job-d", JobStartedData{Repo: "api", Task: "done already"})
+	hub.SetJobState("job-d", &JobState{Repo: "api", Phase: PhaseDone, closed: true})
+	hub.Emit("job-d", JobCompletedData{})
+
+	hub.Emit("job-e", JobStartedData{Repo: "api", Task: "deploy config"})
+	hub.SetJobState("job-e", &JobState{Repo: "api", Task: "deploy config", Phase: PhaseAwaitingPush})
+	hub.Emit("job-e", ToolStartedData{ToolName: "create_pr"})
+	hub.Emit("job-e", PhaseChangedData{Phase: string(PhaseAwaitingPush)})
+
+	got := hub.ActiveJobs(time.Now().Add(time.Minute))
+
+	if len(got.Running) != 2 {
+		t.Fatalf("running = %+v, want job-a and job-b", got.Running)
+	}
+	a, b := got.Running[0], got.Running[1]
+	if a.JobID != "job-a" || b.JobID != "job-b" {
+		t.Fatalf("running order = %s, %s; want job-a, job-b", a.JobID, b.JobID)
+	}
+	if a.CurrentTool != "Read auth/login.go" {
+		t.Errorf("job-a current tool = %q, want the main agent's Read", a.CurrentTool)
+	}
+	if a.RequestedBy != "U1" || a.RequestedByName != "Ada" || a.Platform != "slack" {
+		t.Errorf("job-a requester = %q %q on %q", a.RequestedBy, a.RequestedByName, a.Platform)
+	}
+	if a.StartedAt == nil || a.ElapsedMs < time.Minute.Milliseconds() || a.PhaseElapsedMs < time.Minute.Milliseconds() {
+		t.Errorf("job-a started %v, elapsed %dms, phase %dms; want about a minute", a.StartedAt, a.ElapsedMs, a.PhaseElapsedMs)
+	}
+	if b.CurrentTool != "" {
+		t.Errorf("job-b current tool = %q, want none after run_tests completed", b.CurrentTool)
+	}
+
+	if len(got.Queued) != 2 {
+		t.Fatalf("queued = %+v, want job-c and job-e", got.Queued)
+	}
+	c, e := got.Queued[0], got.Queued[1]
+	if c.JobID != "job-c" || e.JobID != "job-e" {
+		t.Fatalf("queued order = %s, %s; want the restored job-c first", c.JobID, e.JobID)
+	}
+	if c.StartedAt != nil || c.ElapsedMs != 0 {
+		t.Errorf("restored job-c started %v, elapsed %dms; want unset", c.StartedAt, c.ElapsedMs)
+	}
+	if e.CurrentTool != "" {
+		t.Errorf("job-e current tool = %q, want none after a phase change", e.CurrentTool)
+	}
+}
+
+func TestHub_ServeActive(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+
+	rec := httptest.NewRecorder()
+	hub.ServeActive(rec, httptest.NewRequest(http.MethodGet, "/api/active", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	var body map[string][]activeJob
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["running"] == nil || body["queued"] == nil {
+		t.Errorf("body = %s, want empty running and queued lists", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	hub.ServeActive(rec, httptest.NewRequest(http.MethodPost, "/api/active", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST status = %d, want 405", rec.Code)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// maxAPIBodySize is the maximum request body size accepted by the job submission API.
+const maxAPIBodySize = 1 << 20 // 1 MB
+
+// submitJobRequest is the body of POST /api/jobs.
+type submitJobRequest struct {
+	Repo       string   `json:"repo"`
+	Task       string   `json:"task"`
+	BaseBranch string   `json:"base_branch,omitempty"` // optional; defaults to the repo's base branch
+	Plan       string   `json:"plan,omitempty"`        // optional pre-approved plan; skips planning
+	Paths      []string `json:"paths,omitempty"`       // optional; limits the job to these directories or files
+}
+
+// NewJobsHandler serves /api/jobs: GET lists jobs, POST submits a new job
+// without going through Slack.
+func NewJobsHandler(hub *Hub, orch *Orchestrator, approver *Approver) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			hub.ServeJobList(w, r)
+		case http.MethodPost:
+			serveSubmitJob(w, r, orch, approver)
+		default:
+			http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// cancelJob cancels a job on behalf of the web UI or API and tells its chat
+// thread, if any. It returns ErrJobNotFound for unknown jobs.
+func cancelJob(hub *Hub, orch *Orchestrator, notifier Notifier, jobID, cancelledBy string) error {
+	state, ok := hub.GetJobState(jobID)
+	if jobID == "" || !ok {
+		return ErrJobNotFound
+	}
+	ctx := state.ThreadContext(context.Background())
+	if err := orch.CancelJob(ctx, jobID, cancelledBy); err != nil {
+		return err
+	}
+	if state.Channel != "" {
+		if err := notifier.Notify(ctx, "Job cancelled via the web UI/API."); err != nil {
+			slog.ErrorContext(ctx, "cancel: failed to notify", "job_id", jobID, "err", err)
+		}
+	}
+	return nil
+}
+
+// serveSubmitJob handles POST /api/jobs — starts a job and returns its ID as
+// soon as it is created. Planning (and implementation, when a plan is supplied)
+// continues in the background; progress is available via /events and /api/jobs/{id}.
+func serveSubmitJob(w http.ResponseWriter, r *http.Request, orch *Orchestrator, approver *Approver) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxAPIBodySize+1))
+	if err != nil {
+		http.Error(w, `{"error":"failed to read body"}`, http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxAPIBodySize {
+		http.Error(w, `{"error":"request body too large"}`, http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var req submitJobRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, `{"error":"invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+	req.Repo = strings.TrimSpace(req.Repo)
+	req.Task = strings.TrimSpace(req.Task)
+	if req.Repo == "" || req.Task == "" {
+		http.Error(w, `{"error":"repo and task are required"}`, http.StatusBadRequest)
+		return
+	}
+	paths, err := cleanScopePaths(req.Paths)
+	if err != nil {
+		http.Error(w, `{"error":"invalid paths"}`, http.StatusBadRequest)
+		return
+	}
+	intent := IntentResult{Repo: req.Repo, Task: req.Task, BaseBranch: strings.TrimSpace(req.BaseBranch), Paths: paths}
+
+	jobIDCh := make(chan string, 1)
+	doneCh := make(chan OrchestratorResult, 1)
+	go func() {
+		defer recoverGoroutine("api: submit job", func() { doneCh <- OrchestratorResult{Text: panicReply} })
+		// Detached from the request — the job outlives the HTTP response.
+		ctx := context.Background()
+		result, err := orch.HandleDirectRequest(ctx, intent, req.Plan, func(jobID string) {
+			jobIDCh <- jobID
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "api: submit job failed", "job_id", result.JobID, "err", err)
+			result.Text = err.Error()
+		}
+		doneCh <- result
+
+		// A supplied plan counts as pre-approved.
+		if err == nil && req.Plan != "" && result.JobID != "" && len(result.PlanBlocks) > 0 {
+			approver.Approve(ctx, result.JobID, "", "", "API")
+		}
+	}()
+
+	var jobID string
+	select {
+	case jobID = <-jobIDCh:
+	case result := <-doneCh:
+		if result.JobID == "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": result.Text})
+			return
+		}
+		jobID = result.JobID
+	case <-r.Context().Done():
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"job_id": jobID})
+}
+
+// serveRerunJob handles POST /api/jobs/{id}/rerun — starts a failed, cancelled
+// or expired job's request again as a new job and returns the new job's ID as
+// soon as it is created. The new job reports to the old one's chat thread, if
+// any.
+func serveRerunJob(w http.ResponseWriter, r *http.Request, orch *Orchestrator, approver *Approver, jobID string) {
+	src, err := orch.rerunSource(jobID)
+	if err == nil && src.Channel != "" && orch.hub.ActiveJobForThread(src.Channel, src.ThreadTS) != "" {
+		err = errThreadBusy
+	}
+	if err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, ErrJobNotFound) {
+			status = http.StatusNotFound
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": rerunErrorText(err)})
+		return
+	}
+
+	jobIDCh := make(chan string, 1)
+	doneCh := make(chan OrchestratorResult, 1)
+	go func() {
+		defer recoverGoroutine("api: rerun job", func() { doneCh <- OrchestratorResult{Text: panicReply} })
+		ctx := context.Background()
+		result, err := approver.Rerun(ctx, src, "API", func(newJobID string) {
+			jobIDCh <- newJobID
+			approver.notifyRerun(ctx, src, "Retrying this job from the web UI/API...")
+		})
+		switch {
+		case err != nil:
+			slog.ErrorContext(ctx, "api: rerun job failed", "job_id", jobID, "new_job_id", result.JobID, "err", err)
+			result.Text = rerunErrorText(err)
+			approver.notifyRerun(ctx, src, errorReply(err))
+		case result.PlanText != "":
+			// The thread hasn't seen the new plan; approving by text works everywhere.
+			approver.notifyRerun(ctx, src, fmt.Sprintf("Here's my plan:\n\n%s\n\nReply \"go\" to approve, or tell me what to change.", planMarkdown(orch.hub, result)))
+		case result.Text != "" && !result.Reported:
+			approver.notifyRerun(ctx, src, result.Text)
+		}
+		doneCh <- result
+	}()
+
+	var newJobID string
+	select {
+	case newJobID = <-jobIDCh:
+	case result := <-doneCh:
+		if result.JobID == "" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": result.Text})
+			return
+		}
+		newJobID = result.JobID
+	case <-r.Context().Done():
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"job_id": newJobID, "rerun_of": jobID})
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestJobsHandler_SubmitValidation(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	handler := NewJobsHandler(hub, nil, nil)
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{"invalid JSON", http.MethodPost, "{", http.StatusBadRequest},
+		{"missing repo", http.MethodPost, `{"task":"fix it"}`, http.StatusBadRequest},
+		{"missing task", http.MethodPost, `{"repo":"myrepo"}`, http.StatusBadRequest},
+		{"whitespace only", http.MethodPost, `{"repo":" ","task":" "}`, http.StatusBadRequest},
+		{"method not allowed", http.MethodDelete, "", http.StatusMethodNotAllowed},
+		{"GET lists jobs", http.MethodGet, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/jobs", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/slack-go/slack"
 )
@@ -11,84 +11,139 @@ import (
 // Approver provides a shared approval path used by both Slack button interactions
 // and the web UI approve endpoint.
 type Approver struct {
-	slackClient  *slack.Client
-	hub          *Hub
-	orchestrator *Orchestrator
+	notifier      Notifier
+	hub           *Hub
+	orchestrator  *Orchestrator
+	autoFixChecks bool // start a fix job when a PR's CI checks fail
 }
 
-// NewApprover creates an Approver.
-func NewApprover(slackClient *slack.Client, hub *Hub, orch *Orchestrator) *Approver {
+// NewApprover creates an Approver. Plan-message updates are only performed
+// when the job's platform is Slack; other backends get plain-text progress only.
+func NewApprover(notifier Notifier, hub *Hub, orch *Orchestrator, autoFixChecks bool) *Approver {
 	return &Approver{
-		slackClient:  slackClient,
-		hub:          hub,
-		orchestrator: orch,
+		notifier:      notifier,
+		hub:           hub,
+		orchestrator:  orch,
+		autoFixChecks: autoFixChecks,
 	}
 }
 
 // Approve runs the implementation for an approved plan. It is safe to call from
 // multiple goroutines; TryStartImplementation provides an atomic guard.
 func (a *Approver) Approve(ctx context.Context, jobID, channel, threadTS, approvedBy string) {
+	defer recoverGoroutine("approve: job "+jobID, func() {
+		a.orchestrator.closeJob(ctx, jobID, JobErrorData{Error: "internal error"})
+		_ = a.notifier.Notify(ctx, panicReply)
+	})
 	if !a.hub.TryStartImplementation(jobID) {
-		log.Printf("approve: job %s already implementing or wrong phase, ignoring", jobID)
+		slog.InfoContext(ctx, "approve: already implementing or wrong phase, ignoring", "job_id", jobID)
 		return
 	}
 
-	a.hub.Emit(jobID, EventPlanApproved, map[string]any{
-		"approved_by": approvedBy,
+	a.hub.Emit(jobID, PlanApprovedData{
+		ApprovedBy: approvedBy,
 	})
 
-	// Ensure context has Slack thread info.
-	ctx = WithSlackThread(ctx, channel, threadTS)
-	ctx = WithHub(ctx, a.hub)
+	ctx = a.threadContext(ctx, jobID, channel, threadTS)
+	state, ok := a.hub.GetJobState(jobID)
 
 	// Update the plan message: remove button, show "Approved by ...".
-	state, ok := a.hub.GetJobState(jobID)
-	if ok {
+	if sn, isSlack := a.platformNotifier(ctx).(*SlackPlatform); ok && isSlack {
 		state.mu.Lock()
 		planMsgTS := state.PlanMsgTS
 		planContent := state.PlanContent
 		state.mu.Unlock()
 		if planMsgTS != "" {
 			blocks := formatApprovedPlanBlocks(planContent, approvedBy)
-			_, _, _, err := a.slackClient.UpdateMessage(channel, planMsgTS,
+			_, _, _, err := sn.Client().UpdateMessage(channel, planMsgTS,
 				slack.MsgOptionText(formatPlanMessage(planContent), false),
 				slack.MsgOptionBlocks(blocks...),
 			)
 			if err != nil {
-				log.Printf("approve: failed to update plan message: %v", err)
+				slog.WarnContext(ctx, "approve: failed to update plan message", "job_id", jobID, "err", err)
 			}
 		}
 	}
 
 	// Post "Implementing..." message to thread.
-	_, _, err := a.slackClient.PostMessage(channel,
-		slack.MsgOptionText("Implementing approved plan...", false),
-		slack.MsgOptionTS(threadTS),
-	)
-	if err != nil {
-		log.Printf("approve: failed to post implementing message: %v", err)
+	if err := a.notifier.Notify(ctx, "Implementing approved plan..."); err != nil {
+		slog.ErrorContext(ctx, "approve: failed to post implementing message", "job_id", jobID, "err", err)
 	}
 
 	result, err := a.orchestrator.HandleApproval(ctx, jobID)
+	if err != nil {
+		slog.ErrorContext(ctx, "approve: orchestrator error", "job_id", jobID, "err", err)
+		a.hub.ClearImplementation(jobID)
+	}
+	a.reportResult(ctx, jobID, channel, threadTS, result, err)
+}
+
+// userMentioner is implemented by notifiers that can mention a user on the
+// platform the context's job came from.
+type userMentioner interface {
+	mentionUser(ctx context.Context, userID string) string
+}
 
+// threadContext returns ctx carrying the chat thread and platform the job came from.
+func (a *Approver) threadContext(ctx context.Context, jobID, channel, threadTS string) context.Context {
+	ctx = WithSlackThread(ctx, channel, threadTS)
+	ctx = WithHub(ctx, a.hub)
+	if state, ok := a.hub.GetJobState(jobID); ok && PlatformFromCtx(ctx) == "" {
+		state.mu.Lock()
+		platform := state.Platform
+		state.mu.Unlock()
+		if platform != "" {
+			ctx = WithPlatform(ctx, platform)
+		}
+	}
+	return ctx
+}
+
+// reportResult posts the outcome of implementing or pushing to the thread: a
+// dry run's diff, the PR link or the error. For a new PR it then follows the
+// PR's CI checks.
+func (a *Approver) reportResult(ctx context.Context, jobID, channel, threadTS string, result OrchestratorResult, err error) {
 	var text string
 	if err != nil {
-		log.Printf("approve: orchestrator error: %v", err)
 		text = fmt.Sprintf("Sorry, I hit an error trying to implement: %s", err.Error())
-		a.hub.ClearImplementation(jobID)
+	} else if result.Text != "" {
+		text = result.Text // errors, a dry run, or a PR opened with caveats
 	} else if result.PRURL != "" {
 		text = fmt.Sprintf("Done! %s", result.PRURL)
-	} else if result.Text != "" {
-		text = result.Text
 	} else {
 		text = "Done!"
 	}
+	if err == nil && result.Diff == "" {
+		// Whoever approved, the result is for the requester.
+		if m, ok := a.notifier.(userMentioner); ok {
+			if requester := a.hub.JobRequester(jobID); requester != "" {
+				text = m.mentionUser(ctx, requester) + " " + text
+			}
+		}
+	}
 
-	_, _, err = a.slackClient.PostMessage(channel,
-		slack.MsgOptionText(text, false),
-		slack.MsgOptionTS(threadTS),
-	)
-	if err != nil {
-		log.Printf("approve: failed to post result: %v", err)
+	if err := a.notifier.Notify(ctx, text); err != nil {
+		slog.ErrorContext(ctx, "approve: failed to post result", "job_id", jobID, "err", err)
+	}
+	if err == nil && result.Diff != "" {
+		a.postDiff(ctx, jobID, result.Diff)
+	}
+
+	// Follow the PR's CI checks, reporting back into the thread.
+	if err == nil && result.PRURL != "" {
+		if pr, ok := a.hub.ThreadPR(channel, threadTS); ok && pr.URL == result.PRURL {
+			go a.WatchChecks(context.WithoutCancel(ctx), jobID, pr, a.autoFixChecks)
+		}
+	}
+}
+
+// platformNotifier resolves the notifier for the platform in ctx, so Slack-only
+// features are used only for jobs that came from Slack.
+func (a *Approver) platformNotifier(ctx context.Context) Notifier {
+	if r, ok := a.notifier.(*chatRouter); ok {
+		if p := r.forPlatform(PlatformFromCtx(ctx)); p != nil {
+			return p
+		}
 	}
+	return a.notifier
 }
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReportResult_MentionsRequester(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	hub.SetJobState("job-1", &JobState{Platform: "slack", RequestedBy: "U1"})
+	platform := &fakePlatform{name: "slack"}
+	a := NewApprover(newChatRouter(platform), hub, nil, false)
+	// Approved by someone else in the thread; the result still goes to U1.
+	ctx := WithPlatform(context.Background(), "slack")
+
+	a.reportResult(ctx, "job-1", "C1", "ts1", OrchestratorResult{IsJob: true, JobID: "job-1", PRURL: "https://x/pull/1"}, nil)
+	a.reportResult(ctx, "job-1", "C1", "ts1", OrchestratorResult{IsJob: true, JobID: "job-1", Text: "Here's the diff.", Diff: "+x"}, nil)
+	if len(platform.sent) < 2 || platform.sent[0] != "@U1 Done! https://x/pull/1" || platform.sent[1] != "Here's the diff." {
+		t.Errorf("sent %q", platform.sent)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Artifacts kept for every implemented job, served by GET
+// /api/jobs/{id}/artifacts/{name}.
+const (
+	artifactDiff       = "changes.diff"     // the committed changes
+	artifactTranscript = "transcript.jsonl" // Claude Code's stream-json output, all sessions
+	artifactTestLog    = "tests.log"        // every test run, untruncated
+)
+
+// jobArtifactsDir is where files kept for a job, beyond its events, are stored.
+func jobArtifactsDir(dataDir, jobID string) string {
+	return filepath.Join(dataDir, "artifacts", jobID)
+}
+
+// writeArtifact stores a job artifact, replacing any earlier one of that name.
+func (h *Hub) writeArtifact(jobID, name string, data []byte) error {
+	dir := jobArtifactsDir(h.dataDir, jobID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), data, 0o644)
+}
+
+// appendArtifact opens a job artifact for appending, or returns nil (logging
+// why) if it can't. The caller closes it.
+func (h *Hub) appendArtifact(jobID, name string) *os.File {
+	if h == nil || jobID == "" {
+		return nil
+	}
+	dir := jobArtifactsDir(h.dataDir, jobID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		slog.Error("hub: failed to create artifacts dir", "job_id", jobID, "err", err)
+		return nil
+	}
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		slog.Error("hub: failed to open artifact", "job_id", jobID, "artifact", name, "err", err)
+		return nil
+	}
+	return f
+}
+
+// Artifacts lists the names of a job's artifacts.
+func (h *Hub) Artifacts(jobID string) []string {
+	entries, err := os.ReadDir(jobArtifactsDir(h.dataDir, jobID))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// saveDiff stores the changes committed at repoDir's HEAD as the job's diff
+// artifact and returns the names of all its artifacts, for job_completed.
+func (o *Orchestrator) saveDiff(ctx context.Context, jobID, repoDir string) []string {
+	diff, err := HeadDiff(ctx, repoDir)
+	if err == nil {
+		err = o.hub.writeArtifact(jobID, artifactDiff, []byte(redactSecrets(string(diff))))
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "orchestrator: failed to save diff", "job_id", jobID, "err", err)
+	}
+	return o.hub.Artifacts(jobID)
+}
+
+// serveArtifacts handles GET /api/jobs/{id}/artifacts (a JSON list of names)
+// and GET /api/jobs/{id}/artifacts/{name} (the file, as plain text).
+func (h *Hub) serveArtifacts(w http.ResponseWriter, r *http.Request, jobID, name string) {
+	if !isPlainFileName(jobID) {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	if name == "" {
+		names := h.Artifacts(jobID)
+		if names == nil {
+			names = []string{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]string{"artifacts": names})
+		return
+	}
+	if !isPlainFileName(name) {
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+	f, err := os.Open(filepath.Join(jobArtifactsDir(h.dataDir, jobID), name))
+	if err != nil {
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+	// Artifacts hold repo content; never let a browser render them as HTML.
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+// isPlainFileName reports whether name names a file in a directory rather than
+// a path out of it.
+func isPlainFileName(name string) bool {
+	return name != "" && name == filepath.Base(name) && !strings.HasPrefix(name, ".")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestHub_ServeArtifacts(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	if err := hub.writeArtifact("job-1", artifactDiff, []byte("diff --git a/x b/x\n")); err != nil {
+		t.Fatalf("writeArtifact: %v", err)
+	}
+	f := hub.appendArtifact("job-1", artifactTestLog)
+	io.WriteString(f, "$ go test ./...\n")
+	f.Close()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		hub.ServeJobAPI(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/jobs/job-1/artifacts")
+	var list struct{ Artifacts []string }
+	json.NewDecoder(rec.Body).Decode(&list)
+	if !slices.Equal(list.Artifacts, []string{artifactDiff, artifactTestLog}) {
+		t.Errorf("artifacts = %v", list.Artifacts)
+	}
+
+	rec = get("/api/jobs/job-1/artifacts/" + artifactDiff)
+	if rec.Code != http.StatusOK || rec.Body.String() != "diff --git a/x b/x\n" {
+		t.Errorf("diff: %d %q", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	for _, path := range []string{
+		"/api/jobs/job-1/artifacts/missing.log",
+		"/api/jobs/job-1/artifacts/..%2F..%2Fthread-jobs.json",
+		"/api/jobs/../artifacts/x",
+	} {
+		if rec := get(path); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s = %d, want 404", path, rec.Code)
+		}
+	}
+
+	rec = get("/api/jobs/job-2/artifacts")
+	json.NewDecoder(rec.Body).Decode(&list)
+	if rec.Code != http.StatusOK || len(list.Artifacts) != 0 {
+		t.Errorf("job without artifacts: %d %v", rec.Code, list.Artifacts)
+	}
+}
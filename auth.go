@@ -2,32 +2,91 @@ package main
 
 import (
 	"crypto/subtle"
+	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 )
 
-// requireAuth wraps a handler with Bearer token authentication.
-// Accepts the token via Authorization: Bearer <token> header or ?token=<token> query parameter.
-func requireAuth(token string, next http.Handler) http.Handler {
+// authCookie carries a token accepted from ?token= so that reloads and
+// client-side navigation in the web UI stay authenticated.
+const authCookie = "bob_token"
+
+// authenticator checks requests against the static API tokens and, if
+// configured, OIDC-issued JWTs.
+type authenticator struct {
+	tokens [][]byte
+	oidc   *oidcVerifier // nil disables OIDC
+}
+
+// newAuthenticator returns an authenticator accepting any of tokens (empty
+// entries are ignored) or a JWT accepted by oidc.
+func newAuthenticator(tokens []string, oidc *oidcVerifier) *authenticator {
+	a := &authenticator{oidc: oidc}
+	for _, t := range tokens {
+		if t = strings.TrimSpace(t); t != "" {
+			a.tokens = append(a.tokens, []byte(t))
+		}
+	}
+	return a
+}
+
+// valid reports whether token is a static API token or a valid OIDC JWT.
+func (a *authenticator) valid(r *http.Request, token string) bool {
+	if token == "" {
+		return false
+	}
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), t) == 1 {
+			return true
+		}
+	}
+	if a.oidc != nil && strings.Count(token, ".") == 2 {
+		if _, err := a.oidc.Verify(r.Context(), token); err != nil {
+			slog.InfoContext(r.Context(), "auth: rejected OIDC token", "err", err)
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// requireAuth wraps a handler with token authentication.
+// Accepts the token via Authorization: Bearer <token> header, ?token=<token>
+// query parameter (for browser navigation and EventSource) or the bob_token
+// cookie set after a successful ?token= login.
+func requireAuth(auth *authenticator, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Check Authorization header first.
-		if auth := r.Header.Get("Authorization"); len(auth) > 7 && auth[:7] == "Bearer " {
-			if subtle.ConstantTimeCompare([]byte(auth[7:]), []byte(token)) == 1 {
+		if h := r.Header.Get("Authorization"); len(h) > 7 && h[:7] == "Bearer " {
+			if auth.valid(r, h[7:]) {
 				next.ServeHTTP(w, r)
 				return
 			}
 		}
-		// Fallback: check query parameter (for browser navigation and EventSource).
-		if q := r.URL.Query().Get("token"); q != "" {
-			if subtle.ConstantTimeCompare([]byte(q), []byte(token)) == 1 {
-				next.ServeHTTP(w, r)
-				return
-			}
+		// Fallback: check query parameter, remembering it in a cookie.
+		if q := r.URL.Query().Get("token"); q != "" && auth.valid(r, q) {
+			http.SetCookie(w, &http.Cookie{
+				Name:     authCookie,
+				Value:    q,
+				Path:     "/",
+				MaxAge:   int((7 * 24 * time.Hour).Seconds()),
+				HttpOnly: true,
+				Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+				SameSite: http.SameSiteStrictMode,
+			})
+			next.ServeHTTP(w, r)
+			return
+		}
+		if c, err := r.Cookie(authCookie); err == nil && auth.valid(r, c.Value) {
+			next.ServeHTTP(w, r)
+			return
 		}
 		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
 	})
 }
 
 // requireAuthFunc is the same as requireAuth but accepts an http.HandlerFunc.
-func requireAuthFunc(token string, next http.HandlerFunc) http.Handler {
-	return requireAuth(token, next)
+func requireAuthFunc(auth *authenticator, next http.HandlerFunc) http.Handler {
+	return requireAuth(auth, next)
 }
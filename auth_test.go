@@ -12,7 +12,7 @@ func TestRequireAuth(t *testing.T) {
 	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
-	handler := requireAuth(token, inner)
+	handler := requireAuth(newAuthenticator([]string{token, "", " second-token "}, nil), inner)
 
 	tests := []struct {
 		name       string
@@ -27,6 +27,8 @@ func TestRequireAuth(t *testing.T) {
 		{"no auth at all", "", "", http.StatusUnauthorized},
 		{"malformed header falls through", "Token secret-token-123", "", http.StatusUnauthorized},
 		{"both present header valid", "Bearer secret-token-123", "wrong-token", http.StatusOK},
+		{"additional token", "Bearer second-token", "", http.StatusOK},
+		{"empty token never matches", "Bearer ", "", http.StatusUnauthorized},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -46,3 +48,31 @@ func TestRequireAuth(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireAuth_QueryTokenSetsCookie(t *testing.T) {
+	handler := requireAuth(newAuthenticator([]string{"secret"}, nil), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jobs/abc?token=secret", nil))
+	cookies := rec.Result().Cookies()
+	if rec.Code != http.StatusOK || len(cookies) != 1 || cookies[0].Name != authCookie || !cookies[0].HttpOnly {
+		t.Fatalf("status = %d, cookies = %v", rec.Code, cookies)
+	}
+
+	// A reload without ?token= is authenticated by the cookie.
+	req := httptest.NewRequest(http.MethodGet, "/jobs/abc", nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("cookie status = %d, want 200", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/jobs/abc", nil)
+	req.AddCookie(&http.Cookie{Name: authCookie, Value: "wrong"})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad cookie status = %d, want 401", rec.Code)
+	}
+}
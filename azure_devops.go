@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	adoAPIVersion = "7.1"
+	// adoMaxDescription is the longest pull request description Azure DevOps accepts.
+	adoMaxDescription = 4000
+)
+
+// workItemPattern matches Azure Boards work item mentions, "AB#123", the
+// syntax Azure Boards also links from GitHub.
+var workItemPattern = regexp.MustCompile(`\bAB#(\d+)\b`)
+
+// AzureDevOpsProvider implements VCSProvider for the Git repos of an Azure
+// DevOps organization, across its projects. Repos in the default project are
+// named by their bare name, others as "project/repo".
+type AzureDevOpsProvider struct {
+	orgURL  string // e.g. https://dev.azure.com/contoso, or a collection URL on Azure DevOps Server
+	project string // default project; empty names every repo with its project
+	token   string // personal access token with Code (read & write) scope
+}
+
+// NewAzureDevOpsProvider creates an AzureDevOpsProvider.
+func NewAzureDevOpsProvider(orgURL, project, token string) *AzureDevOpsProvider {
+	return &AzureDevOpsProvider{orgURL: strings.TrimSuffix(orgURL, "/"), project: project, token: token}
+}
+
+func (a *AzureDevOpsProvider) Name() string  { return "azure-devops" }
+func (a *AzureDevOpsProvider) Token() string { return a.token }
+
+// locate returns the project and repo a name refers to. Bare names are in the
+// default project, or, without one, in the project of the same name, where
+// Azure DevOps puts a project's first repo.
+func (a *AzureDevOpsProvider) locate(name string) (project, short string) {
+	project, short = splitRepoName(name)
+	if project == "" {
+		project = cmp.Or(a.project, short)
+	}
+	return project, short
+}
+
+// repoName returns the name Bob uses for a repo in project.
+func (a *AzureDevOpsProvider) repoName(project, short string) string {
+	if strings.EqualFold(project, a.project) {
+		return short
+	}
+	return project + "/" + short
+}
+
+// webURL returns the repo's web URL, which is also its clone URL.
+func (a *AzureDevOpsProvider) webURL(name string) string {
+	project, short := a.locate(name)
+	return fmt.Sprintf("%s/%s/_git/%s", a.orgURL, url.PathEscape(project), url.PathEscape(short))
+}
+
+// apiURL returns the REST URL of the repo's resource at rest, e.g. "/pullrequests".
+func (a *AzureDevOpsProvider) apiURL(name, rest string, query url.Values) string {
+	project, short := a.locate(name)
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("api-version", adoAPIVersion)
+	return fmt.Sprintf("%s/%s/_apis/git/repositories/%s%s?%s", a.orgURL, url.PathEscape(project), url.PathEscape(short), rest, query.Encode())
+}
+
+func (a *AzureDevOpsProvider) FetchURL(name string) string {
+	u, err := url.Parse(a.webURL(name))
+	if err != nil {
+		return a.CleanURL(name)
+	}
+	u.User = url.UserPassword("bob", a.token) // any user name works with a PAT
+	return u.String()
+}
+
+func (a *AzureDevOpsProvider) CleanURL(name string) string {
+	return a.webURL(name)
+}
+
+// adoRepository is a Git repository from the Azure DevOps API.
+type adoRepository struct {
+	Name          string `json:"name"`
+	DefaultBranch string `json:"defaultBranch"` // "refs/heads/main"
+	RemoteURL     string `json:"remoteUrl"`
+	IsDisabled    bool   `json:"isDisabled"`
+	Project       struct {
+		Name       string `json:"name"`
+		Visibility string `json:"visibility"`
+	} `json:"project"`
+}
+
+// listRepositories returns the enabled Git repos in every project the token
+// can see.
+func (a *AzureDevOpsProvider) listRepositories(ctx context.Context) ([]adoRepository, error) {
+	apiURL := fmt.Sprintf("%s/_apis/git/repositories?api-version=%s", a.orgURL, adoAPIVersion)
+	body, status, err := a.do(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("azure devops api status %d: %s", status, body)
+	}
+	var resp struct {
+		Value []adoRepository `json:"value"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+	return slices.DeleteFunc(resp.Value, func(r adoRepository) bool { return r.IsDisabled }), nil
+}
+
+func (a *AzureDevOpsProvider) toRepo(r adoRepository) repo {
+	return repo{
+		Name:          a.repoName(r.Project.Name, r.Name),
+		CloneURL:      r.RemoteURL,
+		Private:       r.Project.Visibility != "public",
+		DefaultBranch: strings.TrimPrefix(r.DefaultBranch, "refs/heads/"),
+	}
+}
+
+// ListRepos returns the organization's repos, for the request form's picker.
+func (a *AzureDevOpsProvider) ListRepos(ctx context.Context) ([]repo, error) {
+	repos, err := a.listRepositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]repo, 0, len(repos))
+	for _, r := range repos {
+		out = append(out, a.toRepo(r))
+	}
+	return out, nil
+}
+
+// FindRepo looks a repo up by name, case-insensitively, in every project. A
+// bare name found in several projects resolves to the default project's, and
+// is otherwise ambiguous.
+func (a *AzureDevOpsProvider) FindRepo(ctx context.Context, name string) (repo, error) {
+	project, short := splitRepoName(name)
+	repos, err := a.listRepositories(ctx)
+	if err != nil {
+		return repo{}, err
+	}
+	var matches []adoRepository
+	for _, r := range repos {
+		if strings.EqualFold(r.Name, short) && (project == "" || strings.EqualFold(r.Project.Name, project)) {
+			matches = append(matches, r)
+		}
+	}
+	if len(matches) > 1 {
+		if i := slices.IndexFunc(matches, func(r adoRepository) bool { return strings.EqualFold(r.Project.Name, a.project) }); i >= 0 {
+			matches = matches[i : i+1]
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return repo{}, fmt.Errorf("repository %q not found", name)
+	case 1:
+		return a.toRepo(matches[0]), nil
+	}
+	var projects []string
+	for _, r := range matches {
+		projects = append(projects, r.Project.Name)
+	}
+	return repo{}, fmt.Errorf("repository %q is in several projects (%s); name it as project/repo", name, strings.Join(projects, ", "))
+}
+
+// OpenPullRequest opens a pull request and returns its web URL, linking the
+// Azure Boards work items mentioned (AB#123) in the title or body. The body is
+// cut to the 4,000 characters Azure DevOps allows.
+func (a *AzureDevOpsProvider) OpenPullRequest(ctx context.Context, name, branch, base, title, body string, draft bool) (string, error) {
+	pr := map[string]any{
+		"sourceRefName": "refs/heads/" + branch,
+		"targetRefName": "refs/heads/" + base,
+		"title":         title,
+		"description":   truncate(body, adoMaxDescription-3),
+		"isDraft":       draft,
+	}
+	var refs []map[string]string
+	for _, id := range workItemIDs(title + "\n" + body) {
+		refs = append(refs, map[string]string{"id": id})
+	}
+	if len(refs) > 0 {
+		pr["workItemRefs"] = refs
+	}
+	payload, err := json.Marshal(pr)
+	if err != nil {
+		return "", fmt.Errorf("marshal PR body: %w", err)
+	}
+
+	respBody, status, err := a.do(ctx, http.MethodPost, a.apiURL(name, "/pullrequests", nil), payload)
+	if err != nil {
+		return "", err
+	}
+	// TF401179: an active pull request for the source and target branch already exists.
+	if status == http.StatusConflict && bytes.Contains(respBody, []byte("TF401179")) {
+		return a.updateOpenPullRequest(ctx, name, branch, pr)
+	}
+	if status != http.StatusCreated {
+		return "", fmt.Errorf("azure devops api status %d: %s", status, respBody)
+	}
+	var created struct {
+		PullRequestID int `json:"pullRequestId"`
+	}
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return "", fmt.Errorf("parse PR response: %w", err)
+	}
+	return a.pullRequestURL(name, created.PullRequestID), nil
+}
+
+// pullRequestURL returns the web URL of the repo's pull request id.
+func (a *AzureDevOpsProvider) pullRequestURL(name string, id int) string {
+	return a.webURL(name) + "/pullrequest/" + strconv.Itoa(id)
+}
+
+// updateOpenPullRequest sets the title and description of the active pull
+// request for branch, which already has the pushed changes, and returns its URL.
+func (a *AzureDevOpsProvider) updateOpenPullRequest(ctx context.Context, name, branch string, pr map[string]any) (string, error) {
+	query := url.Values{"searchCriteria.sourceRefName": {"refs/heads/" + branch}, "searchCriteria.status": {"active"}}
+	respBody, status, err := a.do(ctx, http.MethodGet, a.apiURL(name, "/pullrequests", query), nil)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("azure devops api status %d: %s", status, respBody)
+	}
+	var open struct {
+		Value []struct {
+			PullRequestID int `json:"pullRequestId"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(respBody, &open); err != nil {
+		return "", fmt.Errorf("parse PR list: %w", err)
+	}
+	if len(open.Value) == 0 {
+		return "", fmt.Errorf("azure devops says a PR for %s exists, but none is active", branch)
+	}
+	id := open.Value[0].PullRequestID
+	prURL := a.pullRequestURL(name, id)
+	payload, err := json.Marshal(map[string]any{"title": pr["title"], "description": pr["description"]})
+	if err != nil {
+		return "", fmt.Errorf("marshal PR update: %w", err)
+	}
+	if respBody, status, err = a.do(ctx, http.MethodPatch, a.apiURL(name, "/pullrequests/"+strconv.Itoa(id), nil), payload); err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("update PR %s: azure devops api status %d: %s", prURL, status, respBody)
+	}
+	slog.InfoContext(ctx, "azure devops: updated the active pull request for the branch", "branch", branch, "pr", prURL)
+	return prURL, nil
+}
+
+// workItemIDs returns the IDs of the work items mentioned in s, in order and
+// without duplicates.
+func workItemIDs(s string) []string {
+	var ids []string
+	for _, m := range workItemPattern.FindAllStringSubmatch(s, -1) {
+		if !slices.Contains(ids, m[1]) {
+			ids = append(ids, m[1])
+		}
+	}
+	return ids
+}
+
+// workItemFooter lists the work items a request mentions for the PR body, so
+// Azure Boards links them to the PR, on Azure DevOps or GitHub.
+func workItemFooter(texts ...string) string {
+	ids := workItemIDs(strings.Join(texts, "\n"))
+	if len(ids) == 0 {
+		return ""
+	}
+	for i, id := range ids {
+		ids[i] = "AB#" + id
+	}
+	return "\n\nWork items: " + strings.Join(ids, ", ")
+}
+
+// Ping checks that the token can call the API.
+func (a *AzureDevOpsProvider) Ping(ctx context.Context) error {
+	body, status, err := a.do(ctx, http.MethodGet, fmt.Sprintf("%s/_apis/projects?$top=1&api-version=%s", a.orgURL, adoAPIVersion), nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("azure devops api status %d: %s", status, truncate(string(body), 200))
+	}
+	return nil
+}
+
+// do performs an authenticated Azure DevOps API request and returns the body
+// and status. The PAT goes in as the password of basic auth.
+func (a *AzureDevOpsProvider) do(ctx context.Context, method, apiURL string, payload []byte) ([]byte, int, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, reqBody)
+	if err != nil {
+		return nil, 0, fmt.Errorf("create request: %w", err)
+	}
+	req.SetBasicAuth("", a.token)
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("azure devops api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read response: %w", err)
+	}
+	return body, resp.StatusCode, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// overBudget reports whether the job's LLM cost so far exceeds the per-job
+// budget. Always false when no budget is configured.
+func (o *Orchestrator) overBudget(jobID string) (float64, bool) {
+	if o.maxJobCostUSD <= 0 {
+		return 0, false
+	}
+	cost := o.hub.JobCost(jobID)
+	return cost, cost > o.maxJobCostUSD
+}
+
+// sessionLimits returns the Claude Code limits for a job's next session in
+// repo. Once the job has cost FallbackAfterUSD, its sessions run on the
+// fallback model.
+func (o *Orchestrator) sessionLimits(ctx context.Context, jobID, repo string) SessionLimits {
+	l := o.sessions.forRepo(repo)
+	if l.FallbackModel == "" || l.FallbackAfterUSD <= 0 || l.Model == l.FallbackModel {
+		return l
+	}
+	if cost := o.hub.JobCost(jobID); cost >= l.FallbackAfterUSD {
+		slog.InfoContext(ctx, "orchestrator: job past its fallback threshold, using the fallback model", "job_id", jobID, "cost_usd", cost, "model", l.FallbackModel)
+		l.Model = l.FallbackModel
+	}
+	return l
+}
+
+// abortOverBudget ends a job that exceeded its budget and returns the reply
+// for the thread. The budget is checked between steps, so a job can overshoot
+// by at most one Claude Code run.
+func (o *Orchestrator) abortOverBudget(ctx context.Context, jobID string, cost float64) OrchestratorResult {
+	msg := fmt.Sprintf("job cost $%.2f exceeded the $%.2f budget", cost, o.maxJobCostUSD)
+	slog.WarnContext(ctx, "orchestrator: job over budget", "job_id", jobID, "cost_usd", cost, "budget_usd", o.maxJobCostUSD)
+	o.closeJob(ctx, jobID, JobErrorData{
+		Error:          msg,
+		BudgetExceeded: true,
+		TotalCostUSD:   cost,
+	})
+	return OrchestratorResult{IsJob: true, JobID: jobID,
+		Text: fmt.Sprintf("I stopped this job because it has cost $%.2f so far, over its $%.2f budget.", cost, o.maxJobCostUSD)}
+}
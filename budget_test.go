@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestBudgetEnforcement(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	o := &Orchestrator{hub: hub, maxJobCostUSD: 1.00}
+	hub.SetJobState("job-1", &JobState{Phase: PhasePlanning})
+
+	hub.Emit("job-1", LLMResponseData{CostUSD: 0.60})
+	if _, over := o.overBudget("job-1"); over {
+		t.Fatal("over budget at $0.60")
+	}
+
+	hub.Emit("job-1", LLMResponseData{CostUSD: 0.55})
+	cost, over := o.overBudget("job-1")
+	if !over {
+		t.Fatalf("not over budget at $%.2f", cost)
+	}
+
+	result := o.abortOverBudget(context.Background(), "job-1", cost)
+	if !strings.Contains(result.Text, "$1.15") || !strings.Contains(result.Text, "$1.00") {
+		t.Errorf("Text = %q", result.Text)
+	}
+	state, _ := hub.GetJobState("job-1")
+	if state.Phase != PhaseDone || !state.closed {
+		t.Errorf("job not closed: phase=%q closed=%v", state.Phase, state.closed)
+	}
+
+	t.Run("fallback model", func(t *testing.T) {
+		o := &Orchestrator{hub: hub, sessions: NewSessionConfig(SessionLimits{Model: "opus", FallbackModel: "sonnet", FallbackAfterUSD: 1}, nil)}
+		if got := o.sessionLimits(context.Background(), "job-1", "web").Model; got != "sonnet" {
+			t.Errorf("at $1.15: model = %q, want the fallback", got)
+		}
+		hub.SetJobState("job-2", &JobState{Phase: PhasePlanning})
+		if got := o.sessionLimits(context.Background(), "job-2", "web").Model; got != "opus" {
+			t.Errorf("at $0: model = %q", got)
+		}
+	})
+
+	t.Run("no budget", func(t *testing.T) {
+		unlimited := &Orchestrator{hub: hub}
+		if _, over := unlimited.overBudget("job-1"); over {
+			t.Error("over budget with no budget configured")
+		}
+	})
+}
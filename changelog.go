@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultChangelogFile is where a requested changelog entry goes when the
+// repo's .bob.yml doesn't name a changelog.
+const defaultChangelogFile = "CHANGELOG.md"
+
+// changelogHeader starts a changelog Bob creates.
+const changelogHeader = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/).
+`
+
+// changelogSections are Keep a Changelog's types of changes, in its order.
+var changelogSections = []string{"Added", "Changed", "Deprecated", "Removed", "Fixed", "Security"}
+
+// reConventionalType matches a conventional commit type, e.g. "feat(ui): ".
+var reConventionalType = regexp.MustCompile(`^[a-z]+(\([^)]*\))?!?: `)
+
+// changelogFor returns the changelog an implementation job adds an entry to:
+// the repo's, or CHANGELOG.md if the requester asked for an entry. Empty
+// means none.
+func changelogFor(cfg *RepoConfig, requested bool) string {
+	switch {
+	case cfg != nil && cfg.Changelog != "":
+		return cfg.Changelog
+	case requested:
+		return defaultChangelogFile
+	}
+	return ""
+}
+
+// withChangelog returns the config with file added to its paths, so a job
+// limited to part of the repo keeps its changelog entry.
+func (c *RepoConfig) withChangelog(file string) *RepoConfig {
+	if c == nil || file == "" || c.inScope(file) {
+		return c
+	}
+	scoped := *c
+	scoped.Paths = append(slices.Clone(c.Paths), file)
+	return &scoped
+}
+
+// changelogPreamble asks the implementation session for the changelog entry.
+func changelogPreamble(file string) string {
+	if file == "" {
+		return ""
+	}
+	return fmt.Sprintf("## Changelog\n\nAdd one entry for this change to `%s`, under `## [Unreleased]` and the section that fits (Added, Changed, Deprecated, Removed, Fixed or Security), following Keep a Changelog and the file's existing style. Create the file, heading or section if missing. Write it for the project's users, not as a commit message.\n\n", file)
+}
+
+// ensureChangelogEntry adds an entry for task to the changelog if the session
+// changed other files but not the changelog. Failures are logged, not fatal:
+// the change itself is done.
+func (o *Orchestrator) ensureChangelogEntry(ctx context.Context, jobID, repoDir, file, task string) {
+	if file == "" {
+		return
+	}
+	files, err := changedFiles(ctx, repoDir)
+	if err != nil {
+		slog.WarnContext(ctx, "orchestrator: failed to check for a changelog entry", "job_id", jobID, "err", err)
+		return
+	}
+	if len(files) == 0 || slices.Contains(files, file) {
+		return
+	}
+	_, err = o.runStep(ctx, jobID, "update_changelog", file, func(ctx context.Context) (string, error) {
+		path := filepath.Join(repoDir, file)
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		section, entry := changelogSection(task), changelogEntry(task)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", err
+		}
+		return section + ": " + entry, os.WriteFile(path, []byte(addChangelogEntry(string(data), section, entry)), 0o644)
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "orchestrator: failed to add a changelog entry", "job_id", jobID, "file", file, "err", err)
+	}
+}
+
+// changelogSection picks the Keep a Changelog section for task's entry from
+// its first word or conventional commit type; Changed if nothing fits.
+func changelogSection(task string) string {
+	subject, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(task)), "\n")
+	if strings.Contains(subject, "vulnerab") || strings.Contains(subject, "cve-") {
+		return "Security"
+	}
+	words := strings.FieldsFunc(subject, func(r rune) bool { return !unicode.IsLetter(r) })
+	if len(words) == 0 {
+		return "Changed"
+	}
+	switch words[0] {
+	case "add", "adds", "implement", "introduce", "support", "create", "feat":
+		return "Added"
+	case "deprecate":
+		return "Deprecated"
+	case "remove", "delete", "drop":
+		return "Removed"
+	case "fix", "fixes", "resolve", "correct", "bug":
+		return "Fixed"
+	case "security":
+		return "Security"
+	}
+	return "Changed"
+}
+
+// changelogEntry is task's first line as a changelog entry, without a
+// conventional commit prefix.
+func changelogEntry(task string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(task), "\n")
+	subject = strings.TrimRight(truncateWords(strings.TrimSpace(reConventionalType.ReplaceAllString(subject, "")), maxCommitSubject), ".")
+	r, size := utf8.DecodeRuneInString(subject)
+	return string(unicode.ToUpper(r)) + subject[size:]
+}
+
+// addChangelogEntry adds entry under section in the Unreleased part of a Keep
+// a Changelog file, adding the header, heading and section as needed. Entries
+// use the file's list marker.
+func addChangelogEntry(content, section, entry string) string {
+	if strings.TrimSpace(content) == "" {
+		content = changelogHeader
+	}
+	bullet := "- "
+	if strings.Contains(content, "\n* ") && !strings.Contains(content, "\n- ") {
+		bullet = "* "
+	}
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	isRelease := func(l string) bool { return strings.HasPrefix(l, "## ") }
+
+	unreleased := slices.IndexFunc(lines, func(l string) bool {
+		return isRelease(l) && strings.EqualFold(strings.Trim(strings.TrimSpace(l[3:]), "[]"), "unreleased")
+	})
+	if unreleased < 0 {
+		if first := slices.IndexFunc(lines, isRelease); first >= 0 {
+			lines = slices.Insert(lines, first, "## [Unreleased]", "")
+			unreleased = first
+		} else {
+			lines = append(lines, "", "## [Unreleased]")
+			unreleased = len(lines) - 1
+		}
+	}
+	end := len(lines)
+	if next := slices.IndexFunc(lines[unreleased+1:], isRelease); next >= 0 {
+		end = unreleased + 1 + next
+	}
+
+	if sec := slices.Index(lines[unreleased+1:end], "### "+section); sec >= 0 {
+		// After the section's last line.
+		sec += unreleased + 1
+		last := sec
+		for i := sec + 1; i < end && !strings.HasPrefix(lines[i], "### "); i++ {
+			if strings.TrimSpace(lines[i]) != "" {
+				last = i
+			}
+		}
+		lines = slices.Insert(lines, last+1, bullet+entry)
+		return strings.Join(lines, "\n") + "\n"
+	}
+
+	// A new section, before the first one that comes after it in Keep a
+	// Changelog's order, or at the end of Unreleased.
+	rank := slices.Index(changelogSections, section)
+	for i := unreleased + 1; i < end; i++ {
+		name, ok := strings.CutPrefix(lines[i], "### ")
+		if r := slices.Index(changelogSections, strings.TrimSpace(name)); ok && r > rank {
+			lines = slices.Insert(lines, i, "### "+section, "", bullet+entry, "")
+			return strings.Join(lines, "\n") + "\n"
+		}
+	}
+	at := end
+	for at > unreleased+1 && strings.TrimSpace(lines[at-1]) == "" {
+		at--
+	}
+	add := []string{"", "### " + section, "", bullet + entry}
+	if at < len(lines) && strings.TrimSpace(lines[at]) != "" {
+		add = append(add, "")
+	}
+	lines = slices.Insert(lines, at, add...)
+	return strings.Join(lines, "\n") + "\n"
+}
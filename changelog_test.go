@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAddChangelogEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		section string
+		want    string
+	}{
+		{
+			name:    "new file",
+			section: "Added",
+			want:    changelogHeader + "\n## [Unreleased]\n\n### Added\n\n- Dark mode\n",
+		},
+		{
+			name:    "existing section",
+			content: "# Changelog\n\n## [Unreleased]\n\n### Added\n\n- Search\n\n### Fixed\n\n- Login\n\n## [1.0.0] - 2026-01-01\n\n### Added\n\n- Everything\n",
+			section: "Added",
+			want:    "# Changelog\n\n## [Unreleased]\n\n### Added\n\n- Search\n- Dark mode\n\n### Fixed\n\n- Login\n\n## [1.0.0] - 2026-01-01\n\n### Added\n\n- Everything\n",
+		},
+		{
+			name:    "new section in order",
+			content: "# Changelog\n\n## [Unreleased]\n\n### Added\n\n- Search\n\n### Fixed\n\n- Login\n",
+			section: "Changed",
+			want:    "# Changelog\n\n## [Unreleased]\n\n### Added\n\n- Search\n\n### Changed\n\n- Dark mode\n\n### Fixed\n\n- Login\n",
+		},
+		{
+			name:    "new section last",
+			content: "# Changelog\n\n## [Unreleased]\n\n### Added\n\n- Search\n\n## [1.0.0]\n",
+			section: "Security",
+			want:    "# Changelog\n\n## [Unreleased]\n\n### Added\n\n- Search\n\n### Security\n\n- Dark mode\n\n## [1.0.0]\n",
+		},
+		{
+			name:    "no unreleased heading",
+			content: "# Changelog\n\n## [1.0.0] - 2026-01-01\n\n* Everything\n",
+			section: "Fixed",
+			want:    "# Changelog\n\n## [Unreleased]\n\n### Fixed\n\n* Dark mode\n\n## [1.0.0] - 2026-01-01\n\n* Everything\n",
+		},
+		{
+			name:    "empty unreleased",
+			content: "# Changelog\n\n## Unreleased\n",
+			section: "Removed",
+			want:    "# Changelog\n\n## Unreleased\n\n### Removed\n\n- Dark mode\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addChangelogEntry(tt.content, tt.section, "Dark mode"); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangelogSection(t *testing.T) {
+	tests := map[string]string{
+		"Add dark mode to settings":      "Added",
+		"feat(ui): dark mode":            "Added",
+		"fix: login loops on Safari":     "Fixed",
+		"Remove the legacy export":       "Removed",
+		"Deprecate the v1 API":           "Deprecated",
+		"Bump lodash for CVE-2026-1234":  "Security",
+		"Rename the billing module":      "Changed",
+		"":                               "Changed",
+		"Fix the flaky test\nadd a note": "Fixed",
+	}
+	for task, want := range tests {
+		if got := changelogSection(task); got != want {
+			t.Errorf("changelogSection(%q) = %q, want %q", task, got, want)
+		}
+	}
+}
+
+func TestChangelogEntry(t *testing.T) {
+	tests := map[string]string{
+		"add dark mode to settings.": "Add dark mode to settings",
+		"feat(ui): dark mode":        "Dark mode",
+		"Fix login\n\nIt loops.":     "Fix login",
+		"Note: the API changed":      "Note: the API changed",
+	}
+	for task, want := range tests {
+		if got := changelogEntry(task); got != want {
+			t.Errorf("changelogEntry(%q) = %q, want %q", task, got, want)
+		}
+	}
+}
+
+func TestChangelogFor(t *testing.T) {
+	if got := changelogFor(nil, false); got != "" {
+		t.Errorf("no config, not requested: %q, want none", got)
+	}
+	if got := changelogFor(nil, true); got != defaultChangelogFile {
+		t.Errorf("requested: %q, want %q", got, defaultChangelogFile)
+	}
+	cfg := &RepoConfig{Changelog: "docs/CHANGES.md", Paths: []string{"web"}}
+	if got := changelogFor(cfg, false); got != "docs/CHANGES.md" {
+		t.Errorf("configured: %q, want docs/CHANGES.md", got)
+	}
+
+	scoped := cfg.withChangelog("docs/CHANGES.md")
+	if !scoped.inScope("docs/CHANGES.md") || !scoped.inScope("web/app.js") || scoped.inScope("api/main.go") {
+		t.Errorf("scoped paths = %v, want web and the changelog", scoped.Paths)
+	}
+	if !slices.Equal(cfg.Paths, []string{"web"}) {
+		t.Errorf("withChangelog changed the original paths: %v", cfg.Paths)
+	}
+	if unscoped := (&RepoConfig{}).withChangelog("CHANGELOG.md"); len(unscoped.Paths) != 0 {
+		t.Errorf("unscoped config got paths %v", unscoped.Paths)
+	}
+}
+
+func TestEnsureChangelogEntry(t *testing.T) {
+	drainHub(t)
+	ctx := context.Background()
+	o := &Orchestrator{hub: NewHub(t.TempDir())}
+
+	t.Run("adds an entry the session didn't", func(t *testing.T) {
+		dir := gitRepo(t, map[string]string{"main.go": "package main\n", "CHANGELOG.md": "# Changelog\n\n## [Unreleased]\n"})
+		os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+		o.ensureChangelogEntry(ctx, "job-1", dir, "CHANGELOG.md", "fix the empty main")
+		data, _ := os.ReadFile(filepath.Join(dir, "CHANGELOG.md"))
+		if !strings.Contains(string(data), "### Fixed\n\n- Fix the empty main\n") {
+			t.Errorf("changelog = %q, want a Fixed entry", data)
+		}
+	})
+
+	t.Run("keeps the session's entry", func(t *testing.T) {
+		dir := gitRepo(t, map[string]string{"main.go": "package main\n", "CHANGELOG.md": "# Changelog\n"})
+		os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+		own := "# Changelog\n\n## [Unreleased]\n\n### Changed\n\n- main does nothing, faster\n"
+		os.WriteFile(filepath.Join(dir, "CHANGELOG.md"), []byte(own), 0o644)
+		o.ensureChangelogEntry(ctx, "job-2", dir, "CHANGELOG.md", "fix the empty main")
+		if data, _ := os.ReadFile(filepath.Join(dir, "CHANGELOG.md")); string(data) != own {
+			t.Errorf("changelog = %q, want the session's own", data)
+		}
+	})
+
+	t.Run("no changes, no entry", func(t *testing.T) {
+		dir := gitRepo(t, map[string]string{"main.go": "package main\n"})
+		o.ensureChangelogEntry(ctx, "job-3", dir, "docs/CHANGELOG.md", "fix the empty main")
+		if _, err := os.Stat(filepath.Join(dir, "docs/CHANGELOG.md")); !os.IsNotExist(err) {
+			t.Errorf("changelog created without other changes (err %v)", err)
+		}
+	})
+}
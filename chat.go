@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// ChatPlatform is a chat integration Bob takes requests from and reports to.
+// Slack and Discord implement it; platform-specific interactive features (Block
+// Kit buttons, reactions, message edits) stay behind capability checks.
+type ChatPlatform interface {
+	Notifier
+	// Name identifies the platform on jobs and in contexts (e.g. "slack").
+	Name() string
+	// ThreadMessages returns the conversation in a thread, oldest first, with
+	// Bob's own messages as RoleAssistant and mentions stripped.
+	ThreadMessages(ctx context.Context, channel, thread string) ([]Message, error)
+	// StripMention removes bot mentions from message text.
+	StripMention(text string) string
+	// MentionUser formats a mention of the given user ID.
+	MentionUser(userID string) string
+}
+
+// WithPlatform returns a context carrying the name of the chat platform the
+// request came from, so notifications are routed back to it.
+func WithPlatform(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, ctxKeyPlatform, name)
+}
+
+// PlatformFromCtx extracts the chat platform name from the context.
+func PlatformFromCtx(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKeyPlatform).(string)
+	return v
+}
+
+// chatRouter is a Notifier that delivers to the platform named in the context,
+// falling back to the first registered platform.
+type chatRouter struct {
+	platforms []ChatPlatform
+}
+
+func newChatRouter(platforms ...ChatPlatform) *chatRouter {
+	return &chatRouter{platforms: platforms}
+}
+
+// forPlatform returns the named platform, or the default one.
+func (r *chatRouter) forPlatform(name string) ChatPlatform {
+	for _, p := range r.platforms {
+		if p.Name() == name {
+			return p
+		}
+	}
+	if len(r.platforms) == 0 {
+		return nil
+	}
+	return r.platforms[0]
+}
+
+// mentionUser formats a mention of userID on the platform named in ctx.
+func (r *chatRouter) mentionUser(ctx context.Context, userID string) string {
+	p := r.forPlatform(PlatformFromCtx(ctx))
+	if p == nil {
+		return userID
+	}
+	return p.MentionUser(userID)
+}
+
+func (r *chatRouter) Notify(ctx context.Context, text string) error {
+	p := r.forPlatform(PlatformFromCtx(ctx))
+	if p == nil {
+		return fmt.Errorf("notify: no chat platform configured")
+	}
+	return p.Notify(ctx, redactSecrets(text))
+}
+
+// chatMention is a platform-neutral mention of Bob.
+type chatMention struct {
+	Channel   string // where replies go (for Discord, the thread's channel ID)
+	Thread    string // thread identifier; together with Channel keys the job
+	User      string // platform user ID of the author
+	UserName  string // the author's display name, if the platform gives it
+	Text      string // raw message text, mentions included
+	NewThread bool   // the mention started the thread; there is no history to read
+
+	// DefaultRepo is used when the request doesn't name a repo; when empty the
+	// channel's configured default applies.
+	DefaultRepo string
+}
+
+// handleChatMention is the plain-text mention flow for platforms without
+// Slack's interactive features: approval and cancellation are text commands
+// and plans are posted as messages.
+func handleChatMention(p ChatPlatform, orch *Orchestrator, hub *Hub, approver *Approver, limits *userLimits, bobURL, apiToken string, m chatMention) {
+	ctx := WithSlackThread(context.Background(), m.Channel, m.Thread)
+	ctx = WithPlatform(ctx, p.Name())
+	ctx = WithUser(ctx, m.User)
+	ctx = WithUserName(ctx, m.UserName)
+	ctx = WithHub(ctx, hub)
+
+	user := p.MentionUser(m.User)
+	reply := func(text string) {
+		if err := p.Notify(ctx, text); err != nil {
+			slog.ErrorContext(ctx, p.Name()+": failed to post message", "err", err)
+		}
+	}
+	userText := p.StripMention(m.Text)
+	defer recoverGoroutine(p.Name()+" mention", func() { reply(fmt.Sprintf("%s %s", user, panicReply)) })
+
+	// Cancellation must not wait for the thread lock (see handleMention).
+	if isCancelText(userText) {
+		if jobID := hub.ActiveJobForThread(m.Channel, m.Thread); jobID != "" {
+			if err := orch.CancelJob(ctx, jobID, user); err != nil {
+				reply(fmt.Sprintf("%s There's nothing to cancel — the job has already finished.", user))
+				return
+			}
+			reply(fmt.Sprintf("%s Cancelled.", user))
+			return
+		}
+	}
+
+	if filter, ok := helpQuery(userText); ok {
+		channelRepo := m.DefaultRepo
+		if channelRepo == "" {
+			channelRepo = hub.GetChannelRepo(m.Channel)
+		}
+		reply(fmt.Sprintf("%s %s", user, orch.HelpMessage(filter, channelRepo)))
+		return
+	}
+
+	hub.LockThread(m.Channel, m.Thread)
+	defer hub.UnlockThread(m.Channel, m.Thread)
+
+	var result OrchestratorResult
+	var err error
+
+	if activeJobID := hub.ActiveJobForThread(m.Channel, m.Thread); activeJobID != "" {
+		state, hasState := hub.GetJobState(activeJobID)
+		if hasState && state.Phase == PhaseAwaitingPush && isPushText(userText) {
+			approver.Push(ctx, activeJobID, m.Channel, m.Thread, user)
+			return
+		}
+		if hasState && state.Phase == PhaseAwaitingApproval && isApprovalText(userText) {
+			approver.Approve(ctx, activeJobID, m.Channel, m.Thread, user)
+			return
+		}
+		if msg := limits.quotaExceeded(p.Name(), m.User, false); msg != "" {
+			reply(fmt.Sprintf("%s %s", user, msg))
+			return
+		}
+		reply(workingMessage("Working on it...", bobURL, activeJobID, apiToken))
+		result, err = orch.HandleReply(ctx, activeJobID, userText)
+	} else if lastJobID := hub.LastThreadJob(m.Channel, m.Thread); lastJobID != "" && isRetryText(userText) {
+		if msg := limits.quotaExceeded(p.Name(), m.User, true); msg != "" {
+			reply(fmt.Sprintf("%s %s", user, msg))
+			return
+		}
+		result, err = approver.RerunThreadJob(ctx, lastJobID, user, func(jobID string) {
+			limits.startJob(p.Name(), m.User, jobID)
+			reply(workingMessage("Retrying...", bobURL, jobID, apiToken))
+		})
+	} else {
+		if msg := limits.quotaExceeded(p.Name(), m.User, true); msg != "" {
+			reply(fmt.Sprintf("%s %s", user, msg))
+			return
+		}
+		messages := []Message{{Role: RoleUser, Content: userText}}
+		if !m.NewThread {
+			if history, herr := p.ThreadMessages(ctx, m.Channel, m.Thread); herr != nil {
+				slog.WarnContext(ctx, p.Name()+": failed to get thread messages", "err", herr)
+			} else if len(history) > 0 {
+				messages = history
+			}
+		}
+		defaultRepo := m.DefaultRepo
+		if defaultRepo == "" {
+			defaultRepo = hub.GetChannelRepo(m.Channel)
+		}
+		result, err = orch.HandleNewRequest(ctx, messages, defaultRepo, func(jobID string) {
+			limits.startJob(p.Name(), m.User, jobID)
+			reply(workingMessage("Working on a plan...", bobURL, jobID, apiToken))
+		})
+	}
+
+	switch {
+	case err != nil:
+		slog.ErrorContext(ctx, "orchestrator error", "job_id", result.JobID, "err", err)
+		reply(fmt.Sprintf("%s %s", user, errorReply(err)))
+	case result.Reported:
+	case result.PlanText != "" && result.PlanChanges != "":
+		reply(fmt.Sprintf("%s Here's my updated plan. What changed:\n\n%s\n\n%s\n\nMention me with \"go\" to approve, or tell me what to change.", user, result.PlanChanges, planMarkdown(hub, result)))
+	case result.PlanText != "":
+		reply(fmt.Sprintf("%s Here's my plan:\n\n%s\n\nMention me with \"go\" to approve, or tell me what to change.", user, planMarkdown(hub, result)))
+	case result.IsJob && result.PRURL != "" && result.Text == "":
+		reply(fmt.Sprintf("%s Done! %s", requesterMention(p, hub, result.JobID, user), result.PRURL))
+	case result.Text != "":
+		reply(fmt.Sprintf("%s %s", user, result.Text))
+	default:
+		reply(fmt.Sprintf("%s Done!", requesterMention(p, hub, result.JobID, user)))
+	}
+}
+
+// requesterMention mentions the user who requested the job, who may not be the
+// one who last spoke in the thread, falling back to user.
+func requesterMention(p ChatPlatform, hub *Hub, jobID, user string) string {
+	if requester := hub.JobRequester(jobID); requester != "" {
+		return p.MentionUser(requester)
+	}
+	return user
+}
+
+// planMarkdown returns the job's plan as Markdown. PlanText is formatted for
+// Slack, so the raw plan content is preferred.
+func planMarkdown(hub *Hub, result OrchestratorResult) string {
+	if state, ok := hub.GetJobState(result.JobID); ok {
+		state.mu.Lock()
+		plan := state.PlanContent
+		state.mu.Unlock()
+		if plan != "" {
+			return plan
+		}
+	}
+	return result.PlanText
+}
+
+// errorReply is the chat reply when a request fails before a job reports its
+// own outcome.
+func errorReply(err error) string {
+	if errors.Is(err, errLLMUnavailable) {
+		return "Sorry, the model I use is overloaded or rate limited right now. Please try again in a few minutes."
+	}
+	return "Sorry, I hit an error trying to respond. Please try again."
+}
+
+// workingMessage appends the job link to a status message when BOB_URL is set.
+// The API token is only embedded when given.
+func workingMessage(msg, bobURL, jobID, apiToken string) string {
+	if bobURL == "" {
+		return msg
+	}
+	if apiToken == "" {
+		return fmt.Sprintf("%s Follow my progress here: %s/jobs/%s", msg, bobURL, jobID)
+	}
+	return fmt.Sprintf("%s Follow my progress here: %s/jobs/%s?token=%s", msg, bobURL, jobID, apiToken)
+}
+
+// splitMessage splits text into chunks of at most max bytes, preferring line
+// breaks, for platforms with a message size limit. A code block cut in two is
+// closed at the end of one chunk and reopened at the start of the next.
+func splitMessage(text string, max int) []string {
+	const fence = "```"
+	limit := max
+	if strings.Contains(text, fence) && max > 4*len(fence) {
+		limit = max - len("\n"+fence) // room to close a block
+	}
+	var chunks []string
+	for len(text) > max {
+		cut := strings.LastIndex(text[:limit], "\n")
+		if cut <= len(fence) {
+			cut = len(prefix(text, limit))
+		}
+		chunk := text[:cut]
+		text = strings.TrimPrefix(text[cut:], "\n")
+		if strings.Count(chunk, fence)%2 == 1 {
+			chunk += "\n" + fence
+			text = fence + "\n" + text
+		}
+		chunks = append(chunks, chunk)
+	}
+	return append(chunks, text)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type fakePlatform struct {
+	name string
+	sent []string
+}
+
+func (f *fakePlatform) Name() string { return f.name }
+func (f *fakePlatform) Notify(_ context.Context, text string) error {
+	f.sent = append(f.sent, text)
+	return nil
+}
+func (f *fakePlatform) ThreadMessages(context.Context, string, string) ([]Message, error) {
+	return nil, nil
+}
+func (f *fakePlatform) StripMention(text string) string  { return text }
+func (f *fakePlatform) MentionUser(userID string) string { return "@" + userID }
+
+func TestChatRouter_RoutesByPlatform(t *testing.T) {
+	slackP := &fakePlatform{name: "slack"}
+	discordP := &fakePlatform{name: "discord"}
+	r := newChatRouter(slackP, discordP)
+
+	tests := []struct {
+		name     string
+		platform string
+		want     *fakePlatform
+	}{
+		{"named platform", "discord", discordP},
+		{"default when unset", "", slackP},
+		{"default when unknown", "teams", slackP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slackP.sent, discordP.sent = nil, nil
+			ctx := context.Background()
+			if tt.platform != "" {
+				ctx = WithPlatform(ctx, tt.platform)
+			}
+			if err := r.Notify(ctx, "hi"); err != nil {
+				t.Fatalf("Notify: %v", err)
+			}
+			if len(tt.want.sent) != 1 {
+				t.Errorf("expected message on %s, got slack=%v discord=%v", tt.want.name, slackP.sent, discordP.sent)
+			}
+		})
+	}
+}
+
+func TestChatRouter_NoPlatforms(t *testing.T) {
+	if err := newChatRouter().Notify(context.Background(), "hi"); err == nil {
+		t.Error("expected error with no platforms configured")
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		max  int
+		want []string
+	}{
+		{"fits", "hello", 10, []string{"hello"}},
+		{"splits at newline", "aaaa\nbbbb\ncc", 10, []string{"aaaa\nbbbb", "cc"}},
+		{"hard split", "abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"rune boundary", "ééé", 3, []string{"é", "é", "é"}},
+		{"code block", "```\naaaa\nbbbb\ncccc\n```", 20, []string{"```\naaaa\nbbbb\n```", "```\ncccc\n```"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitMessage(tt.text, tt.max)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("splitMessage(%q, %d) = %q, want %q", tt.text, tt.max, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Check watching after Bob pushes to a PR: poll every checkPollInterval until
+// every check run completes, giving up after checkWatchTimeout, or after
+// checkStartGrace if the repo reports no checks at all.
+var (
+	checkPollInterval = 30 * time.Second
+	checkStartGrace   = 5 * time.Minute
+	checkWatchTimeout = time.Hour
+)
+
+// CheckRun is one CI check on a commit.
+type CheckRun struct {
+	Name       string
+	Status     string // queued, in_progress or completed
+	Conclusion string // set once completed: success, failure, neutral, skipped, ...
+	URL        string
+	Output     string // the check's reported title and summary
+}
+
+// failed reports whether a completed check counts as a failure.
+func (c CheckRun) failed() bool {
+	switch c.Conclusion {
+	case "failure", "timed_out", "cancelled", "action_required", "startup_failure":
+		return true
+	}
+	return false
+}
+
+// checkRunLister is implemented by providers that report CI check runs.
+type checkRunLister interface {
+	ListCheckRuns(ctx context.Context, name, ref string) ([]CheckRun, error)
+}
+
+// ListCheckRuns returns the latest check runs for ref (a branch or commit).
+func (g *GitHubProvider) ListCheckRuns(ctx context.Context, name, ref string) ([]CheckRun, error) {
+	body, err := g.get(ctx, fmt.Sprintf("%s/repos/%s/commits/%s/check-runs?per_page=100", g.apiURL, g.repoPath(name), ref), "application/vnd.github+json")
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		CheckRuns []struct {
+			Name       string `json:"name"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+			HTMLURL    string `json:"html_url"`
+			Output     struct {
+				Title   string `json:"title"`
+				Summary string `json:"summary"`
+			} `json:"output"`
+		} `json:"check_runs"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+	runs := make([]CheckRun, len(resp.CheckRuns))
+	for i, c := range resp.CheckRuns {
+		runs[i] = CheckRun{
+			Name: c.Name, Status: c.Status, Conclusion: c.Conclusion, URL: c.HTMLURL,
+			Output: strings.TrimSpace(c.Output.Title + "\n\n" + c.Output.Summary),
+		}
+	}
+	return runs, nil
+}
+
+// waitForChecks polls until every check run on ref has completed. It returns
+// nil runs if none show up within checkStartGrace.
+func waitForChecks(ctx context.Context, lister checkRunLister, name, ref string) ([]CheckRun, error) {
+	start := time.Now()
+	for {
+		runs, err := lister.ListCheckRuns(ctx, name, ref)
+		if err != nil {
+			return nil, err
+		}
+		pending := len(runs) == 0
+		for _, c := range runs {
+			if c.Status != "completed" {
+				pending = true
+			}
+		}
+		switch {
+		case !pending:
+			return runs, nil
+		case len(runs) == 0 && time.Since(start) > checkStartGrace:
+			return nil, nil
+		case time.Since(start) > checkWatchTimeout:
+			return nil, fmt.Errorf("checks still running after %s", checkWatchTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(checkPollInterval):
+		}
+	}
+}
+
+// checksResult summarizes completed check runs as a checks_completed payload.
+func checksResult(runs []CheckRun) ChecksCompletedData {
+	d := ChecksCompletedData{Conclusion: "success"}
+	for _, c := range runs {
+		d.Checks = append(d.Checks, CheckResult{Name: c.Name, Conclusion: c.Conclusion, URL: c.URL})
+		if c.failed() {
+			d.Conclusion = "failure"
+		}
+	}
+	return d
+}
+
+// formatChecksMessage is the thread message for a PR's finished checks.
+func formatChecksMessage(prURL string, runs []CheckRun) string {
+	var failed []string
+	for _, c := range runs {
+		if c.failed() {
+			failed = append(failed, fmt.Sprintf("• %s (%s) %s", c.Name, strings.ReplaceAll(c.Conclusion, "_", " "), c.URL))
+		}
+	}
+	if len(failed) == 0 {
+		return fmt.Sprintf("All %d checks passed on %s.", len(runs), prURL)
+	}
+	return fmt.Sprintf("%d of %d checks failed on %s:\n%s", len(failed), len(runs), prURL, strings.Join(failed, "\n"))
+}
+
+// checkFixPrompt describes the failing checks for a fix session.
+func checkFixPrompt(runs []CheckRun) string {
+	var b strings.Builder
+	b.WriteString("## Failing checks\n")
+	for _, c := range runs {
+		if !c.failed() {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s (%s)\n\n%s\n", c.Name, c.Conclusion, c.URL)
+		if c.Output != "" {
+			fmt.Fprintf(&b, "\n```\n%s\n```\n", truncate(redactSecrets(c.Output), 4000))
+		}
+	}
+	return b.String()
+}
+
+// WatchChecks waits for the CI checks on a PR Bob just pushed to and reports
+// them in the job's timeline and chat thread. With autoFix, failing checks
+// start an unattended fix job on the PR's branch, whose own checks are then
+// watched without another fix attempt. ctx carries the job's chat thread.
+func (a *Approver) WatchChecks(ctx context.Context, jobID string, pr threadPR, autoFix bool) {
+	defer recoverGoroutine("checks: job "+jobID, nil)
+	vcs, r, err := findRepo(ctx, a.orchestrator.providers, pr.Repo)
+	if err != nil {
+		slog.ErrorContext(ctx, "checks: failed to find repo", "job_id", jobID, "repo", pr.Repo, "err", err)
+		return
+	}
+	lister, ok := vcs.(checkRunLister)
+	if !ok {
+		return
+	}
+	runs, err := waitForChecks(ctx, lister, pr.Repo, pr.Branch)
+	if err != nil {
+		slog.WarnContext(ctx, "checks: stopped waiting for checks", "job_id", jobID, "err", err)
+		return
+	}
+	if runs == nil {
+		slog.InfoContext(ctx, "checks: no check runs", "job_id", jobID, "pr", pr.URL)
+		return
+	}
+
+	result := checksResult(runs)
+	a.hub.Emit(jobID, result)
+	text := formatChecksMessage(pr.URL, runs)
+	if result.Conclusion == "failure" && autoFix {
+		text += "\n\nI'll try to fix them."
+	}
+	if err := a.notifier.Notify(ctx, text); err != nil {
+		slog.ErrorContext(ctx, "checks: failed to post result", "job_id", jobID, "err", err)
+	}
+	if result.Conclusion != "failure" || !autoFix {
+		return
+	}
+
+	channel, _ := ctx.Value(ctxKeyChannel).(string)
+	threadTS, _ := ctx.Value(ctxKeyThreadTS).(string)
+	fix, err := a.orchestrator.runPRFollowUp(ctx, vcs, r, prFollowUp{
+		Repo:         pr.Repo,
+		Branch:       pr.Branch,
+		PRURL:        pr.URL,
+		Task:         "Fix failing checks on " + pr.URL,
+		Input:        text,
+		Prompt:       checkFixPrompt(runs),
+		SystemPrompt: checkFixSystemPrompt,
+		Commit:       "Fix failing checks",
+		Channel:      channel,
+		ThreadTS:     threadTS,
+		Platform:     PlatformFromCtx(ctx),
+	}, nil)
+	if err != nil {
+		slog.ErrorContext(ctx, "checks: fix failed", "job_id", jobID, "err", err)
+		return
+	}
+	if err := a.notifier.Notify(ctx, fix.Text); err != nil {
+		slog.ErrorContext(ctx, "checks: failed to post fix result", "job_id", jobID, "err", err)
+	}
+	if strings.HasPrefix(fix.Text, followUpPushed) {
+		a.WatchChecks(ctx, fix.JobID, pr, false)
+	}
+}
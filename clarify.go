@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Clarification is a question Claude Code asked while planning and the
+// user's answer, empty until they reply.
+type Clarification struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer,omitempty"`
+}
+
+// recordQuestion remembers a question the planning session asked.
+func (s *JobState) recordQuestion(question string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Clarifications = append(s.Clarifications, Clarification{Question: question})
+}
+
+// recordAnswer fills in the answer to the last open question.
+func (s *JobState) recordAnswer(answer string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := len(s.Clarifications); n > 0 && s.Clarifications[n-1].Answer == "" {
+		s.Clarifications[n-1].Answer = answer
+		return
+	}
+	s.Clarifications = append(s.Clarifications, Clarification{Answer: answer})
+}
+
+// resumePlanPrompt returns the prompt for a new planning session that picks
+// up where one that can't be resumed left off: the first prompt followed by
+// the questions asked and answered since. Empty if the first prompt wasn't
+// recorded.
+func resumePlanPrompt(planPrompt string, clarifications []Clarification) string {
+	if planPrompt == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(planPrompt)
+	b.WriteString("\n\n## Clarifications\n\nYou already asked the user these questions about the task. Use the answers; don't ask them again.\n")
+	for _, c := range clarifications {
+		question := c.Question
+		if question == "" {
+			question = "(not recorded)"
+		}
+		fmt.Fprintf(&b, "\n**Question:** %s\n**Answer:** %s\n", question, c.Answer)
+	}
+	return b.String()
+}
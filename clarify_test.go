@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResumePlanPrompt(t *testing.T) {
+	state := &JobState{}
+	state.recordQuestion("Which environment?")
+	state.recordAnswer("staging")
+	state.recordQuestion("Keep the old flag?")
+	state.recordAnswer("no")
+	state.recordAnswer("also update the docs") // no open question
+
+	got := resumePlanPrompt("## Task\n\nadd a flag", state.Clarifications)
+	for _, want := range []string{
+		"## Task\n\nadd a flag\n\n## Clarifications",
+		"**Question:** Which environment?\n**Answer:** staging\n",
+		"**Question:** Keep the old flag?\n**Answer:** no\n",
+		"**Question:** (not recorded)\n**Answer:** also update the docs\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("prompt missing %q:\n%s", want, got)
+		}
+	}
+	if resumePlanPrompt("", state.Clarifications) != "" {
+		t.Error("prompt without a recorded first prompt")
+	}
+}
+
+func TestHandleReply_AnswerRestartsLostSession(t *testing.T) {
+	// A fake claude CLI that has lost every session and plans from whatever
+	// prompt it's given.
+	bin := t.TempDir()
+	script := `#!/bin/sh
+for a in "$@"; do
+  if [ "$a" = "--resume" ]; then
+    echo "No conversation found with session ID: $2" >&2
+    exit 1
+  fi
+done
+printf '%s' "$2" > "$PROMPT_FILE"
+echo '{"type":"system","subtype":"init","session_id":"new-session"}'
+echo '{"type":"result","subtype":"success","result":"Deploy to staging."}'
+`
+	if err := os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	promptFile := filepath.Join(t.TempDir(), "prompt")
+	t.Setenv("PROMPT_FILE", promptFile)
+
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	o := &Orchestrator{hub: hub}
+	state := &JobState{
+		Repo: "web", Task: "deploy it", Phase: PhaseAwaitingQuestion, SessionID: "lost-session",
+		RepoDir: t.TempDir(), PlanPrompt: "## Task\n\ndeploy it",
+		Clarifications: []Clarification{{Question: "Which environment?"}},
+		repoConfig:     &RepoConfig{},
+	}
+	hub.SetJobState("job-1", state)
+
+	result, err := o.HandleReply(context.Background(), "job-1", "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.PlanBlocks) == 0 || state.Phase != PhaseAwaitingApproval || state.SessionID != "new-session" {
+		t.Fatalf("result = %+v, phase = %s, session = %s", result, state.Phase, state.SessionID)
+	}
+	prompt, err := os.ReadFile(promptFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(prompt), planSystemPrompt) || !strings.Contains(string(prompt), "**Question:** Which environment?\n**Answer:** staging") {
+		t.Errorf("new session's prompt:\n%s", prompt)
+	}
+}
@@ -4,10 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -22,8 +27,6 @@ Requirements for the plan:
 - Note any existing patterns or conventions the implementation should follow
 - If you need clarification from the user before you can produce a complete plan, use the AskUserQuestion tool. Do NOT call ExitPlanMode. Stop immediately after asking — do not continue exploring or planning.
 
-Do NOT modify any files. Use only read-only tools (Read, Glob, Grep, Task with Explore agents).
-
 When your plan is complete, write it to the plan file and call ExitPlanMode.`
 
 const executeSystemPrompt = `You are a senior software engineer implementing changes to a codebase.
@@ -39,27 +42,140 @@ Rules:
 
 When done, output a brief summary of what was changed.`
 
+// fixTestsSystemPrompt is used for sessions that repair a failing test suite
+// after implementation.
+const fixTestsSystemPrompt = `You are a senior software engineer. You implemented the approved plan below, but the repository's test suite now fails.
+
+Rules:
+- Fix the cause of the failures shown in the test output
+- Prefer fixing the implementation over changing tests; only change a test if the plan intentionally changed the behavior it covers
+- Do not disable, skip, or delete tests to make them pass
+- Stay within the scope of the plan
+- Do not run tests or start servers — the tests are re-run for you
+
+When done, output a brief summary of what you fixed.`
+
+// fixSecuritySystemPrompt is used for the session that addresses security
+// scanner findings before the PR is opened.
+const fixSecuritySystemPrompt = `You are a senior software engineer. You implemented the approved plan below, and security scanners report findings in the changes.
+
+Rules:
+- Fix the issues the findings point to in the code you changed, e.g. unchecked input, hardcoded secrets or vulnerable dependency versions
+- Do not silence the scanners: no ignore comments, suppressions or scanner config changes
+- Leave findings in code the plan didn't touch alone
+- Stay within the scope of the plan
+- Do not run tests, scanners or servers — they are re-run for you
+
+When done, output a brief summary of what you fixed and which findings you left, and why.`
+
+var (
+	cliVersionOnce sync.Once
+	cliVersion     string
+)
+
+// claudeCodeVersion returns the installed Claude Code CLI version as reported by
+// `claude --version`. The result is cached for the life of the process; an
+// empty string means the version could not be determined.
+func claudeCodeVersion() string {
+	cliVersionOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, "claude", "--version").Output()
+		if err != nil {
+			return
+		}
+		cliVersion = strings.TrimSpace(string(out))
+	})
+	return cliVersion
+}
+
+const reviewSystemPrompt = `You are a senior software engineer addressing code review feedback on your own pull request.
+
+The working tree is checked out at the head of the pull request branch. You have been given a reviewer's comment, the file and line it refers to, and the surrounding diff hunk.
+
+Rules:
+- Make the smallest change that fully addresses the comment
+- Follow existing codebase conventions
+- Do not revisit unrelated parts of the pull request
+- If the comment is a question rather than a change request, make no file changes and answer it in your summary
+- Do not run tests or start servers — just make the file changes
+
+When done, output a brief summary of what was changed (or your answer), addressed to the reviewer.`
+
+const checkFixSystemPrompt = `You are a senior software engineer fixing failing CI checks on your own pull request.
+
+The working tree is checked out at the head of the pull request branch. You have been given the failing check runs and the output they reported.
+
+Rules:
+- Find the cause of each failure from the check output and the code, then fix it
+- Make the smallest change that makes the checks pass; do not weaken or delete tests to do so
+- Follow existing codebase conventions
+- Do not revisit unrelated parts of the pull request
+- If a failure is unrelated to the pull request (flaky test, infrastructure error), make no file changes and say so in your summary
+
+When done, output a brief summary of what was wrong and what you changed.`
+
+const prReviewSystemPrompt = `You are a senior software engineer reviewing a teammate's pull request.
+
+The working tree is checked out at the head of the pull request. You have been given the pull request description and its diff. Read the surrounding code as needed to understand the change.
+
+Focus on correctness bugs, security issues, missing error handling, missing tests, and clear violations of the codebase's existing conventions. Do not comment on style preferences or restate what the code does. Only comment when you have something actionable to say; an empty comment list is fine.
+
+Your final message MUST be a single JSON object and nothing else:
+{"summary":"<overall assessment in a few sentences>","comments":[{"path":"<file path relative to the repo root>","line":<line number in the new version of the file, within the diff>,"body":"<comment>"}]}`
+
+const explainSystemPrompt = `You are a senior software engineer answering a teammate's question about this codebase.
+
+The working tree is checked out at the tip of the repository's base branch. Explore the code to answer the question accurately: find the relevant entry points, follow the calls, and read the code rather than guessing from names.
+
+Your final message is posted as the reply in the chat thread. Answer the question directly in a few short paragraphs or a short list, citing the files (path:line) the answer is based on. Say so if the code doesn't answer the question.`
+
 // SessionOpts configures a RunSession call.
 type SessionOpts struct {
-	RepoDir        string // working directory (worktree path for jobs)
-	Prompt         string // the -p argument
-	SystemPrompt   string // prepended to prompt (planning or execution instructions)
-	SessionID      string // --resume <id>; empty = new session
-	PermissionMode string // "plan" or "acceptEdits"
+	RepoDir        string        // working directory (worktree path for jobs)
+	Prompt         string        // the -p argument
+	SystemPrompt   string        // prepended to prompt (planning or execution instructions)
+	SessionID      string        // --resume <id>; empty = new session
+	ResumeFallback bool          // start a new session if SessionID can't be resumed (Prompt must stand on its own)
+	PermissionMode string        // "plan" or "acceptEdits"
+	Tools          ToolRules     // --allowedTools and --disallowedTools
+	GuardInjection bool          // scan tool results for prompt injection into SessionResult.Injections
+	Sandbox        *sandboxSpec  // container to run in; nil runs on the host
+	Limits         SessionLimits // timeout, --max-turns and --model
 }
 
 // SessionResult captures the structured outcome of a Claude Code session.
 type SessionResult struct {
-	SessionID    string // from system/init event
-	PlanFilePath string // Write to .claude/plans/ detected
-	Question     string // from AskUserQuestion tool_use input
-	PlanExited   bool   // ExitPlanMode tool_use detected
-	ResultText   string // from result event
+	SessionID    string  // from system/init event
+	Model        string  // from system/init event
+	CLIVersion   string  // from system/init event
+	PlanFilePath string  // Write to .claude/plans/ detected
+	Question     string  // from AskUserQuestion tool_use input
+	PlanExited   bool    // ExitPlanMode tool_use detected
+	ResultText   string  // from result event
+	CostUSD      float64 // from result event (total_cost_usd for this run)
 	IsError      bool
+	Injections   []InjectionFinding // suspected prompt injection in tool results, with GuardInjection
 }
 
 // RunSession executes a Claude Code CLI session.
 func RunSession(ctx context.Context, claudeCodeToken string, hub *Hub, jobID string, opts SessionOpts) (*SessionResult, error) {
+	sr, err := runSession(ctx, claudeCodeToken, hub, jobID, opts)
+	if err != nil && opts.SessionID != "" && opts.ResumeFallback && isMissingSession(err) {
+		slog.WarnContext(ctx, "claude code: session can't be resumed, starting a new one", "job_id", jobID, "session_id", opts.SessionID)
+		opts.SessionID = ""
+		return runSession(ctx, claudeCodeToken, hub, jobID, opts)
+	}
+	return sr, err
+}
+
+// isMissingSession reports whether a run failed because the CLI has no
+// record of the session to resume, e.g. after its config dir was lost.
+func isMissingSession(err error) bool {
+	return strings.Contains(err.Error(), "No conversation found")
+}
+
+func runSession(ctx context.Context, claudeCodeToken string, hub *Hub, jobID string, opts SessionOpts) (*SessionResult, error) {
 	if _, err := os.Stat(opts.RepoDir); os.IsNotExist(err) {
 		return nil, fmt.Errorf("repository not found at %s", opts.RepoDir)
 	}
@@ -70,8 +186,9 @@ func RunSession(ctx context.Context, claudeCodeToken string, hub *Hub, jobID str
 		prompt = opts.SystemPrompt + "\n\n---\n\n" + prompt
 	}
 
-	// Run Claude Code CLI with a 15-minute timeout.
-	cliCtx, cancel := context.WithTimeout(ctx, 15*time.Minute)
+	// Run Claude Code CLI with the configured timeout (15 minutes by default).
+	timeout := opts.Limits.timeout()
+	cliCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	args := []string{
@@ -82,26 +199,46 @@ func RunSession(ctx context.Context, claudeCodeToken string, hub *Hub, jobID str
 	if opts.PermissionMode != "" {
 		args = append(args, "--permission-mode", opts.PermissionMode)
 	}
+	args = append(args, opts.Tools.args()...)
 	// AskUserQuestion is allowed — the stream parser detects it and kills
 	// the process immediately so no tokens are wasted on the error result.
 	if opts.SessionID != "" {
 		args = append(args, "--resume", opts.SessionID)
 	}
+	if opts.Limits.MaxTurns > 0 {
+		args = append(args, "--max-turns", strconv.Itoa(opts.Limits.MaxTurns))
+	}
+	if opts.Limits.Model != "" {
+		args = append(args, "--model", opts.Limits.Model)
+	}
+	if fallback := opts.Limits.FallbackModel; fallback != "" && fallback != opts.Limits.Model {
+		args = append(args, "--fallback-model", fallback)
+	}
 
-	cmd := exec.CommandContext(cliCtx, "claude", args...)
-	cmd.Dir = opts.RepoDir
-	cmd.Env = append(os.Environ(), "CLAUDE_CODE_OAUTH_TOKEN="+claudeCodeToken, "HOME=/home/worker")
+	cmd := opts.Sandbox.command(cliCtx, opts.RepoDir, []string{"CLAUDE_CODE_OAUTH_TOKEN=" + claudeCodeToken}, "claude", args...)
 
 	sp := newClaudeStreamParser(hub, jobID)
 	sp.cancelOnQuestion = cancel
+	if opts.GuardInjection {
+		sp.toolSources = make(map[string]string)
+	}
+	if f := hub.appendArtifact(jobID, artifactTranscript); f != nil {
+		defer f.Close()
+		sp.transcript = f
+	}
 	cmd.Stdout = sp
 	cmd.Stderr = sp
+	runStart := time.Now()
 	runErr := cmd.Run()
+	observeClaudeCodeRun(opts.PermissionMode, time.Since(runStart), runErr)
 
 	// If the process was killed because AskUserQuestion was detected,
 	// the question was captured — return it as a successful result.
 	if runErr != nil && sp.question == "" {
-		return nil, fmt.Errorf("claude code failed: %s: %w", truncate(sp.raw.String(), 500), runErr)
+		if errors.Is(cliCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return nil, fmt.Errorf("claude code timed out after %s", timeout)
+		}
+		return nil, fmt.Errorf("claude code failed: %s: %w", truncate(redactSecrets(sp.raw.String()), 500), runErr)
 	}
 
 	return sp.result(), nil
@@ -114,8 +251,9 @@ type claudeStreamParser struct {
 	hub   *Hub
 	jobID string
 
-	lineBuf []byte
-	raw     bytes.Buffer // full raw bytes, for error messages
+	lineBuf    []byte
+	raw        bytes.Buffer // full raw bytes, for error messages
+	transcript io.Writer    // redacted lines are appended here when set
 
 	// cancelOnQuestion, when set, is called when AskUserQuestion is detected
 	// to kill the CLI process immediately and avoid wasted tokens.
@@ -123,22 +261,37 @@ type claudeStreamParser struct {
 
 	// Structured results captured from the stream.
 	sessionID    string
+	model        string
+	servedModel  string // from the last assistant message; differs from model after a fallback
+	cliVersion   string
 	planFilePath string
 	question     string
 	planExited   bool
 	resultText   string
+	costUSD      float64
 	isError      bool
 
-	pendingTaskDescs    map[string]string // tool_use_id → Task description
-	suppressResultIDs   map[string]bool   // tool_use IDs whose error results should be hidden (ExitPlanMode, AskUserQuestion)
-	thinkingStartedAt   time.Time
+	runningAgents     map[string]*runningAgent // Task tool_use ID → sub-agent still working
+	suppressResultIDs map[string]bool          // tool_use IDs whose error results should be hidden (ExitPlanMode, AskUserQuestion)
+	toolSources       map[string]string        // tool_use ID → what it read, for the injection guard; nil when off
+	injections        []InjectionFinding
+	thinkingStartedAt time.Time
+}
+
+// runningAgent is a Claude Code sub-agent (Task tool call) that hasn't
+// returned its result yet.
+type runningAgent struct {
+	description string
+	agentType   string
+	startedAt   time.Time
+	toolCalls   map[string]int // by tool name
 }
 
 func newClaudeStreamParser(hub *Hub, jobID string) *claudeStreamParser {
 	return &claudeStreamParser{
 		hub:               hub,
 		jobID:             jobID,
-		pendingTaskDescs:   make(map[string]string),
+		runningAgents:     make(map[string]*runningAgent),
 		suppressResultIDs: make(map[string]bool),
 	}
 }
@@ -147,7 +300,11 @@ func (p *claudeStreamParser) Write(data []byte) (int, error) {
 	p.raw.Write(data)
 	for _, b := range data {
 		if b == '\n' {
-			p.processLine(string(p.lineBuf))
+			line := redactSecrets(string(p.lineBuf))
+			if p.transcript != nil {
+				io.WriteString(p.transcript, line+"\n")
+			}
+			p.processLine(line)
 			p.lineBuf = p.lineBuf[:0]
 		} else {
 			p.lineBuf = append(p.lineBuf, b)
@@ -159,11 +316,15 @@ func (p *claudeStreamParser) Write(data []byte) (int, error) {
 func (p *claudeStreamParser) result() *SessionResult {
 	return &SessionResult{
 		SessionID:    p.sessionID,
+		Model:        p.model,
+		CLIVersion:   p.cliVersion,
 		PlanFilePath: p.planFilePath,
 		Question:     p.question,
 		PlanExited:   p.planExited,
 		ResultText:   p.resultText,
+		CostUSD:      p.costUSD,
 		IsError:      p.isError,
+		Injections:   p.injections,
 	}
 }
 
@@ -171,14 +332,24 @@ func (p *claudeStreamParser) result() *SessionResult {
 type claudeStreamEvent struct {
 	Type            string `json:"type"`
 	Subtype         string `json:"subtype"`
-	SessionID       string `json:"session_id"` // populated on type=system, subtype=init
+	SessionID       string `json:"session_id"`          // populated on type=system, subtype=init
+	Model           string `json:"model"`               // populated on type=system, subtype=init
+	CLIVersion      string `json:"claude_code_version"` // populated on type=system, subtype=init
 	ParentToolUseID string `json:"parent_tool_use_id"`
 	Message         struct {
 		Role    string            `json:"role"`
+		Model   string            `json:"model"` // the model that wrote it, after any fallback
 		Content []json.RawMessage `json:"content"`
 	} `json:"message"`
-	Result string `json:"result"` // populated on type=result
-	Error  string `json:"error"`  // populated on type=result,subtype=error
+	Result       string  `json:"result"`         // populated on type=result
+	Error        string  `json:"error"`          // populated on type=result,subtype=error
+	TotalCostUSD float64 `json:"total_cost_usd"` // populated on type=result
+	Usage        struct {
+		InputTokens              int64 `json:"input_tokens"`
+		OutputTokens             int64 `json:"output_tokens"`
+		CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+		CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	} `json:"usage"` // populated on type=result
 }
 
 type claudeContentBlock struct {
@@ -192,10 +363,29 @@ type claudeContentBlock struct {
 
 // claudeToolResultBlock represents a tool_result content block in a "user" event.
 type claudeToolResultBlock struct {
-	Type      string `json:"type"`
-	ToolUseID string `json:"tool_use_id"`
-	Content   string `json:"content"`
-	IsError   bool   `json:"is_error"`
+	Type      string          `json:"type"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"` // a string, or text blocks (e.g. a sub-agent's report)
+	IsError   bool            `json:"is_error"`
+}
+
+// text returns the result's content as text.
+func (b claudeToolResultBlock) text() string {
+	var s string
+	if err := json.Unmarshal(b.Content, &s); err == nil {
+		return s
+	}
+	var blocks []struct {
+		Text string `json:"text"`
+	}
+	json.Unmarshal(b.Content, &blocks)
+	parts := make([]string, 0, len(blocks))
+	for _, tb := range blocks {
+		if tb.Text != "" {
+			parts = append(parts, tb.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
 }
 
 func (p *claudeStreamParser) processLine(line string) {
@@ -207,7 +397,7 @@ func (p *claudeStreamParser) processLine(line string) {
 	var evt claudeStreamEvent
 	if err := json.Unmarshal([]byte(line), &evt); err != nil {
 		// Not JSON (e.g. stderr noise) — emit verbatim.
-		p.emit(line)
+		p.emit(line, "")
 		return
 	}
 
@@ -216,7 +406,20 @@ func (p *claudeStreamParser) processLine(line string) {
 		if evt.Subtype == "init" && evt.SessionID != "" {
 			p.sessionID = evt.SessionID
 		}
+		if evt.Subtype == "init" {
+			p.model = evt.Model
+			p.cliVersion = evt.CLIVersion
+			if p.hub != nil && p.jobID != "" && (evt.Model != "" || evt.CLIVersion != "") {
+				p.hub.Emit(p.jobID, JobMetadataData{
+					Model:      evt.Model,
+					CLIVersion: evt.CLIVersion,
+				})
+			}
+		}
 	case "assistant":
+		if evt.Message.Model != "" && evt.ParentToolUseID == "" {
+			p.servedModel = evt.Message.Model
+		}
 		for _, raw := range evt.Message.Content {
 			var block claudeContentBlock
 			if err := json.Unmarshal(raw, &block); err != nil {
@@ -226,15 +429,16 @@ func (p *claudeStreamParser) processLine(line string) {
 			case "text":
 				for _, textLine := range strings.Split(block.Text, "\n") {
 					if strings.TrimSpace(textLine) != "" {
-						p.emit(textLine)
+						p.emit(textLine, evt.ParentToolUseID)
 					}
 				}
 			case "thinking":
 				p.thinkingStartedAt = time.Now()
 				if p.hub != nil && p.jobID != "" {
-					p.hub.Emit(p.jobID, EventClaudeCodeLine, map[string]any{
-						"thinking":    block.Thinking,
-						"thinking_ts": time.Now().UnixMilli(),
+					p.hub.Emit(p.jobID, ClaudeCodeLineData{
+						Thinking:   &block.Thinking,
+						ThinkingTS: time.Now().UnixMilli(),
+						AgentID:    evt.ParentToolUseID,
 					})
 				}
 			case "tool_use":
@@ -242,7 +446,6 @@ func (p *claudeStreamParser) processLine(line string) {
 			}
 		}
 	case "user":
-		var completed []map[string]any
 		for _, raw := range evt.Message.Content {
 			var block claudeToolResultBlock
 			if err := json.Unmarshal(raw, &block); err != nil {
@@ -251,6 +454,16 @@ func (p *claudeStreamParser) processLine(line string) {
 			if block.Type != "tool_result" {
 				continue
 			}
+			if _, ok := p.runningAgents[block.ToolUseID]; ok {
+				p.finishAgent(block.ToolUseID, block.IsError)
+				continue
+			}
+			if source, ok := p.toolSources[block.ToolUseID]; ok {
+				delete(p.toolSources, block.ToolUseID)
+				if !block.IsError {
+					p.guardInjection(source, block.text())
+				}
+			}
 			if block.IsError {
 				// Suppress error results for internal signals (ExitPlanMode, AskUserQuestion)
 				// whose "errors" are just confirmation prompts, not real failures.
@@ -259,23 +472,11 @@ func (p *claudeStreamParser) processLine(line string) {
 					continue
 				}
 				if p.hub != nil && p.jobID != "" {
-					p.hub.Emit(p.jobID, EventClaudeCodeLine, map[string]any{
-						"tool_error": truncate(block.Content, 300),
-					})
+					toolErr := truncate(block.text(), 300)
+					p.hub.Emit(p.jobID, ClaudeCodeLineData{ToolError: &toolErr, AgentID: evt.ParentToolUseID})
 				}
-				continue
-			}
-			if desc, ok := p.pendingTaskDescs[block.ToolUseID]; ok {
-				completed = append(completed, map[string]any{"description": desc})
-				delete(p.pendingTaskDescs, block.ToolUseID)
 			}
 		}
-		if len(completed) > 0 && p.hub != nil && p.jobID != "" {
-			p.hub.Emit(p.jobID, EventClaudeCodeLine, map[string]any{
-				"agents_finished": len(completed),
-				"agents":          completed,
-			})
-		}
 	case "result":
 		if evt.Subtype == "error" && evt.Error != "" {
 			p.resultText = evt.Error
@@ -283,6 +484,24 @@ func (p *claudeStreamParser) processLine(line string) {
 		} else {
 			p.resultText = evt.Result
 		}
+		p.costUSD = evt.TotalCostUSD
+		// Record the run's cost so stats and budget enforcement include Claude Code.
+		if p.hub != nil && p.jobID != "" && (evt.TotalCostUSD > 0 || evt.Usage.InputTokens > 0) {
+			model := p.servedModel
+			if model == "" {
+				model = p.model
+			}
+			p.hub.Emit(p.jobID, LLMResponseData{
+				Model:            model,
+				StopReason:       evt.Subtype,
+				Summary:          "claude code session",
+				InputTokens:      evt.Usage.InputTokens,
+				OutputTokens:     evt.Usage.OutputTokens,
+				CacheReadTokens:  evt.Usage.CacheReadInputTokens,
+				CacheWriteTokens: evt.Usage.CacheCreationInputTokens,
+				CostUSD:          evt.TotalCostUSD,
+			})
+		}
 		// Don't re-emit result text — it was already shown from assistant text blocks.
 	case "rate_limit_event":
 		// no-op
@@ -328,29 +547,77 @@ func (p *claudeStreamParser) processToolUse(block claudeContentBlock, parentTool
 		}
 	}
 
-	// Track Task sub-agent IDs for later aggregation.
+	if a, ok := p.runningAgents[parentToolUseID]; ok {
+		a.toolCalls[block.Name]++
+	}
+	if p.toolSources != nil && block.ID != "" && block.Name != "Task" {
+		p.toolSources[block.ID] = toolSource(block.Name, block.Input)
+	}
+	p.emitTool(block.Name, block.Input, parentToolUseID)
+
+	// A Task call starts a sub-agent, whose own output carries its ID.
 	if block.Name == "Task" && block.ID != "" {
 		var input struct {
-			Description string `json:"description"`
+			Description  string `json:"description"`
+			SubagentType string `json:"subagent_type"`
 		}
-		if err := json.Unmarshal(block.Input, &input); err == nil && input.Description != "" {
-			p.pendingTaskDescs[block.ID] = input.Description
+		json.Unmarshal(block.Input, &input)
+		p.runningAgents[block.ID] = &runningAgent{
+			description: input.Description,
+			agentType:   input.SubagentType,
+			startedAt:   time.Now(),
+			toolCalls:   map[string]int{},
 		}
+		if p.hub != nil && p.jobID != "" {
+			p.hub.Emit(p.jobID, AgentStartedData{
+				AgentID:     block.ID,
+				ParentID:    parentToolUseID,
+				Description: input.Description,
+				AgentType:   input.SubagentType,
+			})
+		}
+	}
+}
+
+// guardInjection records suspected prompt injection in a tool result.
+func (p *claudeStreamParser) guardInjection(source, text string) {
+	for _, f := range scanInjection(source, text) {
+		if len(p.injections) == maxInjectionFindings {
+			return
+		}
+		p.injections = append(p.injections, f)
 	}
+}
 
-	p.emitTool(block.Name, block.Input)
+// finishAgent emits agent_finished for the sub-agent started by the Task call
+// id, now that its result is in.
+func (p *claudeStreamParser) finishAgent(id string, isError bool) {
+	a := p.runningAgents[id]
+	delete(p.runningAgents, id)
+	if p.hub == nil || p.jobID == "" {
+		return
+	}
+	p.hub.Emit(p.jobID, AgentFinishedData{
+		AgentID:     id,
+		Description: a.description,
+		AgentType:   a.agentType,
+		ToolCalls:   a.toolCalls,
+		DurationMs:  time.Since(a.startedAt).Milliseconds(),
+		IsError:     isError,
+	})
 }
 
-func (p *claudeStreamParser) emit(text string) {
+// emit emits a text line, from the sub-agent agentID if set.
+func (p *claudeStreamParser) emit(text, agentID string) {
 	if p.hub == nil || p.jobID == "" {
 		return
 	}
-	p.hub.Emit(p.jobID, EventClaudeCodeLine, map[string]any{"text": text})
+	p.hub.Emit(p.jobID, ClaudeCodeLineData{Text: text, AgentID: agentID})
 }
 
 // emitTool emits a claude_code_line event carrying the full tool input so the
 // UI can render rich diffs (Edit/Write) and checklists (TodoWrite).
-func (p *claudeStreamParser) emitTool(name string, input json.RawMessage) {
+func (p *claudeStreamParser) emitTool(name string, input json.RawMessage, agentID string) {
 	if p.hub == nil || p.jobID == "" {
 		return
 	}
@@ -358,8 +625,9 @@ func (p *claudeStreamParser) emitTool(name string, input json.RawMessage) {
 	if len(input) > 0 {
 		inputStr = string(input)
 	}
-	p.hub.Emit(p.jobID, EventClaudeCodeLine, map[string]any{
-		"tool_name":  name,
-		"tool_input": inputStr,
+	p.hub.Emit(p.jobID, ClaudeCodeLineData{
+		ToolName:  name,
+		ToolInput: inputStr,
+		AgentID:   agentID,
 	})
 }
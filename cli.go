@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cliPlatform is the platform name of jobs started with `bob run`.
+const cliPlatform = "cli"
+
+// cliEventWait bounds how long the CLI waits for an event to be printed
+// before it prompts or exits, so output isn't interleaved.
+const cliEventWait = 5 * time.Second
+
+// runCLI runs `bob run`: one job, from planning to the pull request, without a
+// chat platform. It reads the same settings as the server, prints the job's
+// events to out, and asks for answers, plan approval and dry-run pushes on in.
+// It returns the process exit code.
+func runCLI(args []string, in io.Reader, out io.Writer) int {
+	fs := flag.NewFlagSet("bob run", flag.ContinueOnError)
+	fs.SetOutput(out)
+	repo := fs.String("repo", "", "repository to work on (required)")
+	task := fs.String("task", "", "what to do (required)")
+	base := fs.String("base-branch", "", "branch to work from and open the PR against (default: the repo's)")
+	planFile := fs.String("plan", "", "file with a plan to implement, skipping planning")
+	paths := fs.String("paths", "", "comma-separated directories or files to limit the job to")
+	dryRun := fs.Bool("dry-run", false, "show the diff and ask before committing and opening the PR")
+	changelog := fs.Bool("changelog", false, "add an entry for the change to the repo's changelog")
+	yes := fs.Bool("yes", false, "approve the plan (and a dry run's push) without asking")
+	jsonEvents := fs.Bool("json", false, "print events as JSON lines")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if strings.TrimSpace(*repo) == "" || strings.TrimSpace(*task) == "" {
+		fmt.Fprintln(out, "bob run: --repo and --task are required")
+		fs.Usage()
+		return 2
+	}
+	intent := IntentResult{Repo: strings.TrimSpace(*repo), Task: strings.TrimSpace(*task), BaseBranch: strings.TrimSpace(*base), DryRun: *dryRun, Changelog: *changelog}
+	if *paths != "" {
+		cleaned, err := cleanScopePaths(strings.Split(*paths, ","))
+		if err != nil {
+			fmt.Fprintf(out, "bob run: invalid --paths: %v\n", err)
+			return 2
+		}
+		intent.Paths = cleaned
+	}
+	var plan string
+	if *planFile != "" {
+		data, err := os.ReadFile(*planFile)
+		if err != nil {
+			fmt.Fprintf(out, "bob run: %v\n", err)
+			return 2
+		}
+		plan = string(data)
+	}
+
+	_, logs := loadSettings()
+	registerEnvSecrets()
+	providers, _ := vcsProvidersFromEnv()
+	claudeCodeToken := os.Getenv("CLAUDE_CODE_OAUTH_TOKEN")
+	if claudeCodeToken == "" {
+		fatal("CLAUDE_CODE_OAUTH_TOKEN must be set")
+	}
+	hub := hubFromEnv()
+	logs.setHub(hub)
+	// No intent LLM: the repo and task are given. Access rules are per chat
+	// channel and user group, so they don't apply; the repo filter does.
+	orch, _ := orchestratorFromEnv(nil, providers, claudeCodeToken, hub, nil)
+
+	events := newCLIEvents(out, *jsonEvents)
+	client := &sseClient{send: make(chan sseMessage, 1024)}
+	if !hub.add(client) {
+		fatal("bob run: can't subscribe to events")
+	}
+	defer hub.remove(client)
+	go events.print(client.send)
+
+	ctx := WithPlatform(context.Background(), cliPlatform)
+	ctx = WithUser(ctx, os.Getenv("USER"))
+	ctx = WithHub(ctx, hub)
+
+	var jobID string
+	interrupt, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var jobIDMu sync.Mutex
+	go func() {
+		<-interrupt.Done()
+		jobIDMu.Lock()
+		id := jobID
+		jobIDMu.Unlock()
+		if id == "" {
+			os.Exit(130)
+		}
+		fmt.Fprintln(out, "Cancelling...")
+		_ = orch.CancelJob(ctx, id, "CLI")
+	}()
+
+	result, err := orch.HandleDirectRequest(ctx, intent, plan, func(id string) {
+		jobIDMu.Lock()
+		jobID = id
+		jobIDMu.Unlock()
+	})
+	input := bufio.NewScanner(in)
+	for {
+		if err != nil {
+			fmt.Fprintf(out, "Error: %v\n", err)
+			events.wait(jobID, func() bool { return events.finished(jobID) })
+			return 1
+		}
+		if result.JobID == "" {
+			fmt.Fprintln(out, result.Text)
+			return 1
+		}
+		state, ok := hub.GetJobState(result.JobID)
+		if !ok {
+			return 1
+		}
+		state.mu.Lock()
+		phase, closed := state.Phase, state.closed
+		state.mu.Unlock()
+		if closed || phase == PhaseDone {
+			events.wait(result.JobID, func() bool { return events.finished(result.JobID) })
+			if result.Text != "" {
+				fmt.Fprintln(out, result.Text)
+			}
+			if result.PRURL != "" && !strings.Contains(result.Text, result.PRURL) {
+				fmt.Fprintln(out, result.PRURL)
+			}
+			if events.succeeded(result.JobID) {
+				return 0
+			}
+			return 1
+		}
+		events.wait(result.JobID, func() bool { return events.inPhase(result.JobID, phase) })
+
+		switch phase {
+		case PhaseAwaitingQuestion:
+			fmt.Fprintf(out, "\n%s\n\nYour answer: ", result.Text)
+			answer, ok := readLine(input)
+			if !ok || answer == "" {
+				return cliAbort(ctx, orch, out, result.JobID)
+			}
+			result, err = orch.HandleReply(ctx, result.JobID, answer)
+		case PhaseAwaitingApproval:
+			plan := result.PlanText
+			if plan == "" {
+				plan = result.Text
+			}
+			fmt.Fprintf(out, "\n%s\n\n", plan)
+			reply := "y"
+			if !*yes || len(result.Injections) > 0 { // --yes doesn't cover a plan made after suspected prompt injection
+				fmt.Fprint(out, "Implement this plan? [y = yes, n = cancel, or type feedback]: ")
+				var ok bool
+				if reply, ok = readLine(input); !ok {
+					return cliAbort(ctx, orch, out, result.JobID)
+				}
+			}
+			switch strings.ToLower(reply) {
+			case "y", "yes":
+				if !hub.TryStartImplementation(result.JobID) {
+					fmt.Fprintln(out, "The job isn't waiting for approval anymore.")
+					return 1
+				}
+				hub.Emit(result.JobID, PlanApprovedData{ApprovedBy: cliPlatform})
+				id := result.JobID
+				if result, err = orch.HandleApproval(ctx, id); err != nil {
+					hub.ClearImplementation(id)
+				}
+			case "", "n", "no":
+				return cliAbort(ctx, orch, out, result.JobID)
+			default:
+				result, err = orch.HandleReply(ctx, result.JobID, reply)
+			}
+		case PhaseAwaitingPush:
+			fmt.Fprintf(out, "\n%s\n\n%s\n", result.Diff, result.Text)
+			reply := "y"
+			if !*yes || len(result.SecretFindings) > 0 { // --yes doesn't cover publishing credentials
+				fmt.Fprint(out, "Commit and open the pull request? [y = yes, n = cancel, or type feedback]: ")
+				var ok bool
+				if reply, ok = readLine(input); !ok {
+					return cliAbort(ctx, orch, out, result.JobID)
+				}
+			}
+			switch strings.ToLower(reply) {
+			case "y", "yes":
+				if !hub.TryStartPush(result.JobID) {
+					fmt.Fprintln(out, "The job has no changes waiting to be pushed.")
+					return 1
+				}
+				hub.Emit(result.JobID, PushApprovedData{ApprovedBy: cliPlatform})
+				result, err = orch.HandlePush(ctx, result.JobID)
+			case "", "n", "no":
+				return cliAbort(ctx, orch, out, result.JobID)
+			default:
+				result, err = orch.HandleReply(ctx, result.JobID, reply)
+			}
+		default:
+			fmt.Fprintf(out, "The job stopped in phase %s: %s\n", phase, result.Text)
+			return 1
+		}
+	}
+}
+
+// readLine reads a trimmed line of input; false at the end of input.
+func readLine(s *bufio.Scanner) (string, bool) {
+	if !s.Scan() {
+		return "", false
+	}
+	return strings.TrimSpace(s.Text()), true
+}
+
+// cliAbort cancels a job the user declined to go on with.
+func cliAbort(ctx context.Context, orch *Orchestrator, out io.Writer, jobID string) int {
+	if err := orch.CancelJob(ctx, jobID, "CLI"); err != nil {
+		fmt.Fprintf(out, "Couldn't cancel job %s: %v\n", jobID, err)
+	} else {
+		fmt.Fprintln(out, "Cancelled.")
+	}
+	return 1
+}
+
+// cliEvents prints hub events for `bob run` and tracks the phase each job was
+// last seen in, so the CLI can wait for its output before prompting.
+type cliEvents struct {
+	out  io.Writer
+	json bool
+
+	mu       sync.Mutex
+	phases   map[string]JobPhase  // job ID → last printed phase_changed
+	terminal map[string]EventType // job ID → the terminal event printed
+}
+
+func newCLIEvents(out io.Writer, json bool) *cliEvents {
+	return &cliEvents{out: out, json: json, phases: make(map[string]JobPhase), terminal: make(map[string]EventType)}
+}
+
+// print prints the events from a hub subscription until it's closed.
+func (p *cliEvents) print(msgs <-chan sseMessage) {
+	defer recoverGoroutine("cli: events", nil)
+	for msg := range msgs {
+		var e Event
+		if err := json.Unmarshal(msg.data, &e); err != nil {
+			continue
+		}
+		p.mu.Lock()
+		if p.json {
+			fmt.Fprintf(p.out, "%s\n", msg.data)
+		} else if line := formatCLIEvent(e); line != "" {
+			fmt.Fprintf(p.out, "%s %s\n", e.Timestamp.Format(time.TimeOnly), line)
+		}
+		switch d := e.Data.(type) {
+		case PhaseChangedData:
+			p.phases[e.JobID] = JobPhase(d.Phase)
+		case JobCompletedData, JobErrorData, JobCancelledData, JobExpiredData:
+			p.terminal[e.JobID] = e.Type
+		}
+		p.mu.Unlock()
+	}
+}
+
+func (p *cliEvents) inPhase(jobID string, phase JobPhase) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.phases[jobID] == phase
+}
+
+func (p *cliEvents) finished(jobID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.terminal[jobID] != ""
+}
+
+func (p *cliEvents) succeeded(jobID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.terminal[jobID] == EventJobCompleted
+}
+
+// wait waits up to cliEventWait for cond, e.g. for the event that put the job
+// in its current phase to be printed.
+func (p *cliEvents) wait(jobID string, cond func() bool) {
+	if jobID == "" {
+		return
+	}
+	for deadline := time.Now().Add(cliEventWait); !cond() && time.Now().Before(deadline); {
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// formatCLIEvent describes an event in one line for the terminal, or returns
+// empty string for events not worth showing there.
+func formatCLIEvent(e Event) string {
+	switch d := e.Data.(type) {
+	case JobStartedData:
+		return fmt.Sprintf("job %s started: %s — %s", e.JobID, d.Repo, truncate(d.Task, 100))
+	case PhaseChangedData:
+		return "phase: " + d.Phase
+	case ToolStartedData:
+		return fmt.Sprintf("%s started: %s", d.ToolName, truncate(firstLine(d.Input), 100))
+	case ToolCompletedData:
+		status := "done"
+		if d.IsError {
+			status = "failed"
+		}
+		return fmt.Sprintf("%s %s in %s: %s", d.ToolName, status, (time.Duration(d.DurationMs) * time.Millisecond).Round(time.Second), truncate(firstLine(d.ResultPreview), 100))
+	case ClaudeCodeLineData:
+		return formatCLILine(d)
+	case ClaudeCodeLinesData:
+		var lines []string
+		for _, l := range d.Lines {
+			if s := formatCLILine(l); s != "" {
+				lines = append(lines, s)
+			}
+		}
+		return strings.Join(lines, "\n         ")
+	case AgentFinishedData:
+		status := "finished"
+		if d.IsError {
+			status = "failed"
+		}
+		return fmt.Sprintf("  sub-agent %s %s in %s (%d tool calls)", truncate(d.Description, 80), status, (time.Duration(d.DurationMs) * time.Millisecond).Round(time.Second), d.totalToolCalls())
+	case PromptInjectionData:
+		return fmt.Sprintf("prompt injection guard: %d suspicious passage(s) read while planning", len(d.Findings))
+	case SecretScanData:
+		if len(d.Findings) == 0 {
+			return ""
+		}
+		return fmt.Sprintf("secret scan: %d possible secret(s) in the changes; push held", len(d.Findings))
+	case SecurityScanData:
+		if len(d.Findings) == 0 {
+			return fmt.Sprintf("security scan: no findings (%s)", strings.Join(d.Scanners, ", "))
+		}
+		return "security scan: findings from " + findingScanners(d.Findings)
+	case JobCompletedData:
+		return fmt.Sprintf("job completed (cost $%.2f) %s", d.TotalCostUSD, d.PRURL)
+	case JobErrorData:
+		return "job failed: " + d.Error
+	case JobCancelledData:
+		return "job cancelled"
+	case JobExpiredData:
+		return "job expired"
+	}
+	return ""
+}
+
+// formatCLILine describes one line of Claude Code output, leaving out thinking.
+func formatCLILine(d ClaudeCodeLineData) string {
+	switch {
+	case d.Text != "":
+		return "  " + truncate(firstLine(d.Text), 160)
+	case d.ToolName != "":
+		return fmt.Sprintf("  → %s %s", d.ToolName, truncate(d.ToolInput, 120))
+	case d.ToolError != nil:
+		return "  ✗ " + truncate(firstLine(*d.ToolError), 160)
+	}
+	return ""
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunCLI_Usage(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"--repo", "web"},
+		{"--repo", "web", "--task", "fix it", "--paths", "../etc"},
+		{"--repo", "web", "--task", "fix it", "--plan", "/nonexistent/plan.md"},
+		{"--bogus"},
+	} {
+		var out bytes.Buffer
+		if code := runCLI(args, strings.NewReader(""), &out); code != 2 {
+			t.Errorf("runCLI(%q) = %d, want 2; output %q", args, code, out.String())
+		}
+	}
+}
+
+func TestCLIEvents(t *testing.T) {
+	var out bytes.Buffer
+	p := newCLIEvents(&out, false)
+	msgs := make(chan sseMessage, 10)
+	ts := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	for _, e := range []Event{
+		{JobID: "j1", Type: EventJobStarted, Timestamp: ts, Data: JobStartedData{Repo: "web", Task: "fix login"}},
+		{JobID: "j1", Type: EventPhaseChanged, Timestamp: ts, Data: PhaseChangedData{Phase: string(PhaseAwaitingApproval)}},
+		{JobID: "j1", Type: EventToolCompleted, Timestamp: ts, Data: ToolCompletedData{ToolName: "run_tests", DurationMs: 2500, IsError: true, ResultPreview: "FAIL\nmore"}},
+		{JobID: "j1", Type: EventClaudeCodeLine, Timestamp: ts, Data: ClaudeCodeLineData{ToolName: "Edit", ToolInput: "login.go"}},
+		{JobID: "j1", Type: EventJobCompleted, Timestamp: ts, Data: JobCompletedData{PRURL: "https://x/pr/1", TotalCostUSD: 1.5}},
+	} {
+		data, err := json.Marshal(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs <- sseMessage{data: data}
+	}
+	close(msgs)
+	p.print(msgs)
+
+	want := "15:04:05 job j1 started: web — fix login\n" +
+		"15:04:05 phase: awaiting_approval\n" +
+		"15:04:05 run_tests failed in 3s: FAIL\n" +
+		"15:04:05   → Edit login.go\n" +
+		"15:04:05 job completed (cost $1.50) https://x/pr/1\n"
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if !p.inPhase("j1", PhaseAwaitingApproval) || !p.finished("j1") || !p.succeeded("j1") {
+		t.Errorf("phases = %v, terminal = %v", p.phases, p.terminal)
+	}
+	if p.finished("j2") {
+		t.Error("unknown job reported as finished")
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// maxCoalescedLines caps a batch, so a burst of output is still streamed in
+// pieces rather than held until the window closes.
+const maxCoalescedLines = 200
+
+// lineCoalescer batches a job's consecutive claude_code_line events into one
+// claude_code_lines event, sent when the window after the first line closes,
+// the batch is full, or any other event for the job is emitted. Observers
+// (metrics, progress, webhooks) still see every line as it happens; only what
+// is persisted and streamed is batched.
+type lineCoalescer struct {
+	hub    *Hub
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*lineBatch // jobID → lines not yet sent
+}
+
+// lineBatch is a job's held lines. The batch event takes the first line's
+// timestamp and the last line's ID, so IDs stay ordered within the job.
+type lineBatch struct {
+	first, last Event
+	lines       []ClaudeCodeLineData
+	timer       *time.Timer
+}
+
+func newLineCoalescer(hub *Hub, window time.Duration) *lineCoalescer {
+	return &lineCoalescer{hub: hub, window: window, pending: make(map[string]*lineBatch)}
+}
+
+// emit holds e if it is a Claude Code line, and otherwise sends the job's
+// held lines before e.
+func (c *lineCoalescer) emit(e Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	line, ok := e.Data.(ClaudeCodeLineData)
+	if !ok {
+		c.flushLocked(e.JobID)
+		c.hub.enqueue(e)
+		return
+	}
+	b := c.pending[e.JobID]
+	if b == nil {
+		b = &lineBatch{first: e}
+		b.timer = time.AfterFunc(c.window, func() { c.flush(e.JobID, b) })
+		c.pending[e.JobID] = b
+	}
+	b.lines = append(b.lines, line)
+	b.last = e
+	if len(b.lines) >= maxCoalescedLines {
+		c.flushLocked(e.JobID)
+	}
+}
+
+// flush sends b when its window closes, unless it was already sent.
+func (c *lineCoalescer) flush(jobID string, b *lineBatch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pending[jobID] == b {
+		c.flushLocked(jobID)
+	}
+}
+
+func (c *lineCoalescer) flushLocked(jobID string) {
+	b := c.pending[jobID]
+	if b == nil {
+		return
+	}
+	delete(c.pending, jobID)
+	b.timer.Stop()
+	if len(b.lines) == 1 {
+		c.hub.enqueue(b.first)
+		return
+	}
+	c.hub.enqueue(Event{
+		ID:            b.last.ID,
+		JobID:         jobID,
+		Type:          EventClaudeCodeLines,
+		Timestamp:     b.first.Timestamp,
+		SchemaVersion: eventSchemaVersion,
+		Data:          ClaudeCodeLinesData{Lines: b.lines},
+	})
+}
+
+// EnableLineCoalescing batches Claude Code output lines emitted within window
+// of each other. Call it before any events are emitted.
+func (h *Hub) EnableLineCoalescing(window time.Duration) {
+	if window > 0 {
+		h.coalescer.Store(newLineCoalescer(h, window))
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestLineCoalescing(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	hub.EnableLineCoalescing(50 * time.Millisecond)
+	c := &sseClient{send: make(chan sseMessage, 16)}
+	hub.add(c)
+	defer hub.remove(c)
+
+	next := func() Event {
+		t.Helper()
+		select {
+		case msg := <-c.send:
+			var e Event
+			if err := json.Unmarshal(msg.data, &e); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			return e
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for an event")
+			return Event{}
+		}
+	}
+
+	// Lines are held until another event for the job follows.
+	hub.Emit("job-1", ClaudeCodeLineData{Text: "one"})
+	hub.Emit("job-1", ClaudeCodeLineData{Text: "two"})
+	hub.Emit("job-1", ClaudeCodeLineData{ToolName: "Read", ToolInput: `{"file_path":"main.go"}`})
+	hub.Emit("job-1", ToolCompletedData{ToolName: "plan_changes"})
+
+	e := next()
+	d, ok := e.Data.(ClaudeCodeLinesData)
+	if e.Type != EventClaudeCodeLines || !ok || len(d.Lines) != 3 || d.Lines[1].Text != "two" || d.Lines[2].ToolName != "Read" {
+		t.Fatalf("first event = %+v, want the three lines batched", e)
+	}
+	if e := next(); e.Type != EventToolCompleted {
+		t.Fatalf("second event = %s, want tool_completed", e.Type)
+	}
+
+	// A lone line is sent as itself once the window closes.
+	hub.Emit("job-1", ClaudeCodeLineData{Text: "three"})
+	e = next()
+	if line, ok := e.Data.(ClaudeCodeLineData); e.Type != EventClaudeCodeLine || !ok || line.Text != "three" {
+		t.Fatalf("event = %+v, want a single claude_code_line", e)
+	}
+}
+
+func TestSSEClientSummary(t *testing.T) {
+	c := &sseClient{jobID: "job-1", summary: true}
+	if c.wants(Event{JobID: "job-1", Type: EventClaudeCodeLines}) {
+		t.Error("summary clients should skip output lines")
+	}
+	if !c.wants(Event{JobID: "job-1", Type: EventPhaseChanged}) {
+		t.Error("summary clients should get phase changes")
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxCommitSubject is the longest commit subject line Bob writes.
+const maxCommitSubject = 72
+
+// gitIdentity is a git author or co-author.
+type gitIdentity struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// defaultGitAuthor is who Bob commits as unless BOB_GIT_AUTHOR_NAME and
+// BOB_GIT_AUTHOR_EMAIL say otherwise.
+var defaultGitAuthor = gitIdentity{Name: "Bob", Email: "bob@noreply"}
+
+// orDefault fills in missing fields from defaultGitAuthor.
+func (g gitIdentity) orDefault() gitIdentity {
+	if g.Name == "" {
+		g.Name = defaultGitAuthor.Name
+	}
+	if g.Email == "" {
+		g.Email = defaultGitAuthor.Email
+	}
+	return g
+}
+
+func (g gitIdentity) String() string { return fmt.Sprintf("%s <%s>", g.Name, g.Email) }
+
+// commitSpec describes the commit Bob makes for a job. The message is built
+// from the task once the committed files are known.
+type commitSpec struct {
+	Task     string
+	Author   gitIdentity  // zero uses defaultGitAuthor
+	CoAuthor *gitIdentity // the requesting user, credited in a Co-authored-by trailer
+}
+
+// message returns a Conventional Commits message for the task: a
+// "type(scope): description" subject, the full task as the body when the
+// subject can't hold it, and the co-author trailer.
+func (c commitSpec) message(files []string) string {
+	task := strings.TrimSpace(c.Task)
+	first, rest, _ := strings.Cut(task, "\n")
+	prefix := commitType(task, files)
+	if scope := commitScope(files); scope != "" {
+		prefix += "(" + scope + ")"
+	}
+	prefix += ": "
+	desc := truncateWords(commitDescription(first), maxCommitSubject-len(prefix))
+
+	var b strings.Builder
+	b.WriteString(prefix + desc)
+	if desc != commitDescription(first) || strings.TrimSpace(rest) != "" {
+		b.WriteString("\n\n" + task)
+	}
+	if c.CoAuthor != nil && c.CoAuthor.Email != "" {
+		b.WriteString("\n\nCo-authored-by: " + c.CoAuthor.String())
+	}
+	return b.String()
+}
+
+// commitTypes maps task keywords to Conventional Commits types, checked in
+// order so that e.g. "fix the flaky test" is a fix rather than a test change.
+var commitTypes = []struct {
+	typ string
+	re  *regexp.Regexp
+}{
+	{"fix", regexp.MustCompile(`\b(fix(es|ed|ing)?|bugs?|broken|crash(es|ing)?|regression|typos?)\b`)},
+	{"docs", regexp.MustCompile(`\b(docs?|documentation|document|readme)\b`)},
+	{"test", regexp.MustCompile(`\b(tests?|testing|coverage)\b`)},
+	{"refactor", regexp.MustCompile(`\b(refactor(ing)?|clean ?up|restructure|simplify|rename)\b`)},
+	{"perf", regexp.MustCompile(`\b(perf|performance|faster|speed up|optimi[sz]e)\b`)},
+	{"chore", regexp.MustCompile(`\b(bump|upgrade|update (the )?dependenc(y|ies)|deps|dependabot|lint|ci)\b`)},
+}
+
+// commitType classifies the change by the task's wording, or as docs when only
+// Markdown files changed. Anything else is a feat.
+func commitType(task string, files []string) string {
+	if len(files) > 0 {
+		docsOnly := true
+		for _, f := range files {
+			docsOnly = docsOnly && strings.EqualFold(path.Ext(f), ".md")
+		}
+		if docsOnly {
+			return "docs"
+		}
+	}
+	lower := strings.ToLower(task)
+	for _, t := range commitTypes {
+		if t.re.MatchString(lower) {
+			return t.typ
+		}
+	}
+	return "feat"
+}
+
+// commitScope returns the top-level directory all files are in, if they share
+// one, as the commit scope.
+func commitScope(files []string) string {
+	scope := ""
+	for _, f := range files {
+		dir, _, ok := strings.Cut(path.Clean(f), "/")
+		if !ok || strings.HasPrefix(dir, ".") || (scope != "" && dir != scope) {
+			return ""
+		}
+		scope = dir
+	}
+	return strings.ToLower(scope)
+}
+
+// commitDescription turns a task line into an imperative description: no
+// trailing period and a lowercase first letter, unless it starts an acronym.
+func commitDescription(line string) string {
+	line = strings.TrimRight(strings.TrimSpace(line), ".")
+	r, size := utf8.DecodeRuneInString(line)
+	next, _ := utf8.DecodeRuneInString(line[size:])
+	if unicode.IsUpper(r) && !unicode.IsUpper(next) {
+		line = string(unicode.ToLower(r)) + line[size:]
+	}
+	return line
+}
+
+// truncateWords shortens s to at most max bytes, cutting at a word boundary
+// where there is one.
+func truncateWords(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	if i := strings.LastIndex(s[:cut], " "); i > 0 {
+		cut = i
+	}
+	return strings.TrimSpace(s[:cut])
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommitType(t *testing.T) {
+	tests := []struct {
+		task  string
+		files []string
+		want  string
+	}{
+		{"Add a dark mode toggle", nil, "feat"},
+		{"Fix the flaky date test", nil, "fix"},
+		{"Add tests for the parser", nil, "test"},
+		{"Refactor the session store", nil, "refactor"},
+		{"Bump lodash to 4.17.21", nil, "chore"},
+		{"Explain setup", []string{"README.md", "docs/setup.md"}, "docs"},
+		{"Add a prefix to routes", nil, "feat"}, // "prefix" doesn't contain the word "fix"
+	}
+	for _, tt := range tests {
+		if got := commitType(tt.task, tt.files); got != tt.want {
+			t.Errorf("commitType(%q) = %q, want %q", tt.task, got, tt.want)
+		}
+	}
+}
+
+func TestCommitScope(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  string
+	}{
+		{[]string{"web/app.ts", "web/lib/x.ts"}, "web"},
+		{[]string{"web/app.ts", "api/main.go"}, ""},
+		{[]string{"main.go"}, ""},
+		{[]string{".github/workflows/ci.yml"}, ""},
+	}
+	for _, tt := range tests {
+		if got := commitScope(tt.files); got != tt.want {
+			t.Errorf("commitScope(%v) = %q, want %q", tt.files, got, tt.want)
+		}
+	}
+}
+
+func TestCommitSpec_Message(t *testing.T) {
+	c := commitSpec{Task: "Fix the login redirect."}
+	if got := c.message([]string{"web/login.ts"}); got != "fix(web): fix the login redirect" {
+		t.Errorf("message = %q", got)
+	}
+
+	c = commitSpec{Task: "API: " + strings.Repeat("add endpoint ", 10), CoAuthor: &gitIdentity{Name: "Ada", Email: "ada@example.com"}}
+	got := c.message([]string{"main.go"})
+	subject, body, _ := strings.Cut(got, "\n\n")
+	if len(subject) > maxCommitSubject || !strings.HasPrefix(subject, "feat: API: add endpoint") || strings.HasSuffix(subject, " ") {
+		t.Errorf("subject = %q", subject)
+	}
+	if !strings.Contains(body, strings.TrimSpace(c.Task)) || !strings.HasSuffix(got, "\n\nCo-authored-by: Ada <ada@example.com>") {
+		t.Errorf("message = %q", got)
+	}
+}
+
+func TestGitIdentity_OrDefault(t *testing.T) {
+	if got := (gitIdentity{Name: "Release Bot"}).orDefault(); got != (gitIdentity{Name: "Release Bot", Email: "bob@noreply"}) {
+		t.Errorf("orDefault = %+v", got)
+	}
+}
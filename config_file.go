@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configVars are the environment variables Bob reads, and so the settings a
+// config file (BOB_CONFIG_FILE) can hold.
+var configVars = []string{
+	"SLACK_BOT_TOKEN", "SLACK_SIGNING_SECRET", "SLACK_APP_TOKEN", "DISCORD_BOT_TOKEN",
+	"TEAMS_OUTGOING_WEBHOOK_SECRET", "TEAMS_INCOMING_WEBHOOK_URL",
+	"ANTHROPIC_API_KEY", "OPENAI_API_KEY", "OPENAI_BASE_URL", "CLOUD_ML_REGION", "ANTHROPIC_VERTEX_PROJECT_ID",
+	"GITHUB_TOKEN", "GITHUB_OWNER", "GITHUB_ORG", "GITHUB_APP_ID", "GITHUB_APP_PRIVATE_KEY_PATH", "GITHUB_APP_INSTALLATION_ID",
+	"GITHUB_WEBHOOK_SECRET", "GITHUB_BOT_LOGIN", "GITLAB_URL", "GITLAB_TOKEN", "GITLAB_GROUP",
+	"AZURE_DEVOPS_URL", "AZURE_DEVOPS_TOKEN", "AZURE_DEVOPS_PROJECT",
+	"SENTRY_CLIENT_SECRET", "SENTRY_AUTH_TOKEN", "SENTRY_URL", "SENTRY_TAG",
+	"JIRA_URL", "JIRA_EMAIL", "JIRA_API_TOKEN", "JIRA_CRITERIA_FIELD", "JIRA_START_TRANSITION", "JIRA_REVIEW_TRANSITION",
+	"CLAUDE_CODE_OAUTH_TOKEN", "WORKSPACE_DIR", "REPO_ALLOWLIST", "REPO_BLOCKLIST", "ALLOWED_REPOS",
+	"MAX_INBOUND_MESSAGES_PER_MIN",
+	"BOB_URL", "BOB_LISTEN_ADDR", "BOB_UI_LISTEN_ADDR", "BOB_TLS_CERT", "BOB_TLS_KEY", "BOB_TLS_AUTOCERT_DOMAINS",
+	"BOB_API_TOKEN", "BOB_API_TOKENS", "BOB_OIDC_ISSUER", "BOB_OIDC_AUDIENCE", "BOB_ACK_MESSAGE",
+	"BOB_LOG_LEVEL", "BOB_LOG_FORMAT", "BOB_LLM_PROVIDER", "BOB_LLM_MODEL", "BOB_LLM_FALLBACK_MODELS", "BOB_LLM_THINKING_BUDGET",
+	"BOB_EVENT_STORE", "BOB_EVENT_COALESCE_MS", "BOB_RETENTION_DAYS", "BOB_RETENTION_MAX_GB", "BOB_ARCHIVE_UPLOAD_CMD",
+	"BOB_REPORT_CHANNEL", "BOB_REPORT_HOUR",
+	"BOB_ACCESS_FILE", "BOB_TOOL_POLICY_FILE", "BOB_GUIDELINES_FILE", "BOB_TEST_FIX_RETRIES", "BOB_MAX_JOB_COST_USD",
+	"BOB_WORKSPACE_MAX_GB", "BOB_JOB_WORKSPACE_MAX_GB", "BOB_WORKSPACE_GC_HOURS",
+	"BOB_SANDBOX_IMAGE", "BOB_SANDBOX_VOLUME", "BOB_SANDBOX_REPO_IMAGES", "BOB_ALLOW_HOST_TESTS", "BOB_SANDBOX_TEST_NETWORK",
+	"BOB_CLAUDE_TIMEOUT", "BOB_CLAUDE_MAX_TURNS", "BOB_CLAUDE_MODEL", "BOB_CLAUDE_FALLBACK_MODEL", "BOB_CLAUDE_FALLBACK_AFTER_USD",
+	"BOB_CLAUDE_REPO_TIMEOUTS", "BOB_CLAUDE_REPO_MAX_TURNS", "BOB_CLAUDE_REPO_MODELS", "BOB_STEP_TIMEOUTS", "BOB_SECURITY_SCANNERS",
+	"BOB_GIT_AUTHOR_NAME", "BOB_GIT_AUTHOR_EMAIL", "BOB_GIT_CO_AUTHOR",
+	"BOB_MAX_USER_JOBS_PER_DAY", "BOB_MAX_USER_COST_USD_PER_DAY", "BOB_CHECKS_AUTOFIX",
+	"BOB_WAIT_REMIND_HOURS", "BOB_WAIT_EXPIRE_HOURS", "BOB_WEBHOOK_SECRET", "BOB_WEBHOOK_URLS",
+}
+
+// secretConfigVars may only be given in a config file as a reference (${VAR}
+// or file:/path), so the file itself never holds a credential.
+var secretConfigVars = map[string]bool{
+	"SLACK_BOT_TOKEN": true, "SLACK_SIGNING_SECRET": true, "SLACK_APP_TOKEN": true, "DISCORD_BOT_TOKEN": true,
+	"TEAMS_OUTGOING_WEBHOOK_SECRET": true, "TEAMS_INCOMING_WEBHOOK_URL": true,
+	"ANTHROPIC_API_KEY": true, "OPENAI_API_KEY": true, "GITHUB_TOKEN": true, "GITHUB_WEBHOOK_SECRET": true,
+	"GITLAB_TOKEN": true, "AZURE_DEVOPS_TOKEN": true, "SENTRY_CLIENT_SECRET": true, "SENTRY_AUTH_TOKEN": true, "JIRA_API_TOKEN": true,
+	"CLAUDE_CODE_OAUTH_TOKEN": true, "BOB_API_TOKEN": true, "BOB_API_TOKENS": true, "BOB_WEBHOOK_SECRET": true,
+}
+
+// reloadableConfigVars take effect when the config file is reloaded (SIGHUP).
+// Changes to any other setting need a restart.
+var reloadableConfigVars = map[string]bool{
+	"BOB_LOG_LEVEL": true, "REPO_ALLOWLIST": true, "REPO_BLOCKLIST": true,
+	"MAX_INBOUND_MESSAGES_PER_MIN": true, "BOB_MAX_USER_JOBS_PER_DAY": true, "BOB_MAX_USER_COST_USD_PER_DAY": true,
+	"BOB_CLAUDE_TIMEOUT": true, "BOB_CLAUDE_MAX_TURNS": true, "BOB_CLAUDE_MODEL": true,
+	"BOB_CLAUDE_FALLBACK_MODEL": true, "BOB_CLAUDE_FALLBACK_AFTER_USD": true,
+	"BOB_CLAUDE_REPO_TIMEOUTS": true, "BOB_CLAUDE_REPO_MAX_TURNS": true, "BOB_CLAUDE_REPO_MODELS": true,
+}
+
+// configFile is a YAML file of settings applied to the environment, so the
+// rest of Bob reads them like any environment variable. Variables already set
+// in the environment win over the file.
+type configFile struct {
+	path   string
+	getenv func(string) string // the environment at startup, for ${VAR} references
+
+	mu      sync.Mutex
+	applied map[string]string // variables set from the file, and their values
+}
+
+// loadConfigFile reads the config file at path and applies it to the
+// environment.
+func loadConfigFile(path string) (*configFile, error) {
+	startup := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			startup[k] = v
+		}
+	}
+	c := &configFile{path: path, getenv: func(k string) string { return startup[k] }, applied: make(map[string]string)}
+	if _, err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload re-reads the file and applies it, returning the variables whose
+// value changed. Nothing is applied if the file is invalid.
+func (c *configFile) reload() ([]string, error) {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, err
+	}
+	values, err := parseConfigFile(data, c.getenv)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.path, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var changed []string
+	for _, name := range configVars {
+		v, inFile := values[name]
+		prev, wasApplied := c.applied[name]
+		if !wasApplied && c.getenv(name) != "" {
+			continue // set in the environment
+		}
+		switch {
+		case inFile && (!wasApplied || v != prev):
+			os.Setenv(name, v)
+			c.applied[name] = v
+		case !inFile && wasApplied:
+			os.Setenv(name, c.getenv(name))
+			delete(c.applied, name)
+		default:
+			continue
+		}
+		changed = append(changed, name)
+	}
+	return changed, nil
+}
+
+// watch reloads the file on SIGHUP until ctx is done, calling apply after
+// each successful reload. Invalid files are logged and ignored.
+func (c *configFile) watch(ctx context.Context, apply func()) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+		changed, err := c.reload()
+		if err != nil {
+			slog.Error("config: reload failed, keeping the current settings", "path", c.path, "err", err)
+			continue
+		}
+		var live, restart []string
+		for _, name := range changed {
+			if reloadableConfigVars[name] {
+				live = append(live, name)
+			} else {
+				restart = append(restart, name)
+			}
+		}
+		apply()
+		slog.Info("config: reloaded", "path", c.path, "changed", live)
+		if len(restart) > 0 {
+			slog.Warn("config: some changes take effect after a restart", "settings", restart)
+		}
+	}
+}
+
+// parseConfigFile maps a config file to environment variables. Keys are the
+// variable names in lower case, optionally nested on underscores and without
+// the BOB_ prefix, so these all set BOB_CLAUDE_TIMEOUT:
+//
+//	bob_claude_timeout: 30m
+//	claude_timeout: 30m
+//	claude: {timeout: 30m}
+//
+// Lists are joined with commas (repo: {allowlist: [web, api-*]}) and maps
+// become repo=value pairs (claude: {repo_models: {web: opus}}). A value of
+// ${VAR} is read from the environment and file:/path from a file; secrets
+// must be given one of these ways.
+func parseConfigFile(data []byte, getenv func(string) string) (map[string]string, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	known := make(map[string]bool, len(configVars))
+	for _, name := range configVars {
+		known[name] = true
+	}
+	values := make(map[string]string)
+	var walk func(prefix string, m map[string]any) error
+	walk = func(prefix string, m map[string]any) error {
+		for k, v := range m {
+			key := strings.ToUpper(strings.ReplaceAll(prefix+k, "-", "_"))
+			name := key
+			if !known[name] {
+				name = "BOB_" + key
+			}
+			if !known[name] {
+				nested, ok := v.(map[string]any)
+				if !ok {
+					return fmt.Errorf("unknown setting %q", prefix+k)
+				}
+				if err := walk(prefix+k+"_", nested); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, dup := values[name]; dup {
+				return fmt.Errorf("%s is set twice", name)
+			}
+			raw, err := configValue(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", prefix+k, err)
+			}
+			value, err := resolveConfigValue(raw, getenv)
+			if err != nil {
+				return fmt.Errorf("%s: %w", prefix+k, err)
+			}
+			if secretConfigVars[name] && value != "" && value == raw {
+				return fmt.Errorf("%s is a secret; reference it as ${%s} or file:/path instead", prefix+k, name)
+			}
+			values[name] = value
+		}
+		return nil
+	}
+	if err := walk("", doc); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// configValue renders a YAML value in the format of its environment variable.
+func configValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := configValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		pairs := make([]string, 0, len(v))
+		for k, item := range v {
+			s, err := configValue(item)
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, k+"="+s)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
+
+// resolveConfigValue expands a ${VAR} reference or reads a file:/path one.
+// Anything else is returned as is.
+func resolveConfigValue(raw string, getenv func(string) string) (string, error) {
+	if path, ok := strings.CutPrefix(raw, "file:"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	if strings.Contains(raw, "${") {
+		return os.Expand(raw, getenv), nil
+	}
+	return raw, nil
+}
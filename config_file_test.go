@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfigFile(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "gitlab")
+	os.WriteFile(secret, []byte("glpat-xyz\n"), 0o600)
+	env := map[string]string{"SLACK_BOT_TOKEN": "xoxb-1"}
+	data := []byte(`
+slack:
+  bot_token: ${SLACK_BOT_TOKEN}
+gitlab:
+  token: file:` + secret + `
+  group: acme
+claude:
+  timeout: 30m
+  max_turns: 40
+  repo_models: {web: opus, api: sonnet}
+repo:
+  allowlist: [web, api-*]
+max_inbound_messages_per_min: 10
+bob_checks_autofix: true
+wait_remind_hours: 1.5
+`)
+	got, err := parseConfigFile(data, func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatalf("parseConfigFile: %v", err)
+	}
+	want := map[string]string{
+		"SLACK_BOT_TOKEN":              "xoxb-1",
+		"GITLAB_TOKEN":                 "glpat-xyz",
+		"GITLAB_GROUP":                 "acme",
+		"BOB_CLAUDE_TIMEOUT":           "30m",
+		"BOB_CLAUDE_MAX_TURNS":         "40",
+		"BOB_CLAUDE_REPO_MODELS":       "api=sonnet,web=opus",
+		"REPO_ALLOWLIST":               "web,api-*",
+		"MAX_INBOUND_MESSAGES_PER_MIN": "10",
+		"BOB_CHECKS_AUTOFIX":           "true",
+		"BOB_WAIT_REMIND_HOURS":        "1.5",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+}
+
+func TestParseConfigFile_Errors(t *testing.T) {
+	for data, want := range map[string]string{
+		"github:\n  token: ghp_literal\n":             "is a secret",
+		"claude:\n  colour: blue\n":                   `unknown setting "claude_colour"`,
+		"claude_model: opus\nclaude: {model: opus}\n": "set twice",
+		"[not a map]": "parse config",
+	} {
+		_, err := parseConfigFile([]byte(data), os.Getenv)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: err = %v, want %q", data, err, want)
+		}
+	}
+}
+
+func TestConfigFile_Reload(t *testing.T) {
+	t.Setenv("BOB_CLAUDE_MODEL", "haiku") // the environment wins
+	t.Setenv("BOB_CLAUDE_TIMEOUT", "")
+	t.Setenv("BOB_LOG_LEVEL", "")
+	path := filepath.Join(t.TempDir(), "bob.yaml")
+	os.WriteFile(path, []byte("claude: {model: opus, timeout: 30m}\nlog_level: debug\n"), 0o644)
+
+	c, err := loadConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadConfigFile: %v", err)
+	}
+	if os.Getenv("BOB_CLAUDE_MODEL") != "haiku" || os.Getenv("BOB_CLAUDE_TIMEOUT") != "30m" || os.Getenv("BOB_LOG_LEVEL") != "debug" {
+		t.Fatalf("env = %q %q %q", os.Getenv("BOB_CLAUDE_MODEL"), os.Getenv("BOB_CLAUDE_TIMEOUT"), os.Getenv("BOB_LOG_LEVEL"))
+	}
+
+	os.WriteFile(path, []byte("claude: {model: opus, timeout: 45m}\n"), 0o644)
+	changed, err := c.reload()
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{"BOB_LOG_LEVEL", "BOB_CLAUDE_TIMEOUT"}) {
+		t.Errorf("changed = %v", changed)
+	}
+	if os.Getenv("BOB_CLAUDE_TIMEOUT") != "45m" || os.Getenv("BOB_LOG_LEVEL") != "" {
+		t.Errorf("env = %q %q", os.Getenv("BOB_CLAUDE_TIMEOUT"), os.Getenv("BOB_LOG_LEVEL"))
+	}
+
+	// An invalid file leaves the settings alone.
+	os.WriteFile(path, []byte("claude: {colour: blue}\n"), 0o644)
+	if _, err := c.reload(); err == nil || os.Getenv("BOB_CLAUDE_TIMEOUT") != "45m" {
+		t.Errorf("err = %v, timeout = %q", err, os.Getenv("BOB_CLAUDE_TIMEOUT"))
+	}
+}
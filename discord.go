@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	discordMaxMessage    = 2000 // characters per message
+	discordMaxThreadName = 100
+	discordThreadArchive = 1440 // minutes of inactivity before a thread auto-archives
+)
+
+var discordMentionRe = regexp.MustCompile(`<@!?[0-9]+>\s*`)
+
+// DiscordPlatform is the Discord ChatPlatform. Each job lives in a Discord
+// thread; the thread ID is used as both channel and thread in the context.
+type DiscordPlatform struct {
+	session *discordgo.Session
+}
+
+// NewDiscordPlatform creates a DiscordPlatform for a bot token. The bot needs
+// the Message Content privileged intent enabled in the developer portal.
+func NewDiscordPlatform(token string) (*DiscordPlatform, error) {
+	session, err := discordgo.New("Bot " + token)
+	if err != nil {
+		return nil, fmt.Errorf("discord session: %w", err)
+	}
+	session.Identify.Intents = discordgo.IntentsGuildMessages | discordgo.IntentsMessageContent
+	return &DiscordPlatform{session: session}, nil
+}
+
+// Name implements ChatPlatform.
+func (p *DiscordPlatform) Name() string { return "discord" }
+
+// Notify posts text to the thread in the context, split to fit Discord's
+// message size limit.
+func (p *DiscordPlatform) Notify(ctx context.Context, text string) error {
+	channel, _ := ctx.Value(ctxKeyChannel).(string)
+	if channel == "" {
+		return fmt.Errorf("notify: no discord channel in context")
+	}
+	for _, chunk := range splitMessage(text, discordMaxMessage) {
+		if _, err := p.session.ChannelMessageSend(channel, chunk, discordgo.WithContext(ctx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ThreadMessages implements ChatPlatform. Only the latest 100 messages are read.
+func (p *DiscordPlatform) ThreadMessages(ctx context.Context, _, thread string) ([]Message, error) {
+	msgs, err := p.session.ChannelMessages(thread, 100, "", "", "", discordgo.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return discordThreadToMessages(msgs, p.botUserID()), nil
+}
+
+// StripMention implements ChatPlatform.
+func (p *DiscordPlatform) StripMention(text string) string {
+	return strings.TrimSpace(discordMentionRe.ReplaceAllString(text, ""))
+}
+
+// MentionUser implements ChatPlatform.
+func (p *DiscordPlatform) MentionUser(userID string) string { return fmt.Sprintf("<@%s>", userID) }
+
+// botUserID returns Bob's Discord user ID, known once the gateway is ready.
+func (p *DiscordPlatform) botUserID() string {
+	if p.session.State == nil || p.session.State.User == nil {
+		return ""
+	}
+	return p.session.State.User.ID
+}
+
+// RunDiscord connects to the Discord gateway and handles messages that mention
+// Bob. Blocks until ctx is done.
+func RunDiscord(ctx context.Context, p *DiscordPlatform, orch *Orchestrator, hub *Hub, approver *Approver, bobURL, apiToken string, limits *userLimits) error {
+	p.session.AddHandler(func(s *discordgo.Session, m *discordgo.MessageCreate) {
+		if !mentionsBot(m.Message, p.botUserID()) {
+			return
+		}
+		slog.Info("discord: mention", "user", m.Author.ID, "channel", m.ChannelID, "text", m.Content)
+
+		if !limits.allow(p.Name(), m.Author.ID) {
+			slog.Warn("discord: rate limited mention", "user", m.Author.ID, "channel", m.ChannelID)
+			_, _ = s.ChannelMessageSend(m.ChannelID, fmt.Sprintf("<@%s> You're sending me requests faster than I can take them. Please try again in a moment.", m.Author.ID))
+			return
+		}
+
+		go func() {
+			mention, err := p.mentionThread(m.Message)
+			if err != nil {
+				slog.Error("discord: failed to start a thread", "channel", m.ChannelID, "err", err)
+				return
+			}
+			handleChatMention(p, orch, hub, approver, limits, bobURL, apiToken, mention)
+		}()
+	})
+
+	if err := p.session.Open(); err != nil {
+		return fmt.Errorf("discord open: %w", err)
+	}
+	slog.Info("discord: connected")
+	<-ctx.Done()
+	return p.session.Close()
+}
+
+// mentionThread resolves the thread a mention belongs to. Mentions in a
+// regular channel start a new thread on the message, like Slack threads.
+func (p *DiscordPlatform) mentionThread(m *discordgo.Message) (chatMention, error) {
+	mention := chatMention{User: m.Author.ID, UserName: m.Author.DisplayName(), Text: m.Content}
+
+	ch, err := p.session.State.Channel(m.ChannelID)
+	if err != nil {
+		if ch, err = p.session.Channel(m.ChannelID); err != nil {
+			return chatMention{}, fmt.Errorf("get channel %s: %w", m.ChannelID, err)
+		}
+	}
+	if ch.IsThread() {
+		mention.Channel, mention.Thread = ch.ID, ch.ID
+		return mention, nil
+	}
+
+	thread, err := p.session.MessageThreadStart(m.ChannelID, m.ID, discordThreadName(p.StripMention(m.Content)), discordThreadArchive)
+	if err != nil {
+		return chatMention{}, fmt.Errorf("start thread: %w", err)
+	}
+	mention.Channel, mention.Thread = thread.ID, thread.ID
+	mention.NewThread = true
+	return mention, nil
+}
+
+// mentionsBot reports whether a message from a human mentions the bot.
+func mentionsBot(m *discordgo.Message, botUserID string) bool {
+	if m.Author == nil || m.Author.Bot || botUserID == "" {
+		return false
+	}
+	for _, u := range m.Mentions {
+		if u.ID == botUserID {
+			return true
+		}
+	}
+	return false
+}
+
+// discordThreadName derives a thread name from the request text.
+func discordThreadName(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return "Bob"
+	}
+	if utf8.RuneCountInString(text) > discordMaxThreadName {
+		text = string([]rune(text)[:discordMaxThreadName-1]) + "…"
+	}
+	return text
+}
+
+// discordThreadToMessages converts thread history (newest first, as returned
+// by the API) into conversation messages, oldest first.
+func discordThreadToMessages(msgs []*discordgo.Message, botUserID string) []Message {
+	var messages []Message
+	for i := len(msgs) - 1; i >= 0; i-- {
+		msg := msgs[i]
+		text := strings.TrimSpace(discordMentionRe.ReplaceAllString(msg.Content, ""))
+		if text == "" {
+			continue
+		}
+		role := RoleUser
+		if msg.Author != nil && msg.Author.ID == botUserID {
+			role = RoleAssistant
+		}
+		messages = append(messages, Message{Role: role, Content: text})
+	}
+	return messages
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDiscordStripMention(t *testing.T) {
+	p := &DiscordPlatform{}
+	tests := []struct {
+		in, want string
+	}{
+		{"<@123> fix the bug", "fix the bug"},
+		{"<@!123> fix the bug", "fix the bug"},
+		{"hey <@123>", "hey"},
+		{"no mention", "no mention"},
+	}
+	for _, tt := range tests {
+		if got := p.StripMention(tt.in); got != tt.want {
+			t.Errorf("StripMention(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMentionsBot(t *testing.T) {
+	bot := &discordgo.User{ID: "bot"}
+	tests := []struct {
+		name string
+		msg  *discordgo.Message
+		want bool
+	}{
+		{"mentioned", &discordgo.Message{Author: &discordgo.User{ID: "u"}, Mentions: []*discordgo.User{bot}}, true},
+		{"not mentioned", &discordgo.Message{Author: &discordgo.User{ID: "u"}}, false},
+		{"from a bot", &discordgo.Message{Author: &discordgo.User{ID: "b2", Bot: true}, Mentions: []*discordgo.User{bot}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mentionsBot(tt.msg, "bot"); got != tt.want {
+				t.Errorf("mentionsBot = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscordThreadToMessages(t *testing.T) {
+	// The API returns newest first.
+	msgs := []*discordgo.Message{
+		{Author: &discordgo.User{ID: "u"}, Content: "<@999> yes, go ahead"},
+		{Author: &discordgo.User{ID: "999"}, Content: "Which repo?"},
+		{Author: &discordgo.User{ID: "u"}, Content: "<@123> fix the login bug"},
+		{Author: &discordgo.User{ID: "u"}, Content: "<@123>"},
+	}
+	got := discordThreadToMessages(msgs, "999")
+	want := []Message{
+		{Role: RoleUser, Content: "fix the login bug"},
+		{Role: RoleAssistant, Content: "Which repo?"},
+		{Role: RoleUser, Content: "yes, go ahead"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d messages, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("message %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestDiscordThreadName(t *testing.T) {
+	if got := discordThreadName("  fix\nthe   bug "); got != "fix the bug" {
+		t.Errorf("got %q", got)
+	}
+	if got := discordThreadName(""); got != "Bob" {
+		t.Errorf("empty text: got %q", got)
+	}
+	if got := discordThreadName(strings.Repeat("a", 150)); len([]rune(got)) != discordMaxThreadName {
+		t.Errorf("long text: got %d runes", len([]rune(got)))
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	// maxEventDiff bounds the diff carried in a diff_ready event; the full
+	// diff is the job's changes.diff artifact.
+	maxEventDiff = 200_000
+	// maxThreadDiff bounds the diff posted inline on platforms that can't
+	// attach files.
+	maxThreadDiff = 3_000
+)
+
+// pushTexts is the set of messages that push a dry run's changes.
+var pushTexts = map[string]bool{
+	"push it": true,
+	"push":    true,
+}
+
+func isPushText(text string) bool {
+	return pushTexts[strings.ToLower(strings.TrimSpace(text))]
+}
+
+// pendingPush is what publishing a dry run's changes needs from the
+// implementation step.
+type pendingPush struct {
+	FinalResponse string       // Claude Code's summary, the PR body
+	Verify        verifyResult // test results, noted in the PR body and reply
+	Scan          scanResult   // security findings, noted in the PR body and reply
+	StartTime     time.Time    // when implementation started, for the job's duration
+	// SecretsApproved is set once the user has seen the secret_scan findings
+	// and replied "push it" anyway.
+	SecretsApproved bool
+}
+
+// fileUploader is implemented by chat platforms that can attach a file to
+// the thread in ctx.
+type fileUploader interface {
+	UploadFile(ctx context.Context, name, content string) error
+}
+
+// awaitPush holds a dry run's implemented changes: it records the diff as the
+// job's artifact and in a diff_ready event, and waits in awaiting_push for
+// HandlePush. The worktree is kept as is until then.
+func (o *Orchestrator) awaitPush(ctx context.Context, jobID, repoDir string, p pendingPush) OrchestratorResult {
+	fail := func(text string, err error) OrchestratorResult {
+		o.closeJob(ctx, jobID, JobErrorData{
+			Error: err.Error(), TotalDurationMs: time.Since(p.StartTime).Milliseconds(),
+		})
+		return OrchestratorResult{IsJob: true, JobID: jobID, Text: text}
+	}
+	diff, err := WorktreeDiff(ctx, repoDir)
+	if err != nil {
+		return fail("Changes were implemented but I couldn't produce the diff: "+err.Error(), err)
+	}
+	if strings.TrimSpace(diff) == "" {
+		return fail("The dry run finished without changing any files, so there's nothing to push.", errors.New("no files changed"))
+	}
+	diff = redactSecrets(diff)
+	if err := o.hub.writeArtifact(jobID, artifactDiff, []byte(diff)); err != nil {
+		slog.ErrorContext(ctx, "orchestrator: failed to save dry-run diff", "job_id", jobID, "err", err)
+	}
+	ev := DiffReadyData{Diff: diff}
+	if len(diff) > maxEventDiff {
+		ev = DiffReadyData{Diff: prefix(diff, maxEventDiff), Truncated: true}
+	}
+	o.hub.Emit(jobID, ev)
+
+	state, _ := o.hub.GetJobState(jobID)
+	state.mu.Lock()
+	state.pending = &p
+	state.mu.Unlock()
+	o.hub.SetPhase(jobID, PhaseAwaitingPush)
+
+	return OrchestratorResult{IsJob: true, JobID: jobID, Text: formatDryRunMessage(p.Verify, p.Scan), Diff: diff}
+}
+
+// formatDryRunMessage tells the requester the dry run's changes are waiting.
+func formatDryRunMessage(vr verifyResult, scan scanResult) string {
+	var b strings.Builder
+	b.WriteString("Dry run: the changes are ready, but nothing has been committed or pushed.")
+	switch {
+	case vr.Command == "":
+	case vr.Passed:
+		fmt.Fprintf(&b, " `%s` passes.", vr.Command)
+	default:
+		fmt.Fprintf(&b, " `%s` still fails after %d fix attempt(s).", vr.Command, vr.Attempts)
+	}
+	if len(scan.Findings) > 0 {
+		fmt.Fprintf(&b, " Security scanners reported findings (%s); they'll be listed in the PR description.", findingScanners(scan.Findings))
+	}
+	b.WriteString(" Reply `push it` to commit them and open the pull request, describe what to change, or `cancel`.")
+	return b.String()
+}
+
+// HandlePush publishes a dry run's held changes, as HandleApproval would have
+// without the dry run. The caller must have won Hub.TryStartPush.
+func (o *Orchestrator) HandlePush(ctx context.Context, jobID string) (result OrchestratorResult, err error) {
+	state, ok := o.hub.GetJobState(jobID)
+	if !ok {
+		return OrchestratorResult{}, fmt.Errorf("no state for job %s", jobID)
+	}
+	state.mu.Lock()
+	p := state.pending
+	state.pending = nil
+	state.mu.Unlock()
+	if p == nil {
+		return OrchestratorResult{}, fmt.Errorf("job %s has no changes waiting to be pushed", jobID)
+	}
+
+	jobCtx, release := o.jobContext(ctx, jobID)
+	defer release()
+	defer o.replaceIfCancelled(jobID, &result, &err)
+	defer o.recoverJob(ctx, jobID, &result, &err)
+
+	cfg, err := o.repoConfig(jobCtx, jobID)
+	if err != nil {
+		o.closeJob(ctx, jobID, JobErrorData{Error: err.Error()})
+		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("I couldn't read the repo's %s: %s", repoConfigFile, err.Error())}, nil
+	}
+	return o.publishChanges(ctx, jobCtx, jobID, cfg, *p), nil
+}
+
+// Push publishes a dry run's changes, from a "push it" reply or the web UI.
+// Like Approve, it is safe to call from multiple goroutines.
+func (a *Approver) Push(ctx context.Context, jobID, channel, threadTS, approvedBy string) {
+	defer recoverGoroutine("push: job "+jobID, func() {
+		a.orchestrator.closeJob(ctx, jobID, JobErrorData{Error: "internal error"})
+		_ = a.notifier.Notify(ctx, panicReply)
+	})
+	if !a.hub.TryStartPush(jobID) {
+		slog.InfoContext(ctx, "push: no changes waiting to be pushed, ignoring", "job_id", jobID)
+		return
+	}
+	a.hub.Emit(jobID, PushApprovedData{ApprovedBy: approvedBy})
+
+	ctx = a.threadContext(ctx, jobID, channel, threadTS)
+	if err := a.notifier.Notify(ctx, "Pushing the changes..."); err != nil {
+		slog.ErrorContext(ctx, "push: failed to post pushing message", "job_id", jobID, "err", err)
+	}
+	result, err := a.orchestrator.HandlePush(ctx, jobID)
+	if err != nil {
+		slog.ErrorContext(ctx, "push: orchestrator error", "job_id", jobID, "err", err)
+	}
+	a.reportResult(ctx, jobID, channel, threadTS, result, err)
+}
+
+// postDiff shares a dry run's diff in the thread: as a file where the
+// platform supports it, otherwise inline and truncated.
+func (a *Approver) postDiff(ctx context.Context, jobID, diff string) {
+	if up, ok := a.platformNotifier(ctx).(fileUploader); ok {
+		err := up.UploadFile(ctx, artifactDiff, diff)
+		if err == nil {
+			return
+		}
+		slog.WarnContext(ctx, "approve: failed to upload diff", "job_id", jobID, "err", err)
+	}
+	text := diff
+	if len(text) > maxThreadDiff {
+		text = prefix(text, maxThreadDiff) + "\n… (truncated; the full diff is on the job page)"
+	}
+	if err := a.notifier.Notify(ctx, "```diff\n"+text+"\n```"); err != nil {
+		slog.ErrorContext(ctx, "approve: failed to post diff", "job_id", jobID, "err", err)
+	}
+}
+
+// UploadFile implements fileUploader as a Slack snippet in the thread (needs
+// the files:write scope).
+func (n *SlackPlatform) UploadFile(ctx context.Context, name, content string) error {
+	channel, _ := ctx.Value(ctxKeyChannel).(string)
+	threadTS, _ := ctx.Value(ctxKeyThreadTS).(string)
+	if channel == "" {
+		return fmt.Errorf("upload: no slack channel in context")
+	}
+	return uploadSlackSnippet(ctx, n.client, channel, threadTS, name, content)
+}
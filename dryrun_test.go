@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorktreeDiff(t *testing.T) {
+	dir := gitRepo(t, map[string]string{"main.go": "package main\n", "old.txt": "gone\n"})
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "new.go"), []byte("package main\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("TOKEN=secret\n"), 0o644)
+	os.Remove(filepath.Join(dir, "old.txt"))
+
+	diff, err := WorktreeDiff(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("WorktreeDiff: %v", err)
+	}
+	for _, want := range []string{"+func main() {}", "b/new.go", "-gone"} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("diff missing %q:\n%s", want, diff)
+		}
+	}
+	if strings.Contains(diff, "secret") {
+		t.Errorf("diff includes a secret file:\n%s", diff)
+	}
+
+	clean := gitRepo(t, map[string]string{"main.go": "package main\n"})
+	if diff, err := WorktreeDiff(context.Background(), clean); err != nil || diff != "" {
+		t.Errorf("clean worktree diff = %q, %v", diff, err)
+	}
+}
+
+func TestAwaitPush(t *testing.T) {
+	drainHub(t)
+	dir := t.TempDir()
+	hub := NewHub(dir)
+	o := &Orchestrator{hub: hub}
+	repoDir := gitRepo(t, map[string]string{"main.go": "package main\n"})
+	os.WriteFile(filepath.Join(repoDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644)
+	hub.SetJobState("job-1", &JobState{Repo: "web", Phase: PhaseImplementing, RepoDir: repoDir})
+
+	result := o.awaitPush(context.Background(), "job-1", repoDir, pendingPush{
+		FinalResponse: "Added main.", Verify: verifyResult{Command: "go test ./...", Passed: true}, StartTime: time.Now(),
+	})
+	if !strings.Contains(result.Diff, "+func main() {}") || !strings.Contains(result.Text, "`go test ./...` passes") || !strings.Contains(result.Text, "push it") {
+		t.Errorf("result = %+v", result)
+	}
+	state, _ := hub.GetJobState("job-1")
+	if state.Phase != PhaseAwaitingPush || state.pending == nil || state.pending.FinalResponse != "Added main." {
+		t.Errorf("phase = %s, pending = %+v", state.Phase, state.pending)
+	}
+	if data, err := os.ReadFile(filepath.Join(jobArtifactsDir(dir, "job-1"), artifactDiff)); err != nil || string(data) != result.Diff {
+		t.Errorf("diff artifact = %q, %v", data, err)
+	}
+
+	// Only one push wins.
+	if !hub.TryStartPush("job-1") || hub.TryStartPush("job-1") {
+		t.Error("TryStartPush should succeed exactly once")
+	}
+
+	// Let the event log catch up before the temp dir is removed.
+	for range 100 {
+		if data, _ := os.ReadFile(filepath.Join(dir, "job-1.jsonl")); strings.Contains(string(data), string(EventPhaseChanged)) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestIsPushText(t *testing.T) {
+	for text, want := range map[string]bool{"push it": true, " Push ": true, "go": false, "push it to main": false} {
+		if got := isPushText(text); got != want {
+			t.Errorf("isPushText(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// eventSchemaVersion is stamped on every emitted event. Bump it when a payload
+// changes incompatibly (a field removed, renamed or retyped); adding optional
+// fields doesn't need a bump. Events stored before versioning decode as 0 and
+// share version 1's field names.
+const eventSchemaVersion = 1
+
+// EventData is the typed payload of an Event. Each event type has one
+// payload struct, whose JSON field names are the event's public schema.
+type EventData interface {
+	EventType() EventType
+}
+
+// JobStartedData is the payload of job_started.
+type JobStartedData struct {
+	Task            string `json:"task"`
+	Repo            string `json:"repo"`
+	Phase           string `json:"phase"`
+	SlackThreadURL  string `json:"slack_thread_url"`
+	Channel         string `json:"channel"`
+	ThreadTS        string `json:"thread_ts"`
+	Platform        string `json:"platform"`
+	RequestedBy     string `json:"requested_by,omitempty"`      // chat user ID of the requester; empty for webhook-started jobs
+	RequestedByName string `json:"requested_by_name,omitempty"` // the requester's display name, if the platform gave it
+	VCSProvider     string `json:"vcs_provider"`
+	IntentModel     string `json:"intent_model"`
+	CLIVersion      string `json:"cli_version"`
+	RerunOf         string `json:"rerun_of,omitempty"` // the failed job this one re-runs
+}
+
+// LLMResponseData is the payload of llm_response: the usage and cost of one
+// LLM call or Claude Code session.
+type LLMResponseData struct {
+	Model            string  `json:"model,omitempty"` // the model that served the call, after any fallback
+	StopReason       string  `json:"stop_reason"`
+	Summary          string  `json:"summary"`
+	InputTokens      int64   `json:"input_tokens"`
+	OutputTokens     int64   `json:"output_tokens"`
+	CacheReadTokens  int64   `json:"cache_read_tokens"`
+	CacheWriteTokens int64   `json:"cache_write_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	Thinking         string  `json:"thinking,omitempty"` // extended thinking summary (BOB_LLM_THINKING_BUDGET)
+}
+
+// LLMRetryData is the payload of llm_retry, emitted when an LLM call is rate
+// limited or overloaded and will be retried after WaitMs.
+type LLMRetryData struct {
+	Provider string `json:"provider"`
+	Attempt  int    `json:"attempt"` // the failed attempt, 1-based
+	WaitMs   int64  `json:"wait_ms"`
+	Error    string `json:"error"`
+}
+
+// ToolStartedData is the payload of tool_started, emitted when a job step begins.
+type ToolStartedData struct {
+	ToolName string `json:"tool_name"`
+	Input    string `json:"input"`
+	Source   string `json:"source,omitempty"` // run_tests: where the command came from
+}
+
+// ToolCompletedData is the payload of tool_completed.
+type ToolCompletedData struct {
+	ToolName      string `json:"tool_name"`
+	IsError       bool   `json:"is_error"`
+	ResultPreview string `json:"result_preview"`
+	DurationMs    int64  `json:"duration_ms"`
+}
+
+// ClaudeCodeLineData is the payload of claude_code_line. Exactly one kind is
+// set: a text line, a thinking block, a tool call or a tool error (or, in jobs
+// from before agent_finished, finished sub-agents). Thinking and ToolError are
+// pointers because an empty value is still that kind of line. AgentID is set on
+// a sub-agent's lines.
+type ClaudeCodeLineData struct {
+	Text           string         `json:"text,omitempty"`
+	Thinking       *string        `json:"thinking,omitempty"`
+	ThinkingTS     int64          `json:"thinking_ts,omitempty"` // Unix ms
+	ToolName       string         `json:"tool_name,omitempty"`
+	ToolInput      string         `json:"tool_input,omitempty"` // raw JSON tool input
+	ToolError      *string        `json:"tool_error,omitempty"`
+	AgentID        string         `json:"agent_id,omitempty"` // the Task call that started the sub-agent
+	AgentsFinished int            `json:"agents_finished,omitempty"`
+	Agents         []AgentSummary `json:"agents,omitempty"`
+}
+
+// ClaudeCodeLinesData is the payload of claude_code_lines: consecutive
+// claude_code_line payloads batched by the Hub (see BOB_EVENT_COALESCE_MS).
+type ClaudeCodeLinesData struct {
+	Lines []ClaudeCodeLineData `json:"lines"`
+}
+
+// AgentSummary describes a finished Claude Code sub-agent (Task tool) in
+// older jobs' claude_code_line events.
+type AgentSummary struct {
+	Description string `json:"description"`
+}
+
+// AgentStartedData is the payload of agent_started: Claude Code started a
+// sub-agent (Task tool). Its output follows as claude_code_line events with
+// AgentID set, possibly interleaved with other agents'.
+type AgentStartedData struct {
+	AgentID     string `json:"agent_id"`            // the Task call's tool_use ID
+	ParentID    string `json:"parent_id,omitempty"` // the sub-agent that started it, if not the main session
+	Description string `json:"description"`
+	AgentType   string `json:"agent_type,omitempty"` // the Task call's subagent_type
+}
+
+// AgentFinishedData is the payload of agent_finished: a sub-agent returned
+// its result.
+type AgentFinishedData struct {
+	AgentID     string         `json:"agent_id"`
+	Description string         `json:"description"`
+	AgentType   string         `json:"agent_type,omitempty"`
+	ToolCalls   map[string]int `json:"tool_calls,omitempty"` // by tool name
+	DurationMs  int64          `json:"duration_ms"`
+	IsError     bool           `json:"is_error,omitempty"`
+}
+
+// totalToolCalls returns the number of tool calls the sub-agent made.
+func (d AgentFinishedData) totalToolCalls() int {
+	n := 0
+	for _, c := range d.ToolCalls {
+		n += c
+	}
+	return n
+}
+
+// PlanGeneratedData is the payload of plan_generated.
+type PlanGeneratedData struct {
+	Plan    string `json:"plan"`
+	Changes string `json:"changes,omitempty"` // Markdown summary of changes from the previous plan
+}
+
+// PlanApprovedData is the payload of plan_approved.
+type PlanApprovedData struct {
+	ApprovedBy string `json:"approved_by"`
+}
+
+// DiffReadyData is the payload of diff_ready: a dry run's implemented changes,
+// held until someone pushes them.
+type DiffReadyData struct {
+	Diff      string `json:"diff"`                // unified diff, truncated to maxEventDiff
+	Truncated bool   `json:"truncated,omitempty"` // the full diff is the changes.diff artifact
+}
+
+// PushApprovedData is the payload of push_approved.
+type PushApprovedData struct {
+	ApprovedBy string `json:"approved_by"`
+}
+
+// PlanSupersededData is the (empty) payload of plan_superseded.
+type PlanSupersededData struct{}
+
+// PhaseChangedData is the payload of phase_changed.
+type PhaseChangedData struct {
+	Phase string `json:"phase"`
+}
+
+// JobCompletedData is the payload of job_completed.
+type JobCompletedData struct {
+	FinalResponse   string   `json:"final_response,omitempty"`
+	PRURL           string   `json:"pr_url,omitempty"`
+	TestsPassed     *bool    `json:"tests_passed,omitempty"` // nil when the job didn't run tests
+	TotalDurationMs int64    `json:"total_duration_ms,omitempty"`
+	TotalCostUSD    float64  `json:"total_cost_usd,omitempty"`
+	Artifacts       []string `json:"artifacts,omitempty"` // names under /api/jobs/{id}/artifacts/
+}
+
+// JobErrorData is the payload of job_error.
+type JobErrorData struct {
+	Error             string  `json:"error"`
+	BudgetExceeded    bool    `json:"budget_exceeded,omitempty"`
+	DiskQuotaExceeded bool    `json:"disk_quota_exceeded,omitempty"`
+	TotalDurationMs   int64   `json:"total_duration_ms,omitempty"`
+	TotalCostUSD      float64 `json:"total_cost_usd,omitempty"`
+}
+
+// WorkspaceUsageData is the payload of workspace_usage: the size of the
+// job's worktree after a step.
+type WorkspaceUsageData struct {
+	Bytes    int64 `json:"bytes"`
+	MaxBytes int64 `json:"max_bytes,omitempty"` // the per-job quota; 0 if there is none
+}
+
+// JobCancelledData is the payload of job_cancelled.
+type JobCancelledData struct {
+	CancelledBy string `json:"cancelled_by,omitempty"`
+}
+
+// JobRemindedData is the payload of job_reminded.
+type JobRemindedData struct {
+	Phase  string `json:"phase"`   // what the job is waiting for
+	IdleMs int64  `json:"idle_ms"` // time since the job's last activity
+}
+
+// JobExpiredData is the payload of job_expired, a terminal event.
+type JobExpiredData struct {
+	Phase  string `json:"phase"`
+	IdleMs int64  `json:"idle_ms"`
+}
+
+// JobMetadataData is the payload of job_metadata: the model and CLI version
+// reported by a Claude Code session.
+type JobMetadataData struct {
+	Model      string `json:"model"`
+	CLIVersion string `json:"cli_version"`
+}
+
+// WorkPreservedData is the payload of work_preserved: uncommitted changes found
+// in a job's worktree were saved before it was reset.
+type WorkPreservedData struct {
+	Stash string   `json:"stash"`           // stash commit in the repo's base clone
+	Patch string   `json:"patch,omitempty"` // patch file under the job's artifacts
+	Files []string `json:"files"`
+}
+
+// ChecksCompletedData is the payload of checks_completed, emitted once every
+// CI check on a PR Bob pushed to has finished. It may follow the job's
+// terminal event.
+type ChecksCompletedData struct {
+	Conclusion string        `json:"conclusion"` // success or failure
+	Checks     []CheckResult `json:"checks"`
+}
+
+// CheckResult is one check run in checks_completed.
+type CheckResult struct {
+	Name       string `json:"name"`
+	Conclusion string `json:"conclusion"`
+	URL        string `json:"url,omitempty"`
+}
+
+// SecurityScanData is the payload of security_scan, emitted after each
+// security scan of a job's changes.
+type SecurityScanData struct {
+	Scanners []string          `json:"scanners"`            // scanners that ran
+	Findings []SecurityFinding `json:"findings,omitempty"`  // those that reported something
+	AfterFix bool              `json:"after_fix,omitempty"` // the rescan after Claude Code's fix pass
+}
+
+// SecretScanData is the payload of secret_scan, the check for credentials in
+// a job's changes before they are committed. Findings hold the push until
+// the user says how to proceed.
+type SecretScanData struct {
+	Findings []SecretFinding `json:"findings,omitempty"`
+}
+
+// PromptInjectionData is the payload of prompt_injection, emitted when
+// the request or what a planning session read looks like it instructs the
+// agent. The plan then waits for a person to approve it.
+type PromptInjectionData struct {
+	Findings []InjectionFinding `json:"findings"`
+}
+
+// SecurityFinding is one scanner's report in security_scan.
+type SecurityFinding struct {
+	Scanner string `json:"scanner"`
+	Command string `json:"command"`
+	Output  string `json:"output"` // redacted, and truncated to maxScanOutput from the end
+}
+
+// JobRerunData is the payload of job_rerun, emitted on a failed job when its
+// request is started again as NewJobID.
+type JobRerunData struct {
+	NewJobID string `json:"new_job_id"`
+	RerunBy  string `json:"rerun_by"`
+}
+
+// UnknownEventData keeps the payload of an event type this build doesn't know,
+// so it survives a round trip through the store unchanged.
+type UnknownEventData struct {
+	Type   EventType
+	Fields map[string]any
+}
+
+func (JobStartedData) EventType() EventType      { return EventJobStarted }
+func (LLMResponseData) EventType() EventType     { return EventLLMResponse }
+func (LLMRetryData) EventType() EventType        { return EventLLMRetry }
+func (ToolStartedData) EventType() EventType     { return EventToolStarted }
+func (ToolCompletedData) EventType() EventType   { return EventToolCompleted }
+func (ClaudeCodeLineData) EventType() EventType  { return EventClaudeCodeLine }
+func (ClaudeCodeLinesData) EventType() EventType { return EventClaudeCodeLines }
+func (AgentStartedData) EventType() EventType    { return EventAgentStarted }
+func (AgentFinishedData) EventType() EventType   { return EventAgentFinished }
+func (PlanGeneratedData) EventType() EventType   { return EventPlanGenerated }
+func (PlanApprovedData) EventType() EventType    { return EventPlanApproved }
+func (DiffReadyData) EventType() EventType       { return EventDiffReady }
+func (PushApprovedData) EventType() EventType    { return EventPushApproved }
+func (PlanSupersededData) EventType() EventType  { return EventPlanSuperseded }
+func (PhaseChangedData) EventType() EventType    { return EventPhaseChanged }
+func (JobCompletedData) EventType() EventType    { return EventJobCompleted }
+func (JobErrorData) EventType() EventType        { return EventJobError }
+func (JobCancelledData) EventType() EventType    { return EventJobCancelled }
+func (JobRemindedData) EventType() EventType     { return EventJobReminded }
+func (JobExpiredData) EventType() EventType      { return EventJobExpired }
+func (JobMetadataData) EventType() EventType     { return EventJobMetadata }
+func (WorkPreservedData) EventType() EventType   { return EventWorkPreserved }
+func (ChecksCompletedData) EventType() EventType { return EventChecksCompleted }
+func (SecurityScanData) EventType() EventType    { return EventSecurityScan }
+func (WorkspaceUsageData) EventType() EventType  { return EventWorkspaceUsage }
+func (SecretScanData) EventType() EventType      { return EventSecretScan }
+func (JobRerunData) EventType() EventType        { return EventJobRerun }
+func (PromptInjectionData) EventType() EventType { return EventPromptInjection }
+func (d UnknownEventData) EventType() EventType  { return d.Type }
+
+func (d UnknownEventData) MarshalJSON() ([]byte, error) { return json.Marshal(d.Fields) }
+
+// decodeEventData decodes the JSON payload of an event of type t. A null or
+// missing payload decodes to the type's zero value.
+func decodeEventData(t EventType, raw json.RawMessage) (EventData, error) {
+	switch t {
+	case EventJobStarted:
+		return decodeAs[JobStartedData](raw)
+	case EventLLMResponse:
+		return decodeAs[LLMResponseData](raw)
+	case EventLLMRetry:
+		return decodeAs[LLMRetryData](raw)
+	case EventToolStarted:
+		return decodeAs[ToolStartedData](raw)
+	case EventToolCompleted:
+		return decodeAs[ToolCompletedData](raw)
+	case EventClaudeCodeLine:
+		return decodeAs[ClaudeCodeLineData](raw)
+	case EventClaudeCodeLines:
+		return decodeAs[ClaudeCodeLinesData](raw)
+	case EventAgentStarted:
+		return decodeAs[AgentStartedData](raw)
+	case EventAgentFinished:
+		return decodeAs[AgentFinishedData](raw)
+	case EventPlanGenerated:
+		return decodeAs[PlanGeneratedData](raw)
+	case EventPlanApproved:
+		return decodeAs[PlanApprovedData](raw)
+	case EventDiffReady:
+		return decodeAs[DiffReadyData](raw)
+	case EventPushApproved:
+		return decodeAs[PushApprovedData](raw)
+	case EventPlanSuperseded:
+		return decodeAs[PlanSupersededData](raw)
+	case EventPhaseChanged:
+		return decodeAs[PhaseChangedData](raw)
+	case EventJobCompleted:
+		return decodeAs[JobCompletedData](raw)
+	case EventJobError:
+		return decodeAs[JobErrorData](raw)
+	case EventJobCancelled:
+		return decodeAs[JobCancelledData](raw)
+	case EventJobReminded:
+		return decodeAs[JobRemindedData](raw)
+	case EventJobExpired:
+		return decodeAs[JobExpiredData](raw)
+	case EventJobMetadata:
+		return decodeAs[JobMetadataData](raw)
+	case EventWorkPreserved:
+		return decodeAs[WorkPreservedData](raw)
+	case EventChecksCompleted:
+		return decodeAs[ChecksCompletedData](raw)
+	case EventSecurityScan:
+		return decodeAs[SecurityScanData](raw)
+	case EventWorkspaceUsage:
+		return decodeAs[WorkspaceUsageData](raw)
+	case EventSecretScan:
+		return decodeAs[SecretScanData](raw)
+	case EventPromptInjection:
+		return decodeAs[PromptInjectionData](raw)
+	case EventJobRerun:
+		return decodeAs[JobRerunData](raw)
+	}
+	d := UnknownEventData{Type: t}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &d.Fields); err != nil {
+			return d, err
+		}
+	}
+	return d, nil
+}
+
+func decodeAs[T EventData](raw json.RawMessage) (EventData, error) {
+	var d T
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &d); err != nil {
+			return d, err
+		}
+	}
+	return d, nil
+}
+
+// UnmarshalJSON decodes an event, including its payload into the struct for
+// its type.
+func (e *Event) UnmarshalJSON(b []byte) error {
+	var aux struct {
+		ID            string          `json:"id"`
+		JobID         string          `json:"job_id"`
+		Type          EventType       `json:"type"`
+		Timestamp     time.Time       `json:"timestamp"`
+		SchemaVersion int             `json:"schema_version"`
+		Data          json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	data, err := decodeEventData(aux.Type, aux.Data)
+	if err != nil {
+		return err
+	}
+	*e = Event{ID: aux.ID, JobID: aux.JobID, Type: aux.Type, Timestamp: aux.Timestamp, SchemaVersion: aux.SchemaVersion, Data: data}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEvent_JSONRoundTrip(t *testing.T) {
+	passed := true
+	thinking := ""
+	ts := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, data := range []EventData{
+		JobStartedData{Task: "fix login", Repo: "web", IntentModel: "haiku"},
+		LLMResponseData{InputTokens: 1200, OutputTokens: 30, CostUSD: 0.012},
+		ToolStartedData{ToolName: "run_tests", Input: "go test ./...", Source: "go.mod"},
+		ClaudeCodeLineData{Thinking: &thinking, ThinkingTS: 1735732800000},
+		ClaudeCodeLineData{AgentsFinished: 1, Agents: []AgentSummary{{Description: "explore"}}},
+		ClaudeCodeLineData{ToolName: "Read", AgentID: "toolu_1"},
+		AgentStartedData{AgentID: "toolu_1", Description: "explore", AgentType: "Explore"},
+		AgentFinishedData{AgentID: "toolu_1", Description: "explore", ToolCalls: map[string]int{"Read": 3}, DurationMs: 4200},
+		PlanSupersededData{},
+		JobCompletedData{PRURL: "https://github.com/org/web/pull/7", TestsPassed: &passed, TotalCostUSD: 1.5},
+		JobErrorData{Error: "budget exceeded", BudgetExceeded: true},
+		WorkspaceUsageData{Bytes: 3 << 30, MaxBytes: 5 << 30},
+	} {
+		in := Event{ID: "e1", JobID: "job-1", Type: data.EventType(), Timestamp: ts, SchemaVersion: eventSchemaVersion, Data: data}
+		b, err := json.Marshal(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out Event
+		if err := json.Unmarshal(b, &out); err != nil {
+			t.Fatalf("unmarshal %s: %v", b, err)
+		}
+		if !reflect.DeepEqual(out, in) {
+			t.Errorf("round trip of %s:\n got %+v\nwant %+v", b, out, in)
+		}
+	}
+}
+
+func TestEvent_UnmarshalLegacy(t *testing.T) {
+	// Events stored before versioning have no schema_version.
+	var e Event
+	raw := `{"id":"e1","job_id":"job-1","type":"llm_response","timestamp":"2025-01-01T12:00:00Z","data":{"input_tokens":1200,"output_tokens":30,"cost_usd":0.5}}`
+	if err := json.Unmarshal([]byte(raw), &e); err != nil {
+		t.Fatal(err)
+	}
+	want := LLMResponseData{InputTokens: 1200, OutputTokens: 30, CostUSD: 0.5}
+	if e.SchemaVersion != 0 || e.Data != want {
+		t.Errorf("got version %d data %+v", e.SchemaVersion, e.Data)
+	}
+
+	raw = `{"id":"e2","job_id":"job-1","type":"job_cancelled","timestamp":"2025-01-01T12:00:00Z","data":null}`
+	if err := json.Unmarshal([]byte(raw), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Data != (JobCancelledData{}) {
+		t.Errorf("null data = %+v", e.Data)
+	}
+}
+
+func TestEvent_UnknownType(t *testing.T) {
+	raw := `{"id":"e1","job_id":"job-1","type":"from_the_future","timestamp":"2025-01-01T12:00:00Z","schema_version":2,"data":{"widgets":3}}`
+	var e Event
+	if err := json.Unmarshal([]byte(raw), &e); err != nil {
+		t.Fatal(err)
+	}
+	d, ok := e.Data.(UnknownEventData)
+	if !ok || d.EventType() != "from_the_future" || d.Fields["widgets"] != 3.0 {
+		t.Fatalf("data = %#v", e.Data)
+	}
+	b, err := json.Marshal(e.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"widgets":3}` {
+		t.Errorf("re-encoded data = %s", b)
+	}
+}
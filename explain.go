@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// startExplain answers a question about a repo's code with a read-only Claude
+// Code session on its base branch and replies with the answer. The job has no
+// plan, branch or pull request.
+func (o *Orchestrator) startExplain(ctx context.Context, intent IntentResult, onJobCreated func(jobID string)) (result OrchestratorResult, err error) {
+	if reject := o.validateIntent(ctx, &intent); reject != "" {
+		return OrchestratorResult{Text: reject}, nil
+	}
+
+	vcs, r, err := findRepo(ctx, o.providers, intent.Repo)
+	if err != nil {
+		return OrchestratorResult{Text: fmt.Sprintf("I couldn't find the repository *%s*. Please check the repository name and try again.", intent.Repo)}, nil
+	}
+	if r.Name != "" && r.Name != intent.Repo {
+		// Matched loosely; continue with the canonical name.
+		intent.Repo = r.Name
+		if reject := o.validateIntent(ctx, &intent); reject != "" {
+			return OrchestratorResult{Text: reject}, nil
+		}
+	}
+
+	channel, _ := ctx.Value(ctxKeyChannel).(string)
+	threadTS, _ := ctx.Value(ctxKeyThreadTS).(string)
+
+	jobID := o.createJob(intent, vcs, channel, threadTS, PlatformFromCtx(ctx), UserFromCtx(ctx), UserNameFromCtx(ctx), nil)
+	if onJobCreated != nil {
+		onJobCreated(jobID)
+	}
+	jobCtx, release := o.jobContext(ctx, jobID)
+	defer release()
+	defer o.replaceIfCancelled(jobID, &result, &err)
+	defer o.recoverJob(ctx, jobID, &result, &err)
+	o.hub.SetPhase(jobID, PhaseExplaining)
+
+	startTime := time.Now()
+	fail := func(text string, err error) (OrchestratorResult, error) {
+		o.closeJob(ctx, jobID, JobErrorData{
+			Error: err.Error(), TotalDurationMs: time.Since(startTime).Milliseconds(),
+		})
+		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("%s: %s", text, err.Error())}, nil
+	}
+
+	unlockClone := lockBaseClone(intent.Repo)
+	defer unlockClone()
+	baseDir, cfg, _, err := o.prepareBaseClone(jobCtx, vcs, intent.Repo, r.DefaultBranch, intent.BaseBranch)
+	if err != nil {
+		return fail("I ran into an error cloning the repository", err)
+	}
+	repoDir, err := CreateWorktree(jobCtx, baseDir, jobID)
+	unlockClone()
+	if err != nil {
+		return fail("Failed to create worktree", err)
+	}
+	state, _ := o.hub.GetJobState(jobID)
+	state.mu.Lock()
+	state.RepoDir = repoDir
+	state.BaseDir = baseDir
+	state.mu.Unlock()
+
+	slog.InfoContext(jobCtx, "orchestrator: explaining", "question", intent.Task)
+	var sr *SessionResult
+	_, err = o.runStep(jobCtx, jobID, "explain_code", intent.Task, func(ctx context.Context) (string, error) {
+		var err error
+		sr, err = RunSession(ctx, o.claudeCodeToken, o.hub, jobID, SessionOpts{
+			RepoDir:        repoDir,
+			Prompt:         explainPrompt(intent.Task, cfg),
+			SystemPrompt:   explainSystemPrompt,
+			PermissionMode: "plan",
+			Tools:          o.toolRules(cfg, toolPhaseRead),
+			Sandbox:        o.sandboxFor(intent.Repo, cfg),
+			Limits:         o.sessionLimits(ctx, jobID, intent.Repo),
+		})
+		if err == nil && sr.IsError {
+			err = errors.New(sr.ResultText)
+		}
+		return sessionPreview(sr, err)
+	})
+	if err != nil {
+		return fail("Claude Code encountered an error while exploring the code", err)
+	}
+
+	if cost, over := o.overBudget(jobID); over {
+		return o.abortOverBudget(ctx, jobID, cost), nil
+	}
+
+	answer := strings.TrimSpace(sr.ResultText)
+	if answer == "" {
+		answer = "I explored the code but couldn't come up with an answer. Try rephrasing the question or pointing me at a file."
+	}
+	o.closeJob(ctx, jobID, JobCompletedData{
+		FinalResponse:   answer,
+		TotalDurationMs: time.Since(startTime).Milliseconds(),
+	})
+	o.hub.SetPhase(jobID, PhaseDone)
+	return OrchestratorResult{IsJob: true, JobID: jobID, Text: answer}, nil
+}
+
+// explainPrompt is the question for an explain session, scoped to the repo's
+// configured paths if it has any.
+func explainPrompt(question string, cfg *RepoConfig) string {
+	prompt := "## Question\n\n" + question
+	if cfg != nil && len(cfg.Paths) > 0 {
+		prompt += "\n\n## Scope\n\nThis team works in: " + strings.Join(cfg.Paths, ", ") + ". Focus on these paths unless the question is about other parts of the repo."
+	}
+	return prompt
+}
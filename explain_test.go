@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestHandleNewRequest_Explain(t *testing.T) {
+	drainHub(t)
+	llm := &fakeLLM{resp: LLMResponse{Text: `{"repo":"payments-api","task":"How does auth work?","explain":true,"summary":"Explain auth in payments-api.","confidence":0.5}`}}
+	o := &Orchestrator{llm: llm, hub: NewHub(t.TempDir())}
+	ctx := WithSlackThread(context.Background(), "C1", "ts1")
+
+	result, err := o.HandleNewRequest(ctx, []Message{{Role: RoleUser, Content: "how does auth work in payments-api?"}}, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Questions don't change anything, so they start without a confirmation.
+	if !strings.Contains(result.Text, "couldn't find the repository *payments-api*") {
+		t.Errorf("result = %+v, want the explain job to be started", result)
+	}
+	if _, ok := o.hub.TakePendingIntent("C1", "ts1"); ok {
+		t.Error("explain requests should not wait for confirmation")
+	}
+}
+
+func TestExplainPrompt(t *testing.T) {
+	if got := explainPrompt("Where are invoices sent?", nil); got != "## Question\n\nWhere are invoices sent?" {
+		t.Errorf("prompt = %q", got)
+	}
+	got := explainPrompt("Where are invoices sent?", &RepoConfig{Paths: []string{"services/billing", "libs/mail"}})
+	if !strings.Contains(got, "services/billing, libs/mail") {
+		t.Errorf("scoped prompt = %q", got)
+	}
+}
@@ -1,17 +1,16 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 )
 
 // sanitizeGitOutput removes embedded credentials from git command output.
@@ -62,7 +61,7 @@ func changedFiles(ctx context.Context, repoDir string) ([]string, error) {
 		}
 		seen[f] = true
 		if isSecretFile(f) {
-			log.Printf("WARNING: skipping potentially sensitive file: %s", f)
+			slog.WarnContext(ctx, "git: skipping potentially sensitive file", "file", f)
 			continue
 		}
 		files = append(files, f)
@@ -71,78 +70,174 @@ func changedFiles(ctx context.Context, repoDir string) ([]string, error) {
 }
 
 type repo struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	CloneURL    string `json:"clone_url"`
-	Private     bool   `json:"private"`
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	CloneURL      string `json:"clone_url"`
+	Private       bool   `json:"private"`
+	DefaultBranch string `json:"default_branch"`
 }
 
-// FindRepo checks whether a repository exists in the GitHub owner's org/account.
-func FindRepo(ctx context.Context, token, owner, name string) (repo, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s", owner, name)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return repo{}, fmt.Errorf("create request: %w", err)
+// baseCloneLocks serializes jobs' use of a base clone. FETCH_HEAD is shared:
+// between a job's fetch and the worktree it creates from FETCH_HEAD, another
+// job's fetch would move it.
+var baseCloneLocks sync.Map // base clone dir → *sync.Mutex
+
+// repoDirName returns the directory name for repoName's base clone and
+// mirror: the repo's name, prefixed with "owner@" for another owner's repo
+// ("other-org/api" → "other-org@api"), so same-named repos don't share one.
+func repoDirName(repoName string) string {
+	owner, short := splitRepoName(repoName)
+	if owner == "" || owner == "." {
+		return filepath.Base(short)
+	}
+	return owner + "@" + short
+}
+
+// lockBaseClone locks repoName's base clone until the returned function is
+// called; calling it again is a no-op, so it can also be deferred. Hold it
+// from EnsureBaseClone until the job's worktree exists.
+func lockBaseClone(repoName string) (unlock func()) {
+	v, _ := baseCloneLocks.LoadOrStore(workspacePath(repoDirName(repoName)), &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return sync.OnceFunc(mu.Unlock)
+}
+
+// EnsureBaseClone ensures a base clone exists at <workspace>/<repoDirName>, cloned
+// from the repo's local mirror (or shallow from the remote if the mirror can't
+// be updated), and fetches the latest defaultBranch (main if empty), leaving
+// FETCH_HEAD at its tip. The base clone is never used directly by jobs; worktrees are
+// created from it instead. A base clone that is no longer a valid repository,
+// or whose objects turn out to be corrupt, is removed and cloned again.
+// Callers hold lockBaseClone.
+func EnsureBaseClone(ctx context.Context, vcs VCSProvider, repoName, defaultBranch string) (baseDir string, err error) {
+	if !repoRules.Load().permits(repoName) {
+		return "", errRepoNotPermitted(repoName)
+	}
+	baseDir = workspacePath(repoDirName(repoName))
+	fetchURL := vcs.FetchURL(repoName)
+	token := vcs.Token()
+
+	if err := checkBaseClone(ctx, baseDir); err != nil {
+		slog.WarnContext(ctx, "git: base clone is broken, cloning again", "repo", repoName, "err", err)
+		if err := os.RemoveAll(baseDir); err != nil {
+			return "", fmt.Errorf("remove broken base clone: %w", err)
+		}
+	}
+	cloned := false
+	if _, err := os.Stat(baseDir); os.IsNotExist(err) {
+		if err := cloneBase(ctx, vcs, repoName, baseDir); err != nil {
+			return "", err
+		}
+		cloned = true
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Accept", "application/vnd.github+json")
 
-	resp, err := http.DefaultClient.Do(req)
+	// Fetch the latest default branch so FETCH_HEAD is current.
+	if defaultBranch == "" {
+		defaultBranch = "main"
+	}
+	fetch := func() ([]byte, error) {
+		cmd := exec.CommandContext(ctx, "git", "fetch", fetchURL, defaultBranch)
+		cmd.Dir = baseDir
+		return cmd.CombinedOutput()
+	}
+	out, err := fetch()
+	if err != nil && !cloned && isCorruptRepoOutput(out) {
+		slog.WarnContext(ctx, "git: base clone is corrupt, cloning again", "repo", repoName, "output", sanitizeGitOutput(out, token))
+		if err := os.RemoveAll(baseDir); err != nil {
+			return "", fmt.Errorf("remove corrupt base clone: %w", err)
+		}
+		if err := cloneBase(ctx, vcs, repoName, baseDir); err != nil {
+			return "", err
+		}
+		out, err = fetch()
+	}
 	if err != nil {
-		return repo{}, fmt.Errorf("github api: %w", err)
+		return "", fmt.Errorf("fetch %s failed: %s: %w", defaultBranch, sanitizeGitOutput(out, token), err)
 	}
-	defer resp.Body.Close()
+	return baseDir, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+// cloneBase clones repoName to baseDir. The clone is made next to baseDir and
+// renamed into place, so an interrupted clone never leaves a partial base
+// clone behind.
+func cloneBase(ctx context.Context, vcs VCSProvider, repoName, baseDir string) error {
+	fetchURL := vcs.FetchURL(repoName)
+	tmp := baseDir + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return fmt.Errorf("clear partial clone: %w", err)
+	}
+
+	// Borrow objects from the local mirror when it is available; --dissociate
+	// copies them so the clone never depends on the cache.
+	args := []string{"clone", "--depth", "1", fetchURL, tmp}
+	if mirror, err := ensureMirror(ctx, mirrorPath(repoName), vcs, repoName); err != nil {
+		slog.WarnContext(ctx, "git: mirror unavailable, cloning from the remote", "repo", repoName, "err", err)
+	} else {
+		args = []string{"clone", "--reference", mirror, "--dissociate", fetchURL, tmp}
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return repo{}, fmt.Errorf("read response: %w", err)
+		os.RemoveAll(tmp)
+		return fmt.Errorf("git clone failed: %s: %w", sanitizeGitOutput(output, vcs.Token()), err)
 	}
 
-	if resp.StatusCode == http.StatusNotFound {
-		return repo{}, fmt.Errorf("repository %q not found", name)
+	// Remove token from stored remote URL so Claude Code can't read it from .git/config.
+	setURL := exec.CommandContext(ctx, "git", "remote", "set-url", "origin", vcs.CleanURL(repoName))
+	setURL.Dir = tmp
+	if out, err := setURL.CombinedOutput(); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("set-url failed: %s: %w", out, err)
 	}
-	if resp.StatusCode != http.StatusOK {
-		return repo{}, fmt.Errorf("github api status %d: %s", resp.StatusCode, body)
+	if err := os.Rename(tmp, baseDir); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("move clone into place: %w", err)
 	}
+	return nil
+}
 
-	var r repo
-	if err := json.Unmarshal(body, &r); err != nil {
-		return repo{}, fmt.Errorf("parse response: %w", err)
+// checkBaseClone reports an error if baseDir exists but isn't the top of a git
+// repository, e.g. after an interrupted clone from before clones were renamed
+// into place. A missing baseDir is fine.
+func checkBaseClone(ctx context.Context, baseDir string) error {
+	if _, err := os.Stat(baseDir); os.IsNotExist(err) {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--git-dir")
+	cmd.Dir = baseDir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w", strings.TrimSpace(string(out)), err)
+	}
+	if dir := strings.TrimSpace(string(out)); dir != ".git" {
+		return fmt.Errorf("git dir is %s, not %s", dir, filepath.Join(baseDir, ".git"))
 	}
-	return r, nil
+	return nil
 }
 
-// EnsureBaseClone ensures a shallow base clone exists at /workspace/<repoName>
-// and fetches the latest main. The base clone is never used directly by jobs;
-// worktrees are created from it instead.
-func EnsureBaseClone(ctx context.Context, owner, token, repoName string) (baseDir string, err error) {
-	repoName = filepath.Base(repoName)
-	baseDir = filepath.Join("/workspace", repoName)
-	fetchURL := fmt.Sprintf("https://x-access-token:%s@github.com/%s/%s.git", token, owner, repoName)
-
-	if _, err := os.Stat(baseDir); os.IsNotExist(err) {
-		cmd := exec.CommandContext(ctx, "git", "clone", "--depth", "1", fetchURL, baseDir)
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			return "", fmt.Errorf("git clone failed: %s: %w", sanitizeGitOutput(output, token), err)
-		}
+// corruptRepoMarkers are git error messages that mean a repository's own data
+// is damaged, as opposed to a network or remote failure.
+var corruptRepoMarkers = []string{
+	"corrupt",
+	"bad object",
+	"broken link",
+	"unable to read",
+	"did not send all necessary objects",
+	"bad index file",
+	"index file smaller than expected",
+	"not a git repository",
+}
 
-		// Remove token from stored remote URL so Claude Code can't read it from .git/config.
-		cleanURL := fmt.Sprintf("https://github.com/%s/%s.git", owner, repoName)
-		setURL := exec.CommandContext(ctx, "git", "remote", "set-url", "origin", cleanURL)
-		setURL.Dir = baseDir
-		if out, err := setURL.CombinedOutput(); err != nil {
-			return "", fmt.Errorf("set-url failed: %s: %w", out, err)
+// isCorruptRepoOutput reports whether git's output points at a corrupt repository.
+func isCorruptRepoOutput(out []byte) bool {
+	s := strings.ToLower(string(out))
+	for _, m := range corruptRepoMarkers {
+		if strings.Contains(s, m) {
+			return true
 		}
 	}
-
-	// Fetch latest main so FETCH_HEAD is current.
-	fetch := exec.CommandContext(ctx, "git", "fetch", fetchURL, "main")
-	fetch.Dir = baseDir
-	if out, err := fetch.CombinedOutput(); err != nil {
-		return "", fmt.Errorf("fetch main failed: %s: %w", sanitizeGitOutput(out, token), err)
-	}
-	return baseDir, nil
+	return false
 }
 
 // CreateWorktree creates a git worktree for a job from FETCH_HEAD.
@@ -164,7 +259,7 @@ func RemoveWorktree(ctx context.Context, baseDir, wtPath, jobID string) {
 	rm := exec.CommandContext(ctx, "git", "worktree", "remove", "--force", wtPath)
 	rm.Dir = baseDir
 	if out, err := rm.CombinedOutput(); err != nil {
-		log.Printf("worktree remove failed (non-fatal): %s: %v", out, err)
+		slog.WarnContext(ctx, "git: worktree remove failed (non-fatal)", "job_id", jobID, "output", string(out), "err", err)
 	}
 
 	prune := exec.CommandContext(ctx, "git", "worktree", "prune")
@@ -175,20 +270,42 @@ func RemoveWorktree(ctx context.Context, baseDir, wtPath, jobID string) {
 	del := exec.CommandContext(ctx, "git", "branch", "-D", branch)
 	del.Dir = baseDir
 	if out, err := del.CombinedOutput(); err != nil {
-		log.Printf("branch delete failed (non-fatal): %s: %v", out, err)
+		slog.WarnContext(ctx, "git: branch delete failed (non-fatal)", "job_id", jobID, "output", string(out), "err", err)
 	}
 }
 
-// ResetWorktree fetches latest main and hard-resets the worktree, giving a
-// clean starting point for implementation. Fetch runs on the base clone,
+// savedWork is uncommitted work found in a worktree and saved before a reset.
+type savedWork struct {
+	Stash string   // commit of the stash entry holding the changes
+	Files []string // changed paths, as reported by git status
+	Patch []byte   // binary diff against HEAD, including untracked files
+}
+
+// ResetWorktree fetches the latest base branch and hard-resets the worktree,
+// giving a clean starting point for implementation. Fetch runs on the base clone,
 // FETCH_HEAD is resolved to a SHA there, and the SHA is used for the reset
 // in the worktree (avoids per-worktree FETCH_HEAD portability issues).
-func ResetWorktree(ctx context.Context, baseDir, wtPath, token, owner, repoName string) error {
-	fetchURL := fmt.Sprintf("https://x-access-token:%s@github.com/%s/%s.git", token, owner, repoName)
-	fetch := exec.CommandContext(ctx, "git", "fetch", fetchURL, "main")
+//
+// Uncommitted changes, e.g. from an interrupted implementation, are stashed
+// first and returned; nil means the worktree was clean. If they can't be
+// saved, nothing is reset.
+func ResetWorktree(ctx context.Context, baseDir, wtPath string, vcs VCSProvider, repoName, base string) (*savedWork, error) {
+	if err := checkWorktree(ctx, baseDir, wtPath); err != nil {
+		return nil, err
+	}
+	saved, err := saveUncommittedWork(ctx, wtPath)
+	if err != nil {
+		return nil, fmt.Errorf("save uncommitted changes: %w", err)
+	}
+
+	fetchURL := vcs.FetchURL(repoName)
+	token := vcs.Token()
+	unlock := lockBaseClone(repoName)
+	defer unlock()
+	fetch := exec.CommandContext(ctx, "git", "fetch", fetchURL, base)
 	fetch.Dir = baseDir
 	if out, err := fetch.CombinedOutput(); err != nil {
-		return fmt.Errorf("fetch main failed: %s: %w", sanitizeGitOutput(out, token), err)
+		return saved, fmt.Errorf("fetch %s failed: %s: %w", base, sanitizeGitOutput(out, token), err)
 	}
 
 	// Resolve FETCH_HEAD to a commit hash on the base clone where it's reliable.
@@ -196,56 +313,303 @@ func ResetWorktree(ctx context.Context, baseDir, wtPath, token, owner, repoName
 	revParse.Dir = baseDir
 	hashOut, err := revParse.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("rev-parse FETCH_HEAD failed: %s: %w", hashOut, err)
+		return saved, fmt.Errorf("rev-parse FETCH_HEAD failed: %s: %w", hashOut, err)
 	}
 	commit := strings.TrimSpace(string(hashOut))
 
 	reset := exec.CommandContext(ctx, "git", "reset", "--hard", commit)
 	reset.Dir = wtPath
 	if out, err := reset.CombinedOutput(); err != nil {
-		return fmt.Errorf("reset worktree failed: %s: %w", out, err)
+		return saved, fmt.Errorf("reset worktree failed: %s: %w", out, err)
 	}
 
 	clean := exec.CommandContext(ctx, "git", "clean", "-fd")
 	clean.Dir = wtPath
 	if out, err := clean.CombinedOutput(); err != nil {
-		return fmt.Errorf("clean worktree failed: %s: %w", out, err)
+		return saved, fmt.Errorf("clean worktree failed: %s: %w", out, err)
+	}
+	return saved, nil
+}
+
+// checkWorktree refuses destructive commands outside a job worktree: wtPath
+// must lie under baseDir/worktrees and be the top level of its own checkout,
+// so a missing or half-removed worktree can't make git act on the base clone.
+func checkWorktree(ctx context.Context, baseDir, wtPath string) error {
+	root := filepath.Join(baseDir, "worktrees") + string(filepath.Separator)
+	if wtPath == "" || !strings.HasPrefix(filepath.Clean(wtPath), root) {
+		return fmt.Errorf("refusing to reset %q: not a job worktree", wtPath)
+	}
+	top := exec.CommandContext(ctx, "git", "rev-parse", "--show-toplevel")
+	top.Dir = wtPath
+	out, err := top.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("refusing to reset %q: %s: %w", wtPath, strings.TrimSpace(string(out)), err)
+	}
+	got, _ := filepath.EvalSymlinks(strings.TrimSpace(string(out)))
+	want, _ := filepath.EvalSymlinks(wtPath)
+	if got != want {
+		return fmt.Errorf("refusing to reset %q: it is inside %s, not a worktree of its own", wtPath, got)
+	}
+	return nil
+}
+
+// saveUncommittedWork records any uncommitted changes in wtPath, untracked
+// files included, as a stash entry in the base clone. The worktree itself is
+// left as it was. It returns nil for a clean worktree.
+func saveUncommittedWork(ctx context.Context, wtPath string) (*savedWork, error) {
+	git := func(args ...string) ([]byte, error) {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = wtPath
+		out, err := cmd.Output()
+		if err != nil {
+			var stderr []byte
+			if ee, ok := err.(*exec.ExitError); ok {
+				stderr = ee.Stderr
+			}
+			return nil, fmt.Errorf("git %s: %s: %w", args[0], strings.TrimSpace(string(stderr)), err)
+		}
+		return out, nil
+	}
+
+	status, err := git("status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(string(status))) == 0 {
+		return nil, nil
+	}
+	saved := &savedWork{}
+	for _, line := range strings.Split(strings.TrimRight(string(status), "\n"), "\n") {
+		if len(line) > 3 {
+			saved.Files = append(saved.Files, line[3:])
+		}
+	}
+
+	// Staging everything lets the stash and the patch include untracked files.
+	if _, err := git("add", "-A"); err != nil {
+		return nil, err
+	}
+	if saved.Patch, err = git("diff", "--cached", "--binary", "HEAD"); err != nil {
+		return nil, err
+	}
+	// stash create doesn't touch the worktree or the shared stash list, so
+	// concurrent jobs on the same clone can't pick up each other's entry.
+	out, err := git("-c", "user.name=Bob", "-c", "user.email=bob@noreply", "stash", "create", "bob: uncommitted changes before reset")
+	if err != nil {
+		return nil, err
+	}
+	saved.Stash = strings.TrimSpace(string(out))
+	if saved.Stash == "" {
+		return nil, fmt.Errorf("git stash create returned no commit")
+	}
+	if _, err := git("stash", "store", "-m", "bob: uncommitted changes before reset", saved.Stash); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// FetchBranch fetches a remote branch into the base clone so FETCH_HEAD points
+// at its tip (for creating a worktree from an existing PR branch).
+func FetchBranch(ctx context.Context, baseDir string, vcs VCSProvider, repoName, branch string) error {
+	fetch := exec.CommandContext(ctx, "git", "fetch", vcs.FetchURL(repoName), branch)
+	fetch.Dir = baseDir
+	if out, err := fetch.CombinedOutput(); err != nil {
+		return fmt.Errorf("fetch %s failed: %s: %w", branch, sanitizeGitOutput(out, vcs.Token()), err)
 	}
 	return nil
 }
 
-// CreatePullRequest commits all changes, pushes a new branch, and opens a PR.
+// CreatePullRequest commits all changes, pushes a new branch, and opens a PR
+// (or merge request) into base through the provider, as a draft and with
+// reviewers, labels and assignees per opts.
 // repoDir is the working directory (typically a worktree path).
-// Returns the PR HTML URL.
-func CreatePullRequest(ctx context.Context, owner, token, repoName, repoDir, title, branch, body string) (string, error) {
-	repoName = filepath.Base(repoName)
+// Returns the PR HTML URL and the branch pushed, which differs from branch if
+// that name was taken.
+func CreatePullRequest(ctx context.Context, vcs VCSProvider, repoName, repoDir, title, branch, base, body string, commit commitSpec, opts PROptions) (prURL, pushed string, err error) {
+	// Never push onto a branch that's already there: it may back another PR.
+	if branch, err = unusedBranch(ctx, vcs, repoName, repoDir, branch); err != nil {
+		return "", "", err
+	}
+
+	// Create branch.
+	checkoutCmd := exec.CommandContext(ctx, "git", "checkout", "-b", branch)
+	checkoutCmd.Dir = repoDir
+	if out, err := checkoutCmd.CombinedOutput(); err != nil {
+		return "", "", fmt.Errorf("create branch failed: %s: %w", out, err)
+	}
+
+	if err := commitAndPush(ctx, vcs, repoName, repoDir, commit, branch); err != nil {
+		return "", "", err
+	}
+
+	prURL, err = vcs.OpenPullRequest(ctx, repoName, branch, base, title, body, opts.Draft)
+	if errors.Is(err, ErrNoChanges) {
+		deleteRemoteBranch(ctx, vcs, repoName, repoDir, branch)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	annotatePullRequest(ctx, vcs, repoName, prURL, opts)
+	return prURL, branch, nil
+}
+
+// deleteRemoteBranch deletes a branch pushed for a pull request that wasn't
+// opened. Failures are only logged.
+func deleteRemoteBranch(ctx context.Context, vcs VCSProvider, repoName, repoDir, branch string) {
+	cmd := exec.CommandContext(ctx, "git", "push", vcs.FetchURL(repoName), "--delete", branch)
+	cmd.Dir = repoDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		slog.WarnContext(ctx, "git: failed to delete branch", "branch", branch, "output", sanitizeGitOutput(out, vcs.Token()), "err", err)
+	}
+}
+
+// maxBranchAttempts caps the names unusedBranch tries.
+const maxBranchAttempts = 5
+
+// unusedBranch returns branch if the remote has no branch by that name, or
+// else the first free variant with a fresh random suffix.
+func unusedBranch(ctx context.Context, vcs VCSProvider, repoName, repoDir, branch string) (string, error) {
+	name := branch
+	for range maxBranchAttempts {
+		cmd := exec.CommandContext(ctx, "git", "ls-remote", "--heads", vcs.FetchURL(repoName), "refs/heads/"+name)
+		cmd.Dir = repoDir
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("check for branch %s: %w", name, err)
+		}
+		if len(strings.TrimSpace(string(out))) == 0 {
+			if name != branch {
+				slog.InfoContext(ctx, "git: branch name taken, using another", "branch", branch, "using", name)
+			}
+			return name, nil
+		}
+		name = branch + "-" + branchSuffix()
+	}
+	return "", fmt.Errorf("branch %s and %d variants of it already exist", branch, maxBranchAttempts-1)
+}
+
+// SparseCheckout limits the worktree at repoDir to paths (cone mode, which
+// keeps the files at the repo root too). For a file, its directory is checked
+// out. The setting is per worktree, so the base clone and other jobs keep a
+// full checkout.
+func SparseCheckout(ctx context.Context, repoDir string, paths []string) error {
+	var dirs []string
+	for _, p := range paths {
+		kind := exec.CommandContext(ctx, "git", "cat-file", "-t", "HEAD:"+p)
+		kind.Dir = repoDir
+		if out, err := kind.Output(); err == nil && strings.TrimSpace(string(out)) == "blob" {
+			if p = path.Dir(p); p == "." {
+				continue // a root file is always checked out
+			}
+		}
+		dirs = append(dirs, p)
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, "git", append([]string{"sparse-checkout", "set", "--cone", "--"}, dirs...)...)
+	cmd.Dir = repoDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git sparse-checkout: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// DiscardChanges reverts files in repoDir to HEAD, deleting them if they are
+// untracked.
+func DiscardChanges(ctx context.Context, repoDir string, files []string) error {
+	for _, f := range files {
+		tracked := exec.CommandContext(ctx, "git", "cat-file", "-e", "HEAD:"+f)
+		tracked.Dir = repoDir
+		if tracked.Run() != nil {
+			if err := os.RemoveAll(filepath.Join(repoDir, f)); err != nil {
+				return fmt.Errorf("remove %s: %w", f, err)
+			}
+			continue
+		}
+		checkout := exec.CommandContext(ctx, "git", "checkout", "HEAD", "--", f)
+		checkout.Dir = repoDir
+		if out, err := checkout.CombinedOutput(); err != nil {
+			return fmt.Errorf("restore %s failed: %s: %w", f, out, err)
+		}
+	}
+	return nil
+}
+
+// HeadDiff returns the changes made by repoDir's HEAD commit as a patch.
+func HeadDiff(ctx context.Context, repoDir string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", "show", "--format=", "--binary", "HEAD")
+	cmd.Dir = repoDir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git show failed: %w", err)
+	}
+	return out, nil
+}
+
+// WorktreeDiff returns the uncommitted changes at repoDir, including new
+// files, as a unified diff against HEAD. Files that look like secrets are left
+// out, as they are from commits.
+func WorktreeDiff(ctx context.Context, repoDir string) (string, error) {
+	files, err := changedFiles(ctx, repoDir)
+	if err != nil || len(files) == 0 {
+		return "", err
+	}
+	// New files only show up in git diff once they are in the index; mark
+	// them intent-to-add, which commits stage for real anyway.
+	var present []string
+	for _, f := range files {
+		if _, err := os.Lstat(filepath.Join(repoDir, f)); err == nil {
+			present = append(present, f)
+		}
+	}
+	if len(present) > 0 {
+		add := exec.CommandContext(ctx, "git", append([]string{"add", "--intent-to-add", "--"}, present...)...)
+		add.Dir = repoDir
+		if out, err := add.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("git add failed: %s: %w", out, err)
+		}
+	}
+	cmd := exec.CommandContext(ctx, "git", append([]string{"diff", "--binary", "HEAD", "--"}, files...)...)
+	cmd.Dir = repoDir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git diff failed: %w", err)
+	}
+	return string(out), nil
+}
+
+// PushToBranch commits all changes and pushes them on top of an existing remote
+// branch (e.g. the head branch of an open PR).
+func PushToBranch(ctx context.Context, vcs VCSProvider, repoName, repoDir string, commit commitSpec, branch string) error {
+	return commitAndPush(ctx, vcs, repoName, repoDir, commit, "HEAD:refs/heads/"+branch)
+}
+
+// commitAndPush stages changed files (filtering out secrets), commits, and
+// pushes refspec to the provider.
+func commitAndPush(ctx context.Context, vcs VCSProvider, repoName, repoDir string, commit commitSpec, refspec string) error {
+	token := vcs.Token()
 
 	// Configure git user.
+	author := commit.Author.orDefault()
 	for _, args := range [][]string{
-		{"config", "user.name", "Bob"},
-		{"config", "user.email", "bob@noreply"},
+		{"config", "user.name", author.Name},
+		{"config", "user.email", author.Email},
 	} {
 		cmd := exec.CommandContext(ctx, "git", args...)
 		cmd.Dir = repoDir
 		if out, err := cmd.CombinedOutput(); err != nil {
-			return "", fmt.Errorf("git config failed: %s: %w", out, err)
+			return fmt.Errorf("git config failed: %s: %w", out, err)
 		}
 	}
 
-	// Create branch.
-	checkoutCmd := exec.CommandContext(ctx, "git", "checkout", "-b", branch)
-	checkoutCmd.Dir = repoDir
-	if out, err := checkoutCmd.CombinedOutput(); err != nil {
-		return "", fmt.Errorf("create branch failed: %s: %w", out, err)
-	}
-
 	// Collect changed and untracked files, filtering out secrets.
 	filesToAdd, err := changedFiles(ctx, repoDir)
 	if err != nil {
-		return "", err
+		return err
 	}
 	if len(filesToAdd) == 0 {
-		return "", fmt.Errorf("no files to commit")
+		return ErrNoChanges
 	}
 
 	// Stage only the approved files.
@@ -253,76 +617,28 @@ func CreatePullRequest(ctx context.Context, owner, token, repoName, repoDir, tit
 	addCmd := exec.CommandContext(ctx, "git", addArgs...)
 	addCmd.Dir = repoDir
 	if out, err := addCmd.CombinedOutput(); err != nil {
-		return "", fmt.Errorf("stage changes failed: %s: %w", out, err)
+		return fmt.Errorf("stage changes failed: %s: %w", out, err)
 	}
 
 	// Commit.
-	commitCmd := exec.CommandContext(ctx, "git", "commit", "-m", title)
+	commitCmd := exec.CommandContext(ctx, "git", "commit", "-m", commit.message(filesToAdd))
 	commitCmd.Dir = repoDir
 	if out, err := commitCmd.CombinedOutput(); err != nil {
-		return "", fmt.Errorf("commit failed: %s: %w", out, err)
+		return fmt.Errorf("commit failed: %s: %w", out, err)
 	}
 
 	// Token URL for authenticated fetch/push operations.
-	pushURL := fmt.Sprintf("https://x-access-token:%s@github.com/%s/%s.git", token, owner, repoName)
+	pushURL := vcs.FetchURL(repoName)
 
 	// Unshallow if needed (ignore error if already full).
 	// Uses explicit token URL since the stored origin no longer has credentials.
 	unshallow := exec.CommandContext(ctx, "git", "fetch", "--unshallow", pushURL)
 	unshallow.Dir = repoDir
 	unshallow.CombinedOutput() // best-effort
-	pushCmd := exec.CommandContext(ctx, "git", "push", pushURL, branch)
+	pushCmd := exec.CommandContext(ctx, "git", "push", pushURL, refspec)
 	pushCmd.Dir = repoDir
 	if out, err := pushCmd.CombinedOutput(); err != nil {
-		return "", fmt.Errorf("push failed: %s: %w", sanitizeGitOutput(out, token), err)
-	}
-
-	// Create PR via GitHub API.
-	prPayload := struct {
-		Title string `json:"title"`
-		Head  string `json:"head"`
-		Base  string `json:"base"`
-		Body  string `json:"body,omitempty"`
-	}{
-		Title: title,
-		Head:  branch,
-		Base:  "main",
-		Body:  body,
-	}
-	prJSON, err := json.Marshal(prPayload)
-	if err != nil {
-		return "", fmt.Errorf("marshal PR body: %w", err)
-	}
-
-	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls", owner, repoName)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(prJSON))
-	if err != nil {
-		return "", fmt.Errorf("create request: %w", err)
-	}
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("github api: %w", err)
+		return fmt.Errorf("push failed: %s: %w", sanitizeGitOutput(out, token), err)
 	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusCreated {
-		return "", fmt.Errorf("github api status %d: %s", resp.StatusCode, respBody)
-	}
-
-	var prResult struct {
-		HTMLURL string `json:"html_url"`
-	}
-	if err := json.Unmarshal(respBody, &prResult); err != nil {
-		return "", fmt.Errorf("parse PR response: %w", err)
-	}
-	return prResult.HTMLURL, nil
+	return nil
 }
@@ -1,7 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestSanitizeGitOutput(t *testing.T) {
@@ -81,3 +89,267 @@ func TestIsSecretFile(t *testing.T) {
 		}
 	}
 }
+
+func TestDiscardChanges(t *testing.T) {
+	dir := gitRepo(t, map[string]string{"api/main.go": "package main\n", "web/app.ts": "x\n"})
+	os.WriteFile(filepath.Join(dir, "api/main.go"), []byte("changed\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "api/new.go"), []byte("new\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "web/app.ts"), []byte("kept\n"), 0o644)
+
+	if err := DiscardChanges(context.Background(), dir, []string{"api/main.go", "api/new.go"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "api/main.go")); string(b) != "package main\n" {
+		t.Errorf("api/main.go = %q, want restored", b)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "api/new.go")); !os.IsNotExist(err) {
+		t.Error("untracked api/new.go should be removed")
+	}
+	if b, _ := os.ReadFile(filepath.Join(dir, "web/app.ts")); string(b) != "kept\n" {
+		t.Errorf("web/app.ts = %q, want untouched", b)
+	}
+}
+
+func TestSparseCheckout(t *testing.T) {
+	dir := gitRepo(t, map[string]string{"README.md": "x\n", "services/payments/pay.go": "x\n", "services/billing/bill.go": "x\n", "web/app.ts": "x\n"})
+
+	if err := SparseCheckout(context.Background(), dir, []string{"services/payments/pay.go", "README.md"}); err != nil {
+		t.Fatalf("SparseCheckout: %v", err)
+	}
+	for file, want := range map[string]bool{
+		"README.md":                true,
+		"services/payments/pay.go": true,
+		"services/billing/bill.go": false,
+		"web/app.ts":               false,
+	} {
+		if _, err := os.Stat(filepath.Join(dir, file)); (err == nil) != want {
+			t.Errorf("%s checked out = %v, want %v", file, err == nil, want)
+		}
+	}
+}
+
+func TestSaveUncommittedWork(t *testing.T) {
+	ctx := context.Background()
+	base := gitRepo(t, map[string]string{"main.go": "package main\n"})
+	wt := filepath.Join(base, "worktrees", "job-1")
+	git := func(dir string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+		return string(out)
+	}
+	git(base, "worktree", "add", "-q", "-b", "job/job-1", wt, "HEAD")
+
+	if err := checkWorktree(ctx, base, wt); err != nil {
+		t.Errorf("checkWorktree(worktree) = %v", err)
+	}
+	stray := filepath.Join(base, "worktrees", "stray")
+	os.MkdirAll(stray, 0o755)
+	for _, dir := range []string{"", base, stray} {
+		if err := checkWorktree(ctx, base, dir); err == nil {
+			t.Errorf("checkWorktree(%q) should fail", dir)
+		}
+	}
+
+	if saved, err := saveUncommittedWork(ctx, wt); err != nil || saved != nil {
+		t.Fatalf("clean worktree: saved = %+v, err = %v", saved, err)
+	}
+
+	os.WriteFile(filepath.Join(wt, "main.go"), []byte("package main // wip\n"), 0o644)
+	os.WriteFile(filepath.Join(wt, "new.go"), []byte("package main\n"), 0o644)
+	saved, err := saveUncommittedWork(ctx, wt)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(saved.Files) != 2 || !strings.Contains(string(saved.Patch), "new.go") {
+		t.Errorf("saved = %+v", saved)
+	}
+	if got := git(base, "show", saved.Stash+":new.go"); got != "package main\n" {
+		t.Errorf("stash new.go = %q", got)
+	}
+	if list := git(base, "stash", "list"); !strings.Contains(list, "uncommitted changes before reset") {
+		t.Errorf("stash list = %q", list)
+	}
+}
+
+func TestHeadDiff(t *testing.T) {
+	dir := gitRepo(t, map[string]string{"main.go": "package main\n"})
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main // changed\n"), 0o644)
+	cmd := exec.Command("git", "-c", "user.name=t", "-c", "user.email=t@t", "commit", "-qam", "change")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("commit: %s", out)
+	}
+
+	diff, err := HeadDiff(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("HeadDiff: %v", err)
+	}
+	if !strings.HasPrefix(string(diff), "diff --git") || !strings.Contains(string(diff), "+package main // changed") {
+		t.Errorf("diff = %q", diff)
+	}
+}
+
+func TestEnsureBaseClone(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	old := workspaceRoot
+	workspaceRoot = filepath.Join(root, "workspace")
+	t.Cleanup(func() { workspaceRoot = old })
+	vcs := localVCS{dir: filepath.Join(root, "remote")}
+	remote := vcs.FetchURL("app")
+	runGit(t, root, "init", "-q", "--bare", "-b", "main", remote)
+	work := filepath.Join(root, "work")
+	runGit(t, root, "clone", "-q", remote, work)
+	first := commitToRemote(t, work, "a.txt")
+	ctx := context.Background()
+
+	baseDir, err := EnsureBaseClone(ctx, vcs, "app", "main")
+	if err != nil {
+		t.Fatalf("EnsureBaseClone: %v", err)
+	}
+	if head := runGit(t, baseDir, "rev-parse", "FETCH_HEAD"); head != first {
+		t.Errorf("FETCH_HEAD = %s, want %s", head, first)
+	}
+	if _, err := os.Stat(baseDir + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary clone left behind")
+	}
+
+	// An existing clone is brought up to date.
+	second := commitToRemote(t, work, "b.txt")
+	if _, err := EnsureBaseClone(ctx, vcs, "app", "main"); err != nil {
+		t.Fatalf("EnsureBaseClone update: %v", err)
+	}
+	if head := runGit(t, baseDir, "rev-parse", "FETCH_HEAD"); head != second {
+		t.Errorf("FETCH_HEAD after update = %s, want %s", head, second)
+	}
+
+	// A directory that isn't a repository, e.g. from an interrupted clone, is replaced.
+	if err := os.RemoveAll(filepath.Join(baseDir, ".git")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EnsureBaseClone(ctx, vcs, "app", "main"); err != nil {
+		t.Fatalf("EnsureBaseClone after breaking the clone: %v", err)
+	}
+	if head := runGit(t, baseDir, "rev-parse", "FETCH_HEAD"); head != second {
+		t.Errorf("FETCH_HEAD after re-clone = %s, want %s", head, second)
+	}
+}
+
+func TestIsCorruptRepoOutput(t *testing.T) {
+	for out, want := range map[string]bool{
+		"error: object file .git/objects/ab/cd is empty\nfatal: loose object abcd (stored in .git/objects/ab/cd) is corrupt": true,
+		"fatal: bad object HEAD": true,
+		"fatal: unable to access 'https://github.com/acme/app.git/': Could not resolve host: github.com": false,
+		"fatal: couldn't find remote ref main": false,
+	} {
+		if got := isCorruptRepoOutput([]byte(out)); got != want {
+			t.Errorf("isCorruptRepoOutput(%q) = %v, want %v", out, got, want)
+		}
+	}
+}
+
+func TestRepoDirName(t *testing.T) {
+	for name, want := range map[string]string{
+		"app":            "app",
+		"other-org/app":  "other-org@app",
+		"/other-org/app": "other-org@app",
+		"../app":         "..@app",
+	} {
+		if got := repoDirName(name); got != want {
+			t.Errorf("repoDirName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestLockBaseClone(t *testing.T) {
+	unlock := lockBaseClone("org/app")
+	lockBaseClone("app")() // the default owner's repo of the same name has its own lock
+	locked := make(chan struct{})
+	go func() {
+		defer lockBaseClone("org/app")()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		t.Fatal("second lock acquired while the first was held")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlock()
+	unlock() // no-op
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("second lock not acquired after unlock")
+	}
+}
+
+func TestUnusedBranch(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	vcs := localVCS{dir: filepath.Join(root, "remote")}
+	remote := vcs.FetchURL("app")
+	runGit(t, root, "init", "-q", "--bare", "-b", "main", remote)
+	work := filepath.Join(root, "work")
+	runGit(t, root, "clone", "-q", remote, work)
+	commitToRemote(t, work, "a.txt")
+	ctx := context.Background()
+
+	got, err := unusedBranch(ctx, vcs, "app", work, "bob/fix-login-0a1b2c3d")
+	if err != nil || got != "bob/fix-login-0a1b2c3d" {
+		t.Fatalf("unusedBranch = %q, %v, want the name unchanged", got, err)
+	}
+
+	runGit(t, work, "push", "-q", "origin", "HEAD:refs/heads/bob/fix-login-0a1b2c3d")
+	got, err = unusedBranch(ctx, vcs, "app", work, "bob/fix-login-0a1b2c3d")
+	if err != nil {
+		t.Fatalf("unusedBranch: %v", err)
+	}
+	if !regexp.MustCompile(`^bob/fix-login-0a1b2c3d-[0-9a-f]{8}$`).MatchString(got) {
+		t.Errorf("unusedBranch = %q, want a fresh variant", got)
+	}
+}
+
+// noCommitsVCS is a localVCS whose host finds no commits between the branches.
+type noCommitsVCS struct{ localVCS }
+
+func (noCommitsVCS) OpenPullRequest(context.Context, string, string, string, string, string, bool) (string, error) {
+	return "", ErrNoChanges
+}
+
+func TestCreatePullRequest_NoChanges(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	vcs := noCommitsVCS{localVCS{dir: filepath.Join(root, "remote")}}
+	remote := vcs.FetchURL("app")
+	runGit(t, root, "init", "-q", "--bare", "-b", "main", remote)
+	work := filepath.Join(root, "work")
+	runGit(t, root, "clone", "-q", remote, work)
+	commitToRemote(t, work, "a.txt")
+	ctx := context.Background()
+
+	if _, _, err := CreatePullRequest(ctx, vcs, "app", work, "t", "bob/nothing", "main", "", commitSpec{Task: "t"}, PROptions{}); !errors.Is(err, ErrNoChanges) {
+		t.Fatalf("clean worktree: err = %v, want ErrNoChanges", err)
+	}
+
+	runGit(t, work, "checkout", "-q", "main")
+	if err := os.WriteFile(filepath.Join(work, "b.txt"), []byte("b"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := CreatePullRequest(ctx, vcs, "app", work, "t", "bob/empty", "main", "", commitSpec{Task: "t"}, PROptions{}); !errors.Is(err, ErrNoChanges) {
+		t.Fatalf("no commits: err = %v, want ErrNoChanges", err)
+	}
+	if heads := runGit(t, work, "ls-remote", "--heads", remote, "refs/heads/bob/empty"); heads != "" {
+		t.Errorf("branch was left on the remote: %s", heads)
+	}
+}
@@ -0,0 +1,641 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// GitHubProvider implements VCSProvider for GitHub repositories owned by a
+// single org or user. With several owners there is one provider per owner;
+// repos of all but the default owner are named "owner/repo".
+type GitHubProvider struct {
+	owner     string
+	qualified bool           // not the default owner: name repos "owner/repo"
+	token     string         // personal access token; unused when app is set
+	app       *githubAppAuth // GitHub App installation auth, if configured
+	apiURL    string         // overridable for tests
+
+	repoList repoListCache // owner's repositories, for matching loosely written names
+
+	loginMu sync.Mutex
+	login   string // account the token acts as, once looked up (see Login)
+}
+
+// NewGitHubProvider creates a GitHubProvider that authenticates with a personal access token.
+func NewGitHubProvider(owner, token string) *GitHubProvider {
+	return &GitHubProvider{owner: owner, token: token, apiURL: "https://api.github.com"}
+}
+
+// NewGitHubAppProvider creates a GitHubProvider that authenticates as a GitHub
+// App installation, using short-lived installation tokens for git and API calls.
+func NewGitHubAppProvider(owner string, app *githubAppAuth) *GitHubProvider {
+	return &GitHubProvider{owner: owner, app: app, apiURL: app.apiURL}
+}
+
+func (g *GitHubProvider) Name() string { return "github" }
+
+// Token returns the current credential. For a GitHub App this is the cached
+// installation token, renewed when close to expiry; if minting fails the error
+// is logged and the failure surfaces from the git or API call that follows.
+func (g *GitHubProvider) Token() string {
+	if g.app == nil {
+		return g.token
+	}
+	tok, err := g.app.Token(context.Background())
+	if err != nil {
+		slog.Error("github: failed to mint an app installation token", "err", err)
+	}
+	return tok
+}
+
+func (g *GitHubProvider) FetchURL(name string) string {
+	return fmt.Sprintf("https://x-access-token:%s@github.com/%s.git", g.Token(), g.repoPath(name))
+}
+
+func (g *GitHubProvider) CleanURL(name string) string {
+	return fmt.Sprintf("https://github.com/%s.git", g.repoPath(name))
+}
+
+// repoPath returns the "owner/repo" path of a repo named with or without its
+// owner.
+func (g *GitHubProvider) repoPath(name string) string {
+	_, short := splitRepoName(name)
+	return g.owner + "/" + short
+}
+
+// repoName returns the name Bob uses for the owner's repo short: "owner/repo"
+// unless this is the default owner.
+func (g *GitHubProvider) repoName(short string) string {
+	if g.qualified {
+		return g.owner + "/" + short
+	}
+	return short
+}
+
+// owns reports whether name, if it includes an owner, names one of this
+// owner's repos.
+func (g *GitHubProvider) owns(name string) bool {
+	owner, _ := splitRepoName(name)
+	return owner == "" || strings.EqualFold(owner, g.owner)
+}
+
+// FindRepo checks whether a repository exists in the GitHub owner's org/account.
+// A name that isn't found exactly is matched against the owner's full
+// repository list, ignoring case and separators. A name with another owner
+// ("other-org/repo") is never found.
+func (g *GitHubProvider) FindRepo(ctx context.Context, name string) (repo, error) {
+	if !g.owns(name) {
+		return repo{}, fmt.Errorf("repository %q not found in %s", name, g.owner)
+	}
+	_, name = splitRepoName(name)
+	url := fmt.Sprintf("%s/repos/%s", g.apiURL, g.repoPath(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return repo{}, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+g.Token())
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return repo{}, fmt.Errorf("github api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return repo{}, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		if repos, err := g.ListRepos(ctx); err != nil {
+			slog.ErrorContext(ctx, "github: failed to list repos", "err", err)
+		} else if r, ok := matchRepo(repos, name); ok {
+			return r, nil
+		}
+		return repo{}, fmt.Errorf("repository %q not found", name)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return repo{}, fmt.Errorf("github api status %d: %s", resp.StatusCode, body)
+	}
+
+	var r repo
+	if err := json.Unmarshal(body, &r); err != nil {
+		return repo{}, fmt.Errorf("parse response: %w", err)
+	}
+	r.Name = g.repoName(r.Name)
+	return r, nil
+}
+
+// OpenPullRequest creates a PR via the GitHub API and returns its HTML URL.
+func (g *GitHubProvider) OpenPullRequest(ctx context.Context, name, branch, base, title, body string, draft bool) (string, error) {
+	prPayload := struct {
+		Title string `json:"title"`
+		Head  string `json:"head"`
+		Base  string `json:"base"`
+		Body  string `json:"body,omitempty"`
+		Draft bool   `json:"draft,omitempty"`
+	}{
+		Title: title,
+		Head:  branch,
+		Base:  base,
+		Body:  body,
+		Draft: draft,
+	}
+	prJSON, err := json.Marshal(prPayload)
+	if err != nil {
+		return "", fmt.Errorf("marshal PR body: %w", err)
+	}
+
+	apiURL := fmt.Sprintf("%s/repos/%s/pulls", g.apiURL, g.repoPath(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(prJSON))
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+g.Token())
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("github api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusUnprocessableEntity {
+		switch {
+		case bytes.Contains(respBody, []byte("A pull request already exists")):
+			return g.updateOpenPullRequest(ctx, name, branch, title, body)
+		case bytes.Contains(respBody, []byte("No commits between")):
+			return "", ErrNoChanges
+		}
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("github api status %d: %s", resp.StatusCode, respBody)
+	}
+
+	var prResult struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.Unmarshal(respBody, &prResult); err != nil {
+		return "", fmt.Errorf("parse PR response: %w", err)
+	}
+	return prResult.HTMLURL, nil
+}
+
+// updateOpenPullRequest sets the title and body of the open pull request for
+// branch, which already has the pushed changes, and returns its URL.
+func (g *GitHubProvider) updateOpenPullRequest(ctx context.Context, name, branch, title, body string) (string, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/pulls?state=open&head=%s", g.apiURL, g.repoPath(name), url.QueryEscape(g.owner+":"+branch))
+	respBody, err := g.get(ctx, apiURL, "application/vnd.github+json")
+	if err != nil {
+		return "", fmt.Errorf("find open PR for %s: %w", branch, err)
+	}
+	var prs []struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.Unmarshal(respBody, &prs); err != nil {
+		return "", fmt.Errorf("parse PR list: %w", err)
+	}
+	if len(prs) == 0 {
+		return "", fmt.Errorf("github says a PR for %s exists, but none is open", branch)
+	}
+	pr := prs[0]
+	apiURL = fmt.Sprintf("%s/repos/%s/pulls/%d", g.apiURL, g.repoPath(name), pr.Number)
+	if err := g.send(ctx, http.MethodPatch, apiURL, map[string]string{"title": title, "body": body}, http.StatusOK); err != nil {
+		return "", fmt.Errorf("update PR %s: %w", pr.HTMLURL, err)
+	}
+	slog.InfoContext(ctx, "github: updated the open PR for the branch", "branch", branch, "pr", pr.HTMLURL)
+	return pr.HTMLURL, nil
+}
+
+// ReplyToReviewComment posts a threaded reply to a pull request review comment.
+func (g *GitHubProvider) ReplyToReviewComment(ctx context.Context, name string, prNumber int, commentID int64, body string) error {
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return fmt.Errorf("marshal reply: %w", err)
+	}
+	apiURL := fmt.Sprintf("%s/repos/%s/pulls/%d/comments/%d/replies", g.apiURL, g.repoPath(name), prNumber, commentID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+g.Token())
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("github api: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("github api status %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+// issueComment is one entry of an issue conversation.
+type issueComment struct {
+	Author     string
+	AuthorType string // "User" or "Bot"
+	Body       string
+}
+
+// CreateIssueComment posts a comment on an issue (or pull request).
+func (g *GitHubProvider) CreateIssueComment(ctx context.Context, name string, number int, body string) error {
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return fmt.Errorf("marshal comment: %w", err)
+	}
+	apiURL := fmt.Sprintf("%s/repos/%s/issues/%d/comments", g.apiURL, g.repoPath(name), number)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+g.Token())
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("github api: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("github api status %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+// IssueConversation returns an issue as its opening post (title and body)
+// followed by up to 100 comments, oldest first.
+func (g *GitHubProvider) IssueConversation(ctx context.Context, name string, number int) ([]issueComment, error) {
+	type user struct {
+		Login string `json:"login"`
+		Type  string `json:"type"`
+	}
+	body, err := g.get(ctx, fmt.Sprintf("%s/repos/%s/issues/%d", g.apiURL, g.repoPath(name), number), "application/vnd.github+json")
+	if err != nil {
+		return nil, err
+	}
+	var issue struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+		User  user   `json:"user"`
+	}
+	if err := json.Unmarshal(body, &issue); err != nil {
+		return nil, fmt.Errorf("parse issue: %w", err)
+	}
+
+	body, err = g.get(ctx, fmt.Sprintf("%s/repos/%s/issues/%d/comments?per_page=100", g.apiURL, g.repoPath(name), number), "application/vnd.github+json")
+	if err != nil {
+		return nil, err
+	}
+	var comments []struct {
+		Body string `json:"body"`
+		User user   `json:"user"`
+	}
+	if err := json.Unmarshal(body, &comments); err != nil {
+		return nil, fmt.Errorf("parse comments: %w", err)
+	}
+
+	conv := []issueComment{{Author: issue.User.Login, AuthorType: issue.User.Type, Body: issue.Title + "\n\n" + issue.Body}}
+	for _, c := range comments {
+		conv = append(conv, issueComment{Author: c.User.Login, AuthorType: c.User.Type, Body: c.Body})
+	}
+	return conv, nil
+}
+
+// GetPullRequest fetches a pull request's metadata and unified diff.
+func (g *GitHubProvider) GetPullRequest(ctx context.Context, name string, number int) (pullRequestInfo, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/pulls/%d", g.apiURL, g.repoPath(name), number)
+
+	body, err := g.get(ctx, apiURL, "application/vnd.github+json")
+	if err != nil {
+		return pullRequestInfo{}, err
+	}
+	var pr struct {
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		HTMLURL string `json:"html_url"`
+		Head    struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if err := json.Unmarshal(body, &pr); err != nil {
+		return pullRequestInfo{}, fmt.Errorf("parse response: %w", err)
+	}
+
+	diff, err := g.get(ctx, apiURL, "application/vnd.github.diff")
+	if err != nil {
+		return pullRequestInfo{}, err
+	}
+	return pullRequestInfo{
+		Title:   pr.Title,
+		Body:    pr.Body,
+		HTMLURL: pr.HTMLURL,
+		HeadSHA: pr.Head.SHA,
+		HeadRef: fmt.Sprintf("refs/pull/%d/head", number),
+		Diff:    string(diff),
+	}, nil
+}
+
+// PullRequestStatus returns a pull request's state, mergeability and each
+// reviewer's latest review.
+func (g *GitHubProvider) PullRequestStatus(ctx context.Context, name string, number int) (prStatus, error) {
+	apiURL := fmt.Sprintf("%s/repos/%s/pulls/%d", g.apiURL, g.repoPath(name), number)
+	body, err := g.get(ctx, apiURL, "application/vnd.github+json")
+	if err != nil {
+		return prStatus{}, err
+	}
+	var pr struct {
+		Title          string `json:"title"`
+		HTMLURL        string `json:"html_url"`
+		State          string `json:"state"`
+		Merged         bool   `json:"merged"`
+		Draft          bool   `json:"draft"`
+		MergeableState string `json:"mergeable_state"`
+		Head           struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if err := json.Unmarshal(body, &pr); err != nil {
+		return prStatus{}, fmt.Errorf("parse response: %w", err)
+	}
+
+	body, err = g.get(ctx, apiURL+"/reviews?per_page=100", "application/vnd.github+json")
+	if err != nil {
+		return prStatus{}, err
+	}
+	var reviews []struct {
+		State string `json:"state"`
+		User  struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	if err := json.Unmarshal(body, &reviews); err != nil {
+		return prStatus{}, fmt.Errorf("parse reviews: %w", err)
+	}
+
+	st := prStatus{
+		Number: number, Title: pr.Title, URL: pr.HTMLURL, State: pr.State,
+		Draft: pr.Draft, HeadSHA: pr.Head.SHA, Mergeable: pr.MergeableState,
+		Reviews: make(map[string]string),
+	}
+	if pr.Merged {
+		st.State = "merged"
+	}
+	// Reviews come oldest first; a comment doesn't undo an approval or a
+	// request for changes.
+	for _, r := range reviews {
+		state := strings.ToLower(r.State)
+		switch state {
+		case "approved", "changes_requested", "dismissed":
+		case "commented":
+			if st.Reviews[r.User.Login] != "" {
+				continue
+			}
+		default:
+			continue
+		}
+		st.Reviews[r.User.Login] = state
+	}
+	for user, state := range st.Reviews {
+		if state == "dismissed" {
+			delete(st.Reviews, user)
+		}
+	}
+	return st, nil
+}
+
+// SubmitReview posts a COMMENT review with inline comments on the PR. If GitHub
+// rejects the inline comments (a line outside the diff fails the whole review),
+// it retries once with the comments folded into the review body.
+func (g *GitHubProvider) SubmitReview(ctx context.Context, name string, number int, commitSHA string, review prReview) error {
+	err := g.postReview(ctx, name, number, commitSHA, review)
+	var statusErr *githubStatusError
+	if errors.As(err, &statusErr) && statusErr.status == http.StatusUnprocessableEntity && len(review.Comments) > 0 {
+		slog.WarnContext(ctx, "github: inline review comments rejected, posting as body", "err", err)
+		return g.postReview(ctx, name, number, commitSHA, review.inlineCommentsAsBody())
+	}
+	return err
+}
+
+func (g *GitHubProvider) postReview(ctx context.Context, name string, number int, commitSHA string, review prReview) error {
+	type comment struct {
+		Path string `json:"path"`
+		Line int    `json:"line"`
+		Side string `json:"side"`
+		Body string `json:"body"`
+	}
+	payload := struct {
+		CommitID string    `json:"commit_id,omitempty"`
+		Body     string    `json:"body"`
+		Event    string    `json:"event"`
+		Comments []comment `json:"comments,omitempty"`
+	}{CommitID: commitSHA, Body: review.Summary, Event: "COMMENT"}
+	for _, c := range review.Comments {
+		payload.Comments = append(payload.Comments, comment{Path: c.Path, Line: c.Line, Side: "RIGHT", Body: c.Body})
+	}
+	reqBody, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal review: %w", err)
+	}
+
+	apiURL := fmt.Sprintf("%s/repos/%s/pulls/%d/reviews", g.apiURL, g.repoPath(name), number)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+g.Token())
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("github api: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return &githubStatusError{status: resp.StatusCode, body: respBody}
+	}
+	return nil
+}
+
+// AnnotatePullRequest requests reviewers, adds labels and sets assignees on an
+// opened PR. Reviewers of the form "org/team" are requested as teams.
+func (g *GitHubProvider) AnnotatePullRequest(ctx context.Context, name, prURL string, opts PROptions) error {
+	number, err := prNumberFromURL(prURL)
+	if err != nil {
+		return err
+	}
+	users, teams := []string{}, append([]string{}, opts.TeamReviewers...)
+	for _, r := range opts.Reviewers {
+		if _, team, ok := strings.Cut(r, "/"); ok {
+			teams = append(teams, team)
+		} else {
+			users = append(users, strings.TrimPrefix(r, "@"))
+		}
+	}
+	if len(users) > 0 || len(teams) > 0 {
+		apiURL := fmt.Sprintf("%s/repos/%s/pulls/%d/requested_reviewers", g.apiURL, g.repoPath(name), number)
+		payload := map[string][]string{"reviewers": users, "team_reviewers": teams}
+		if err := g.post(ctx, apiURL, payload, http.StatusCreated); err != nil {
+			return fmt.Errorf("request reviewers: %w", err)
+		}
+	}
+	if len(opts.Labels) > 0 {
+		apiURL := fmt.Sprintf("%s/repos/%s/issues/%d/labels", g.apiURL, g.repoPath(name), number)
+		if err := g.post(ctx, apiURL, map[string][]string{"labels": opts.Labels}, http.StatusOK); err != nil {
+			return fmt.Errorf("add labels: %w", err)
+		}
+	}
+	if len(opts.Assignees) > 0 {
+		apiURL := fmt.Sprintf("%s/repos/%s/issues/%d/assignees", g.apiURL, g.repoPath(name), number)
+		if err := g.post(ctx, apiURL, map[string][]string{"assignees": opts.Assignees}, http.StatusCreated); err != nil {
+			return fmt.Errorf("add assignees: %w", err)
+		}
+	}
+	return nil
+}
+
+// Login returns the login of the account a personal access token acts as,
+// from GET /user, cached once known. A GitHub App installation acts as its
+// bot account, whose comments are marked as a bot's, so it returns "".
+func (g *GitHubProvider) Login(ctx context.Context) (string, error) {
+	if g.app != nil {
+		return "", nil
+	}
+	g.loginMu.Lock()
+	login := g.login
+	g.loginMu.Unlock()
+	if login != "" {
+		return login, nil
+	}
+	body, err := g.get(ctx, g.apiURL+"/user", "application/vnd.github+json")
+	if err != nil {
+		return "", err
+	}
+	var user struct {
+		Login string `json:"login"`
+	}
+	if err := json.Unmarshal(body, &user); err != nil || user.Login == "" {
+		return "", fmt.Errorf("github api: no login in GET /user response")
+	}
+	g.loginMu.Lock()
+	g.login = user.Login
+	g.loginMu.Unlock()
+	return user.Login, nil
+}
+
+// CanPush reports whether login has write access (write, maintain or admin)
+// to the repo. Someone who isn't a collaborator can't.
+func (g *GitHubProvider) CanPush(ctx context.Context, name, login string) (bool, error) {
+	body, err := g.get(ctx, fmt.Sprintf("%s/repos/%s/collaborators/%s/permission", g.apiURL, g.repoPath(name), url.PathEscape(login)), "application/vnd.github+json")
+	var statusErr *githubStatusError
+	if errors.As(err, &statusErr) && statusErr.status == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var perm struct {
+		Permission string `json:"permission"`
+	}
+	if err := json.Unmarshal(body, &perm); err != nil {
+		return false, fmt.Errorf("decode permission: %w", err)
+	}
+	return perm.Permission == "admin" || perm.Permission == "maintain" || perm.Permission == "write", nil
+}
+
+// Ping checks that the token (or the App installation) can call the API.
+// GET /rate_limit doesn't count against the rate limit.
+func (g *GitHubProvider) Ping(ctx context.Context) error {
+	_, err := g.get(ctx, g.apiURL+"/rate_limit", "application/vnd.github+json")
+	return err
+}
+
+// post performs an authenticated JSON POST and checks for wantStatus.
+func (g *GitHubProvider) post(ctx context.Context, url string, payload any, wantStatus int) error {
+	return g.send(ctx, http.MethodPost, url, payload, wantStatus)
+}
+
+// send performs an authenticated JSON request and checks for wantStatus.
+func (g *GitHubProvider) send(ctx context.Context, method, url string, payload any, wantStatus int) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+g.Token())
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("github api: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		respBody, _ := io.ReadAll(resp.Body)
+		return &githubStatusError{status: resp.StatusCode, body: respBody}
+	}
+	return nil
+}
+
+// get performs an authenticated GET and returns the body of a 200 response.
+func (g *GitHubProvider) get(ctx context.Context, url, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+g.Token())
+	req.Header.Set("Accept", accept)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("github api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &githubStatusError{status: resp.StatusCode, body: body}
+	}
+	return body, nil
+}
+
+// githubStatusError is an unexpected GitHub API response status.
+type githubStatusError struct {
+	status int
+	body   []byte
+}
+
+func (e *githubStatusError) Error() string {
+	return fmt.Sprintf("github api status %d: %s", e.status, e.body)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// tokenRefreshMargin is how long before expiry an installation token is renewed,
+// so a token handed to a long clone or push doesn't expire mid-operation.
+const tokenRefreshMargin = 10 * time.Minute
+
+// githubAppAuth authenticates as a GitHub App installation. It signs a JWT with
+// the App's private key and exchanges it for a short-lived (1h) installation
+// token, cached until shortly before it expires.
+type githubAppAuth struct {
+	appID          string
+	key            *rsa.PrivateKey
+	owner          string
+	installationID string // resolved from owner on first use if empty
+	apiURL         string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// newGitHubAppAuth parses the App's PEM-encoded private key. installationID may
+// be empty, in which case the installation for owner is looked up.
+func newGitHubAppAuth(appID string, keyPEM []byte, installationID, owner string) (*githubAppAuth, error) {
+	key, err := parseRSAPrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &githubAppAuth{
+		appID:          appID,
+		key:            key,
+		owner:          owner,
+		installationID: installationID,
+		apiURL:         "https://api.github.com",
+	}, nil
+}
+
+func parseRSAPrivateKey(keyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("github app: private key is not PEM-encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("github app: parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("github app: private key is not RSA")
+	}
+	return key, nil
+}
+
+// Token returns a valid installation token, minting a new one if needed.
+func (a *githubAppAuth) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && time.Until(a.expires) > tokenRefreshMargin {
+		return a.token, nil
+	}
+
+	jwt, err := a.jwt(time.Now())
+	if err != nil {
+		return "", err
+	}
+	if a.installationID == "" {
+		id, err := a.findInstallation(ctx, jwt)
+		if err != nil {
+			return "", err
+		}
+		a.installationID = id
+	}
+
+	var tok struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	url := fmt.Sprintf("%s/app/installations/%s/access_tokens", a.apiURL, a.installationID)
+	if err := a.do(ctx, http.MethodPost, url, jwt, http.StatusCreated, &tok); err != nil {
+		return "", fmt.Errorf("github app: create installation token: %w", err)
+	}
+	a.token, a.expires = tok.Token, tok.ExpiresAt
+	return a.token, nil
+}
+
+// findInstallation looks up the App's installation on the owner, trying an
+// organization first and then a user account.
+func (a *githubAppAuth) findInstallation(ctx context.Context, jwt string) (string, error) {
+	var inst struct {
+		ID int64 `json:"id"`
+	}
+	var lastErr error
+	for _, kind := range []string{"orgs", "users"} {
+		url := fmt.Sprintf("%s/%s/%s/installation", a.apiURL, kind, a.owner)
+		if lastErr = a.do(ctx, http.MethodGet, url, jwt, http.StatusOK, &inst); lastErr == nil {
+			return fmt.Sprintf("%d", inst.ID), nil
+		}
+	}
+	return "", fmt.Errorf("github app: no installation found for %q: %w", a.owner, lastErr)
+}
+
+// do performs an App-authenticated API request and decodes the response into v.
+func (a *githubAppAuth) do(ctx context.Context, method, url, jwt string, wantStatus int, v any) error {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("github api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != wantStatus {
+		return fmt.Errorf("github api status %d: %s", resp.StatusCode, body)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("parse response: %w", err)
+	}
+	return nil
+}
+
+// jwt returns an RS256-signed App JWT. iat is backdated to allow for clock
+// drift; GitHub caps exp at 10 minutes.
+func (a *githubAppAuth) jwt(now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-60 * time.Second).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": a.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("github app: sign jwt: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func testAppKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return key, keyPEM
+}
+
+func TestGitHubAppAuth_JWT(t *testing.T) {
+	key, keyPEM := testAppKey(t)
+	app, err := newGitHubAppAuth("123", keyPEM, "", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1_700_000_000, 0)
+	jwt, err := app.jwt(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("jwt has %d parts", len(parts))
+	}
+
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Iss != "123" || claims.Iat >= now.Unix() || claims.Exp-now.Unix() > 600 {
+		t.Errorf("claims = %+v", claims)
+	}
+}
+
+func TestGitHubAppAuth_Token(t *testing.T) {
+	_, keyPEM := testAppKey(t)
+
+	var minted atomic.Int32
+	expiresIn := time.Hour
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ey") {
+			t.Errorf("%s: missing app JWT", r.URL.Path)
+		}
+		switch r.URL.Path {
+		case "/orgs/acme/installation":
+			http.NotFound(w, r)
+		case "/users/acme/installation":
+			fmt.Fprint(w, `{"id":42}`)
+		case "/app/installations/42/access_tokens":
+			n := minted.Add(1)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]any{
+				"token":      fmt.Sprintf("ghs_%d", n),
+				"expires_at": time.Now().Add(expiresIn),
+			})
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	app, err := newGitHubAppAuth("123", keyPEM, "", "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.apiURL = srv.URL
+	gh := NewGitHubAppProvider("acme", app)
+
+	if got := gh.Token(); got != "ghs_1" {
+		t.Errorf("first token = %q, want ghs_1", got)
+	}
+	if got := gh.Token(); got != "ghs_1" {
+		t.Errorf("cached token = %q, want ghs_1", got)
+	}
+	if !strings.Contains(gh.FetchURL("repo"), "x-access-token:ghs_1@") {
+		t.Errorf("FetchURL = %q", gh.FetchURL("repo"))
+	}
+
+	// A token close to expiry is renewed.
+	app.expires = time.Now().Add(time.Minute)
+	if got := gh.Token(); got != "ghs_2" {
+		t.Errorf("renewed token = %q, want ghs_2", got)
+	}
+	if app.installationID != "42" {
+		t.Errorf("installationID = %q, want 42", app.installationID)
+	}
+}
+
+func TestParseRSAPrivateKey(t *testing.T) {
+	key, pkcs1 := testAppKey(t)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8 := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	for name, keyPEM := range map[string][]byte{"pkcs1": pkcs1, "pkcs8": pkcs8} {
+		if _, err := parseRSAPrivateKey(keyPEM); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := parseRSAPrivateKey([]byte("not a key")); err == nil {
+		t.Error("expected error for non-PEM input")
+	}
+}
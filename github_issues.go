@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GitHubIssuePlatform is the ChatPlatform for GitHub issues. The repo name is
+// the channel and the issue number the thread, so a job's plan, questions and
+// results are posted as issue comments.
+type GitHubIssuePlatform struct {
+	gh        []*GitHubProvider // one per owner, default first
+	login     string            // Bob's GitHub account: assigning an issue to it or mentioning it starts a job
+	mentionRe *regexp.Regexp
+}
+
+// NewGitHubIssuePlatform creates a GitHubIssuePlatform acting as login on the
+// issues of gh's owners.
+func NewGitHubIssuePlatform(gh []*GitHubProvider, login string) *GitHubIssuePlatform {
+	return &GitHubIssuePlatform{
+		gh:        gh,
+		login:     login,
+		mentionRe: regexp.MustCompile(`(?i)@` + regexp.QuoteMeta(login) + `\b\s*`),
+	}
+}
+
+// Name implements ChatPlatform.
+func (p *GitHubIssuePlatform) Name() string { return "github" }
+
+// Notify posts text as a comment on the issue in the context.
+func (p *GitHubIssuePlatform) Notify(ctx context.Context, text string) error {
+	repo, _ := ctx.Value(ctxKeyChannel).(string)
+	thread, _ := ctx.Value(ctxKeyThreadTS).(string)
+	number, err := strconv.Atoi(thread)
+	if repo == "" || err != nil {
+		return fmt.Errorf("notify: no github issue in context")
+	}
+	gh := p.provider(repo)
+	if gh == nil {
+		return fmt.Errorf("notify: no GitHub owner configured for %q", repo)
+	}
+	return gh.CreateIssueComment(ctx, repo, number, text)
+}
+
+// provider returns the provider for the owner of repo, a name as FindRepo
+// returns it, or nil if that owner isn't configured.
+func (p *GitHubIssuePlatform) provider(repo string) *GitHubProvider {
+	return githubProviderFor(p.gh, repo)
+}
+
+// githubProviderFor returns the provider in gh for the owner of repo, or nil
+// if that owner isn't configured. An unqualified name is the default owner's.
+func githubProviderFor(gh []*GitHubProvider, repo string) *GitHubProvider {
+	owner, _ := splitRepoName(repo)
+	for _, g := range gh {
+		if owner == "" && !g.qualified || owner != "" && strings.EqualFold(owner, g.owner) {
+			return g
+		}
+	}
+	return nil
+}
+
+// repoName returns the name Bob knows the webhook's repository by, or empty
+// string if its owner isn't configured.
+func (p *GitHubIssuePlatform) repoName(owner, name string) string {
+	for _, g := range p.gh {
+		if strings.EqualFold(owner, g.owner) {
+			return g.repoName(name)
+		}
+	}
+	return ""
+}
+
+// ThreadMessages implements ChatPlatform. Comments by Bob's account or by bots
+// count as assistant messages.
+func (p *GitHubIssuePlatform) ThreadMessages(ctx context.Context, repo, thread string) ([]Message, error) {
+	number, err := strconv.Atoi(thread)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issue number %q", thread)
+	}
+	gh := p.provider(repo)
+	if gh == nil {
+		return nil, fmt.Errorf("no GitHub owner configured for %q", repo)
+	}
+	conv, err := gh.IssueConversation(ctx, repo, number)
+	if err != nil {
+		return nil, err
+	}
+	var messages []Message
+	for _, c := range conv {
+		text := p.StripMention(c.Body)
+		if text == "" {
+			continue
+		}
+		role := RoleUser
+		if strings.EqualFold(c.Author, p.login) || c.AuthorType == "Bot" {
+			role = RoleAssistant
+		}
+		messages = append(messages, Message{Role: role, Content: text})
+	}
+	return messages, nil
+}
+
+// StripMention implements ChatPlatform.
+func (p *GitHubIssuePlatform) StripMention(text string) string {
+	return strings.TrimSpace(p.mentionRe.ReplaceAllString(text, ""))
+}
+
+// MentionUser implements ChatPlatform.
+func (p *GitHubIssuePlatform) MentionUser(login string) string { return "@" + login }
+
+// mentions reports whether text mentions Bob's account.
+func (p *GitHubIssuePlatform) mentions(text string) bool {
+	return p.mentionRe.MatchString(text)
+}
+
+// githubIssueEvent covers the fields we use from issues and issue_comment
+// webhook payloads.
+type githubIssueEvent struct {
+	Action string `json:"action"`
+	Issue  struct {
+		Number      int       `json:"number"`
+		Title       string    `json:"title"`
+		Body        string    `json:"body"`
+		State       string    `json:"state"`
+		PullRequest *struct{} `json:"pull_request"` // set when the issue is a PR
+	} `json:"issue"`
+	Assignee *struct {
+		Login string `json:"login"`
+	} `json:"assignee"`
+	Comment *struct {
+		Body string `json:"body"`
+		User struct {
+			Login string `json:"login"`
+			Type  string `json:"type"`
+		} `json:"user"`
+	} `json:"comment"`
+	Sender struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+	Repository struct {
+		Name  string `json:"name"`
+		Owner struct {
+			Login string `json:"login"`
+		} `json:"owner"`
+	} `json:"repository"`
+}
+
+// issueDispatcher turns issue webhooks into chat mentions handled by the same
+// flow as Discord: plan, "go" to approve, implementation and a PR. Job links
+// posted on issues never carry the API token, since issues may be public.
+type issueDispatcher struct {
+	platform *GitHubIssuePlatform
+	orch     *Orchestrator
+	hub      *Hub
+	approver *Approver
+	limits   *userLimits // per-user rate limit and quotas, by GitHub login
+	bobURL   string
+}
+
+func newIssueDispatcher(platform *GitHubIssuePlatform, orch *Orchestrator, hub *Hub, approver *Approver, limits *userLimits, bobURL string) *issueDispatcher {
+	return &issueDispatcher{platform: platform, orch: orch, hub: hub, approver: approver, limits: limits, bobURL: bobURL}
+}
+
+// dispatch handles an issues or issue_comment event, reporting whether it
+// started work.
+func (d *issueDispatcher) dispatch(eventType string, evt githubIssueEvent) bool {
+	m, ok := d.mentionFromEvent(eventType, evt)
+	if !ok {
+		return false
+	}
+	slog.Info("github: issue event", "event", eventType, "repo", m.Channel, "issue", m.Thread, "user", m.User)
+	if !d.limits.allow(d.platform.Name(), m.User) {
+		slog.Warn("github: rate limited issue event", "user", m.User, "repo", m.Channel, "issue", m.Thread)
+		go func() {
+			ctx := WithSlackThread(context.Background(), m.Channel, m.Thread)
+			if err := d.platform.Notify(ctx, fmt.Sprintf("%s You're sending me requests faster than I can take them. Please try again in a moment.", d.platform.MentionUser(m.User))); err != nil {
+				slog.Error("github: failed to post message", "err", err)
+			}
+		}()
+		return false
+	}
+	go handleChatMention(d.platform, d.orch, d.hub, d.approver, d.limits, d.bobURL, "", m)
+	return true
+}
+
+// mentionFromEvent converts an issue webhook into a chat mention, returning
+// false for events Bob should ignore. Assigning an open issue to Bob starts a
+// job from the issue; a comment counts when it mentions Bob, or when it
+// approves a plan that is awaiting approval on that issue. Only the job's
+// requester or someone with write access can approve a plan or a push: on a
+// public repo anyone can comment.
+func (d *issueDispatcher) mentionFromEvent(eventType string, evt githubIssueEvent) (chatMention, bool) {
+	if evt.Issue.PullRequest != nil || evt.Issue.State != "open" {
+		return chatMention{}, false
+	}
+	repo, thread := d.platform.repoName(evt.Repository.Owner.Login, evt.Repository.Name), strconv.Itoa(evt.Issue.Number)
+	if repo == "" {
+		return chatMention{}, false // an owner Bob doesn't serve
+	}
+	m := chatMention{Channel: repo, Thread: thread, DefaultRepo: repo}
+
+	switch {
+	case eventType == "issues" && evt.Action == "assigned":
+		if evt.Assignee == nil || !strings.EqualFold(evt.Assignee.Login, d.platform.login) {
+			return chatMention{}, false
+		}
+		if d.hub.ActiveJobForThread(repo, thread) != "" {
+			return chatMention{}, false // already working on it
+		}
+		m.User = evt.Sender.Login
+		m.Text = fmt.Sprintf("Issue #%d: %s\n\n%s", evt.Issue.Number, evt.Issue.Title, evt.Issue.Body)
+		m.NewThread = true
+		return m, true
+
+	case eventType == "issue_comment" && evt.Action == "created":
+		if evt.Comment == nil || evt.Comment.User.Type == "Bot" || strings.EqualFold(evt.Comment.User.Login, d.platform.login) {
+			return chatMention{}, false
+		}
+		m.User = evt.Comment.User.Login
+		m.Text = evt.Comment.Body
+		phase, requester := d.activeJob(repo, thread)
+		text := d.platform.StripMention(m.Text)
+		approving := phase == PhaseAwaitingApproval && isApprovalText(text) || phase == PhaseAwaitingPush && isPushText(text)
+		if approving && !d.mayApprove(repo, requester, m.User) {
+			slog.Warn("github: ignoring approval from someone who can't give it", "repo", repo, "issue", thread, "user", m.User)
+			return chatMention{}, false
+		}
+		if d.platform.mentions(m.Text) || approving {
+			return m, true
+		}
+	}
+	return chatMention{}, false
+}
+
+// activeJob returns the phase and requester of the issue's active job, or
+// empty strings if it has none.
+func (d *issueDispatcher) activeJob(repo, thread string) (JobPhase, string) {
+	jobID := d.hub.ActiveJobForThread(repo, thread)
+	if jobID == "" {
+		return "", ""
+	}
+	state, ok := d.hub.GetJobState(jobID)
+	if !ok {
+		return "", ""
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.Phase, state.RequestedBy
+}
+
+// mayApprove reports whether login can approve the job requester asked for on
+// repo: the requester can, and so can anyone with write access. A failed
+// permission check counts as no.
+func (d *issueDispatcher) mayApprove(repo, requester, login string) bool {
+	if requester != "" && strings.EqualFold(login, requester) {
+		return true
+	}
+	gh := d.platform.provider(repo)
+	if gh == nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ok, err := gh.CanPush(ctx, repo, login)
+	if err != nil {
+		slog.Warn("github: failed to check permission", "repo", repo, "user", login, "err", err)
+		return false
+	}
+	return ok
+}
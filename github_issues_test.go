@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func issueEvent(action string, mutate func(*githubIssueEvent)) githubIssueEvent {
+	var evt githubIssueEvent
+	evt.Action = action
+	evt.Issue.Number = 7
+	evt.Issue.Title = "Login is broken"
+	evt.Issue.Body = "Steps..."
+	evt.Issue.State = "open"
+	evt.Repository.Name = "web"
+	evt.Repository.Owner.Login = "acme"
+	evt.Sender.Login = "alice"
+	if mutate != nil {
+		mutate(&evt)
+	}
+	return evt
+}
+
+func withComment(body, login, userType string) func(*githubIssueEvent) {
+	return func(evt *githubIssueEvent) {
+		evt.Comment = &struct {
+			Body string `json:"body"`
+			User struct {
+				Login string `json:"login"`
+				Type  string `json:"type"`
+			} `json:"user"`
+		}{Body: body}
+		evt.Comment.User.Login = login
+		evt.Comment.User.Type = userType
+	}
+}
+
+func withAssignee(login string) func(*githubIssueEvent) {
+	return func(evt *githubIssueEvent) {
+		evt.Assignee = &struct {
+			Login string `json:"login"`
+		}{Login: login}
+	}
+}
+
+func TestIssueDispatcher_MentionFromEvent(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	// carol maintains acme/web; mallory is just passing by.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/web/collaborators/carol/permission":
+			w.Write([]byte(`{"permission":"write"}`))
+		case "/repos/acme/web/collaborators/mallory/permission":
+			w.Write([]byte(`{"permission":"read"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	d := newIssueDispatcher(NewGitHubIssuePlatform([]*GitHubProvider{{owner: "acme", apiURL: srv.URL}, {owner: "acme-labs", qualified: true}}, "bob-bot"), nil, hub, nil, nil, "")
+
+	// Issue 8 has a plan awaiting approval, requested by alice.
+	hub.SetJobState("job-8", &JobState{Phase: PhaseAwaitingApproval, Channel: "web", ThreadTS: "8", RequestedBy: "alice"})
+	hub.RegisterThreadJob("web", "8", "job-8")
+	onIssue8 := func(evt *githubIssueEvent) { evt.Issue.Number = 8 }
+
+	tests := []struct {
+		name      string
+		eventType string
+		evt       githubIssueEvent
+		want      bool
+		newThread bool
+	}{
+		{"assigned to bob", "issues", issueEvent("assigned", withAssignee("Bob-Bot")), true, true},
+		{"assigned to someone else", "issues", issueEvent("assigned", withAssignee("carol")), false, false},
+		{"assigned while job active", "issues", issueEvent("assigned", func(e *githubIssueEvent) { withAssignee("bob-bot")(e); onIssue8(e) }), false, false},
+		{"closed issue", "issues", issueEvent("assigned", func(e *githubIssueEvent) { withAssignee("bob-bot")(e); e.Issue.State = "closed" }), false, false},
+		{"pull request", "issue_comment", issueEvent("created", func(e *githubIssueEvent) {
+			withComment("@bob-bot fix", "alice", "User")(e)
+			e.Issue.PullRequest = &struct{}{}
+		}), false, false},
+		{"comment mentions bob", "issue_comment", issueEvent("created", withComment("@bob-bot please fix", "alice", "User")), true, false},
+		{"comment without mention", "issue_comment", issueEvent("created", withComment("any update?", "alice", "User")), false, false},
+		{"comment by a bot", "issue_comment", issueEvent("created", withComment("@bob-bot hi", "ci", "Bot")), false, false},
+		{"approval without mention", "issue_comment", issueEvent("created", func(e *githubIssueEvent) { withComment("go", "alice", "User")(e); onIssue8(e) }), true, false},
+		{"approval by a maintainer", "issue_comment", issueEvent("created", func(e *githubIssueEvent) { withComment("lgtm", "carol", "User")(e); onIssue8(e) }), true, false},
+		{"approval by a third party", "issue_comment", issueEvent("created", func(e *githubIssueEvent) { withComment("go", "mallory", "User")(e); onIssue8(e) }), false, false},
+		{"approval mentioning bob by a third party", "issue_comment", issueEvent("created", func(e *githubIssueEvent) { withComment("@bob-bot go", "mallory", "User")(e); onIssue8(e) }), false, false},
+		{"approval by a stranger", "issue_comment", issueEvent("created", func(e *githubIssueEvent) { withComment("go", "eve", "User")(e); onIssue8(e) }), false, false},
+		{"question by a third party", "issue_comment", issueEvent("created", func(e *githubIssueEvent) { withComment("@bob-bot why?", "mallory", "User")(e); onIssue8(e) }), true, false},
+		{"approval with no plan", "issue_comment", issueEvent("created", withComment("go", "alice", "User")), false, false},
+		{"edited comment", "issue_comment", issueEvent("edited", withComment("@bob-bot fix", "alice", "User")), false, false},
+		{"owner not served", "issues", issueEvent("assigned", func(e *githubIssueEvent) { withAssignee("bob-bot")(e); e.Repository.Owner.Login = "someone-else" }), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := d.mentionFromEvent(tt.eventType, tt.evt)
+			if ok != tt.want {
+				t.Fatalf("ok = %v, want %v", ok, tt.want)
+			}
+			if !ok {
+				return
+			}
+			if m.Channel != "web" || m.DefaultRepo != "web" || m.NewThread != tt.newThread {
+				t.Errorf("mention = %+v", m)
+			}
+		})
+	}
+
+	m, ok := d.mentionFromEvent("issues", issueEvent("assigned", func(e *githubIssueEvent) { withAssignee("bob-bot")(e); e.Repository.Owner.Login = "ACME-Labs" }))
+	if !ok || m.Channel != "acme-labs/web" || m.DefaultRepo != "acme-labs/web" {
+		t.Errorf("other owner's issue: ok = %v, mention = %+v", ok, m)
+	}
+}
+
+func TestGitHubIssuePlatform_StripMention(t *testing.T) {
+	p := NewGitHubIssuePlatform(nil, "bob")
+	tests := []struct {
+		in, want string
+	}{
+		{"@bob fix the bug", "fix the bug"},
+		{"@Bob go", "go"},
+		{"ask @bobby", "ask @bobby"},
+	}
+	for _, tt := range tests {
+		if got := p.StripMention(tt.in); got != tt.want {
+			t.Errorf("StripMention(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if p.mentions("cc @bobby") {
+		t.Error("@bobby should not count as a mention of @bob")
+	}
+}
+
+func TestGitHubIssuePlatform_NotifyAndThread(t *testing.T) {
+	var posted string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /repos/acme/web/issues/7/comments", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		posted = body["body"]
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("GET /repos/acme/web/issues/7", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"title":"Login is broken","body":"It 500s","user":{"login":"alice","type":"User"}}`))
+	})
+	mux.HandleFunc("GET /repos/acme/web/issues/7/comments", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"body":"Here's my plan","user":{"login":"bob","type":"User"}},{"body":"@bob go","user":{"login":"alice","type":"User"}}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	gh := &GitHubProvider{owner: "acme", token: "tok", apiURL: srv.URL}
+	p := NewGitHubIssuePlatform([]*GitHubProvider{gh}, "bob")
+
+	ctx := WithSlackThread(context.Background(), "web", "7")
+	if err := p.Notify(ctx, "Done!"); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if posted != "Done!" {
+		t.Errorf("posted %q", posted)
+	}
+
+	msgs, err := p.ThreadMessages(context.Background(), "web", "7")
+	if err != nil {
+		t.Fatalf("ThreadMessages: %v", err)
+	}
+	want := []Message{
+		{Role: RoleUser, Content: "Login is broken\n\nIt 500s"},
+		{Role: RoleAssistant, Content: "Here's my plan"},
+		{Role: RoleUser, Content: "go"},
+	}
+	if len(msgs) != len(want) {
+		t.Fatalf("got %d messages, want %d: %+v", len(msgs), len(want), msgs)
+	}
+	for i := range want {
+		if msgs[i] != want[i] {
+			t.Errorf("message %d = %+v, want %+v", i, msgs[i], want[i])
+		}
+	}
+
+	if err := p.Notify(context.Background(), "x"); err == nil {
+		t.Error("expected error without an issue in context")
+	}
+}
+
+func TestIssueDispatcher_RateLimited(t *testing.T) {
+	drainHub(t)
+	limits := newUserLimits(1, 0, 0)
+	d := newIssueDispatcher(NewGitHubIssuePlatform([]*GitHubProvider{{owner: "acme"}}, "bob-bot"), nil, NewHub(t.TempDir()), nil, limits, "")
+
+	// alice used her one request this minute, through any issue.
+	limits.allow("github", "alice")
+	if d.dispatch("issue_comment", issueEvent("created", withComment("@bob-bot please fix", "alice", "User"))) {
+		t.Error("rate limited comment started work")
+	}
+	if d.dispatch("issues", issueEvent("assigned", func(e *githubIssueEvent) { withAssignee("bob-bot")(e); e.Sender.Login = "alice" })) {
+		t.Error("rate limited assignment started work")
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// repoListTTL is how long the owner's repository list is reused before it is
+// fetched again.
+const repoListTTL = 10 * time.Minute
+
+// repoListCache holds the owner's repositories between lookups.
+type repoListCache struct {
+	mu      sync.Mutex
+	repos   []repo
+	fetched time.Time
+}
+
+// ListRepos returns every repository in the owner's org or account that
+// repoRules permits, following the API's pagination, named as FindRepo names
+// them. The list is cached for repoListTTL.
+func (g *GitHubProvider) ListRepos(ctx context.Context) ([]repo, error) {
+	g.repoList.mu.Lock()
+	defer g.repoList.mu.Unlock()
+	if g.repoList.repos != nil && time.Since(g.repoList.fetched) < repoListTTL {
+		return repoRules.Load().filter(g.repoList.repos), nil
+	}
+
+	repos, err := g.fetchRepos(ctx, fmt.Sprintf("%s/orgs/%s/repos?per_page=100", g.apiURL, g.owner))
+	var se *githubStatusError
+	if errors.As(err, &se) && se.status == http.StatusNotFound {
+		// Not an org: list the authenticated user's own repositories.
+		repos, err = g.fetchRepos(ctx, g.apiURL+"/user/repos?affiliation=owner&per_page=100")
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range repos {
+		repos[i].Name = g.repoName(repos[i].Name)
+	}
+	g.repoList.repos, g.repoList.fetched = repos, time.Now()
+	return repoRules.Load().filter(repos), nil
+}
+
+// fetchRepos reads a repository listing page by page.
+func (g *GitHubProvider) fetchRepos(ctx context.Context, url string) ([]repo, error) {
+	repos := []repo{}
+	for url != "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+g.Token())
+		req.Header.Set("Accept", "application/vnd.github+json")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("github api: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read response: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, &githubStatusError{status: resp.StatusCode, body: body}
+		}
+
+		var page []repo
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, fmt.Errorf("parse response: %w", err)
+		}
+		repos = append(repos, page...)
+		url = nextLink(resp.Header.Get("Link"))
+	}
+	return repos, nil
+}
+
+// linkNext matches the rel="next" entry of a Link header.
+var linkNext = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// nextLink returns the URL of the next page from a Link header, or "" on the
+// last page.
+func nextLink(header string) string {
+	if m := linkNext.FindStringSubmatch(header); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// matchRepo finds the repository a loosely written name refers to, ignoring
+// case and separators ("Lets Meet" → "letsmeet"). It only answers when exactly
+// one repository matches.
+func matchRepo(repos []repo, name string) (repo, bool) {
+	_, name = splitRepoName(name)
+	want := normalizeRepoName(name)
+	var found []repo
+	for _, r := range repos {
+		if _, short := splitRepoName(r.Name); normalizeRepoName(short) == want {
+			found = append(found, r)
+		}
+	}
+	if len(found) != 1 {
+		return repo{}, false
+	}
+	return found[0], true
+}
+
+// normalizeRepoName lowercases a name and drops spaces, dashes, underscores
+// and dots.
+func normalizeRepoName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '_', '.':
+			return -1
+		}
+		return r
+	}, strings.ToLower(name))
+}
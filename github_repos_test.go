@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHubProvider_ListRepos(t *testing.T) {
+	var srv *httptest.Server
+	calls := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orgs/acme/repos", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/orgs/acme/repos?per_page=100&page=2>; rel="next", <%[1]s/orgs/acme/repos?per_page=100&page=2>; rel="last"`, srv.URL))
+			w.Write([]byte(`[{"name":"web","default_branch":"main"}]`))
+		case "2":
+			w.Write([]byte(`[{"name":"Lets-Meet","default_branch":"develop"}]`))
+		}
+	})
+	mux.HandleFunc("GET /repos/acme/{name}", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	srv = httptest.NewServer(mux)
+	defer srv.Close()
+
+	gh := &GitHubProvider{owner: "acme", token: "tok", apiURL: srv.URL}
+	repos, err := gh.ListRepos(context.Background())
+	if err != nil {
+		t.Fatalf("ListRepos: %v", err)
+	}
+	if len(repos) != 2 || repos[1].Name != "Lets-Meet" {
+		t.Fatalf("repos = %+v, want both pages", repos)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+
+	// The second page's repo is found by a loose name, from the cached list.
+	r, err := gh.FindRepo(context.Background(), "lets_meet")
+	if err != nil {
+		t.Fatalf("FindRepo: %v", err)
+	}
+	if r.Name != "Lets-Meet" || r.DefaultBranch != "develop" {
+		t.Errorf("FindRepo = %+v", r)
+	}
+	if calls != 2 {
+		t.Errorf("calls = %d, want the list to be cached", calls)
+	}
+
+	if _, err := gh.FindRepo(context.Background(), "api"); err == nil {
+		t.Error("expected an error for an unknown repo")
+	}
+}
+
+func TestGitHubProvider_ListReposUser(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orgs/alice/repos", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("GET /user/repos", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name":"dotfiles"}]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	gh := &GitHubProvider{owner: "alice", token: "tok", apiURL: srv.URL}
+	repos, err := gh.ListRepos(context.Background())
+	if err != nil {
+		t.Fatalf("ListRepos: %v", err)
+	}
+	if len(repos) != 1 || repos[0].Name != "dotfiles" {
+		t.Errorf("repos = %+v", repos)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		header, want string
+	}{
+		{"", ""},
+		{`<https://api.github.com/x?page=3>; rel="next", <https://api.github.com/x?page=5>; rel="last"`, "https://api.github.com/x?page=3"},
+		{`<https://api.github.com/x?page=1>; rel="first", <https://api.github.com/x?page=4>; rel="prev"`, ""},
+	}
+	for _, tt := range tests {
+		if got := nextLink(tt.header); got != tt.want {
+			t.Errorf("nextLink(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestMatchRepo(t *testing.T) {
+	repos := []repo{{Name: "letsmeet"}, {Name: "web-app"}, {Name: "web_app"}}
+	if r, ok := matchRepo(repos, "LetsMeet"); !ok || r.Name != "letsmeet" {
+		t.Errorf("matchRepo(LetsMeet) = %+v, %v", r, ok)
+	}
+	if _, ok := matchRepo(repos, "webapp"); ok {
+		t.Error("ambiguous name should not match")
+	}
+	if _, ok := matchRepo(repos, "api"); ok {
+		t.Error("unknown name should not match")
+	}
+}
+
+func TestFindRepo_Owners(t *testing.T) {
+	mux := http.NewServeMux()
+	for path, name := range map[string]string{"/repos/acme/api": "api", "/repos/acme-labs/api": "api", "/repos/acme-labs/tools": "tools"} {
+		mux.HandleFunc("GET "+path, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name":"` + name + `","default_branch":"main"}`))
+		})
+	}
+	mux.HandleFunc("GET /repos/{owner}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("GET /orgs/{owner}/repos", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	acme := &GitHubProvider{owner: "acme", token: "tok", apiURL: srv.URL}
+	labs := &GitHubProvider{owner: "acme-labs", qualified: true, token: "tok", apiURL: srv.URL}
+	providers := []VCSProvider{acme, labs}
+
+	for name, want := range map[string]struct {
+		vcs  *GitHubProvider
+		repo string
+	}{
+		"api":             {acme, "api"},
+		"acme/api":        {acme, "api"},
+		"acme-labs/api":   {labs, "acme-labs/api"},
+		"ACME-Labs/api":   {labs, "acme-labs/api"},
+		"tools":           {labs, "acme-labs/tools"},
+		"acme-labs/tools": {labs, "acme-labs/tools"},
+	} {
+		vcs, r, err := findRepo(context.Background(), providers, name)
+		if err != nil || vcs != want.vcs || r.Name != want.repo {
+			t.Errorf("findRepo(%q) = %v, %q, %v; want %s's %q", name, vcs, r.Name, err, want.vcs.owner, want.repo)
+		}
+	}
+	if _, _, err := findRepo(context.Background(), providers, "other-org/api"); err == nil {
+		t.Error("findRepo should not find a repo of an owner that isn't configured")
+	}
+	if got := labs.CleanURL("acme-labs/api"); got != "https://github.com/acme-labs/api.git" {
+		t.Errorf("CleanURL = %q", got)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHubProvider_OpenPullRequest(t *testing.T) {
+	var updated map[string]string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /repos/acme/web/pulls", func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Head string }
+		json.NewDecoder(r.Body).Decode(&body)
+		switch body.Head {
+		case "bob/new":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"html_url":"https://github.com/acme/web/pull/1"}`))
+		case "bob/open":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"message":"Validation Failed","errors":[{"resource":"PullRequest","code":"custom","message":"A pull request already exists for acme:bob/open."}]}`))
+		case "bob/empty":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"message":"Validation Failed","errors":[{"resource":"PullRequest","code":"custom","message":"No commits between main and bob/empty"}]}`))
+		default:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"message":"Validation Failed"}`))
+		}
+	})
+	mux.HandleFunc("GET /repos/acme/web/pulls", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("head") != "acme:bob/open" || r.URL.Query().Get("state") != "open" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`[{"number":7,"html_url":"https://github.com/acme/web/pull/7"}]`))
+	})
+	mux.HandleFunc("PATCH /repos/acme/web/pulls/7", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&updated)
+		w.Write([]byte(`{}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	g := &GitHubProvider{owner: "acme", token: "tok", apiURL: srv.URL}
+	ctx := context.Background()
+
+	if url, err := g.OpenPullRequest(ctx, "web", "bob/new", "main", "Fix login", "body", false); err != nil || url != "https://github.com/acme/web/pull/1" {
+		t.Errorf("new PR: %q, %v", url, err)
+	}
+	url, err := g.OpenPullRequest(ctx, "web", "bob/open", "main", "Fix login", "new body", false)
+	if err != nil || url != "https://github.com/acme/web/pull/7" {
+		t.Errorf("existing PR: %q, %v", url, err)
+	}
+	if updated["title"] != "Fix login" || updated["body"] != "new body" {
+		t.Errorf("existing PR updated with %v", updated)
+	}
+	if _, err := g.OpenPullRequest(ctx, "web", "bob/empty", "main", "Fix login", "body", false); !errors.Is(err, ErrNoChanges) {
+		t.Errorf("no commits: err = %v, want ErrNoChanges", err)
+	}
+	if _, err := g.OpenPullRequest(ctx, "web", "bob/bad", "main", "Fix login", "body", false); err == nil || errors.Is(err, ErrNoChanges) {
+		t.Errorf("other validation error: err = %v", err)
+	}
+}
+
+func TestGitHubProvider_Ping(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rate_limit" || r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"resources":{}}`))
+	}))
+	defer srv.Close()
+
+	if err := (&GitHubProvider{owner: "acme", token: "tok", apiURL: srv.URL}).Ping(context.Background()); err != nil {
+		t.Errorf("valid token: %v", err)
+	}
+	if err := (&GitHubProvider{owner: "acme", token: "bad", apiURL: srv.URL}).Ping(context.Background()); err == nil {
+		t.Error("expected error on 401")
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxGitHubBodySize is the maximum request body size accepted from GitHub webhooks.
+const maxGitHubBodySize = 5 << 20 // 5 MB
+
+// githubReviewCommentEvent covers the fields we use from a
+// pull_request_review_comment webhook payload.
+type githubReviewCommentEvent struct {
+	Action  string `json:"action"`
+	Comment struct {
+		ID       int64  `json:"id"`
+		Body     string `json:"body"`
+		Path     string `json:"path"`
+		Line     int    `json:"line"`
+		DiffHunk string `json:"diff_hunk"`
+		User     struct {
+			Login string `json:"login"`
+			Type  string `json:"type"`
+		} `json:"user"`
+	} `json:"comment"`
+	PullRequest struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+		State   string `json:"state"`
+		Head    struct {
+			Ref string `json:"ref"`
+		} `json:"head"`
+	} `json:"pull_request"`
+	Repository struct {
+		Name     string `json:"name"`
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// verifyGitHubSignature checks the X-Hub-Signature-256 header against the body.
+func verifyGitHubSignature(secret string, body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	want, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// isBobBranch reports whether a PR head branch was created by Bob.
+func isBobBranch(ref string) bool {
+	return strings.HasPrefix(ref, "bob/")
+}
+
+// NewGitHubWebhookHandler handles GitHub webhooks. Review comments on open,
+// Bob-created PRs by their requester or a repo writer trigger a follow-up
+// implementation on the same branch, within the author's rate limit and
+// quotas. When
+// issues is set, issue assignments and comments drive the issue workflow.
+func NewGitHubWebhookHandler(secret string, orch *Orchestrator, issues *issueDispatcher, accounts *githubAccounts, limits *userLimits) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxGitHubBodySize+1))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxGitHubBodySize {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if !verifyGitHubSignature(secret, body, r.Header.Get("X-Hub-Signature-256")) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		switch eventType := r.Header.Get("X-GitHub-Event"); eventType {
+		case "pull_request_review_comment":
+		case "issues", "issue_comment":
+			if issues == nil {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			var evt githubIssueEvent
+			if err := json.Unmarshal(body, &evt); err != nil {
+				http.Error(w, "failed to parse event", http.StatusBadRequest)
+				return
+			}
+			if issues.dispatch(eventType, evt) {
+				w.WriteHeader(http.StatusAccepted)
+			} else {
+				w.WriteHeader(http.StatusNoContent)
+			}
+			return
+		default:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		var evt githubReviewCommentEvent
+		if err := json.Unmarshal(body, &evt); err != nil {
+			http.Error(w, "failed to parse event", http.StatusBadRequest)
+			return
+		}
+
+		rc, ok := reviewCommentFromEvent(evt)
+		if !ok || accounts.isBob(r.Context(), rc.Author) {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		var hub *Hub
+		if orch != nil {
+			hub = orch.hub
+		}
+		if !accounts.mayChangePR(r.Context(), hub, rc) {
+			slog.Warn("github: ignoring review comment from someone who can't change the PR", "comment_id", rc.CommentID, "user", rc.Author, "pr", rc.PRURL)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		slog.Info("github: review comment", "comment_id", rc.CommentID, "user", rc.Author, "pr", rc.PRURL)
+		if !limits.allow("github", rc.Author) {
+			slog.Warn("github: rate limited review comment", "comment_id", rc.CommentID, "user", rc.Author, "pr", rc.PRURL)
+			go accounts.reply(rc, "You're sending me requests faster than I can take them. Please try again in a moment.")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if msg := limits.quotaExceeded("github", rc.Author, true); msg != "" {
+			go accounts.reply(rc, msg)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		go func() {
+			defer recoverGoroutine("github: review comment", nil)
+			started := func(jobID string) { limits.startJob("github", rc.Author, jobID) }
+			if _, err := orch.HandleReviewComment(context.Background(), rc, started); err != nil {
+				slog.Error("github: review comment failed", "comment_id", rc.CommentID, "err", err)
+			}
+		}()
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// githubAccounts are Bob's own GitHub accounts: GITHUB_BOT_LOGIN and the
+// users the providers' personal access tokens act as. Bob's replies to review
+// comments come from one of them, and must not be taken for new comments.
+type githubAccounts struct {
+	botLogin  string
+	providers []*GitHubProvider
+}
+
+// isBob reports whether login is one of Bob's accounts. If a token's account
+// can't be looked up it errs on the side of yes: ignoring a person's comment
+// is better than answering Bob's own replies in a loop.
+func (a *githubAccounts) isBob(ctx context.Context, login string) bool {
+	if a == nil {
+		return false
+	}
+	if a.botLogin != "" && strings.EqualFold(login, a.botLogin) {
+		return true
+	}
+	for _, p := range a.providers {
+		own, err := p.Login(ctx)
+		if err != nil {
+			slog.WarnContext(ctx, "github: couldn't look up the token's account, ignoring the comment", "owner", p.owner, "user", login, "err", err)
+			return true
+		}
+		if own != "" && strings.EqualFold(login, own) {
+			return true
+		}
+	}
+	return false
+}
+
+// mayChangePR reports whether a review comment's author may have Bob change
+// the PR: whoever asked for it on GitHub can, and so can anyone with write
+// access to the repo. On a public repo anyone can comment, and the follow-up
+// is pushed without an approval. A failed permission check counts as no.
+func (a *githubAccounts) mayChangePR(ctx context.Context, hub *Hub, rc ReviewComment) bool {
+	if platform, requester := hub.PRRequester(rc.PRURL); platform == "github" && requester != "" && strings.EqualFold(rc.Author, requester) {
+		return true
+	}
+	if a == nil {
+		return false
+	}
+	gh := githubProviderFor(a.providers, rc.Repo)
+	if gh == nil {
+		return false
+	}
+	ok, err := gh.CanPush(ctx, rc.Repo, rc.Author)
+	if err != nil {
+		slog.WarnContext(ctx, "github: failed to check permission", "repo", rc.Repo, "user", rc.Author, "err", err)
+		return false
+	}
+	return ok
+}
+
+// reply answers rc on its review thread, addressed to its author, when Bob
+// turns it down before starting a job.
+func (a *githubAccounts) reply(rc ReviewComment, body string) {
+	if a == nil {
+		return
+	}
+	gh := githubProviderFor(a.providers, rc.Repo)
+	if gh == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := gh.ReplyToReviewComment(ctx, rc.Repo, rc.PRNumber, rc.CommentID, "@"+rc.Author+" "+body); err != nil {
+		slog.Error("github: failed to reply to review comment", "comment_id", rc.CommentID, "err", err)
+	}
+}
+
+// reviewCommentFromEvent converts a webhook payload into a ReviewComment,
+// returning false for events Bob should ignore: anything but new comments,
+// closed PRs, PRs Bob didn't create, and comments from bots (including Bob's
+// GitHub App; see githubAccounts for his token's account).
+func reviewCommentFromEvent(evt githubReviewCommentEvent) (ReviewComment, bool) {
+	if evt.Action != "created" || evt.PullRequest.State != "open" {
+		return ReviewComment{}, false
+	}
+	if !isBobBranch(evt.PullRequest.Head.Ref) || evt.Comment.User.Type == "Bot" {
+		return ReviewComment{}, false
+	}
+	if strings.TrimSpace(evt.Comment.Body) == "" {
+		return ReviewComment{}, false
+	}
+	return ReviewComment{
+		Repo:      cmp.Or(evt.Repository.FullName, evt.Repository.Name),
+		Branch:    evt.PullRequest.Head.Ref,
+		PRNumber:  evt.PullRequest.Number,
+		PRURL:     evt.PullRequest.HTMLURL,
+		CommentID: evt.Comment.ID,
+		Author:    evt.Comment.User.Login,
+		Path:      evt.Comment.Path,
+		Line:      evt.Comment.Line,
+		DiffHunk:  evt.Comment.DiffHunk,
+		Body:      evt.Comment.Body,
+	}, true
+}
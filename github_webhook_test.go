@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVerifyGitHubSignature(t *testing.T) {
+	body := []byte(`{"action":"created"}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	valid := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		want   bool
+	}{
+		{"valid", "s3cret", valid, true},
+		{"wrong secret", "other", valid, false},
+		{"missing prefix", "s3cret", valid[len("sha256="):], false},
+		{"not hex", "s3cret", "sha256=zz", false},
+		{"empty", "s3cret", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyGitHubSignature(tt.secret, body, tt.header); got != tt.want {
+				t.Errorf("verifyGitHubSignature = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReviewCommentFromEvent(t *testing.T) {
+	base := func() githubReviewCommentEvent {
+		var evt githubReviewCommentEvent
+		evt.Action = "created"
+		evt.PullRequest.State = "open"
+		evt.PullRequest.Number = 7
+		evt.PullRequest.Head.Ref = "bob/fix-login-abcd1234"
+		evt.Comment.ID = 42
+		evt.Comment.Body = "Please rename this"
+		evt.Comment.User.Login = "alice"
+		evt.Comment.User.Type = "User"
+		evt.Repository.Name = "myrepo"
+		return evt
+	}
+
+	t.Run("accepted", func(t *testing.T) {
+		rc, ok := reviewCommentFromEvent(base())
+		if !ok {
+			t.Fatal("expected event to be accepted")
+		}
+		if rc.Repo != "myrepo" || rc.Branch != "bob/fix-login-abcd1234" || rc.PRNumber != 7 || rc.CommentID != 42 {
+			t.Errorf("rc = %+v", rc)
+		}
+		evt := base()
+		evt.Repository.FullName = "acme-labs/myrepo"
+		if rc, _ := reviewCommentFromEvent(evt); rc.Repo != "acme-labs/myrepo" {
+			t.Errorf("repo = %q, want the owner kept for findRepo", rc.Repo)
+		}
+	})
+
+	ignored := map[string]func(*githubReviewCommentEvent){
+		"edited":         func(e *githubReviewCommentEvent) { e.Action = "edited" },
+		"closed PR":      func(e *githubReviewCommentEvent) { e.PullRequest.State = "closed" },
+		"not bob branch": func(e *githubReviewCommentEvent) { e.PullRequest.Head.Ref = "feature/x" },
+		"bot author":     func(e *githubReviewCommentEvent) { e.Comment.User.Type = "Bot" },
+		"empty body":     func(e *githubReviewCommentEvent) { e.Comment.Body = "  " },
+	}
+	for name, mutate := range ignored {
+		t.Run(name, func(t *testing.T) {
+			evt := base()
+			mutate(&evt)
+			if _, ok := reviewCommentFromEvent(evt); ok {
+				t.Error("expected event to be ignored")
+			}
+		})
+	}
+}
+
+func TestGitHubAccounts_IsBob(t *testing.T) {
+	var lookups atomic.Int32
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" || fail {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		lookups.Add(1)
+		w.Write([]byte(`{"login":"bob-pat"}`))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	accounts := &githubAccounts{botLogin: "bob-bot", providers: []*GitHubProvider{{owner: "acme", token: "tok", apiURL: srv.URL}}}
+	for login, want := range map[string]bool{"bob-pat": true, "Bob-PAT": true, "bob-bot": true, "alice": false} {
+		if got := accounts.isBob(ctx, login); got != want {
+			t.Errorf("isBob(%q) = %v, want %v", login, got, want)
+		}
+	}
+	if n := lookups.Load(); n != 1 {
+		t.Errorf("GET /user called %d times, want 1 (cached)", n)
+	}
+
+	fail = true
+	unknown := &githubAccounts{providers: []*GitHubProvider{{owner: "acme", token: "bad", apiURL: srv.URL}}}
+	if !unknown.isBob(ctx, "alice") {
+		t.Error("comment accepted although the token's account couldn't be looked up")
+	}
+	var none *githubAccounts
+	if none.isBob(ctx, "alice") {
+		t.Error("nil accounts matched")
+	}
+}
+
+// postReviewComment sends handler a signed review comment by login on one of
+// Bob's PRs in acme/myrepo, returning the response status.
+func postReviewComment(t *testing.T, handler http.Handler, login string) int {
+	t.Helper()
+	var evt githubReviewCommentEvent
+	evt.Action = "created"
+	evt.PullRequest.State = "open"
+	evt.PullRequest.Number = 7
+	evt.PullRequest.HTMLURL = "https://github.com/acme/myrepo/pull/7"
+	evt.PullRequest.Head.Ref = "bob/fix-login-abcd1234"
+	evt.Comment.ID = 43
+	evt.Comment.Body = "Done: renamed it."
+	evt.Comment.User.Login = login
+	evt.Comment.User.Type = "User"
+	evt.Repository.Name = "myrepo"
+	evt.Repository.FullName = "acme/myrepo"
+	body, _ := json.Marshal(evt)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(string(body)))
+	req.Header.Set("X-GitHub-Event", "pull_request_review_comment")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+// permissionServer is the GitHub API for acme/myrepo: the token is bob-pat's,
+// and perms are the collaborators' permissions. Anyone else is a 404. Replies
+// to review comment 43 go to replies, if set.
+func permissionServer(t *testing.T, perms map[string]string, replies chan<- string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/repos/acme/myrepo/pulls/7/comments/43/replies" && replies != nil {
+			var body struct {
+				Body string `json:"body"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			replies <- body.Body
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		if r.URL.Path == "/user" {
+			w.Write([]byte(`{"login":"bob-pat"}`))
+			return
+		}
+		user, ok := strings.CutPrefix(r.URL.Path, "/repos/acme/myrepo/collaborators/")
+		user, ok2 := strings.CutSuffix(user, "/permission")
+		if perm, known := perms[user]; ok && ok2 && known {
+			json.NewEncoder(w).Encode(map[string]string{"permission": perm})
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGitHubWebhook_IgnoresOwnPATReply(t *testing.T) {
+	srv := permissionServer(t, nil, nil)
+	accounts := &githubAccounts{providers: []*GitHubProvider{{owner: "acme", token: "tok", apiURL: srv.URL}}}
+	// A nil orchestrator: the comment must not get as far as HandleReviewComment.
+	// Bob's ReplyToReviewComment, posted with a personal access token, comes
+	// back as a "User" comment on his own branch.
+	if code := postReviewComment(t, NewGitHubWebhookHandler("s3cret", nil, nil, accounts, nil), "bob-pat"); code != http.StatusNoContent {
+		t.Errorf("status = %d, want 204 for Bob's own reply", code)
+	}
+}
+
+func TestGitHubWebhook_IgnoresCommentersWithoutWriteAccess(t *testing.T) {
+	srv := permissionServer(t, map[string]string{"reader": "read"}, nil)
+	accounts := &githubAccounts{providers: []*GitHubProvider{{owner: "acme", token: "tok", apiURL: srv.URL}}}
+	// A nil orchestrator: these comments must not get as far as HandleReviewComment.
+	handler := NewGitHubWebhookHandler("s3cret", nil, nil, accounts, nil)
+	for _, login := range []string{"reader", "stranger"} {
+		if code := postReviewComment(t, handler, login); code != http.StatusNoContent {
+			t.Errorf("%s: status = %d, want 204", login, code)
+		}
+	}
+}
+
+func TestGitHubWebhook_ReviewCommentLimits(t *testing.T) {
+	replies := make(chan string, 1)
+	srv := permissionServer(t, map[string]string{"writer": "write"}, replies)
+	accounts := &githubAccounts{providers: []*GitHubProvider{{owner: "acme", token: "tok", apiURL: srv.URL}}}
+	reply := func() string {
+		select {
+		case r := <-replies:
+			return r
+		case <-time.After(5 * time.Second):
+			return ""
+		}
+	}
+
+	// A nil orchestrator: these comments must not get as far as HandleReviewComment.
+	t.Run("rate limit", func(t *testing.T) {
+		limits := newUserLimits(1, 0, 0)
+		limits.allow("github", "writer") // used up on an issue comment
+		if code := postReviewComment(t, NewGitHubWebhookHandler("s3cret", nil, nil, accounts, limits), "writer"); code != http.StatusNoContent {
+			t.Errorf("status = %d, want 204", code)
+		}
+		if got := reply(); !strings.HasPrefix(got, "@writer You're sending me requests faster than I can take them.") {
+			t.Errorf("reply = %q", got)
+		}
+	})
+
+	t.Run("daily job quota", func(t *testing.T) {
+		limits := newUserLimits(0, 1, 0)
+		limits.startJob("github", "writer", "job-1")
+		if code := postReviewComment(t, NewGitHubWebhookHandler("s3cret", nil, nil, accounts, limits), "writer"); code != http.StatusNoContent {
+			t.Errorf("status = %d, want 204", code)
+		}
+		if got := reply(); !strings.HasPrefix(got, "@writer You've hit your quota of 1 jobs for today.") {
+			t.Errorf("reply = %q", got)
+		}
+	})
+}
+
+func TestGitHubAccounts_MayChangePR(t *testing.T) {
+	drainHub(t)
+	srv := permissionServer(t, map[string]string{"writer": "write", "admin": "admin", "reader": "read"}, nil)
+	accounts := &githubAccounts{providers: []*GitHubProvider{{owner: "acme", token: "tok", apiURL: srv.URL}}}
+	hub := NewHub(t.TempDir())
+	// carol asked for the PR from an issue; dave asked in Slack, by user ID.
+	hub.RecordThreadPR("acme/myrepo", "5", threadPR{JobID: "job-1", URL: "https://github.com/acme/myrepo/pull/7", Platform: "github", RequestedBy: "carol"})
+	hub.RecordThreadPR("C1", "1.2", threadPR{JobID: "job-2", URL: "https://github.com/acme/myrepo/pull/8", Platform: "slack", RequestedBy: "dave"})
+
+	ctx := context.Background()
+	for _, tt := range []struct {
+		author, pr string
+		want       bool
+	}{
+		{"writer", "https://github.com/acme/myrepo/pull/7", true},
+		{"admin", "https://github.com/acme/myrepo/pull/7", true},
+		{"Carol", "https://github.com/acme/myrepo/pull/7", true},
+		{"carol", "https://github.com/acme/myrepo/pull/9", false},
+		{"dave", "https://github.com/acme/myrepo/pull/8", false},
+		{"reader", "https://github.com/acme/myrepo/pull/7", false},
+		{"stranger", "https://github.com/acme/myrepo/pull/7", false},
+	} {
+		rc := ReviewComment{Repo: "acme/myrepo", PRURL: tt.pr, Author: tt.author}
+		if got := accounts.mayChangePR(ctx, hub, rc); got != tt.want {
+			t.Errorf("mayChangePR(%s on %s) = %v, want %v", tt.author, tt.pr, got, tt.want)
+		}
+	}
+
+	down := &githubAccounts{providers: []*GitHubProvider{{owner: "acme", token: "tok", apiURL: "http://127.0.0.1:1"}}}
+	if down.mayChangePR(ctx, hub, ReviewComment{Repo: "acme/myrepo", PRURL: "https://github.com/acme/myrepo/pull/7", Author: "writer"}) {
+		t.Error("failed permission lookup counted as write access")
+	}
+}
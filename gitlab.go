@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitLabProvider implements VCSProvider for projects in a GitLab group,
+// including self-hosted instances.
+type GitLabProvider struct {
+	baseURL string // e.g. https://gitlab.example.com
+	group   string // group (or user) namespace, may be nested: "platform/backend"
+	token   string
+}
+
+// NewGitLabProvider creates a GitLabProvider. baseURL defaults to gitlab.com.
+func NewGitLabProvider(baseURL, group, token string) *GitLabProvider {
+	if baseURL == "" {
+		baseURL = "https://gitlab.com"
+	}
+	return &GitLabProvider{baseURL: strings.TrimSuffix(baseURL, "/"), group: group, token: token}
+}
+
+func (g *GitLabProvider) Name() string  { return "gitlab" }
+func (g *GitLabProvider) Token() string { return g.token }
+
+func (g *GitLabProvider) FetchURL(name string) string {
+	u, err := url.Parse(g.baseURL)
+	if err != nil {
+		return g.CleanURL(name)
+	}
+	u.User = url.UserPassword("oauth2", g.token)
+	u.Path = fmt.Sprintf("/%s/%s.git", g.group, name)
+	return u.String()
+}
+
+func (g *GitLabProvider) CleanURL(name string) string {
+	return fmt.Sprintf("%s/%s/%s.git", g.baseURL, g.group, name)
+}
+
+// projectPath returns the URL-encoded "group/name" project ID used by the GitLab API.
+func (g *GitLabProvider) projectPath(name string) string {
+	return url.PathEscape(g.group + "/" + name)
+}
+
+// FindRepo looks up a project in the configured group.
+func (g *GitLabProvider) FindRepo(ctx context.Context, name string) (repo, error) {
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s", g.baseURL, g.projectPath(name))
+	body, status, err := g.do(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return repo{}, err
+	}
+	if status == http.StatusNotFound {
+		return repo{}, fmt.Errorf("project %q not found", name)
+	}
+	if status != http.StatusOK {
+		return repo{}, fmt.Errorf("gitlab api status %d: %s", status, body)
+	}
+
+	var p struct {
+		Name          string `json:"name"`
+		Description   string `json:"description"`
+		HTTPURLToRepo string `json:"http_url_to_repo"`
+		Visibility    string `json:"visibility"`
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return repo{}, fmt.Errorf("parse response: %w", err)
+	}
+	return repo{
+		Name:          p.Name,
+		Description:   p.Description,
+		CloneURL:      p.HTTPURLToRepo,
+		Private:       p.Visibility != "public",
+		DefaultBranch: p.DefaultBranch,
+	}, nil
+}
+
+// OpenPullRequest opens a merge request and returns its web URL. Drafts are
+// marked with GitLab's "Draft:" title prefix.
+func (g *GitLabProvider) OpenPullRequest(ctx context.Context, name, branch, base, title, body string, draft bool) (string, error) {
+	if draft {
+		title = "Draft: " + title
+	}
+	payload, err := json.Marshal(map[string]string{
+		"source_branch": branch,
+		"target_branch": base,
+		"title":         title,
+		"description":   body,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal MR body: %w", err)
+	}
+
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests", g.baseURL, g.projectPath(name))
+	respBody, status, err := g.do(ctx, http.MethodPost, apiURL, payload)
+	if err != nil {
+		return "", err
+	}
+	if status == http.StatusConflict && bytes.Contains(respBody, []byte("merge request already exists")) {
+		return g.updateOpenMergeRequest(ctx, name, branch, title, body)
+	}
+	if status != http.StatusCreated {
+		return "", fmt.Errorf("gitlab api status %d: %s", status, respBody)
+	}
+
+	var mr struct {
+		WebURL string `json:"web_url"`
+	}
+	if err := json.Unmarshal(respBody, &mr); err != nil {
+		return "", fmt.Errorf("parse MR response: %w", err)
+	}
+	return mr.WebURL, nil
+}
+
+// updateOpenMergeRequest sets the title and description of the open merge
+// request for branch, which already has the pushed changes, and returns its URL.
+func (g *GitLabProvider) updateOpenMergeRequest(ctx context.Context, name, branch, title, body string) (string, error) {
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests?state=opened&source_branch=%s", g.baseURL, g.projectPath(name), url.QueryEscape(branch))
+	respBody, status, err := g.do(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("gitlab api status %d: %s", status, respBody)
+	}
+	var mrs []struct {
+		IID    int    `json:"iid"`
+		WebURL string `json:"web_url"`
+	}
+	if err := json.Unmarshal(respBody, &mrs); err != nil {
+		return "", fmt.Errorf("parse MR list: %w", err)
+	}
+	if len(mrs) == 0 {
+		return "", fmt.Errorf("gitlab says an MR for %s exists, but none is open", branch)
+	}
+	mr := mrs[0]
+	payload, err := json.Marshal(map[string]string{"title": title, "description": body})
+	if err != nil {
+		return "", fmt.Errorf("marshal MR update: %w", err)
+	}
+	apiURL = fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%d", g.baseURL, g.projectPath(name), mr.IID)
+	if respBody, status, err = g.do(ctx, http.MethodPut, apiURL, payload); err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("update MR %s: gitlab api status %d: %s", mr.WebURL, status, respBody)
+	}
+	slog.InfoContext(ctx, "gitlab: updated the open merge request for the branch", "branch", branch, "mr", mr.WebURL)
+	return mr.WebURL, nil
+}
+
+// AnnotatePullRequest sets reviewers and assignees and adds labels on an
+// opened merge request. Reviewers and assignees are GitLab usernames, resolved
+// to user IDs. GitLab has no team reviewers, so those are skipped.
+func (g *GitLabProvider) AnnotatePullRequest(ctx context.Context, name, prURL string, opts PROptions) error {
+	iid, err := prNumberFromURL(prURL)
+	if err != nil {
+		return err
+	}
+	if len(opts.TeamReviewers) > 0 {
+		slog.InfoContext(ctx, "gitlab: no team reviewers on merge requests, skipping", "teams", opts.TeamReviewers, "pr", prURL)
+	}
+	update := map[string]any{}
+	if len(opts.Labels) > 0 {
+		update["add_labels"] = strings.Join(opts.Labels, ",")
+	}
+	for field, names := range map[string][]string{"reviewer_ids": opts.Reviewers, "assignee_ids": opts.Assignees} {
+		var ids []int
+		for _, n := range names {
+			id, err := g.userID(ctx, strings.TrimPrefix(n, "@"))
+			if err != nil {
+				return fmt.Errorf("look up user %q: %w", n, err)
+			}
+			ids = append(ids, id)
+		}
+		if len(ids) > 0 {
+			update[field] = ids
+		}
+	}
+	if len(update) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("marshal MR update: %w", err)
+	}
+
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%d", g.baseURL, g.projectPath(name), iid)
+	respBody, status, err := g.do(ctx, http.MethodPut, apiURL, payload)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("gitlab api status %d: %s", status, respBody)
+	}
+	return nil
+}
+
+// userID resolves a GitLab username to its user ID.
+func (g *GitLabProvider) userID(ctx context.Context, username string) (int, error) {
+	apiURL := fmt.Sprintf("%s/api/v4/users?username=%s", g.baseURL, url.QueryEscape(username))
+	body, status, err := g.do(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	if status != http.StatusOK {
+		return 0, fmt.Errorf("gitlab api status %d: %s", status, body)
+	}
+	var users []struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(body, &users); err != nil {
+		return 0, fmt.Errorf("parse response: %w", err)
+	}
+	if len(users) == 0 {
+		return 0, fmt.Errorf("no such user")
+	}
+	return users[0].ID, nil
+}
+
+// Ping checks that the token can call the API.
+func (g *GitLabProvider) Ping(ctx context.Context) error {
+	body, status, err := g.do(ctx, http.MethodGet, g.baseURL+"/api/v4/user", nil)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("gitlab api status %d: %s", status, body)
+	}
+	return nil
+}
+
+// do performs an authenticated GitLab API request and returns the body and status.
+func (g *GitLabProvider) do(ctx context.Context, method, apiURL string, payload []byte) ([]byte, int, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, reqBody)
+	if err != nil {
+		return nil, 0, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("PRIVATE-TOKEN", g.token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("gitlab api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read response: %w", err)
+	}
+	return body, resp.StatusCode, nil
+}
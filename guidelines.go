@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// maxGuidelinesBytes caps each guidelines file included in a prompt.
+const maxGuidelinesBytes = 16 << 10
+
+// defaultGuidelinesFiles are where a repo's contribution guidelines are looked
+// for, in order, unless its .bob.yml names others. CLAUDE.md isn't among them:
+// Claude Code reads it from the worktree itself.
+var defaultGuidelinesFiles = []string{"CONTRIBUTING.md", ".github/CONTRIBUTING.md", "docs/CONTRIBUTING.md"}
+
+// loadRepoGuidelines returns the first of files committed at rev in the git
+// repo at dir, truncated to maxGuidelinesBytes, or "" if there is none.
+func loadRepoGuidelines(ctx context.Context, dir, rev string, files []string) string {
+	for _, f := range files {
+		cmd := exec.CommandContext(ctx, "git", "show", rev+":"+f)
+		cmd.Dir = dir
+		if out, err := cmd.Output(); err == nil && strings.TrimSpace(string(out)) != "" {
+			return truncate(strings.TrimSpace(string(out)), maxGuidelinesBytes)
+		}
+	}
+	return ""
+}
+
+// orgGuidelines reads the org-wide guidelines file (BOB_GUIDELINES_FILE). It
+// is read for every job, so edits apply without a restart.
+func (o *Orchestrator) orgGuidelines(ctx context.Context) string {
+	if o.guidelinesFile == "" {
+		return ""
+	}
+	data, err := os.ReadFile(o.guidelinesFile)
+	if err != nil {
+		slog.WarnContext(ctx, "orchestrator: failed to read guidelines file", "path", o.guidelinesFile, "err", err)
+		return ""
+	}
+	return truncate(strings.TrimSpace(string(data)), maxGuidelinesBytes)
+}
+
+// guidelinesPreamble formats the org's and the repo's guidelines for the top
+// of a task prompt, or returns "" if there are none.
+func guidelinesPreamble(org, repo string) string {
+	if org == "" && repo == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("## Coding guidelines\n\nFollow these conventions in the code you write. Where they conflict, the repository's win.\n\n")
+	if org != "" {
+		fmt.Fprintf(&b, "### Organization\n\n%s\n\n", org)
+	}
+	if repo != "" {
+		fmt.Fprintf(&b, "### This repository\n\n%s\n\n", repo)
+	}
+	return b.String()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGuidelinesPreamble(t *testing.T) {
+	if got := guidelinesPreamble("", ""); got != "" {
+		t.Errorf("no guidelines: got %q", got)
+	}
+	got := guidelinesPreamble("Use slog.", "Wrap errors with %w.")
+	if !strings.HasPrefix(got, "## Coding guidelines") || strings.Index(got, "Use slog.") > strings.Index(got, "Wrap errors") {
+		t.Errorf("got %q", got)
+	}
+	if got := guidelinesPreamble("", "Wrap errors with %w."); strings.Contains(got, "### Organization") {
+		t.Errorf("repo only: got %q", got)
+	}
+}
+
+func TestOrchestrator_OrgGuidelines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "guidelines.md")
+	os.WriteFile(path, []byte("Use slog.\n"), 0o644)
+	o := &Orchestrator{guidelinesFile: path}
+	cfg := &RepoConfig{Prompt: "Use pnpm.", orgGuidelines: o.orgGuidelines(context.Background())}
+	got := cfg.promptPreamble()
+	if !strings.Contains(got, "### Organization\n\nUse slog.") || strings.Index(got, "Use slog.") > strings.Index(got, "Use pnpm.") {
+		t.Errorf("preamble = %q", got)
+	}
+
+	// A missing file doesn't stop the job.
+	o.guidelinesFile = filepath.Join(t.TempDir(), "missing.md")
+	if got := o.orgGuidelines(context.Background()); got != "" {
+		t.Errorf("missing file: got %q", got)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	// readinessTimeout bounds each readiness check.
+	readinessTimeout = 5 * time.Second
+	// readinessCacheTTL is how long a readiness report is reused. Probes come
+	// every few seconds; the APIs behind the checks don't need to hear each one.
+	readinessCacheTTL = 30 * time.Second
+)
+
+// Build details, set at build time:
+//
+//	go build -ldflags "-X main.buildCommit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%FT%TZ)"
+//
+// Without them, the VCS details Go stamps into the binary are used.
+var (
+	buildCommit string
+	buildTime   string
+)
+
+// pinger is implemented by LLM and VCS providers that can check their
+// credentials and reachability with a cheap authenticated call.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// readinessCheck is one dependency /readyz checks.
+type readinessCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// readiness serves /readyz: Bob is ready when every check passes.
+type readiness struct {
+	checks []readinessCheck
+
+	mu      sync.Mutex
+	checked time.Time
+	last    readinessReport
+}
+
+// readinessReport is the /readyz response.
+type readinessReport struct {
+	Ready  bool                   `json:"ready"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+type checkResult struct {
+	OK         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// newReadiness creates the readiness checks: Slack auth, each VCS provider's
+// and the intent LLM's credentials where they can be pinged, and that the
+// workspace is writable.
+func newReadiness(slack *SlackPlatform, providers []VCSProvider, llm LLMProvider) *readiness {
+	r := &readiness{}
+	if slack != nil {
+		r.checks = append(r.checks, readinessCheck{"slack", func(ctx context.Context) error {
+			_, err := slack.Client().AuthTestContext(ctx)
+			return err
+		}})
+	}
+	for _, p := range providers {
+		if pp, ok := p.(pinger); ok {
+			name := p.Name()
+			if gh, ok := p.(*GitHubProvider); ok {
+				name += ":" + gh.owner
+			}
+			r.checks = append(r.checks, readinessCheck{name, pp.Ping})
+		}
+	}
+	if pp, ok := llm.(pinger); ok {
+		r.checks = append(r.checks, readinessCheck{"llm:" + llm.Name(), pp.Ping})
+	}
+	r.checks = append(r.checks, readinessCheck{"workspace", func(context.Context) error { return workspaceWritable() }})
+	return r
+}
+
+// report runs the checks, concurrently, or returns the last report if it is
+// recent enough.
+func (r *readiness) report(ctx context.Context) readinessReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.checked.IsZero() && time.Since(r.checked) < readinessCacheTTL {
+		return r.last
+	}
+
+	results := make([]checkResult, len(r.checks))
+	var wg sync.WaitGroup
+	for i, c := range r.checks {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+			defer cancel()
+			start := time.Now()
+			err := c.check(ctx)
+			results[i] = checkResult{OK: err == nil, DurationMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				results[i].Error = truncate(redactSecrets(err.Error()), 300)
+			}
+		})
+	}
+	wg.Wait()
+
+	rep := readinessReport{Ready: true, Checks: make(map[string]checkResult, len(r.checks))}
+	for i, c := range r.checks {
+		rep.Checks[c.name] = results[i]
+		rep.Ready = rep.Ready && results[i].OK
+	}
+	r.checked, r.last = time.Now(), rep
+	return rep
+}
+
+// ServeHTTP handles GET /readyz: 200 when ready, 503 with the failing checks
+// otherwise.
+func (r *readiness) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	rep := r.report(req.Context())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if !rep.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(rep)
+}
+
+// serveHealthz handles GET /healthz: the process is up and serving.
+func serveHealthz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("Cache-Control", "no-store")
+	fmt.Fprintln(w, "ok")
+}
+
+// workspaceWritable checks that a file can be created in the workspace.
+func workspaceWritable() error {
+	f, err := os.CreateTemp(workspaceRoot, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// buildInfo is the /version response.
+type buildInfo struct {
+	Version    string `json:"version,omitempty"` // module version, for go install'ed builds
+	Commit     string `json:"commit,omitempty"`
+	BuildTime  string `json:"build_time,omitempty"` // the commit time if not set at build time
+	Modified   bool   `json:"modified,omitempty"`   // built from a tree with uncommitted changes
+	GoVersion  string `json:"go_version"`
+	ClaudeCode string `json:"claude_code,omitempty"` // installed Claude Code CLI version
+}
+
+// currentBuildInfo returns what Bob was built from: the -ldflags values, or
+// the VCS details Go recorded in the binary.
+func currentBuildInfo() buildInfo {
+	info := buildInfo{Commit: buildCommit, BuildTime: buildTime, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if v := bi.Main.Version; v != "" && v != "(devel)" {
+			info.Version = v
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	return info
+}
+
+// serveVersion handles GET /version.
+func serveVersion(w http.ResponseWriter, _ *http.Request) {
+	info := currentBuildInfo()
+	info.ClaudeCode = claudeCodeVersion()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxHelpRepos caps the repos listed in the help message.
+const maxHelpRepos = 10
+
+// helpQuery reports whether a mention asks for help: an empty mention, "help"
+// or "?". Words after "help" filter the listed repos ("help api").
+func helpQuery(text string) (filter string, ok bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	switch text {
+	case "", "?", "help":
+		return "", true
+	}
+	if rest, found := strings.CutPrefix(text, "help "); found {
+		return strings.TrimSpace(rest), true
+	}
+	return "", false
+}
+
+// HelpMessage describes what Bob does, which repos it works on (matching
+// filter, with the channel's default first), how busy it is, and example
+// requests. It is Markdown and costs no LLM call.
+func (o *Orchestrator) HelpMessage(filter, channelRepo string) string {
+	var b strings.Builder
+	b.WriteString("**Hi, I'm Bob.** Mention me with a coding task and I'll take it from request to pull request:\n\n")
+	b.WriteString("1. I read the repo and post a plan.\n")
+	b.WriteString("2. Reply with feedback to revise it, or \"go\" to approve.\n")
+	b.WriteString("3. I implement the plan, run the tests and open a pull request.\n")
+	b.WriteString("4. Mention me again in the thread to change the pull request.\n\n")
+	b.WriteString("Say \"cancel\" in the thread to stop a job, or \"retry\" to start a failed one again.\n")
+	b.WriteString("In Slack, say \"be quiet\" for just plans, questions and results in the thread, or \"verbose\" for a play-by-play.\n\n")
+
+	b.WriteString("**Repos**\n")
+	repos, more := o.helpRepos(filter, channelRepo)
+	switch {
+	case len(repos) > 0:
+		for _, r := range repos {
+			if r == channelRepo {
+				fmt.Fprintf(&b, "- `%s` (this channel's default)\n", r)
+			} else {
+				fmt.Fprintf(&b, "- `%s`\n", r)
+			}
+		}
+		if more > 0 {
+			fmt.Fprintf(&b, "- …and %d more\n", more)
+		}
+	case filter != "" && len(o.allowedRepos) > 0:
+		fmt.Fprintf(&b, "No repos I work on match %q.\n", filter)
+	default:
+		var names []string
+		for _, p := range o.providers {
+			names = append(names, p.Name())
+		}
+		fmt.Fprintf(&b, "Any repo I can reach on %s. Name it in your request.\n", strings.Join(names, " or "))
+	}
+
+	running, waiting := o.hub.JobCounts()
+	fmt.Fprintf(&b, "\n**Right now** %d %s running and %d waiting on a reply.\n\n", running, plural(running, "job", "jobs"), waiting)
+
+	b.WriteString("**Examples**\n")
+	b.WriteString("- `@bob add rate limiting to the login endpoint in api`\n")
+	b.WriteString("- `@bob fix the flaky date test in web, branch off develop`\n")
+	b.WriteString("- `@bob review PR #123 in api`\n")
+	b.WriteString("- `@bob what's the status of that PR?` (in a job's thread)\n")
+	b.WriteString("- `@bob bump lodash in web and open it as a draft, tag @frontend`\n")
+	return b.String()
+}
+
+// helpRepos returns up to maxHelpRepos allowed repos containing filter, the
+// channel's default first, and how many more matched. Without an allowlist it
+// returns only the channel default.
+func (o *Orchestrator) helpRepos(filter, channelRepo string) ([]string, int) {
+	var matches []string
+	for r := range o.allowedRepos {
+		if strings.Contains(strings.ToLower(r), filter) {
+			matches = append(matches, r)
+		}
+	}
+	if len(o.allowedRepos) == 0 && channelRepo != "" && strings.Contains(strings.ToLower(channelRepo), filter) {
+		matches = append(matches, channelRepo)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if (matches[i] == channelRepo) != (matches[j] == channelRepo) {
+			return matches[i] == channelRepo
+		}
+		return matches[i] < matches[j]
+	})
+	if len(matches) > maxHelpRepos {
+		return matches[:maxHelpRepos], len(matches) - maxHelpRepos
+	}
+	return matches, 0
+}
+
+// plural returns one or many depending on n.
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestHelpQuery(t *testing.T) {
+	tests := []struct {
+		text   string
+		filter string
+		ok     bool
+	}{
+		{"", "", true},
+		{"  Help ", "", true},
+		{"?", "", true},
+		{"help API", "api", true},
+		{"helpful tips for api", "", false},
+		{"fix the login bug in api", "", false},
+	}
+	for _, tt := range tests {
+		filter, ok := helpQuery(tt.text)
+		if filter != tt.filter || ok != tt.ok {
+			t.Errorf("helpQuery(%q) = %q, %v; want %q, %v", tt.text, filter, ok, tt.filter, tt.ok)
+		}
+	}
+}
+
+func TestHelpMessage(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	hub.SetJobState("job-1", &JobState{Phase: PhasePlanning})
+	hub.SetJobState("job-2", &JobState{Phase: PhaseAwaitingApproval})
+	hub.SetJobState("job-3", &JobState{Phase: PhaseDone, closed: true})
+
+	allowed := map[string]bool{"web": true, "api": true, "api-gateway": true}
+	for i := range maxHelpRepos {
+		allowed[fmt.Sprintf("svc-%02d", i)] = true
+	}
+	o := &Orchestrator{hub: hub, allowedRepos: allowed}
+
+	msg := o.HelpMessage("", "web")
+	for _, want := range []string{"- `web` (this channel's default)\n- `api`\n", "…and 3 more", "1 job running and 1 waiting", "review PR #123"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("help message missing %q:\n%s", want, msg)
+		}
+	}
+	if msg := o.HelpMessage("api", "web"); !strings.Contains(msg, "- `api`\n- `api-gateway`\n\n") {
+		t.Errorf("filtered help message:\n%s", msg)
+	}
+
+	o = &Orchestrator{hub: hub, providers: []VCSProvider{&GitHubProvider{}}}
+	if msg := o.HelpMessage("", ""); !strings.Contains(msg, "Any repo I can reach on github") {
+		t.Errorf("help message without allowlist:\n%s", msg)
+	}
+}
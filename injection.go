@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// maxInjectionFindings caps the findings recorded for one job.
+const maxInjectionFindings = 20
+
+// injectionHeading leads a plan whose planning read suspected prompt injection.
+const injectionHeading = "⚠️ *Possible prompt injection*"
+
+// injectionPatterns are text that reads as instructions to the agent rather
+// than content: planted in a file, issue or ticket, they try to steer a
+// session that can edit files and whose work gets pushed. They are meant to
+// catch the common phrasings, not to be a complete defense; a finding only
+// makes a person look before anything runs.
+var injectionPatterns = []struct {
+	rule string
+	re   *regexp.Regexp
+}{
+	{"override-instructions", regexp.MustCompile(`(?i)\b(?:ignore|disregard|forget|override)\s+(?:all\s+|any\s+)?(?:of\s+)?(?:the\s+|your\s+)?(?:previous|prior|above|earlier|preceding|original|system)\s+(?:instructions|prompts?|rules|directions|guidelines)`)},
+	{"new-instructions", regexp.MustCompile(`(?i)\b(?:new|updated|real)\s+instructions\s*:|\byou\s+are\s+now\s+(?:a|an|in)\b|\bfrom\s+now\s+on,?\s+you\s+(?:must|will|should)\b`)},
+	{"addressed-to-agent", regexp.MustCompile(`(?i)\b(?:AI|LLM|coding)\s+(?:agents?|assistants?|models?)\b[^.\n]{0,40}\b(?:must|should|are\s+(?:instructed|required)\s+to)\b`)},
+	{"push-to-default-branch", regexp.MustCompile(`(?i)\bpush\b[^.\n]{0,30}\bto\s+(?:origin\s+)?(?:main|master)\b`)},
+	{"exfiltrate-secrets", regexp.MustCompile(`(?i)\b(?:print|reveal|send|post|upload|exfiltrate|leak)\s+(?:the\s+|your\s+|all\s+)?(?:env(?:ironment)?\s+variables|secrets|credentials|api\s+keys|tokens)\b`)},
+	{"chat-markup", regexp.MustCompile(`<\|im_start\|>|\[/?INST\]|<</?SYS>>|</?system>`)},
+	{"hidden-characters", regexp.MustCompile(`[\x{E0000}-\x{E007F}\x{202A}-\x{202E}\x{2066}-\x{2069}]`)},
+}
+
+// InjectionFinding is text read while planning that looks like an attempt to
+// instruct the agent.
+type InjectionFinding struct {
+	Source  string `json:"source"` // "request", or the tool call that read it (e.g. "Read docs/setup.md")
+	Rule    string `json:"rule"`
+	Excerpt string `json:"excerpt"` // around the match, redacted, with hidden characters escaped
+}
+
+// scanInjection returns a finding for each rule text breaks, read from source.
+func scanInjection(source, text string) []InjectionFinding {
+	var findings []InjectionFinding
+	for _, p := range injectionPatterns {
+		loc := p.re.FindStringIndex(text)
+		if loc == nil {
+			continue
+		}
+		findings = append(findings, InjectionFinding{Source: source, Rule: p.rule, Excerpt: injectionExcerpt(text, loc[0], loc[1])})
+	}
+	return findings
+}
+
+// injectionExcerpt returns the line around text[start:end] on one line,
+// redacted, with invisible and bidi characters shown as escapes so a
+// reviewer sees what was hidden.
+func injectionExcerpt(text string, start, end int) string {
+	from := max(strings.LastIndexByte(text[:start], '\n')+1, start-60)
+	to := end + 60
+	if nl := strings.IndexByte(text[end:], '\n'); nl >= 0 && end+nl < to {
+		to = end + nl
+	}
+	to = min(to, len(text))
+	s := strings.ToValidUTF8(text[from:to], "")
+	s = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\r' {
+			return ' '
+		}
+		return r
+	}, s)
+	var b strings.Builder
+	for _, r := range s {
+		if (r >= 0xE0000 && r <= 0xE007F) || (r >= 0x202A && r <= 0x202E) || (r >= 0x2066 && r <= 0x2069) {
+			fmt.Fprintf(&b, "\\u{%X}", r)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return truncate(strings.TrimSpace(redactSecrets(b.String())), 200)
+}
+
+// toolSource describes what a tool call read, for a finding's Source.
+func toolSource(name string, input json.RawMessage) string {
+	var in struct {
+		FilePath string `json:"file_path"`
+		Path     string `json:"path"`
+		Pattern  string `json:"pattern"`
+		URL      string `json:"url"`
+		Command  string `json:"command"`
+		Query    string `json:"query"`
+	}
+	json.Unmarshal(input, &in)
+	for _, s := range []string{in.FilePath, in.URL, in.Command, in.Pattern, in.Query, in.Path} {
+		if s != "" {
+			return name + " " + truncate(s, 120)
+		}
+	}
+	return name
+}
+
+// recordInjections emits findings in a prompt_injection event and keeps
+// them on the job, whose plan then warns about them and can't be approved
+// without a person seeing it (see OrchestratorResult.Injections).
+func (o *Orchestrator) recordInjections(ctx context.Context, jobID string, findings []InjectionFinding) {
+	if len(findings) == 0 {
+		return
+	}
+	o.hub.Emit(jobID, PromptInjectionData{Findings: findings})
+	slog.WarnContext(ctx, "orchestrator: possible prompt injection in what planning read", "job_id", jobID, "findings", len(findings))
+	state, _ := o.hub.GetJobState(jobID)
+	state.mu.Lock()
+	state.Injections = append(state.Injections, findings...)
+	if len(state.Injections) > maxInjectionFindings {
+		state.Injections = state.Injections[:maxInjectionFindings]
+	}
+	state.mu.Unlock()
+}
+
+// formatInjectionFindings explains the findings above a plan.
+func formatInjectionFindings(findings []InjectionFinding) string {
+	var b strings.Builder
+	b.WriteString(injectionHeading)
+	b.WriteString("\nWhile planning I read text that looks like instructions aimed at me, not at you. Check that the plan does only what you asked before approving it:\n")
+	for _, f := range findings {
+		fmt.Fprintf(&b, "• %s — %s: `%s`\n", strings.ReplaceAll(f.Source, "`", "'"), f.Rule, strings.ReplaceAll(f.Excerpt, "`", "'"))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// formatInjectionBlock is formatInjectionFindings as a plan message block.
+func formatInjectionBlock(findings []InjectionFinding) slack.Block {
+	text := formatInjectionFindings(findings)
+	if len(text) > 2800 {
+		text = prefix(text, 2800) + "\n..."
+	}
+	return slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil)
+}
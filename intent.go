@@ -4,26 +4,50 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"strings"
+	"sync"
 
 	"github.com/anthropics/anthropic-sdk-go"
-	"github.com/anthropics/anthropic-sdk-go/option"
 )
 
-const intentSystemPrompt = `You are a task parser for a software team's coding assistant. The assistant has access to a pre-configured GitHub organization — you do NOT need to ask for the org name, owner, or any credentials.
+const intentSystemPrompt = `You are a task parser for a software team's coding assistant. The assistant has access to pre-configured GitHub organizations — you do NOT need to ask for the org name, owner, or any credentials.
 
 Given the Slack conversation, extract:
-- repo: the repository name (just the short name, e.g. "letsmeet" — never owner/repo)
-- task: a clear description of the coding work to do (implement, fix, review, refactor, etc.)
+- repo: the repository name: just the short name (e.g. "letsmeet"), or owner/repo only if the user names the owner or project (e.g. "acme-labs/letsmeet")
+- task: a clear description of the coding work to do (implement, fix, review, refactor, etc.); keep work item references such as AB#123 as written
 - question: a single clarifying question ONLY if you genuinely cannot identify the repo name or task at all
+- review_pr: the pull request number if the user asks to review an existing pull request (e.g. "review PR #123"), otherwise 0
+- explain: true if the user only asks a question about the code (e.g. "how does auth work in payments-api?", "where are invoices generated?") and wants an answer, not changes
+- pr_status: true if the user asks where a pull request stands (e.g. "what's the status of that PR?", "is #42 merged yet?")
+- pr_comment: true if the user asks you to comment on a pull request (e.g. "summarize the change for reviewers on the PR"); put what the comment should say in task
+- pr: the pull request number for pr_status or pr_comment if the user gives one, otherwise 0 (the PR this thread opened is meant)
+- base_branch: the branch to start from and open the pull request against, only if the user names one (e.g. "branch off develop", "against release/2.1"), otherwise empty
+- draft: true only if the user asks for a draft pull request (e.g. "open as draft")
+- ticket: the Jira issue key the user refers to (e.g. "implement PROJ-123" → "PROJ-123"), otherwise empty
+- paths: directories or files the user limits the change to (e.g. "only touch services/payments/" → ["services/payments"]), relative to the repo root, otherwise empty
+- dry_run: true only if the user wants to see the diff before anything is pushed (e.g. "dry run", "show me the diff first", "don't push yet")
+- changelog: true only if the user asks for a changelog entry with the change (e.g. "add it to the changelog", "with a CHANGELOG entry")
+- reviewers, team_reviewers, labels, assignees: usernames (without @), team slugs, labels and usernames the user explicitly asks to put on the pull request (e.g. "tag @backend-team" → team_reviewers ["backend-team"], "label it hotfix" → labels ["hotfix"]), otherwise empty
+- summary: one plain sentence restating what will be done, in which repo
+- confidence: how sure you are (0.0 to 1.0) that repo and task are what the user means
+- destructive: true if the task deletes code, data or files, migrates data or schemas, or rewrites large parts of the codebase
 
 IMPORTANT: Your entire response MUST be a single JSON object. Never include prose, explanations, or markdown outside the JSON. Respond ONLY with:
-{"repo":"...","task":"...","question":""}
+{"repo":"...","task":"...","question":"","review_pr":0,"explain":false,"pr_status":false,"pr_comment":false,"pr":0,"base_branch":"","draft":false,"ticket":"","paths":[],"dry_run":false,"changelog":false,"reviewers":[],"team_reviewers":[],"labels":[],"assignees":[],"summary":"...","confidence":0.9,"destructive":false}
 Rules:
 - If a repo name is mentioned, even informally, extract it. Do not ask to confirm it.
 - If a task is implied (fix bugs, add feature, review code, etc.) describe it clearly.
 - Set question only when truly stuck — never to ask about org, owner, access, or credentials.
-- If question is set, leave repo and task empty.`
+- If question is set, leave repo and task empty.
+- Set review_pr only for reviews of an existing pull request, never for requests to write code.
+- Set explain only for questions about how the code works; put the user's question in task. Never set it when the user asks for any change.
+- Set pr_status and pr_comment only for requests about an existing pull request, never for requests to change code.`
+
+// intentModel is the default model for intent parsing on the Anthropic API.
+// The model actually used is recorded on every job so quality regressions can
+// be correlated with model changes.
+const intentModel = anthropic.ModelClaudeHaiku4_5_20251001
 
 // Claude Haiku 4.5 pricing (USD per token).
 const (
@@ -33,6 +57,43 @@ const (
 	haikuPriceCacheWritePerToken = 1.00 / 1_000_000
 )
 
+// Claude Sonnet 4.x pricing (USD per token), for BOB_LLM_MODEL set to a Sonnet model.
+const (
+	sonnetPriceInputPerToken      = 3.00 / 1_000_000
+	sonnetPriceOutputPerToken     = 15.00 / 1_000_000
+	sonnetPriceCacheReadPerToken  = 0.30 / 1_000_000
+	sonnetPriceCacheWritePerToken = 3.75 / 1_000_000
+)
+
+// Claude Opus 4 and 4.1 pricing (USD per token). Later Opus models cost less,
+// so this errs high.
+const (
+	opusPriceInputPerToken      = 15.00 / 1_000_000
+	opusPriceOutputPerToken     = 75.00 / 1_000_000
+	opusPriceCacheReadPerToken  = 1.50 / 1_000_000
+	opusPriceCacheWritePerToken = 18.75 / 1_000_000
+)
+
+// claudePrice is a model family's pricing (USD per token).
+type claudePrice struct {
+	input, output, cacheRead, cacheWrite float64
+}
+
+// claudePrices maps model families, found in any provider's model ID, to
+// their pricing. The most expensive is last: unknown models are priced at it.
+var claudePrices = []struct {
+	family string
+	price  claudePrice
+}{
+	{"haiku", claudePrice{haikuPriceInputPerToken, haikuPriceOutputPerToken, haikuPriceCacheReadPerToken, haikuPriceCacheWritePerToken}},
+	{"sonnet", claudePrice{sonnetPriceInputPerToken, sonnetPriceOutputPerToken, sonnetPriceCacheReadPerToken, sonnetPriceCacheWritePerToken}},
+	{"opus", claudePrice{opusPriceInputPerToken, opusPriceOutputPerToken, opusPriceCacheReadPerToken, opusPriceCacheWritePerToken}},
+}
+
+// unpricedModels are the unknown models already warned about, so each is
+// logged once.
+var unpricedModels sync.Map // model → struct{}
+
 func computeIntentCost(input, output, cacheRead, cacheWrite int64) float64 {
 	return float64(input)*haikuPriceInputPerToken +
 		float64(output)*haikuPriceOutputPerToken +
@@ -40,65 +101,138 @@ func computeIntentCost(input, output, cacheRead, cacheWrite int64) float64 {
 		float64(cacheWrite)*haikuPriceCacheWritePerToken
 }
 
+// computeClaudeCost prices usage at the rates of model's family (by any
+// provider's model ID). A model of no known family is priced at the most
+// expensive rates, so budgets err on the safe side, with a warning.
+func computeClaudeCost(model string, input, output, cacheRead, cacheWrite int64) float64 {
+	priciest := claudePrices[len(claudePrices)-1]
+	p, known := priciest.price, false
+	for _, c := range claudePrices {
+		if strings.Contains(strings.ToLower(model), c.family) {
+			p, known = c.price, true
+			break
+		}
+	}
+	if !known {
+		if _, warned := unpricedModels.LoadOrStore(model, struct{}{}); !warned {
+			slog.Warn("llm: no pricing for model, using the most expensive rates", "model", model, "family", priciest.family)
+		}
+	}
+	return float64(input)*p.input +
+		float64(output)*p.output +
+		float64(cacheRead)*p.cacheRead +
+		float64(cacheWrite)*p.cacheWrite
+}
+
 // IntentResult holds the structured output of an intent parse.
 type IntentResult struct {
 	Repo     string `json:"repo"`
 	Task     string `json:"task"`
 	Question string `json:"question"`
+	ReviewPR int    `json:"review_pr"` // non-zero for "review PR #N" requests
+	// Explain is set for questions about the code, answered without changes.
+	Explain bool `json:"explain"`
+	// PRStatus asks where a pull request stands; PRComment asks Bob to comment
+	// on one it opened, saying what Task describes. PR is the pull request's
+	// number if the user gave one, otherwise the thread's PR is meant.
+	PRStatus  bool `json:"pr_status"`
+	PRComment bool `json:"pr_comment"`
+	PR        int  `json:"pr"`
+	// BaseBranch is the branch the user asked to work from, if any.
+	BaseBranch string `json:"base_branch"`
+	// DryRun holds the implemented diff for a "push it" instead of opening the PR.
+	DryRun bool `json:"dry_run"`
+	// Changelog adds an entry for the change to CHANGELOG.md, or to the
+	// changelog the repo's .bob.yml names.
+	Changelog bool `json:"changelog"`
+	// PROptions are how the user asked for the pull request to be opened.
+	PROptions
+	// PRFooter is appended to the pull request body, e.g. a link to the
+	// Sentry issue the job came from.
+	PRFooter string `json:"-"`
+	// RerunOf is the failed job this request re-runs, if any.
+	RerunOf string `json:"-"`
+	// Ticket is the Jira issue key the request refers to, if any.
+	Ticket string `json:"ticket"`
+	// Paths are the directories or files the user limited the change to.
+	Paths []string `json:"paths"`
+	// Context is material for the planning prompt beyond the task, e.g. the
+	// full Jira ticket.
+	Context string `json:"-"`
+	// Summary restates the request; Confidence (0–1, 0 if not reported) and
+	// Destructive decide whether Bob asks for confirmation before starting.
+	Summary     string  `json:"summary"`
+	Confidence  float64 `json:"confidence"`
+	Destructive bool    `json:"destructive"`
 	// Token usage for cost tracking.
 	InputTokens      int64
 	OutputTokens     int64
 	CacheReadTokens  int64
 	CacheWriteTokens int64
+	CostUSD          float64
+	Model            string `json:"-"` // the model that parsed the request
+	Thinking         string `json:"-"` // the model's extended thinking, if enabled
+	// Retries are the rate limit and overload retries parsing needed, emitted
+	// once the job exists.
+	Retries []LLMRetryData `json:"-"`
 }
 
-// ParseIntent calls Claude Haiku with the conversation to extract the task intent.
-func ParseIntent(ctx context.Context, apiKey string, messages []Message) (IntentResult, error) {
-	client := anthropic.NewClient(option.WithAPIKey(apiKey))
-
-	params := make([]anthropic.MessageParam, len(messages))
-	for i, msg := range messages {
-		block := anthropic.NewTextBlock(msg.Content)
-		switch msg.Role {
-		case RoleUser:
-			params[i] = anthropic.NewUserMessage(block)
-		case RoleAssistant:
-			params[i] = anthropic.NewAssistantMessage(block)
-		}
+// minIntentConfidence is the reported confidence below which Bob confirms its
+// understanding of a request before starting work.
+const minIntentConfidence = 0.6
+
+// confirmationReason returns why Bob should confirm the intent before starting
+// a job, or "" if it can start right away.
+func (r IntentResult) confirmationReason() string {
+	switch {
+	case r.Destructive:
+		return "This looks destructive, so I'd like a go-ahead first."
+	case r.Confidence > 0 && r.Confidence < minIntentConfidence:
+		return "I'm not sure I understood you correctly."
 	}
+	return ""
+}
 
-	resp, err := client.Messages.New(ctx, anthropic.MessageNewParams{
-		Model:     anthropic.ModelClaudeHaiku4_5_20251001,
+// formatIntentConfirmation asks the user to confirm an intent.
+func formatIntentConfirmation(r IntentResult, reason string) string {
+	summary := r.Summary
+	if summary == "" {
+		summary = r.Task
+	}
+	return fmt.Sprintf("Here's what I understood — confirm to proceed:\n\n*Repo:* %s\n*Task:* %s\n\n%s Reply \"go\" to start, or tell me what to change.", r.Repo, summary, reason)
+}
+
+// ParseIntent calls the orchestration LLM with the conversation to extract the task intent.
+func ParseIntent(ctx context.Context, llm LLMProvider, messages []Message) (IntentResult, error) {
+	var retries []LLMRetryData
+	resp, err := completeWithRetry(ctx, llm, LLMRequest{
+		System:    intentSystemPrompt,
+		Messages:  messages,
 		MaxTokens: 512,
-		System: []anthropic.TextBlockParam{
-			{Text: intentSystemPrompt},
-		},
-		Messages: params,
-	})
+		JSON:      true,
+	}, func(r LLMRetryData) { retries = append(retries, r) })
 	if err != nil {
 		return IntentResult{}, fmt.Errorf("intent: %w", err)
 	}
 
-	for _, block := range resp.Content {
-		if block.Type == "text" {
-			text := strings.TrimSpace(block.Text)
-			// Strip markdown code block if present.
-			text = strings.TrimPrefix(text, "```json")
-			text = strings.TrimPrefix(text, "```")
-			text = strings.TrimSuffix(text, "```")
-			text = strings.TrimSpace(text)
-
-			var result IntentResult
-			if err := json.Unmarshal([]byte(text), &result); err != nil {
-				return IntentResult{}, fmt.Errorf("intent: parse response %q: %w", text, err)
-			}
-			result.InputTokens = int64(resp.Usage.InputTokens)
-			result.OutputTokens = int64(resp.Usage.OutputTokens)
-			result.CacheReadTokens = int64(resp.Usage.CacheReadInputTokens)
-			result.CacheWriteTokens = int64(resp.Usage.CacheCreationInputTokens)
-			return result, nil
-		}
-	}
+	text := strings.TrimSpace(resp.Text)
+	// Strip markdown code block if present.
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	text = strings.TrimSpace(text)
 
-	return IntentResult{}, fmt.Errorf("intent: empty response")
+	var result IntentResult
+	if err := json.Unmarshal([]byte(text), &result); err != nil {
+		return IntentResult{}, fmt.Errorf("intent: parse response %q: %w", text, err)
+	}
+	result.InputTokens = resp.InputTokens
+	result.OutputTokens = resp.OutputTokens
+	result.CacheReadTokens = resp.CacheReadTokens
+	result.CacheWriteTokens = resp.CacheWriteTokens
+	result.CostUSD = resp.CostUSD
+	result.Model = resp.Model
+	result.Thinking = resp.Thinking
+	result.Retries = retries
+	return result, nil
 }
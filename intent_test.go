@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"math"
 	"testing"
 )
@@ -35,3 +36,73 @@ func TestComputeIntentCost(t *testing.T) {
 		})
 	}
 }
+
+func TestComputeClaudeCost(t *testing.T) {
+	sonnet := 1000*sonnetPriceInputPerToken + 100*sonnetPriceOutputPerToken + 200*sonnetPriceCacheReadPerToken + 50*sonnetPriceCacheWritePerToken
+	opus := 1000*opusPriceInputPerToken + 100*opusPriceOutputPerToken + 200*opusPriceCacheReadPerToken + 50*opusPriceCacheWritePerToken
+	haiku := computeIntentCost(1000, 100, 200, 50)
+	for model, want := range map[string]float64{
+		string(intentModel):                            haiku,
+		bedrockIntentModel:                             haiku,
+		"claude-sonnet-4-5":                            sonnet,
+		"us.anthropic.claude-sonnet-4-5-20250929-v1:0": sonnet,
+		"claude-sonnet-4-5@20250929":                   sonnet,
+		"claude-opus-4-1":                              opus,
+		"us.anthropic.claude-opus-4-1-20250805-v1:0":   opus,
+		"claude-mystery-5":                             opus,
+	} {
+		if got := computeClaudeCost(model, 1000, 100, 200, 50); math.Abs(got-want) > 1e-12 {
+			t.Errorf("computeClaudeCost(%q) = %g, want %g", model, got, want)
+		}
+	}
+}
+
+type fakeLLM struct {
+	resp LLMResponse
+	req  LLMRequest
+}
+
+func (f *fakeLLM) Name() string  { return "fake" }
+func (f *fakeLLM) Model() string { return "fake-model" }
+func (f *fakeLLM) Complete(_ context.Context, req LLMRequest) (LLMResponse, error) {
+	f.req = req
+	return f.resp, nil
+}
+
+func TestParseIntent(t *testing.T) {
+	llm := &fakeLLM{resp: LLMResponse{
+		Text:        "```json\n{\"repo\":\"bob\",\"task\":\"fix it\",\"question\":\"\",\"review_pr\":0,\"base_branch\":\"develop\"}\n```",
+		InputTokens: 10, OutputTokens: 5, CostUSD: 0.01,
+	}}
+	got, err := ParseIntent(context.Background(), llm, []Message{{Role: RoleUser, Content: "fix bob"}})
+	if err != nil {
+		t.Fatalf("ParseIntent: %v", err)
+	}
+	if got.Repo != "bob" || got.Task != "fix it" || got.BaseBranch != "develop" {
+		t.Errorf("intent = %+v", got)
+	}
+	if got.InputTokens != 10 || got.OutputTokens != 5 || got.CostUSD != 0.01 {
+		t.Errorf("usage = %+v", got)
+	}
+	if llm.req.System != intentSystemPrompt || !llm.req.JSON {
+		t.Errorf("request = %+v", llm.req)
+	}
+}
+
+func TestIntentResult_ConfirmationReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		intent IntentResult
+		want   bool
+	}{
+		{"confident", IntentResult{Confidence: 0.9}, false},
+		{"not reported", IntentResult{}, false},
+		{"low confidence", IntentResult{Confidence: 0.3}, true},
+		{"destructive", IntentResult{Confidence: 1, Destructive: true}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.intent.confirmationReason() != ""; got != tt.want {
+			t.Errorf("%s: needs confirmation = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
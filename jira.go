@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// maxTicketPromptLen caps the ticket text put in the planning prompt.
+const maxTicketPromptLen = 20000
+
+// jiraKeyPattern matches a Jira issue key, e.g. "PROJ-123".
+var jiraKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]+-[1-9][0-9]*$`)
+
+// JiraClient reads and updates the Jira tickets jobs are started from.
+type JiraClient struct {
+	baseURL string // e.g. https://acme.atlassian.net
+	email   string // Jira Cloud account for basic auth; empty sends token as a bearer token (Data Center)
+	token   string
+	// criteriaField is the custom field holding acceptance criteria (e.g.
+	// "customfield_10035"); empty if they are part of the description.
+	criteriaField string
+	// startTransition is applied when a job starts and reviewTransition when
+	// its pull request is opened; empty skips the transition.
+	startTransition  string
+	reviewTransition string
+}
+
+// NewJiraClient creates a JiraClient for the Jira at baseURL.
+func NewJiraClient(baseURL, email, token, criteriaField, startTransition, reviewTransition string) *JiraClient {
+	return &JiraClient{
+		baseURL:          strings.TrimSuffix(baseURL, "/"),
+		email:            email,
+		token:            token,
+		criteriaField:    criteriaField,
+		startTransition:  startTransition,
+		reviewTransition: reviewTransition,
+	}
+}
+
+// jiraTicket is the part of a Jira issue a job works from.
+type jiraTicket struct {
+	Key         string
+	Summary     string
+	Description string
+	Criteria    string // acceptance criteria, if kept in a separate field
+	Status      string
+	URL         string
+}
+
+// prompt describes the ticket for the planning session.
+func (t jiraTicket) prompt() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Jira ticket %s: %s\n\n%s (status: %s)\n", t.Key, t.Summary, t.URL, t.Status)
+	if t.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(t.Description))
+	}
+	if t.Criteria != "" {
+		fmt.Fprintf(&b, "\n### Acceptance criteria\n\n%s\n", strings.TrimSpace(t.Criteria))
+	}
+	return truncate(b.String(), maxTicketPromptLen) + "\n"
+}
+
+// do sends a Jira REST API request and returns the response body. body is
+// marshalled as JSON if non-nil.
+func (j *JiraClient) do(ctx context.Context, method, path string, body any, want int) ([]byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, j.baseURL+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if j.email != "" {
+		req.SetBasicAuth(j.email, j.token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+j.token)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jira api: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != want {
+		return nil, fmt.Errorf("jira api status %d: %s", resp.StatusCode, truncate(string(respBody), 300))
+	}
+	return respBody, nil
+}
+
+// Ticket fetches the ticket with the given key.
+func (j *JiraClient) Ticket(ctx context.Context, key string) (jiraTicket, error) {
+	fields := "summary,description,status"
+	if j.criteriaField != "" {
+		fields += "," + j.criteriaField
+	}
+	body, err := j.do(ctx, http.MethodGet, fmt.Sprintf("/rest/api/2/issue/%s?fields=%s", url.PathEscape(key), fields), nil, http.StatusOK)
+	if err != nil {
+		return jiraTicket{}, err
+	}
+	var resp struct {
+		Key    string                     `json:"key"`
+		Fields map[string]json.RawMessage `json:"fields"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return jiraTicket{}, fmt.Errorf("parse response: %w", err)
+	}
+	var status struct {
+		Name string `json:"name"`
+	}
+	t := jiraTicket{Key: resp.Key, URL: j.baseURL + "/browse/" + resp.Key}
+	// Text fields are null when empty; a rich-text criteria field that isn't
+	// a plain string is left out.
+	json.Unmarshal(resp.Fields["summary"], &t.Summary)
+	json.Unmarshal(resp.Fields["description"], &t.Description)
+	json.Unmarshal(resp.Fields["status"], &status)
+	if j.criteriaField != "" {
+		json.Unmarshal(resp.Fields[j.criteriaField], &t.Criteria)
+	}
+	t.Status = status.Name
+	return t, nil
+}
+
+// Transition moves the ticket through the transition named name
+// (case-insensitive), e.g. "In Progress". It fails if the ticket's workflow
+// doesn't offer that transition from its current status.
+func (j *JiraClient) Transition(ctx context.Context, key, name string) error {
+	path := fmt.Sprintf("/rest/api/2/issue/%s/transitions", url.PathEscape(key))
+	body, err := j.do(ctx, http.MethodGet, path, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"transitions"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("parse response: %w", err)
+	}
+	for _, t := range resp.Transitions {
+		if strings.EqualFold(t.Name, name) {
+			_, err := j.do(ctx, http.MethodPost, path, map[string]any{"transition": map[string]string{"id": t.ID}}, http.StatusNoContent)
+			return err
+		}
+	}
+	return fmt.Errorf("no %q transition available", name)
+}
+
+// Comment adds a comment to the ticket.
+func (j *JiraClient) Comment(ctx context.Context, key, text string) error {
+	_, err := j.do(ctx, http.MethodPost, fmt.Sprintf("/rest/api/2/issue/%s/comment", url.PathEscape(key)), map[string]string{"body": text}, http.StatusCreated)
+	return err
+}
+
+// ticketTask prefixes task with the ticket key, for the PR title and commit
+// subject, unless it already starts with it.
+func ticketTask(key, task string) string {
+	if strings.HasPrefix(task, key) {
+		return task
+	}
+	return key + ": " + task
+}
+
+// ticketBranchName is taskBranchName with the ticket key up front, in the
+// upper case Jira's development panel matches branches on.
+func ticketBranchName(key, task string) string {
+	task = strings.TrimPrefix(strings.TrimPrefix(task, key), ":")
+	return "bob/" + key + "-" + strings.TrimPrefix(taskBranchName(task), "bob/")
+}
+
+// withTicket prepares a request that references a Jira ticket: the key goes in
+// front of the task, and, with Jira configured, the full ticket is fetched for
+// the planning prompt and linked from the PR. It returns a user-facing reply
+// if the ticket can't be fetched, or empty string to go ahead.
+func (o *Orchestrator) withTicket(ctx context.Context, intent *IntentResult) string {
+	intent.Ticket = strings.ToUpper(strings.TrimSpace(intent.Ticket))
+	if !jiraKeyPattern.MatchString(intent.Ticket) {
+		intent.Ticket = ""
+		return ""
+	}
+	intent.Task = ticketTask(intent.Ticket, intent.Task)
+	if o.jira == nil {
+		return ""
+	}
+	t, err := o.jira.Ticket(ctx, intent.Ticket)
+	if err != nil {
+		slog.WarnContext(ctx, "jira: failed to fetch ticket", "ticket", intent.Ticket, "err", err)
+		return fmt.Sprintf("I couldn't fetch the Jira ticket %s: %s", intent.Ticket, err.Error())
+	}
+	intent.Context = t.prompt()
+	intent.PRFooter += "\n\nJira ticket: " + t.URL
+	return ""
+}
+
+// startTicket moves a job's ticket to the start transition.
+func (o *Orchestrator) startTicket(ctx context.Context, key string) {
+	if o.jira == nil || key == "" || o.jira.startTransition == "" {
+		return
+	}
+	if err := o.jira.Transition(ctx, key, o.jira.startTransition); err != nil {
+		slog.WarnContext(ctx, "jira: failed to transition ticket", "ticket", key, "transition", o.jira.startTransition, "err", err)
+	}
+}
+
+// linkTicket comments the job's pull request on its ticket and moves the
+// ticket to the review transition.
+func (o *Orchestrator) linkTicket(ctx context.Context, key, prURL string) {
+	if o.jira == nil || key == "" {
+		return
+	}
+	if err := o.jira.Comment(ctx, key, "Bob opened a pull request: "+prURL); err != nil {
+		slog.WarnContext(ctx, "jira: failed to comment on ticket", "ticket", key, "err", err)
+	}
+	if o.jira.reviewTransition == "" {
+		return
+	}
+	if err := o.jira.Transition(ctx, key, o.jira.reviewTransition); err != nil {
+		slog.WarnContext(ctx, "jira: failed to transition ticket", "ticket", key, "transition", o.jira.reviewTransition, "err", err)
+	}
+}
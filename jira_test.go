@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeJira serves one ticket, PROJ-123, recording transitions and comments.
+func fakeJira(t *testing.T) (*httptest.Server, *[]string) {
+	t.Helper()
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "bob@acme.com" || pass != "tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /rest/api/2/issue/PROJ-123":
+			if f := r.URL.Query().Get("fields"); !strings.Contains(f, "customfield_10035") {
+				t.Errorf("fields = %q", f)
+			}
+			w.Write([]byte(`{"key":"PROJ-123","fields":{"summary":"Round invoice totals","description":"Totals are off by a cent.","status":{"name":"To Do"},"customfield_10035":"Totals match the ledger."}}`))
+		case "GET /rest/api/2/issue/PROJ-123/transitions":
+			w.Write([]byte(`{"transitions":[{"id":"11","name":"In Progress"},{"id":"21","name":"In Review"}]}`))
+		case "POST /rest/api/2/issue/PROJ-123/transitions":
+			var body struct {
+				Transition struct{ ID string } `json:"transition"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			calls = append(calls, "transition "+body.Transition.ID)
+			w.WriteHeader(http.StatusNoContent)
+		case "POST /rest/api/2/issue/PROJ-123/comment":
+			var body struct{ Body string }
+			json.NewDecoder(r.Body).Decode(&body)
+			calls = append(calls, "comment "+body.Body)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestJiraClient(t *testing.T) {
+	srv, calls := fakeJira(t)
+	j := NewJiraClient(srv.URL+"/", "bob@acme.com", "tok", "customfield_10035", "In Progress", "in review")
+	ctx := context.Background()
+
+	ticket, err := j.Ticket(ctx, "PROJ-123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := jiraTicket{Key: "PROJ-123", Summary: "Round invoice totals", Description: "Totals are off by a cent.", Criteria: "Totals match the ledger.", Status: "To Do", URL: srv.URL + "/browse/PROJ-123"}
+	if ticket != want {
+		t.Errorf("ticket = %+v, want %+v", ticket, want)
+	}
+	if p := ticket.prompt(); !strings.HasPrefix(p, "## Jira ticket PROJ-123: Round invoice totals\n") || !strings.Contains(p, "### Acceptance criteria\n\nTotals match the ledger.") {
+		t.Errorf("prompt = %q", p)
+	}
+
+	if err := j.Transition(ctx, "PROJ-123", j.reviewTransition); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Transition(ctx, "PROJ-123", "Done"); err == nil {
+		t.Error("transition not offered by the workflow succeeded")
+	}
+	if err := j.Comment(ctx, "PROJ-123", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(*calls, "; "); got != "transition 21; comment hi" {
+		t.Errorf("calls = %q", got)
+	}
+	if _, err := j.Ticket(ctx, "PROJ-9"); err == nil {
+		t.Error("missing ticket fetched")
+	}
+}
+
+func TestWithTicket(t *testing.T) {
+	srv, _ := fakeJira(t)
+	o := &Orchestrator{jira: NewJiraClient(srv.URL, "bob@acme.com", "tok", "customfield_10035", "", "")}
+	ctx := context.Background()
+
+	intent := IntentResult{Repo: "billing-api", Task: "Implement the ticket", Ticket: "proj-123"}
+	if reply := o.withTicket(ctx, &intent); reply != "" {
+		t.Fatalf("reply = %q", reply)
+	}
+	if intent.Task != "PROJ-123: Implement the ticket" || intent.Ticket != "PROJ-123" {
+		t.Errorf("intent = %+v", intent)
+	}
+	if !strings.Contains(intent.Context, "Totals are off by a cent.") || intent.PRFooter != "\n\nJira ticket: "+srv.URL+"/browse/PROJ-123" {
+		t.Errorf("context = %q, footer = %q", intent.Context, intent.PRFooter)
+	}
+
+	intent = IntentResult{Task: "Implement PROJ-9", Ticket: "PROJ-9"}
+	if reply := o.withTicket(ctx, &intent); !strings.HasPrefix(reply, "I couldn't fetch the Jira ticket PROJ-9") {
+		t.Errorf("reply = %q", reply)
+	}
+
+	intent = IntentResult{Task: "Fix the build", Ticket: "the ticket"}
+	if reply := o.withTicket(ctx, &intent); reply != "" || intent.Ticket != "" || intent.Task != "Fix the build" {
+		t.Errorf("invalid key: reply = %q, intent = %+v", reply, intent)
+	}
+}
+
+func TestTicketBranchName(t *testing.T) {
+	got := ticketBranchName("PROJ-123", "PROJ-123: Round invoice totals")
+	if !strings.HasPrefix(got, "bob/PROJ-123-round-invoice-totals-") || !isValidBranchName(got) {
+		t.Errorf("branch = %q", got)
+	}
+}
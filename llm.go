@@ -1,5 +1,7 @@
 package main
 
+import "context"
+
 type Role string
 
 const (
@@ -11,3 +13,31 @@ type Message struct {
 	Role    Role
 	Content string
 }
+
+// LLMProvider runs the orchestration LLM calls (intent parsing) against a
+// model vendor. Claude Code sessions are not affected; they use their own auth.
+type LLMProvider interface {
+	Name() string  // "anthropic", "openai", "bedrock" or "vertex"
+	Model() string // recorded on every job as intent_model
+	Complete(ctx context.Context, req LLMRequest) (LLMResponse, error)
+}
+
+// LLMRequest is a single-turn completion over a conversation.
+type LLMRequest struct {
+	System    string
+	Messages  []Message
+	MaxTokens int
+	JSON      bool // the reply must be a JSON object
+}
+
+// LLMResponse is the text reply with usage for cost tracking.
+type LLMResponse struct {
+	Text             string
+	Thinking         string // the model's summarized extended thinking, if enabled
+	Model            string // the model that served the call
+	InputTokens      int64
+	OutputTokens     int64 // including thinking tokens
+	CacheReadTokens  int64
+	CacheWriteTokens int64
+	CostUSD          float64
+}
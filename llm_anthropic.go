@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/bedrock"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/vertex"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"golang.org/x/oauth2/google"
+)
+
+// Default Claude Haiku 4.5 model IDs on Bedrock (cross-region inference
+// profile) and Vertex AI.
+const (
+	bedrockIntentModel = "us.anthropic.claude-haiku-4-5-20251001-v1:0"
+	vertexIntentModel  = "claude-haiku-4-5@20251001"
+)
+
+// minThinkingBudget is the smallest extended thinking budget the API accepts.
+const minThinkingBudget = 1024
+
+// anthropicLLM is an LLMProvider for Claude models, called directly or through
+// AWS Bedrock or Google Vertex AI. Usage is priced at Claude Sonnet rates for
+// Sonnet models and Claude Haiku 4.5 rates otherwise (see computeClaudeCost).
+type anthropicLLM struct {
+	name   string
+	model  string
+	client anthropic.Client
+
+	// thinkingBudget is the extended thinking tokens allowed per call
+	// (BOB_LLM_THINKING_BUDGET); 0 disables thinking.
+	thinkingBudget int64
+}
+
+// thinkingBudgetFromEnv parses BOB_LLM_THINKING_BUDGET, 0 if unset.
+func thinkingBudgetFromEnv() (int64, error) {
+	v := os.Getenv("BOB_LLM_THINKING_BUDGET")
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 || (n > 0 && n < minThinkingBudget) {
+		return 0, fmt.Errorf("BOB_LLM_THINKING_BUDGET must be 0 or at least %d tokens, got %q", minThinkingBudget, v)
+	}
+	return n, nil
+}
+
+// NewAnthropicLLM calls the Anthropic API with an API key.
+func NewAnthropicLLM(apiKey, model string) LLMProvider {
+	if model == "" {
+		model = string(intentModel)
+	}
+	return &anthropicLLM{name: "anthropic", model: model, client: anthropic.NewClient(option.WithAPIKey(apiKey))}
+}
+
+// NewBedrockLLM calls Claude on AWS Bedrock, authenticating with the default
+// AWS credential chain (environment, shared config, instance role).
+func NewBedrockLLM(ctx context.Context, model string) (LLMProvider, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("bedrock: load AWS config: %w", err)
+	}
+	if model == "" {
+		model = bedrockIntentModel
+	}
+	return &anthropicLLM{name: "bedrock", model: model, client: anthropic.NewClient(bedrock.WithConfig(cfg))}, nil
+}
+
+// NewVertexLLM calls Claude on Google Vertex AI, authenticating with
+// application default credentials.
+func NewVertexLLM(ctx context.Context, region, projectID, model string) (LLMProvider, error) {
+	creds, err := google.FindDefaultCredentials(ctx, "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return nil, fmt.Errorf("vertex: find credentials: %w", err)
+	}
+	if model == "" {
+		model = vertexIntentModel
+	}
+	return &anthropicLLM{name: "vertex", model: model, client: anthropic.NewClient(vertex.WithCredentials(ctx, region, projectID, creds))}, nil
+}
+
+func (l *anthropicLLM) Name() string  { return l.name }
+func (l *anthropicLLM) Model() string { return l.model }
+
+// Ping checks that the API key is accepted, by listing a model. Bedrock and
+// Vertex AI have no models endpoint and aren't checked.
+func (l *anthropicLLM) Ping(ctx context.Context) error {
+	if l.name != "anthropic" {
+		return nil
+	}
+	_, err := l.client.Models.List(ctx, anthropic.ModelListParams{Limit: anthropic.Int(1)})
+	return err
+}
+
+// Complete implements LLMProvider.
+func (l *anthropicLLM) Complete(ctx context.Context, req LLMRequest) (LLMResponse, error) {
+	params := make([]anthropic.MessageParam, len(req.Messages))
+	for i, msg := range req.Messages {
+		block := anthropic.NewTextBlock(msg.Content)
+		switch msg.Role {
+		case RoleUser:
+			params[i] = anthropic.NewUserMessage(block)
+		case RoleAssistant:
+			params[i] = anthropic.NewAssistantMessage(block)
+		}
+	}
+
+	// The system prompt is the same on every call, so it is marked for the
+	// prompt cache. Prompts under the model's minimum cacheable length (1024
+	// tokens for Sonnet, 4096 for Haiku 4.5) are sent uncached by the API.
+	body := anthropic.MessageNewParams{
+		Model:     anthropic.Model(l.model),
+		MaxTokens: int64(req.MaxTokens),
+		System: []anthropic.TextBlockParam{
+			{Text: req.System, CacheControl: anthropic.NewCacheControlEphemeralParam()},
+		},
+		Messages: params,
+	}
+	if l.thinkingBudget > 0 {
+		// max_tokens covers thinking as well, so the reply keeps its own allowance.
+		body.Thinking = anthropic.ThinkingConfigParamOfEnabled(l.thinkingBudget)
+		body.MaxTokens += l.thinkingBudget
+	}
+	resp, err := l.client.Messages.New(ctx, body, option.WithMaxRetries(0)) // retried by completeWithRetry
+	if err != nil {
+		return LLMResponse{}, fmt.Errorf("%s: %w", l.name, err)
+	}
+
+	out := LLMResponse{
+		Model:            l.model,
+		InputTokens:      resp.Usage.InputTokens,
+		OutputTokens:     resp.Usage.OutputTokens,
+		CacheReadTokens:  resp.Usage.CacheReadInputTokens,
+		CacheWriteTokens: resp.Usage.CacheCreationInputTokens,
+	}
+	// Thinking is billed as output tokens and counted in them.
+	out.CostUSD = computeClaudeCost(l.model, out.InputTokens, out.OutputTokens, out.CacheReadTokens, out.CacheWriteTokens)
+	var thinking []string
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "thinking":
+			thinking = append(thinking, block.Thinking)
+		case "text":
+			out.Text = block.Text
+			out.Thinking = strings.Join(thinking, "\n\n")
+			return out, nil
+		}
+	}
+	return out, fmt.Errorf("%s: empty response", l.name)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+func TestAnthropicLLM_Complete(t *testing.T) {
+	var got struct {
+		System []struct {
+			Text         string            `json:"text"`
+			CacheControl map[string]string `json:"cache_control"`
+		} `json:"system"`
+		Messages []struct {
+			Role string `json:"role"`
+		} `json:"messages"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-haiku-4-5",
+			"content":[{"type":"text","text":"{\"repo\":\"bob\"}"}],"stop_reason":"end_turn",
+			"usage":{"input_tokens":10,"output_tokens":20,"cache_read_input_tokens":4000,"cache_creation_input_tokens":0}}`))
+	}))
+	defer srv.Close()
+
+	llm := &anthropicLLM{name: "anthropic", model: "claude-haiku-4-5", client: anthropic.NewClient(option.WithBaseURL(srv.URL), option.WithAPIKey("sk-test"))}
+	resp, err := llm.Complete(context.Background(), LLMRequest{
+		System:    "sys",
+		Messages:  []Message{{Role: RoleUser, Content: "hi"}, {Role: RoleAssistant, Content: "hello"}},
+		MaxTokens: 512,
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	if len(got.System) != 1 || got.System[0].Text != "sys" || got.System[0].CacheControl["type"] != "ephemeral" {
+		t.Errorf("system = %+v, want it marked for caching", got.System)
+	}
+	if len(got.Messages) != 2 || got.Messages[1].Role != "assistant" {
+		t.Errorf("messages = %+v", got.Messages)
+	}
+	if resp.Text != `{"repo":"bob"}` || resp.CacheReadTokens != 4000 || resp.CostUSD <= 0 {
+		t.Errorf("resp = %+v", resp)
+	}
+}
+
+func TestAnthropicLLM_CompleteWithThinking(t *testing.T) {
+	var got struct {
+		MaxTokens int64 `json:"max_tokens"`
+		Thinking  struct {
+			Type         string `json:"type"`
+			BudgetTokens int64  `json:"budget_tokens"`
+		} `json:"thinking"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5",
+			"content":[{"type":"thinking","thinking":"The repo is named.","signature":"sig"},{"type":"text","text":"{}"}],"stop_reason":"end_turn",
+			"usage":{"input_tokens":100,"output_tokens":1500}}`))
+	}))
+	defer srv.Close()
+
+	llm := &anthropicLLM{name: "anthropic", model: "claude-sonnet-4-5", thinkingBudget: 2048,
+		client: anthropic.NewClient(option.WithBaseURL(srv.URL), option.WithAPIKey("sk-test"))}
+	resp, err := llm.Complete(context.Background(), LLMRequest{System: "sys", Messages: []Message{{Role: RoleUser, Content: "hi"}}, MaxTokens: 512})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if got.Thinking.Type != "enabled" || got.Thinking.BudgetTokens != 2048 || got.MaxTokens != 2560 {
+		t.Errorf("request max_tokens = %d, thinking = %+v", got.MaxTokens, got.Thinking)
+	}
+	if resp.Text != "{}" || resp.Thinking != "The repo is named." {
+		t.Errorf("resp = %+v", resp)
+	}
+	if want := computeClaudeCost("claude-sonnet-4-5", 100, 1500, 0, 0); resp.CostUSD != want {
+		t.Errorf("cost = %v, want %v (thinking billed as output)", resp.CostUSD, want)
+	}
+}
+
+func TestLLMFromEnv_ThinkingBudget(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "sk-test")
+	t.Setenv("BOB_LLM_PROVIDER", "anthropic")
+	t.Setenv("BOB_LLM_THINKING_BUDGET", "4096")
+	llm, err := llmFromEnv("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l, ok := llm.(*anthropicLLM); !ok || l.thinkingBudget != 4096 {
+		t.Errorf("llm = %+v", llm)
+	}
+
+	for _, v := range []string{"512", "-1", "lots"} {
+		t.Setenv("BOB_LLM_THINKING_BUDGET", v)
+		if _, err := llmFromEnv(""); err == nil {
+			t.Errorf("budget %q: want error", v)
+		}
+	}
+
+	t.Setenv("BOB_LLM_THINKING_BUDGET", "2048")
+	t.Setenv("BOB_LLM_PROVIDER", "openai")
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	if _, err := llmFromEnv(""); err == nil {
+		t.Error("want error for thinking with OpenAI")
+	}
+}
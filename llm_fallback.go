@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+)
+
+// fallbackLLM tries a chain of models in order: when one is rate limited or
+// overloaded, the call goes to the next (BOB_LLM_FALLBACK_MODELS). If they all
+// are, the last error is returned, so completeWithRetry backs off and starts
+// over with the first. LLMResponse.Model records which model served the call.
+type fallbackLLM struct {
+	chain []LLMProvider // the primary model first
+}
+
+// newFallbackLLM returns primary, or a fallbackLLM over primary and fallbacks
+// if there are any.
+func newFallbackLLM(primary LLMProvider, fallbacks ...LLMProvider) LLMProvider {
+	if len(fallbacks) == 0 {
+		return primary
+	}
+	return &fallbackLLM{chain: append([]LLMProvider{primary}, fallbacks...)}
+}
+
+func (f *fallbackLLM) Name() string  { return f.chain[0].Name() }
+func (f *fallbackLLM) Model() string { return f.chain[0].Model() }
+
+// Ping checks the primary model's provider.
+func (f *fallbackLLM) Ping(ctx context.Context) error {
+	if p, ok := f.chain[0].(pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// Complete implements LLMProvider.
+func (f *fallbackLLM) Complete(ctx context.Context, req LLMRequest) (LLMResponse, error) {
+	var resp LLMResponse
+	var err error
+	for i, llm := range f.chain {
+		resp, err = llm.Complete(ctx, req)
+		if err == nil {
+			if resp.Model == "" {
+				resp.Model = llm.Model()
+			}
+			return resp, nil
+		}
+		if _, ok := retryableLLMError(err); !ok || i == len(f.chain)-1 {
+			break
+		}
+		slog.WarnContext(ctx, "llm: model unavailable, falling back", "model", llm.Model(), "fallback", f.chain[i+1].Model(), "err", err)
+	}
+	return resp, err
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+// modelLLM is a flakyLLM with its own model name.
+type modelLLM struct {
+	flakyLLM
+	model string
+}
+
+func (m *modelLLM) Model() string { return m.model }
+
+func TestFallbackLLM(t *testing.T) {
+	ctx := context.Background()
+
+	primary := &modelLLM{flakyLLM: flakyLLM{errs: []error{apiError(statusOverloaded, "")}}, model: "claude-sonnet-4-5"}
+	fallback := &modelLLM{model: "claude-haiku-4-5"}
+	llm := newFallbackLLM(primary, fallback)
+	resp, err := llm.Complete(ctx, LLMRequest{})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if resp.Model != "claude-haiku-4-5" || primary.calls != 1 || fallback.calls != 1 {
+		t.Errorf("model = %q, calls = %d/%d", resp.Model, primary.calls, fallback.calls)
+	}
+	if llm.Model() != "claude-sonnet-4-5" {
+		t.Errorf("Model() = %q, want the primary", llm.Model())
+	}
+
+	// Other errors aren't worth another model.
+	primary = &modelLLM{flakyLLM: flakyLLM{errs: []error{apiError(http.StatusBadRequest, "")}}, model: "claude-sonnet-4-5"}
+	fallback = &modelLLM{model: "claude-haiku-4-5"}
+	if _, err := newFallbackLLM(primary, fallback).Complete(ctx, LLMRequest{}); err == nil || fallback.calls != 0 {
+		t.Errorf("err = %v, fallback calls = %d", err, fallback.calls)
+	}
+
+	// When every model is overloaded, the error stays retryable.
+	primary = &modelLLM{flakyLLM: flakyLLM{errs: []error{apiError(statusOverloaded, "")}}, model: "a"}
+	fallback = &modelLLM{flakyLLM: flakyLLM{errs: []error{apiError(statusOverloaded, "")}}, model: "b"}
+	_, err = newFallbackLLM(primary, fallback).Complete(ctx, LLMRequest{})
+	if _, ok := retryableLLMError(err); !ok {
+		t.Errorf("err = %v, want retryable", err)
+	}
+
+	if single := newFallbackLLM(primary); single != LLMProvider(primary) {
+		t.Error("no fallbacks should return the primary as is")
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	openAIDefaultURL   = "https://api.openai.com/v1"
+	openAIIntentModel  = "gpt-4.1-mini"
+	maxOpenAIErrorBody = 4096
+)
+
+// GPT-4.1 mini pricing (USD per token), used for all OpenAI models.
+const (
+	openAIPriceInputPerToken     = 0.40 / 1_000_000
+	openAIPriceOutputPerToken    = 1.60 / 1_000_000
+	openAIPriceCacheReadPerToken = 0.10 / 1_000_000
+)
+
+// openAILLM is an LLMProvider for the OpenAI Chat Completions API, or any
+// compatible endpoint (OPENAI_BASE_URL).
+type openAILLM struct {
+	apiKey  string
+	baseURL string
+	model   string
+	http    *http.Client
+}
+
+// NewOpenAILLM creates an OpenAI provider. baseURL defaults to the public API.
+func NewOpenAILLM(apiKey, baseURL, model string) LLMProvider {
+	if baseURL == "" {
+		baseURL = openAIDefaultURL
+	}
+	if model == "" {
+		model = openAIIntentModel
+	}
+	return &openAILLM{apiKey: apiKey, baseURL: strings.TrimRight(baseURL, "/"), model: model, http: http.DefaultClient}
+}
+
+func (l *openAILLM) Name() string  { return "openai" }
+func (l *openAILLM) Model() string { return l.model }
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model          string            `json:"model"`
+	Messages       []openAIMessage   `json:"messages"`
+	MaxTokens      int               `json:"max_completion_tokens,omitempty"`
+	ResponseFormat map[string]string `json:"response_format,omitempty"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens        int64 `json:"prompt_tokens"`
+		CompletionTokens    int64 `json:"completion_tokens"`
+		PromptTokensDetails struct {
+			CachedTokens int64 `json:"cached_tokens"`
+		} `json:"prompt_tokens_details"`
+	} `json:"usage"`
+}
+
+// Ping checks that the API key is accepted by listing the models.
+func (l *openAILLM) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.baseURL+"/models", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+l.apiKey)
+	resp, err := l.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("openai: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxOpenAIErrorBody))
+		return fmt.Errorf("openai: status %d: %s", resp.StatusCode, msg)
+	}
+	return nil
+}
+
+// Complete implements LLMProvider.
+func (l *openAILLM) Complete(ctx context.Context, req LLMRequest) (LLMResponse, error) {
+	body := openAIChatRequest{Model: l.model, MaxTokens: req.MaxTokens}
+	if req.System != "" {
+		body.Messages = append(body.Messages, openAIMessage{Role: "system", Content: req.System})
+	}
+	for _, msg := range req.Messages {
+		body.Messages = append(body.Messages, openAIMessage{Role: string(msg.Role), Content: msg.Content})
+	}
+	if req.JSON {
+		body.ResponseFormat = map[string]string{"type": "json_object"}
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return LLMResponse{}, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, l.baseURL+"/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return LLMResponse{}, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+l.apiKey)
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.http.Do(httpReq)
+	if err != nil {
+		return LLMResponse{}, fmt.Errorf("openai: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxOpenAIErrorBody))
+		return LLMResponse{}, fmt.Errorf("openai: status %d: %s", resp.StatusCode, msg)
+	}
+
+	var out openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return LLMResponse{}, fmt.Errorf("openai: decode response: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return LLMResponse{}, fmt.Errorf("openai: empty response")
+	}
+
+	// Prompt tokens include cached ones; report them separately like Anthropic.
+	cached := out.Usage.PromptTokensDetails.CachedTokens
+	r := LLMResponse{
+		Text:            out.Choices[0].Message.Content,
+		Model:           l.model,
+		InputTokens:     out.Usage.PromptTokens - cached,
+		OutputTokens:    out.Usage.CompletionTokens,
+		CacheReadTokens: cached,
+	}
+	r.CostUSD = float64(r.InputTokens)*openAIPriceInputPerToken +
+		float64(r.OutputTokens)*openAIPriceOutputPerToken +
+		float64(r.CacheReadTokens)*openAIPriceCacheReadPerToken
+	return r, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAILLM_Complete(t *testing.T) {
+	var got openAIChatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", auth)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"repo\":\"bob\"}"}}],
+			"usage":{"prompt_tokens":100,"completion_tokens":20,"prompt_tokens_details":{"cached_tokens":40}}}`))
+	}))
+	defer srv.Close()
+
+	llm := NewOpenAILLM("sk-test", srv.URL+"/v1/", "")
+	resp, err := llm.Complete(context.Background(), LLMRequest{
+		System:    "sys",
+		Messages:  []Message{{Role: RoleUser, Content: "hi"}, {Role: RoleAssistant, Content: "hello"}},
+		MaxTokens: 512,
+		JSON:      true,
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	if got.Model != openAIIntentModel || got.MaxTokens != 512 || got.ResponseFormat["type"] != "json_object" {
+		t.Errorf("request = %+v", got)
+	}
+	wantRoles := []string{"system", "user", "assistant"}
+	if len(got.Messages) != len(wantRoles) {
+		t.Fatalf("got %d messages, want %d", len(got.Messages), len(wantRoles))
+	}
+	for i, role := range wantRoles {
+		if got.Messages[i].Role != role {
+			t.Errorf("message %d role = %q, want %q", i, got.Messages[i].Role, role)
+		}
+	}
+
+	if resp.Text != `{"repo":"bob"}` {
+		t.Errorf("Text = %q", resp.Text)
+	}
+	if resp.InputTokens != 60 || resp.CacheReadTokens != 40 || resp.OutputTokens != 20 {
+		t.Errorf("usage = %+v", resp)
+	}
+	if resp.CostUSD <= 0 {
+		t.Errorf("CostUSD = %g, want > 0", resp.CostUSD)
+	}
+}
+
+func TestOpenAILLM_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"bad key"}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	if _, err := NewOpenAILLM("bad", srv.URL, "gpt-x").Complete(context.Background(), LLMRequest{}); err == nil {
+		t.Error("expected error on 401")
+	}
+}
+
+func TestOpenAILLM_Ping(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			http.Error(w, `{"error":"bad key"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer srv.Close()
+
+	if err := NewOpenAILLM("sk-test", srv.URL+"/v1", "").(*openAILLM).Ping(context.Background()); err != nil {
+		t.Errorf("valid key: %v", err)
+	}
+	if err := NewOpenAILLM("bad", srv.URL+"/v1", "").(*openAILLM).Ping(context.Background()); err == nil {
+		t.Error("expected error on 401")
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// Backoff for LLM calls rejected as rate limited (429) or overloaded (529):
+// up to llmMaxRetries retries, waiting a random time up to llmRetryBase·2^n
+// (full jitter), capped at llmRetryMax, or the server's Retry-After if longer.
+var (
+	llmMaxRetries = 4
+	llmRetryBase  = 2 * time.Second
+	llmRetryMax   = 30 * time.Second
+)
+
+// statusOverloaded is the status Anthropic returns when its API is overloaded.
+const statusOverloaded = 529
+
+// errLLMUnavailable wraps the last error once retries are exhausted, so chat
+// replies can tell the user to try again later instead of reporting a failure.
+var errLLMUnavailable = errors.New("LLM provider is overloaded or rate limited")
+
+// completeWithRetry calls llm.Complete, retrying transient rate limit and
+// overload errors. onRetry, if non-nil, is called before each wait.
+func completeWithRetry(ctx context.Context, llm LLMProvider, req LLMRequest, onRetry func(LLMRetryData)) (LLMResponse, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := llm.Complete(ctx, req)
+		if err == nil {
+			return resp, nil
+		}
+		retryAfter, ok := retryableLLMError(err)
+		if !ok {
+			return resp, err
+		}
+		if attempt > llmMaxRetries {
+			return resp, fmt.Errorf("%w after %d attempts: %w", errLLMUnavailable, attempt, err)
+		}
+		wait := max(llmBackoff(attempt), retryAfter)
+		slog.WarnContext(ctx, "llm: call failed, retrying", "provider", llm.Name(), "attempt", attempt, "wait", wait.Round(time.Millisecond), "err", err)
+		if onRetry != nil {
+			onRetry(LLMRetryData{Provider: llm.Name(), Attempt: attempt, WaitMs: wait.Milliseconds(), Error: err.Error()})
+		}
+		select {
+		case <-ctx.Done():
+			return resp, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// llmBackoff returns a random wait before retry number attempt (1-based).
+func llmBackoff(attempt int) time.Duration {
+	ceiling := min(llmRetryBase<<(attempt-1), llmRetryMax)
+	if ceiling <= 0 {
+		ceiling = llmRetryMax
+	}
+	return rand.N(ceiling) + 1
+}
+
+// retryableLLMError reports whether err is a rate limit or overload error,
+// with the server's Retry-After if it sent one.
+func retryableLLMError(err error) (time.Duration, bool) {
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) {
+		return 0, false
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests && apiErr.StatusCode != statusOverloaded {
+		return 0, false
+	}
+	if apiErr.Response == nil {
+		return 0, true
+	}
+	secs, err := strconv.Atoi(apiErr.Response.Header.Get("Retry-After"))
+	if err != nil || secs <= 0 {
+		return 0, true
+	}
+	return min(time.Duration(secs)*time.Second, llmRetryMax), true
+}
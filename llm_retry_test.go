@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// flakyLLM fails with errs in turn, then succeeds.
+type flakyLLM struct {
+	errs  []error
+	calls int
+}
+
+func (f *flakyLLM) Name() string  { return "flaky" }
+func (f *flakyLLM) Model() string { return "flaky-model" }
+func (f *flakyLLM) Complete(context.Context, LLMRequest) (LLMResponse, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return LLMResponse{}, err
+	}
+	return LLMResponse{Text: `{"repo":"bob","task":"fix it"}`}, nil
+}
+
+func apiError(status int, retryAfter string) error {
+	resp := &http.Response{StatusCode: status, Header: http.Header{}}
+	if retryAfter != "" {
+		resp.Header.Set("Retry-After", retryAfter)
+	}
+	req, _ := http.NewRequest(http.MethodPost, "https://api.anthropic.com/v1/messages", nil)
+	return &anthropic.Error{StatusCode: status, Request: req, Response: resp}
+}
+
+func fastLLMRetries(t *testing.T) {
+	t.Helper()
+	base, ceiling := llmRetryBase, llmRetryMax
+	llmRetryBase, llmRetryMax = time.Millisecond, 5*time.Millisecond
+	t.Cleanup(func() { llmRetryBase, llmRetryMax = base, ceiling })
+}
+
+func TestCompleteWithRetry(t *testing.T) {
+	fastLLMRetries(t)
+	ctx := context.Background()
+
+	llm := &flakyLLM{errs: []error{apiError(statusOverloaded, ""), apiError(http.StatusTooManyRequests, "1")}}
+	var retries []LLMRetryData
+	if _, err := completeWithRetry(ctx, llm, LLMRequest{}, func(r LLMRetryData) { retries = append(retries, r) }); err != nil {
+		t.Fatalf("completeWithRetry: %v", err)
+	}
+	if llm.calls != 3 || len(retries) != 2 || retries[1].Attempt != 2 || retries[1].WaitMs != 5 {
+		t.Errorf("calls = %d, retries = %+v", llm.calls, retries)
+	}
+
+	llm = &flakyLLM{errs: []error{apiError(http.StatusBadRequest, "")}}
+	if _, err := completeWithRetry(ctx, llm, LLMRequest{}, nil); err == nil || llm.calls != 1 || errors.Is(err, errLLMUnavailable) {
+		t.Errorf("bad request: calls = %d, err = %v", llm.calls, err)
+	}
+
+	errs := make([]error, llmMaxRetries+1)
+	for i := range errs {
+		errs[i] = apiError(statusOverloaded, "")
+	}
+	llm = &flakyLLM{errs: errs}
+	_, err := completeWithRetry(ctx, llm, LLMRequest{}, nil)
+	if !errors.Is(err, errLLMUnavailable) || llm.calls != llmMaxRetries+1 {
+		t.Errorf("exhausted: calls = %d, err = %v", llm.calls, err)
+	}
+	if got := errorReply(err); got == errorReply(errors.New("boom")) {
+		t.Errorf("errorReply for exhausted retries = %q", got)
+	}
+}
+
+func TestParseIntent_Retries(t *testing.T) {
+	fastLLMRetries(t)
+	llm := &flakyLLM{errs: []error{apiError(statusOverloaded, "")}}
+	got, err := ParseIntent(context.Background(), llm, []Message{{Role: RoleUser, Content: "fix bob"}})
+	if err != nil {
+		t.Fatalf("ParseIntent: %v", err)
+	}
+	if len(got.Retries) != 1 || got.Retries[0].Provider != "flaky" {
+		t.Errorf("Retries = %+v", got.Retries)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// jobLogHandler tags log records with the job they belong to: its ID (a
+// job_id attribute, or from the context) and, looked up from the hub, the job's
+// channel, repo and phase. A job's server logs can then be found by ID.
+type jobLogHandler struct {
+	slog.Handler
+	hub   *atomic.Pointer[Hub] // shared by derived handlers; nil hub skips the lookup
+	level *slog.LevelVar
+}
+
+// newLogHandler returns a jobLogHandler writing to w at level ("debug",
+// "info", "warn" or "error"; empty is info) in format ("text" or "json";
+// empty is text).
+func newLogHandler(w io.Writer, level, format string) (*jobLogHandler, error) {
+	lvl := new(slog.LevelVar)
+	if err := setLogLevel(lvl, level); err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q (want text or json)", format)
+	}
+	return &jobLogHandler{Handler: h, hub: new(atomic.Pointer[Hub]), level: lvl}, nil
+}
+
+// setLevel changes the level records are logged at, e.g. on a config reload.
+func (h *jobLogHandler) setLevel(level string) error { return setLogLevel(h.level, level) }
+
+func setLogLevel(v *slog.LevelVar, level string) error {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return fmt.Errorf("invalid log level %q", level)
+		}
+	}
+	v.Set(lvl)
+	return nil
+}
+
+// setHub sets the hub job details are looked up in.
+func (h *jobLogHandler) setHub(hub *Hub) { h.hub.Store(hub) }
+
+// Handle implements slog.Handler. Attributes the caller set win over the
+// looked-up ones.
+func (h *jobLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	set := map[string]string{}
+	r.Attrs(func(a slog.Attr) bool {
+		set[a.Key] = a.Value.String()
+		return true
+	})
+	jobID := set["job_id"]
+	if jobID == "" {
+		jobID = JobIDFromCtx(ctx)
+	}
+	channel, _ := ctx.Value(ctxKeyChannel).(string)
+	var repo string
+	var phase JobPhase
+	hub := HubFromCtx(ctx)
+	if hub == nil {
+		hub = h.hub.Load()
+	}
+	if jobID != "" && hub != nil {
+		if state, ok := hub.GetJobState(jobID); ok {
+			state.mu.Lock()
+			repo, phase = state.Repo, state.Phase
+			if channel == "" {
+				channel = state.Channel
+			}
+			state.mu.Unlock()
+		}
+	}
+	for _, a := range []slog.Attr{
+		slog.String("job_id", jobID), slog.String("channel", channel),
+		slog.String("repo", repo), slog.String("phase", string(phase)),
+	} {
+		if _, ok := set[a.Key]; !ok && a.Value.String() != "" {
+			r.AddAttrs(a)
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *jobLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &jobLogHandler{Handler: h.Handler.WithAttrs(attrs), hub: h.hub, level: h.level}
+}
+
+// WithGroup implements slog.Handler.
+func (h *jobLogHandler) WithGroup(name string) slog.Handler {
+	return &jobLogHandler{Handler: h.Handler.WithGroup(name), hub: h.hub, level: h.level}
+}
+
+// fatal logs msg at error level and exits, for startup failures.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestJobLogHandler(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	hub.SetJobState("job-1", &JobState{Repo: "web", Phase: PhaseImplementing, Channel: "C1"})
+
+	var buf bytes.Buffer
+	h, err := newLogHandler(&buf, "", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.setHub(hub)
+	logger := slog.New(h)
+
+	line := func() map[string]any {
+		t.Helper()
+		var m map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+			t.Fatalf("parse %q: %v", buf.String(), err)
+		}
+		buf.Reset()
+		return m
+	}
+
+	logger.InfoContext(WithJobID(context.Background(), "job-1"), "orchestrator: started")
+	got := line()
+	for k, want := range map[string]string{"job_id": "job-1", "channel": "C1", "repo": "web", "phase": string(PhaseImplementing)} {
+		if got[k] != want {
+			t.Errorf("%s = %v, want %q", k, got[k], want)
+		}
+	}
+
+	logger.Info("checks: no check runs", "job_id", "job-1", "repo", "api")
+	got = line()
+	if got["repo"] != "api" || got["phase"] != string(PhaseImplementing) {
+		t.Errorf("attr job: repo = %v, phase = %v", got["repo"], got["phase"])
+	}
+
+	logger.Info("hub: started")
+	got = line()
+	if _, ok := got["job_id"]; ok {
+		t.Errorf("untagged line has job_id: %v", got)
+	}
+
+	logger.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("debug logged at info level: %s", buf.String())
+	}
+}
+
+func TestNewLogHandler_Invalid(t *testing.T) {
+	if _, err := newLogHandler(&bytes.Buffer{}, "loud", ""); err == nil {
+		t.Error("invalid level accepted")
+	}
+	if _, err := newLogHandler(&bytes.Buffer{}, "", "xml"); err == nil {
+		t.Error("invalid format accepted")
+	}
+	if _, err := newLogHandler(&bytes.Buffer{}, "DEBUG", "JSON"); err != nil {
+		t.Errorf("upper case: %v", err)
+	}
+}
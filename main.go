@@ -2,75 +2,324 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/slack-go/slack"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		os.Exit(runCLI(os.Args[2:], os.Stdin, os.Stdout))
+	}
+	cfgFile, logs := loadSettings()
+
 	botToken := os.Getenv("SLACK_BOT_TOKEN")
 	signingSecret := os.Getenv("SLACK_SIGNING_SECRET")
-	anthropicKey := os.Getenv("ANTHROPIC_API_KEY")
-	githubToken := os.Getenv("GITHUB_TOKEN")
-	githubOwner := os.Getenv("GITHUB_OWNER")
+	slackAppToken := os.Getenv("SLACK_APP_TOKEN") // xapp-...; enables Socket Mode
+	discordToken := os.Getenv("DISCORD_BOT_TOKEN")
+	teamsSecret := os.Getenv("TEAMS_OUTGOING_WEBHOOK_SECRET")
+	teamsWebhookURL := os.Getenv("TEAMS_INCOMING_WEBHOOK_URL")
 	claudeCodeToken := os.Getenv("CLAUDE_CODE_OAUTH_TOKEN")
 	bobURL := os.Getenv("BOB_URL") // e.g. https://bob.example.com
 	apiToken := os.Getenv("BOB_API_TOKEN")
-	if githubOwner == "" {
-		githubOwner = os.Getenv("GITHUB_ORG") // backwards compat
-	}
+	githubWebhookSecret := os.Getenv("GITHUB_WEBHOOK_SECRET")
+	githubBotLogin := os.Getenv("GITHUB_BOT_LOGIN")
+	sentrySecret := os.Getenv("SENTRY_CLIENT_SECRET")
+	ackText := os.Getenv("BOB_ACK_MESSAGE") // e.g. "Looking into this..."; empty disables
 
-	if botToken == "" || signingSecret == "" || anthropicKey == "" {
-		log.Fatal("SLACK_BOT_TOKEN, SLACK_SIGNING_SECRET, and ANTHROPIC_API_KEY must be set")
+	// The orchestration LLM (intent parsing) can be routed through another
+	// vendor, and fall back to other models when it is overloaded.
+	llm, err := llmFromEnv(os.Getenv("BOB_LLM_MODEL")) // BOB_LLM_MODEL overrides the provider's default model
+	if err != nil {
+		fatal("intent LLM setup failed", "err", err)
+	}
+	var fallbacks []LLMProvider
+	for _, model := range strings.Split(os.Getenv("BOB_LLM_FALLBACK_MODELS"), ",") {
+		if model = strings.TrimSpace(model); model == "" {
+			continue
+		}
+		fallback, err := llmFromEnv(model)
+		if err != nil {
+			fatal("intent LLM setup failed", "model", model, "err", err)
+		}
+		fallbacks = append(fallbacks, fallback)
+	}
+	slog.Info("intent parsing", "provider", llm.Name(), "model", llm.Model(), "fallbacks", len(fallbacks))
+	llm = newFallbackLLM(llm, fallbacks...)
+	if botToken == "" && discordToken == "" && teamsSecret == "" && githubBotLogin == "" {
+		fatal("SLACK_BOT_TOKEN, DISCORD_BOT_TOKEN, TEAMS_OUTGOING_WEBHOOK_SECRET or GITHUB_BOT_LOGIN must be set")
+	}
+	if (teamsSecret == "") != (teamsWebhookURL == "") {
+		fatal("TEAMS_OUTGOING_WEBHOOK_SECRET and TEAMS_INCOMING_WEBHOOK_URL must be set together")
 	}
-	if githubToken == "" || githubOwner == "" {
-		log.Fatal("GITHUB_TOKEN and GITHUB_OWNER must be set")
+	// Socket Mode replaces the signed /webhooks/slack endpoints.
+	if botToken != "" && slackAppToken == "" && signingSecret == "" {
+		fatal("SLACK_SIGNING_SECRET (or SLACK_APP_TOKEN for Socket Mode) must be set")
 	}
+	providers, githubProviders := vcsProvidersFromEnv()
 	if claudeCodeToken == "" {
-		log.Fatal("CLAUDE_CODE_OAUTH_TOKEN must be set")
+		fatal("CLAUDE_CODE_OAUTH_TOKEN must be set")
 	}
 	if apiToken == "" {
-		log.Fatal("BOB_API_TOKEN must be set")
+		fatal("BOB_API_TOKEN must be set")
 	}
+	// The UI, API and event stream accept BOB_API_TOKEN, any extra tokens in
+	// BOB_API_TOKENS, or a JWT from the OIDC issuer.
+	var oidc *oidcVerifier
+	if issuer := os.Getenv("BOB_OIDC_ISSUER"); issuer != "" {
+		var err error
+		if oidc, err = newOIDCVerifier(issuer, os.Getenv("BOB_OIDC_AUDIENCE")); err != nil {
+			fatal("BOB_OIDC_AUDIENCE must be set with BOB_OIDC_ISSUER", "err", err)
+		}
+	}
+	auth := newAuthenticator(append([]string{apiToken}, strings.Split(os.Getenv("BOB_API_TOKENS"), ",")...), oidc)
 
-	slackClient := slack.New(botToken)
+	registerEnvSecrets()
 
-	// Resolve bot user ID once at startup.
-	authResp, err := slackClient.AuthTest()
-	if err != nil {
-		log.Fatalf("slack auth test failed: %v", err)
+	// Chat platforms. Notifications are routed to the platform a job came from;
+	// the first configured platform is the default.
+	var platforms []ChatPlatform
+	var slackPlatform *SlackPlatform
+	if botToken != "" {
+		var slackOpts []slack.Option
+		if slackAppToken != "" {
+			slackOpts = append(slackOpts, slack.OptionAppLevelToken(slackAppToken))
+		}
+		slackClient := slack.New(botToken, slackOpts...)
+
+		// Resolve bot user ID once at startup.
+		authResp, err := slackClient.AuthTest()
+		if err != nil {
+			fatal("slack auth test failed", "err", err)
+		}
+		slog.Info("slack bot user", "user", authResp.UserID)
+		slackPlatform = NewSlackPlatform(slackClient, authResp.UserID)
+		platforms = append(platforms, slackPlatform)
+	}
+	var discordPlatform *DiscordPlatform
+	if discordToken != "" {
+		p, err := NewDiscordPlatform(discordToken)
+		if err != nil {
+			fatal("discord setup failed", "err", err)
+		}
+		discordPlatform = p
+		platforms = append(platforms, discordPlatform)
+	}
+	var teamsPlatform *TeamsPlatform
+	if teamsSecret != "" {
+		p, err := NewTeamsPlatform(teamsSecret, teamsWebhookURL)
+		if err != nil {
+			fatal("teams setup failed", "err", err)
+		}
+		teamsPlatform = p
+		platforms = append(platforms, teamsPlatform)
+	}
+	var issuePlatform *GitHubIssuePlatform
+	if githubBotLogin != "" {
+		if len(githubProviders) == 0 || githubWebhookSecret == "" {
+			fatal("GITHUB_BOT_LOGIN needs GitHub credentials and GITHUB_WEBHOOK_SECRET")
+		}
+		issuePlatform = NewGitHubIssuePlatform(githubProviders, githubBotLogin)
+		platforms = append(platforms, issuePlatform)
+	}
+	var sentryPlatform *SentryPlatform
+	if sentrySecret != "" {
+		sentryToken := os.Getenv("SENTRY_AUTH_TOKEN")
+		if sentryToken == "" {
+			fatal("SENTRY_CLIENT_SECRET needs SENTRY_AUTH_TOKEN to comment on issues")
+		}
+		sentryPlatform = NewSentryPlatform(os.Getenv("SENTRY_URL"), sentryToken, os.Getenv("SENTRY_TAG"))
+		platforms = append(platforms, sentryPlatform)
 	}
-	botUserID := authResp.UserID
-	log.Printf("Bot user ID: %s", botUserID)
 
-	hub := NewHub("/workspace/.bob")
+	hub := hubFromEnv()
+	logs.setHub(hub)
 
-	allowedRepos := parseAllowedRepos(os.Getenv("ALLOWED_REPOS"))
-	if allowedRepos != nil {
-		log.Printf("Repo allowlist active: %v", allowedRepos)
+	// Archive old jobs, by age and/or total size of the live job files.
+	var retention retentionPolicy
+	if v := os.Getenv("BOB_RETENTION_DAYS"); v != "" {
+		if days, err := strconv.Atoi(v); err == nil && days > 0 {
+			retention.MaxAge = time.Duration(days) * 24 * time.Hour
+		}
+	}
+	if v := os.Getenv("BOB_RETENTION_MAX_GB"); v != "" {
+		if gb, err := strconv.ParseFloat(v, 64); err == nil && gb > 0 {
+			retention.MaxBytes = int64(gb * (1 << 30))
+		}
+	}
+	retention.Upload = os.Getenv("BOB_ARCHIVE_UPLOAD_CMD")
+	if retention.enabled() {
+		slog.Info("archiving old jobs", "max_age", retention.MaxAge, "max_bytes", retention.MaxBytes, "upload", retention.Upload != "")
+		go hub.RunRetention(context.Background(), retention, time.Hour)
 	}
 
-	orch := NewOrchestrator(anthropicKey, githubOwner, githubToken, claudeCodeToken, hub, allowedRepos)
+	// Daily summary of jobs and cost, posted to a Slack channel.
+	if channel := os.Getenv("BOB_REPORT_CHANNEL"); channel != "" {
+		if slackPlatform == nil {
+			fatal("BOB_REPORT_CHANNEL needs Slack")
+		}
+		hour := 9
+		if v := os.Getenv("BOB_REPORT_HOUR"); v != "" {
+			h, err := strconv.Atoi(v)
+			if err != nil || h < 0 || h > 23 {
+				fatal("invalid BOB_REPORT_HOUR", "value", v)
+			}
+			hour = h
+		}
+		slog.Info("daily report enabled", "channel", channel, "hour_utc", hour)
+		go hub.RunDailyReport(context.Background(), slackPlatform, channel, hour)
+	}
 
-	maxPerMinute := 15.0
-	if v := os.Getenv("MAX_INBOUND_MESSAGES_PER_MIN"); v != "" {
-		if parsed, err := strconv.ParseFloat(v, 64); err == nil {
-			maxPerMinute = parsed
+	// Optional per-channel and per-user-group repo access rules.
+	var access *accessPolicy
+	if path := os.Getenv("BOB_ACCESS_FILE"); path != "" {
+		var members groupMemberLister
+		if slackPlatform != nil {
+			members = slackPlatform
+		}
+		var err error
+		if access, err = loadAccessPolicy(path, members); err != nil {
+			fatal("invalid BOB_ACCESS_FILE", "err", err)
 		}
+		slog.Info("repo access rules loaded", "path", path)
+	}
+
+	orch, sessions := orchestratorFromEnv(llm, providers, claudeCodeToken, hub, access)
+
+	// Remove worktrees that finished jobs left behind, and keep the
+	// workspace size metric current.
+	if orch.disk.GCAfter > 0 {
+		slog.Info("workspace garbage collection", "after", orch.disk.GCAfter, "max_bytes", orch.disk.MaxBytes, "max_job_bytes", orch.disk.MaxJobBytes)
+		go orch.RunWorkspaceGC(context.Background(), time.Hour)
 	}
 
-	approver := NewApprover(slackClient, hub, orch)
+	// Per-user inbound rate limit and optional daily quotas.
+	maxPerMinute, maxUserJobs, maxUserCostUSD := userLimitsFromEnv()
+	if maxUserJobs > 0 {
+		slog.Info("per-user daily job quota", "jobs", maxUserJobs)
+	}
+	if maxUserCostUSD > 0 {
+		slog.Info("per-user daily cost quota", "usd", maxUserCostUSD)
+	}
+	limits := newUserLimits(maxPerMinute, maxUserJobs, maxUserCostUSD)
+	hub.limits.Store(limits)
+
+	// On SIGHUP, re-read the config file and apply the settings that can
+	// change at runtime.
+	if cfgFile != nil {
+		go cfgFile.watch(context.Background(), func() {
+			if err := logs.setLevel(os.Getenv("BOB_LOG_LEVEL")); err != nil {
+				slog.Warn("config: keeping the log level", "err", err)
+			}
+			if rules, err := parseRepoFilter(os.Getenv("REPO_ALLOWLIST"), os.Getenv("REPO_BLOCKLIST")); err != nil {
+				slog.Warn("config: keeping the repo filter", "err", err)
+			} else {
+				repoRules.Store(rules)
+			}
+			sessions.update(sessionLimitsFromEnv())
+			limits.setLimits(userLimitsFromEnv())
+		})
+	}
 
+	// The notifier is the pluggable delivery backend for progress and results.
+	notifier := newChatRouter(platforms...)
+	// After opening a PR, Bob reports its CI checks; optionally it tries to fix failures.
+	approver := NewApprover(notifier, hub, orch, os.Getenv("BOB_CHECKS_AUTOFIX") == "true")
+
+	// Nudge, then close, jobs left waiting on a reply. 0 disables either.
+	wait := waitPolicy{RemindAfter: 24 * time.Hour, ExpireAfter: 7 * 24 * time.Hour}
+	for env, d := range map[string]*time.Duration{"BOB_WAIT_REMIND_HOURS": &wait.RemindAfter, "BOB_WAIT_EXPIRE_HOURS": &wait.ExpireAfter} {
+		if v := os.Getenv(env); v != "" {
+			hours, err := strconv.ParseFloat(v, 64)
+			if err != nil || hours < 0 {
+				fatal("invalid "+env, "value", v)
+			}
+			*d = time.Duration(hours * float64(time.Hour))
+		}
+	}
+	if wait.enabled() {
+		go approver.RunWaitSweeper(context.Background(), wait, 10*time.Minute)
+	}
+
+	// Live per-phase progress messages, edited in place, where the platform supports it.
+	if slackPlatform != nil {
+		posters := map[string]progressPoster{"slack": slackPlatform, "": slackPlatform}
+		hub.progress.Store(newProgressReporter(context.Background(), hub, posters, 3*time.Second))
+	}
+
+	// Outbound webhooks on job lifecycle events, from config and the API.
+	webhooks := newWebhookDispatcher(context.Background(), hub, hub.dataDir, os.Getenv("BOB_WEBHOOK_SECRET"), bobURL, parseWebhookURLs(os.Getenv("BOB_WEBHOOK_URLS")))
+	hub.webhooks.Store(webhooks)
+
+	server, err := serverConfigFromEnv(hub.dataDir)
+	if err != nil {
+		fatal("invalid server settings", "err", err)
+	}
+	// Webhooks go on mux; everything else on uiMux, which is the same mux
+	// unless the UI has a listener of its own.
 	mux := http.NewServeMux()
-	mux.Handle("/webhooks/slack", NewSlackHandler(slackClient, signingSecret, orch, hub, botUserID, approver, bobURL, apiToken, maxPerMinute))
-	mux.Handle("/webhooks/slack/interactions", NewSlackInteractionHandler(slackClient, signingSecret, approver))
-	mux.Handle("/webhooks/slack/commands", NewSlashCommandHandler(signingSecret, hub))
-	mux.Handle("/events", requireAuthFunc(apiToken, hub.ServeSSE))
-	mux.Handle("/api/jobs/", requireAuth(apiToken, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	uiMux := mux
+	if server.UIAddr != "" {
+		uiMux = http.NewServeMux()
+	}
+	if slackPlatform != nil {
+		slackDispatch := newSlackDispatcher(slackPlatform, orch, hub, approver, bobURL, apiToken, limits, ackText)
+		if slackAppToken != "" {
+			slog.Info("using Slack Socket Mode")
+			go func() {
+				if err := RunSlackSocketMode(context.Background(), slackPlatform.Client(), slackDispatch); err != nil {
+					fatal("slack socket mode failed", "err", err)
+				}
+			}()
+		} else {
+			mux.Handle("/webhooks/slack", NewSlackHandler(signingSecret, slackDispatch))
+			mux.Handle("/webhooks/slack/interactions", NewSlackInteractionHandler(signingSecret, slackDispatch))
+			mux.Handle("/webhooks/slack/commands", NewSlashCommandHandler(signingSecret, slackDispatch))
+		}
+	}
+	if discordPlatform != nil {
+		go func() {
+			if err := RunDiscord(context.Background(), discordPlatform, orch, hub, approver, bobURL, apiToken, limits); err != nil {
+				fatal("discord failed", "err", err)
+			}
+		}()
+	}
+	if teamsPlatform != nil {
+		mux.Handle("/webhooks/teams", NewTeamsHandler(teamsPlatform, orch, hub, approver, limits, bobURL, apiToken))
+	}
+	if githubWebhookSecret != "" {
+		var issues *issueDispatcher
+		if issuePlatform != nil {
+			issues = newIssueDispatcher(issuePlatform, orch, hub, approver, limits, bobURL)
+		}
+		accounts := &githubAccounts{botLogin: githubBotLogin, providers: githubProviders}
+		mux.Handle("/webhooks/github", NewGitHubWebhookHandler(githubWebhookSecret, orch, issues, accounts, limits))
+	}
+	if sentryPlatform != nil {
+		mux.Handle("/webhooks/sentry", NewSentryWebhookHandler(sentrySecret, sentryPlatform, orch, hub, bobURL))
+	}
+	// Probes and build info, unauthenticated, on every listener.
+	ready := newReadiness(slackPlatform, providers, llm)
+	for _, m := range slices.Compact([]*http.ServeMux{mux, uiMux}) {
+		m.HandleFunc("/healthz", serveHealthz)
+		m.Handle("/readyz", ready)
+		m.HandleFunc("/version", serveVersion)
+	}
+	uiMux.Handle("/metrics", requireAuth(auth, promhttp.Handler()))
+	uiMux.Handle("/events", requireAuthFunc(auth, hub.ServeSSE))
+	uiMux.Handle("/ws/events", requireAuth(auth, NewWebSocketHandler(hub, orch, notifier)))
+	uiMux.Handle("/api/jobs/", requireAuth(auth, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// POST /api/jobs/{id}/approve — web UI approval endpoint.
 		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/approve") {
 			path := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
@@ -80,9 +329,10 @@ func main() {
 				return
 			}
 
+			// Jobs submitted via the API have no Slack thread; approval still works.
 			state, ok := hub.GetJobState(jobID)
-			if !ok || state.Channel == "" || state.ThreadTS == "" {
-				http.Error(w, `{"error":"job not found or missing Slack thread info"}`, http.StatusNotFound)
+			if !ok {
+				http.Error(w, `{"error":"job not found"}`, http.StatusNotFound)
 				return
 			}
 
@@ -92,17 +342,397 @@ func main() {
 			w.Write([]byte(`{"ok":true}`))
 			return
 		}
+		// POST /api/jobs/{id}/push — push a dry run's changes from the web UI.
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/push") {
+			path := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
+			jobID := strings.TrimSuffix(path, "/push")
+			state, ok := hub.GetJobState(jobID)
+			if !ok {
+				http.Error(w, `{"error":"job not found"}`, http.StatusNotFound)
+				return
+			}
+
+			go approver.Push(context.Background(), jobID, state.Channel, state.ThreadTS, "web UI")
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok":true}`))
+			return
+		}
+		// POST /api/jobs/{id}/rerun — start a failed job's request again.
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/rerun") {
+			path := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
+			serveRerunJob(w, r, orch, approver, strings.TrimSuffix(path, "/rerun"))
+			return
+		}
+		// POST /api/jobs/{id}/cancel — stop a running or waiting job.
+		if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/cancel") {
+			path := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
+			jobID := strings.TrimSuffix(path, "/cancel")
+			switch err := cancelJob(hub, orch, notifier, jobID, "API"); {
+			case errors.Is(err, ErrJobNotFound):
+				http.Error(w, `{"error":"job not found"}`, http.StatusNotFound)
+				return
+			case err != nil:
+				http.Error(w, `{"error":"job already finished"}`, http.StatusConflict)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"ok":true}`))
+			return
+		}
 		hub.ServeJobAPI(w, r)
 	})))
-	mux.Handle("/api/jobs", requireAuthFunc(apiToken, hub.ServeJobList))
-	mux.Handle("/api/stats", requireAuthFunc(apiToken, hub.ServeStats))
+	uiMux.Handle("/api/jobs", requireAuth(auth, NewJobsHandler(hub, orch, approver)))
+	uiMux.Handle("/api/stats", requireAuthFunc(auth, hub.ServeStats))
+	uiMux.Handle("/api/active", requireAuthFunc(auth, hub.ServeActive))
+	uiMux.Handle("/api/webhooks", requireAuth(auth, webhooks))
+	uiMux.Handle("/api/webhooks/", requireAuth(auth, webhooks))
 	ui := serveUI()
-	mux.Handle("/assets/", ui)
-	mux.Handle("/jobs/", ui)
-	mux.Handle("/", ui)
+	uiMux.Handle("/assets/", ui)
+	uiMux.Handle("/jobs/", requireAuth(auth, ui))
+	uiMux.Handle("/", ui)
+
+	if err := server.serve(recoverHandler(mux), recoverHandler(uiMux)); err != nil {
+		fatal("server failed", "err", err)
+	}
+}
+
+// loadSettings applies BOB_CONFIG_FILE to the environment, where the
+// environment wins, sets up logging and the workspace, and loads the repo
+// filter. Settings errors are fatal.
+func loadSettings() (*configFile, *jobLogHandler) {
+	// Settings can also come from a YAML file; the environment wins over it.
+	var cfgFile *configFile
+	if path := os.Getenv("BOB_CONFIG_FILE"); path != "" {
+		var err error
+		if cfgFile, err = loadConfigFile(path); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	log.Println("Bob listening on :8080")
-	if err := http.ListenAndServe(":8080", mux); err != nil {
+	logs, err := newLogHandler(os.Stderr, os.Getenv("BOB_LOG_LEVEL"), os.Getenv("BOB_LOG_FORMAT"))
+	if err != nil {
 		log.Fatal(err)
 	}
+	slog.SetDefault(slog.New(logs))
+	if cfgFile != nil {
+		slog.Info("settings loaded", "path", cfgFile.path)
+	}
+	if dir := os.Getenv("WORKSPACE_DIR"); dir != "" {
+		if !filepath.IsAbs(dir) {
+			fatal("WORKSPACE_DIR must be an absolute path")
+		}
+		workspaceRoot = filepath.Clean(dir)
+	}
+	rules, err := parseRepoFilter(os.Getenv("REPO_ALLOWLIST"), os.Getenv("REPO_BLOCKLIST"))
+	if err != nil {
+		fatal("invalid REPO_ALLOWLIST/REPO_BLOCKLIST", "err", err)
+	}
+	if rules != nil {
+		repoRules.Store(rules)
+		slog.Info("repo filter active", "allow", rules.allow, "block", rules.block)
+	}
+	return cfgFile, logs
+}
+
+// vcsProvidersFromEnv sets up the configured repo hosts, GitHub first, and
+// returns them with the GitHub providers, one per owner, default first.
+func vcsProvidersFromEnv() ([]VCSProvider, []*GitHubProvider) {
+	githubToken := os.Getenv("GITHUB_TOKEN")
+	githubOwner := os.Getenv("GITHUB_OWNER")
+	if githubOwner == "" {
+		githubOwner = os.Getenv("GITHUB_ORG") // backwards compat
+	}
+	githubOwners := parseGitHubOwners(githubOwner)
+	githubAppID := os.Getenv("GITHUB_APP_ID")
+	githubAppKeyPath := os.Getenv("GITHUB_APP_PRIVATE_KEY_PATH")
+	githubAppInstallationID := os.Getenv("GITHUB_APP_INSTALLATION_ID") // optional; looked up from the default owner
+	gitlabURL := os.Getenv("GITLAB_URL")                               // e.g. https://gitlab.example.com; defaults to gitlab.com
+	gitlabToken := os.Getenv("GITLAB_TOKEN")
+	gitlabGroup := os.Getenv("GITLAB_GROUP")
+	adoURL := os.Getenv("AZURE_DEVOPS_URL") // organization URL, e.g. https://dev.azure.com/contoso
+	adoToken := os.Getenv("AZURE_DEVOPS_TOKEN")
+
+	// Repos are resolved against GitHub first, then GitLab, then Azure DevOps.
+	var providers []VCSProvider
+	var githubProviders []*GitHubProvider
+	switch {
+	case githubAppID != "" && len(githubOwners) > 0:
+		// A GitHub App takes precedence over a personal access token.
+		keyPEM, err := os.ReadFile(githubAppKeyPath)
+		if err != nil {
+			fatal("can't read GITHUB_APP_PRIVATE_KEY_PATH", "err", err)
+		}
+		slog.Info("authenticating to GitHub as an app", "app_id", githubAppID)
+		for i, owner := range githubOwners {
+			// Each owner has its own installation of the App.
+			installationID := ""
+			if i == 0 {
+				installationID = githubAppInstallationID
+			}
+			app, err := newGitHubAppAuth(githubAppID, keyPEM, installationID, owner)
+			if err != nil {
+				fatal("GitHub app setup failed", "owner", owner, "err", err)
+			}
+			githubProviders = append(githubProviders, NewGitHubAppProvider(owner, app))
+		}
+	case githubToken != "" && len(githubOwners) > 0:
+		for _, owner := range githubOwners {
+			githubProviders = append(githubProviders, NewGitHubProvider(owner, githubToken))
+		}
+	}
+	for i, p := range githubProviders {
+		p.qualified = i > 0
+		providers = append(providers, p)
+	}
+	if len(githubOwners) > 1 {
+		slog.Info("serving several GitHub owners", "default", githubOwners[0], "owners", githubOwners)
+	}
+	if gitlabToken != "" && gitlabGroup != "" {
+		providers = append(providers, NewGitLabProvider(gitlabURL, gitlabGroup, gitlabToken))
+	}
+	if adoURL != "" && adoToken != "" {
+		providers = append(providers, NewAzureDevOpsProvider(adoURL, os.Getenv("AZURE_DEVOPS_PROJECT"), adoToken))
+	}
+	if len(providers) == 0 {
+		fatal("GITHUB_OWNER with GITHUB_TOKEN or GITHUB_APP_ID (or GITLAB_TOKEN and GITLAB_GROUP, or AZURE_DEVOPS_URL and AZURE_DEVOPS_TOKEN) must be set")
+	}
+	return providers, githubProviders
+}
+
+// parseGitHubOwners splits GITHUB_OWNER's comma-separated owners, dropping
+// blanks and duplicates. The first is the default owner.
+func parseGitHubOwners(raw string) []string {
+	var owners []string
+	for _, o := range strings.Split(raw, ",") {
+		if o = strings.TrimSpace(o); o != "" && !slices.ContainsFunc(owners, func(s string) bool { return strings.EqualFold(s, o) }) {
+			owners = append(owners, o)
+		}
+	}
+	return owners
+}
+
+// registerEnvSecrets registers Bob's own credentials for redaction.
+func registerEnvSecrets() {
+	// Never echo Bob's own credentials into events, chat messages or PRs.
+	// BOB_API_TOKEN is left out: job links in chat deliberately carry it.
+	for _, name := range []string{
+		"SLACK_BOT_TOKEN", "SLACK_SIGNING_SECRET", "SLACK_APP_TOKEN", "DISCORD_BOT_TOKEN",
+		"ANTHROPIC_API_KEY", "OPENAI_API_KEY", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN",
+		"GITHUB_TOKEN", "GITHUB_WEBHOOK_SECRET", "GITLAB_TOKEN", "AZURE_DEVOPS_TOKEN", "CLAUDE_CODE_OAUTH_TOKEN",
+		"BOB_WEBHOOK_SECRET", "CLOUDFLARED_TOKEN", "TEAMS_OUTGOING_WEBHOOK_SECRET", "TEAMS_INCOMING_WEBHOOK_URL",
+	} {
+		registerSecrets(os.Getenv(name))
+	}
+}
+
+// hubFromEnv creates the Hub with the event store BOB_EVENT_STORE selects.
+func hubFromEnv() *Hub {
+	dataDir := workspacePath(".bob")
+	var hub *Hub
+	if os.Getenv("BOB_EVENT_STORE") == "sqlite" {
+		store, err := newSQLiteStore(filepath.Join(dataDir, "events.db"))
+		if err != nil {
+			slog.Warn("sqlite event store unavailable, falling back to JSONL", "err", err)
+			hub = NewHub(dataDir)
+		} else {
+			slog.Info("using SQLite event store")
+			hub = NewHubWithStore(dataDir, store)
+		}
+	} else {
+		hub = NewHub(dataDir)
+	}
+	if v := os.Getenv("BOB_EVENT_COALESCE_MS"); v != "" {
+		if ms, err := strconv.Atoi(v); err == nil && ms > 0 {
+			hub.EnableLineCoalescing(time.Duration(ms) * time.Millisecond)
+			slog.Info("coalescing Claude Code output", "window_ms", ms)
+		}
+	}
+	return hub
+}
+
+// orchestratorFromEnv creates the Orchestrator with the job settings from the
+// environment. The session limits are returned for config reloads.
+func orchestratorFromEnv(llm LLMProvider, providers []VCSProvider, claudeCodeToken string, hub *Hub, access *accessPolicy) (*Orchestrator, *SessionConfig) {
+	allowedRepos := parseAllowedRepos(os.Getenv("ALLOWED_REPOS"))
+	if allowedRepos != nil {
+		slog.Info("repo allowlist active", "repos", allowedRepos)
+	}
+
+	testFixRetries := 2
+	if v := os.Getenv("BOB_TEST_FIX_RETRIES"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed >= 0 {
+			testFixRetries = parsed
+		}
+	}
+
+	var maxJobCostUSD float64 // 0 = unlimited
+	if v := os.Getenv("BOB_MAX_JOB_COST_USD"); v != "" {
+		if parsed, err := strconv.ParseFloat(v, 64); err == nil && parsed > 0 {
+			maxJobCostUSD = parsed
+			slog.Info("per-job cost budget", "usd", maxJobCostUSD)
+		}
+	}
+
+	// Optional container sandbox for Claude Code and test runs. The volume is
+	// also needed for repos whose .bob.yml sets a test_image.
+	volume := os.Getenv("BOB_SANDBOX_VOLUME")
+	if volume == "" {
+		volume = defaultSandboxVolume
+	}
+	image := os.Getenv("BOB_SANDBOX_IMAGE")
+	hostTests := os.Getenv("BOB_ALLOW_HOST_TESTS") == "true"
+	testNetwork := os.Getenv("BOB_SANDBOX_TEST_NETWORK")
+	if testNetwork != "" {
+		if err := checkDockerNetwork(context.Background(), testNetwork); err != nil {
+			fatal("BOB_SANDBOX_TEST_NETWORK: no such Docker network", "err", err)
+		}
+		slog.Info("running repo test and scanner commands on a Docker network", "network", testNetwork)
+	}
+	sandbox := NewSandbox(image, volume, parseRepoImages(os.Getenv("BOB_SANDBOX_REPO_IMAGES")), hostTests, testNetwork)
+	switch {
+	case image != "":
+		slog.Info("sandboxing Claude Code and tests", "image", image, "volume", volume)
+	case hostTests:
+		slog.Warn("running repo test commands on the host (BOB_ALLOW_HOST_TESTS); they share Bob's network")
+	}
+
+	// Claude Code run limits: global defaults with per-repo overrides.
+	sessions := NewSessionConfig(sessionLimitsFromEnv())
+
+	// Claude Code tool rules by phase, replacing the defaults.
+	var tools toolPolicy
+	if path := os.Getenv("BOB_TOOL_POLICY_FILE"); path != "" {
+		var err error
+		if tools, err = loadToolPolicy(path); err != nil {
+			fatal("invalid BOB_TOOL_POLICY_FILE", "err", err)
+		}
+		slog.Info("tool policy loaded", "path", path)
+	}
+
+	// Commit identity, and whether to credit the requesting Slack user.
+	gitAuthor := gitIdentity{Name: os.Getenv("BOB_GIT_AUTHOR_NAME"), Email: os.Getenv("BOB_GIT_AUTHOR_EMAIL")}
+	creditRequester := os.Getenv("BOB_GIT_CO_AUTHOR") == "true"
+	if gitAuthor.Name != "" || gitAuthor.Email != "" {
+		slog.Info("committing as", "author", gitAuthor.orDefault().String())
+	}
+
+	// Jira tickets referenced in requests, e.g. "implement PROJ-123 in billing-api".
+	var jira *JiraClient
+	if jiraURL := os.Getenv("JIRA_URL"); jiraURL != "" {
+		jiraToken := os.Getenv("JIRA_API_TOKEN")
+		if jiraToken == "" {
+			fatal("JIRA_URL needs JIRA_API_TOKEN")
+		}
+		startTransition := os.Getenv("JIRA_START_TRANSITION")
+		if startTransition == "" {
+			startTransition = "In Progress"
+		} else if startTransition == "none" {
+			startTransition = ""
+		}
+		jira = NewJiraClient(jiraURL, os.Getenv("JIRA_EMAIL"), jiraToken, os.Getenv("JIRA_CRITERIA_FIELD"), startTransition, os.Getenv("JIRA_REVIEW_TRANSITION"))
+		slog.Info("jira enabled", "url", jiraURL)
+	}
+
+	return NewOrchestrator(llm, providers, claudeCodeToken, hub, allowedRepos, testFixRetries, maxJobCostUSD, sandbox, sessions, gitAuthor, creditRequester, access, jira, os.Getenv("BOB_GUIDELINES_FILE"), parseStepTimeouts(os.Getenv("BOB_STEP_TIMEOUTS")), parseScannerList(os.Getenv("BOB_SECURITY_SCANNERS")), workspaceQuotaFromEnv(), tools), sessions
+}
+
+// sessionLimitsFromEnv reads the Claude Code run limits: BOB_CLAUDE_TIMEOUT,
+// BOB_CLAUDE_MAX_TURNS, BOB_CLAUDE_MODEL and its fallback, and their per-repo
+// overrides.
+func sessionLimitsFromEnv() (SessionLimits, map[string]SessionLimits) {
+	var defaults SessionLimits
+	if v := os.Getenv("BOB_CLAUDE_TIMEOUT"); v != "" {
+		if parsed, err := time.ParseDuration(v); err == nil && parsed > 0 {
+			defaults.Timeout = parsed
+		}
+	}
+	if v := os.Getenv("BOB_CLAUDE_MAX_TURNS"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
+			defaults.MaxTurns = parsed
+		}
+	}
+	defaults.Model = os.Getenv("BOB_CLAUDE_MODEL")
+	defaults.FallbackModel = os.Getenv("BOB_CLAUDE_FALLBACK_MODEL")
+	if v := os.Getenv("BOB_CLAUDE_FALLBACK_AFTER_USD"); v != "" {
+		if parsed, err := strconv.ParseFloat(v, 64); err == nil && parsed > 0 {
+			defaults.FallbackAfterUSD = parsed
+		}
+	}
+	return defaults, parseRepoSessionLimits(
+		os.Getenv("BOB_CLAUDE_REPO_TIMEOUTS"),
+		os.Getenv("BOB_CLAUDE_REPO_MAX_TURNS"),
+		os.Getenv("BOB_CLAUDE_REPO_MODELS"),
+	)
+}
+
+// userLimitsFromEnv reads the per-user inbound rate limit and daily quotas;
+// zero quotas are unlimited.
+func userLimitsFromEnv() (perMinute float64, maxDailyJobs int, maxDailyCostUSD float64) {
+	perMinute = 15
+	if v := os.Getenv("MAX_INBOUND_MESSAGES_PER_MIN"); v != "" {
+		if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+			perMinute = parsed
+		}
+	}
+	if v := os.Getenv("BOB_MAX_USER_JOBS_PER_DAY"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
+			maxDailyJobs = parsed
+		}
+	}
+	if v := os.Getenv("BOB_MAX_USER_COST_USD_PER_DAY"); v != "" {
+		if parsed, err := strconv.ParseFloat(v, 64); err == nil && parsed > 0 {
+			maxDailyCostUSD = parsed
+		}
+	}
+	return perMinute, maxDailyJobs, maxDailyCostUSD
+}
+
+// llmFromEnv creates the intent LLM for BOB_LLM_PROVIDER with model; empty
+// uses the provider's default. BOB_LLM_THINKING_BUDGET enables extended
+// thinking, which only Claude providers support.
+func llmFromEnv(model string) (LLMProvider, error) {
+	budget, err := thinkingBudgetFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	llm, err := llmProviderFromEnv(model)
+	if err != nil || budget == 0 {
+		return llm, err
+	}
+	claude, ok := llm.(*anthropicLLM)
+	if !ok {
+		return nil, fmt.Errorf("BOB_LLM_THINKING_BUDGET needs a Claude provider (anthropic, bedrock or vertex), not %s", llm.Name())
+	}
+	claude.thinkingBudget = budget
+	return claude, nil
+}
+
+// llmProviderFromEnv creates the LLMProvider for BOB_LLM_PROVIDER.
+func llmProviderFromEnv(model string) (LLMProvider, error) {
+	switch provider := os.Getenv("BOB_LLM_PROVIDER"); provider {
+	case "", "anthropic":
+		apiKey := os.Getenv("ANTHROPIC_API_KEY")
+		if apiKey == "" {
+			return nil, errors.New("ANTHROPIC_API_KEY must be set")
+		}
+		return NewAnthropicLLM(apiKey, model), nil
+	case "openai":
+		apiKey := os.Getenv("OPENAI_API_KEY")
+		if apiKey == "" {
+			return nil, errors.New("OPENAI_API_KEY must be set with BOB_LLM_PROVIDER=openai")
+		}
+		return NewOpenAILLM(apiKey, os.Getenv("OPENAI_BASE_URL"), model), nil
+	case "bedrock":
+		return NewBedrockLLM(context.Background(), model)
+	case "vertex":
+		region, projectID := os.Getenv("CLOUD_ML_REGION"), os.Getenv("ANTHROPIC_VERTEX_PROJECT_ID")
+		if region == "" || projectID == "" {
+			return nil, errors.New("CLOUD_ML_REGION and ANTHROPIC_VERTEX_PROJECT_ID must be set with BOB_LLM_PROVIDER=vertex")
+		}
+		return NewVertexLLM(context.Background(), region, projectID, model)
+	default:
+		return nil, fmt.Errorf("unknown BOB_LLM_PROVIDER %q (want anthropic, openai, bedrock or vertex)", provider)
+	}
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics, served at /metrics. Job, tool and LLM metrics are derived
+// from Hub events in observeEvent, so every emitter is covered without extra
+// instrumentation at the call sites.
+var (
+	metricJobsStarted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "bob_jobs_started_total",
+		Help: "Jobs started.",
+	})
+	metricJobsFinished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_jobs_finished_total",
+		Help: "Jobs finished, by status (completed, error, cancelled).",
+	}, []string{"status"})
+	metricToolDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bob_tool_duration_seconds",
+		Help:    "Duration of orchestrator steps (clone_repo, generate_plan, implement_changes, create_pull_request).",
+		Buckets: []float64{0.5, 1, 5, 15, 30, 60, 120, 300, 600, 900},
+	}, []string{"tool", "status"})
+	metricClaudeCodeDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "bob_claude_code_run_duration_seconds",
+		Help:    "Duration of Claude Code CLI runs, by permission mode.",
+		Buckets: []float64{5, 15, 30, 60, 120, 300, 600, 900},
+	}, []string{"mode", "status"})
+	metricLLMTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_llm_tokens_total",
+		Help: "LLM tokens, by kind (input, output, cache_read, cache_write).",
+	}, []string{"kind"})
+	metricLLMCost = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "bob_llm_cost_usd_total",
+		Help: "Estimated LLM cost in USD.",
+	})
+	metricSSEClients = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "bob_sse_clients",
+		Help: "Connected SSE clients.",
+	})
+	metricAuditEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_audit_events_total",
+		Help: "Audit log entries, by action (e.g. access_denied).",
+	}, []string{"action"})
+	metricWorkspaceBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "bob_workspace_bytes",
+		Help: "Disk used by the workspace (clones, worktrees, mirrors and data), as last measured.",
+	})
+	metricEventsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bob_events_dropped_total",
+		Help: "Events dropped, by where (broadcast: hub queue full; sse_client: slow client).",
+	}, []string{"where"})
+)
+
+// observeEvent updates metrics from a Hub event.
+func observeEvent(data EventData) {
+	switch d := data.(type) {
+	case JobStartedData:
+		metricJobsStarted.Inc()
+	case JobCompletedData:
+		metricJobsFinished.WithLabelValues("completed").Inc()
+	case JobErrorData:
+		metricJobsFinished.WithLabelValues("error").Inc()
+	case JobCancelledData:
+		metricJobsFinished.WithLabelValues("cancelled").Inc()
+	case JobExpiredData:
+		metricJobsFinished.WithLabelValues("expired").Inc()
+	case ToolCompletedData:
+		status := "ok"
+		if d.IsError {
+			status = "error"
+		}
+		if d.DurationMs >= 0 {
+			metricToolDuration.WithLabelValues(d.ToolName, status).Observe(float64(d.DurationMs) / 1000)
+		}
+	case LLMResponseData:
+		// Counters must not decrease, so negative values are dropped.
+		for kind, n := range map[string]int64{
+			"input": d.InputTokens, "output": d.OutputTokens,
+			"cache_read": d.CacheReadTokens, "cache_write": d.CacheWriteTokens,
+		} {
+			if n > 0 {
+				metricLLMTokens.WithLabelValues(kind).Add(float64(n))
+			}
+		}
+		if d.CostUSD > 0 {
+			metricLLMCost.Add(d.CostUSD)
+		}
+	}
+}
+
+// observeClaudeCodeRun records the duration of one Claude Code CLI run.
+func observeClaudeCodeRun(mode string, d time.Duration, err error) {
+	if mode == "" {
+		mode = "default"
+	}
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	metricClaudeCodeDuration.WithLabelValues(mode, status).Observe(d.Seconds())
+}
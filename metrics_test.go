@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveEvent(t *testing.T) {
+	started := testutil.ToFloat64(metricJobsStarted)
+	errored := testutil.ToFloat64(metricJobsFinished.WithLabelValues("error"))
+	input := testutil.ToFloat64(metricLLMTokens.WithLabelValues("input"))
+	cost := testutil.ToFloat64(metricLLMCost)
+
+	observeEvent(JobStartedData{Task: "x"})
+	observeEvent(JobErrorData{Error: "boom"})
+	observeEvent(LLMResponseData{InputTokens: 100, OutputTokens: 5, CostUSD: 0.25})
+	observeEvent(LLMResponseData{InputTokens: 10})
+	// Negative values never decrease counters.
+	observeEvent(LLMResponseData{InputTokens: -50, CostUSD: -1})
+
+	if got := testutil.ToFloat64(metricJobsStarted) - started; got != 1 {
+		t.Errorf("jobs started delta = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metricJobsFinished.WithLabelValues("error")) - errored; got != 1 {
+		t.Errorf("jobs errored delta = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(metricLLMTokens.WithLabelValues("input")) - input; got != 110 {
+		t.Errorf("input tokens delta = %v, want 110", got)
+	}
+	if got := testutil.ToFloat64(metricLLMCost) - cost; got != 0.25 {
+		t.Errorf("cost delta = %v, want 0.25", got)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+// mirrorDir is the workspace directory holding a bare mirror of every repo Bob
+// has cloned. New base clones borrow objects from the mirror instead of
+// downloading the whole repo again.
+const mirrorDir = ".cache"
+
+// mirrorRefspecs are the refs kept in a mirror. Branches and tags only: a full
+// `git clone --mirror` would also pull every refs/pull/* on GitHub.
+var mirrorRefspecs = []string{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"}
+
+// mirrorLocks serializes fetches into the same mirror.
+var mirrorLocks sync.Map // mirror dir → *sync.Mutex
+
+// mirrorPath returns where the mirror of repoName lives.
+func mirrorPath(repoName string) string {
+	return workspacePath(mirrorDir, repoDirName(repoName)+".git")
+}
+
+// ensureMirror creates the bare mirror of repoName at dir, or fetches it up to
+// date if it exists, and returns dir. A new mirror is built next to dir and
+// renamed into place, so an interrupted first fetch never leaves a partial
+// mirror behind. The fetch URL is never stored in the mirror's config.
+func ensureMirror(ctx context.Context, dir string, vcs VCSProvider, repoName string) (string, error) {
+	v, _ := mirrorLocks.LoadOrStore(dir, &sync.Mutex{})
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	defer mu.Unlock()
+
+	target := dir
+	_, statErr := os.Stat(dir)
+	if os.IsNotExist(statErr) {
+		target = dir + ".tmp"
+		if err := os.RemoveAll(target); err != nil {
+			return "", fmt.Errorf("clear partial mirror: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+			return "", fmt.Errorf("create mirror dir: %w", err)
+		}
+		init := exec.CommandContext(ctx, "git", "init", "--bare", "--quiet", target)
+		if out, err := init.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("init mirror failed: %s: %w", out, err)
+		}
+	}
+
+	args := append([]string{"fetch", "--prune", "--quiet", vcs.FetchURL(repoName)}, mirrorRefspecs...)
+	fetch := exec.CommandContext(ctx, "git", args...)
+	fetch.Dir = target
+	if out, err := fetch.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("fetch mirror failed: %s: %w", sanitizeGitOutput(out, vcs.Token()), err)
+	}
+
+	if target != dir {
+		if err := os.Rename(target, dir); err != nil {
+			return "", fmt.Errorf("install mirror: %w", err)
+		}
+	}
+	return dir, nil
+}
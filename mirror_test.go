@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// localVCS serves repos from bare repositories in a directory.
+type localVCS struct{ dir string }
+
+func (v localVCS) Name() string { return "local" }
+func (v localVCS) FindRepo(context.Context, string) (repo, error) {
+	return repo{}, nil
+}
+func (v localVCS) FetchURL(name string) string { return filepath.Join(v.dir, name+".git") }
+func (v localVCS) CleanURL(name string) string { return v.FetchURL(name) }
+func (v localVCS) Token() string               { return "" }
+func (v localVCS) OpenPullRequest(context.Context, string, string, string, string, string, bool) (string, error) {
+	return "", nil
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s: %v", args, out, err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commitToRemote commits a file in work and pushes it to the remote's main.
+func commitToRemote(t *testing.T, work, file string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(work, file), []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, work, "add", file)
+	runGit(t, work, "commit", "-qm", file)
+	runGit(t, work, "push", "-q", "origin", "HEAD:main")
+	return runGit(t, work, "rev-parse", "HEAD")
+}
+
+func TestEnsureMirror(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	vcs := localVCS{dir: filepath.Join(root, "remote")}
+	remote := vcs.FetchURL("app")
+	runGit(t, root, "init", "-q", "--bare", "-b", "main", remote)
+	work := filepath.Join(root, "work")
+	runGit(t, root, "clone", "-q", remote, work)
+	first := commitToRemote(t, work, "a.txt")
+
+	mirror := filepath.Join(root, "cache", "app.git")
+	got, err := ensureMirror(context.Background(), mirror, vcs, "app")
+	if err != nil {
+		t.Fatalf("ensureMirror: %v", err)
+	}
+	if got != mirror {
+		t.Errorf("dir = %q, want %q", got, mirror)
+	}
+	if head := runGit(t, mirror, "rev-parse", "refs/heads/main"); head != first {
+		t.Errorf("mirror main = %s, want %s", head, first)
+	}
+	if _, err := os.Stat(mirror + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary mirror left behind")
+	}
+
+	// A second call fetches new commits into the existing mirror.
+	second := commitToRemote(t, work, "b.txt")
+	if _, err := ensureMirror(context.Background(), mirror, vcs, "app"); err != nil {
+		t.Fatalf("ensureMirror update: %v", err)
+	}
+	if head := runGit(t, mirror, "rev-parse", "refs/heads/main"); head != second {
+		t.Errorf("mirror main after update = %s, want %s", head, second)
+	}
+
+	// Clones made with --reference/--dissociate don't depend on the mirror.
+	clone := filepath.Join(root, "clone")
+	runGit(t, root, "clone", "-q", "--reference", mirror, "--dissociate", remote, clone)
+	if err := os.RemoveAll(mirror); err != nil {
+		t.Fatal(err)
+	}
+	if head := runGit(t, clone, "rev-parse", "HEAD"); head != second {
+		t.Errorf("clone HEAD = %s, want %s", head, second)
+	}
+	runGit(t, clone, "fsck", "--no-dangling")
+}
+
+func TestEnsureMirror_FetchFailureLeavesNoMirror(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	mirror := filepath.Join(root, "cache", "missing.git")
+	if _, err := ensureMirror(context.Background(), mirror, localVCS{dir: root}, "missing"); err == nil {
+		t.Fatal("ensureMirror succeeded for a missing remote")
+	}
+	if _, err := os.Stat(mirror); !os.IsNotExist(err) {
+		t.Errorf("mirror dir exists after a failed first fetch")
+	}
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -28,30 +30,68 @@ const (
 	EventJobStarted        EventType = "job_started"
 	EventLLMCall           EventType = "llm_call"
 	EventLLMResponse       EventType = "llm_response"
+	EventLLMRetry          EventType = "llm_retry"
 	EventToolStarted       EventType = "tool_started"
 	EventClaudeCodeLine    EventType = "claude_code_line"
+	EventClaudeCodeLines   EventType = "claude_code_lines" // coalesced claude_code_line events
+	EventAgentStarted      EventType = "agent_started"     // a Claude Code sub-agent (Task tool) started
+	EventAgentFinished     EventType = "agent_finished"
 	EventToolCompleted     EventType = "tool_completed"
 	EventSlackNotification EventType = "slack_notification"
 	EventPlanGenerated     EventType = "plan_generated"
 	EventPlanApproved      EventType = "plan_approved"
 	EventPlanSuperseded    EventType = "plan_superseded"
+	EventDiffReady         EventType = "diff_ready"
+	EventPushApproved      EventType = "push_approved"
 	EventPhaseChanged      EventType = "phase_changed"
 	EventJobCompleted      EventType = "job_completed"
 	EventJobError          EventType = "job_error"
+	EventJobCancelled      EventType = "job_cancelled"
+	EventJobReminded       EventType = "job_reminded" // a job waiting on the user was nudged in its thread
+	EventJobExpired        EventType = "job_expired"  // a job waiting on the user was closed for lack of a reply
+	EventJobMetadata       EventType = "job_metadata" // model and CLI versions reported by a Claude Code session
+	EventWorkPreserved     EventType = "work_preserved"
+	EventChecksCompleted   EventType = "checks_completed" // CI outcome of the job's PR, possibly after the job closed
+	EventSecurityScan      EventType = "security_scan"    // security scanner results before the PR is opened
+	EventWorkspaceUsage    EventType = "workspace_usage"  // disk used by the job's worktree
+	EventSecretScan        EventType = "secret_scan"      // credentials found (or not) in the changes before commit
+	EventJobRerun          EventType = "job_rerun"        // a finished job's request was started again as a new job
+	EventPromptInjection   EventType = "prompt_injection" // planning read text that looks like instructions to the agent
 )
 
 // Event is a single monitoring event.
 type Event struct {
-	ID        string         `json:"id"`
-	JobID     string         `json:"job_id"`
-	Type      EventType      `json:"type"`
-	Timestamp time.Time      `json:"timestamp"`
-	Data      map[string]any `json:"data"`
+	ID            string    `json:"id"`
+	JobID         string    `json:"job_id"`
+	Type          EventType `json:"type"`
+	Timestamp     time.Time `json:"timestamp"`
+	SchemaVersion int       `json:"schema_version"`
+	Data          EventData `json:"data"` // one of the *Data structs in events.go, matching Type
 }
 
+// sseClient is a live event stream, over SSE or a WebSocket.
 type sseClient struct {
-	jobID string // empty = receive all events
-	send  chan []byte
+	jobID   string        // empty = receive all events
+	summary bool          // leave out detailEvents
+	filter  *streamFilter // WebSocket subscriptions; replaces jobID and summary when set
+	send    chan sseMessage
+}
+
+// wants reports whether the client should receive e.
+func (c *sseClient) wants(e Event) bool {
+	if c.filter != nil {
+		return c.filter.wants(e)
+	}
+	if c.summary && detailEvents[e.Type] {
+		return false
+	}
+	return c.jobID == "" || c.jobID == e.JobID
+}
+
+// sseMessage is a marshaled event queued for an SSE client.
+type sseMessage struct {
+	id   string
+	data []byte
 }
 
 // JobPhase tracks where a job is in its lifecycle.
@@ -62,6 +102,9 @@ const (
 	PhaseAwaitingQuestion JobPhase = "awaiting_question"
 	PhaseAwaitingApproval JobPhase = "awaiting_approval"
 	PhaseImplementing     JobPhase = "implementing"
+	PhaseReviewing        JobPhase = "reviewing"     // read-only PR review, no approval step
+	PhaseExplaining       JobPhase = "explaining"    // read-only answer to a question about the code
+	PhaseAwaitingPush     JobPhase = "awaiting_push" // dry run: diff posted, waiting for "push it"
 	PhaseDone             JobPhase = "done"
 )
 
@@ -76,9 +119,52 @@ type JobState struct {
 	PlanContent  string // cached plan text (read from disk after planning completes)
 	Channel      string
 	ThreadTS     string
+	Platform     string // chat platform the job was requested from ("slack", "discord")
+	RequestedBy  string // chat user ID of the requester, addressed when the job is done; empty for webhook-started jobs
 	PlanMsgTS    string
-	RepoDir      string // worktree path (/workspace/<repo>/worktrees/<jobID>)
-	BaseDir      string // base clone path (/workspace/<repo>)
+	RepoDir      string       // worktree path (<workspace>/<repo>/worktrees/<jobID>)
+	BaseDir      string       // base clone path (<workspace>/<repo>)
+	BaseBranch   string       // branch the job starts from and opens its PR against
+	PRURL        string       // existing PR a follow-up job updates; empty opens a new PR
+	PRBranch     string       // head branch of PRURL
+	PRHints      PROptions    // how the user asked for a new PR to be opened (draft, reviewers, ...)
+	CoAuthor     *gitIdentity // requesting user credited on the job's commits; nil for none
+	DryRun       bool         // hold the implemented diff for a "push it" instead of opening the PR
+	Changelog    bool         // add a changelog entry, even if the repo's .bob.yml doesn't ask for one
+	PRFooter     string       // appended to the PR body (e.g. a link to the Sentry issue)
+	Ticket       string       // Jira issue key the job works on, if any
+	Paths        []string     // paths the requester limited the job to, narrowing the repo's paths
+
+	// Suspected prompt injection in the request or what planning read; the
+	// plan warns about it and is never approved automatically.
+	Injections []InjectionFinding
+
+	// Enough of the planning session to start it over, without cloning or
+	// parsing the request again, if it can't be resumed after a question.
+	PlanPrompt     string          // the planning session's first prompt
+	Clarifications []Clarification // questions asked while planning, and the answers
+
+	vcs        VCSProvider        // provider that hosts Repo
+	vcsName    string             // provider name of a job restored from disk, until vcs is re-attached
+	repoConfig *RepoConfig        // the repo's .bob.yml; nil until loaded (see Orchestrator.repoConfig)
+	cancel     context.CancelFunc // cancels the in-flight step (session, clone, PR); nil when idle
+	cancelled  bool               // set by CancelJob
+	closed     bool               // terminal event emitted and worktree removed
+	costUSD    float64            // running total of llm_response costs
+	pending    *pendingPush       // implemented dry-run changes awaiting a push; nil otherwise
+}
+
+// ThreadContext returns ctx carrying the job's chat thread and platform, so
+// notifications about the job reach the conversation it came from.
+func (s *JobState) ThreadContext(ctx context.Context) context.Context {
+	s.mu.Lock()
+	channel, threadTS, platform := s.Channel, s.ThreadTS, s.Platform
+	s.mu.Unlock()
+	ctx = WithSlackThread(ctx, channel, threadTS)
+	if platform != "" {
+		ctx = WithPlatform(ctx, platform)
+	}
+	return ctx
 }
 
 // Hub manages SSE clients, persists events to JSONL files, and fans out events.
@@ -89,57 +175,127 @@ type Hub struct {
 	broadcast     chan Event
 	seq           uint64
 	dataDir       string
-	jobFiles      map[string]*os.File
+	auditMu       sync.Mutex // serializes audit log appends
+	store         EventStore // owned by the run goroutine for writes
 
-	threadMu   sync.Mutex
-	threadJobs map[string]string // "channel:threadTS" → jobID
+	threadMu         sync.Mutex
+	threadJobs       map[string]string          // "channel:threadTS" → jobID, persisted in thread-jobs.json
+	lastThreadJobs   map[string]string          // "channel:threadTS" → the thread's last finished job, for "retry"; in memory only
+	threadPRs        map[string]threadPR        // "channel:threadTS" → last PR, persisted in thread-prs.json
+	threadVerbosity  map[string]threadVerbosity // "channel:threadTS" → verbosity other than normal, persisted in thread-verbosity.json
+	threadJobsSaveMu sync.Mutex
 
-	jobStates   sync.Map // jobID → *JobState
-	threadLocks sync.Map // "channel:threadTS" → *sync.Mutex
+	jobStates      sync.Map // jobID → *JobState
+	threadLocks    sync.Map // "channel:threadTS" → *sync.Mutex
+	pendingIntents sync.Map // "channel:threadTS" → pendingIntent awaiting confirmation
 
 	channelReposMu sync.RWMutex
 	channelRepos   map[string]string // channelID → repo name
+
+	progress  atomic.Pointer[progressReporter]  // live progress messages; nil disables
+	webhooks  atomic.Pointer[webhookDispatcher] // outbound lifecycle webhooks; nil disables
+	limits    atomic.Pointer[userLimits]        // per-user quotas, charged with job costs; nil disables
+	coalescer atomic.Pointer[lineCoalescer]     // batches Claude Code lines; nil sends each line
+
+	activityMu sync.Mutex
+	activity   map[string]*jobActivity // jobID → what an open job is doing, for /api/active
 }
 
-// NewHub creates a Hub that persists events under dataDir and starts the run goroutine.
+// NewHub creates a Hub that persists events as JSONL files under dataDir and
+// starts the run goroutine.
 func NewHub(dataDir string) *Hub {
+	return NewHubWithStore(dataDir, newJSONLStore(dataDir))
+}
+
+// NewHubWithStore creates a Hub that persists events to store. dataDir still
+// holds the Hub's own small state files (e.g. channel repos).
+func NewHubWithStore(dataDir string, store EventStore) *Hub {
 	if err := os.MkdirAll(dataDir, 0o755); err != nil {
-		log.Printf("hub: failed to create data dir %s: %v", dataDir, err)
+		slog.Error("hub: failed to create data dir", "dir", dataDir, "err", err)
 	}
 	h := &Hub{
-		clients:       make(map[*sseClient]struct{}),
-		maxSSEClients: 50,
-		broadcast:     make(chan Event, 4096),
-		dataDir:       dataDir,
-		jobFiles:      make(map[string]*os.File),
-		threadJobs:    make(map[string]string),
-		channelRepos:  make(map[string]string),
+		clients:         make(map[*sseClient]struct{}),
+		maxSSEClients:   50,
+		broadcast:       make(chan Event, 4096),
+		dataDir:         dataDir,
+		store:           store,
+		threadJobs:      make(map[string]string),
+		lastThreadJobs:  make(map[string]string),
+		threadPRs:       make(map[string]threadPR),
+		threadVerbosity: make(map[string]threadVerbosity),
+		channelRepos:    make(map[string]string),
+		activity:        make(map[string]*jobActivity),
 	}
 	h.loadChannelRepos()
+	h.loadThreadJobs()
+	h.loadThreadPRs()
+	h.loadThreadVerbosity()
+	h.moveLegacyAuditLog()
 	go h.run()
 	return h
 }
 
-// Emit enqueues an event for the given job. No-ops if jobID is empty or hub is nil.
-func (h *Hub) Emit(jobID string, t EventType, data map[string]any) {
+// Emit enqueues an event for the given job; its type is data's. No-ops if
+// jobID is empty or hub is nil.
+func (h *Hub) Emit(jobID string, data EventData) {
 	if h == nil || jobID == "" {
 		return
 	}
+	t := data.EventType()
 	id := atomic.AddUint64(&h.seq, 1)
 	e := Event{
-		ID:        fmt.Sprintf("%d", id),
-		JobID:     jobID,
-		Type:      t,
-		Timestamp: time.Now(),
-		Data:      data,
+		ID:            fmt.Sprintf("%d", id),
+		JobID:         jobID,
+		Type:          t,
+		Timestamp:     time.Now(),
+		SchemaVersion: eventSchemaVersion,
+		Data:          data,
+	}
+	observeEvent(data)
+	h.trackActivity(e)
+	h.progress.Load().observe(e)
+	h.webhooks.Load().observe(e)
+	h.limits.Load().observe(e)
+	if d, ok := data.(LLMResponseData); ok {
+		h.addJobCost(jobID, d.CostUSD)
+	}
+	if c := h.coalescer.Load(); c != nil {
+		c.emit(e)
+		return
 	}
+	h.enqueue(e)
+}
+
+// enqueue hands e to the run goroutine for persisting and fan-out.
+func (h *Hub) enqueue(e Event) {
 	select {
 	case h.broadcast <- e:
 	default:
-		log.Printf("hub: broadcast channel full, dropping %s for job %s", t, jobID)
+		metricEventsDropped.WithLabelValues("broadcast").Inc()
+		slog.Warn("hub: broadcast channel full, dropping event", "event", e.Type, "job_id", e.JobID)
+	}
+}
+
+// addJobCost adds to the job's running cost total.
+func (h *Hub) addJobCost(jobID string, cost float64) {
+	if state, ok := h.GetJobState(jobID); ok {
+		state.mu.Lock()
+		state.costUSD += cost
+		state.mu.Unlock()
 	}
 }
 
+// JobCost returns the job's LLM cost so far in USD (intent parsing plus Claude Code runs).
+func (h *Hub) JobCost(jobID string) float64 {
+	state, ok := h.GetJobState(jobID)
+	if !ok {
+		return 0
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.costUSD
+}
+
 // ActiveJobForThread returns the active job ID for a Slack thread, or empty string.
 func (h *Hub) ActiveJobForThread(channel, threadTS string) string {
 	if h == nil {
@@ -156,8 +312,9 @@ func (h *Hub) RegisterThreadJob(channel, threadTS, jobID string) {
 		return
 	}
 	h.threadMu.Lock()
-	defer h.threadMu.Unlock()
 	h.threadJobs[channel+":"+threadTS] = jobID
+	h.threadMu.Unlock()
+	h.saveThreadJobs()
 }
 
 // UnregisterThreadJob removes the thread→job mapping when a job closes.
@@ -166,8 +323,23 @@ func (h *Hub) UnregisterThreadJob(channel, threadTS string) {
 		return
 	}
 	h.threadMu.Lock()
-	defer h.threadMu.Unlock()
+	if jobID, ok := h.threadJobs[channel+":"+threadTS]; ok {
+		h.lastThreadJobs[channel+":"+threadTS] = jobID
+	}
 	delete(h.threadJobs, channel+":"+threadTS)
+	h.threadMu.Unlock()
+	h.saveThreadJobs()
+}
+
+// LastThreadJob returns the job that last finished in a thread since Bob
+// started, or empty string.
+func (h *Hub) LastThreadJob(channel, threadTS string) string {
+	if h == nil {
+		return ""
+	}
+	h.threadMu.Lock()
+	defer h.threadMu.Unlock()
+	return h.lastThreadJobs[channel+":"+threadTS]
 }
 
 // LockThread acquires a per-thread mutex, serializing handleMention calls for the same thread.
@@ -205,6 +377,18 @@ func (h *Hub) GetJobState(jobID string) (*JobState, bool) {
 	return v.(*JobState), true
 }
 
+// JobRequester returns the chat user ID of the user who requested the job, or
+// empty string if it isn't known.
+func (h *Hub) JobRequester(jobID string) string {
+	state, ok := h.GetJobState(jobID)
+	if !ok {
+		return ""
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.RequestedBy
+}
+
 // SetPhase updates a job's phase and emits a phase_changed event.
 func (h *Hub) SetPhase(jobID string, phase JobPhase) {
 	if h == nil {
@@ -215,9 +399,13 @@ func (h *Hub) SetPhase(jobID string, phase JobPhase) {
 		return
 	}
 	state.mu.Lock()
-	defer state.mu.Unlock()
 	state.Phase = phase
-	h.Emit(jobID, EventPhaseChanged, map[string]any{"phase": string(phase)})
+	h.Emit(jobID, PhaseChangedData{Phase: string(phase)})
+	channel := state.Channel
+	state.mu.Unlock()
+	if channel != "" {
+		h.saveThreadJobs()
+	}
 }
 
 // TryStartImplementation atomically transitions a job from awaiting_approval to implementing.
@@ -237,7 +425,27 @@ func (h *Hub) TryStartImplementation(jobID string) bool {
 		return false
 	}
 	state.Phase = PhaseImplementing
-	h.Emit(jobID, EventPhaseChanged, map[string]any{"phase": string(PhaseImplementing)})
+	h.Emit(jobID, PhaseChangedData{Phase: string(PhaseImplementing)})
+	return true
+}
+
+// TryStartPush atomically transitions a dry-run job from awaiting_push to
+// implementing. Returns true if this call won the race.
+func (h *Hub) TryStartPush(jobID string) bool {
+	if h == nil {
+		return false
+	}
+	state, ok := h.GetJobState(jobID)
+	if !ok {
+		return false
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.Phase != PhaseAwaitingPush || state.pending == nil {
+		return false
+	}
+	state.Phase = PhaseImplementing
+	h.Emit(jobID, PhaseChangedData{Phase: string(PhaseImplementing)})
 	return true
 }
 
@@ -254,10 +462,45 @@ func (h *Hub) ClearImplementation(jobID string) {
 	defer state.mu.Unlock()
 	if state.Phase == PhaseImplementing {
 		state.Phase = PhaseAwaitingApproval
-		h.Emit(jobID, EventPhaseChanged, map[string]any{"phase": string(PhaseAwaitingApproval)})
+		h.Emit(jobID, PhaseChangedData{Phase: string(PhaseAwaitingApproval)})
 	}
 }
 
+// JobCounts returns how many open jobs are working (planning, implementing,
+// reviewing or explaining) and how many are waiting on a reply (a question, approval or push).
+func (h *Hub) JobCounts() (running, waiting int) {
+	if h == nil {
+		return 0, 0
+	}
+	h.jobStates.Range(func(_, v any) bool {
+		s := v.(*JobState)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.closed {
+			return true
+		}
+		switch s.Phase {
+		case PhasePlanning, PhaseImplementing, PhaseReviewing, PhaseExplaining:
+			running++
+		case PhaseAwaitingQuestion, PhaseAwaitingApproval, PhaseAwaitingPush:
+			waiting++
+		}
+		return true
+	})
+	return running, waiting
+}
+
+// IsCancelled reports whether a job was cancelled.
+func (h *Hub) IsCancelled(jobID string) bool {
+	state, ok := h.GetJobState(jobID)
+	if !ok {
+		return false
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return state.cancelled
+}
+
 // SetChannelRepo sets the default repo for a Slack channel and persists to disk.
 func (h *Hub) SetChannelRepo(channel, repo string) {
 	h.channelReposMu.Lock()
@@ -288,17 +531,17 @@ func (h *Hub) loadChannelRepos() {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if !os.IsNotExist(err) {
-			log.Printf("hub: failed to load channel repos: %v", err)
+			slog.Error("hub: failed to load channel repos", "err", err)
 		}
 		return
 	}
 	var m map[string]string
 	if err := json.Unmarshal(data, &m); err != nil {
-		log.Printf("hub: failed to parse channel repos: %v", err)
+		slog.Error("hub: failed to parse channel repos", "err", err)
 		return
 	}
 	h.channelRepos = m
-	log.Printf("hub: loaded %d channel-repo mappings", len(m))
+	slog.Info("hub: loaded channel-repo mappings", "mappings", len(m))
 }
 
 func (h *Hub) saveChannelRepos() {
@@ -306,44 +549,41 @@ func (h *Hub) saveChannelRepos() {
 	data, err := json.Marshal(h.channelRepos)
 	h.channelReposMu.RUnlock()
 	if err != nil {
-		log.Printf("hub: failed to marshal channel repos: %v", err)
+		slog.Error("hub: failed to marshal channel repos", "err", err)
 		return
 	}
 	path := filepath.Join(h.dataDir, channelReposFile)
 	tmp := path + ".tmp"
 	if err := os.WriteFile(tmp, data, 0o644); err != nil {
-		log.Printf("hub: failed to write channel repos: %v", err)
+		slog.Error("hub: failed to write channel repos", "err", err)
 		return
 	}
 	if err := os.Rename(tmp, path); err != nil {
-		log.Printf("hub: failed to rename channel repos: %v", err)
+		slog.Error("hub: failed to rename channel repos", "err", err)
 	}
 }
 
-// run processes the broadcast channel — single goroutine owns jobFiles.
+// run processes the broadcast channel — single goroutine owns store writes.
 func (h *Hub) run() {
 	for e := range h.broadcast {
-		// Persist to JSONL file.
-		if f, err := h.openJobFile(e.JobID); err != nil {
-			log.Printf("hub: open file for job %s: %v", e.JobID, err)
-		} else {
-			line, _ := json.Marshal(e)
-			f.Write(append(line, '\n'))
+		if err := h.store.Append(e); err != nil {
+			slog.Error("hub: failed to persist event", "job_id", e.JobID, "err", err)
 		}
 
 		// Marshal once, fan out to matching clients.
 		data, err := json.Marshal(e)
 		if err != nil {
-			log.Printf("hub: marshal event: %v", err)
+			slog.Error("hub: failed to marshal event", "job_id", e.JobID, "err", err)
 			continue
 		}
 		h.mu.RLock()
 		for c := range h.clients {
-			if c.jobID == "" || c.jobID == e.JobID {
+			if c.wants(e) {
 				select {
-				case c.send <- data:
+				case c.send <- sseMessage{id: e.ID, data: data}:
 				default:
 					// Client too slow, drop.
+					metricEventsDropped.WithLabelValues("sse_client").Inc()
 				}
 			}
 		}
@@ -351,19 +591,6 @@ func (h *Hub) run() {
 	}
 }
 
-func (h *Hub) openJobFile(jobID string) (*os.File, error) {
-	if f, ok := h.jobFiles[jobID]; ok {
-		return f, nil
-	}
-	path := filepath.Join(h.dataDir, jobID+".jsonl")
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
-	if err != nil {
-		return nil, err
-	}
-	h.jobFiles[jobID] = f
-	return f, nil
-}
-
 func (h *Hub) add(c *sseClient) bool {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -371,6 +598,7 @@ func (h *Hub) add(c *sseClient) bool {
 		return false
 	}
 	h.clients[c] = struct{}{}
+	metricSSEClients.Inc()
 	return true
 }
 
@@ -379,14 +607,22 @@ func (h *Hub) remove(c *sseClient) {
 	if _, ok := h.clients[c]; ok {
 		delete(h.clients, c)
 		close(c.send)
+		metricSSEClients.Dec()
 	}
 	h.mu.Unlock()
 }
 
+// SSE liveness: an idle stream gets a comment every sseKeepaliveInterval so
+// proxies and load balancers don't close it, and a write that doesn't finish
+// within sseWriteTimeout drops the client, releasing its buffer.
+var (
+	sseKeepaliveInterval = 20 * time.Second
+	sseWriteTimeout      = 10 * time.Second
+)
+
 // ServeSSE handles GET /events?job={id} — streams live events to the browser.
 func (h *Hub) ServeSSE(w http.ResponseWriter, r *http.Request) {
-	flusher, ok := w.(http.Flusher)
-	if !ok {
+	if _, ok := w.(http.Flusher); !ok {
 		http.Error(w, "streaming not supported", http.StatusInternalServerError)
 		return
 	}
@@ -395,8 +631,9 @@ func (h *Hub) ServeSSE(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Connection", "keep-alive")
 
 	c := &sseClient{
-		jobID: r.URL.Query().Get("job"),
-		send:  make(chan []byte, 64),
+		jobID:   r.URL.Query().Get("job"),
+		summary: r.URL.Query().Get("verbosity") == verbositySummary,
+		send:    make(chan sseMessage, 64),
 	}
 	if !h.add(c) {
 		http.Error(w, "too many connections", http.StatusServiceUnavailable)
@@ -404,152 +641,189 @@ func (h *Hub) ServeSSE(w http.ResponseWriter, r *http.Request) {
 	}
 	defer h.remove(c)
 
+	rc := http.NewResponseController(w)
+	// flush sends what's been written, failing once the client is gone or has
+	// stopped reading.
+	flush := func() error {
+		if err := rc.Flush(); err != nil {
+			slog.Debug("hub: SSE client gone", "job_id", c.jobID, "err", err)
+			return err
+		}
+		return nil
+	}
+	// deadline bounds the next write, if the server supports it.
+	deadline := func() error {
+		err := rc.SetWriteDeadline(time.Now().Add(sseWriteTimeout))
+		if errors.Is(err, http.ErrNotSupported) {
+			return nil
+		}
+		return err
+	}
+
+	// Resuming a job stream replays persisted events after the given ID. The
+	// client is registered first so nothing emitted during the replay is lost;
+	// live copies of replayed events are skipped.
+	after := r.Header.Get("Last-Event-ID")
+	if q := r.URL.Query().Get("after"); q != "" {
+		after = q
+	}
+	var replayed map[string]bool
+	if after != "" && c.jobID != "" {
+		events, err := h.store.Events(c.jobID)
+		if err != nil && !errors.Is(err, ErrJobNotFound) {
+			slog.Error("hub: failed to replay events", "job_id", c.jobID, "err", err)
+		}
+		replayed = make(map[string]bool)
+		for _, e := range eventsAfter(events, after) {
+			if !c.wants(e) {
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			if deadline() != nil || writeSSE(w, sseMessage{id: e.ID, data: data}) != nil {
+				return
+			}
+			replayed[e.ID] = true
+		}
+	}
+	// Send the headers (and any replay) now, so proxies see a live response.
+	if deadline() != nil || flush() != nil {
+		return
+	}
+
+	keepalive := time.NewTicker(sseKeepaliveInterval)
+	defer keepalive.Stop()
 	for {
 		select {
 		case msg, ok := <-c.send:
 			if !ok {
 				return
 			}
-			fmt.Fprintf(w, "data: %s\n\n", msg)
-			flusher.Flush()
+			if replayed[msg.id] {
+				continue
+			}
+			if deadline() != nil || writeSSE(w, msg) != nil || flush() != nil {
+				return
+			}
+			keepalive.Reset(sseKeepaliveInterval)
+		case <-keepalive.C:
+			if deadline() != nil {
+				return
+			}
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil || flush() != nil {
+				return
+			}
 		case <-r.Context().Done():
 			return
 		}
 	}
 }
 
-// ServeJobAPI handles GET /api/jobs/{id} — returns the full event history as JSON.
+// writeSSE writes one SSE message. The id lets EventSource send Last-Event-ID
+// when it reconnects.
+func writeSSE(w io.Writer, msg sseMessage) error {
+	_, err := fmt.Fprintf(w, "id: %s\ndata: %s\n\n", msg.id, msg.data)
+	return err
+}
+
+// eventsAfter returns the events following the one with the given ID. Event
+// IDs restart with the process, so the last match wins; an unknown ID replays
+// the full history.
+func eventsAfter(events []Event, id string) []Event {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].ID == id {
+			return events[i+1:]
+		}
+	}
+	return events
+}
+
+// ServeJobAPI handles GET /api/jobs/{id} — returns the full event history as JSON —
+// the job's timeline under /api/jobs/{id}/timeline, and its artifacts under
+// /api/jobs/{id}/artifacts.
 func (h *Hub) ServeJobAPI(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
 	if id == "" {
 		http.Error(w, "missing job id", http.StatusBadRequest)
 		return
 	}
+	if jobID, rest, ok := strings.Cut(id, "/artifacts"); ok && (rest == "" || strings.HasPrefix(rest, "/")) {
+		h.serveArtifacts(w, r, jobID, strings.TrimPrefix(rest, "/"))
+		return
+	}
+	jobID, timeline := strings.CutSuffix(id, "/timeline")
 
-	path := filepath.Join(h.dataDir, id+".jsonl")
-	f, err := os.Open(path)
+	events, err := h.store.Events(jobID)
+	if archiver, ok := h.store.(jobArchiver); ok && errors.Is(err, ErrJobNotFound) {
+		events, err = archiver.ArchivedEvents(jobID)
+	}
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, ErrJobNotFound) {
 			http.Error(w, "job not found", http.StatusNotFound)
 		} else {
 			http.Error(w, "internal error", http.StatusInternalServerError)
 		}
 		return
 	}
-	defer f.Close()
-
-	var events []Event
-	scanner := bufio.NewScanner(f)
-	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
-	for scanner.Scan() {
-		var e Event
-		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
-			continue
-		}
-		events = append(events, e)
-	}
-	if events == nil {
-		events = []Event{}
-	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if timeline {
+		json.NewEncoder(w).Encode(buildTimeline(jobID, events, time.Now()))
+		return
+	}
 	json.NewEncoder(w).Encode(events)
 }
 
 type jobSummary struct {
 	ID        string    `json:"id"`
 	Task      string    `json:"task"`
+	Repo      string    `json:"repo,omitempty"`
 	StartedAt time.Time `json:"started_at"`
 	Status    string    `json:"status"`
 	Phase     string    `json:"phase,omitempty"`
 	CostUSD   float64   `json:"cost_usd"`
+
+	RequestedBy     string `json:"requested_by,omitempty"`      // chat user ID, from job_started
+	RequestedByName string `json:"requested_by_name,omitempty"` // display name, from job_started
+
+	// Versions used by the job, for correlating quality changes.
+	IntentModel string `json:"intent_model,omitempty"`
+	Model       string `json:"model,omitempty"`
+	CLIVersion  string `json:"cli_version,omitempty"`
 }
 
-// ServeJobList handles GET /api/jobs — returns a summary of all known jobs.
+// ServeJobList handles GET /api/jobs — returns a page of job summaries, newest
+// first, filtered by the status, repo and since parameters (see parseJobQuery).
+// With archived=true it lists archived jobs instead.
 func (h *Hub) ServeJobList(w http.ResponseWriter, r *http.Request) {
-	entries, err := os.ReadDir(h.dataDir)
+	q, err := parseJobQuery(r.URL.Query(), time.Now())
 	if err != nil {
-		if os.IsNotExist(err) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	var page jobPage
+	if q.Archived {
+		archiver, ok := h.store.(jobArchiver)
+		if !ok {
 			w.Header().Set("Content-Type", "application/json")
-			w.Write([]byte("[]"))
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "this event store doesn't archive jobs"})
 			return
 		}
+		page, err = archiver.ArchivedJobs(q)
+	} else {
+		page, err = h.store.ListJobs(q)
+	}
+	if err != nil {
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
-
-	var jobs []jobSummary
-	for _, entry := range entries {
-		if !strings.HasSuffix(entry.Name(), ".jsonl") {
-			continue
-		}
-		id := strings.TrimSuffix(entry.Name(), ".jsonl")
-		summary := jobSummary{ID: id, Status: "running"}
-
-		path := filepath.Join(h.dataDir, entry.Name())
-		f, err := os.Open(path)
-		if err != nil {
-			continue
-		}
-
-		scanner := bufio.NewScanner(f)
-		scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
-		var cost float64
-		var latestPhase string
-		first := true
-		for scanner.Scan() {
-			var e Event
-			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
-				continue
-			}
-			if first {
-				if task, ok := e.Data["task"].(string); ok {
-					summary.Task = task
-				}
-				summary.StartedAt = e.Timestamp
-				first = false
-			}
-			switch e.Type {
-			case EventLLMResponse:
-				if v, ok := e.Data["cost_usd"].(float64); ok {
-					cost += v
-				}
-			case EventPhaseChanged:
-				if v, ok := e.Data["phase"].(string); ok {
-					latestPhase = v
-				}
-			case EventJobCompleted:
-				summary.Status = "completed"
-				if v, ok := e.Data["total_cost_usd"].(float64); ok {
-					cost = v // authoritative total
-				}
-			case EventJobError:
-				summary.Status = "error"
-				if v, ok := e.Data["total_cost_usd"].(float64); ok {
-					cost = v
-				}
-			}
-		}
-		f.Close()
-		summary.CostUSD = cost
-		if summary.Status == "running" && latestPhase != "" {
-			summary.Phase = latestPhase
-		}
-		jobs = append(jobs, summary)
-	}
-
-	// Sort by started_at descending (most recent first).
-	for i := 1; i < len(jobs); i++ {
-		for j := i; j > 0 && jobs[j].StartedAt.After(jobs[j-1].StartedAt); j-- {
-			jobs[j], jobs[j-1] = jobs[j-1], jobs[j]
-		}
-	}
-
-	if jobs == nil {
-		jobs = []jobSummary{}
-	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(jobs)
+	json.NewEncoder(w).Encode(page)
 }
 
 type statsResponse struct {
@@ -557,6 +831,8 @@ type statsResponse struct {
 	CompletedJobs         int     `json:"completed_jobs"`
 	ErrorJobs             int     `json:"error_jobs"`
 	RunningJobs           int     `json:"running_jobs"`
+	CancelledJobs         int     `json:"cancelled_jobs"`
+	ExpiredJobs           int     `json:"expired_jobs"`
 	TotalCostUSD          float64 `json:"total_cost_usd"`
 	TotalInputTokens      int64   `json:"total_input_tokens"`
 	TotalOutputTokens     int64   `json:"total_output_tokens"`
@@ -566,73 +842,11 @@ type statsResponse struct {
 
 // ServeStats handles GET /api/stats — returns aggregate cost and token stats.
 func (h *Hub) ServeStats(w http.ResponseWriter, r *http.Request) {
-	entries, err := os.ReadDir(h.dataDir)
+	stats, err := h.store.Stats()
 	if err != nil {
-		if os.IsNotExist(err) {
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(statsResponse{})
-			return
-		}
 		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
-
-	var stats statsResponse
-	for _, entry := range entries {
-		if !strings.HasSuffix(entry.Name(), ".jsonl") {
-			continue
-		}
-		stats.TotalJobs++
-
-		path := filepath.Join(h.dataDir, entry.Name())
-		f, err := os.Open(path)
-		if err != nil {
-			continue
-		}
-
-		scanner := bufio.NewScanner(f)
-		scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
-		status := "running"
-		for scanner.Scan() {
-			var e Event
-			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
-				continue
-			}
-			switch e.Type {
-			case EventLLMResponse:
-				if v, ok := e.Data["input_tokens"].(float64); ok {
-					stats.TotalInputTokens += int64(v)
-				}
-				if v, ok := e.Data["output_tokens"].(float64); ok {
-					stats.TotalOutputTokens += int64(v)
-				}
-				if v, ok := e.Data["cache_read_tokens"].(float64); ok {
-					stats.TotalCacheReadTokens += int64(v)
-				}
-				if v, ok := e.Data["cache_write_tokens"].(float64); ok {
-					stats.TotalCacheWriteTokens += int64(v)
-				}
-				if v, ok := e.Data["cost_usd"].(float64); ok {
-					stats.TotalCostUSD += v
-				}
-			case EventJobCompleted:
-				status = "completed"
-			case EventJobError:
-				status = "error"
-			}
-		}
-		f.Close()
-
-		switch status {
-		case "completed":
-			stats.CompletedJobs++
-		case "error":
-			stats.ErrorJobs++
-		default:
-			stats.RunningJobs++
-		}
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(stats)
 }
@@ -642,7 +856,7 @@ func (h *Hub) ServeStats(w http.ResponseWriter, r *http.Request) {
 func serveUI() http.Handler {
 	dist, err := fs.Sub(uiFS, "ui/dist")
 	if err != nil {
-		log.Fatalf("serveUI: failed to sub ui/dist: %v", err)
+		fatal("serveUI: failed to sub ui/dist", "err", err)
 	}
 	fileServer := http.FileServer(http.FS(dist))
 
@@ -666,7 +880,6 @@ func serveUI() http.Handler {
 	})
 }
 
-
 // generateJobID returns a new UUID v4 string.
 func generateJobID() string {
 	return uuid.New().String()
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -224,3 +229,115 @@ func TestHub_ChannelRepos(t *testing.T) {
 		}
 	})
 }
+
+func TestEventsAfter(t *testing.T) {
+	events := []Event{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "1"}, {ID: "2"}}
+	tests := []struct {
+		name string
+		id   string
+		want int
+	}{
+		{"last match wins", "1", 1},
+		{"latest event", "2", 0},
+		{"earlier event", "3", 2},
+		{"unknown ID replays all", "9", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := eventsAfter(events, tt.id); len(got) != tt.want {
+				t.Errorf("eventsAfter(%q) returned %d events, want %d", tt.id, len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestHub_ServeSSE_Replay(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	hub.Emit("job-1", JobStartedData{Task: "t"})
+	hub.Emit("job-1", ToolStartedData{ToolName: "a"})
+	hub.Emit("job-1", ToolCompletedData{ToolName: "a"})
+	time.Sleep(50 * time.Millisecond) // let the hub persist
+
+	srv := httptest.NewServer(http.HandlerFunc(hub.ServeSSE))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		query  string
+		header string
+	}{
+		{"Last-Event-ID header", "", "1"},
+		{"after query", "&after=1", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?job=job-1"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Last-Event-ID", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			var ids []string
+			scanner := bufio.NewScanner(resp.Body)
+			for len(ids) < 2 && scanner.Scan() {
+				if id, ok := strings.CutPrefix(scanner.Text(), "id: "); ok {
+					ids = append(ids, id)
+				}
+			}
+			if strings.Join(ids, ",") != "2,3" {
+				t.Errorf("replayed IDs = %v, want [2 3]", ids)
+			}
+		})
+	}
+}
+
+func TestHub_ServeSSE_Keepalive(t *testing.T) {
+	drainHub(t)
+	interval := sseKeepaliveInterval
+	sseKeepaliveInterval = 10 * time.Millisecond
+	t.Cleanup(func() { sseKeepaliveInterval = interval })
+
+	hub := NewHub(t.TempDir())
+	srv := httptest.NewServer(http.HandlerFunc(hub.ServeSSE))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?job=job-1", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	found := false
+	for !found && scanner.Scan() {
+		found = scanner.Text() == ": keepalive"
+	}
+	if !found {
+		t.Fatalf("no keepalive before the stream ended: %v", scanner.Err())
+	}
+
+	// A client that goes away is dropped, releasing its buffer.
+	resp.Body.Close()
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		hub.mu.RLock()
+		n := len(hub.clients)
+		hub.mu.RUnlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d SSE clients still registered after disconnect", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"path"
 	"regexp"
 	"strings"
 )
@@ -18,8 +20,113 @@ var (
 	reBold       = regexp.MustCompile(`\*{2}(.+?)\*{2}`)
 	reItalic     = regexp.MustCompile(`\*(.+?)\*`)
 	reStrike     = regexp.MustCompile(`~~(.+?)~~`)
+	reListItem   = regexp.MustCompile(`^([ \t]*)([-*+]|\d+[.)])[ \t]+(.*)$`)
+	reTaskBox    = regexp.MustCompile(`^\[([ xX])\][ \t]+`)
+	reFence      = regexp.MustCompile("^[ \t]*```")
+	reCodeBlock  = regexp.MustCompile("(?s)```([^\n`]*)\n(.*?)```")
 )
 
+// listBullets are the bullets for each level of a nested list.
+var listBullets = []string{"•", "◦", "▪"}
+
+// slackMaxCodeBlock is the longest fenced code block posted inline in Slack;
+// longer ones are uploaded as snippets instead.
+const slackMaxCodeBlock = 2500
+
+// codeSnippet is a fenced code block taken out of a Slack message to be
+// uploaded as a file.
+type codeSnippet struct {
+	name    string
+	content string
+}
+
+// snippetTypes maps code block languages to a file extension and Slack
+// snippet type.
+var snippetTypes = map[string]struct{ ext, slackType string }{
+	"go":         {".go", "go"},
+	"python":     {".py", "python"},
+	"py":         {".py", "python"},
+	"javascript": {".js", "javascript"},
+	"js":         {".js", "javascript"},
+	"typescript": {".ts", "typescript"},
+	"ts":         {".ts", "typescript"},
+	"sh":         {".sh", "shell"},
+	"bash":       {".sh", "shell"},
+	"shell":      {".sh", "shell"},
+	"json":       {".json", "json"},
+	"yaml":       {".yaml", "yaml"},
+	"yml":        {".yaml", "yaml"},
+	"sql":        {".sql", "sql"},
+	"diff":       {".diff", "diff"},
+	"rust":       {".rs", "rust"},
+	"java":       {".java", "java"},
+}
+
+// snippetType returns the Slack snippet type for a file name, or "text".
+func snippetType(name string) string {
+	for _, t := range snippetTypes {
+		if t.ext == path.Ext(name) {
+			return t.slackType
+		}
+	}
+	return "text"
+}
+
+// extractLongCodeBlocks replaces the fenced code blocks in s longer than
+// slackMaxCodeBlock with a note naming the snippet they are uploaded as, and
+// returns them. The names only depend on s, so a message re-rendered from the
+// same text refers to the same snippets.
+func extractLongCodeBlocks(s string) (string, []codeSnippet) {
+	var snippets []codeSnippet
+	out := reCodeBlock.ReplaceAllStringFunc(s, func(block string) string {
+		if len(block) <= slackMaxCodeBlock {
+			return block
+		}
+		m := reCodeBlock.FindStringSubmatch(block)
+		ext := ".txt"
+		if t, ok := snippetTypes[strings.ToLower(strings.TrimSpace(m[1]))]; ok {
+			ext = t.ext
+		}
+		code := strings.TrimSuffix(m[2], "\n")
+		snippet := codeSnippet{name: fmt.Sprintf("snippet-%d%s", len(snippets)+1, ext), content: code}
+		snippets = append(snippets, snippet)
+		return fmt.Sprintf("_(%d lines of code, attached as %s)_", strings.Count(code, "\n")+1, snippet.name)
+	})
+	return out, snippets
+}
+
+// convertLists turns Markdown list items outside code blocks into mrkdwn
+// bullets, indenting nested items with em spaces since Slack drops leading
+// spaces. Numbered items keep their numbers; task list boxes become ☐ and ☑.
+func convertLists(s string) string {
+	lines := strings.Split(s, "\n")
+	inFence := false
+	for i, line := range lines {
+		if reFence.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		m := reListItem.FindStringSubmatch(line)
+		if inFence || m == nil {
+			continue
+		}
+		width := len(strings.ReplaceAll(m[1], "\t", "    "))
+		level := min(width/2, len(listBullets)-1)
+		marker := m[2]
+		if !strings.ContainsAny(marker, ".)") {
+			marker = listBullets[level]
+		}
+		item := reTaskBox.ReplaceAllStringFunc(m[3], func(box string) string {
+			if strings.ContainsAny(box, "xX") {
+				return "☑ "
+			}
+			return "☐ "
+		})
+		lines[i] = strings.Repeat("\u2003", level) + marker + " " + item
+	}
+	return strings.Join(lines, "\n")
+}
+
 // segment represents a chunk of text that is either code (to be preserved
 // verbatim) or regular text (to be transformed).
 type segment struct {
@@ -76,10 +183,11 @@ func splitByRegex(s string, re *regexp.Regexp) []segment {
 
 // transformMarkdown applies Markdown → Slack mrkdwn conversions to a non-code
 // text segment. Uses a placeholder for Slack bold markers so the italic step
-// doesn't consume them.
+// doesn't consume them. With mixed set, *single stars* are left alone, as
+// they are already mrkdwn bold.
 //
 // Order:
-//  1. Headers → bold placeholder
+//  1. Headers → bold placeholder (bold inside them dropped)
 //  2. Images (before links — superset pattern)
 //  3. Links
 //  4. Bold-italic → bold placeholder + italic
@@ -87,28 +195,49 @@ func splitByRegex(s string, re *regexp.Regexp) []segment {
 //  6. Italic → Slack italic (safe — all ** and placeholders already consumed)
 //  7. Strikethrough
 //  8. Replace placeholders with *
-func transformMarkdown(s string) string {
-	s = reHeader.ReplaceAllString(s, boldPlaceholder+"$1"+boldPlaceholder)
+func transformMarkdown(s string, mixed bool) string {
+	s = reHeader.ReplaceAllStringFunc(s, func(h string) string {
+		title := reHeader.FindStringSubmatch(h)[1]
+		return boldPlaceholder + strings.ReplaceAll(title, "**", "") + boldPlaceholder
+	})
 	s = reImage.ReplaceAllString(s, "<$2|$1>")
 	s = reLink.ReplaceAllString(s, "<$2|$1>")
 	s = reBoldItalic.ReplaceAllString(s, boldPlaceholder+"_${1}_"+boldPlaceholder)
 	s = reBold.ReplaceAllString(s, boldPlaceholder+"${1}"+boldPlaceholder)
-	s = reItalic.ReplaceAllString(s, "_${1}_")
+	if !mixed {
+		s = reItalic.ReplaceAllString(s, "_${1}_")
+	}
 	s = reStrike.ReplaceAllString(s, "~${1}~")
 	s = strings.ReplaceAll(s, boldPlaceholder, "*")
 	return s
 }
 
-// markdownToMrkdwn converts standard Markdown to Slack mrkdwn format.
-// Code blocks and inline code are preserved verbatim.
+// markdownToMrkdwn converts standard Markdown to Slack mrkdwn format. Code
+// blocks and inline code are preserved verbatim, except that a fenced
+// block's language tag, which Slack would show as its first line, is dropped.
 func markdownToMrkdwn(s string) string {
-	segments := splitCodeSegments(s)
+	return convertMarkdown(s, false)
+}
+
+// mixedToMrkdwn converts the Markdown in text that may already be partly
+// mrkdwn, such as Bob's replies quoting a model's output: like
+// markdownToMrkdwn, but *single stars* stay bold.
+func mixedToMrkdwn(s string) string {
+	return convertMarkdown(s, true)
+}
+
+func convertMarkdown(s string, mixed bool) string {
+	segments := splitCodeSegments(convertLists(s))
 	var b strings.Builder
 	for _, seg := range segments {
-		if seg.isCode {
+		switch {
+		case seg.isCode && strings.HasPrefix(seg.text, "```"):
+			_, code, _ := strings.Cut(seg.text, "\n")
+			b.WriteString("```\n" + code)
+		case seg.isCode:
 			b.WriteString(seg.text)
-		} else {
-			b.WriteString(transformMarkdown(seg.text))
+		default:
+			b.WriteString(transformMarkdown(seg.text, mixed))
 		}
 	}
 	return b.String()
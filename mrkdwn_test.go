@@ -1,7 +1,10 @@
 package main
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/slack-go/slack"
 )
 
 func TestMarkdownToMrkdwn_Headers(t *testing.T) {
@@ -111,7 +114,7 @@ func TestMarkdownToMrkdwn_Strikethrough(t *testing.T) {
 
 func TestMarkdownToMrkdwn_CodeBlockPreserved(t *testing.T) {
 	in := "before\n```go\nfunc **notBold**() {}\n```\nafter **bold**"
-	want := "before\n```go\nfunc **notBold**() {}\n```\nafter *bold*"
+	want := "before\n```\nfunc **notBold**() {}\n```\nafter *bold*" // the language tag would show as a line
 	got := markdownToMrkdwn(in)
 	if got != want {
 		t.Errorf("markdownToMrkdwn(code block) =\n%s\nwant:\n%s", got, want)
@@ -146,7 +149,7 @@ Add *unit tests* for the handler. Remove ~~old tests~~.`
 
 Modify *handler.go* to add the new endpoint. See <https://example.com/rfc|RFC> for details.
 
-` + "```go\nfunc NewHandler() {\n\t// **important**\n}\n```" + `
+` + "```\nfunc NewHandler() {\n\t// **important**\n}\n```" + `
 
 *Step 2: Tests*
 
@@ -191,3 +194,69 @@ func TestMarkdownToMrkdwn_NoChange(t *testing.T) {
 		t.Errorf("markdownToMrkdwn(%q) = %q, want unchanged", in, got)
 	}
 }
+
+func TestMarkdownToMrkdwn_Lists(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"- one\n- two *italic*", "• one\n• two _italic_"},
+		{"* one\n  * nested\n    + deeper\n      - deepest", "• one\n\u2003◦ nested\n\u2003\u2003▪ deeper\n\u2003\u2003▪ deepest"},
+		{"1. first\n2. second\n   - sub", "1. first\n2. second\n\u2003◦ sub"},
+		{"- [ ] todo\n- [x] done", "• ☐ todo\n• ☑ done"},
+		{"```\n- not a list\n```\n- a list", "```\n- not a list\n```\n• a list"},
+		{"run `go test` - then push", "run `go test` - then push"},
+		{"**bold** line", "*bold* line"},
+	}
+	for _, tt := range tests {
+		if got := markdownToMrkdwn(tt.in); got != tt.want {
+			t.Errorf("markdownToMrkdwn(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownToMrkdwn_BoldHeading(t *testing.T) {
+	if got, want := markdownToMrkdwn("## **Plan** for the API"), "*Plan for the API*"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestMixedToMrkdwn(t *testing.T) {
+	in := "*Done!* Here's what changed:\n\n## Summary\n- Updated **handler.go**\n```go\nx := 1\n```"
+	want := "*Done!* Here's what changed:\n\n*Summary*\n• Updated *handler.go*\n```\nx := 1\n```"
+	if got := mixedToMrkdwn(in); got != want {
+		t.Errorf("mixedToMrkdwn =\n%s\nwant:\n%s", got, want)
+	}
+
+	in = "<@U123> Done! See <https://github.com/o/r/pull/1|the PR> for *details*"
+	if got := mixedToMrkdwn(in); got != in {
+		t.Errorf("mixedToMrkdwn = %q, want it unchanged", got)
+	}
+}
+
+func TestMrkdwnSections_LongCodeBlock(t *testing.T) {
+	body := "Plan:\n```\n" + strings.Repeat("fmt.Println(line)\n", 300) + "```"
+	blocks := mrkdwnSections("*Plan*", body)
+	if len(blocks) != 1 {
+		t.Fatalf("blocks = %d, want 1", len(blocks))
+	}
+	text := blocks[0].(*slack.SectionBlock).Text.Text
+	if !strings.Contains(text, "_(300 lines of code, attached as snippet-1.txt)_") || strings.Contains(text, "Println") {
+		t.Errorf("section text = %q", text)
+	}
+}
+
+func TestExtractLongCodeBlocks(t *testing.T) {
+	long := strings.Repeat("fmt.Println(1)\n", 200)
+	in := "intro\n```go\nshort()\n```\n```go\n" + long + "```\n```\n" + long + "```"
+	out, snippets := extractLongCodeBlocks(in)
+	want := "intro\n```go\nshort()\n```\n_(200 lines of code, attached as snippet-1.go)_\n_(200 lines of code, attached as snippet-2.txt)_"
+	if out != want {
+		t.Errorf("text =\n%s\nwant:\n%s", out, want)
+	}
+	if len(snippets) != 2 || snippets[0].content != strings.TrimSuffix(long, "\n") || snippets[1].name != "snippet-2.txt" {
+		t.Errorf("snippets = %d: %+v", len(snippets), snippets[0].name)
+	}
+	if snippetType("snippet-1.go") != "go" || snippetType("changes.diff") != "diff" || snippetType("snippet-2.txt") != "text" {
+		t.Error("wrong snippet types")
+	}
+}
@@ -2,6 +2,18 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/slack-go/slack"
+)
+
+// Slack truncates messages past about 4000 characters and rejects section
+// blocks over 3000, so longer text is split across several of each.
+const (
+	slackMaxMessage     = 3900
+	slackMaxSectionText = 2900
+	slackMaxSections    = 20 // of a message's 50 blocks
 )
 
 type ctxKey int
@@ -12,6 +24,10 @@ const (
 	ctxKeyJobID     ctxKey = iota
 	ctxKeyHub       ctxKey = iota
 	ctxKeyMentionTS ctxKey = iota
+	ctxKeyPlatform  ctxKey = iota
+	ctxKeyCoAuthor  ctxKey = iota
+	ctxKeyUser      ctxKey = iota
+	ctxKeyUserName  ctxKey = iota
 )
 
 // WithSlackThread returns a context carrying the Slack channel and thread timestamp.
@@ -38,6 +54,41 @@ func WithMentionTS(ctx context.Context, ts string) context.Context {
 	return context.WithValue(ctx, ctxKeyMentionTS, ts)
 }
 
+// WithUser returns a context carrying the chat user ID of the requester.
+func WithUser(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, ctxKeyUser, userID)
+}
+
+// UserFromCtx extracts the requester's chat user ID from the context.
+func UserFromCtx(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKeyUser).(string)
+	return v
+}
+
+// WithUserName returns a context carrying the requester's display name, shown
+// with the jobs they start.
+func WithUserName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, ctxKeyUserName, name)
+}
+
+// UserNameFromCtx extracts the requester's display name, or empty string.
+func UserNameFromCtx(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKeyUserName).(string)
+	return v
+}
+
+// WithCoAuthor returns a context carrying the requesting user's identity, to be
+// credited as co-author on the commits of the job the request starts.
+func WithCoAuthor(ctx context.Context, id gitIdentity) context.Context {
+	return context.WithValue(ctx, ctxKeyCoAuthor, &id)
+}
+
+// CoAuthorFromCtx extracts the requesting user's identity, or nil.
+func CoAuthorFromCtx(ctx context.Context) *gitIdentity {
+	v, _ := ctx.Value(ctxKeyCoAuthor).(*gitIdentity)
+	return v
+}
+
 // WithHub returns a context carrying the monitoring Hub.
 func WithHub(ctx context.Context, hub *Hub) context.Context {
 	return context.WithValue(ctx, ctxKeyHub, hub)
@@ -48,3 +99,179 @@ func HubFromCtx(ctx context.Context) *Hub {
 	v, _ := ctx.Value(ctxKeyHub).(*Hub)
 	return v
 }
+
+// Notifier delivers plain-text progress and result messages to whatever chat
+// thread the context carries. Interactive features (buttons, reactions, message
+// edits) are backend-specific and accessed through capability checks.
+type Notifier interface {
+	Notify(ctx context.Context, text string) error
+}
+
+// SlackPlatform is the Slack ChatPlatform, posting into the thread stored in
+// the context by WithSlackThread.
+type SlackPlatform struct {
+	client    *slack.Client
+	botUserID string
+}
+
+// NewSlackPlatform creates a SlackPlatform. botUserID identifies Bob's own
+// messages when reading thread history.
+func NewSlackPlatform(client *slack.Client, botUserID string) *SlackPlatform {
+	return &SlackPlatform{client: client, botUserID: botUserID}
+}
+
+// Name implements ChatPlatform.
+func (n *SlackPlatform) Name() string { return "slack" }
+
+// Notify posts text as a threaded reply (see postSlackText).
+func (n *SlackPlatform) Notify(ctx context.Context, text string) error {
+	channel, _ := ctx.Value(ctxKeyChannel).(string)
+	threadTS, _ := ctx.Value(ctxKeyThreadTS).(string)
+	if channel == "" {
+		return fmt.Errorf("notify: no slack channel in context")
+	}
+	return postSlackText(ctx, n.client, channel, threadTS, text)
+}
+
+// postSlackText posts text, which may mix mrkdwn with a model's Markdown, to
+// the thread (or the channel, without threadTS) as mrkdwn, split over several
+// messages if it is too long for one. Code blocks too long to read inline
+// follow as snippets.
+func postSlackText(ctx context.Context, client *slack.Client, channel, threadTS, text string) error {
+	text, snippets := extractLongCodeBlocks(text)
+	for _, chunk := range splitMessage(mixedToMrkdwn(text), slackMaxMessage) {
+		if err := postSlackMessage(ctx, client, channel, threadTS, chunk); err != nil {
+			return err
+		}
+	}
+	postCodeSnippets(ctx, client, channel, threadTS, snippets)
+	return nil
+}
+
+// postSlackMessage posts one plain mrkdwn message.
+func postSlackMessage(ctx context.Context, client *slack.Client, channel, threadTS, text string) error {
+	opts := []slack.MsgOption{slack.MsgOptionText(text, false)}
+	if threadTS != "" {
+		opts = append(opts, slack.MsgOptionTS(threadTS))
+	}
+	_, _, err := client.PostMessageContext(ctx, channel, opts...)
+	return err
+}
+
+// postCodeSnippets uploads the code blocks extractLongCodeBlocks took out of
+// a message just posted. One that can't be uploaded (the files:write scope is
+// missing) is posted inline after all.
+func postCodeSnippets(ctx context.Context, client *slack.Client, channel, threadTS string, snippets []codeSnippet) {
+	for _, s := range snippets {
+		err := uploadSlackSnippet(ctx, client, channel, threadTS, s.name, s.content)
+		if err == nil {
+			continue
+		}
+		slog.WarnContext(ctx, "slack: failed to upload code snippet, posting it inline", "name", s.name, "err", err)
+		for _, chunk := range splitMessage("```\n"+s.content+"\n```", slackMaxMessage) {
+			if err := postSlackMessage(ctx, client, channel, threadTS, chunk); err != nil {
+				slog.ErrorContext(ctx, "slack: failed to post code block", "name", s.name, "err", err)
+				break
+			}
+		}
+	}
+}
+
+// uploadSlackSnippet uploads content as a snippet named name to the thread.
+func uploadSlackSnippet(ctx context.Context, client *slack.Client, channel, threadTS, name, content string) error {
+	_, err := client.UploadFileV2Context(ctx, slack.UploadFileV2Parameters{
+		Content:         content,
+		FileSize:        len(content),
+		Filename:        name,
+		Title:           name,
+		Channel:         channel,
+		ThreadTimestamp: threadTS,
+		SnippetType:     snippetType(name),
+	})
+	return err
+}
+
+// mrkdwnSections returns section blocks for heading and a Markdown body,
+// splitting the body over as many sections as it needs up to
+// slackMaxSections. Anything past that is cut off. Long code blocks are left
+// out, for the caller to post with postCodeSnippets.
+func mrkdwnSections(heading, body string) []slack.Block {
+	body, _ = extractLongCodeBlocks(body)
+	chunks := splitMessage(markdownToMrkdwn(body), slackMaxSectionText-len(heading)-2)
+	if len(chunks) > slackMaxSections {
+		chunks = append(chunks[:slackMaxSections-1], "...")
+	}
+	chunks[0] = heading + "\n\n" + chunks[0]
+	blocks := make([]slack.Block, len(chunks))
+	for i, c := range chunks {
+		blocks[i] = slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, c, false, false), nil, nil)
+	}
+	return blocks
+}
+
+// PostProgress implements progressPoster.
+func (n *SlackPlatform) PostProgress(ctx context.Context, channel, threadTS, text string) (string, error) {
+	_, ts, err := n.client.PostMessageContext(ctx, channel, slack.MsgOptionText(text, false), slack.MsgOptionTS(threadTS))
+	return ts, err
+}
+
+// UpdateProgress implements progressPoster via chat.update.
+func (n *SlackPlatform) UpdateProgress(ctx context.Context, channel, ts, text string) error {
+	_, _, _, err := n.client.UpdateMessageContext(ctx, channel, ts, slack.MsgOptionText(text, false))
+	return err
+}
+
+// ThreadMessages implements ChatPlatform.
+func (n *SlackPlatform) ThreadMessages(ctx context.Context, channel, threadTS string) ([]Message, error) {
+	replies, _, _, err := n.client.GetConversationRepliesContext(ctx, &slack.GetConversationRepliesParameters{
+		ChannelID: channel,
+		Timestamp: threadTS,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return threadToMessages(replies, n.botUserID), nil
+}
+
+// UserIdentity returns a Slack user's name and email address, for crediting
+// them as a commit co-author. It needs the users:read.email scope.
+func (n *SlackPlatform) UserIdentity(ctx context.Context, userID string) (gitIdentity, error) {
+	user, err := n.client.GetUserInfoContext(ctx, userID)
+	if err != nil {
+		return gitIdentity{}, err
+	}
+	if user.Profile.Email == "" {
+		return gitIdentity{}, fmt.Errorf("user %s has no visible email address", userID)
+	}
+	name := user.Profile.RealName
+	if name == "" {
+		name = user.Name
+	}
+	return gitIdentity{Name: name, Email: user.Profile.Email}, nil
+}
+
+// UserName returns a Slack user's display name, falling back to their real
+// name and handle. It needs the users:read scope.
+func (n *SlackPlatform) UserName(ctx context.Context, userID string) (string, error) {
+	user, err := n.client.GetUserInfoContext(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range []string{user.Profile.DisplayName, user.Profile.RealName, user.Name} {
+		if name != "" {
+			return name, nil
+		}
+	}
+	return userID, nil
+}
+
+// StripMention implements ChatPlatform.
+func (n *SlackPlatform) StripMention(text string) string { return stripMention(text) }
+
+// MentionUser implements ChatPlatform.
+func (n *SlackPlatform) MentionUser(userID string) string { return fmt.Sprintf("<@%s>", userID) }
+
+// Client returns the underlying Slack client for Slack-only interactive features.
+func (n *SlackPlatform) Client() *slack.Client {
+	return n.client
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestSlackPlatform_RequiresThread(t *testing.T) {
+	n := NewSlackPlatform(nil, "")
+	if err := n.Notify(context.Background(), "hello"); err == nil {
+		t.Error("expected error when context has no Slack channel")
+	}
+}
+
+func TestSlackPlatform_SplitsLongMessages(t *testing.T) {
+	var posted []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		posted = append(posted, r.Form.Get("text"))
+		if r.Form.Get("thread_ts") != "1.0" {
+			t.Errorf("thread_ts = %q", r.Form.Get("thread_ts"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true,"channel":"C1","ts":"2.0"}`))
+	}))
+	defer srv.Close()
+
+	n := NewSlackPlatform(slack.New("xoxb-test", slack.OptionAPIURL(srv.URL+"/")), "")
+	text := strings.Repeat("ünïcödé line\n", 700)
+	if err := n.Notify(WithSlackThread(context.Background(), "C1", "1.0"), text); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(posted) < 2 {
+		t.Fatalf("posted %d messages, want the text split", len(posted))
+	}
+	for _, p := range posted {
+		if len(p) > slackMaxMessage {
+			t.Errorf("message is %d bytes, over %d", len(p), slackMaxMessage)
+		}
+	}
+	if got := strings.Join(posted, "\n"); got != strings.TrimSuffix(text, "\n") && got != text {
+		t.Errorf("messages don't add up to the text")
+	}
+}
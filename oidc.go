@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oidcClockSkew is the leeway allowed on exp and nbf.
+const oidcClockSkew = time.Minute
+
+// oidcKeyRefresh is the minimum time between JWKS fetches triggered by an
+// unknown key ID, so forged kids can't make Bob hammer the issuer.
+const oidcKeyRefresh = time.Minute
+
+// oidcClaims are the JWT claims Bob checks and logs.
+type oidcClaims struct {
+	Issuer    string       `json:"iss"`
+	Subject   string       `json:"sub"`
+	Email     string       `json:"email,omitempty"`
+	Audience  oidcAudience `json:"aud"`
+	ExpiresAt int64        `json:"exp"`
+	NotBefore int64        `json:"nbf,omitempty"`
+}
+
+// oidcAudience is the aud claim, which may be a string or an array.
+type oidcAudience []string
+
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = oidcAudience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// oidcVerifier verifies RS256 and ES256 JWTs (ID or access tokens) from an
+// OIDC issuer. Signing keys come from the jwks_uri in the issuer's discovery
+// document, fetched on first use and again when a token names an unknown key.
+type oidcVerifier struct {
+	issuer   string
+	audience string // required aud
+	client   *http.Client
+
+	mu       sync.Mutex
+	keys     map[string]crypto.PublicKey // by kid
+	fetched  time.Time
+	fetching chan struct{} // closed when the key fetch in flight ends; nil if none
+}
+
+// newOIDCVerifier creates a verifier for tokens issued by issuer to audience.
+// The audience is required: an issuer such as Google mints tokens for every
+// app its users sign in to, and without it any of them would be accepted.
+func newOIDCVerifier(issuer, audience string) (*oidcVerifier, error) {
+	if strings.TrimSpace(audience) == "" {
+		return nil, errors.New("an audience is required")
+	}
+	return &oidcVerifier{
+		issuer:   strings.TrimSuffix(issuer, "/"),
+		audience: audience,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Verify checks the token's signature, issuer, audience and validity window
+// and returns its claims.
+func (v *oidcVerifier) Verify(ctx context.Context, raw string) (oidcClaims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return oidcClaims{}, errors.New("malformed JWT")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return oidcClaims{}, fmt.Errorf("header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return oidcClaims{}, fmt.Errorf("signature: %w", err)
+	}
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return oidcClaims{}, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if header.Alg != "RS256" {
+			return oidcClaims{}, fmt.Errorf("alg %q doesn't match RSA key", header.Alg)
+		}
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig); err != nil {
+			return oidcClaims{}, errors.New("bad signature")
+		}
+	case *ecdsa.PublicKey:
+		if header.Alg != "ES256" || len(sig) != 64 {
+			return oidcClaims{}, fmt.Errorf("alg %q doesn't match EC key", header.Alg)
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(k, digest[:], r, s) {
+			return oidcClaims{}, errors.New("bad signature")
+		}
+	default:
+		return oidcClaims{}, fmt.Errorf("unsupported key type %T", key)
+	}
+
+	var claims oidcClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return oidcClaims{}, fmt.Errorf("claims: %w", err)
+	}
+	now := time.Now()
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != v.issuer:
+		return oidcClaims{}, fmt.Errorf("issuer %q not trusted", claims.Issuer)
+	case v.audience == "" || !claims.Audience.contains(v.audience):
+		return oidcClaims{}, fmt.Errorf("audience %v doesn't include %q", claims.Audience, v.audience)
+	case claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(oidcClockSkew)):
+		return oidcClaims{}, errors.New("token expired")
+	case claims.NotBefore != 0 && now.Add(oidcClockSkew).Before(time.Unix(claims.NotBefore, 0)):
+		return oidcClaims{}, errors.New("token not yet valid")
+	}
+	return claims, nil
+}
+
+func (a oidcAudience) contains(aud string) bool {
+	for _, s := range a {
+		if s == aud {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeJWTPart(part string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// key returns the signing key with ID kid, refreshing the key set if it is
+// unknown and the last fetch wasn't too recent. The fetch runs without v.mu
+// held, so tokens with known keys don't wait for it; requests for unknown
+// keys meanwhile wait for its result instead of fetching again.
+func (v *oidcVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	if k, ok := v.keys[kid]; ok {
+		v.mu.Unlock()
+		return k, nil
+	}
+	if wait := v.fetching; wait != nil {
+		v.mu.Unlock()
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	} else {
+		if time.Since(v.fetched) < oidcKeyRefresh {
+			v.mu.Unlock()
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		done := make(chan struct{})
+		v.fetching = done
+		v.mu.Unlock()
+
+		// Other requests share this fetch, so one giving up mustn't cancel it;
+		// the client's timeout bounds it.
+		keys, err := v.fetchKeys(context.WithoutCancel(ctx))
+		v.mu.Lock()
+		v.fetched, v.fetching = time.Now(), nil
+		if err == nil {
+			v.keys = keys
+		}
+		v.mu.Unlock()
+		close(done)
+		if err != nil {
+			return nil, fmt.Errorf("fetch signing keys: %w", err)
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if k, ok := v.keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// fetchKeys reads the issuer's discovery document and its JWKS.
+func (v *oidcVerifier) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.getJSON(ctx, v.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.JWKSURI == "" {
+		return nil, errors.New("discovery document has no jwks_uri")
+	}
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := v.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch {
+		case k.Kty == "RSA":
+			n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+			y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	return keys, nil
+}
+
+func (v *oidcVerifier) getJSON(ctx context.Context, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
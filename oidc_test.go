@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeIssuer serves a discovery document and JWKS for one RSA and one EC key.
+type fakeIssuer struct {
+	*httptest.Server
+	rsaKey      *rsa.PrivateKey
+	ecKey       *ecdsa.PrivateKey
+	jwksFetches atomic.Int32
+	keysGate    chan struct{} // if set, JWKS requests wait for it
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeIssuer{rsaKey: rsaKey, ecKey: ecKey}
+	b64 := base64.RawURLEncoding.EncodeToString
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": f.URL, "jwks_uri": f.URL + "/keys"})
+		case "/keys":
+			f.jwksFetches.Add(1)
+			if f.keysGate != nil {
+				<-f.keysGate
+			}
+			json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+				{"kty": "RSA", "kid": "rsa1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+				{"kty": "EC", "kid": "ec1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+// sign returns a JWT with claims, signed by the key named kid.
+func (f *fakeIssuer) sign(t *testing.T, kid string, claims map[string]any) string {
+	t.Helper()
+	alg := "RS256"
+	if kid == "ec1" {
+		alg = "ES256"
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(input))
+	var sig []byte
+	if alg == "RS256" {
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, f.rsaKey, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		r, s, err := ecdsa.Sign(rand.Reader, f.ecKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return input + "." + b64(sig)
+}
+
+func TestOIDCVerifier(t *testing.T) {
+	iss := newFakeIssuer(t)
+	v, err := newOIDCVerifier(iss.URL+"/", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	valid := func() map[string]any {
+		return map[string]any{"iss": iss.URL, "sub": "u1", "email": "u1@example.com", "aud": "bob", "exp": now.Add(time.Hour).Unix()}
+	}
+	with := func(k string, val any) map[string]any {
+		c := valid()
+		if val == nil {
+			delete(c, k)
+		} else {
+			c[k] = val
+		}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"RS256", iss.sign(t, "rsa1", valid()), false},
+		{"ES256", iss.sign(t, "ec1", valid()), false},
+		{"audience array", iss.sign(t, "rsa1", with("aud", []string{"other", "bob"})), false},
+		{"wrong audience", iss.sign(t, "rsa1", with("aud", "other")), true},
+		{"wrong issuer", iss.sign(t, "rsa1", with("iss", "https://evil.example.com")), true},
+		{"expired", iss.sign(t, "rsa1", with("exp", now.Add(-time.Hour).Unix())), true},
+		{"no exp", iss.sign(t, "rsa1", with("exp", nil)), true},
+		{"not yet valid", iss.sign(t, "rsa1", with("nbf", now.Add(time.Hour).Unix())), true},
+		{"unknown key", iss.sign(t, "other", valid()), true},
+		{"tampered", iss.sign(t, "rsa1", valid()) + "x", true},
+		{"malformed", "not-a-jwt", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Verify(context.Background(), tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && claims.Subject != "u1" {
+				t.Errorf("claims = %+v", claims)
+			}
+		})
+	}
+	// Unknown key IDs don't refetch the JWKS more than once a minute.
+	if n := iss.jwksFetches.Load(); n != 1 {
+		t.Errorf("JWKS fetched %d times, want 1", n)
+	}
+}
+
+func TestNewOIDCVerifier_RequiresAudience(t *testing.T) {
+	if _, err := newOIDCVerifier("https://accounts.google.com", " "); err == nil {
+		t.Error("verifier created without an audience")
+	}
+}
+
+func TestOIDCVerifier_KeyFetchDoesNotBlock(t *testing.T) {
+	iss := newFakeIssuer(t)
+	v, _ := newOIDCVerifier(iss.URL, "bob")
+	claims := map[string]any{"iss": iss.URL, "sub": "u1", "aud": "bob", "exp": time.Now().Add(time.Hour).Unix()}
+	if _, err := v.Verify(t.Context(), iss.sign(t, "rsa1", claims)); err != nil {
+		t.Fatal(err)
+	}
+
+	// An unknown key starts a refresh that hangs until the gate opens.
+	gate := make(chan struct{})
+	iss.keysGate = gate
+	v.mu.Lock()
+	v.fetched = time.Time{}
+	v.mu.Unlock()
+	refreshed := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(context.Background(), iss.sign(t, "rotated", claims))
+		refreshed <- err
+	}()
+	for iss.jwksFetches.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	verified := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(context.Background(), iss.sign(t, "ec1", claims))
+		verified <- err
+	}()
+	select {
+	case err := <-verified:
+		if err != nil {
+			t.Errorf("known key: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("a token with a known key waited for the key refresh")
+	}
+	close(gate)
+	if err := <-refreshed; err == nil {
+		t.Error("token with an unknown key verified")
+	}
+}
+
+func TestRequireAuth_OIDC(t *testing.T) {
+	iss := newFakeIssuer(t)
+	v, _ := newOIDCVerifier(iss.URL, "bob")
+	handler := requireAuth(newAuthenticator([]string{"static"}, v),
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	jwt := iss.sign(t, "rsa1", map[string]any{"iss": iss.URL, "sub": "u1", "aud": "bob", "exp": time.Now().Add(time.Hour).Unix()})
+	otherApp := iss.sign(t, "rsa1", map[string]any{"iss": iss.URL, "sub": "u1", "aud": "another-app", "exp": time.Now().Add(time.Hour).Unix()})
+
+	for token, want := range map[string]int{
+		jwt:                       http.StatusOK,
+		"static":                  http.StatusOK,
+		jwt[:len(jwt)-4] + "AAAA": http.StatusUnauthorized,
+		otherApp:                  http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/jobs", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("status = %d, want %d", rec.Code, want)
+		}
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -19,13 +21,18 @@ const planMarker = "\U0001f4cb *Plan*"
 
 // OrchestratorResult is the outcome of an orchestration run.
 type OrchestratorResult struct {
-	Text           string        // text reply for clarifying questions or errors
-	IsJob          bool          // true if a monitoring job was started
-	PRURL          string        // set if a pull request was created
-	PlanBlocks     []slack.Block // set when plan is generated (for Block Kit message)
-	PlanText       string        // full plan text with marker (for MsgOptionText fallback)
-	QuestionBlocks []slack.Block // set when clarification is needed (for Block Kit message)
-	JobID          string        // job ID (for storing plan msg TS)
+	Text           string             // text reply for clarifying questions or errors
+	IsJob          bool               // true if a monitoring job was started
+	PRURL          string             // set if a pull request was created
+	PlanBlocks     []slack.Block      // set when plan is generated (for Block Kit message)
+	PlanText       string             // full plan text with marker (for MsgOptionText fallback)
+	PlanChanges    string             // Markdown summary of a revised plan's changes; included in PlanText and PlanBlocks
+	QuestionBlocks []slack.Block      // set when clarification is needed (for Block Kit message)
+	Diff           string             // a dry run's changes, posted to the thread after Text
+	SecretFindings []SecretFinding    // possible credentials the changes are held for; Text asks what to do
+	Injections     []InjectionFinding // suspected prompt injection the plan warns about; it must not be approved automatically
+	Reported       bool               // the outcome has already been posted to the thread (e.g. a re-run implemented straight away)
+	JobID          string             // job ID (for storing plan msg TS)
 }
 
 // maxTaskLen is the maximum length of a task description extracted from intent parsing.
@@ -34,35 +41,76 @@ const maxTaskLen = 2000
 
 // Orchestrator drives the deterministic coding workflow.
 type Orchestrator struct {
-	anthropicKey    string
-	githubOwner     string
-	githubToken     string
+	llm             LLMProvider   // intent parsing
+	providers       []VCSProvider // searched in order when resolving a repo
 	claudeCodeToken string
 	hub             *Hub
 	allowedRepos    map[string]bool
+	testFixRetries  int            // fix sessions to run when tests fail after implementation
+	maxJobCostUSD   float64        // per-job LLM cost budget; 0 means unlimited
+	sandbox         *Sandbox       // runs Claude Code and tests in containers; nil runs on the host
+	sessions        *SessionConfig // Claude Code timeout, max turns and model, per repo
+	tools           toolPolicy     // Claude Code tool rules by phase; nil uses defaultToolRules
+	gitAuthor       gitIdentity    // commit author; zero uses defaultGitAuthor
+	creditRequester bool           // add the requesting chat user as a commit co-author
+	access          *accessPolicy  // repos each channel and user group may target; nil allows all
+	jira            *JiraClient    // fetches and updates referenced Jira tickets; nil if not configured
+	guidelinesFile  string         // org-wide coding guidelines prepended to task prompts; empty for none
+
+	stepTimeouts map[string]time.Duration // per step, see runStep; steps not listed have no limit
+
+	securityScanners []string // run before opening a PR when a repo doesn't set its own; see securityScan
+
+	disk      workspaceQuota // workspace disk quotas and garbage collection
+	diskUsage workspaceUsage // the workspace's size, as last measured
 }
 
 // NewOrchestrator creates a new Orchestrator.
-func NewOrchestrator(anthropicKey, githubOwner, githubToken, claudeCodeToken string, hub *Hub, allowedRepos map[string]bool) *Orchestrator {
-	return &Orchestrator{
-		anthropicKey:    anthropicKey,
-		githubOwner:     githubOwner,
-		githubToken:     githubToken,
+func NewOrchestrator(llm LLMProvider, providers []VCSProvider, claudeCodeToken string, hub *Hub, allowedRepos map[string]bool, testFixRetries int, maxJobCostUSD float64, sandbox *Sandbox, sessions *SessionConfig, gitAuthor gitIdentity, creditRequester bool, access *accessPolicy, jira *JiraClient, guidelinesFile string, stepTimeouts map[string]time.Duration, securityScanners []string, disk workspaceQuota, tools toolPolicy) *Orchestrator {
+	o := &Orchestrator{
+		llm:             llm,
+		providers:       providers,
 		claudeCodeToken: claudeCodeToken,
 		hub:             hub,
 		allowedRepos:    allowedRepos,
+		testFixRetries:  testFixRetries,
+		maxJobCostUSD:   maxJobCostUSD,
+		sandbox:         sandbox,
+		sessions:        sessions,
+		tools:           tools,
+		gitAuthor:       gitAuthor,
+		creditRequester: creditRequester,
+		access:          access,
+		jira:            jira,
+		guidelinesFile:  guidelinesFile,
+		stepTimeouts:    stepTimeouts,
+
+		securityScanners: securityScanners,
+		disk:             disk,
 	}
+	o.restoreProviders()
+	return o
 }
 
 // HandleNewRequest parses intent from a first mention and starts the planning session.
 // defaultRepo is the channel's configured default repo (may be empty).
 // onJobCreated is called with the job ID right after the job is created, before cloning or planning.
 func (o *Orchestrator) HandleNewRequest(ctx context.Context, messages []Message, defaultRepo string, onJobCreated func(jobID string)) (OrchestratorResult, error) {
-	intent, err := ParseIntent(ctx, o.anthropicKey, messages)
+	channel, _ := ctx.Value(ctxKeyChannel).(string)
+	threadTS, _ := ctx.Value(ctxKeyThreadTS).(string)
+
+	// A "go" after Bob asked for confirmation starts the request as understood.
+	// Anything else is a correction, parsed along with the rest of the thread.
+	if pending, ok := o.hub.TakePendingIntent(channel, threadTS); ok && isApprovalText(lastUserMessage(messages)) {
+		slog.InfoContext(ctx, "orchestrator: intent confirmed", "repo", pending.Repo, "task", pending.Task)
+		return o.dispatchIntent(ctx, pending, onJobCreated)
+	}
+
+	intent, err := ParseIntent(ctx, o.llm, messages)
 	if err != nil {
 		return OrchestratorResult{}, fmt.Errorf("parse intent: %w", err)
 	}
-	log.Printf("orchestrator: intent: repo=%q task=%q question=%q", intent.Repo, intent.Task, intent.Question)
+	slog.InfoContext(ctx, "orchestrator: intent parsed", "repo", intent.Repo, "task", intent.Task, "question", intent.Question, "confidence", intent.Confidence, "destructive", intent.Destructive)
 
 	if intent.Question != "" {
 		return OrchestratorResult{Text: intent.Question}, nil
@@ -71,166 +119,342 @@ func (o *Orchestrator) HandleNewRequest(ctx context.Context, messages []Message,
 	// Fall back to channel default repo if intent parsing didn't extract one.
 	if intent.Repo == "" && defaultRepo != "" {
 		intent.Repo = defaultRepo
-		log.Printf("orchestrator: using channel default repo %q", defaultRepo)
+		slog.InfoContext(ctx, "orchestrator: using channel default repo", "repo", defaultRepo)
 	}
 
-	if intent.Repo == "" || intent.Task == "" {
-		return OrchestratorResult{Text: "I couldn't determine the repository or task from your message. Could you please specify which repository you'd like me to work on and what changes you'd like me to make?"}, nil
+	if reason := intent.confirmationReason(); reason != "" && intent.ReviewPR == 0 && !intent.Explain && !intent.PRStatus && intent.Repo != "" && channel != "" {
+		o.hub.SetPendingIntent(channel, threadTS, intent)
+		return OrchestratorResult{Text: formatIntentConfirmation(intent, reason)}, nil
 	}
+	return o.dispatchIntent(ctx, intent, onJobCreated)
+}
 
-	// Validate repo name format (alphanumeric, hyphens, underscores, periods).
-	if !isValidRepoName(intent.Repo) {
-		return OrchestratorResult{Text: "The repository name I extracted doesn't look valid. Could you specify the repository name more clearly?"}, nil
+// dispatchIntent starts the job a parsed request asks for: a review, an
+// answer to a question about the code, a follow-up on the thread's pull request, or a new job.
+// Pull request status questions and comments are answered without a job.
+func (o *Orchestrator) dispatchIntent(ctx context.Context, intent IntentResult, onJobCreated func(jobID string)) (OrchestratorResult, error) {
+	if intent.ReviewPR > 0 {
+		return o.startReview(ctx, intent, onJobCreated)
+	}
+	if intent.Explain {
+		return o.startExplain(ctx, intent, onJobCreated)
+	}
+	if intent.PRStatus {
+		return o.answerPRStatus(ctx, intent)
+	}
+	if intent.PRComment {
+		return o.commentOnPR(ctx, intent)
 	}
+	if reply := o.withTicket(ctx, &intent); reply != "" {
+		return OrchestratorResult{Text: reply}, nil
+	}
+	intent.PRFooter += workItemFooter(intent.Task)
 
-	// Truncate excessively long task descriptions.
-	if len(intent.Task) > maxTaskLen {
-		intent.Task = intent.Task[:maxTaskLen]
+	// A follow-up in a thread that already has a PR for this repo updates it.
+	channel, _ := ctx.Value(ctxKeyChannel).(string)
+	threadTS, _ := ctx.Value(ctxKeyThreadTS).(string)
+	if pr, ok := o.hub.ThreadPR(channel, threadTS); ok && (intent.Repo == "" || intent.Repo == pr.Repo) {
+		intent.Repo = pr.Repo
+		return o.startJob(ctx, intent, "", &pr, onJobCreated)
 	}
+	return o.startJob(ctx, intent, "", nil, onJobCreated)
+}
 
-	// Check repo allowlist if configured.
-	if len(o.allowedRepos) > 0 && !o.allowedRepos[intent.Repo] {
-		return OrchestratorResult{Text: fmt.Sprintf("Repository %q is not in the allowed list.", intent.Repo)}, nil
+// lastUserMessage returns the text of the newest user message.
+func lastUserMessage(messages []Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == RoleUser {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+// HandleDirectRequest starts a job from an explicit repo and task, skipping intent
+// parsing. Used by the REST API. intent.BaseBranch may be empty for the repo's
+// default, and intent.Paths must already be cleaned. If plan is non-empty, the
+// planning session is skipped and the job goes straight to awaiting_approval
+// with that plan.
+func (o *Orchestrator) HandleDirectRequest(ctx context.Context, intent IntentResult, plan string, onJobCreated func(jobID string)) (OrchestratorResult, error) {
+	return o.startJob(ctx, intent, plan, nil, onJobCreated)
+}
+
+// startJob validates the request, creates the job, prepares its worktree, and
+// runs the planning session (or adopts plan, if given). If pr is set, the
+// worktree starts from the PR's branch and the job pushes to it on approval.
+func (o *Orchestrator) startJob(ctx context.Context, intent IntentResult, plan string, pr *threadPR, onJobCreated func(jobID string)) (result OrchestratorResult, err error) {
+	if reject := o.validateIntent(ctx, &intent); reject != "" {
+		return OrchestratorResult{Text: reject}, nil
 	}
 
-	// Verify repo exists via GitHub API.
-	if _, err := FindRepo(ctx, o.githubToken, o.githubOwner, intent.Repo); err != nil {
-		return OrchestratorResult{Text: fmt.Sprintf("I couldn't find the repository *%s* in the GitHub organization. Please check the repository name and try again.", intent.Repo)}, nil
+	// Verify repo exists with one of the configured providers.
+	vcs, r, err := findRepo(ctx, o.providers, intent.Repo)
+	if err != nil {
+		return OrchestratorResult{Text: fmt.Sprintf("I couldn't find the repository *%s*. Please check the repository name and try again.", intent.Repo)}, nil
+	}
+	if r.Name != "" && r.Name != intent.Repo {
+		// Matched loosely; continue with the canonical name.
+		intent.Repo = r.Name
+		if reject := o.validateIntent(ctx, &intent); reject != "" {
+			return OrchestratorResult{Text: reject}, nil
+		}
 	}
 
 	channel, _ := ctx.Value(ctxKeyChannel).(string)
 	threadTS, _ := ctx.Value(ctxKeyThreadTS).(string)
 
-	jobID := o.createJob(intent, channel, threadTS)
+	jobID := o.createJob(intent, vcs, channel, threadTS, PlatformFromCtx(ctx), UserFromCtx(ctx), UserNameFromCtx(ctx), CoAuthorFromCtx(ctx))
 	if onJobCreated != nil {
 		onJobCreated(jobID)
 	}
-	jobCtx := WithJobID(ctx, jobID)
-	jobCtx = WithHub(jobCtx, o.hub)
-
-	// Emit intent cost.
-	intentCost := computeIntentCost(intent.InputTokens, intent.OutputTokens, intent.CacheReadTokens, intent.CacheWriteTokens)
-	o.hub.Emit(jobID, EventLLMResponse, map[string]any{
-		"stop_reason":        "end_turn",
-		"summary":            "intent parsed",
-		"input_tokens":       intent.InputTokens,
-		"output_tokens":      intent.OutputTokens,
-		"cache_read_tokens":  intent.CacheReadTokens,
-		"cache_write_tokens": intent.CacheWriteTokens,
-		"cost_usd":           intentCost,
-	})
+	jobCtx, release := o.jobContext(ctx, jobID)
+	defer release()
+	defer o.replaceIfCancelled(jobID, &result, &err)
+	defer o.recoverJob(ctx, jobID, &result, &err)
+	o.startTicket(jobCtx, intent.Ticket)
+
+	for _, r := range intent.Retries {
+		o.hub.Emit(jobID, r)
+	}
+	// Emit intent cost (zero when intent parsing was skipped).
+	intentCost := intent.CostUSD
+	if intent.InputTokens > 0 || intent.OutputTokens > 0 {
+		o.hub.Emit(jobID, LLMResponseData{
+			Model:            intent.Model,
+			StopReason:       "end_turn",
+			Summary:          "intent parsed",
+			InputTokens:      intent.InputTokens,
+			OutputTokens:     intent.OutputTokens,
+			CacheReadTokens:  intent.CacheReadTokens,
+			CacheWriteTokens: intent.CacheWriteTokens,
+			CostUSD:          intentCost,
+			Thinking:         intent.Thinking,
+		})
+	}
 
 	startTime := time.Now()
 
-	// Ensure base clone exists and fetch latest main.
-	log.Printf("orchestrator: ensuring base clone for %s", intent.Repo)
-	o.hub.Emit(jobID, EventToolStarted, map[string]any{"tool_name": "clone_repo", "input": intent.Repo})
-	cloneStart := time.Now()
-	baseDir, err := EnsureBaseClone(jobCtx, o.githubOwner, o.githubToken, intent.Repo)
+	// Ensure base clone exists and fetch the latest base branch.
+	slog.InfoContext(jobCtx, "orchestrator: ensuring base clone")
+	unlockClone := lockBaseClone(intent.Repo)
+	defer unlockClone()
+	var (
+		baseDir, base string
+		cfg           *RepoConfig
+		scopeErr      error
+	)
+	_, err = o.runStep(jobCtx, jobID, "clone_repo", intent.Repo, func(ctx context.Context) (string, error) {
+		var err error
+		if baseDir, cfg, base, err = o.prepareBaseClone(ctx, vcs, intent.Repo, r.DefaultBranch, intent.BaseBranch); err != nil {
+			return "", err
+		}
+		if cfg, scopeErr = cfg.scopedTo(intent.Paths); scopeErr != nil {
+			return "", scopeErr
+		}
+		startBranch := base
+		if pr != nil {
+			// The PR may have been merged and its branch deleted; start over then.
+			if err := FetchBranch(ctx, baseDir, vcs, intent.Repo, pr.Branch); err != nil {
+				slog.WarnContext(ctx, "orchestrator: can't fetch follow-up branch, opening a new PR", "branch", pr.Branch, "pr", pr.URL, "err", err)
+				pr = nil
+				if err := FetchBranch(ctx, baseDir, vcs, intent.Repo, base); err != nil {
+					return "", err
+				}
+			} else {
+				startBranch = pr.Branch
+			}
+		}
+		return "base clone ready (" + startBranch + ")", nil
+	})
 	if err != nil {
-		o.hub.Emit(jobID, EventToolCompleted, map[string]any{
-			"tool_name": "clone_repo", "is_error": true,
-			"result_preview": err.Error(), "duration_ms": time.Since(cloneStart).Milliseconds(),
-		})
-		o.closeJob(ctx, jobID, EventJobError, map[string]any{
-			"error": err.Error(), "total_duration_ms": time.Since(startTime).Milliseconds(), "total_cost_usd": intentCost,
+		o.closeJob(ctx, jobID, JobErrorData{
+			Error: err.Error(), TotalDurationMs: time.Since(startTime).Milliseconds(), TotalCostUSD: intentCost,
 		})
+		if scopeErr != nil {
+			return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("I can't limit this job to the paths you gave: %s.", err.Error())}, nil
+		}
 		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("I ran into an error cloning the repository: %s", err.Error())}, nil
 	}
-	o.hub.Emit(jobID, EventToolCompleted, map[string]any{
-		"tool_name": "clone_repo", "is_error": false,
-		"result_preview": "base clone ready", "duration_ms": time.Since(cloneStart).Milliseconds(),
-	})
 
-	// Create per-job worktree from latest main.
+	// Create per-job worktree from the latest base branch (or the PR's branch).
 	repoDir, err := CreateWorktree(jobCtx, baseDir, jobID)
+	unlockClone()
 	if err != nil {
-		o.closeJob(ctx, jobID, EventJobError, map[string]any{
-			"error": err.Error(), "total_duration_ms": time.Since(startTime).Milliseconds(), "total_cost_usd": intentCost,
+		o.closeJob(ctx, jobID, JobErrorData{
+			Error: err.Error(), TotalDurationMs: time.Since(startTime).Milliseconds(), TotalCostUSD: intentCost,
 		})
 		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("Failed to create worktree: %s", err.Error())}, nil
 	}
+	if cfg.SparseCheckout && len(cfg.Paths) > 0 {
+		if err := SparseCheckout(jobCtx, repoDir, cfg.Paths); err != nil {
+			// The full checkout still works; the scope is enforced before the PR either way.
+			slog.WarnContext(jobCtx, "orchestrator: sparse checkout failed, keeping the full worktree", "err", err)
+		}
+	}
 
 	// Store paths in job state.
 	state, _ := o.hub.GetJobState(jobID)
 	state.mu.Lock()
 	state.RepoDir = repoDir
 	state.BaseDir = baseDir
+	state.BaseBranch = base
+	state.repoConfig = cfg
+	if pr != nil {
+		state.PRURL, state.PRBranch = pr.URL, pr.Branch
+	}
 	state.mu.Unlock()
 
+	// Caller supplied a plan — skip the planning session.
+	if plan != "" {
+		return o.processSessionResult(ctx, jobID, &SessionResult{ResultText: plan}, repoDir)
+	}
+
+	// Issue and ticket text is as untrusted as the repo's files.
+	o.recordInjections(jobCtx, jobID, append(scanInjection("request", intent.Task), scanInjection("ticket", intent.Context)...))
+
 	// Run planning session.
-	log.Printf("orchestrator: starting planning session for %s", intent.Repo)
-	o.hub.Emit(jobID, EventToolStarted, map[string]any{"tool_name": "generate_plan", "input": intent.Task})
-	planStart := time.Now()
-
-	sr, err := RunSession(jobCtx, o.claudeCodeToken, o.hub, jobID, SessionOpts{
-		RepoDir:        repoDir,
-		Prompt:         fmt.Sprintf("## Task\n\n%s", intent.Task),
-		SystemPrompt:   planSystemPrompt,
-		PermissionMode: "plan",
+	slog.InfoContext(jobCtx, "orchestrator: starting planning session")
+	planPrompt := fmt.Sprintf("%s%s%s## Task\n\n%s", cfg.promptPreamble(), followUpPreamble(pr), intent.Context, intent.Task)
+	state.mu.Lock()
+	state.PlanPrompt = planPrompt
+	state.mu.Unlock()
+	var sr *SessionResult
+	_, err = o.runStep(jobCtx, jobID, "generate_plan", intent.Task, func(ctx context.Context) (string, error) {
+		var err error
+		sr, err = RunSession(ctx, o.claudeCodeToken, o.hub, jobID, SessionOpts{
+			RepoDir:        repoDir,
+			Prompt:         planPrompt,
+			SystemPrompt:   planSystemPrompt,
+			PermissionMode: "plan",
+			Tools:          o.toolRules(cfg, toolPhasePlan),
+			GuardInjection: true,
+			Sandbox:        o.sandboxFor(intent.Repo, cfg),
+			Limits:         o.sessionLimits(ctx, jobID, intent.Repo),
+		})
+		return sessionPreview(sr, err)
 	})
-	planDurationMs := time.Since(planStart).Milliseconds()
 	if err != nil {
-		o.hub.Emit(jobID, EventToolCompleted, map[string]any{
-			"tool_name": "generate_plan", "is_error": true,
-			"result_preview": truncate(err.Error(), 300), "duration_ms": planDurationMs,
-		})
-		o.closeJob(ctx, jobID, EventJobError, map[string]any{
-			"error": err.Error(), "total_duration_ms": time.Since(startTime).Milliseconds(), "total_cost_usd": intentCost,
+		o.closeJob(ctx, jobID, JobErrorData{
+			Error: err.Error(), TotalDurationMs: time.Since(startTime).Milliseconds(), TotalCostUSD: intentCost,
 		})
 		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("Claude Code encountered an error during planning: %s", err.Error())}, nil
 	}
-	o.hub.Emit(jobID, EventToolCompleted, map[string]any{
-		"tool_name": "generate_plan", "is_error": false,
-		"result_preview": truncate(sr.ResultText, 300), "duration_ms": planDurationMs,
-	})
+
+	if cost, over := o.overBudget(jobID); over {
+		return o.abortOverBudget(ctx, jobID, cost), nil
+	}
 
 	return o.processSessionResult(ctx, jobID, sr, repoDir)
 }
 
+// validateIntent checks the repo and task of a request before any job is created,
+// truncating an overlong task in place. It returns a user-facing rejection, or
+// empty string if the request may proceed.
+func (o *Orchestrator) validateIntent(ctx context.Context, intent *IntentResult) string {
+	if intent.Repo == "" || intent.Task == "" {
+		return "I couldn't determine the repository or task from your message. Could you please specify which repository you'd like me to work on and what changes you'd like me to make?"
+	}
+
+	// Validate repo name format (alphanumeric, hyphens, underscores, periods).
+	if !isValidRepoName(intent.Repo) {
+		return "The repository name I extracted doesn't look valid. Could you specify the repository name more clearly?"
+	}
+
+	if intent.BaseBranch != "" && !isValidBranchName(intent.BaseBranch) {
+		return fmt.Sprintf("%q doesn't look like a valid branch name.", intent.BaseBranch)
+	}
+	paths, err := cleanScopePaths(intent.Paths)
+	if err != nil {
+		return fmt.Sprintf("I can only limit changes to paths inside the repository: %s.", err)
+	}
+	intent.Paths = paths
+	intent.PROptions = PROptions{}.merge(intent.PROptions) // trims "@" and caps the lists
+
+	// Truncate excessively long task descriptions.
+	if len(intent.Task) > maxTaskLen {
+		intent.Task = prefix(intent.Task, maxTaskLen)
+	}
+
+	// Check repo allowlist if configured.
+	if len(o.allowedRepos) > 0 && !o.allowedRepos[intent.Repo] {
+		return fmt.Sprintf("Repository %q is not in the allowed list.", intent.Repo)
+	}
+	if reject := o.checkAccess(ctx, intent.Repo); reject != "" {
+		return reject
+	}
+	return o.workspaceFull(ctx)
+}
+
 // HandleReply continues a planning session with user input (answer to question or plan feedback).
-func (o *Orchestrator) HandleReply(ctx context.Context, jobID, userText string) (OrchestratorResult, error) {
+func (o *Orchestrator) HandleReply(ctx context.Context, jobID, userText string) (result OrchestratorResult, err error) {
 	state, ok := o.hub.GetJobState(jobID)
 	if !ok {
 		return OrchestratorResult{}, fmt.Errorf("no state for job %s", jobID)
 	}
 
-	// If the user is giving feedback on an approved plan, transition back to planning.
-	if state.Phase == PhaseAwaitingApproval {
+	// If the user is giving feedback on a plan, or on a dry run's changes,
+	// transition back to planning.
+	if state.Phase == PhaseAwaitingApproval || state.Phase == PhaseAwaitingPush {
+		state.mu.Lock()
+		state.pending = nil
+		state.mu.Unlock()
 		o.hub.SetPhase(jobID, PhasePlanning)
-		o.hub.Emit(jobID, EventPlanSuperseded, nil)
+		o.hub.Emit(jobID, PlanSupersededData{})
 	}
 
+	// An answer to a question goes to the planning session that asked it. If
+	// that session is gone, a new one starts from the first prompt and the
+	// answers so far, in the same worktree.
+	var restart string
+	if state.Phase == PhaseAwaitingQuestion {
+		state.recordAnswer(userText)
+		state.mu.Lock()
+		restart = resumePlanPrompt(state.PlanPrompt, state.Clarifications)
+		state.mu.Unlock()
+		o.hub.SetPhase(jobID, PhasePlanning)
+	}
 	state.mu.Lock()
-	repoDir := state.RepoDir
+	repoDir, sessionID := state.RepoDir, state.SessionID
 	state.mu.Unlock()
 
-	jobCtx := WithJobID(ctx, jobID)
-	jobCtx = WithHub(jobCtx, o.hub)
+	jobCtx, release := o.jobContext(ctx, jobID)
+	defer release()
+	defer o.replaceIfCancelled(jobID, &result, &err)
+	defer o.recoverJob(ctx, jobID, &result, &err)
 
-	log.Printf("orchestrator: resuming planning session %s for job %s", state.SessionID, jobID)
-	o.hub.Emit(jobID, EventToolStarted, map[string]any{"tool_name": "generate_plan", "input": userText})
-	planStart := time.Now()
+	cfg, err := o.repoConfig(jobCtx, jobID)
+	if err != nil {
+		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("I couldn't read the repo's %s: %s", repoConfigFile, err.Error())}, nil
+	}
 
-	sr, err := RunSession(jobCtx, o.claudeCodeToken, o.hub, jobID, SessionOpts{
-		RepoDir:        repoDir,
-		Prompt:         userText,
-		SessionID:      state.SessionID,
-		PermissionMode: "plan",
-		// No SystemPrompt on resume — already in session context.
+	slog.InfoContext(jobCtx, "orchestrator: resuming planning session", "session_id", sessionID)
+	var sr *SessionResult
+	_, err = o.runStep(jobCtx, jobID, "generate_plan", userText, func(ctx context.Context) (string, error) {
+		opts := SessionOpts{
+			RepoDir:        repoDir,
+			Prompt:         userText,
+			SessionID:      sessionID,
+			PermissionMode: "plan",
+			Tools:          o.toolRules(cfg, toolPhasePlan),
+			GuardInjection: true,
+			Sandbox:        o.sandboxFor(state.Repo, cfg),
+			Limits:         o.sessionLimits(ctx, jobID, state.Repo),
+			// No SystemPrompt on resume — already in session context.
+		}
+		var err error
+		if sessionID != "" || restart == "" {
+			sr, err = RunSession(ctx, o.claudeCodeToken, o.hub, jobID, opts)
+		}
+		if restart != "" && (sessionID == "" || err != nil && isMissingSession(err)) {
+			slog.WarnContext(ctx, "orchestrator: planning session can't be resumed, starting a new one with the answers so far", "job_id", jobID, "session_id", sessionID)
+			opts.Prompt, opts.SystemPrompt, opts.SessionID = restart, planSystemPrompt, ""
+			sr, err = RunSession(ctx, o.claudeCodeToken, o.hub, jobID, opts)
+		}
+		return sessionPreview(sr, err)
 	})
-	planDurationMs := time.Since(planStart).Milliseconds()
 	if err != nil {
-		o.hub.Emit(jobID, EventToolCompleted, map[string]any{
-			"tool_name": "generate_plan", "is_error": true,
-			"result_preview": truncate(err.Error(), 300), "duration_ms": planDurationMs,
-		})
 		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("Claude Code encountered an error: %s", err.Error())}, nil
 	}
-	o.hub.Emit(jobID, EventToolCompleted, map[string]any{
-		"tool_name": "generate_plan", "is_error": false,
-		"result_preview": truncate(sr.ResultText, 300), "duration_ms": planDurationMs,
-	})
 
 	// Update session ID if it changed.
 	if sr.SessionID != "" {
@@ -239,11 +463,15 @@ func (o *Orchestrator) HandleReply(ctx context.Context, jobID, userText string)
 		state.mu.Unlock()
 	}
 
+	if cost, over := o.overBudget(jobID); over {
+		return o.abortOverBudget(ctx, jobID, cost), nil
+	}
+
 	return o.processSessionResult(ctx, jobID, sr, repoDir)
 }
 
 // HandleApproval runs implementation for an approved plan.
-func (o *Orchestrator) HandleApproval(ctx context.Context, jobID string) (OrchestratorResult, error) {
+func (o *Orchestrator) HandleApproval(ctx context.Context, jobID string) (result OrchestratorResult, err error) {
 	state, ok := o.hub.GetJobState(jobID)
 	if !ok {
 		return OrchestratorResult{}, fmt.Errorf("no state for job %s", jobID)
@@ -256,92 +484,282 @@ func (o *Orchestrator) HandleApproval(ctx context.Context, jobID string) (Orches
 	planContent := state.PlanContent
 	repoDir := state.RepoDir
 	baseDir := state.BaseDir
+	base := state.BaseBranch
+	vcs := state.vcs
+	prURL, prBranch := state.PRURL, state.PRBranch
+	sessionID := state.SessionID
+	dryRun := state.DryRun
+	wantChangelog := state.Changelog
 	state.mu.Unlock()
 
-	jobCtx := WithJobID(ctx, jobID)
-	jobCtx = WithHub(jobCtx, o.hub)
+	jobCtx, release := o.jobContext(ctx, jobID)
+	defer release()
+	defer o.replaceIfCancelled(jobID, &result, &err)
+	defer o.recoverJob(ctx, jobID, &result, &err)
 
 	startTime := time.Now()
 
-	// Reset worktree to latest main before implementation.
-	if err := ResetWorktree(jobCtx, baseDir, repoDir, o.githubToken, o.githubOwner, filepath.Base(repo)); err != nil {
+	cfg, err := o.repoConfig(jobCtx, jobID)
+	if err != nil {
 		o.hub.ClearImplementation(jobID)
-		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("Failed to reset worktree: %s", err.Error())}, nil
+		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("I couldn't read the repo's %s: %s", repoConfigFile, err.Error())}, nil
+	}
+	changelog := changelogFor(cfg, wantChangelog)
+	cfg = cfg.withChangelog(changelog)
+	if prBranch != "" {
+		changelog = "" // a follow-up's PR has its entry already
 	}
 
-	prompt := fmt.Sprintf("## Task\n\n%s\n\n## Approved Plan\n\n%s", task, planContent)
-
-	log.Printf("orchestrator: starting implementation session for job %s", jobID)
-	o.hub.Emit(jobID, EventToolStarted, map[string]any{"tool_name": "implement_changes", "input": task})
-	implStart := time.Now()
+	// Reset worktree to the latest base branch (or the PR's branch, for a
+	// follow-up) before implementation.
+	if base == "" {
+		base = resolveBaseBranch("", cfg, "") // restored from before base branches were recorded
+	}
+	startBranch := base
+	if prBranch != "" {
+		startBranch = prBranch
+	}
+	saved, err := ResetWorktree(jobCtx, baseDir, repoDir, vcs, repo, startBranch)
+	if saved != nil {
+		o.preserveWork(jobID, saved)
+	}
+	if err != nil {
+		o.hub.ClearImplementation(jobID)
+		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("Failed to reset worktree: %s", err.Error())}, nil
+	}
 
-	sr, err := RunSession(jobCtx, o.claudeCodeToken, o.hub, jobID, SessionOpts{
-		RepoDir:        repoDir,
-		Prompt:         prompt,
-		SystemPrompt:   executeSystemPrompt,
-		PermissionMode: "acceptEdits",
-		// Fresh session — no --resume.
+	var pr *threadPR
+	if prBranch != "" {
+		pr = &threadPR{URL: prURL, Branch: prBranch}
+	}
+	testCommand, _ := testCommandFor(repoDir, cfg)
+	prompt := fmt.Sprintf("%s%s%s%s## Task\n\n%s\n\n## Approved Plan\n\n%s", cfg.promptPreamble(), followUpPreamble(pr), testCommandPreamble(testCommand), changelogPreamble(changelog), task, planContent)
+
+	slog.InfoContext(jobCtx, "orchestrator: starting implementation session", "session_id", sessionID)
+	var sr *SessionResult
+	_, err = o.runStep(jobCtx, jobID, "implement_changes", task, func(ctx context.Context) (string, error) {
+		var err error
+		sr, err = RunSession(ctx, o.claudeCodeToken, o.hub, jobID, SessionOpts{
+			RepoDir:        repoDir,
+			Prompt:         prompt,
+			SystemPrompt:   executeSystemPrompt,
+			PermissionMode: "acceptEdits",
+			Tools:          o.toolRules(cfg, toolPhaseImplement),
+			Sandbox:        o.sandboxFor(repo, cfg),
+			Limits:         o.sessionLimits(ctx, jobID, repo),
+			// Resume the planning session so the codebase exploration is already
+			// in context; the prompt carries the full plan in case it's gone.
+			SessionID:      sessionID,
+			ResumeFallback: true,
+		})
+		return sessionPreview(sr, err)
 	})
-	implDurationMs := time.Since(implStart).Milliseconds()
 	if err != nil {
-		o.hub.Emit(jobID, EventToolCompleted, map[string]any{
-			"tool_name": "implement_changes", "is_error": true,
-			"result_preview": truncate(err.Error(), 300), "duration_ms": implDurationMs,
-		})
-		o.closeJob(ctx, jobID, EventJobError, map[string]any{
-			"error": err.Error(), "total_duration_ms": time.Since(startTime).Milliseconds(),
+		o.closeJob(ctx, jobID, JobErrorData{
+			Error: err.Error(), TotalDurationMs: time.Since(startTime).Milliseconds(),
 		})
 		o.hub.ClearImplementation(jobID)
 		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("Claude Code encountered an error: %s", err.Error())}, nil
 	}
-	o.hub.Emit(jobID, EventToolCompleted, map[string]any{
-		"tool_name": "implement_changes", "is_error": false,
-		"result_preview": truncate(sr.ResultText, 300), "duration_ms": implDurationMs,
-	})
 
 	if sr.IsError {
-		o.closeJob(ctx, jobID, EventJobError, map[string]any{
-			"error": sr.ResultText, "total_duration_ms": time.Since(startTime).Milliseconds(),
+		o.closeJob(ctx, jobID, JobErrorData{
+			Error: sr.ResultText, TotalDurationMs: time.Since(startTime).Milliseconds(),
 		})
 		o.hub.ClearImplementation(jobID)
 		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("Claude Code reported an error: %s", sr.ResultText)}, nil
 	}
 
-	// Create PR.
-	log.Printf("orchestrator: creating pull request for %s", repo)
-	branch := taskBranchName(task)
-	title := task
-	if len(title) > 72 {
-		title = title[:72]
+	if cost, over := o.overBudget(jobID); over {
+		o.hub.ClearImplementation(jobID)
+		return o.abortOverBudget(ctx, jobID, cost), nil
+	}
+	if size, over := o.overDiskQuota(jobID); over {
+		o.hub.ClearImplementation(jobID)
+		return o.abortOverDiskQuota(ctx, jobID, size), nil
+	}
+
+	// Run the tests and let Claude Code fix failures before opening the PR.
+	vr, err := o.verifyChanges(jobCtx, jobID, repo, repoDir, task, planContent, cfg)
+	if err != nil {
+		o.closeJob(ctx, jobID, JobErrorData{
+			Error: err.Error(), TotalDurationMs: time.Since(startTime).Milliseconds(),
+		})
+		o.hub.ClearImplementation(jobID)
+		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("Changes were implemented but I couldn't run the tests: %s", err.Error())}, nil
+	}
+	if cost, over := o.overBudget(jobID); over {
+		o.hub.ClearImplementation(jobID)
+		return o.abortOverBudget(ctx, jobID, cost), nil
+	}
+	if size, over := o.overDiskQuota(jobID); over {
+		o.hub.ClearImplementation(jobID)
+		return o.abortOverDiskQuota(ctx, jobID, size), nil
+	}
+
+	o.ensureChangelogEntry(jobCtx, jobID, repoDir, changelog, task)
+
+	// Drop changes outside the repo's configured paths.
+	if err := o.enforceScope(jobCtx, jobID, repoDir, cfg); err != nil {
+		o.closeJob(ctx, jobID, JobErrorData{
+			Error: err.Error(), TotalDurationMs: time.Since(startTime).Milliseconds(),
+		})
+		o.hub.ClearImplementation(jobID)
+		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("Changes were implemented but I couldn't limit them to the configured paths: %s", err.Error())}, nil
 	}
-	o.hub.Emit(jobID, EventToolStarted, map[string]any{"tool_name": "create_pull_request", "input": repo})
-	prStart := time.Now()
-	prURL, err := CreatePullRequest(jobCtx, o.githubOwner, o.githubToken, repo, repoDir, title, branch, sr.ResultText)
-	prDurationMs := time.Since(prStart).Milliseconds()
+
+	// Scan the changes for security issues, letting Claude Code fix findings
+	// if the repo asks for it. Remaining findings go in the PR body.
+	scan, err := o.securityScan(jobCtx, jobID, repo, repoDir, task, planContent, cfg, &vr)
 	if err != nil {
-		o.hub.Emit(jobID, EventToolCompleted, map[string]any{
-			"tool_name": "create_pull_request", "is_error": true,
-			"result_preview": err.Error(), "duration_ms": prDurationMs,
+		o.closeJob(ctx, jobID, JobErrorData{
+			Error: err.Error(), TotalDurationMs: time.Since(startTime).Milliseconds(),
+		})
+		o.hub.ClearImplementation(jobID)
+		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("Changes were implemented but the security scan failed: %s", err.Error())}, nil
+	}
+	if cost, over := o.overBudget(jobID); over {
+		o.hub.ClearImplementation(jobID)
+		return o.abortOverBudget(ctx, jobID, cost), nil
+	}
+
+	if dryRun || cfg.DryRun {
+		return o.awaitPush(jobCtx, jobID, repoDir, pendingPush{FinalResponse: sr.ResultText, Verify: vr, Scan: scan, StartTime: startTime}), nil
+	}
+	return o.publishChanges(ctx, jobCtx, jobID, cfg, pendingPush{FinalResponse: sr.ResultText, Verify: vr, Scan: scan, StartTime: startTime}), nil
+}
+
+// publishChanges commits the implemented changes and opens a pull request, or
+// pushes them to the existing one for a follow-up, then closes the job.
+func (o *Orchestrator) publishChanges(ctx, jobCtx context.Context, jobID string, cfg *RepoConfig, p pendingPush) OrchestratorResult {
+	state, _ := o.hub.GetJobState(jobID)
+	state.mu.Lock()
+	repo, task, repoDir, base := state.Repo, state.Task, state.RepoDir, state.BaseBranch
+	vcs := state.vcs
+	prURL, prBranch := state.PRURL, state.PRBranch
+	prHints := state.PRHints
+	coAuthor := state.CoAuthor
+	channel, threadTS := state.Channel, state.ThreadTS
+	platform, requester := state.Platform, state.RequestedBy
+	footer, ticket := state.PRFooter, state.Ticket
+	state.mu.Unlock()
+	if base == "" {
+		base = resolveBaseBranch("", cfg, "")
+	}
+	vr, startTime := p.Verify, p.StartTime
+
+	// Hold changes that look like they contain credentials until the user
+	// says how to proceed; "push it" comes back here with SecretsApproved.
+	if !p.SecretsApproved {
+		findings, err := o.checkSecrets(jobCtx, jobID, repoDir)
+		if err != nil {
+			o.closeJob(ctx, jobID, JobErrorData{
+				Error: err.Error(), TotalDurationMs: time.Since(startTime).Milliseconds(),
+			})
+			o.hub.ClearImplementation(jobID)
+			return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("Changes were implemented but I couldn't check them for secrets: %s", err.Error())}
+		}
+		if len(findings) > 0 {
+			p.SecretsApproved = true
+			state.mu.Lock()
+			state.pending = &p
+			state.mu.Unlock()
+			o.hub.SetPhase(jobID, PhaseAwaitingPush)
+			return OrchestratorResult{IsJob: true, JobID: jobID, Text: formatSecretFindings(findings), SecretFindings: findings}
+		}
+	}
+
+	// Create PR, or push to the existing one for a follow-up.
+	var err error
+	subject, _, _ := strings.Cut(task, "\n")
+	title := truncateWords(subject, maxCommitSubject)
+	commit := commitSpec{Task: task, Author: o.gitAuthor, CoAuthor: coAuthor}
+	branch := prBranch
+	if prBranch != "" {
+		slog.InfoContext(jobCtx, "orchestrator: pushing follow-up changes", "pr", prURL)
+		_, err = o.runStep(jobCtx, jobID, "update_pull_request", prURL, func(ctx context.Context) (string, error) {
+			return prURL, PushToBranch(ctx, vcs, repo, repoDir, commit, prBranch)
+		})
+	} else {
+		slog.InfoContext(jobCtx, "orchestrator: creating pull request")
+		branch = taskBranchName(task)
+		if ticket != "" {
+			branch = ticketBranchName(ticket, task)
+		}
+		description := o.prDescription(jobCtx, jobID, repoDir, task, p.FinalResponse, vr)
+		prURL, err = o.runStep(jobCtx, jobID, "create_pull_request", repo, func(ctx context.Context) (string, error) {
+			url, pushed, err := CreatePullRequest(ctx, vcs, repo, repoDir, title, branch, base, vr.prBodyNote()+p.Scan.prBodyNote()+description+footer, commit, cfg.prOptions().merge(prHints))
+			branch = pushed
+			return url, err
+		})
+	}
+	if errors.Is(err, ErrNoChanges) {
+		// Nothing to propose isn't a failure: the session may have found the
+		// code already does what was asked.
+		o.closeJob(ctx, jobID, JobCompletedData{
+			FinalResponse:   p.FinalResponse,
+			TotalDurationMs: time.Since(startTime).Milliseconds(),
 		})
-		o.closeJob(ctx, jobID, EventJobError, map[string]any{
-			"error": err.Error(), "total_duration_ms": time.Since(startTime).Milliseconds(),
+		o.hub.SetPhase(jobID, PhaseDone)
+		text := "There are no changes to propose, so I didn't open a pull request."
+		if prBranch != "" {
+			text = fmt.Sprintf("There are no new changes to push to %s.", prURL)
+		}
+		if summary := strings.TrimSpace(p.FinalResponse); summary != "" {
+			text += "\n\n" + summary
+		}
+		return OrchestratorResult{IsJob: true, JobID: jobID, Text: text}
+	}
+	if err != nil {
+		o.closeJob(ctx, jobID, JobErrorData{
+			Error: err.Error(), TotalDurationMs: time.Since(startTime).Milliseconds(),
 		})
 		o.hub.ClearImplementation(jobID)
-		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("Changes were implemented but I couldn't create the pull request: %s", err.Error())}, nil
+		if prBranch != "" {
+			return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("Changes were implemented but I couldn't push them to %s: %s", prURL, err.Error())}
+		}
+		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("Changes were implemented but I couldn't create the pull request: %s", err.Error())}
+	}
+	o.hub.RecordThreadPR(channel, threadTS, threadPR{JobID: jobID, Repo: repo, URL: prURL, Branch: branch, Platform: platform, RequestedBy: requester})
+	if prBranch == "" {
+		o.linkTicket(jobCtx, ticket, prURL)
 	}
-	o.hub.Emit(jobID, EventToolCompleted, map[string]any{
-		"tool_name": "create_pull_request", "is_error": false,
-		"result_preview": prURL, "duration_ms": prDurationMs,
-	})
 
-	o.closeJob(ctx, jobID, EventJobCompleted, map[string]any{
-		"final_response":    sr.ResultText,
-		"pr_url":            prURL,
-		"total_duration_ms": time.Since(startTime).Milliseconds(),
+	testsPassed := vr.Command == "" || vr.Passed
+	o.closeJob(ctx, jobID, JobCompletedData{
+		FinalResponse:   p.FinalResponse,
+		PRURL:           prURL,
+		TestsPassed:     &testsPassed,
+		TotalDurationMs: time.Since(startTime).Milliseconds(),
+		Artifacts:       o.saveDiff(jobCtx, jobID, repoDir),
 	})
 
 	o.hub.SetPhase(jobID, PhaseDone)
-	return OrchestratorResult{IsJob: true, JobID: jobID, PRURL: prURL}, nil
+	verb := "Opened"
+	if prBranch != "" {
+		verb = "Pushed the changes to"
+	}
+	if vr.Command != "" && !vr.Passed {
+		return OrchestratorResult{IsJob: true, JobID: jobID, PRURL: prURL,
+			Text: fmt.Sprintf("%s %s, but `%s` still fails after %d fix attempt(s) — please take a look.", verb, prURL, vr.Command, vr.Attempts)}
+	}
+	if len(p.Scan.Findings) > 0 {
+		return OrchestratorResult{IsJob: true, JobID: jobID, PRURL: prURL,
+			Text: fmt.Sprintf("%s %s, but security scanners reported findings (%s) — they're listed in the PR description.", verb, prURL, findingScanners(p.Scan.Findings))}
+	}
+	if prBranch != "" {
+		return OrchestratorResult{IsJob: true, JobID: jobID, PRURL: prURL, Text: verb + " " + prURL + "."}
+	}
+	return OrchestratorResult{IsJob: true, JobID: jobID, PRURL: prURL}
+}
+
+// followUpPreamble tells Claude Code that the worktree is an existing PR's
+// branch, or returns empty string for a fresh job.
+func followUpPreamble(pr *threadPR) string {
+	if pr == nil {
+		return ""
+	}
+	return fmt.Sprintf("## Existing pull request\n\nThis is a follow-up to %s. The working tree is that pull request's branch `%s`, including its earlier changes; build on them rather than starting over.\n\n", pr.URL, pr.Branch)
 }
 
 // processSessionResult inspects a planning session result and returns the appropriate
@@ -355,14 +773,16 @@ func (o *Orchestrator) processSessionResult(ctx context.Context, jobID string, s
 		state.SessionID = sr.SessionID
 		state.mu.Unlock()
 	}
+	o.recordInjections(ctx, jobID, sr.Injections)
 
 	if sr.IsError {
-		o.closeJob(ctx, jobID, EventJobError, map[string]any{"error": sr.ResultText})
+		o.closeJob(ctx, jobID, JobErrorData{Error: sr.ResultText})
 		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("Claude Code reported an error: %s", sr.ResultText)}, nil
 	}
 
 	// Clarification needed — detected via AskUserQuestion tool_use.
 	if sr.Question != "" {
+		state.recordQuestion(sr.Question)
 		o.hub.SetPhase(jobID, PhaseAwaitingQuestion)
 		return OrchestratorResult{IsJob: true, JobID: jobID, Text: sr.Question, QuestionBlocks: formatQuestionBlocks(sr.Question)}, nil
 	}
@@ -371,7 +791,7 @@ func (o *Orchestrator) processSessionResult(ctx context.Context, jobID string, s
 	if sr.PlanExited {
 		planContent, err := readPlanFile(sr.PlanFilePath, repoDir)
 		if err != nil {
-			log.Printf("orchestrator: failed to read plan file: %v, falling back to result text", err)
+			slog.WarnContext(ctx, "orchestrator: failed to read plan file, falling back to result text", "job_id", jobID, "err", err)
 			planContent = sr.ResultText
 		}
 		if planContent == "" {
@@ -380,49 +800,57 @@ func (o *Orchestrator) processSessionResult(ctx context.Context, jobID string, s
 
 		state.mu.Lock()
 		state.PlanFilePath = sr.PlanFilePath
-		state.PlanContent = planContent
 		state.mu.Unlock()
-		o.hub.SetPhase(jobID, PhaseAwaitingApproval)
-
-		o.hub.Emit(jobID, EventPlanGenerated, map[string]any{"plan": planContent})
-
-		planText := formatPlanMessage(planContent)
-		blocks := formatPlanBlocks(planContent, jobID)
-		return OrchestratorResult{
-			IsJob:      true,
-			JobID:      jobID,
-			Text:       planText,
-			PlanBlocks: blocks,
-			PlanText:   planText,
-		}, nil
+		return o.presentPlan(jobID, planContent), nil
 	}
 
 	// Fallback: no explicit signal — use ResultText as plan.
 	if sr.ResultText != "" {
-		state.mu.Lock()
-		state.PlanContent = sr.ResultText
-		state.mu.Unlock()
-		o.hub.SetPhase(jobID, PhaseAwaitingApproval)
-
-		o.hub.Emit(jobID, EventPlanGenerated, map[string]any{"plan": sr.ResultText})
-
-		planText := formatPlanMessage(sr.ResultText)
-		blocks := formatPlanBlocks(sr.ResultText, jobID)
-		return OrchestratorResult{
-			IsJob:      true,
-			JobID:      jobID,
-			Text:       planText,
-			PlanBlocks: blocks,
-			PlanText:   planText,
-		}, nil
+		return o.presentPlan(jobID, sr.ResultText), nil
 	}
 
 	// No useful output at all.
-	o.closeJob(ctx, jobID, EventJobError, map[string]any{"error": "no output from planning session"})
+	o.closeJob(ctx, jobID, JobErrorData{Error: "no output from planning session"})
 	return OrchestratorResult{IsJob: true, JobID: jobID, Text: "Claude Code produced no output during planning."}, nil
 }
 
-// readPlanFile reads the plan content from a file written during planning.
+// presentPlan records a new plan, moves the job to awaiting approval and
+// formats the plan for posting. A revised plan leads with what changed since
+// the previous one, and suspected prompt injection goes before everything.
+func (o *Orchestrator) presentPlan(jobID, plan string) OrchestratorResult {
+	state, _ := o.hub.GetJobState(jobID)
+	state.mu.Lock()
+	changes := summarizePlanChanges(state.PlanContent, plan)
+	state.PlanContent = plan
+	injections := slices.Clone(state.Injections)
+	state.mu.Unlock()
+	o.hub.SetPhase(jobID, PhaseAwaitingApproval)
+
+	o.hub.Emit(jobID, PlanGeneratedData{Plan: plan, Changes: changes})
+
+	planText := formatPlanMessage(plan)
+	blocks := formatPlanBlocks(plan, jobID)
+	if changes != "" {
+		planText = fmt.Sprintf("%s\n%s\n\n%s", planChangesHeading, markdownToMrkdwn(changes), planText)
+		blocks = append([]slack.Block{formatPlanChangesBlock(changes)}, blocks...)
+	}
+	if len(injections) > 0 {
+		planText = fmt.Sprintf("%s\n\n%s", formatInjectionFindings(injections), planText)
+		blocks = append([]slack.Block{formatInjectionBlock(injections)}, blocks...)
+	}
+	return OrchestratorResult{
+		IsJob:       true,
+		JobID:       jobID,
+		Text:        planText,
+		PlanBlocks:  blocks,
+		PlanText:    planText,
+		PlanChanges: changes,
+		Injections:  injections,
+	}
+}
+
+// readPlanFile reads the plan content from a file written during planning,
+// with secrets redacted since the plan is posted to chat.
 func readPlanFile(planFilePath, repoDir string) (string, error) {
 	if planFilePath == "" {
 		return "", fmt.Errorf("no plan file path")
@@ -436,76 +864,186 @@ func readPlanFile(planFilePath, repoDir string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("read plan file %s: %w", path, err)
 	}
-	return string(data), nil
+	return redactSecrets(string(data)), nil
 }
 
-// createJob creates a new job and registers it with the hub.
-func (o *Orchestrator) createJob(intent IntentResult, channel, threadTS string) string {
+// intentModel returns the configured intent parsing model, for job metadata.
+func (o *Orchestrator) intentModel() string {
+	if o.llm == nil {
+		return ""
+	}
+	return o.llm.Model()
+}
+
+// createJob creates a new job and registers it with the hub. user and userName
+// are the chat user who asked for it, if any, and their display name.
+func (o *Orchestrator) createJob(intent IntentResult, vcs VCSProvider, channel, threadTS, platform, user, userName string, coAuthor *gitIdentity) string {
 	jobID := generateJobID()
 	slackThreadURL := ""
-	if channel != "" && threadTS != "" {
+	if channel != "" && threadTS != "" && (platform == "" || platform == "slack") {
 		slackThreadURL = fmt.Sprintf("https://slack.com/archives/%s/p%s",
 			channel, strings.ReplaceAll(threadTS, ".", ""))
 	}
 
-	o.hub.Emit(jobID, EventJobStarted, map[string]any{
-		"task":             intent.Task,
-		"phase":            string(PhasePlanning),
-		"slack_thread_url": slackThreadURL,
-		"channel":          channel,
-		"thread_ts":        threadTS,
+	o.hub.Emit(jobID, JobStartedData{
+		Task:            intent.Task,
+		Repo:            intent.Repo,
+		Phase:           string(PhasePlanning),
+		SlackThreadURL:  slackThreadURL,
+		Channel:         channel,
+		ThreadTS:        threadTS,
+		Platform:        platform,
+		RequestedBy:     user,
+		RequestedByName: userName,
+		VCSProvider:     vcs.Name(),
+		IntentModel:     o.intentModel(),
+		CLIVersion:      claudeCodeVersion(),
+		RerunOf:         intent.RerunOf,
 	})
-	o.hub.RegisterThreadJob(channel, threadTS, jobID)
+	if channel != "" {
+		o.hub.RegisterThreadJob(channel, threadTS, jobID)
+	}
 
 	o.hub.SetJobState(jobID, &JobState{
-		Repo:     intent.Repo,
-		Task:     intent.Task,
-		Phase:    PhasePlanning,
-		Channel:  channel,
-		ThreadTS: threadTS,
+		Repo:        intent.Repo,
+		Task:        intent.Task,
+		Phase:       PhasePlanning,
+		Channel:     channel,
+		ThreadTS:    threadTS,
+		Platform:    platform,
+		RequestedBy: user,
+		PRHints:     intent.PROptions,
+		CoAuthor:    coAuthor,
+		DryRun:      intent.DryRun,
+		Changelog:   intent.Changelog,
+		PRFooter:    intent.PRFooter,
+		Ticket:      intent.Ticket,
+		Paths:       intent.Paths,
+		vcs:         vcs,
 	})
 
 	return jobID
 }
 
 // closeJob emits a terminal event, cleans up the worktree, and unregisters the thread→job mapping.
-func (o *Orchestrator) closeJob(ctx context.Context, jobID string, evtType EventType, data map[string]any) {
-	o.hub.Emit(jobID, evtType, data)
+// Only the first call per job has any effect, so a cancelled job's own error path
+// doesn't emit a second terminal event.
+func (o *Orchestrator) closeJob(ctx context.Context, jobID string, data EventData) {
+	channel, _ := ctx.Value(ctxKeyChannel).(string)
+	threadTS, _ := ctx.Value(ctxKeyThreadTS).(string)
 
 	// Clean up worktree if one was created.
 	if state, ok := o.hub.GetJobState(jobID); ok {
 		state.mu.Lock()
+		if state.closed {
+			state.mu.Unlock()
+			return
+		}
+		state.closed = true
 		baseDir := state.BaseDir
 		repoDir := state.RepoDir
+		if state.Channel != "" {
+			channel, threadTS = state.Channel, state.ThreadTS
+		}
 		state.mu.Unlock()
+
+		o.hub.Emit(jobID, data)
 		if baseDir != "" && repoDir != "" {
 			RemoveWorktree(ctx, baseDir, repoDir, jobID)
 		}
+	} else {
+		o.hub.Emit(jobID, data)
 	}
 
-	channel, _ := ctx.Value(ctxKeyChannel).(string)
-	threadTS, _ := ctx.Value(ctxKeyThreadTS).(string)
 	o.hub.UnregisterThreadJob(channel, threadTS)
 	o.hub.SetPhase(jobID, PhaseDone)
 }
 
+// jobContext derives a cancellable context for one step of a job and registers
+// its cancel func so CancelJob can interrupt it. The returned release func must
+// be called when the step finishes.
+func (o *Orchestrator) jobContext(ctx context.Context, jobID string) (context.Context, func()) {
+	jobCtx, cancel := context.WithCancel(WithHub(WithJobID(ctx, jobID), o.hub))
+	state, ok := o.hub.GetJobState(jobID)
+	if !ok {
+		return jobCtx, cancel
+	}
+	state.mu.Lock()
+	if state.cancelled {
+		cancel()
+	}
+	state.cancel = cancel
+	state.mu.Unlock()
+	return jobCtx, func() {
+		state.mu.Lock()
+		state.cancel = nil
+		state.mu.Unlock()
+		cancel()
+	}
+}
+
+// replaceIfCancelled swaps whatever error result a cancelled step produced for a
+// plain cancellation notice. Used as a deferred call in the entry points.
+func (o *Orchestrator) replaceIfCancelled(jobID string, result *OrchestratorResult, err *error) {
+	if o.hub.IsCancelled(jobID) {
+		*result = OrchestratorResult{IsJob: true, JobID: jobID, Text: "Stopped — this job was cancelled."}
+		*err = nil
+	}
+}
+
+// maxPreservedPatch caps the patch archived for preserved work; the stash
+// entry always holds the full changes.
+const maxPreservedPatch = 5 << 20 // 5 MB
+
+// preserveWork archives uncommitted work saved from a job's worktree as a patch
+// under the job's artifacts and emits work_preserved.
+func (o *Orchestrator) preserveWork(jobID string, saved *savedWork) {
+	slog.Info("orchestrator: saved uncommitted changes", "job_id", jobID, "files", len(saved.Files), "stash", saved.Stash)
+	data := WorkPreservedData{Stash: saved.Stash, Files: saved.Files}
+	if len(saved.Patch) <= maxPreservedPatch {
+		name := fmt.Sprintf("uncommitted-%s.patch", time.Now().UTC().Format("20060102T150405Z"))
+		if err := o.hub.writeArtifact(jobID, name, saved.Patch); err != nil {
+			slog.Error("orchestrator: failed to archive uncommitted changes", "job_id", jobID, "err", err)
+		} else {
+			data.Patch = name
+		}
+	}
+	o.hub.Emit(jobID, data)
+}
+
+// CancelJob cancels a job: any running Claude Code process or git command is
+// killed, the worktree is removed, and job_cancelled is emitted.
+func (o *Orchestrator) CancelJob(ctx context.Context, jobID, cancelledBy string) error {
+	state, ok := o.hub.GetJobState(jobID)
+	if !ok {
+		return fmt.Errorf("no state for job %s", jobID)
+	}
+	state.mu.Lock()
+	if state.closed {
+		state.mu.Unlock()
+		return fmt.Errorf("job %s has already finished", jobID)
+	}
+	state.cancelled = true
+	cancel := state.cancel
+	state.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	slog.InfoContext(ctx, "orchestrator: job cancelled", "job_id", jobID, "by", cancelledBy)
+	o.closeJob(ctx, jobID, JobCancelledData{CancelledBy: cancelledBy})
+	return nil
+}
+
 // formatPlanMessage wraps a plan in the standard format for Slack.
 func formatPlanMessage(plan string) string {
+	plan, _ = extractLongCodeBlocks(plan)
 	return fmt.Sprintf("%s\n\n%s\n\n_Reply with your feedback, or say \"go\" to approve and start implementation._", planMarker, markdownToMrkdwn(plan))
 }
 
 // formatPlanBlocks returns Block Kit blocks for a plan message with an Approve button.
 func formatPlanBlocks(plan, jobID string) []slack.Block {
-	// Slack section blocks have a 3000 char limit for text.
-	displayPlan := plan
-	if len(displayPlan) > 2800 {
-		displayPlan = displayPlan[:2800] + "\n..."
-	}
-
-	planSection := slack.NewSectionBlock(
-		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s\n\n%s", planMarker, markdownToMrkdwn(displayPlan)), false, false),
-		nil, nil,
-	)
+	planSections := mrkdwnSections(planMarker, plan)
 
 	divider := slack.NewDividerBlock()
 
@@ -520,20 +1058,12 @@ func formatPlanBlocks(plan, jobID string) []slack.Block {
 
 	actionsBlock := slack.NewActionBlock("plan_actions", approveBtn)
 
-	return []slack.Block{planSection, divider, ctxBlock, actionsBlock}
+	return append(planSections, divider, ctxBlock, actionsBlock)
 }
 
 // formatQuestionBlocks returns Block Kit blocks for a clarification question.
 func formatQuestionBlocks(question string) []slack.Block {
-	displayQuestion := question
-	if len(displayQuestion) > 2800 {
-		displayQuestion = displayQuestion[:2800] + "\n..."
-	}
-
-	section := slack.NewSectionBlock(
-		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("\u2753 *Clarification needed*\n\n%s", markdownToMrkdwn(displayQuestion)), false, false),
-		nil, nil,
-	)
+	sections := mrkdwnSections("\u2753 *Clarification needed*", question)
 
 	divider := slack.NewDividerBlock()
 
@@ -541,20 +1071,12 @@ func formatQuestionBlocks(question string) []slack.Block {
 		slack.NewTextBlockObject(slack.MarkdownType, "Reply in this thread to answer.", false, false),
 	)
 
-	return []slack.Block{section, divider, ctxBlock}
+	return append(sections, divider, ctxBlock)
 }
 
 // formatApprovedPlanBlocks returns Block Kit blocks for an already-approved plan (no button).
 func formatApprovedPlanBlocks(plan, approvedBy string) []slack.Block {
-	displayPlan := plan
-	if len(displayPlan) > 2800 {
-		displayPlan = displayPlan[:2800] + "\n..."
-	}
-
-	planSection := slack.NewSectionBlock(
-		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s\n\n%s", planMarker, markdownToMrkdwn(displayPlan)), false, false),
-		nil, nil,
-	)
+	planSections := mrkdwnSections(planMarker, plan)
 
 	divider := slack.NewDividerBlock()
 
@@ -562,20 +1084,12 @@ func formatApprovedPlanBlocks(plan, approvedBy string) []slack.Block {
 		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("Approved by %s", approvedBy), false, false),
 	)
 
-	return []slack.Block{planSection, divider, ctxBlock}
+	return append(planSections, divider, ctxBlock)
 }
 
 // formatSupersededPlanBlocks returns Block Kit blocks for a plan that was superseded by feedback (no button).
 func formatSupersededPlanBlocks(plan, label string) []slack.Block {
-	displayPlan := plan
-	if len(displayPlan) > 2800 {
-		displayPlan = displayPlan[:2800] + "\n..."
-	}
-
-	planSection := slack.NewSectionBlock(
-		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s\n\n%s", planMarker, markdownToMrkdwn(displayPlan)), false, false),
-		nil, nil,
-	)
+	planSections := mrkdwnSections(planMarker, plan)
 
 	divider := slack.NewDividerBlock()
 
@@ -583,7 +1097,7 @@ func formatSupersededPlanBlocks(plan, label string) []slack.Block {
 		slack.NewTextBlockObject(slack.MarkdownType, label, false, false),
 	)
 
-	return []slack.Block{planSection, divider, ctxBlock}
+	return append(planSections, divider, ctxBlock)
 }
 
 // isValidRepoName checks that a repo name contains only characters allowed by GitHub:
@@ -639,8 +1153,13 @@ func taskBranchName(task string) string {
 	if len(s) > 50 {
 		s = s[:50]
 	}
+	return "bob/" + s + "-" + branchSuffix()
+}
 
+// branchSuffix returns 8 random hex characters that keep branch names for
+// similar tasks apart.
+func branchSuffix() string {
 	var suffix [4]byte
 	rand.Read(suffix[:])
-	return "bob/" + s + "-" + hex.EncodeToString(suffix[:])
+	return hex.EncodeToString(suffix[:])
 }
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
+
+	"github.com/slack-go/slack"
 )
 
 func TestIsValidRepoName(t *testing.T) {
@@ -148,11 +151,23 @@ func TestFormatPlanBlocks(t *testing.T) {
 		}
 	})
 
-	t.Run("long plan truncated", func(t *testing.T) {
+	t.Run("long plan split across sections", func(t *testing.T) {
 		longPlan := strings.Repeat("x", 3000)
 		blocks := formatPlanBlocks(longPlan, "job-456")
-		if len(blocks) != 4 {
-			t.Fatalf("expected 4 blocks, got %d", len(blocks))
+		if len(blocks) != 5 {
+			t.Fatalf("expected 5 blocks, got %d", len(blocks))
+		}
+		for _, b := range blocks[:2] {
+			if text := b.(*slack.SectionBlock).Text.Text; len(text) > 3000 {
+				t.Errorf("section text is %d bytes, over Slack's limit", len(text))
+			}
+		}
+	})
+
+	t.Run("very long plan capped", func(t *testing.T) {
+		blocks := formatPlanBlocks(strings.Repeat("line\n", 50000), "job-456")
+		if len(blocks) != slackMaxSections+3 {
+			t.Fatalf("expected %d blocks, got %d", slackMaxSections+3, len(blocks))
 		}
 	})
 
@@ -173,11 +188,11 @@ func TestFormatQuestionBlocks(t *testing.T) {
 		}
 	})
 
-	t.Run("long question truncated", func(t *testing.T) {
+	t.Run("long question split across sections", func(t *testing.T) {
 		longQ := strings.Repeat("q", 3000)
 		blocks := formatQuestionBlocks(longQ)
-		if len(blocks) != 3 {
-			t.Fatalf("expected 3 blocks, got %d", len(blocks))
+		if len(blocks) != 4 {
+			t.Fatalf("expected 4 blocks, got %d", len(blocks))
 		}
 	})
 }
@@ -273,3 +288,100 @@ func TestReadPlanFile(t *testing.T) {
 		}
 	})
 }
+
+func TestCancelJob(t *testing.T) {
+	drainHub(t)
+	dir := t.TempDir()
+	hub := NewHub(dir)
+	o := &Orchestrator{hub: hub}
+
+	t.Run("cancels in-flight step and closes job", func(t *testing.T) {
+		hub.SetJobState("job-1", &JobState{Phase: PhasePlanning, Channel: "C1", ThreadTS: "ts1"})
+		hub.RegisterThreadJob("C1", "ts1", "job-1")
+		jobCtx, release := o.jobContext(context.Background(), "job-1")
+		defer release()
+
+		if err := o.CancelJob(context.Background(), "job-1", "tester"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if jobCtx.Err() == nil {
+			t.Error("expected job context to be cancelled")
+		}
+		if !hub.IsCancelled("job-1") {
+			t.Error("expected IsCancelled = true")
+		}
+		if got := hub.ActiveJobForThread("C1", "ts1"); got != "" {
+			t.Errorf("ActiveJobForThread = %q, want empty", got)
+		}
+		state, _ := hub.GetJobState("job-1")
+		if state.Phase != PhaseDone {
+			t.Errorf("Phase = %q, want %q", state.Phase, PhaseDone)
+		}
+		waitIndexed(t, dir, "job-1")
+	})
+
+	t.Run("second cancel errors", func(t *testing.T) {
+		if err := o.CancelJob(context.Background(), "job-1", "tester"); err == nil {
+			t.Error("expected error cancelling a finished job")
+		}
+	})
+
+	t.Run("unknown job errors", func(t *testing.T) {
+		if err := o.CancelJob(context.Background(), "nonexistent", "tester"); err == nil {
+			t.Error("expected error for unknown job")
+		}
+	})
+
+	t.Run("cancelled result replaces error", func(t *testing.T) {
+		result := OrchestratorResult{Text: "Claude Code encountered an error: signal: killed"}
+		var err error
+		o.replaceIfCancelled("job-1", &result, &err)
+		if !strings.Contains(result.Text, "cancelled") {
+			t.Errorf("Text = %q, want cancellation notice", result.Text)
+		}
+	})
+}
+
+func TestFollowUpPreamble(t *testing.T) {
+	if got := followUpPreamble(nil); got != "" {
+		t.Errorf("followUpPreamble(nil) = %q, want empty", got)
+	}
+	got := followUpPreamble(&threadPR{URL: "https://github.com/org/web/pull/7", Branch: "bob/fix-login-ab12"})
+	for _, want := range []string{"## Existing pull request", "https://github.com/org/web/pull/7", "`bob/fix-login-ab12`"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("followUpPreamble missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestHandleNewRequest_Confirmation(t *testing.T) {
+	drainHub(t)
+	llm := &fakeLLM{resp: LLMResponse{Text: `{"repo":"api","task":"drop the users table","summary":"Drop the users table in api.","confidence":0.9,"destructive":true}`}}
+	o := &Orchestrator{llm: llm, hub: NewHub(t.TempDir())}
+	ctx := WithSlackThread(context.Background(), "C1", "ts1")
+
+	result, err := o.HandleNewRequest(ctx, []Message{{Role: RoleUser, Content: "drop users in api"}}, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsJob || !strings.Contains(result.Text, "Drop the users table in api.") || !strings.Contains(result.Text, "destructive") {
+		t.Fatalf("result = %+v, want a confirmation request", result)
+	}
+
+	// Confirming starts the understood request without parsing the thread again.
+	llm.req = LLMRequest{}
+	thread := []Message{{Role: RoleUser, Content: "drop users in api"}, {Role: RoleAssistant, Content: result.Text}, {Role: RoleUser, Content: "go"}}
+	result, err = o.HandleNewRequest(ctx, thread, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if llm.req.System != "" {
+		t.Error("confirmation should not re-parse the intent")
+	}
+	if !strings.Contains(result.Text, "couldn't find the repository *api*") {
+		t.Errorf("result = %+v, want the job to be started", result)
+	}
+	if _, ok := o.hub.TakePendingIntent("C1", "ts1"); ok {
+		t.Error("pending intent should be consumed")
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// maxPlanChanges is the number of changed steps listed in a revision summary.
+const maxPlanChanges = 10
+
+// planStepPrefixRe matches list bullets, numbering and heading marks.
+var planStepPrefixRe = regexp.MustCompile(`^(?:[-*+]\s+|\d+[.)]\s+|#+\s+|\[[ xX]\]\s+)+`)
+
+// planSteps splits a Markdown plan into its steps: every non-blank line
+// outside code blocks, without list or heading markup.
+func planSteps(plan string) []string {
+	var steps []string
+	inCode := false
+	for line := range strings.SplitSeq(plan, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		line = strings.TrimSpace(planStepPrefixRe.ReplaceAllString(line, ""))
+		if line != "" {
+			steps = append(steps, line)
+		}
+	}
+	return steps
+}
+
+// planChange is one difference between two plan revisions. Old is empty for
+// an added step and New for a removed one.
+type planChange struct {
+	Old, New string
+}
+
+// diffPlans compares two plans step by step. Steps replaced in place by a
+// similar step are reported as modified rather than removed and added.
+func diffPlans(prev, next string) []planChange {
+	a, b := planSteps(prev), planSteps(next)
+
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var changes []planChange
+	var removed, added []string
+	flush := func() {
+		for len(removed) > 0 || len(added) > 0 {
+			switch {
+			case len(removed) > 0 && len(added) > 0 && similarSteps(removed[0], added[0]):
+				changes = append(changes, planChange{Old: removed[0], New: added[0]})
+				removed, added = removed[1:], added[1:]
+			case len(removed) > 0:
+				changes = append(changes, planChange{Old: removed[0]})
+				removed = removed[1:]
+			default:
+				changes = append(changes, planChange{New: added[0]})
+				added = added[1:]
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			flush()
+			i, j = i+1, j+1
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			added = append(added, b[j])
+			j++
+		default:
+			removed = append(removed, a[i])
+			i++
+		}
+	}
+	flush()
+	return changes
+}
+
+// similarSteps reports whether two steps share at least half their words, so
+// that an edited step reads as modified.
+func similarSteps(a, b string) bool {
+	wa, wb := strings.Fields(strings.ToLower(a)), strings.Fields(strings.ToLower(b))
+	if len(wa) == 0 || len(wb) == 0 {
+		return false
+	}
+	seen := make(map[string]bool, len(wa))
+	for _, w := range wa {
+		seen[w] = true
+	}
+	shared := 0
+	for _, w := range wb {
+		if seen[w] {
+			shared++
+			delete(seen, w)
+		}
+	}
+	return shared*2 >= max(len(wa), len(wb))
+}
+
+// summarizePlanChanges describes in Markdown how next differs from prev, for
+// posting above a revised plan. It returns "" when there is no previous plan.
+func summarizePlanChanges(prev, next string) string {
+	if strings.TrimSpace(prev) == "" {
+		return ""
+	}
+	changes := diffPlans(prev, next)
+	if len(changes) == 0 {
+		return "No changes to the steps of the previous plan."
+	}
+	var b strings.Builder
+	for i, c := range changes {
+		if i == maxPlanChanges {
+			fmt.Fprintf(&b, "- …and %d more\n", len(changes)-maxPlanChanges)
+			break
+		}
+		switch {
+		case c.Old == "":
+			fmt.Fprintf(&b, "- Added: %s\n", truncate(c.New, 200))
+		case c.New == "":
+			fmt.Fprintf(&b, "- Removed: ~~%s~~\n", truncate(c.Old, 200))
+		default:
+			fmt.Fprintf(&b, "- Changed: %s → %s\n", truncate(c.Old, 200), truncate(c.New, 200))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// planChangesHeading introduces a revision summary.
+const planChangesHeading = "\U0001f501 *Changes since the last plan*"
+
+// formatPlanChangesBlock returns a Block Kit section for a revision summary.
+func formatPlanChangesBlock(changes string) slack.Block {
+	if len(changes) > 2800 {
+		changes = prefix(changes, 2800) + "\n..."
+	}
+	return slack.NewSectionBlock(
+		slack.NewTextBlockObject(slack.MarkdownType, fmt.Sprintf("%s\n%s", planChangesHeading, markdownToMrkdwn(changes)), false, false),
+		nil, nil,
+	)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffPlans(t *testing.T) {
+	prev := "## Plan\n\n1. Add a `Retry` helper in util.go\n2. Call it from the client\n3. Update the README\n\n```go\nfunc Retry() {}\n```\n"
+	next := "## Plan\n\n1. Add a `Retry` helper with jitter in util.go\n2. Call it from the client\n3. Add tests for Retry\n\n```go\nfunc Retry(jitter bool) {}\n```\n"
+
+	got := diffPlans(prev, next)
+	want := []planChange{
+		{Old: "Add a `Retry` helper in util.go", New: "Add a `Retry` helper with jitter in util.go"},
+		{Old: "Update the README"},
+		{New: "Add tests for Retry"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("diffPlans = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if changes := diffPlans("- a\n- b", "1. a\n2. b"); len(changes) != 0 {
+		t.Errorf("reformatted plan: %+v", changes)
+	}
+}
+
+func TestSummarizePlanChanges(t *testing.T) {
+	if got := summarizePlanChanges("", "1. Do it"); got != "" {
+		t.Errorf("first plan: %q", got)
+	}
+	if got := summarizePlanChanges("1. Do it", "- Do it"); !strings.Contains(got, "No changes") {
+		t.Errorf("unchanged plan: %q", got)
+	}
+	got := summarizePlanChanges("1. Edit main.go\n2. Run tests", "1. Edit main.go\n2. Run the linter\n3. Run tests")
+	if got != "- Added: Run the linter" {
+		t.Errorf("summary = %q", got)
+	}
+
+	var long []string
+	for i := range maxPlanChanges + 3 {
+		long = append(long, strings.Repeat("x", i+1))
+	}
+	if got := summarizePlanChanges("start", strings.Join(long, "\n")); !strings.HasSuffix(got, "…and 4 more") {
+		t.Errorf("long summary = %q", got)
+	}
+}
+
+func TestPresentPlan_Revision(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	o := &Orchestrator{hub: hub}
+	hub.SetJobState("job-1", &JobState{Phase: PhasePlanning})
+
+	first := o.presentPlan("job-1", "1. Edit main.go")
+	if first.PlanChanges != "" || len(first.PlanBlocks) != 4 {
+		t.Errorf("first plan: changes %q, %d blocks", first.PlanChanges, len(first.PlanBlocks))
+	}
+
+	revised := o.presentPlan("job-1", "1. Edit main.go\n2. Add a test")
+	if revised.PlanChanges != "- Added: Add a test" {
+		t.Errorf("PlanChanges = %q", revised.PlanChanges)
+	}
+	if len(revised.PlanBlocks) != 5 || !strings.HasPrefix(revised.PlanText, planChangesHeading) {
+		t.Errorf("revised plan: %d blocks, text %q", len(revised.PlanBlocks), revised.PlanText)
+	}
+	if state, _ := hub.GetJobState("job-1"); state.PlanContent != "1. Edit main.go\n2. Add a test" || state.Phase != PhaseAwaitingApproval {
+		t.Errorf("state = %q, %s", state.PlanContent, state.Phase)
+	}
+}
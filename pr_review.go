@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// maxReviewDiff bounds the PR diff included in the review prompt. Claude Code
+// can read the full files from the worktree for anything beyond it.
+const maxReviewDiff = 100_000
+
+// pullRequestInfo is what a review needs to know about a pull request.
+type pullRequestInfo struct {
+	Title   string
+	Body    string
+	HTMLURL string
+	HeadSHA string // commit the review is attached to
+	HeadRef string // fetchable ref for the PR head, e.g. refs/pull/123/head
+	Diff    string // unified diff against the base branch
+}
+
+// prReview is a structured review produced by Claude Code.
+type prReview struct {
+	Summary  string            `json:"summary"`
+	Comments []prReviewComment `json:"comments"`
+}
+
+// prReviewComment is an inline comment on a line of the PR's new version.
+type prReviewComment struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	Body string `json:"body"`
+}
+
+// prReviewer is implemented by providers that can post pull request reviews.
+type prReviewer interface {
+	GetPullRequest(ctx context.Context, name string, number int) (pullRequestInfo, error)
+	SubmitReview(ctx context.Context, name string, number int, commitSHA string, review prReview) error
+}
+
+// startReview runs a read-only review of an existing pull request and posts
+// the result to the PR as review comments. The job has no approval step.
+func (o *Orchestrator) startReview(ctx context.Context, intent IntentResult, onJobCreated func(jobID string)) (result OrchestratorResult, err error) {
+	if intent.Task == "" {
+		intent.Task = fmt.Sprintf("Review PR #%d", intent.ReviewPR)
+	}
+	if reject := o.validateIntent(ctx, &intent); reject != "" {
+		return OrchestratorResult{Text: reject}, nil
+	}
+
+	vcs, r, err := findRepo(ctx, o.providers, intent.Repo)
+	if err != nil {
+		return OrchestratorResult{Text: fmt.Sprintf("I couldn't find the repository *%s*. Please check the repository name and try again.", intent.Repo)}, nil
+	}
+	if r.Name != "" && r.Name != intent.Repo {
+		// Matched loosely; continue with the canonical name.
+		intent.Repo = r.Name
+		if reject := o.validateIntent(ctx, &intent); reject != "" {
+			return OrchestratorResult{Text: reject}, nil
+		}
+	}
+	reviewer, ok := vcs.(prReviewer)
+	if !ok {
+		return OrchestratorResult{Text: fmt.Sprintf("I can't review pull requests on %s yet.", vcs.Name())}, nil
+	}
+	pr, err := reviewer.GetPullRequest(ctx, intent.Repo, intent.ReviewPR)
+	if err != nil {
+		slog.WarnContext(ctx, "orchestrator: failed to get PR", "repo", intent.Repo, "pr", intent.ReviewPR, "err", err)
+		return OrchestratorResult{Text: fmt.Sprintf("I couldn't find PR #%d in *%s*.", intent.ReviewPR, intent.Repo)}, nil
+	}
+
+	channel, _ := ctx.Value(ctxKeyChannel).(string)
+	threadTS, _ := ctx.Value(ctxKeyThreadTS).(string)
+
+	jobID := o.createJob(intent, vcs, channel, threadTS, PlatformFromCtx(ctx), UserFromCtx(ctx), UserNameFromCtx(ctx), nil)
+	if onJobCreated != nil {
+		onJobCreated(jobID)
+	}
+	jobCtx, release := o.jobContext(ctx, jobID)
+	defer release()
+	defer o.replaceIfCancelled(jobID, &result, &err)
+	defer o.recoverJob(ctx, jobID, &result, &err)
+	o.hub.SetPhase(jobID, PhaseReviewing)
+
+	startTime := time.Now()
+	fail := func(text string, err error) (OrchestratorResult, error) {
+		o.closeJob(ctx, jobID, JobErrorData{
+			Error: err.Error(), TotalDurationMs: time.Since(startTime).Milliseconds(),
+		})
+		return OrchestratorResult{IsJob: true, JobID: jobID, Text: fmt.Sprintf("%s: %s", text, err.Error())}, nil
+	}
+
+	unlockClone := lockBaseClone(intent.Repo)
+	defer unlockClone()
+	baseDir, err := EnsureBaseClone(jobCtx, vcs, intent.Repo, r.DefaultBranch)
+	if err != nil {
+		return fail("I ran into an error cloning the repository", err)
+	}
+	cfg, err := loadRepoConfig(jobCtx, baseDir, "FETCH_HEAD")
+	if err != nil {
+		return fail("I couldn't read the repo's "+repoConfigFile, err)
+	}
+	if err := FetchBranch(jobCtx, baseDir, vcs, intent.Repo, pr.HeadRef); err != nil {
+		return fail("I couldn't fetch the pull request", err)
+	}
+	repoDir, err := CreateWorktree(jobCtx, baseDir, jobID)
+	unlockClone()
+	if err != nil {
+		return fail("Failed to create worktree", err)
+	}
+	state, _ := o.hub.GetJobState(jobID)
+	state.mu.Lock()
+	state.RepoDir = repoDir
+	state.BaseDir = baseDir
+	state.mu.Unlock()
+
+	diff := pr.Diff
+	if len(diff) > maxReviewDiff {
+		diff = prefix(diff, maxReviewDiff) + "\n... (diff truncated — read the changed files for the rest)"
+	}
+	prompt := fmt.Sprintf("## Request\n\n%s\n\n## Pull request: %s\n\n%s\n\n## Diff\n\n```diff\n%s\n```", intent.Task, pr.Title, pr.Body, diff)
+
+	slog.InfoContext(jobCtx, "orchestrator: reviewing", "pr", pr.HTMLURL)
+	var sr *SessionResult
+	_, err = o.runStep(jobCtx, jobID, "review_pr", pr.HTMLURL, func(ctx context.Context) (string, error) {
+		var err error
+		sr, err = RunSession(ctx, o.claudeCodeToken, o.hub, jobID, SessionOpts{
+			RepoDir:        repoDir,
+			Prompt:         prompt,
+			SystemPrompt:   prReviewSystemPrompt,
+			PermissionMode: "plan",
+			Tools:          o.toolRules(cfg, toolPhaseRead),
+			Sandbox:        o.sandboxFor(intent.Repo, cfg),
+			Limits:         o.sessionLimits(ctx, jobID, intent.Repo),
+		})
+		if err == nil && sr.IsError {
+			err = errors.New(sr.ResultText)
+		}
+		return sessionPreview(sr, err)
+	})
+	if err != nil {
+		return fail("Claude Code encountered an error during the review", err)
+	}
+
+	if cost, over := o.overBudget(jobID); over {
+		return o.abortOverBudget(ctx, jobID, cost), nil
+	}
+
+	review := parsePRReview(sr.ResultText)
+	if err := reviewer.SubmitReview(jobCtx, intent.Repo, intent.ReviewPR, pr.HeadSHA, review); err != nil {
+		return fail("I reviewed the PR but couldn't post the review", err)
+	}
+
+	o.closeJob(ctx, jobID, JobCompletedData{
+		FinalResponse:   review.Summary,
+		PRURL:           pr.HTMLURL,
+		TotalDurationMs: time.Since(startTime).Milliseconds(),
+	})
+	o.hub.SetPhase(jobID, PhaseDone)
+	text := fmt.Sprintf("Posted a review with %d comment(s) on %s\n\n%s", len(review.Comments), pr.HTMLURL, review.Summary)
+	return OrchestratorResult{IsJob: true, JobID: jobID, Text: text}, nil
+}
+
+// parsePRReview extracts the JSON review from Claude Code's final message. If
+// the output isn't valid JSON, the whole text becomes the review summary.
+func parsePRReview(text string) prReview {
+	text = strings.TrimSpace(text)
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start >= 0 && end > start {
+		var r prReview
+		if err := json.Unmarshal([]byte(text[start:end+1]), &r); err == nil && (r.Summary != "" || len(r.Comments) > 0) {
+			comments := r.Comments[:0]
+			for _, c := range r.Comments {
+				if c.Path != "" && c.Line > 0 && strings.TrimSpace(c.Body) != "" {
+					comments = append(comments, c)
+				}
+			}
+			r.Comments = comments
+			return r
+		}
+	}
+	return prReview{Summary: text}
+}
+
+// inlineCommentsAsBody folds inline comments into the review body, for when the
+// provider rejects them (e.g. a line that isn't part of the diff).
+func (r prReview) inlineCommentsAsBody() prReview {
+	var b strings.Builder
+	b.WriteString(r.Summary)
+	for _, c := range r.Comments {
+		fmt.Fprintf(&b, "\n\n**%s:%d**\n%s", c.Path, c.Line, c.Body)
+	}
+	return prReview{Summary: b.String()}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParsePRReview(t *testing.T) {
+	t.Run("json with prose and fences", func(t *testing.T) {
+		text := "Here is my review:\n```json\n" +
+			`{"summary":"Looks mostly fine.","comments":[{"path":"main.go","line":12,"body":"Unchecked error."},{"path":"","line":3,"body":"dropped"},{"path":"a.go","line":0,"body":"dropped"}]}` +
+			"\n```"
+		r := parsePRReview(text)
+		if r.Summary != "Looks mostly fine." {
+			t.Errorf("Summary = %q", r.Summary)
+		}
+		if len(r.Comments) != 1 || r.Comments[0].Path != "main.go" || r.Comments[0].Line != 12 {
+			t.Errorf("Comments = %+v", r.Comments)
+		}
+	})
+
+	t.Run("plain text", func(t *testing.T) {
+		r := parsePRReview("No issues found.")
+		if r.Summary != "No issues found." || len(r.Comments) != 0 {
+			t.Errorf("review = %+v", r)
+		}
+	})
+}
+
+func TestGitHubSubmitReview_FallsBackToBody(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/repo/pulls/7/reviews" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var payload map[string]any
+		json.NewDecoder(r.Body).Decode(&payload)
+		bodies = append(bodies, payload)
+		if _, ok := payload["comments"]; ok {
+			http.Error(w, `{"message":"Line could not be resolved"}`, http.StatusUnprocessableEntity)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	gh := NewGitHubProvider("acme", "tok")
+	gh.apiURL = srv.URL
+
+	review := prReview{Summary: "Overall fine.", Comments: []prReviewComment{{Path: "x.go", Line: 99, Body: "Nil deref."}}}
+	if err := gh.SubmitReview(t.Context(), "repo", 7, "abc123", review); err != nil {
+		t.Fatalf("SubmitReview: %v", err)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("requests = %d, want 2", len(bodies))
+	}
+	if bodies[0]["commit_id"] != "abc123" || bodies[0]["event"] != "COMMENT" {
+		t.Errorf("first request = %v", bodies[0])
+	}
+	body, _ := bodies[1]["body"].(string)
+	if !strings.Contains(body, "Overall fine.") || !strings.Contains(body, "**x.go:99**\nNil deref.") {
+		t.Errorf("fallback body = %q", body)
+	}
+}
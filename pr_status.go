@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+)
+
+// prStatus is where a pull request stands: open, merged or closed, its reviews
+// and whether it can be merged.
+type prStatus struct {
+	Number  int
+	Title   string
+	URL     string
+	State   string // open, closed or merged
+	Draft   bool
+	HeadSHA string
+	// Mergeable is GitHub's mergeable_state: clean, dirty (conflicts),
+	// blocked, behind, unstable, or unknown while it's being computed.
+	Mergeable string
+	Reviews   map[string]string // each reviewer's latest review: approved, changes_requested or commented
+}
+
+// prStatusReader is implemented by providers that can report a pull request's status.
+type prStatusReader interface {
+	PullRequestStatus(ctx context.Context, name string, number int) (prStatus, error)
+}
+
+// prCommenter is implemented by providers that can comment on a pull request.
+type prCommenter interface {
+	CreateIssueComment(ctx context.Context, name string, number int, body string) error
+}
+
+// maxPRCommentDiff caps the diff given to the LLM when writing a PR comment.
+const maxPRCommentDiff = 30_000
+
+const prCommentSystemPrompt = `You write comments on pull requests for a coding assistant that opened them. You're given the pull request — title, description and diff — and what a team member asked the comment to say, e.g. a summary of the change for reviewers.
+
+Write the comment in GitHub Markdown. Be concise and concrete, and only describe what the diff shows. Respond with the comment text alone, without a preamble.`
+
+// targetPR resolves the pull request a status or comment request is about: the
+// number the user gave, or else the PR the thread's last job opened. own
+// reports whether Bob opened it in this thread. reply is set if there is none.
+func (o *Orchestrator) targetPR(ctx context.Context, intent IntentResult) (vcs VCSProvider, repo string, number int, own bool, reply string) {
+	channel, _ := ctx.Value(ctxKeyChannel).(string)
+	threadTS, _ := ctx.Value(ctxKeyThreadTS).(string)
+	threadPR, hasThreadPR := o.hub.ThreadPR(channel, threadTS)
+
+	repo, number = intent.Repo, intent.PR
+	if hasThreadPR && (repo == "" || repo == threadPR.Repo) {
+		if n, err := prNumberFromURL(threadPR.URL); err == nil && (number == 0 || number == n) {
+			repo, number, own = threadPR.Repo, n, true
+		}
+	}
+	if number == 0 || repo == "" {
+		return nil, "", 0, false, "Which pull request do you mean? Give me the repo and PR number, or ask in the thread of the job that opened it."
+	}
+	vcs, r, err := findRepo(ctx, o.providers, repo)
+	if err != nil {
+		return nil, "", 0, false, fmt.Sprintf("I couldn't find the repository *%s*. Please check the repository name and try again.", repo)
+	}
+	if r.Name != "" {
+		repo = r.Name
+	}
+	if deny := o.checkAccess(ctx, repo); deny != "" {
+		return nil, "", 0, false, deny
+	}
+	return vcs, repo, number, own, ""
+}
+
+// answerPRStatus replies with a pull request's state, reviews and checks.
+func (o *Orchestrator) answerPRStatus(ctx context.Context, intent IntentResult) (OrchestratorResult, error) {
+	vcs, repo, number, _, reply := o.targetPR(ctx, intent)
+	if reply != "" {
+		return OrchestratorResult{Text: reply}, nil
+	}
+	reader, ok := vcs.(prStatusReader)
+	if !ok {
+		return OrchestratorResult{Text: fmt.Sprintf("I can't look up pull requests on %s yet.", vcs.Name())}, nil
+	}
+	st, err := reader.PullRequestStatus(ctx, repo, number)
+	if err != nil {
+		slog.WarnContext(ctx, "orchestrator: failed to get PR status", "repo", repo, "pr", number, "err", err)
+		return OrchestratorResult{Text: fmt.Sprintf("I couldn't find PR #%d in *%s*.", number, repo)}, nil
+	}
+	var runs []CheckRun
+	if lister, ok := vcs.(checkRunLister); ok && st.HeadSHA != "" {
+		if runs, err = lister.ListCheckRuns(ctx, repo, st.HeadSHA); err != nil {
+			slog.WarnContext(ctx, "orchestrator: failed to list checks", "repo", repo, "pr", number, "err", err)
+		}
+	}
+	return OrchestratorResult{Text: formatPRStatus(st, runs)}, nil
+}
+
+// commentOnPR posts a comment written to intent.Task on a pull request Bob
+// opened in this thread. Other PRs are refused: Bob only speaks for its own.
+func (o *Orchestrator) commentOnPR(ctx context.Context, intent IntentResult) (OrchestratorResult, error) {
+	vcs, repo, number, own, reply := o.targetPR(ctx, intent)
+	if reply != "" {
+		return OrchestratorResult{Text: reply}, nil
+	}
+	if !own {
+		return OrchestratorResult{Text: fmt.Sprintf("I only comment on pull requests I opened, from the thread I opened them in. PR #%d in *%s* isn't one of them.", number, repo)}, nil
+	}
+	reader, canRead := vcs.(prReviewer)
+	commenter, canComment := vcs.(prCommenter)
+	if !canRead || !canComment {
+		return OrchestratorResult{Text: fmt.Sprintf("I can't comment on pull requests on %s yet.", vcs.Name())}, nil
+	}
+	pr, err := reader.GetPullRequest(ctx, repo, number)
+	if err != nil {
+		slog.WarnContext(ctx, "orchestrator: failed to get PR", "repo", repo, "pr", number, "err", err)
+		return OrchestratorResult{Text: fmt.Sprintf("I couldn't find PR #%d in *%s*.", number, repo)}, nil
+	}
+
+	diff := pr.Diff
+	if len(diff) > maxPRCommentDiff {
+		diff = prefix(diff, maxPRCommentDiff) + "\n... (diff truncated)"
+	}
+	resp, err := completeWithRetry(ctx, o.llm, LLMRequest{
+		System: prCommentSystemPrompt,
+		Messages: []Message{{Role: RoleUser, Content: fmt.Sprintf("## Requested comment\n\n%s\n\n## Pull request: %s\n\n%s\n\n## Diff\n\n```diff\n%s\n```",
+			intent.Task, pr.Title, pr.Body, diff)}},
+		MaxTokens: 2048,
+	}, nil)
+	if err != nil {
+		return OrchestratorResult{}, fmt.Errorf("write PR comment: %w", err)
+	}
+	body := strings.TrimSpace(resp.Text)
+	if body == "" {
+		return OrchestratorResult{Text: "I couldn't come up with a comment for that. Could you tell me more about what it should say?"}, nil
+	}
+	if err := commenter.CreateIssueComment(ctx, repo, number, redactSecrets(body)); err != nil {
+		slog.ErrorContext(ctx, "orchestrator: failed to comment on PR", "repo", repo, "pr", number, "err", err)
+		return OrchestratorResult{Text: fmt.Sprintf("I couldn't post the comment on %s: %s", pr.HTMLURL, err.Error())}, nil
+	}
+	slog.InfoContext(ctx, "orchestrator: commented on PR", "repo", repo, "pr", number, "cost_usd", resp.CostUSD)
+	return OrchestratorResult{Text: fmt.Sprintf("I commented on %s:\n\n%s", pr.HTMLURL, markdownToMrkdwn(body))}, nil
+}
+
+// formatPRStatus describes a pull request's status for chat.
+func formatPRStatus(st prStatus, runs []CheckRun) string {
+	var b strings.Builder
+	state := st.State
+	if st.State == "open" && st.Draft {
+		state = "an open draft"
+	}
+	fmt.Fprintf(&b, "*%s* (#%d) is %s: %s", st.Title, st.Number, state, st.URL)
+	if st.State != "open" {
+		return b.String()
+	}
+
+	byState := make(map[string][]string)
+	for user, s := range st.Reviews {
+		byState[s] = append(byState[s], user)
+	}
+	var reviews []string
+	for _, s := range []struct{ state, label string }{
+		{"approved", "approved by"}, {"changes_requested", "changes requested by"}, {"commented", "comments from"},
+	} {
+		if users := byState[s.state]; len(users) > 0 {
+			sort.Strings(users)
+			reviews = append(reviews, s.label+" "+strings.Join(users, ", "))
+		}
+	}
+	if len(reviews) == 0 {
+		reviews = []string{"none yet"}
+	}
+	fmt.Fprintf(&b, "\n• Reviews: %s", strings.Join(reviews, "; "))
+
+	if len(runs) > 0 {
+		var passed, running int
+		var failed []string
+		for _, r := range runs {
+			switch {
+			case r.Status != "completed":
+				running++
+			case r.failed():
+				failed = append(failed, r.Name)
+			default:
+				passed++
+			}
+		}
+		checks := []string{fmt.Sprintf("%d passed", passed)}
+		if len(failed) > 0 {
+			checks = append(checks, fmt.Sprintf("%d failed (%s)", len(failed), strings.Join(failed, ", ")))
+		}
+		if running > 0 {
+			checks = append(checks, fmt.Sprintf("%d running", running))
+		}
+		fmt.Fprintf(&b, "\n• Checks: %s", strings.Join(checks, ", "))
+	}
+
+	switch st.Mergeable {
+	case "clean", "has_hooks":
+		b.WriteString("\n• Ready to merge")
+	case "dirty":
+		b.WriteString("\n• Has conflicts with the base branch")
+	case "behind":
+		b.WriteString("\n• Behind the base branch")
+	case "blocked":
+		b.WriteString("\n• Blocked by branch protection (reviews or required checks)")
+	}
+	return b.String()
+}
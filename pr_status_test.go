@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPRStatusAndComment(t *testing.T) {
+	var commented string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /repos/acme/web", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"name":"web","default_branch":"main"}`))
+	})
+	mux.HandleFunc("GET /repos/acme/web/pulls/7", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "application/vnd.github.diff" {
+			w.Write([]byte("diff --git a/login.go b/login.go\n+fixed"))
+			return
+		}
+		w.Write([]byte(`{"title":"Fix login","html_url":"https://github.com/acme/web/pull/7","state":"open","merged":false,
+			"draft":false,"mergeable_state":"blocked","head":{"sha":"abc123"}}`))
+	})
+	mux.HandleFunc("GET /repos/acme/web/pulls/7/reviews", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"state":"CHANGES_REQUESTED","user":{"login":"bob"}},{"state":"APPROVED","user":{"login":"alice"}},
+			{"state":"APPROVED","user":{"login":"bob"}},{"state":"COMMENTED","user":{"login":"alice"}},{"state":"COMMENTED","user":{"login":"carol"}}]`))
+	})
+	mux.HandleFunc("GET /repos/acme/web/commits/abc123/check-runs", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"check_runs":[{"name":"test","status":"completed","conclusion":"failure"},
+			{"name":"lint","status":"completed","conclusion":"success"},{"name":"e2e","status":"in_progress"}]}`))
+	})
+	mux.HandleFunc("POST /repos/acme/web/issues/7/comments", func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Body string }
+		json.NewDecoder(r.Body).Decode(&body)
+		commented = body.Body
+		w.WriteHeader(http.StatusCreated)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	hub.RecordThreadPR("C1", "ts1", threadPR{JobID: "job-1", Repo: "web", URL: "https://github.com/acme/web/pull/7", Branch: "bob/fix-login"})
+	llm := &fakeLLM{resp: LLMResponse{Text: "This fixes the login redirect."}}
+	o := &Orchestrator{hub: hub, llm: llm, providers: []VCSProvider{&GitHubProvider{owner: "acme", token: "tok", apiURL: srv.URL}}}
+	thread := WithSlackThread(context.Background(), "C1", "ts1")
+
+	t.Run("status of the thread's PR", func(t *testing.T) {
+		result, err := o.dispatchIntent(thread, IntentResult{PRStatus: true}, nil)
+		if err != nil {
+			t.Fatalf("dispatchIntent: %v", err)
+		}
+		want := "*Fix login* (#7) is open: https://github.com/acme/web/pull/7\n" +
+			"• Reviews: approved by alice, bob; comments from carol\n" +
+			"• Checks: 1 passed, 1 failed (test), 1 running\n" +
+			"• Blocked by branch protection (reviews or required checks)"
+		if result.IsJob || result.Text != want {
+			t.Errorf("Text = %q, want %q", result.Text, want)
+		}
+	})
+
+	t.Run("no PR to ask about", func(t *testing.T) {
+		result, _ := o.dispatchIntent(WithSlackThread(context.Background(), "C1", "other"), IntentResult{PRStatus: true}, nil)
+		if !strings.Contains(result.Text, "Which pull request") {
+			t.Errorf("Text = %q", result.Text)
+		}
+	})
+
+	t.Run("comment on the thread's PR", func(t *testing.T) {
+		result, err := o.dispatchIntent(thread, IntentResult{PRComment: true, Task: "summarize the change for reviewers"}, nil)
+		if err != nil {
+			t.Fatalf("dispatchIntent: %v", err)
+		}
+		if commented != "This fixes the login redirect." || !strings.Contains(result.Text, "I commented on https://github.com/acme/web/pull/7") {
+			t.Errorf("commented %q, Text = %q", commented, result.Text)
+		}
+		if prompt := llm.req.Messages[0].Content; !strings.Contains(prompt, "summarize the change") || !strings.Contains(prompt, "+fixed") {
+			t.Errorf("prompt = %q", prompt)
+		}
+	})
+
+	t.Run("refuses to comment on another PR", func(t *testing.T) {
+		commented = ""
+		result, _ := o.dispatchIntent(WithSlackThread(context.Background(), "C1", "other"), IntentResult{PRComment: true, Repo: "web", PR: 7, Task: "say hi"}, nil)
+		if commented != "" || !strings.Contains(result.Text, "only comment on pull requests I opened") {
+			t.Errorf("commented %q, Text = %q", commented, result.Text)
+		}
+	})
+}
+
+func TestFormatPRStatus_Closed(t *testing.T) {
+	got := formatPRStatus(prStatus{Number: 3, Title: "Bump deps", URL: "https://x/3", State: "merged"}, nil)
+	if got != "*Bump deps* (#3) is merged: https://x/3" {
+		t.Errorf("formatPRStatus = %q", got)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// prTemplatePaths are where a repo's pull request template may live, in the
+// order GitHub looks (GitLab's default merge request template last).
+var prTemplatePaths = []string{
+	".github/pull_request_template.md",
+	".github/PULL_REQUEST_TEMPLATE.md",
+	"pull_request_template.md",
+	"PULL_REQUEST_TEMPLATE.md",
+	"docs/pull_request_template.md",
+	"docs/PULL_REQUEST_TEMPLATE.md",
+	".gitlab/merge_request_templates/Default.md",
+}
+
+// maxPRTemplate caps the template given to the LLM; a longer file is not a
+// template worth filling in.
+const maxPRTemplate = 10_000
+
+const prTemplateSystemPrompt = `You write pull request descriptions for a coding assistant. You're given the repository's pull request template, the task, the assistant's summary of what it changed, the test results and the changed files.
+
+Fill in the template: keep its headings, order and comments' intent, write each section from the information given, and tick a checklist item ("- [x]") only if the information shows it is done — leave the others unticked. Remove placeholder text you replaced. Don't invent facts, links or issue numbers; leave a section short rather than guess. Respond with the filled-in Markdown alone, without a preamble or code fences.`
+
+// readPRTemplate returns the repo's pull request template, or empty string if
+// it has none.
+func readPRTemplate(repoDir string) string {
+	for _, p := range prTemplatePaths {
+		data, err := os.ReadFile(filepath.Join(repoDir, p))
+		if err != nil {
+			continue
+		}
+		if len(data) > maxPRTemplate {
+			slog.Warn("orchestrator: PR template too long, not filling it in", "path", p, "bytes", len(data))
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+	return ""
+}
+
+// prDescription returns the body for a new pull request: the repo's template
+// filled in from summary (Claude Code's final response) and the test results,
+// or summary itself if the repo has no template or it can't be filled in.
+func (o *Orchestrator) prDescription(ctx context.Context, jobID, repoDir, task, summary string, vr verifyResult) string {
+	template := readPRTemplate(repoDir)
+	if template == "" || o.llm == nil {
+		return summary
+	}
+	files, err := changedFiles(ctx, repoDir)
+	if err != nil {
+		slog.WarnContext(ctx, "orchestrator: failed to list changed files for the PR template", "job_id", jobID, "err", err)
+	}
+	tests := "No test command was found, so no tests were run."
+	switch {
+	case vr.Skipped != "":
+		tests = vr.Skipped
+	case vr.Command != "" && vr.Passed:
+		tests = fmt.Sprintf("`%s` passed.", vr.Command)
+	case vr.Command != "":
+		tests = fmt.Sprintf("`%s` still fails after %d fix attempt(s).", vr.Command, vr.Attempts)
+	}
+	prompt := fmt.Sprintf("## Template\n\n%s\n\n## Task\n\n%s\n\n## Summary of the changes\n\n%s\n\n## Tests\n\n%s\n\n## Changed files\n\n%s",
+		template, task, summary, tests, strings.Join(files, "\n"))
+
+	body, err := o.runStep(ctx, jobID, "fill_pr_template", "", func(ctx context.Context) (string, error) {
+		resp, err := completeWithRetry(ctx, o.llm, LLMRequest{
+			System:    prTemplateSystemPrompt,
+			Messages:  []Message{{Role: RoleUser, Content: prompt}},
+			MaxTokens: 4096,
+		}, func(r LLMRetryData) { o.hub.Emit(jobID, r) })
+		if err != nil {
+			return "", err
+		}
+		o.hub.Emit(jobID, LLMResponseData{
+			Model:            resp.Model,
+			StopReason:       "end_turn",
+			Summary:          "PR template filled in",
+			InputTokens:      resp.InputTokens,
+			OutputTokens:     resp.OutputTokens,
+			CacheReadTokens:  resp.CacheReadTokens,
+			CacheWriteTokens: resp.CacheWriteTokens,
+			CostUSD:          resp.CostUSD,
+			Thinking:         resp.Thinking,
+		})
+		return strings.TrimSpace(resp.Text), nil
+	})
+	if err != nil || body == "" {
+		slog.WarnContext(ctx, "orchestrator: failed to fill in the PR template, using the summary", "job_id", jobID, "err", err)
+		return summary
+	}
+	return body
+}
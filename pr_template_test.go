@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPRDescription(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	drainHub(t)
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	if err := os.WriteFile(filepath.Join(dir, "login.go"), []byte("package web"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	llm := &fakeLLM{resp: LLMResponse{Text: "## Summary\n\nFixed the redirect.\n\n- [x] Tests pass\n", CostUSD: 0.01}}
+	o := &Orchestrator{hub: NewHub(t.TempDir()), llm: llm}
+	ctx := context.Background()
+	vr := verifyResult{Command: "go test ./...", Passed: true}
+
+	if got := o.prDescription(ctx, "job-1", dir, "fix login", "Fixed the redirect.", vr); got != "Fixed the redirect." {
+		t.Errorf("without a template: %q", got)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, ".github"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	template := "## Summary\n\n<!-- what and why -->\n\n- [ ] Tests pass"
+	if err := os.WriteFile(filepath.Join(dir, ".github", "pull_request_template.md"), []byte(template), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := o.prDescription(ctx, "job-1", dir, "fix login", "Fixed the redirect.", vr)
+	if got != "## Summary\n\nFixed the redirect.\n\n- [x] Tests pass" {
+		t.Errorf("filled in: %q", got)
+	}
+	prompt := llm.req.Messages[0].Content
+	for _, want := range []string{template, "fix login", "Fixed the redirect.", "`go test ./...` passed.", "login.go"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt is missing %q:\n%s", want, prompt)
+		}
+	}
+
+	o.llm = nil
+	if got := o.prDescription(ctx, "job-1", dir, "fix login", "Fixed the redirect.", vr); got != "Fixed the redirect." {
+		t.Errorf("without an LLM: %q", got)
+	}
+}
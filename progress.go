@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressPoster is implemented by chat platforms that can edit a message in
+// place, so a job's progress is one message per phase instead of many.
+type progressPoster interface {
+	// PostProgress posts a new message in the thread and returns its ID.
+	PostProgress(ctx context.Context, channel, threadTS, text string) (string, error)
+	// UpdateProgress replaces the text of a message posted by PostProgress.
+	UpdateProgress(ctx context.Context, channel, msgID, text string) error
+}
+
+// progressRefresh is how often a live progress message is re-rendered to
+// advance the elapsed time even when nothing else changed.
+const progressRefresh = 15 * time.Second
+
+// progressReporter keeps one live progress message per job phase, fed by Hub
+// events and edited in place at most every interval.
+type progressReporter struct {
+	hub      *Hub
+	posters  map[string]progressPoster // platform name → poster; "" is Slack
+	interval time.Duration
+
+	mu     sync.Mutex
+	jobs   map[string]*jobProgress // jobID → current phase
+	ending []*jobProgress          // ended phases awaiting their final render
+}
+
+// jobProgress is the state rendered into one phase's progress message.
+type jobProgress struct {
+	jobID      string
+	phase      JobPhase
+	phaseStart time.Time
+	step       string          // what Bob is doing right now
+	files      map[string]bool // paths as reported by Claude Code
+	testRuns   int
+	testsOK    bool
+	testCmd    string
+	final      string    // outcome, set when the phase ended
+	narration  []string  // Claude Code's text since the last post, posted in verbose threads
+	dirty      bool      // changed since last render
+	renderedAt time.Time // last post or update
+
+	// Resolved from JobState in flush, since observe may run under the job's
+	// lock.
+	resolved bool
+	poster   progressPoster // nil if the job's platform can't edit messages
+	channel  string
+	threadTS string
+	repoDir  string
+	msgID    string // empty until first posted
+}
+
+// newProgressReporter creates a reporter and starts its flush loop, which runs
+// until ctx is done.
+func newProgressReporter(ctx context.Context, hub *Hub, posters map[string]progressPoster, interval time.Duration) *progressReporter {
+	r := &progressReporter{hub: hub, posters: posters, interval: interval, jobs: make(map[string]*jobProgress)}
+	go r.run(ctx)
+	return r
+}
+
+func (r *progressReporter) run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.flush(ctx, time.Now())
+		}
+	}
+}
+
+// observe folds a Hub event into the job's progress. It is called from
+// Hub.Emit, possibly with the job's state locked, so it only touches the
+// reporter's own state.
+func (r *progressReporter) observe(e Event) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	jp := r.jobs[e.JobID]
+	switch d := e.Data.(type) {
+	case JobStartedData:
+		r.startPhase(e.JobID, PhasePlanning, e.Timestamp)
+		return
+	case PhaseChangedData:
+		phase := d.Phase
+		if jp != nil && jp.phase == JobPhase(phase) {
+			return
+		}
+		r.endPhase(e.JobID, e.Timestamp, "finished")
+		if JobPhase(phase) == PhasePlanning || JobPhase(phase) == PhaseImplementing {
+			r.startPhase(e.JobID, JobPhase(phase), e.Timestamp)
+		}
+		return
+	case JobCompletedData:
+		r.endPhase(e.JobID, e.Timestamp, "finished")
+		return
+	case JobErrorData:
+		r.endPhase(e.JobID, e.Timestamp, "failed")
+		return
+	case JobCancelledData:
+		r.endPhase(e.JobID, e.Timestamp, "cancelled")
+		return
+	case JobExpiredData:
+		r.endPhase(e.JobID, e.Timestamp, "expired")
+		return
+	}
+	if jp == nil {
+		return
+	}
+
+	switch d := e.Data.(type) {
+	case ToolStartedData:
+		if label := progressStepLabels[d.ToolName]; label != "" {
+			jp.step, jp.dirty = label, true
+		}
+		if d.ToolName == "run_tests" {
+			jp.testCmd = d.Input
+		}
+	case ToolCompletedData:
+		if d.ToolName == "run_tests" {
+			jp.testRuns++
+			jp.testsOK = !d.IsError
+			jp.dirty = true
+		}
+	case ClaudeCodeLineData:
+		if d.Text != "" && d.AgentID == "" {
+			jp.narration = append(jp.narration, d.Text)
+			if len(jp.narration) > maxNarrationLines {
+				jp.narration = jp.narration[len(jp.narration)-maxNarrationLines:]
+			}
+		}
+		input := d.ToolInput
+		switch d.ToolName {
+		case "Edit", "MultiEdit", "Write", "NotebookEdit":
+			if f := toolInputField(input, "file_path", "notebook_path"); f != "" && !strings.Contains(f, ".claude/plans/") {
+				jp.files[f] = true
+				jp.step, jp.dirty = "Editing `"+f+"`", true
+			}
+		case "Bash":
+			if cmd := toolInputField(input, "command"); cmd != "" {
+				jp.step, jp.dirty = "Running `"+truncate(firstLine(cmd), 60)+"`", true
+			}
+		case "Read", "Grep", "Glob":
+			jp.step, jp.dirty = "Exploring the code", true
+		}
+	}
+}
+
+// startPhase begins tracking a new phase of a job. Caller holds r.mu.
+func (r *progressReporter) startPhase(jobID string, phase JobPhase, at time.Time) {
+	r.jobs[jobID] = &jobProgress{jobID: jobID, phase: phase, phaseStart: at, files: make(map[string]bool), dirty: true}
+}
+
+// endPhase records the outcome of a job's current phase and queues it for a
+// final render. Caller holds r.mu.
+func (r *progressReporter) endPhase(jobID string, at time.Time, outcome string) {
+	jp := r.jobs[jobID]
+	if jp == nil {
+		return
+	}
+	delete(r.jobs, jobID)
+	jp.final = fmt.Sprintf("%s in %s", outcome, formatElapsed(at.Sub(jp.phaseStart)))
+	jp.step = ""
+	jp.dirty = true
+	r.ending = append(r.ending, jp)
+}
+
+// flush renders every phase whose progress changed (or whose elapsed time is
+// stale), and gives ended phases their final render. Quiet threads get no
+// progress messages; verbose ones also get Claude Code's text since the last
+// flush.
+func (r *progressReporter) flush(ctx context.Context, now time.Time) {
+	r.mu.Lock()
+	var unresolved []*jobProgress
+	threads := make(map[*jobProgress][2]string)
+	for _, jp := range slices.Concat(slices.Collect(maps.Values(r.jobs)), r.ending) {
+		// The worktree path is only known once cloning finishes.
+		if !jp.resolved || (jp.poster != nil && jp.repoDir == "") {
+			unresolved = append(unresolved, jp)
+		}
+		threads[jp] = [2]string{jp.channel, jp.threadTS}
+	}
+	r.mu.Unlock()
+	for _, jp := range unresolved {
+		r.resolve(jp)
+		threads[jp] = [2]string{jp.channel, jp.threadTS}
+	}
+	// The thread's verbosity can change mid-job; look it up every time.
+	verbosity := make(map[*jobProgress]threadVerbosity, len(threads))
+	for jp, t := range threads {
+		verbosity[jp] = r.hub.ThreadVerbosity(t[0], t[1])
+	}
+
+	type pending struct {
+		jp        *jobProgress
+		text      string
+		narration bool // posted as a new message, not the progress message
+	}
+	var work []pending
+	narrate := func(jp *jobProgress) {
+		if len(jp.narration) > 0 && verbosity[jp] == threadVerbose {
+			work = append(work, pending{jp, formatNarration(jp.narration), true})
+		}
+		jp.narration = nil
+	}
+	r.mu.Lock()
+	for jobID, jp := range r.jobs {
+		if !jp.resolved {
+			continue // started after the resolve pass; next tick
+		}
+		if jp.poster == nil {
+			delete(r.jobs, jobID)
+			continue
+		}
+		if verbosity[jp] != threadQuiet && (jp.dirty || now.Sub(jp.renderedAt) >= progressRefresh) {
+			work = append(work, pending{jp, jp.render(now), false})
+			jp.dirty, jp.renderedAt = false, now
+		}
+		narrate(jp)
+	}
+	for _, jp := range r.ending {
+		if jp.poster == nil {
+			continue
+		}
+		// A phase that ended before anything was posted gets no message; the
+		// result reply says the same thing. One posted before the thread went
+		// quiet still gets its outcome.
+		if jp.msgID != "" {
+			work = append(work, pending{jp, jp.render(now), false})
+		}
+		narrate(jp)
+	}
+	r.ending = nil
+	r.mu.Unlock()
+
+	for _, w := range work {
+		callCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		if w.narration {
+			if _, err := w.jp.poster.PostProgress(callCtx, w.jp.channel, w.jp.threadTS, w.text); err != nil {
+				slog.WarnContext(ctx, "progress: posting Claude Code's text failed", "job_id", w.jp.jobID, "err", err)
+			}
+		} else if w.jp.msgID == "" {
+			id, err := w.jp.poster.PostProgress(callCtx, w.jp.channel, w.jp.threadTS, w.text)
+			if err != nil {
+				slog.WarnContext(ctx, "progress: post failed", "job_id", w.jp.jobID, "err", err)
+			}
+			r.mu.Lock()
+			w.jp.msgID = id
+			r.mu.Unlock()
+		} else if err := w.jp.poster.UpdateProgress(callCtx, w.jp.channel, w.jp.msgID, w.text); err != nil {
+			slog.WarnContext(ctx, "progress: update failed", "job_id", w.jp.jobID, "err", err)
+		}
+		cancel()
+	}
+}
+
+// resolve looks up where a job's progress is posted.
+func (r *progressReporter) resolve(jp *jobProgress) {
+	var channel, threadTS, platform, repoDir string
+	if state, ok := r.hub.GetJobState(jp.jobID); ok {
+		state.mu.Lock()
+		channel, threadTS, platform, repoDir = state.Channel, state.ThreadTS, state.Platform, state.RepoDir
+		state.mu.Unlock()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	jp.resolved = true
+	if channel != "" && threadTS != "" {
+		jp.poster = r.posters[platform]
+	}
+	jp.channel, jp.threadTS, jp.repoDir = channel, threadTS, repoDir
+}
+
+// render formats the progress message.
+func (jp *jobProgress) render(now time.Time) string {
+	title := map[JobPhase]string{PhasePlanning: "Planning", PhaseImplementing: "Implementing"}[jp.phase]
+	var b strings.Builder
+	if jp.final != "" {
+		fmt.Fprintf(&b, "*%s* %s", title, jp.final)
+	} else {
+		fmt.Fprintf(&b, ":hourglass_flowing_sand: *%s* · %s", title, formatElapsed(now.Sub(jp.phaseStart)))
+	}
+	if len(jp.files) > 0 {
+		files := make([]string, 0, len(jp.files))
+		for f := range jp.files {
+			files = append(files, jp.relPath(f))
+		}
+		sort.Strings(files)
+		shown := files
+		if len(shown) > 5 {
+			shown = shown[:5]
+		}
+		fmt.Fprintf(&b, "\n• Files edited: %d (`%s`", len(files), strings.Join(shown, "`, `"))
+		if len(files) > len(shown) {
+			fmt.Fprintf(&b, ", +%d more", len(files)-len(shown))
+		}
+		b.WriteString(")")
+	}
+	if jp.testRuns > 0 {
+		status := "failing"
+		if jp.testsOK {
+			status = "passing"
+		}
+		fmt.Fprintf(&b, "\n• Tests: `%s` %s (%d run", jp.testCmd, status, jp.testRuns)
+		if jp.testRuns != 1 {
+			b.WriteString("s")
+		}
+		b.WriteString(")")
+	}
+	if jp.step != "" {
+		step := jp.step
+		if jp.repoDir != "" {
+			step = strings.ReplaceAll(step, jp.repoDir+"/", "")
+		}
+		fmt.Fprintf(&b, "\n• Now: %s", step)
+	}
+	return b.String()
+}
+
+// formatNarration quotes Claude Code's text lines for a verbose thread.
+func formatNarration(lines []string) string {
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString("> ")
+		b.WriteString(truncate(l, 500))
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// relPath shortens a file path from Claude Code to be relative to the worktree.
+func (jp *jobProgress) relPath(path string) string {
+	if jp.repoDir != "" {
+		if rel, err := filepath.Rel(jp.repoDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
+
+// progressStepLabels describes orchestrator steps (tool_started events).
+var progressStepLabels = map[string]string{
+	"clone_repo":          "Cloning the repository",
+	"generate_plan":       "Working on a plan",
+	"implement_changes":   "Implementing the plan",
+	"run_tests":           "Running the tests",
+	"fix_tests":           "Fixing failing tests",
+	"update_changelog":    "Adding a changelog entry",
+	"enforce_scope":       "Checking changed paths",
+	"create_pull_request": "Opening the pull request",
+	"update_pull_request": "Pushing to the pull request",
+}
+
+// toolInputField returns the first non-empty string field of a tool input JSON.
+func toolInputField(input string, keys ...string) string {
+	var m map[string]any
+	if json.Unmarshal([]byte(input), &m) != nil {
+		return ""
+	}
+	for _, k := range keys {
+		if v, ok := m[k].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// firstLine returns s up to its first newline.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+// formatElapsed renders a duration as "45s" or "3m 12s".
+func formatElapsed(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	return fmt.Sprintf("%dm %02ds", int(d.Minutes()), int(d.Seconds())%60)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePoster records progress posts and edits.
+type fakePoster struct {
+	mu      sync.Mutex
+	posts   []string
+	updates []string
+}
+
+func (f *fakePoster) PostProgress(ctx context.Context, channel, threadTS, text string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.posts = append(f.posts, text)
+	return "msg-" + string(rune('0'+len(f.posts))), nil
+}
+
+func (f *fakePoster) UpdateProgress(ctx context.Context, channel, msgID, text string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.updates = append(f.updates, msgID+": "+text)
+	return nil
+}
+
+func TestProgressReporter(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	poster := &fakePoster{}
+	r := &progressReporter{hub: hub, posters: map[string]progressPoster{"slack": poster}, interval: time.Second, jobs: make(map[string]*jobProgress)}
+	ctx := context.Background()
+
+	hub.SetJobState("job-1", &JobState{Channel: "C1", ThreadTS: "1.0", Platform: "slack", RepoDir: "/workspace/web/worktrees/job-1"})
+	hub.SetJobState("job-api", &JobState{Platform: ""}) // no thread: never posted
+
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	ev := func(jobID string, at time.Duration, data EventData) {
+		r.observe(Event{JobID: jobID, Type: data.EventType(), Timestamp: t0.Add(at), Data: data})
+	}
+
+	ev("job-1", 0, JobStartedData{})
+	ev("job-api", 0, JobStartedData{})
+	r.flush(ctx, t0.Add(time.Second))
+	if len(poster.posts) != 1 || !strings.Contains(poster.posts[0], "*Planning*") {
+		t.Fatalf("posts = %q", poster.posts)
+	}
+	if _, ok := r.jobs["job-api"]; ok {
+		t.Error("job without a thread should be dropped")
+	}
+
+	// Nothing changed and the refresh interval hasn't passed: no edit.
+	r.flush(ctx, t0.Add(2*time.Second))
+	if len(poster.updates) != 0 {
+		t.Fatalf("unexpected updates %q", poster.updates)
+	}
+
+	// Planning ends; implementation gets its own message.
+	ev("job-1", time.Minute, PhaseChangedData{Phase: "awaiting_approval"})
+	ev("job-1", 2*time.Minute, PhaseChangedData{Phase: "implementing"})
+	ev("job-1", 2*time.Minute, ClaudeCodeLineData{ToolName: "Edit", ToolInput: `{"file_path":"/workspace/web/worktrees/job-1/src/app.go"}`})
+	ev("job-1", 2*time.Minute, ClaudeCodeLineData{ToolName: "Write", ToolInput: `{"file_path":"/workspace/web/worktrees/job-1/src/app_test.go"}`})
+	ev("job-1", 3*time.Minute, ToolStartedData{ToolName: "run_tests", Input: "go test ./..."})
+	ev("job-1", 3*time.Minute, ToolCompletedData{ToolName: "run_tests"})
+	r.flush(ctx, t0.Add(3*time.Minute+12*time.Second))
+
+	if len(poster.updates) != 1 || poster.updates[0] != "msg-1: *Planning* finished in 1m 00s" {
+		t.Errorf("updates = %q", poster.updates)
+	}
+	if len(poster.posts) != 2 {
+		t.Fatalf("posts = %q", poster.posts)
+	}
+	want := ":hourglass_flowing_sand: *Implementing* · 1m 12s\n• Files edited: 2 (`src/app.go`, `src/app_test.go`)\n• Tests: `go test ./...` passing (1 run)\n• Now: Running the tests"
+	if poster.posts[1] != want {
+		t.Errorf("implementing message =\n%s\nwant\n%s", poster.posts[1], want)
+	}
+
+	// Stale elapsed time is refreshed even without new events.
+	r.flush(ctx, t0.Add(3*time.Minute+30*time.Second))
+	if len(poster.updates) != 2 || !strings.HasPrefix(poster.updates[1], "msg-2: :hourglass_flowing_sand: *Implementing* · 1m 30s") {
+		t.Errorf("updates = %q", poster.updates)
+	}
+
+	ev("job-1", 5*time.Minute, JobCompletedData{})
+	r.flush(ctx, t0.Add(5*time.Minute))
+	if last := poster.updates[len(poster.updates)-1]; !strings.HasPrefix(last, "msg-2: *Implementing* finished in 3m 00s\n• Files edited: 2") {
+		t.Errorf("final update = %q", last)
+	}
+	if len(r.jobs) != 0 || len(r.ending) != 0 {
+		t.Errorf("reporter still tracks jobs: %v %v", r.jobs, r.ending)
+	}
+}
+
+func TestProgressReporter_PhaseEndsBeforePost(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	poster := &fakePoster{}
+	r := &progressReporter{hub: hub, posters: map[string]progressPoster{"slack": poster}, interval: time.Second, jobs: make(map[string]*jobProgress)}
+	hub.SetJobState("job-1", &JobState{Channel: "C1", ThreadTS: "1.0", Platform: "slack"})
+
+	now := time.Now()
+	r.observe(Event{JobID: "job-1", Type: EventJobStarted, Timestamp: now})
+	r.observe(Event{JobID: "job-1", Type: EventJobError, Timestamp: now})
+	r.flush(context.Background(), now)
+	if len(poster.posts) != 0 || len(poster.updates) != 0 {
+		t.Errorf("expected no messages, got posts %q updates %q", poster.posts, poster.updates)
+	}
+}
+
+func TestProgressReporter_ThreadVerbosity(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	poster := &fakePoster{}
+	r := &progressReporter{hub: hub, posters: map[string]progressPoster{"slack": poster}, interval: time.Second, jobs: make(map[string]*jobProgress)}
+	ctx := context.Background()
+	hub.SetJobState("job-1", &JobState{Channel: "C1", ThreadTS: "1.0", Platform: "slack"})
+
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	ev := func(at time.Duration, data EventData) {
+		r.observe(Event{JobID: "job-1", Type: data.EventType(), Timestamp: t0.Add(at), Data: data})
+	}
+
+	// Quiet: no progress message, and Claude Code's text is dropped.
+	hub.SetThreadVerbosity("C1", "1.0", threadQuiet)
+	ev(0, JobStartedData{})
+	ev(time.Second, ClaudeCodeLineData{Text: "Looking at the router."})
+	r.flush(ctx, t0.Add(2*time.Second))
+	if len(poster.posts) != 0 || len(poster.updates) != 0 {
+		t.Fatalf("quiet thread: posts %q, updates %q", poster.posts, poster.updates)
+	}
+
+	// Verbose: the progress message, then the main session's text since the
+	// last flush; sub-agents' text stays out.
+	hub.SetThreadVerbosity("C1", "1.0", threadVerbose)
+	ev(3*time.Second, ClaudeCodeLineData{Text: "The handler is in api.go."})
+	ev(3*time.Second, ClaudeCodeLineData{Text: "Found 3 callers.", AgentID: "tu-agent"})
+	ev(3*time.Second, ClaudeCodeLineData{Text: "I'll add the route there."})
+	r.flush(ctx, t0.Add(4*time.Second))
+	if len(poster.posts) != 2 || !strings.Contains(poster.posts[0], "*Planning*") || poster.posts[1] != "> The handler is in api.go.\n> I'll add the route there." {
+		t.Fatalf("verbose thread: posts %q", poster.posts)
+	}
+
+	// Back to normal: progress updates only.
+	hub.SetThreadVerbosity("C1", "1.0", threadNormal)
+	ev(5*time.Second, ClaudeCodeLineData{Text: "Writing the plan."})
+	ev(time.Minute, PhaseChangedData{Phase: "awaiting_approval"})
+	r.flush(ctx, t0.Add(time.Minute))
+	if len(poster.posts) != 2 || len(poster.updates) != 1 || poster.updates[0] != "msg-1: *Planning* finished in 1m 00s" {
+		t.Errorf("normal thread: posts %q, updates %q", poster.posts, poster.updates)
+	}
+}
+
+func TestFormatElapsed(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                       "0s",
+		45 * time.Second:        "45s",
+		192*time.Second + 400e6: "3m 12s",
+		61 * time.Minute:        "61m 00s",
+	} {
+		if got := formatElapsed(d); got != want {
+			t.Errorf("formatElapsed(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// userLimitsPrune is how often idle limiters and past days' usage are dropped.
+const userLimitsPrune = 10 * time.Minute
+
+// userLimits rate-limits mentions per chat user and enforces optional daily
+// job and cost quotas, so one busy user can't starve everyone else. Users are
+// keyed by platform and user ID. Usage is kept in memory and days are UTC, so
+// a restart resets the day's counts.
+type userLimits struct {
+	perMinute    float64
+	maxDailyJobs int     // 0 = unlimited
+	maxDailyCost float64 // USD; 0 = unlimited
+	now          func() time.Time
+
+	mu         sync.Mutex
+	limiters   map[string]*rate.Limiter
+	usage      map[string]*userUsage
+	jobUsers   map[string]string // job ID → user key, for cost attribution
+	lastPruned time.Time
+}
+
+// userUsage is one user's usage on one day.
+type userUsage struct {
+	day     string // UTC date, 2006-01-02
+	jobs    int
+	costUSD float64
+}
+
+func newUserLimits(perMinute float64, maxDailyJobs int, maxDailyCost float64) *userLimits {
+	return &userLimits{
+		perMinute:    perMinute,
+		maxDailyJobs: maxDailyJobs,
+		maxDailyCost: maxDailyCost,
+		now:          time.Now,
+		limiters:     make(map[string]*rate.Limiter),
+		usage:        make(map[string]*userUsage),
+		jobUsers:     make(map[string]string),
+	}
+}
+
+// setLimits changes the limits, e.g. on a config reload. Usage so far today
+// counts against the new quotas.
+func (l *userLimits) setLimits(perMinute float64, maxDailyJobs int, maxDailyCost float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.perMinute, l.maxDailyJobs, l.maxDailyCost = perMinute, maxDailyJobs, maxDailyCost
+	for _, lim := range l.limiters {
+		lim.SetLimitAt(l.now(), rate.Limit(perMinute/60))
+		lim.SetBurstAt(l.now(), int(perMinute/60)+1)
+	}
+}
+
+func userKey(platform, user string) string { return platform + ":" + user }
+
+// allow reports whether the user may send another message now.
+func (l *userLimits) allow(platform, user string) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune()
+	key := userKey(platform, user)
+	lim, ok := l.limiters[key]
+	if !ok {
+		lim = rate.NewLimiter(rate.Limit(l.perMinute/60), int(l.perMinute/60)+1)
+		l.limiters[key] = lim
+	}
+	return lim.AllowN(l.now(), 1)
+}
+
+// quotaExceeded returns why the user can't start more work today, or "" if
+// they can. newJob also checks the daily job count; replies to an existing
+// job only check cost.
+func (l *userLimits) quotaExceeded(platform, user string, newJob bool) string {
+	if l == nil {
+		return ""
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	u := l.today(userKey(platform, user))
+	switch {
+	case newJob && l.maxDailyJobs > 0 && u.jobs >= l.maxDailyJobs:
+		return fmt.Sprintf("You've hit your quota of %d jobs for today. It resets at midnight UTC.", l.maxDailyJobs)
+	case l.maxDailyCost > 0 && u.costUSD >= l.maxDailyCost:
+		return fmt.Sprintf("You've hit your daily quota: your jobs have cost $%.2f today, against a $%.2f limit. It resets at midnight UTC.", u.costUSD, l.maxDailyCost)
+	}
+	return ""
+}
+
+// startJob counts a new job against the user and attributes its cost to them.
+func (l *userLimits) startJob(platform, user, jobID string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := userKey(platform, user)
+	l.today(key).jobs++
+	l.jobUsers[jobID] = key
+}
+
+// observe adds LLM costs to the requesting user's usage. It is called from
+// Hub.Emit, so it only takes the limits' own lock.
+func (l *userLimits) observe(e Event) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key, ok := l.jobUsers[e.JobID]
+	if !ok {
+		return
+	}
+	switch d := e.Data.(type) {
+	case LLMResponseData:
+		l.today(key).costUSD += d.CostUSD
+	case JobCompletedData, JobErrorData, JobCancelledData, JobExpiredData:
+		delete(l.jobUsers, e.JobID)
+	}
+}
+
+// today returns the user's usage for the current UTC day, resetting it when
+// the day has changed. Callers hold l.mu.
+func (l *userLimits) today(key string) *userUsage {
+	day := l.now().UTC().Format(time.DateOnly)
+	u, ok := l.usage[key]
+	if !ok || u.day != day {
+		u = &userUsage{day: day}
+		l.usage[key] = u
+	}
+	return u
+}
+
+// prune drops limiters that have refilled, which behave like new ones, and
+// usage from past days. Callers hold l.mu.
+func (l *userLimits) prune() {
+	now := l.now()
+	if now.Sub(l.lastPruned) < userLimitsPrune {
+		return
+	}
+	l.lastPruned = now
+	for key, lim := range l.limiters {
+		if lim.TokensAt(now) >= float64(lim.Burst()) {
+			delete(l.limiters, key)
+		}
+	}
+	day := now.UTC().Format(time.DateOnly)
+	for key, u := range l.usage {
+		if u.day != day {
+			delete(l.usage, key)
+		}
+	}
+}
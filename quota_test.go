@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUserLimits_Allow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newUserLimits(60, 0, 0) // one per second, burst 2
+	l.now = func() time.Time { return now }
+
+	for i := range 2 {
+		if !l.allow("slack", "U1") {
+			t.Fatalf("message %d should be allowed", i+1)
+		}
+	}
+	if l.allow("slack", "U1") {
+		t.Error("third message in a burst should be limited")
+	}
+	if !l.allow("slack", "U2") || !l.allow("discord", "U1") {
+		t.Error("other users should have their own limit")
+	}
+
+	now = now.Add(time.Second)
+	if !l.allow("slack", "U1") {
+		t.Error("limit should refill")
+	}
+
+	now = now.Add(userLimitsPrune)
+	l.allow("slack", "U3")
+	if len(l.limiters) != 1 {
+		t.Errorf("refilled limiters not pruned: %d left", len(l.limiters))
+	}
+}
+
+func TestUserLimits_Quotas(t *testing.T) {
+	now := time.Date(2025, 1, 1, 23, 0, 0, 0, time.UTC)
+	l := newUserLimits(60, 2, 1.0)
+	l.now = func() time.Time { return now }
+	cost := func(jobID string, usd float64) {
+		l.observe(Event{JobID: jobID, Type: EventLLMResponse, Data: LLMResponseData{CostUSD: usd}})
+	}
+
+	l.startJob("slack", "U1", "job-1")
+	cost("job-1", 0.4)
+	cost("job-other", 5) // not started through a mention: nobody's quota
+	if msg := l.quotaExceeded("slack", "U1", true); msg != "" {
+		t.Fatalf("unexpected quota message %q", msg)
+	}
+
+	l.startJob("slack", "U1", "job-2")
+	msg := l.quotaExceeded("slack", "U1", true)
+	if !strings.Contains(msg, "quota of 2 jobs") {
+		t.Errorf("job quota message = %q", msg)
+	}
+	if msg := l.quotaExceeded("slack", "U1", false); msg != "" {
+		t.Errorf("replies shouldn't count against the job quota: %q", msg)
+	}
+	if msg := l.quotaExceeded("slack", "U2", true); msg != "" {
+		t.Errorf("other user limited: %q", msg)
+	}
+
+	cost("job-2", 0.6)
+	msg = l.quotaExceeded("slack", "U1", false)
+	if !strings.Contains(msg, "$1.00 today, against a $1.00 limit") {
+		t.Errorf("cost quota message = %q", msg)
+	}
+
+	l.observe(Event{JobID: "job-2", Type: EventJobCompleted, Data: JobCompletedData{}})
+	if _, ok := l.jobUsers["job-2"]; ok {
+		t.Error("finished job still attributed")
+	}
+
+	now = now.Add(2 * time.Hour) // next UTC day
+	if msg := l.quotaExceeded("slack", "U1", true); msg != "" {
+		t.Errorf("quota should reset on a new day: %q", msg)
+	}
+}
+
+func TestUserLimits_Nil(t *testing.T) {
+	var l *userLimits
+	if !l.allow("slack", "U1") || l.quotaExceeded("slack", "U1", true) != "" {
+		t.Error("nil limits should allow everything")
+	}
+	l.startJob("slack", "U1", "job-1")
+	l.observe(Event{JobID: "job-1", Data: LLMResponseData{CostUSD: 1}})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// panicReply is what the requester is told when Bob crashes on their request.
+const panicReply = "Sorry, something went wrong on my side and I had to stop. The error has been logged — please try again."
+
+// recoverHandler answers a panicking handler with a 500 and logs the stack
+// instead of dropping the connection.
+func recoverHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			slog.ErrorContext(r.Context(), "http: panic", "method", r.Method, "path", r.URL.Path, "panic", rec, "stack", string(debug.Stack()))
+			http.Error(w, `{"error":"internal error"}`, http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// recoverGoroutine stops a panic from killing the process and logs its stack.
+// onPanic, if set, tells the user. It must be deferred directly:
+//
+//	defer recoverGoroutine("slack mention", func() { ... })
+func recoverGoroutine(name string, onPanic func()) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+	slog.Error(name+": panic", "panic", rec, "stack", string(debug.Stack()))
+	if onPanic != nil {
+		onPanic()
+	}
+}
+
+// recoverJob turns a panic in a job step into a job error: the job is closed
+// with job_error and the step returns panicReply for the caller to post, so the
+// thread doesn't hang. It must be deferred directly, after replaceIfCancelled.
+func (o *Orchestrator) recoverJob(ctx context.Context, jobID string, result *OrchestratorResult, err *error) {
+	rec := recover()
+	if rec == nil {
+		return
+	}
+	slog.ErrorContext(ctx, "orchestrator: panic in job", "job_id", jobID, "panic", rec, "stack", string(debug.Stack()))
+	o.closeJob(ctx, jobID, JobErrorData{Error: fmt.Sprintf("internal error: %v", rec)})
+	*result = OrchestratorResult{IsJob: true, JobID: jobID, Text: panicReply}
+	*err = nil
+}
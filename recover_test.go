@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecoverHandler(t *testing.T) {
+	h := recoverHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/jobs", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+}
+
+func TestRecoverGoroutine(t *testing.T) {
+	called := false
+	func() {
+		defer recoverGoroutine("test", func() { called = true })
+		panic("boom")
+	}()
+	if !called {
+		t.Error("onPanic not called")
+	}
+}
+
+func TestRecoverJob(t *testing.T) {
+	drainHub(t)
+	dir := t.TempDir()
+	hub := NewHub(dir)
+	o := &Orchestrator{hub: hub}
+	hub.SetJobState("job-1", &JobState{Phase: PhaseImplementing, Channel: "C1", ThreadTS: "ts1"})
+	hub.RegisterThreadJob("C1", "ts1", "job-1")
+
+	step := func() (result OrchestratorResult, err error) {
+		defer o.replaceIfCancelled("job-1", &result, &err)
+		defer o.recoverJob(context.Background(), "job-1", &result, &err)
+		var m map[string]int
+		m["x"] = 1 // nil map write panics
+		return OrchestratorResult{}, nil
+	}
+	result, err := step()
+	if err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+	if result.Text != panicReply || result.JobID != "job-1" {
+		t.Errorf("result = %+v", result)
+	}
+	if got := hub.ActiveJobForThread("C1", "ts1"); got != "" {
+		t.Errorf("ActiveJobForThread = %q, want the job closed", got)
+	}
+	state, _ := hub.GetJobState("job-1")
+	if state.Phase != PhaseDone {
+		t.Errorf("Phase = %q, want %q", state.Phase, PhaseDone)
+	}
+	waitIndexed(t, dir, "job-1") // indexed once the job_error is stored
+	if !hub.jobFinished("job-1") {
+		t.Error("expected a job_error event")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+const redacted = "[REDACTED]"
+
+// secretPattern matches one kind of credential by shape. The rule names
+// follow gitleaks' and are reported by the pre-commit scan (scanDiffSecrets).
+type secretPattern struct {
+	rule string
+	re   *regexp.Regexp
+}
+
+// secretPatterns match credentials by shape. Each pattern's first group, if
+// any, is kept so that `NAME=value` becomes `NAME=[REDACTED]`. Values never
+// contain quotes or backslashes, so redacting a raw JSON line keeps it valid.
+var secretPatterns = []secretPattern{
+	{"aws-access-token", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"github-pat", regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,}\b`)},
+	{"github-fine-grained-pat", regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}\b`)},
+	{"gitlab-pat", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}|\bxapp-[A-Za-z0-9-]{10,}`)},
+	{"anthropic-or-openai-api-key", regexp.MustCompile(`\bsk-(?:ant-)?[A-Za-z0-9_-]{20,}`)},
+	{"stripe-access-token", regexp.MustCompile(`\b(?:sk|rk)_(?:live|test)_[A-Za-z0-9]{20,}`)},
+	{"gcp-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"npm-access-token", regexp.MustCompile(`\bnpm_[A-Za-z0-9]{36}\b`)},
+	{"private-key", regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	// Env-style assignments of secret-looking names, as in .env files and `env` output.
+	{"generic-secret-assignment", regexp.MustCompile(`\b([A-Z][A-Z0-9_]*(?:SECRET|TOKEN|PASSWORD|PASSWD|API_KEY|ACCESS_KEY|PRIVATE_KEY)[A-Z0-9_]*\s*[=:]\s*(?:\\?["'])?)[^\s"'$\\]{8,}`)},
+}
+
+// knownSecrets holds the values of Bob's own credentials, longest first so a
+// secret containing another is masked whole.
+var knownSecrets atomic.Pointer[[]string]
+
+// registerSecrets adds credential values that must never appear in events,
+// chat messages or PR bodies. Values shorter than 8 characters are ignored,
+// since masking them would mangle ordinary text.
+func registerSecrets(values ...string) {
+	var secrets []string
+	if cur := knownSecrets.Load(); cur != nil {
+		secrets = append(secrets, *cur...)
+	}
+	for _, v := range values {
+		if v = strings.TrimSpace(v); len(v) >= 8 {
+			secrets = append(secrets, v)
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	knownSecrets.Store(&secrets)
+}
+
+// redactSecrets masks registered credential values and anything shaped like a
+// token, key or secret assignment in s. Claude Code sometimes echoes env vars
+// and .env contents, and test output can include them too.
+func redactSecrets(s string) string {
+	if cur := knownSecrets.Load(); cur != nil {
+		for _, secret := range *cur {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+	}
+	for _, p := range secretPatterns {
+		if p.re.NumSubexp() > 0 {
+			s = p.re.ReplaceAllString(s, "${1}"+redacted)
+		} else {
+			s = p.re.ReplaceAllLiteralString(s, redacted)
+		}
+	}
+	return s
+}
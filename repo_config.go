@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// repoConfigFile is the per-repo configuration file, read from the root of the
+// repo's default branch.
+const repoConfigFile = ".bob.yml"
+
+// RepoConfig is a repo's own configuration for Bob, from .bob.yml. Every field
+// is optional; the zero value means Bob's defaults.
+type RepoConfig struct {
+	TestCommand string   `yaml:"test_command"` // overrides detectTestCommand
+	BaseBranch  string   `yaml:"base_branch"`  // branch to work from and open PRs against (default: the repo's default branch)
+	Image       string   `yaml:"image"`        // sandbox image, when sandboxing is enabled
+	TestImage   string   `yaml:"test_image"`   // image the test command runs in (e.g. golang:1.23), sandboxed or not
+	Paths       []string `yaml:"paths"`        // limits changes to these directories or files
+	Prompt      string   `yaml:"prompt"`       // repo-specific instructions prepended to task prompts
+	Guidelines  []string `yaml:"guidelines"`   // files to read coding guidelines from (default: CONTRIBUTING.md); [] for none
+	Reviewers   []string `yaml:"reviewers"`    // requested as reviewers on opened PRs
+	Labels      []string `yaml:"labels"`       // added to opened PRs
+	Assignees   []string `yaml:"assignees"`    // assigned to opened PRs
+	Draft       bool     `yaml:"draft"`        // open PRs as drafts
+	DryRun      bool     `yaml:"dry_run"`      // post the diff and wait for "push it" before committing
+	Changelog   string   `yaml:"changelog"`    // file implementation jobs add a Keep a Changelog entry to (e.g. CHANGELOG.md)
+
+	// SparseCheckout checks out only Paths (and the files at the repo root) in
+	// job worktrees. Off by default, since builds and tests often need more.
+	SparseCheckout bool `yaml:"sparse_checkout"`
+
+	// SecurityScanners run on the changes before the PR is opened: gosec,
+	// npm-audit, trivy, gitleaks, or any other shell command. nil uses
+	// BOB_SECURITY_SCANNERS; [] runs none. With SecurityFix, Claude Code gets
+	// one pass at fixing the findings; what's left is listed in the PR body.
+	SecurityScanners []string `yaml:"security_scanners"`
+	SecurityFix      bool     `yaml:"security_fix"`
+
+	// Tools adds Claude Code tool rules by phase (plan, read, implement) to
+	// those of BOB_TOOL_POLICY_FILE, e.g. allowing "Bash(make test:*)" while
+	// implementing. The policy's denials still apply.
+	Tools map[string]ToolRules `yaml:"tools"`
+
+	guidelines    string // from the Guidelines files, read by loadRepoConfig
+	orgGuidelines string // from BOB_GUIDELINES_FILE, set by the orchestrator
+}
+
+// loadRepoConfig reads .bob.yml as committed at rev in the git repo at dir.
+// Reading the committed file rather than the working tree means changes made
+// during a job can't alter its own configuration. A missing file yields the
+// zero config. The repo's coding guidelines are read from the same commit.
+func loadRepoConfig(ctx context.Context, dir, rev string) (*RepoConfig, error) {
+	cmd := exec.CommandContext(ctx, "git", "show", rev+":"+repoConfigFile)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		// git show fails the same way for a missing file and a bad rev; tell
+		// them apart so a broken clone isn't mistaken for an unconfigured repo.
+		check := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+		check.Dir = dir
+		if check.Run() != nil {
+			return nil, fmt.Errorf("resolve %s: %w", rev, err)
+		}
+		out = nil
+	}
+	cfg, err := parseRepoConfig(out)
+	if err != nil {
+		return nil, err
+	}
+	files := cfg.Guidelines
+	if files == nil {
+		files = defaultGuidelinesFiles
+	}
+	cfg.guidelines = loadRepoGuidelines(ctx, dir, rev, files)
+	return cfg, nil
+}
+
+// parseRepoConfig parses and validates the contents of a .bob.yml.
+func parseRepoConfig(data []byte) (*RepoConfig, error) {
+	var cfg RepoConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse %s: %w", repoConfigFile, err)
+	}
+
+	if cfg.BaseBranch != "" && !isValidBranchName(cfg.BaseBranch) {
+		return nil, fmt.Errorf("%s: invalid base_branch %q", repoConfigFile, cfg.BaseBranch)
+	}
+	for key, image := range map[string]string{"image": cfg.Image, "test_image": cfg.TestImage} {
+		if image != "" && !reImageRef.MatchString(image) {
+			return nil, fmt.Errorf("%s: invalid %s %q", repoConfigFile, key, image)
+		}
+	}
+	paths, err := cleanScopePaths(cfg.Paths)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", repoConfigFile, err)
+	}
+	cfg.Paths = paths
+	if cfg.Changelog != "" {
+		files, err := cleanScopePaths([]string{cfg.Changelog})
+		if err != nil || len(files) != 1 {
+			return nil, fmt.Errorf("%s: invalid changelog %q", repoConfigFile, cfg.Changelog)
+		}
+		cfg.Changelog = files[0]
+	}
+	if err := checkToolPhases(cfg.Tools); err != nil {
+		return nil, fmt.Errorf("%s: tools: %w", repoConfigFile, err)
+	}
+	if cfg.Guidelines != nil {
+		files, err := cleanScopePaths(cfg.Guidelines)
+		if err != nil {
+			return nil, fmt.Errorf("%s: guidelines: %w", repoConfigFile, err)
+		}
+		cfg.Guidelines = append([]string{}, files...)
+	}
+	return &cfg, nil
+}
+
+// cleanScopePaths normalizes scope paths to be relative to the repo root,
+// without leading or trailing slashes, dropping empty ones.
+func cleanScopePaths(paths []string) ([]string, error) {
+	var clean []string
+	for _, p := range paths {
+		p = strings.Trim(path.Clean(strings.TrimSpace(p)), "/")
+		if p == "" || p == "." {
+			continue
+		}
+		if p == ".." || strings.HasPrefix(p, "../") {
+			return nil, fmt.Errorf("path %q is outside the repo", p)
+		}
+		clean = append(clean, p)
+	}
+	return clean, nil
+}
+
+// isValidBranchName is a conservative check for branch names taken from a
+// repo's config, since they end up on git command lines.
+func isValidBranchName(name string) bool {
+	if name == "" || len(name) > 200 || strings.HasPrefix(name, "-") || strings.Contains(name, "..") {
+		return false
+	}
+	for _, r := range name {
+		if !((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || strings.ContainsRune("-_./", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveBaseBranch picks the branch a job starts from and opens its PR
+// against: the one the user asked for, else the repo's base_branch, else the
+// repo's default branch on the provider, else main.
+func resolveBaseBranch(requested string, cfg *RepoConfig, defaultBranch string) string {
+	switch {
+	case requested != "":
+		return requested
+	case cfg != nil && cfg.BaseBranch != "":
+		return cfg.BaseBranch
+	case defaultBranch != "":
+		return defaultBranch
+	}
+	return "main"
+}
+
+// inScope reports whether file (relative to the repo root) may be changed.
+func (c *RepoConfig) inScope(file string) bool {
+	if c == nil || len(c.Paths) == 0 {
+		return true
+	}
+	for _, p := range c.Paths {
+		if file == p || strings.HasPrefix(file, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// scopedTo returns the config with its paths narrowed to the ones a job was
+// limited to. A job path within the repo's paths is kept; one containing some
+// of them keeps those. It fails if nothing of the job's scope is left.
+func (c *RepoConfig) scopedTo(paths []string) (*RepoConfig, error) {
+	if len(paths) == 0 {
+		return c, nil
+	}
+	scoped := RepoConfig{}
+	if c != nil {
+		scoped = *c
+	}
+	scoped.Paths = nil
+	for _, p := range paths {
+		if c.inScope(p) {
+			scoped.Paths = append(scoped.Paths, p)
+			continue
+		}
+		for _, allowed := range c.Paths {
+			if strings.HasPrefix(allowed, p+"/") {
+				scoped.Paths = append(scoped.Paths, allowed)
+			}
+		}
+	}
+	if len(scoped.Paths) == 0 {
+		return nil, fmt.Errorf("%s isn't within the paths %s allows (%s)", strings.Join(paths, ", "), repoConfigFile, strings.Join(c.Paths, ", "))
+	}
+	return &scoped, nil
+}
+
+// promptPreamble returns the coding guidelines, the repo's instructions and
+// its path scope formatted for the top of a task prompt, or empty string if
+// there are none.
+func (c *RepoConfig) promptPreamble() string {
+	if c == nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(guidelinesPreamble(c.orgGuidelines, c.guidelines))
+	if c.Prompt != "" {
+		fmt.Fprintf(&b, "## Repository instructions\n\n%s\n\n", strings.TrimSpace(c.Prompt))
+	}
+	if len(c.Paths) > 0 {
+		b.WriteString("## Scope\n\nOnly change files under these paths; changes elsewhere will be discarded:\n\n")
+		for _, p := range c.Paths {
+			fmt.Fprintf(&b, "- `%s`\n", p)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// prOptions returns how the repo wants new PRs opened.
+func (c *RepoConfig) prOptions() PROptions {
+	if c == nil {
+		return PROptions{}
+	}
+	return PROptions{
+		Draft:     c.Draft,
+		Reviewers: mergeNames(c.Reviewers),
+		Labels:    mergeNames(c.Labels),
+		Assignees: mergeNames(c.Assignees),
+	}
+}
+
+// sandboxFor returns the container spec for a repo, using the repo's own image
+// unless the operator configured one for it.
+func (o *Orchestrator) sandboxFor(repo string, cfg *RepoConfig) *sandboxSpec {
+	sb := o.sandbox.forRepo(repo)
+	if sb == nil || cfg == nil || cfg.Image == "" {
+		return sb
+	}
+	if _, ok := o.sandbox.repoImages[repo]; !ok {
+		sb.image = cfg.Image
+	}
+	return sb
+}
+
+// testSandboxFor returns the container spec the repo's test command runs in:
+// a container of the repo's test_image if it sets one, whether or not
+// sessions are sandboxed, and otherwise the sessions' own.
+func (o *Orchestrator) testSandboxFor(repo string, cfg *RepoConfig) *sandboxSpec {
+	if cfg == nil || cfg.TestImage == "" {
+		return o.sandboxFor(repo, cfg)
+	}
+	return &sandboxSpec{image: cfg.TestImage, volume: o.sandbox.workspaceVolume(), repoNetwork: o.sandbox.repoNetwork()}
+}
+
+// repoConfig returns a job's repo config, loading it from the worktree's HEAD
+// if the job doesn't have it yet (e.g. after a restart).
+func (o *Orchestrator) repoConfig(ctx context.Context, jobID string) (*RepoConfig, error) {
+	state, ok := o.hub.GetJobState(jobID)
+	if !ok {
+		return nil, fmt.Errorf("no state for job %s", jobID)
+	}
+	state.mu.Lock()
+	cfg, repoDir := state.repoConfig, state.RepoDir
+	state.mu.Unlock()
+	if cfg != nil {
+		return cfg, nil
+	}
+	cfg, err := loadRepoConfig(ctx, repoDir, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	cfg.orgGuidelines = o.orgGuidelines(ctx)
+	state.mu.Lock()
+	paths := state.Paths
+	state.mu.Unlock()
+	if cfg, err = cfg.scopedTo(paths); err != nil {
+		return nil, err
+	}
+	state.mu.Lock()
+	state.repoConfig = cfg
+	state.mu.Unlock()
+	return cfg, nil
+}
+
+// prAnnotator is implemented by providers that can request reviewers, add
+// labels and set assignees on an opened pull request.
+type prAnnotator interface {
+	AnnotatePullRequest(ctx context.Context, name, prURL string, opts PROptions) error
+}
+
+// prepareBaseClone ensures the base clone exists, reads the repo's config from
+// its default branch, and leaves FETCH_HEAD at the tip of the job's base branch
+// (see resolveBaseBranch) for CreateWorktree.
+func (o *Orchestrator) prepareBaseClone(ctx context.Context, vcs VCSProvider, repo, defaultBranch, requested string) (baseDir string, cfg *RepoConfig, base string, err error) {
+	if defaultBranch == "" {
+		defaultBranch = "main"
+	}
+	baseDir, err = EnsureBaseClone(ctx, vcs, repo, defaultBranch)
+	if err != nil {
+		return "", nil, "", err
+	}
+	cfg, err = loadRepoConfig(ctx, baseDir, "FETCH_HEAD")
+	if err != nil {
+		return "", nil, "", err
+	}
+	cfg.orgGuidelines = o.orgGuidelines(ctx)
+	base = resolveBaseBranch(requested, cfg, defaultBranch)
+	if base != defaultBranch {
+		if err := FetchBranch(ctx, baseDir, vcs, repo, base); err != nil {
+			return "", nil, "", err
+		}
+	}
+	return baseDir, cfg, base, nil
+}
+
+// enforceScope discards changes outside the repo's configured paths.
+func (o *Orchestrator) enforceScope(ctx context.Context, jobID, repoDir string, cfg *RepoConfig) error {
+	if cfg == nil || len(cfg.Paths) == 0 {
+		return nil
+	}
+	files, err := changedFiles(ctx, repoDir)
+	if err != nil {
+		return err
+	}
+	var outside []string
+	for _, f := range files {
+		if !cfg.inScope(f) {
+			outside = append(outside, f)
+		}
+	}
+	if len(outside) == 0 {
+		return nil
+	}
+	slog.InfoContext(ctx, "orchestrator: discarding changes outside the configured paths", "job_id", jobID, "files", len(outside))
+	_, err = o.runStep(ctx, jobID, "enforce_scope", strings.Join(cfg.Paths, ", "), func(ctx context.Context) (string, error) {
+		return "discarded " + strings.Join(outside, ", "), DiscardChanges(ctx, repoDir, outside)
+	})
+	return err
+}
+
+// annotatePullRequest requests reviewers, adds labels and sets assignees per
+// opts. Failures are logged, not fatal: the PR is already open.
+func annotatePullRequest(ctx context.Context, vcs VCSProvider, repo, prURL string, opts PROptions) {
+	if !opts.annotations() {
+		return
+	}
+	a, ok := vcs.(prAnnotator)
+	if !ok {
+		slog.InfoContext(ctx, "orchestrator: provider doesn't support reviewers or labels, skipping", "provider", vcs.Name(), "pr", prURL)
+		return
+	}
+	if err := a.AnnotatePullRequest(ctx, repo, prURL, opts); err != nil {
+		slog.WarnContext(ctx, "orchestrator: failed to set reviewers/labels/assignees", "pr", prURL, "err", err)
+	}
+}
+
+// prNumberFromURL extracts the PR (or MR) number from its web URL, which ends
+// in the number for GitHub, GitLab and Azure DevOps.
+func prNumberFromURL(prURL string) (int, error) {
+	n, err := strconv.Atoi(path.Base(strings.TrimSuffix(prURL, "/")))
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("no PR number in %q", prURL)
+	}
+	return n, nil
+}
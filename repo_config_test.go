@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseRepoConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    RepoConfig
+		wantErr bool
+	}{
+		{name: "empty", yaml: "", want: RepoConfig{}},
+		{
+			name: "all fields",
+			yaml: "test_command: npm test\nbase_branch: develop\nimage: node:22\npaths: [web/, ./docs]\nprompt: Use pnpm.\nreviewers: [alice, acme/web]\nlabels: [bob]\nassignees: [carol]\ndraft: true\n",
+			want: RepoConfig{
+				TestCommand: "npm test", BaseBranch: "develop", Image: "node:22",
+				Paths: []string{"web", "docs"}, Prompt: "Use pnpm.",
+				Reviewers: []string{"alice", "acme/web"}, Labels: []string{"bob"},
+				Assignees: []string{"carol"}, Draft: true,
+			},
+		},
+		{name: "unknown field", yaml: "test_cmd: make\n", wantErr: true},
+		{name: "invalid yaml", yaml: "paths: [", wantErr: true},
+		{name: "option-like base branch", yaml: "base_branch: --upload-pack=x\n", wantErr: true},
+		{name: "path outside repo", yaml: "paths: [../other]\n", wantErr: true},
+		{
+			name: "tool rules",
+			yaml: "tools:\n  implement:\n    allow: [\"Bash(make test:*)\"]\n",
+			want: RepoConfig{Tools: map[string]ToolRules{toolPhaseImplement: {Allow: []string{"Bash(make test:*)"}}}},
+		},
+		{name: "tool rules for an unknown phase", yaml: "tools:\n  deploy:\n    allow: [Bash]\n", wantErr: true},
+		{name: "test image", yaml: "test_image: golang:1.23\n", want: RepoConfig{TestImage: "golang:1.23"}},
+		{name: "option-like test image", yaml: "test_image: --privileged\n", wantErr: true},
+		{name: "image with spaces", yaml: "image: node:20 -v /:/host\n", wantErr: true},
+		{name: "changelog", yaml: "changelog: ./docs/CHANGELOG.md\n", want: RepoConfig{Changelog: "docs/CHANGELOG.md"}},
+		{name: "changelog outside repo", yaml: "changelog: ../CHANGELOG.md\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRepoConfig([]byte(tt.yaml))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got.Paths) == 0 {
+				got.Paths = nil
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveBaseBranch(t *testing.T) {
+	tests := []struct {
+		name          string
+		requested     string
+		cfg           *RepoConfig
+		defaultBranch string
+		want          string
+	}{
+		{"fallback", "", nil, "", "main"},
+		{"provider default", "", &RepoConfig{}, "master", "master"},
+		{"config wins over default", "", &RepoConfig{BaseBranch: "develop"}, "master", "develop"},
+		{"request wins over config", "release/2.1", &RepoConfig{BaseBranch: "develop"}, "master", "release/2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveBaseBranch(tt.requested, tt.cfg, tt.defaultBranch); got != tt.want {
+				t.Errorf("resolveBaseBranch = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepoConfig_Defaults(t *testing.T) {
+	var cfg *RepoConfig
+	if !cfg.inScope("any/file.go") {
+		t.Error("nil config should allow every path")
+	}
+	if cfg.promptPreamble() != "" {
+		t.Error("nil config should have no preamble")
+	}
+}
+
+func TestRepoConfig_InScope(t *testing.T) {
+	cfg := &RepoConfig{Paths: []string{"web", "go.mod"}}
+	for file, want := range map[string]bool{
+		"web/index.ts": true,
+		"web":          true,
+		"go.mod":       true,
+		"webapp/x.ts":  false,
+		"api/main.go":  false,
+	} {
+		if got := cfg.inScope(file); got != want {
+			t.Errorf("inScope(%q) = %v, want %v", file, got, want)
+		}
+	}
+}
+
+func TestRepoConfig_ScopedTo(t *testing.T) {
+	scoped, err := (*RepoConfig)(nil).scopedTo([]string{"services/payments"})
+	if err != nil || !reflect.DeepEqual(scoped.Paths, []string{"services/payments"}) {
+		t.Errorf("no config: paths = %v, err = %v", scoped, err)
+	}
+
+	cfg := &RepoConfig{Paths: []string{"services/payments", "services/billing", "web"}, SparseCheckout: true}
+	for _, tc := range []struct {
+		paths []string
+		want  []string
+	}{
+		{nil, cfg.Paths},
+		{[]string{"services/payments/api"}, []string{"services/payments/api"}},
+		{[]string{"services"}, []string{"services/payments", "services/billing"}},
+		{[]string{"web", "docs"}, []string{"web"}},
+	} {
+		scoped, err := cfg.scopedTo(tc.paths)
+		if err != nil {
+			t.Fatalf("scopedTo(%v): %v", tc.paths, err)
+		}
+		if !reflect.DeepEqual(scoped.Paths, tc.want) || !scoped.SparseCheckout {
+			t.Errorf("scopedTo(%v) = %+v, want paths %v", tc.paths, scoped, tc.want)
+		}
+	}
+	if _, err := cfg.scopedTo([]string{"docs"}); err == nil {
+		t.Error("expected an error for a path outside the config's scope")
+	}
+}
+
+func TestCleanScopePaths(t *testing.T) {
+	got, err := cleanScopePaths([]string{" services/payments/ ", "/web", "", ".", "a/../b"})
+	if err != nil || !reflect.DeepEqual(got, []string{"services/payments", "web", "b"}) {
+		t.Errorf("cleanScopePaths = %v, %v", got, err)
+	}
+	for _, p := range []string{"..", "../other", "a/../../b"} {
+		if _, err := cleanScopePaths([]string{p}); err == nil {
+			t.Errorf("cleanScopePaths(%q): expected an error", p)
+		}
+	}
+}
+
+func TestRepoConfig_PromptPreamble(t *testing.T) {
+	cfg := &RepoConfig{Prompt: "Use pnpm.\n", Paths: []string{"web"}}
+	want := "## Repository instructions\n\nUse pnpm.\n\n## Scope\n\nOnly change files under these paths; changes elsewhere will be discarded:\n\n- `web`\n\n"
+	if got := cfg.promptPreamble(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestPRNumberFromURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    int
+		wantErr bool
+	}{
+		{"https://github.com/acme/web/pull/42", 42, false},
+		{"https://gitlab.com/grp/api/-/merge_requests/7/", 7, false},
+		{"https://github.com/acme/web/pulls", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := prNumberFromURL(tt.url)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("prNumberFromURL(%q) = %d, %v", tt.url, got, err)
+		}
+	}
+}
+
+// gitRepo creates a repo in a temp dir with files committed on main.
+func gitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+	run("init", "-q", "-b", "main")
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("add", "-A")
+	run("commit", "-q", "--allow-empty", "-m", "init")
+	return dir
+}
+
+func TestLoadRepoConfig(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("committed file", func(t *testing.T) {
+		dir := gitRepo(t, map[string]string{".bob.yml": "test_command: make check\n"})
+		// Uncommitted edits are ignored.
+		os.WriteFile(filepath.Join(dir, ".bob.yml"), []byte("test_command: rm -rf /\n"), 0o644)
+		cfg, err := loadRepoConfig(ctx, dir, "HEAD")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.TestCommand != "make check" {
+			t.Errorf("TestCommand = %q", cfg.TestCommand)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		dir := gitRepo(t, map[string]string{"README.md": "hi"})
+		cfg, err := loadRepoConfig(ctx, dir, "HEAD")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(*cfg, RepoConfig{}) {
+			t.Errorf("got %+v, want zero config", *cfg)
+		}
+	})
+
+	t.Run("guidelines", func(t *testing.T) {
+		dir := gitRepo(t, map[string]string{".github/CONTRIBUTING.md": "Wrap errors with %w.\n", "docs/STYLE.md": "Use slog.\n"})
+		cfg, err := loadRepoConfig(ctx, dir, "HEAD")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.guidelines != "Wrap errors with %w." {
+			t.Errorf("guidelines = %q", cfg.guidelines)
+		}
+
+		os.WriteFile(filepath.Join(dir, ".bob.yml"), []byte("guidelines: [docs/STYLE.md]\n"), 0o644)
+		runGit(t, dir, "add", ".")
+		runGit(t, dir, "commit", "-qm", "config")
+		if cfg, _ = loadRepoConfig(ctx, dir, "HEAD"); cfg.guidelines != "Use slog." {
+			t.Errorf("guidelines = %q, want docs/STYLE.md", cfg.guidelines)
+		}
+	})
+
+	t.Run("bad rev", func(t *testing.T) {
+		dir := gitRepo(t, nil)
+		if _, err := loadRepoConfig(ctx, dir, "FETCH_HEAD"); err == nil {
+			t.Error("expected error for unresolvable rev")
+		}
+	})
+}
+
+func TestGitHubProvider_AnnotatePullRequest(t *testing.T) {
+	var reviewers, labels, assignees map[string][]string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /repos/acme/web/pulls/42/requested_reviewers", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&reviewers)
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("POST /repos/acme/web/issues/42/labels", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&labels)
+		w.Write([]byte(`[]`))
+	})
+	mux.HandleFunc("POST /repos/acme/web/issues/42/assignees", func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&assignees)
+		w.WriteHeader(http.StatusCreated)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	gh := &GitHubProvider{owner: "acme", token: "tok", apiURL: srv.URL}
+	err := gh.AnnotatePullRequest(context.Background(), "web", "https://github.com/acme/web/pull/42", PROptions{
+		Reviewers:     []string{"@alice", "acme/frontend"},
+		TeamReviewers: []string{"backend"},
+		Labels:        []string{"bob", "automated"},
+		Assignees:     []string{"carol"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(reviewers["reviewers"], []string{"alice"}) || !reflect.DeepEqual(reviewers["team_reviewers"], []string{"backend", "frontend"}) {
+		t.Errorf("reviewers = %v", reviewers)
+	}
+	if !reflect.DeepEqual(labels["labels"], []string{"bob", "automated"}) {
+		t.Errorf("labels = %v", labels)
+	}
+	if !reflect.DeepEqual(assignees["assignees"], []string{"carol"}) {
+		t.Errorf("assignees = %v", assignees)
+	}
+}
+
+func TestPROptions(t *testing.T) {
+	cfg := &RepoConfig{Reviewers: []string{"alice", "acme/web"}, Labels: []string{"bob"}}
+	hints := PROptions{Draft: true, Reviewers: []string{"@alice", " dave ", ""}, TeamReviewers: []string{"@backend-team"}}
+	got := cfg.prOptions().merge(hints)
+	want := PROptions{
+		Draft:         true,
+		Reviewers:     []string{"alice", "acme/web", "dave"},
+		TeamReviewers: []string{"backend-team"},
+		Labels:        []string{"bob"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if (*RepoConfig)(nil).prOptions().annotations() {
+		t.Error("nil config should have no annotations")
+	}
+	many := make([]string, 2*maxPROptionNames)
+	for i := range many {
+		many[i] = fmt.Sprintf("user%d", i)
+	}
+	if n := len(mergeNames(many)); n != maxPROptionNames {
+		t.Errorf("mergeNames kept %d names, want %d", n, maxPROptionNames)
+	}
+}
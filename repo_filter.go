@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync/atomic"
+)
+
+// repoFilter restricts Bob to a subset of the org's repositories with glob
+// patterns (path.Match syntax, case-insensitive) on the repo name. A pattern
+// with a slash matches "owner/repo" names, one without matches the repo's name
+// in any owner. A repo is
+// permitted if it matches no blocklist pattern and, when there is an
+// allowlist, at least one allowlist pattern. Blocked repos look like they
+// don't exist: they are never found, listed or cloned.
+type repoFilter struct {
+	allow []string
+	block []string
+}
+
+// repoRules is the filter from REPO_ALLOWLIST and REPO_BLOCKLIST, set at
+// startup and on config reloads; nil permits every repo.
+var repoRules atomic.Pointer[repoFilter]
+
+// parseRepoFilter parses comma-separated allow and block patterns. It returns
+// nil if both are empty.
+func parseRepoFilter(allow, block string) (*repoFilter, error) {
+	f := &repoFilter{}
+	for _, list := range []struct {
+		raw string
+		dst *[]string
+	}{{allow, &f.allow}, {block, &f.block}} {
+		for _, p := range strings.Split(list.raw, ",") {
+			p = strings.ToLower(strings.TrimSpace(p))
+			if p == "" {
+				continue
+			}
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("invalid repo pattern %q: %w", p, err)
+			}
+			*list.dst = append(*list.dst, p)
+		}
+	}
+	if len(f.allow) == 0 && len(f.block) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// permits reports whether Bob may work on the named repo.
+func (f *repoFilter) permits(name string) bool {
+	if f == nil {
+		return true
+	}
+	name = strings.ToLower(name)
+	if matchesAny(f.block, name) {
+		return false
+	}
+	return len(f.allow) == 0 || matchesAny(f.allow, name)
+}
+
+// filter returns the permitted repos.
+func (f *repoFilter) filter(repos []repo) []repo {
+	if f == nil {
+		return repos
+	}
+	out := make([]repo, 0, len(repos))
+	for _, r := range repos {
+		if f.permits(r.Name) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func matchesAny(patterns []string, name string) bool {
+	_, short := splitRepoName(name)
+	for _, p := range patterns {
+		target := short
+		if strings.Contains(p, "/") {
+			target = name
+		}
+		if ok, _ := path.Match(p, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// errRepoNotPermitted is returned for repos excluded by repoRules.
+func errRepoNotPermitted(name string) error {
+	return fmt.Errorf("repository %q is excluded by REPO_ALLOWLIST/REPO_BLOCKLIST", name)
+}
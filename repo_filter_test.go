@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRepoFilter(t *testing.T) {
+	f, err := parseRepoFilter("api, web-*, payments-*", "*-secrets, payments-infra")
+	if err != nil {
+		t.Fatalf("parseRepoFilter: %v", err)
+	}
+	tests := []struct {
+		repo string
+		want bool
+	}{
+		{"api", true},
+		{"Web-Admin", true},
+		{"payments-api", true},
+		{"payments-infra", false}, // blocklist wins
+		{"web-secrets", false},
+		{"terraform", false},        // not allowlisted
+		{"acme-labs/web-app", true}, // patterns without an owner match any owner
+		{"acme-labs/payments-infra", false},
+	}
+	for _, tt := range tests {
+		if got := f.permits(tt.repo); got != tt.want {
+			t.Errorf("permits(%q) = %v, want %v", tt.repo, got, tt.want)
+		}
+	}
+
+	blockOnly, _ := parseRepoFilter("", "infra-*")
+	if !blockOnly.permits("api") || blockOnly.permits("infra-prod") {
+		t.Error("blocklist without an allowlist should permit everything else")
+	}
+	labs, _ := parseRepoFilter("acme-labs/*", "")
+	if !labs.permits("acme-labs/api") || labs.permits("api") || labs.permits("other/api") {
+		t.Error("owner patterns should match only that owner's repos")
+	}
+	if f, _ := parseRepoFilter(" ", ""); f != nil || !f.permits("anything") {
+		t.Error("empty patterns should disable the filter")
+	}
+	if _, err := parseRepoFilter("[", ""); err == nil {
+		t.Error("expected an error for a malformed pattern")
+	}
+}
+
+func TestFindRepo_Excluded(t *testing.T) {
+	rules, _ := parseRepoFilter("", "infra-*")
+	repoRules.Store(rules)
+	t.Cleanup(func() { repoRules.Store(nil) })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orgs/acme/repos", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"name":"web","default_branch":"main"},{"name":"infra-prod","default_branch":"main"}]`))
+	})
+	mux.HandleFunc("GET /repos/acme/{name}", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	gh := &GitHubProvider{owner: "acme", token: "tok", apiURL: srv.URL}
+
+	repos, err := gh.ListRepos(context.Background())
+	if err != nil || len(repos) != 1 || repos[0].Name != "web" {
+		t.Errorf("ListRepos = %+v, %v; want only web", repos, err)
+	}
+	// Neither by name nor by a loose match.
+	for _, name := range []string{"infra-prod", "infra_prod"} {
+		if _, _, err := findRepo(context.Background(), []VCSProvider{gh}, name); err == nil {
+			t.Errorf("findRepo(%q) should not find an excluded repo", name)
+		}
+	}
+	if _, err := EnsureBaseClone(context.Background(), gh, "infra-prod", "main"); err == nil {
+		t.Error("EnsureBaseClone should refuse an excluded repo")
+	}
+}
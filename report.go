@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// reportTopN is how many repos and requesters the daily report lists.
+const reportTopN = 5
+
+// jobReport sums up the jobs started in [From, To).
+type jobReport struct {
+	From, To   time.Time
+	Jobs       int
+	Completed  int
+	Failed     int // ended with job_error or job_expired
+	Cancelled  int
+	Running    int
+	CostUSD    float64
+	Repos      []reportEntry // most jobs first, at most reportTopN
+	Requesters []reportEntry // by chat user ID, most jobs first, at most reportTopN
+}
+
+// reportEntry is one repo's or requester's share of a jobReport.
+type reportEntry struct {
+	Name    string
+	Label   string // how to show Name, if known (a requester's display name)
+	Jobs    int
+	CostUSD float64
+}
+
+// successRate returns the share of the jobs that ran to the end (not
+// cancelled or still running) that completed, or false if none did.
+func (r jobReport) successRate() (float64, bool) {
+	finished := r.Completed + r.Failed
+	if finished == 0 {
+		return 0, false
+	}
+	return float64(r.Completed) / float64(finished), true
+}
+
+// buildJobReport sums up the jobs in store started in [from, to).
+func buildJobReport(store EventStore, from, to time.Time) (jobReport, error) {
+	r := jobReport{From: from, To: to}
+	repos := make(map[string]*reportEntry)
+	users := make(map[string]*reportEntry)
+	tally := func(entries map[string]*reportEntry, name, label string, cost float64) {
+		if name == "" {
+			return
+		}
+		e, ok := entries[name]
+		if !ok {
+			e = &reportEntry{Name: name}
+			entries[name] = e
+		}
+		if label != "" {
+			e.Label = label
+		}
+		e.Jobs++
+		e.CostUSD += cost
+	}
+
+	q := jobQuery{Since: from, Limit: 500}
+	for {
+		page, err := store.ListJobs(q)
+		if err != nil {
+			return r, err
+		}
+		for _, j := range page.Jobs {
+			if !j.StartedAt.Before(to) {
+				continue
+			}
+			r.Jobs++
+			switch j.Status {
+			case "completed":
+				r.Completed++
+			case "error", "expired":
+				r.Failed++
+			case "cancelled":
+				r.Cancelled++
+			default:
+				r.Running++
+			}
+			r.CostUSD += j.CostUSD
+			tally(repos, j.Repo, "", j.CostUSD)
+			tally(users, j.RequestedBy, j.RequestedByName, j.CostUSD)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		if q.Cursor, err = parseJobCursor(page.NextCursor); err != nil {
+			return r, err
+		}
+	}
+	r.Repos, r.Requesters = topEntries(repos), topEntries(users)
+	return r, nil
+}
+
+// topEntries returns the reportTopN entries with the most jobs, then cost.
+func topEntries(entries map[string]*reportEntry) []reportEntry {
+	top := make([]reportEntry, 0, len(entries))
+	for _, e := range entries {
+		top = append(top, *e)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Jobs != top[j].Jobs {
+			return top[i].Jobs > top[j].Jobs
+		}
+		if top[i].CostUSD != top[j].CostUSD {
+			return top[i].CostUSD > top[j].CostUSD
+		}
+		return top[i].Name < top[j].Name
+	})
+	if len(top) > reportTopN {
+		top = top[:reportTopN]
+	}
+	return top
+}
+
+// formatJobReport renders a day's report, with the week to date for context,
+// for chat. name turns the user ID of a requester whose jobs don't record a
+// display name into something to show.
+func formatJobReport(day, week jobReport, name func(userID string) string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Bob's report for %s*\n", day.From.Format("Monday, 2 January 2006"))
+	if day.Jobs == 0 {
+		b.WriteString("No jobs.\n")
+	} else {
+		counts := []string{fmt.Sprintf("%d completed", day.Completed)}
+		for _, c := range []struct {
+			n     int
+			label string
+		}{{day.Failed, "failed"}, {day.Cancelled, "cancelled"}, {day.Running, "still running"}} {
+			if c.n > 0 {
+				counts = append(counts, fmt.Sprintf("%d %s", c.n, c.label))
+			}
+		}
+		fmt.Fprintf(&b, "• Jobs: %d (%s)\n", day.Jobs, strings.Join(counts, ", "))
+		if rate, ok := day.successRate(); ok {
+			fmt.Fprintf(&b, "• Success rate: %.0f%%\n", rate*100)
+		}
+		fmt.Fprintf(&b, "• Cost: $%.2f\n", day.CostUSD)
+		if len(day.Repos) > 0 {
+			fmt.Fprintf(&b, "• Top repos: %s\n", formatReportEntries(day.Repos, func(s string) string { return s }))
+		}
+		if len(day.Requesters) > 0 {
+			fmt.Fprintf(&b, "• Top requesters: %s\n", formatReportEntries(day.Requesters, name))
+		}
+	}
+	fmt.Fprintf(&b, "\nLast 7 days: %d jobs", week.Jobs)
+	if rate, ok := week.successRate(); ok {
+		fmt.Fprintf(&b, ", %.0f%% successful", rate*100)
+	}
+	fmt.Fprintf(&b, ", $%.2f", week.CostUSD)
+	return strings.TrimSpace(b.String())
+}
+
+func formatReportEntries(entries []reportEntry, name func(string) string) string {
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		label := e.Label
+		if label == "" {
+			label = name(e.Name)
+		}
+		parts[i] = fmt.Sprintf("%s (%d, $%.2f)", label, e.Jobs, e.CostUSD)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// userNamer is implemented by chat platforms that can look up a user's display
+// name, used to show requesters in the report without mentioning them.
+type userNamer interface {
+	UserName(ctx context.Context, userID string) (string, error)
+}
+
+// RunDailyReport posts the report for the previous UTC day to the Slack
+// channel at hour (UTC) every day until ctx is done.
+func (h *Hub) RunDailyReport(ctx context.Context, n Notifier, channel string, hour int) {
+	for {
+		now := time.Now().UTC()
+		next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, time.UTC)
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+		}
+		h.postDailyReport(ctx, n, channel, next)
+	}
+}
+
+// postDailyReport posts the report for the UTC day before now to channel.
+func (h *Hub) postDailyReport(ctx context.Context, n Notifier, channel string, now time.Time) {
+	defer recoverGoroutine("daily report", nil)
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	day, err := buildJobReport(h.store, today.AddDate(0, 0, -1), today)
+	if err != nil {
+		slog.Error("report: failed to build the daily report", "err", err)
+		return
+	}
+	week, err := buildJobReport(h.store, today.AddDate(0, 0, -7), today)
+	if err != nil {
+		slog.Error("report: failed to build the weekly totals", "err", err)
+		return
+	}
+	name := func(userID string) string {
+		if un, ok := n.(userNamer); ok {
+			if name, err := un.UserName(ctx, userID); err == nil && name != "" {
+				return name
+			}
+		}
+		return userID
+	}
+	ctx = WithPlatform(WithSlackThread(ctx, channel, ""), "slack")
+	if err := n.Notify(ctx, formatJobReport(day, week, name)); err != nil {
+		slog.Error("report: failed to post the daily report", "channel", channel, "err", err)
+		return
+	}
+	slog.Info("report: posted the daily report", "channel", channel, "day", day.From.Format(time.DateOnly), "jobs", day.Jobs)
+}
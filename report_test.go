@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDailyReport(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	now := time.Date(2026, 3, 3, 9, 0, 0, 0, time.UTC)
+	yesterday := now.Add(-20 * time.Hour)
+
+	seq := 0
+	names := map[string]string{"U2": "Grace"} // U1's jobs don't record a name
+	job := func(id, repo, user string, started time.Time, cost float64, end EventData) {
+		t.Helper()
+		events := []Event{{JobID: id, Type: EventJobStarted, Timestamp: started, Data: JobStartedData{Task: "t", Repo: repo, RequestedBy: user, RequestedByName: names[user]}}}
+		if cost > 0 {
+			events = append(events, Event{JobID: id, Type: EventLLMResponse, Timestamp: started.Add(time.Minute), Data: LLMResponseData{CostUSD: cost}})
+		}
+		if end != nil {
+			events = append(events, Event{JobID: id, Type: end.EventType(), Timestamp: started.Add(time.Hour), Data: end})
+		}
+		for _, e := range events {
+			seq++
+			e.ID = string(rune('a' + seq))
+			if err := hub.store.Append(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	job("j1", "web", "U1", yesterday, 2, JobCompletedData{})
+	job("j2", "web", "U2", yesterday.Add(time.Hour), 1, JobCompletedData{})
+	job("j3", "api", "U1", yesterday.Add(2*time.Hour), 3, JobErrorData{Error: "boom"})
+	job("j4", "api", "U1", yesterday.Add(3*time.Hour), 0, JobCancelledData{})
+	job("j5", "web", "", yesterday.Add(4*time.Hour), 0, nil)
+	job("j6", "web", "U2", now.Add(-3*24*time.Hour), 4, JobCompletedData{})
+	job("j7", "web", "U2", now.Add(-time.Hour), 5, JobCompletedData{}) // today: not in the report
+
+	platform := &fakePlatform{name: "slack"}
+	hub.postDailyReport(context.Background(), platform, "C-REPORTS", now)
+	if len(platform.sent) != 1 {
+		t.Fatalf("sent %q", platform.sent)
+	}
+	want := "*Bob's report for Monday, 2 March 2026*\n" +
+		"• Jobs: 5 (2 completed, 1 failed, 1 cancelled, 1 still running)\n" +
+		"• Success rate: 67%\n" +
+		"• Cost: $6.00\n" +
+		"• Top repos: web (3, $3.00), api (2, $3.00)\n" +
+		"• Top requesters: U1 (3, $5.00), Grace (1, $1.00)\n" +
+		"\nLast 7 days: 6 jobs, 75% successful, $10.00"
+	if platform.sent[0] != want {
+		t.Errorf("report =\n%s\nwant\n%s", platform.sent[0], want)
+	}
+}
+
+func TestFormatJobReport_NoJobs(t *testing.T) {
+	day := jobReport{From: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)}
+	got := formatJobReport(day, jobReport{}, func(s string) string { return s })
+	if want := "*Bob's report for Monday, 2 March 2026*\nNo jobs.\n\nLast 7 days: 0 jobs, $0.00"; got != want {
+		t.Errorf("formatJobReport = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+var (
+	// errJobRunning is returned when asked to re-run a job that hasn't finished.
+	errJobRunning = errors.New("job is still running")
+	// errJobSucceeded is returned when asked to re-run a job that completed.
+	errJobSucceeded = errors.New("job completed successfully")
+	// errThreadBusy is returned when the job's thread already has an active job.
+	errThreadBusy = errors.New("another job is active in the job's thread")
+)
+
+// retryTexts is the set of messages that re-run the thread's last job.
+var retryTexts = map[string]bool{
+	"retry":     true,
+	"try again": true,
+	"rerun":     true,
+	"re-run":    true,
+}
+
+func isRetryText(text string) bool {
+	return retryTexts[strings.ToLower(strings.TrimSpace(text))]
+}
+
+// rerunSource is what a failed job's re-run starts from: its request, and
+// the plan if one had been approved.
+type rerunSource struct {
+	JobID  string
+	Intent IntentResult
+	Plan   string    // the approved plan, implemented again without planning; empty plans again
+	PR     *threadPR // the PR a follow-up job was updating; nil opens a new one
+
+	// The job's chat thread and requester; the re-run reports there.
+	Channel, ThreadTS, Platform  string
+	RequestedBy, RequestedByName string
+	CoAuthor                     *gitIdentity
+}
+
+// rerunSource reads what re-running jobID needs from its events, so jobs from
+// before a restart can be re-run too, adding the request's details (base
+// branch, paths, PR hints) from its state if Bob still has it. Only jobs that
+// failed, were cancelled or expired can be re-run; others return
+// errJobRunning or errJobSucceeded.
+func (o *Orchestrator) rerunSource(jobID string) (rerunSource, error) {
+	events, err := o.hub.store.Events(jobID)
+	if err != nil || len(events) == 0 {
+		return rerunSource{}, ErrJobNotFound
+	}
+	src := rerunSource{JobID: jobID}
+	var plan string
+	approved, finished := false, false
+	for _, e := range events {
+		switch d := e.Data.(type) {
+		case JobStartedData:
+			src.Intent.Repo, src.Intent.Task = d.Repo, d.Task
+			src.Channel, src.ThreadTS, src.Platform = d.Channel, d.ThreadTS, d.Platform
+			src.RequestedBy, src.RequestedByName = d.RequestedBy, d.RequestedByName
+		case PlanGeneratedData:
+			plan, approved = d.Plan, false
+		case PlanApprovedData:
+			approved = true
+		case JobCompletedData:
+			return rerunSource{}, errJobSucceeded
+		case JobErrorData, JobCancelledData, JobExpiredData:
+			finished = true
+		}
+	}
+	if !finished {
+		return rerunSource{}, errJobRunning
+	}
+	if src.Intent.Repo == "" || src.Intent.Task == "" {
+		return rerunSource{}, fmt.Errorf("job %s has no request to re-run", jobID)
+	}
+	if approved {
+		src.Plan = plan
+	}
+
+	if state, ok := o.hub.GetJobState(jobID); ok {
+		state.mu.Lock()
+		src.Intent.BaseBranch = state.BaseBranch
+		src.Intent.Paths = state.Paths
+		src.Intent.PROptions = state.PRHints
+		src.Intent.DryRun = state.DryRun
+		src.Intent.Changelog = state.Changelog
+		src.Intent.PRFooter = state.PRFooter
+		src.Intent.Ticket = state.Ticket
+		src.CoAuthor = state.CoAuthor
+		if state.PRURL != "" {
+			src.PR = &threadPR{JobID: jobID, Repo: state.Repo, URL: state.PRURL, Branch: state.PRBranch}
+		}
+		state.mu.Unlock()
+	}
+	src.Intent.RerunOf = jobID
+	return src, nil
+}
+
+// Rerun starts src's request again as a new job in the same thread, for the
+// same requester. A job that failed after its plan was approved implements
+// that plan straight away, reporting to the thread like an approval, and the
+// result is marked Reported; otherwise the new job plans again and waits for
+// approval as usual. onJobCreated, if non-nil, is called with the new job's
+// ID as soon as it exists.
+func (a *Approver) Rerun(ctx context.Context, src rerunSource, rerunBy string, onJobCreated func(jobID string)) (OrchestratorResult, error) {
+	if src.Channel != "" && a.hub.ActiveJobForThread(src.Channel, src.ThreadTS) != "" {
+		return OrchestratorResult{}, errThreadBusy
+	}
+	ctx = WithSlackThread(ctx, src.Channel, src.ThreadTS)
+	ctx = WithHub(ctx, a.hub)
+	if src.Platform != "" {
+		ctx = WithPlatform(ctx, src.Platform)
+	}
+	if src.RequestedBy != "" {
+		ctx = WithUser(ctx, src.RequestedBy)
+		ctx = WithUserName(ctx, src.RequestedByName)
+	}
+	if src.CoAuthor != nil {
+		ctx = WithCoAuthor(ctx, *src.CoAuthor)
+	}
+
+	slog.InfoContext(ctx, "rerun: starting a job again", "job_id", src.JobID, "by", rerunBy, "replay_plan", src.Plan != "")
+	result, err := a.orchestrator.startJob(ctx, src.Intent, src.Plan, src.PR, func(jobID string) {
+		a.hub.Emit(src.JobID, JobRerunData{NewJobID: jobID, RerunBy: rerunBy})
+		if onJobCreated != nil {
+			onJobCreated(jobID)
+		}
+	})
+	if err != nil || src.Plan == "" || result.JobID == "" || len(result.PlanBlocks) == 0 {
+		return result, err
+	}
+	// The plan was approved before: implement it again without asking.
+	a.Approve(ctx, result.JobID, src.Channel, src.ThreadTS, rerunBy)
+	return OrchestratorResult{IsJob: true, JobID: result.JobID, Reported: true}, nil
+}
+
+// rerunErrorText explains why a job can't be re-run, for chat and the API.
+func rerunErrorText(err error) string {
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		return "I couldn't find that job."
+	case errors.Is(err, errJobRunning):
+		return "That job is still running; cancel it first if you want to start over."
+	case errors.Is(err, errJobSucceeded):
+		return "That job completed successfully, so there's nothing to retry. Tell me what to change instead."
+	case errors.Is(err, errThreadBusy):
+		return "There's already a job running in this thread."
+	}
+	return fmt.Sprintf("I couldn't retry that job: %s", err.Error())
+}
+
+// RerunThreadJob re-runs jobID, a thread's last job, for a "retry" message.
+// Reasons the job can't be re-run are returned as the reply text.
+func (a *Approver) RerunThreadJob(ctx context.Context, jobID, rerunBy string, onJobCreated func(jobID string)) (OrchestratorResult, error) {
+	src, err := a.orchestrator.rerunSource(jobID)
+	if err == nil {
+		var result OrchestratorResult
+		if result, err = a.Rerun(ctx, src, rerunBy, onJobCreated); !errors.Is(err, errThreadBusy) {
+			return result, err
+		}
+	}
+	return OrchestratorResult{Text: rerunErrorText(err)}, nil
+}
+
+// notifyRerun posts text to the chat thread of the job src re-runs, if any.
+func (a *Approver) notifyRerun(ctx context.Context, src rerunSource, text string) {
+	if src.Channel == "" {
+		return
+	}
+	ctx = WithSlackThread(ctx, src.Channel, src.ThreadTS)
+	if src.Platform != "" {
+		ctx = WithPlatform(ctx, src.Platform)
+	}
+	if err := a.notifier.Notify(ctx, text); err != nil {
+		slog.ErrorContext(ctx, "rerun: failed to notify the thread", "job_id", src.JobID, "err", err)
+	}
+}
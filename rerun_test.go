@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// appendJobEvents stores events for jobID as if the job had emitted them.
+func appendJobEvents(t *testing.T, hub *Hub, jobID string, data ...EventData) {
+	t.Helper()
+	for i, d := range data {
+		e := Event{ID: fmt.Sprintf("%s-%d", jobID, i), JobID: jobID, Type: d.EventType(), Timestamp: time.Now(), Data: d}
+		if err := hub.store.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRerunSource(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	o := &Orchestrator{hub: hub}
+	started := JobStartedData{Repo: "web", Task: "fix login", Channel: "C1", ThreadTS: "1.2", Platform: "slack", RequestedBy: "U1"}
+
+	appendJobEvents(t, hub, "job-approved", started,
+		PlanGeneratedData{Plan: "old plan"}, PlanGeneratedData{Plan: "revised plan"}, PlanApprovedData{ApprovedBy: "U1"},
+		JobErrorData{Error: "git clone failed"})
+	hub.SetJobState("job-approved", &JobState{Repo: "web", BaseBranch: "develop", Paths: []string{"web"}, PRURL: "https://x/pr/3", PRBranch: "bob/fix", closed: true})
+	src, err := o.rerunSource("job-approved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.Intent.Repo != "web" || src.Intent.Task != "fix login" || src.Intent.RerunOf != "job-approved" || src.Plan != "revised plan" {
+		t.Errorf("source = %+v", src)
+	}
+	if src.Channel != "C1" || src.ThreadTS != "1.2" || src.Platform != "slack" || src.RequestedBy != "U1" {
+		t.Errorf("thread = %+v", src)
+	}
+	if src.Intent.BaseBranch != "develop" || len(src.Intent.Paths) != 1 || src.PR == nil || src.PR.Branch != "bob/fix" {
+		t.Errorf("details from the job state = %+v, PR %+v", src.Intent, src.PR)
+	}
+
+	// A plan that was revised after approval, or never approved, is planned again.
+	appendJobEvents(t, hub, "job-planning", started, PlanGeneratedData{Plan: "plan"}, JobCancelledData{CancelledBy: "U1"})
+	if src, err := o.rerunSource("job-planning"); err != nil || src.Plan != "" {
+		t.Errorf("unapproved plan: source = %+v, err = %v", src, err)
+	}
+
+	appendJobEvents(t, hub, "job-done", started, JobCompletedData{PRURL: "https://x/pr/1"})
+	appendJobEvents(t, hub, "job-running", started, PlanGeneratedData{Plan: "plan"})
+	for jobID, want := range map[string]error{"job-done": errJobSucceeded, "job-running": errJobRunning, "job-missing": ErrJobNotFound} {
+		if _, err := o.rerunSource(jobID); !errors.Is(err, want) {
+			t.Errorf("rerunSource(%s) = %v, want %v", jobID, err, want)
+		}
+	}
+}
+
+func TestServeRerunJob_Rejected(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	o := &Orchestrator{hub: hub}
+	approver := NewApprover(nil, hub, o, false)
+	appendJobEvents(t, hub, "job-done", JobStartedData{Repo: "web", Task: "fix"}, JobCompletedData{})
+	appendJobEvents(t, hub, "job-failed", JobStartedData{Repo: "web", Task: "fix", Channel: "C1", ThreadTS: "1.2"}, JobErrorData{Error: "boom"})
+	hub.RegisterThreadJob("C1", "1.2", "job-other")
+
+	for jobID, want := range map[string]int{"job-missing": http.StatusNotFound, "job-done": http.StatusConflict, "job-failed": http.StatusConflict} {
+		rec := httptest.NewRecorder()
+		serveRerunJob(rec, httptest.NewRequest(http.MethodPost, "/api/jobs/"+jobID+"/rerun", nil), o, approver, jobID)
+		if rec.Code != want || !strings.Contains(rec.Body.String(), `"error"`) {
+			t.Errorf("rerun %s: %d %s, want %d", jobID, rec.Code, rec.Body.String(), want)
+		}
+	}
+}
+
+func TestRerun_ThreadBusy(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	approver := NewApprover(nil, hub, &Orchestrator{hub: hub}, false)
+	hub.RegisterThreadJob("C1", "1.2", "job-2")
+	hub.UnregisterThreadJob("C1", "1.2")
+	if got := hub.LastThreadJob("C1", "1.2"); got != "job-2" {
+		t.Errorf("LastThreadJob = %q, want job-2", got)
+	}
+	hub.RegisterThreadJob("C1", "1.2", "job-3")
+
+	src := rerunSource{JobID: "job-2", Intent: IntentResult{Repo: "web", Task: "fix"}, Channel: "C1", ThreadTS: "1.2"}
+	if _, err := approver.Rerun(context.Background(), src, "API", nil); !errors.Is(err, errThreadBusy) {
+		t.Errorf("Rerun = %v, want errThreadBusy", err)
+	}
+	if !isRetryText(" Try again ") || isRetryText("retry the login fix in web") {
+		t.Error("isRetryText")
+	}
+}
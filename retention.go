@@ -0,0 +1,288 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// archiveDir is where archived jobs are kept, under the event store's dir:
+// one <jobID>.jsonl.gz per job plus archiveIndexFile.
+const (
+	archiveDir       = "archive"
+	archiveIndexFile = "index.json"
+)
+
+// archiveUploadTimeout bounds one run of the archive upload command.
+const archiveUploadTimeout = 5 * time.Minute
+
+// retentionPolicy says which finished jobs are archived. Zero fields don't apply.
+type retentionPolicy struct {
+	MaxAge   time.Duration // archive jobs with no events for longer than this
+	MaxBytes int64         // archive the oldest jobs until the live job files fit in this
+	// Upload is a shell command run with each archive file's path as $1, e.g.
+	// `aws s3 cp "$1" s3://bucket/bob/`. The local archive is kept either way.
+	Upload string
+}
+
+func (p retentionPolicy) enabled() bool { return p.MaxAge > 0 || p.MaxBytes > 0 }
+
+// jobArchiver is implemented by event stores that can archive old jobs.
+// Archived jobs leave the job list and stats, but stay readable.
+type jobArchiver interface {
+	// ArchiveJobs archives the finished jobs p selects and returns their IDs.
+	ArchiveJobs(p retentionPolicy, now time.Time) ([]string, error)
+	ArchivedJobs(q jobQuery) (jobPage, error)
+	ArchivedEvents(jobID string) ([]Event, error)
+}
+
+// RunRetention archives old jobs per p every interval until ctx is done. It
+// returns right away if the event store can't archive.
+func (h *Hub) RunRetention(ctx context.Context, p retentionPolicy, interval time.Duration) {
+	archiver, ok := h.store.(jobArchiver)
+	if !ok {
+		slog.Warn("retention: the event store doesn't support archiving; keeping all jobs")
+		return
+	}
+	for {
+		ids, err := archiver.ArchiveJobs(p, time.Now())
+		if err != nil {
+			slog.Error("retention: failed to archive jobs", "err", err)
+		}
+		if len(ids) > 0 {
+			slog.Info("retention: archived jobs", "count", len(ids))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ArchiveJobs implements jobArchiver. Finished jobs are archived oldest first
+// (by their last event): those older than p.MaxAge, then more until the live
+// job files fit in p.MaxBytes.
+func (s *jsonlStore) ArchiveJobs(p retentionPolicy, now time.Time) ([]string, error) {
+	if _, err := s.aggregates(); err != nil { // indexes finished jobs missing from the index
+		return nil, err
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	type candidate struct {
+		id      string
+		size    int64
+		modTime time.Time
+	}
+	var total int64
+	var finished []candidate
+	s.indexMu.Lock()
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".jsonl") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		total += info.Size()
+		id := strings.TrimSuffix(entry.Name(), ".jsonl")
+		if a, ok := s.index[id]; ok && a.Status != "running" {
+			finished = append(finished, candidate{id: id, size: info.Size(), modTime: info.ModTime()})
+		}
+	}
+	s.indexMu.Unlock()
+	sort.Slice(finished, func(i, j int) bool { return finished[i].modTime.Before(finished[j].modTime) })
+
+	var archived []string
+	for _, c := range finished {
+		tooOld := p.MaxAge > 0 && now.Sub(c.modTime) > p.MaxAge
+		overBudget := p.MaxBytes > 0 && total > p.MaxBytes
+		if !tooOld && !overBudget {
+			break // the rest are newer, and the live files fit
+		}
+		ok, err := s.archiveJob(c.id)
+		if err != nil {
+			return archived, fmt.Errorf("archive job %s: %w", c.id, err)
+		}
+		if !ok {
+			continue
+		}
+		total -= c.size
+		archived = append(archived, c.id)
+		if p.Upload != "" {
+			if err := uploadArchive(p.Upload, s.archivePath(c.id)); err != nil {
+				slog.Warn("retention: failed to upload archived job", "job_id", c.id, "err", err)
+			}
+		}
+	}
+	return archived, nil
+}
+
+// archivePath is where jobID's archived events are kept.
+func (s *jsonlStore) archivePath(jobID string) string {
+	return filepath.Join(s.dir, archiveDir, jobID+".jsonl.gz")
+}
+
+// archiveJob compresses a finished job's file into the archive, moves its
+// summary to the archive index and removes the file. Appends wait meanwhile;
+// a job that has been resumed since it finished is left alone (false).
+func (s *jsonlStore) archiveJob(jobID string) (bool, error) {
+	s.filesMu.Lock()
+	defer s.filesMu.Unlock()
+	if f, ok := s.files[jobID]; ok {
+		f.Close()
+		delete(s.files, jobID)
+	}
+	src := filepath.Join(s.dir, jobID+".jsonl")
+	a := &jobAggregate{jobSummary: jobSummary{ID: jobID, Status: "running"}}
+	if err := s.scan(src, a.apply); err != nil {
+		return false, err
+	}
+	if a.Status == "running" {
+		return false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Join(s.dir, archiveDir), 0o755); err != nil {
+		return false, err
+	}
+	if err := gzipFile(src, s.archivePath(jobID)); err != nil {
+		return false, err
+	}
+	if err := s.updateArchiveIndex(func(idx map[string]*jobAggregate) { idx[jobID] = a }); err != nil {
+		return false, err
+	}
+	if err := os.Remove(src); err != nil {
+		return false, err
+	}
+	s.indexMu.Lock()
+	delete(s.index, jobID)
+	s.saveIndex()
+	s.indexMu.Unlock()
+	return true, nil
+}
+
+// gzipFile writes a gzip-compressed copy of src to dst, atomically.
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// uploadArchive runs the upload command for an archive file.
+func uploadArchive(command, path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), archiveUploadTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "sh", "-c", command, "sh", path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w", truncate(strings.TrimSpace(string(out)), 500), err)
+	}
+	return nil
+}
+
+// loadArchiveIndex reads the archived jobs' summaries. Callers hold s.archiveMu.
+func (s *jsonlStore) loadArchiveIndex() (map[string]*jobAggregate, error) {
+	idx := make(map[string]*jobAggregate)
+	data, err := os.ReadFile(filepath.Join(s.dir, archiveDir, archiveIndexFile))
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("parse archive index: %w", err)
+	}
+	return idx, nil
+}
+
+// updateArchiveIndex applies fn to the archive index and writes it atomically.
+func (s *jsonlStore) updateArchiveIndex(fn func(map[string]*jobAggregate)) error {
+	s.archiveMu.Lock()
+	defer s.archiveMu.Unlock()
+	idx, err := s.loadArchiveIndex()
+	if err != nil {
+		return err
+	}
+	fn(idx)
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.dir, archiveDir, archiveIndexFile)
+	if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// ArchivedJobs implements jobArchiver.
+func (s *jsonlStore) ArchivedJobs(q jobQuery) (jobPage, error) {
+	s.archiveMu.Lock()
+	idx, err := s.loadArchiveIndex()
+	s.archiveMu.Unlock()
+	if err != nil {
+		return jobPage{}, err
+	}
+	aggs := make([]*jobAggregate, 0, len(idx))
+	for _, a := range idx {
+		aggs = append(aggs, a)
+	}
+	return q.paginate(sortedSummaries(aggs)), nil
+}
+
+// ArchivedEvents implements jobArchiver.
+func (s *jsonlStore) ArchivedEvents(jobID string) ([]Event, error) {
+	if jobID != filepath.Base(jobID) {
+		return nil, ErrJobNotFound
+	}
+	f, err := os.Open(s.archivePath(jobID))
+	if os.IsNotExist(err) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer zr.Close()
+	events := []Event{}
+	if err := scanEvents(zr, func(e Event) { events = append(events, e) }); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestJSONLStore_ArchiveJobs(t *testing.T) {
+	dir := t.TempDir()
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := newJSONLStore(dir)
+	defer s.Close()
+	for _, e := range []Event{
+		{ID: "1", JobID: "job-old", Type: EventJobStarted, Timestamp: t0, Data: JobStartedData{Task: "old", Repo: "web"}},
+		{ID: "2", JobID: "job-old", Type: EventJobCompleted, Timestamp: t0, Data: JobCompletedData{}},
+		{ID: "3", JobID: "job-new", Type: EventJobStarted, Timestamp: t0.Add(time.Hour), Data: JobStartedData{Task: "new"}},
+		{ID: "4", JobID: "job-new", Type: EventJobError, Timestamp: t0.Add(time.Hour), Data: JobErrorData{Error: "boom"}},
+		{ID: "5", JobID: "job-running", Type: EventJobStarted, Timestamp: t0, Data: JobStartedData{Task: "running"}},
+	} {
+		if err := s.Append(e); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	now := time.Now()
+	for id, age := range map[string]time.Duration{"job-old": 100 * 24 * time.Hour, "job-new": time.Hour, "job-running": 200 * 24 * time.Hour} {
+		mtime := now.Add(-age)
+		if err := os.Chtimes(filepath.Join(dir, id+".jsonl"), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	uploaded := filepath.Join(dir, "uploaded")
+	os.Mkdir(uploaded, 0o755)
+	archived, err := s.ArchiveJobs(retentionPolicy{MaxAge: 90 * 24 * time.Hour, Upload: `cp "$1" ` + uploaded}, now)
+	if err != nil {
+		t.Fatalf("ArchiveJobs: %v", err)
+	}
+	if !slices.Equal(archived, []string{"job-old"}) {
+		t.Fatalf("archived = %v, want [job-old]", archived)
+	}
+	if _, err := os.Stat(filepath.Join(uploaded, "job-old.jsonl.gz")); err != nil {
+		t.Errorf("archive not uploaded: %v", err)
+	}
+
+	page, err := s.ListJobs(jobQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := jobIDs(page.Jobs); slices.Contains(got, "job-old") || len(got) != 2 {
+		t.Errorf("live jobs = %v", got)
+	}
+	page, err = s.ArchivedJobs(jobQuery{Repo: "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Jobs) != 1 || page.Jobs[0].ID != "job-old" || page.Jobs[0].Status != "completed" {
+		t.Errorf("archived jobs = %+v", page.Jobs)
+	}
+	events, err := s.ArchivedEvents("job-old")
+	if err != nil || len(events) != 2 || events[1].Type != EventJobCompleted {
+		t.Errorf("archived events = %v, %v", events, err)
+	}
+	if _, err := s.ArchivedEvents("job-new"); err != ErrJobNotFound {
+		t.Errorf("unarchived job: err = %v", err)
+	}
+
+	// A size budget archives the oldest finished jobs first, never running ones.
+	archived, err = s.ArchiveJobs(retentionPolicy{MaxBytes: 1}, now)
+	if err != nil {
+		t.Fatalf("ArchiveJobs: %v", err)
+	}
+	if !slices.Equal(archived, []string{"job-new"}) {
+		t.Errorf("archived = %v, want [job-new]", archived)
+	}
+	if page, _ := s.ArchivedJobs(jobQuery{}); len(page.Jobs) != 2 || page.Jobs[0].ID != "job-new" {
+		t.Errorf("archived jobs = %v", jobIDs(page.Jobs))
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ReviewComment is a pull request review comment on a Bob-created PR.
+type ReviewComment struct {
+	Repo      string
+	Branch    string // PR head branch
+	PRNumber  int
+	PRURL     string
+	CommentID int64
+	Author    string
+	Path      string
+	Line      int
+	DiffHunk  string
+	Body      string
+}
+
+// reviewReplier is implemented by providers that can reply to review comments.
+type reviewReplier interface {
+	ReplyToReviewComment(ctx context.Context, name string, prNumber int, commentID int64, body string) error
+}
+
+// HandleReviewComment runs a follow-up implementation for a review comment and
+// pushes the result to the PR's existing branch. Each comment gets its own job
+// so it shows up in the monitoring UI; the reviewer is answered on the PR.
+// started, if non-nil, is called with the job's ID once it's created.
+func (o *Orchestrator) HandleReviewComment(ctx context.Context, rc ReviewComment, started func(jobID string)) (OrchestratorResult, error) {
+	vcs, r, err := findRepo(ctx, o.providers, rc.Repo)
+	if err != nil {
+		return OrchestratorResult{}, fmt.Errorf("find repo: %w", err)
+	}
+	slog.InfoContext(ctx, "orchestrator: addressing review comment", "comment_id", rc.CommentID, "pr", rc.PRURL)
+	return o.runPRFollowUp(ctx, vcs, r, prFollowUp{
+		Repo:         r.Name,
+		Branch:       rc.Branch,
+		PRURL:        rc.PRURL,
+		Task:         fmt.Sprintf("Address review comment by %s on %s", rc.Author, rc.PRURL),
+		Input:        rc.Body,
+		Prompt:       fmt.Sprintf("## Review comment\n\n%s\n\n## Location\n\n%s line %d\n\n## Diff hunk\n\n```diff\n%s\n```", rc.Body, rc.Path, rc.Line, rc.DiffHunk),
+		Commit:       fmt.Sprintf("Address review feedback\n\n%s", truncate(rc.Body, 500)),
+		SystemPrompt: reviewSystemPrompt,
+		Started:      started,
+	}, func(text string) { o.replyToReviewer(ctx, vcs, rc, text) })
+}
+
+// followUpPushed starts the reply of a follow-up that pushed a commit.
+const followUpPushed = "Pushed a follow-up commit."
+
+// prFollowUp is an unattended change to an existing PR: a Claude Code session
+// on the PR's branch whose changes are pushed to it without a plan.
+type prFollowUp struct {
+	Repo, Branch, PRURL string
+	Task                string // job task shown in the monitoring UI
+	Input               string // implement_changes step input
+	Prompt              string // session prompt, after the repo's preamble
+	SystemPrompt        string
+	Commit              string // commit message
+	Channel, ThreadTS   string // chat thread the job belongs to, if any
+	Platform            string
+	Started             func(jobID string) // called once the job is created, if set
+}
+
+// runPRFollowUp runs fu as its own job and pushes the changes to the PR's
+// branch. reply, if non-nil, receives the outcome for the requester.
+func (o *Orchestrator) runPRFollowUp(ctx context.Context, vcs VCSProvider, r repo, fu prFollowUp, reply func(string)) (result OrchestratorResult, err error) {
+	if reply == nil {
+		reply = func(string) {}
+	}
+	jobID := o.createJob(IntentResult{Repo: fu.Repo, Task: fu.Task}, vcs, fu.Channel, fu.ThreadTS, fu.Platform, "", "", nil)
+	if fu.Started != nil {
+		fu.Started(jobID)
+	}
+	jobCtx, release := o.jobContext(ctx, jobID)
+	defer release()
+	defer o.replaceIfCancelled(jobID, &result, &err)
+	defer o.recoverJob(ctx, jobID, &result, &err)
+	o.hub.SetPhase(jobID, PhaseImplementing)
+
+	startTime := time.Now()
+	fail := func(text string, err error) (OrchestratorResult, error) {
+		o.closeJob(ctx, jobID, JobErrorData{
+			Error: err.Error(), TotalDurationMs: time.Since(startTime).Milliseconds(),
+		})
+		reply(fmt.Sprintf("%s: %s", text, err.Error()))
+		return OrchestratorResult{IsJob: true, JobID: jobID, Text: text}, nil
+	}
+
+	unlockClone := lockBaseClone(fu.Repo)
+	defer unlockClone()
+	baseDir, err := EnsureBaseClone(jobCtx, vcs, fu.Repo, r.DefaultBranch)
+	if err != nil {
+		return fail("I couldn't clone the repository", err)
+	}
+	cfg, err := loadRepoConfig(jobCtx, baseDir, "FETCH_HEAD")
+	if err != nil {
+		return fail("I couldn't read the repo's "+repoConfigFile, err)
+	}
+	cfg.orgGuidelines = o.orgGuidelines(jobCtx)
+	if err := FetchBranch(jobCtx, baseDir, vcs, fu.Repo, fu.Branch); err != nil {
+		return fail("I couldn't fetch the PR branch", err)
+	}
+	repoDir, err := CreateWorktree(jobCtx, baseDir, jobID)
+	unlockClone()
+	if err != nil {
+		return fail("I couldn't create a worktree", err)
+	}
+	state, _ := o.hub.GetJobState(jobID)
+	state.mu.Lock()
+	state.RepoDir = repoDir
+	state.BaseDir = baseDir
+	state.repoConfig = cfg
+	state.mu.Unlock()
+
+	var sr *SessionResult
+	_, err = o.runStep(jobCtx, jobID, "implement_changes", fu.Input, func(ctx context.Context) (string, error) {
+		var err error
+		sr, err = RunSession(ctx, o.claudeCodeToken, o.hub, jobID, SessionOpts{
+			RepoDir:        repoDir,
+			Prompt:         cfg.promptPreamble() + fu.Prompt,
+			SystemPrompt:   fu.SystemPrompt,
+			PermissionMode: "acceptEdits",
+			Tools:          o.toolRules(cfg, toolPhaseImplement),
+			Sandbox:        o.sandboxFor(fu.Repo, cfg),
+			Limits:         o.sessionLimits(ctx, jobID, fu.Repo),
+		})
+		if err == nil && sr.IsError {
+			err = fmt.Errorf("%s", sr.ResultText)
+		}
+		return sessionPreview(sr, err)
+	})
+	if err != nil {
+		return fail("Claude Code encountered an error", err)
+	}
+
+	if cost, over := o.overBudget(jobID); over {
+		result = o.abortOverBudget(ctx, jobID, cost)
+		reply(result.Text)
+		return result, nil
+	}
+
+	if err := o.enforceScope(jobCtx, jobID, repoDir, cfg); err != nil {
+		return fail("I couldn't limit the changes to the configured paths", err)
+	}
+	files, err := changedFiles(jobCtx, repoDir)
+	if err != nil {
+		return fail("I couldn't inspect the changes", err)
+	}
+	text := sr.ResultText
+	var artifacts []string
+	if len(files) > 0 {
+		commit := commitSpec{Task: fu.Commit, Author: o.gitAuthor}
+		if err := PushToBranch(jobCtx, vcs, fu.Repo, repoDir, commit, fu.Branch); err != nil {
+			return fail("Changes were made but I couldn't push them", err)
+		}
+		text = fmt.Sprintf("%s\n\n%s", followUpPushed, sr.ResultText)
+		artifacts = o.saveDiff(jobCtx, jobID, repoDir)
+	}
+
+	o.closeJob(ctx, jobID, JobCompletedData{
+		FinalResponse:   sr.ResultText,
+		PRURL:           fu.PRURL,
+		TotalDurationMs: time.Since(startTime).Milliseconds(),
+		Artifacts:       artifacts,
+	})
+	reply(text)
+	return OrchestratorResult{IsJob: true, JobID: jobID, PRURL: fu.PRURL, Text: text}, nil
+}
+
+// replyToReviewer answers on the review thread when the provider supports it.
+func (o *Orchestrator) replyToReviewer(ctx context.Context, vcs VCSProvider, rc ReviewComment, body string) {
+	r, ok := vcs.(reviewReplier)
+	if !ok || rc.CommentID == 0 {
+		return
+	}
+	if err := r.ReplyToReviewComment(ctx, rc.Repo, rc.PRNumber, rc.CommentID, body); err != nil {
+		slog.ErrorContext(ctx, "orchestrator: failed to reply to review comment", "comment_id", rc.CommentID, "err", err)
+	}
+}
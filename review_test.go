@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// reviewVCS is a local remote that records Bob's replies to review comments.
+type reviewVCS struct {
+	localVCS
+	mu      sync.Mutex
+	replies []string
+}
+
+func (v *reviewVCS) FindRepo(_ context.Context, name string) (repo, error) {
+	return repo{Name: name, DefaultBranch: "main"}, nil
+}
+
+func (v *reviewVCS) ReplyToReviewComment(_ context.Context, name string, prNumber int, commentID int64, body string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.replies = append(v.replies, body)
+	return nil
+}
+
+func TestHandleReviewComment(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	old := workspaceRoot
+	workspaceRoot = filepath.Join(root, "workspace")
+	t.Cleanup(func() { workspaceRoot = old })
+
+	// The PR branch is one commit ahead of main.
+	vcs := &reviewVCS{localVCS: localVCS{dir: filepath.Join(root, "remote")}}
+	remote := vcs.FetchURL("app")
+	runGit(t, root, "init", "-q", "--bare", "-b", "main", remote)
+	work := filepath.Join(root, "work")
+	runGit(t, root, "clone", "-q", remote, work)
+	commitToRemote(t, work, "main.go")
+	runGit(t, work, "checkout", "-q", "-b", "bob/fix-login-abcd1234")
+	os.WriteFile(filepath.Join(work, "login.go"), []byte("package main\n\nfunc lgn() {}\n"), 0o644)
+	runGit(t, work, "add", "login.go")
+	runGit(t, work, "commit", "-qm", "fix login")
+	runGit(t, work, "push", "-q", "origin", "bob/fix-login-abcd1234")
+	prTip := runGit(t, work, "rev-parse", "HEAD")
+
+	// A fake claude CLI that addresses the comment by renaming the function.
+	bin := t.TempDir()
+	script := `#!/bin/sh
+sed -i 's/lgn/login/' login.go
+echo '{"type":"system","subtype":"init","session_id":"s1"}'
+echo '{"type":"result","subtype":"success","result":"Renamed lgn to login."}'
+`
+	if err := os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	drainHub(t)
+	o := &Orchestrator{hub: NewHub(t.TempDir()), providers: []VCSProvider{vcs}}
+	rc := ReviewComment{
+		Repo: "app", Branch: "bob/fix-login-abcd1234", PRNumber: 7, PRURL: "https://example.com/app/pull/7",
+		CommentID: 42, Author: "alice", Path: "login.go", Line: 3, Body: "Please rename lgn to login",
+	}
+
+	t.Run("pushes to the PR branch", func(t *testing.T) {
+		res, err := o.HandleReviewComment(context.Background(), rc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.JobID == "" || res.PRURL != rc.PRURL || !strings.HasPrefix(res.Text, followUpPushed) {
+			t.Errorf("result = %+v", res)
+		}
+
+		runGit(t, work, "fetch", "-q", "origin")
+		tip := runGit(t, work, "rev-parse", "origin/bob/fix-login-abcd1234")
+		if parent := runGit(t, work, "rev-parse", tip+"^"); parent != prTip {
+			t.Errorf("follow-up commit's parent = %s, want the PR branch's tip %s", parent, prTip)
+		}
+		if msg := runGit(t, work, "log", "-1", "--format=%B", tip); msg != "refactor: address review feedback\n\nAddress review feedback\n\nPlease rename lgn to login" {
+			t.Errorf("commit message = %q", msg)
+		}
+		if got := runGit(t, work, "show", tip+":login.go"); !strings.Contains(got, "func login()") {
+			t.Errorf("pushed login.go = %q", got)
+		}
+		if main := runGit(t, work, "rev-parse", "origin/main"); main == tip {
+			t.Error("follow-up pushed to main")
+		}
+
+		vcs.mu.Lock()
+		defer vcs.mu.Unlock()
+		if len(vcs.replies) != 1 || !strings.HasPrefix(vcs.replies[0], followUpPushed) || !strings.Contains(vcs.replies[0], "Renamed lgn to login.") {
+			t.Errorf("replies = %q", vcs.replies)
+		}
+	})
+
+	t.Run("reports a failure to the reviewer", func(t *testing.T) {
+		vcs.mu.Lock()
+		vcs.replies = nil
+		vcs.mu.Unlock()
+		gone := rc
+		gone.Branch = "bob/deleted-branch"
+		res, err := o.HandleReviewComment(context.Background(), gone, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Text != "I couldn't fetch the PR branch" {
+			t.Errorf("result = %+v", res)
+		}
+		if remote := runGit(t, work, "ls-remote", "origin", "bob/deleted-branch"); remote != "" {
+			t.Errorf("branch created on the remote: %s", remote)
+		}
+
+		vcs.mu.Lock()
+		defer vcs.mu.Unlock()
+		if len(vcs.replies) != 1 || !strings.HasPrefix(vcs.replies[0], "I couldn't fetch the PR branch: ") {
+			t.Errorf("replies = %q", vcs.replies)
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// defaultSandboxVolume is the workspace volume's name in the Compose setup.
+	defaultSandboxVolume = "bob_workspace"
+	// dockerRunFailed is docker run's exit status when it couldn't start the
+	// container, as opposed to the command in it failing.
+	dockerRunFailed = 125
+)
+
+// reImageRef matches a Docker image reference, e.g. "golang:1.23" or
+// "ghcr.io/acme/ci@sha256:...". It can't start with "-", so an image from a
+// repo's .bob.yml is never taken for a docker run flag.
+var reImageRef = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._/:@-]*$`)
+
+// Sandbox runs Claude Code and test commands in ephemeral Docker containers
+// instead of directly in Bob's own container. The workspace volume is mounted
+// at the same path, so worktree paths (and their .git links to the base clone)
+// are valid inside the container. Requires the Docker CLI and socket.
+type Sandbox struct {
+	defaultImage string            // empty runs Claude Code on the host, and tests only if hostTests
+	repoImages   map[string]string // repo name → image override
+	volume       string            // Docker volume holding the workspace
+	hostTests    bool              // BOB_ALLOW_HOST_TESTS: run test commands on the host when no image applies
+	testNetwork  string            // BOB_SANDBOX_TEST_NETWORK: Docker network for repo-defined commands; empty for Docker's default
+}
+
+// NewSandbox creates a Sandbox. repoImages may be nil. With no defaultImage,
+// sessions run on the host and only a repo's test_image uses the volume;
+// tests without one run on the host only if hostTests is set. testNetwork,
+// e.g. "none", is the network containers of repo-defined commands join.
+func NewSandbox(defaultImage, volume string, repoImages map[string]string, hostTests bool, testNetwork string) *Sandbox {
+	return &Sandbox{defaultImage: defaultImage, repoImages: repoImages, volume: volume, hostTests: hostTests, testNetwork: testNetwork}
+}
+
+// repoNetwork returns the Docker network for repo-defined commands, or empty
+// string if they get Docker's default.
+func (s *Sandbox) repoNetwork() string {
+	if s == nil {
+		return ""
+	}
+	return s.testNetwork
+}
+
+// allowsHostTests reports whether the operator opted in to running a repo's
+// test command on the host, next to Bob's credentials.
+func (s *Sandbox) allowsHostTests() bool {
+	return s != nil && s.hostTests
+}
+
+// forRepo returns the container spec for a repo, or nil (run on the host) if
+// sandboxing is disabled.
+func (s *Sandbox) forRepo(repo string) *sandboxSpec {
+	if s == nil || s.defaultImage == "" {
+		return nil
+	}
+	image := s.defaultImage
+	if img, ok := s.repoImages[repo]; ok {
+		image = img
+	}
+	return &sandboxSpec{image: image, volume: s.volume, repoNetwork: s.testNetwork}
+}
+
+// checkDockerNetwork checks that Docker has the network, so a mistyped
+// BOB_SANDBOX_TEST_NETWORK fails at startup rather than every test run.
+func checkDockerNetwork(ctx context.Context, network string) error {
+	out, err := exec.CommandContext(ctx, "docker", "network", "inspect", "--format", "{{.Name}}", network).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("docker network inspect %s: %s", network, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// workspaceVolume returns the Docker volume holding the workspace.
+func (s *Sandbox) workspaceVolume() string {
+	if s == nil || s.volume == "" {
+		return defaultSandboxVolume
+	}
+	return s.volume
+}
+
+// parseRepoImages parses "repo=image,repo2=image2".
+func parseRepoImages(s string) map[string]string {
+	return parseRepoMap(s)
+}
+
+// parseRepoMap parses a per-repo setting list, "repo=value,repo2=value2".
+// Malformed pairs are skipped.
+func parseRepoMap(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+	m := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		repo, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && repo != "" && value != "" {
+			m[repo] = value
+		}
+	}
+	return m
+}
+
+// sandboxSpec is the container a single command runs in. A nil spec runs on the host.
+type sandboxSpec struct {
+	image       string
+	volume      string
+	repoNetwork string // Docker network for repoCommand; empty for the default
+}
+
+var sandboxSeq atomic.Uint64
+
+// hostEnvVars are the variables of Bob's environment a repo-defined command
+// gets on the host: enough for the toolchains, none of Bob's tokens.
+var hostEnvVars = []string{
+	"PATH", "LANG", "TZ", "TMPDIR", "TERM", "USER",
+	"GOPATH", "GOROOT", "GOCACHE", "GOMODCACHE", "GOFLAGS", "GOPROXY", "GOTOOLCHAIN",
+	"NODE_PATH", "NODE_OPTIONS", "JAVA_HOME", "CARGO_HOME", "RUSTUP_HOME", "PYTHONPATH", "VIRTUAL_ENV",
+}
+
+// hostEnv returns hostEnvVars and the locale's LC_ variables from Bob's
+// environment.
+func hostEnv() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if slices.Contains(hostEnvVars, key) || strings.HasPrefix(key, "LC_") {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// sandboxClaudeConfigDir keeps Claude Code's config and session transcripts
+// on the workspace volume, so a session started in one container can be
+// resumed in the next.
+func sandboxClaudeConfigDir() string { return workspacePath(".bob", "claude") }
+
+// command builds the command to run name with args in dir. env entries
+// ("KEY=value") are passed through without appearing on the docker command
+// line. On the host, HOME is the worker's home; in a container it is a
+// scratch directory, since the image's user may not match Bob's UID.
+func (sb *sandboxSpec) command(ctx context.Context, dir string, env []string, name string, args ...string) *exec.Cmd {
+	return sb.commandOn(ctx, "", dir, env, name, args...)
+}
+
+// commandOn is command with the container on network, if set.
+func (sb *sandboxSpec) commandOn(ctx context.Context, network, dir string, env []string, name string, args ...string) *exec.Cmd {
+	if sb == nil {
+		cmd := exec.CommandContext(ctx, name, args...)
+		cmd.Dir = dir
+		cmd.Env = append(append(os.Environ(), env...), "HOME=/home/worker")
+		return cmd
+	}
+
+	container := fmt.Sprintf("bob-%d-%d", os.Getpid(), sandboxSeq.Add(1))
+	dockerArgs := []string{
+		"run", "--rm", "-i", "--init",
+		"--name", container,
+		"--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()),
+		"--mount", fmt.Sprintf("type=volume,src=%s,dst=%s", sb.volume, workspaceRoot),
+		"-w", dir,
+		"-e", "HOME=/tmp",
+		"-e", "CLAUDE_CONFIG_DIR=" + sandboxClaudeConfigDir(),
+	}
+	if network != "" {
+		dockerArgs = append(dockerArgs, "--network="+network)
+	}
+	for _, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		dockerArgs = append(dockerArgs, "-e", key) // value comes from the docker CLI's environment
+	}
+	dockerArgs = append(dockerArgs, sb.image, name)
+	dockerArgs = append(dockerArgs, args...)
+
+	cmd := exec.CommandContext(ctx, "docker", dockerArgs...)
+	cmd.Env = append(os.Environ(), env...)
+	// Killing the docker CLI doesn't stop the container; kill it by name.
+	cmd.Cancel = func() error {
+		exec.Command("docker", "kill", container).Run()
+		return cmd.Process.Kill()
+	}
+	return cmd
+}
+
+// repoCommand is command for a command the repo defines, such as its tests or
+// a security scanner. A container joins the spec's repoNetwork. On the host
+// it gets hostEnv instead of Bob's whole environment; it still shares Bob's
+// network and filesystem.
+func (sb *sandboxSpec) repoCommand(ctx context.Context, dir string, env []string, name string, args ...string) *exec.Cmd {
+	if sb != nil {
+		return sb.commandOn(ctx, sb.repoNetwork, dir, env, name, args...)
+	}
+	cmd := sb.command(ctx, dir, env, name, args...)
+	cmd.Env = append(append(hostEnv(), env...), "HOME=/home/worker")
+	return cmd
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSandboxForRepo(t *testing.T) {
+	var disabled *Sandbox
+	if sb := disabled.forRepo("api"); sb != nil {
+		t.Errorf("nil sandbox: forRepo = %+v, want nil", sb)
+	}
+
+	s := NewSandbox("bob-sandbox:latest", "bob_workspace", parseRepoImages("web=node:20, api=golang:1.25 ,bad"), false, "")
+	if got := s.forRepo("api").image; got != "golang:1.25" {
+		t.Errorf("api image = %q", got)
+	}
+	if got := s.forRepo("other").image; got != "bob-sandbox:latest" {
+		t.Errorf("default image = %q", got)
+	}
+	if len(s.repoImages) != 2 {
+		t.Errorf("repoImages = %v, want 2 entries", s.repoImages)
+	}
+
+	if sb := NewSandbox("", "bob_workspace", parseRepoImages("api=golang:1.25"), false, "").forRepo("api"); sb != nil {
+		t.Errorf("no default image: forRepo = %+v, want nil", sb)
+	}
+}
+
+func TestTestSandboxFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		sandbox *Sandbox
+		cfg     *RepoConfig
+		want    *sandboxSpec
+	}{
+		{"host", nil, &RepoConfig{}, nil},
+		{"test image without sandbox", nil, &RepoConfig{TestImage: "golang:1.23"}, &sandboxSpec{image: "golang:1.23", volume: defaultSandboxVolume}},
+		{"test image, sessions on the host", NewSandbox("", "ws", nil, false, ""), &RepoConfig{TestImage: "node:20"}, &sandboxSpec{image: "node:20", volume: "ws"}},
+		{"test image wins over the sandbox image", NewSandbox("bob-sandbox", "ws", nil, false, ""), &RepoConfig{Image: "my/sandbox", TestImage: "node:20"}, &sandboxSpec{image: "node:20", volume: "ws"}},
+		{"sandbox image", NewSandbox("bob-sandbox", "ws", nil, false, ""), &RepoConfig{Image: "my/sandbox"}, &sandboxSpec{image: "my/sandbox", volume: "ws"}},
+		{"test image, no network", NewSandbox("", "ws", nil, false, "none"), &RepoConfig{TestImage: "node:20"}, &sandboxSpec{image: "node:20", volume: "ws", repoNetwork: "none"}},
+		{"sandbox image, no network", NewSandbox("bob-sandbox", "ws", nil, false, "none"), &RepoConfig{}, &sandboxSpec{image: "bob-sandbox", volume: "ws", repoNetwork: "none"}},
+		{"host, no network", NewSandbox("", "ws", nil, true, "none"), &RepoConfig{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Orchestrator{sandbox: tt.sandbox}
+			got := o.testSandboxFor("api", tt.cfg)
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSandboxSpecCommand(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("host", func(t *testing.T) {
+		var sb *sandboxSpec
+		cmd := sb.command(ctx, "/workspace/api/worktrees/j1", []string{"CI=true"}, "sh", "-c", "go test ./...")
+		if cmd.Args[0] != "sh" || cmd.Dir != "/workspace/api/worktrees/j1" {
+			t.Errorf("Args = %v, Dir = %q", cmd.Args, cmd.Dir)
+		}
+		if !slices.Contains(cmd.Env, "CI=true") || !slices.Contains(cmd.Env, "HOME=/home/worker") {
+			t.Error("env not passed")
+		}
+	})
+
+	t.Run("container", func(t *testing.T) {
+		sb := &sandboxSpec{image: "golang:1.25", volume: "bob_workspace"}
+		cmd := sb.command(ctx, "/workspace/api/worktrees/j1", []string{"SECRET_TOKEN=s3cret"}, "claude", "-p", "hi")
+		args := strings.Join(cmd.Args, " ")
+		for _, want := range []string{
+			"docker run --rm -i",
+			"--mount type=volume,src=bob_workspace,dst=/workspace",
+			"-w /workspace/api/worktrees/j1",
+			"-e CLAUDE_CONFIG_DIR=/workspace/.bob/claude",
+			"-e SECRET_TOKEN golang:1.25 claude -p hi",
+		} {
+			if !strings.Contains(args, want) {
+				t.Errorf("args %q missing %q", args, want)
+			}
+		}
+		if strings.Contains(args, "s3cret") {
+			t.Error("secret value leaked onto the docker command line")
+		}
+		if !slices.Contains(cmd.Env, "SECRET_TOKEN=s3cret") {
+			t.Error("secret not in docker CLI environment")
+		}
+		if cmd.Cancel == nil {
+			t.Error("expected Cancel to kill the container")
+		}
+	})
+
+	t.Run("repo command network", func(t *testing.T) {
+		sb := &sandboxSpec{image: "golang:1.25", volume: "bob_workspace", repoNetwork: "none"}
+		tests := strings.Join(sb.repoCommand(ctx, "/workspace/api/worktrees/j1", nil, "sh", "-c", "go test ./...").Args, " ")
+		if !strings.Contains(tests, " --network=none golang:1.25 sh -c") {
+			t.Errorf("repo command args %q, want --network=none", tests)
+		}
+		// Claude Code sessions need the network to reach the API.
+		if session := strings.Join(sb.command(ctx, "/workspace/api/worktrees/j1", nil, "claude", "-p", "hi").Args, " "); strings.Contains(session, "--network") {
+			t.Errorf("session args %q, want Docker's default network", session)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxSecretFindings caps the findings reported for one diff.
+const maxSecretFindings = 20
+
+// privateKeyHeader marks the start of a private key. The private-key pattern
+// spans lines, but the scan reads a diff line by line.
+var privateKeyHeader = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)
+
+// reHunkHeader captures the first new-file line number of a diff hunk.
+var reHunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)`)
+
+// SecretFinding is something in a job's changes that looks like a credential.
+type SecretFinding struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"` // in the new file
+	Rule    string `json:"rule"`
+	Preview string `json:"preview,omitempty"` // the line, with the secret redacted
+}
+
+// scanDiffSecrets looks for credentials in the lines a unified diff adds:
+// anything matching secretPatterns (including .env-style assignments) or one
+// of Bob's own credentials. Files like .env and *.pem never get this far;
+// changedFiles leaves them out of commits. Previews are redacted, so findings
+// are safe to show.
+func scanDiffSecrets(diff string) []SecretFinding {
+	var findings []SecretFinding
+	var file string
+	line := 0 // of the next context or added line in the new file
+	for _, l := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(l, "+++ "):
+			file = strings.TrimPrefix(strings.TrimPrefix(l, "+++ "), "b/")
+		case strings.HasPrefix(l, "@@"):
+			if m := reHunkHeader.FindStringSubmatch(l); m != nil {
+				line, _ = strconv.Atoi(m[1])
+			}
+		case strings.HasPrefix(l, "-") || strings.HasPrefix(l, `\`):
+		case strings.HasPrefix(l, "+"):
+			if rule := secretRule(l[1:]); rule != "" {
+				preview := truncate(strings.TrimSpace(redactSecrets(l[1:])), 120)
+				findings = append(findings, SecretFinding{File: file, Line: line, Rule: rule, Preview: preview})
+				if len(findings) == maxSecretFindings {
+					return findings
+				}
+			}
+			line++
+		default:
+			line++
+		}
+	}
+	return findings
+}
+
+// secretRule returns the rule a line of code breaks, or empty string.
+func secretRule(s string) string {
+	if cur := knownSecrets.Load(); cur != nil {
+		for _, secret := range *cur {
+			if strings.Contains(s, secret) {
+				return "bob-credential"
+			}
+		}
+	}
+	if privateKeyHeader.MatchString(s) {
+		return "private-key"
+	}
+	for _, p := range secretPatterns {
+		if p.re.MatchString(s) {
+			return p.rule
+		}
+	}
+	return ""
+}
+
+// checkSecrets scans the changes about to be committed for credentials and
+// reports what it finds in a secret_scan event. An error producing the diff
+// is returned; a job shouldn't push what it couldn't check.
+func (o *Orchestrator) checkSecrets(ctx context.Context, jobID, repoDir string) ([]SecretFinding, error) {
+	diff, err := WorktreeDiff(ctx, repoDir)
+	if err != nil {
+		return nil, err
+	}
+	findings := scanDiffSecrets(diff)
+	o.hub.Emit(jobID, SecretScanData{Findings: findings})
+	if len(findings) > 0 {
+		slog.WarnContext(ctx, "orchestrator: possible secrets in the changes, holding the push", "job_id", jobID, "findings", len(findings))
+	}
+	return findings, nil
+}
+
+// formatSecretFindings asks the requester what to do about findings: the
+// changes wait in awaiting_push like a dry run's.
+func formatSecretFindings(findings []SecretFinding) string {
+	var b strings.Builder
+	b.WriteString("I found what looks like credentials in the changes, so I haven't committed or pushed anything:\n")
+	for _, f := range findings {
+		fmt.Fprintf(&b, "• `%s:%d` — %s", f.File, f.Line, f.Rule)
+		if f.Preview != "" {
+			fmt.Fprintf(&b, ": `%s`", strings.ReplaceAll(f.Preview, "`", "'"))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("Reply `push it` if they're safe to publish (e.g. test fixtures), describe what to change (e.g. \"read it from an environment variable\"), or `cancel`.")
+	return b.String()
+}
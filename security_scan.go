@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// scanTimeout bounds a single security scanner run.
+const scanTimeout = 5 * time.Minute
+
+// maxScanOutput is how much of a scanner's output (from the end) is kept for
+// the fix prompt and the PR body.
+const maxScanOutput = 4000
+
+// builtinScanners return the shell command that scans the changed files with a
+// well-known tool, or "" if none of the files concern it. Any other entry in a
+// scanner list is run as a shell command itself, with the changed files in
+// BOB_CHANGED_FILES, one per line.
+var builtinScanners = map[string]func(files []string) string{
+	"gosec": func(files []string) string {
+		var pkgs []string
+		for _, d := range changedDirs(files, func(f string) bool { return strings.HasSuffix(f, ".go") }) {
+			pkgs = append(pkgs, shellQuote("./"+d))
+		}
+		if len(pkgs) == 0 {
+			return ""
+		}
+		return "gosec -quiet -fmt=text " + strings.Join(pkgs, " ")
+	},
+	"npm-audit": func(files []string) string {
+		var audits []string
+		for _, d := range changedDirs(files, func(f string) bool {
+			return path.Base(f) == "package.json" || path.Base(f) == "package-lock.json"
+		}) {
+			audits = append(audits, "(cd "+shellQuote(d)+" && npm audit --audit-level=high)")
+		}
+		return strings.Join(audits, " && ")
+	},
+	"trivy": func(files []string) string {
+		if !slices.ContainsFunc(files, isDependencyOrInfraFile) {
+			return ""
+		}
+		return "trivy fs --quiet --exit-code 1 --scanners vuln,misconfig ."
+	},
+	"gitleaks": func(files []string) string {
+		// Only the changes: the diff of tracked files, plus untracked files in full.
+		return "{ git diff HEAD; git ls-files -z --others --exclude-standard | xargs -0 -r -n1 git diff --no-index /dev/null; } | gitleaks stdin --no-banner --redact --exit-code 1"
+	},
+}
+
+// isDependencyOrInfraFile reports whether a change to f can bring in a
+// vulnerability trivy finds: a dependency manifest or lockfile, a Dockerfile
+// or Terraform.
+func isDependencyOrInfraFile(f string) bool {
+	switch path.Base(f) {
+	case "go.mod", "go.sum", "package.json", "package-lock.json", "yarn.lock", "pnpm-lock.yaml",
+		"requirements.txt", "poetry.lock", "uv.lock", "Pipfile.lock", "Cargo.lock", "Gemfile.lock", "Dockerfile":
+		return true
+	}
+	return strings.HasSuffix(f, ".tf")
+}
+
+// changedDirs returns the directories of the files match accepts, each once
+// and in order, with "." for the repo root.
+func changedDirs(files []string, match func(string) bool) []string {
+	var dirs []string
+	for _, f := range files {
+		if d := path.Dir(f); match(f) && !slices.Contains(dirs, d) {
+			dirs = append(dirs, d)
+		}
+	}
+	return dirs
+}
+
+// parseScannerList parses a comma-separated list of scanners, such as
+// BOB_SECURITY_SCANNERS.
+func parseScannerList(s string) []string {
+	var scanners []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			scanners = append(scanners, name)
+		}
+	}
+	return scanners
+}
+
+// shellQuote quotes s for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// scanResult is the outcome of the security scan before a PR is opened.
+type scanResult struct {
+	Scanners []string          // scanners that ran
+	Findings []SecurityFinding // what they reported after the fix pass, if any
+	Fixed    bool              // Claude Code had a pass at fixing the first findings
+}
+
+// securityScanners returns the scanners to run for the repo: its .bob.yml
+// security_scanners, or else the default list (BOB_SECURITY_SCANNERS).
+func (c *RepoConfig) securityScanners(defaults []string) []string {
+	if c != nil && c.SecurityScanners != nil {
+		return c.SecurityScanners
+	}
+	return defaults
+}
+
+// securityScan runs the repo's security scanners on the job's changes. With
+// security_fix set, findings go to a Claude Code session to fix, after which
+// the paths limit is enforced again and the tests and scanners rerun. Remaining findings are returned for the PR
+// body; the scan never stops the PR. It only returns an error if a session or
+// the re-run tests could not run.
+func (o *Orchestrator) securityScan(ctx context.Context, jobID, repo, repoDir, task, planContent string, cfg *RepoConfig, vr *verifyResult) (scanResult, error) {
+	var res scanResult
+	scanners := cfg.securityScanners(o.securityScanners)
+	if len(scanners) == 0 {
+		return res, nil
+	}
+	sb := o.sandboxFor(repo, cfg)
+	if sb == nil && o.sandbox.repoNetwork() != "" {
+		slog.WarnContext(ctx, "orchestrator: BOB_SANDBOX_TEST_NETWORK is set but scanners would run on the host, skipping the security scan", "job_id", jobID)
+		return res, nil
+	}
+	scan := func() error {
+		files, err := changedFiles(ctx, repoDir)
+		if err != nil {
+			return err
+		}
+		res.Scanners, res.Findings = nil, nil
+		_, err = o.runStep(ctx, jobID, "security_scan", strings.Join(scanners, ", "), func(ctx context.Context) (string, error) {
+			res.Scanners, res.Findings = runScanners(ctx, sb, repoDir, scanners, files)
+			if len(res.Findings) == 0 {
+				return fmt.Sprintf("no findings (%d scanners)", len(res.Scanners)), nil
+			}
+			return fmt.Sprintf("findings from %s", findingScanners(res.Findings)), nil
+		})
+		if err != nil {
+			return err
+		}
+		o.hub.Emit(jobID, SecurityScanData{Scanners: res.Scanners, Findings: res.Findings, AfterFix: res.Fixed})
+		return nil
+	}
+	if err := scan(); err != nil {
+		return res, fmt.Errorf("security scan: %w", err)
+	}
+	if len(res.Findings) == 0 || cfg == nil || !cfg.SecurityFix {
+		return res, nil
+	}
+	if _, over := o.overBudget(jobID); over {
+		return res, nil // the caller aborts the job
+	}
+
+	slog.InfoContext(ctx, "orchestrator: security findings, attempting a fix", "job_id", jobID, "scanners", findingScanners(res.Findings))
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Task\n\n%s\n\n## Approved Plan\n\n%s\n\n## Security findings\n\n", task, planContent)
+	for _, f := range res.Findings {
+		fmt.Fprintf(&b, "### %s (`%s`)\n\n```\n%s\n```\n\n", f.Scanner, f.Command, f.Output)
+	}
+	_, err := o.runStep(ctx, jobID, "fix_security", findingScanners(res.Findings), func(ctx context.Context) (string, error) {
+		return sessionPreview(RunSession(ctx, o.claudeCodeToken, o.hub, jobID, SessionOpts{
+			RepoDir:        repoDir,
+			Prompt:         b.String(),
+			SystemPrompt:   fixSecuritySystemPrompt,
+			PermissionMode: "acceptEdits",
+			Tools:          o.toolRules(cfg, toolPhaseImplement),
+			Sandbox:        sb,
+			Limits:         o.sessionLimits(ctx, jobID, repo),
+		}))
+	})
+	if err != nil {
+		return res, fmt.Errorf("fix security findings: %w", err)
+	}
+	res.Fixed = true
+	if err := o.enforceScope(ctx, jobID, repoDir, cfg); err != nil {
+		return res, err
+	}
+	if vr.Command != "" {
+		if *vr, err = o.verifyChanges(ctx, jobID, repo, repoDir, task, planContent, cfg); err != nil {
+			return res, err
+		}
+	}
+	if err := scan(); err != nil {
+		return res, fmt.Errorf("security scan: %w", err)
+	}
+	return res, nil
+}
+
+// runScanners runs each scanner that applies to files in repoDir (inside sb's
+// container, if set) and returns those that ran and what they reported. A
+// scanner that isn't installed or times out is logged and skipped.
+func runScanners(ctx context.Context, sb *sandboxSpec, repoDir string, scanners, files []string) (ran []string, findings []SecurityFinding) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	env := []string{"CI=true", "BOB_CHANGED_FILES=" + strings.Join(files, "\n")}
+	for _, name := range scanners {
+		command := name
+		if builtin, ok := builtinScanners[name]; ok {
+			if command = builtin(files); command == "" {
+				continue
+			}
+		}
+		runCtx, cancel := context.WithTimeout(ctx, scanTimeout)
+		out, err := sb.repoCommand(runCtx, repoDir, env, "sh", "-c", command).CombinedOutput()
+		timedOut := runCtx.Err() == context.DeadlineExceeded
+		cancel()
+
+		var exitErr *exec.ExitError
+		switch {
+		case err == nil:
+			ran = append(ran, name)
+		case timedOut:
+			slog.WarnContext(ctx, "orchestrator: security scanner timed out", "scanner", name, "timeout", scanTimeout)
+		case errors.As(err, &exitErr) && exitErr.ExitCode() == 127:
+			slog.WarnContext(ctx, "orchestrator: security scanner not installed", "scanner", name, "output", truncate(string(out), 300))
+		case errors.As(err, &exitErr):
+			ran = append(ran, name)
+			findings = append(findings, SecurityFinding{
+				Scanner: name, Command: command,
+				Output: tailTruncate(redactSecrets(strings.TrimSpace(string(out))), maxScanOutput),
+			})
+		default:
+			slog.WarnContext(ctx, "orchestrator: security scanner failed to run", "scanner", name, "err", err)
+		}
+	}
+	return ran, findings
+}
+
+// findingScanners lists the scanners with findings, for messages.
+func findingScanners(findings []SecurityFinding) string {
+	names := make([]string, len(findings))
+	for i, f := range findings {
+		names[i] = f.Scanner
+	}
+	return strings.Join(names, ", ")
+}
+
+// prBodyNote returns a warning listing the remaining findings for the PR body,
+// or empty string if there are none.
+func (r scanResult) prBodyNote() string {
+	if len(r.Findings) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "> **Warning:** security scanners reported findings: %s.\n\n", findingScanners(r.Findings))
+	for _, f := range r.Findings {
+		fmt.Fprintf(&b, "<details><summary>%s</summary>\n\n```\n%s\n```\n\n</details>\n\n", f.Scanner, f.Output)
+	}
+	return b.String()
+}
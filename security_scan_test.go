@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecurityScan(t *testing.T) {
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("password = hunter2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	o := &Orchestrator{hub: hub, securityScanners: []string{"gosec", "no-such-scanner-xyz", "true"}}
+	cfg := &RepoConfig{SecurityScanners: []string{
+		"gosec", // no Go files changed: skipped
+		"no-such-scanner-xyz",
+		`for f in $BOB_CHANGED_FILES; do grep -n password "$f" && exit 1; done; exit 0`,
+	}}
+	res, err := o.securityScan(context.Background(), "job-1", "web", dir, "task", "plan", cfg, &verifyResult{})
+	if err != nil {
+		t.Fatalf("securityScan: %v", err)
+	}
+	if len(res.Scanners) != 1 || len(res.Findings) != 1 || !strings.Contains(res.Findings[0].Output, "1:password") {
+		t.Fatalf("scanResult = %+v", res)
+	}
+	if note := res.prBodyNote(); !strings.Contains(note, "**Warning:** security scanners reported findings") || !strings.Contains(note, "1:password") {
+		t.Errorf("prBodyNote = %q", note)
+	}
+
+	var got *SecurityScanData
+	for deadline := time.Now().Add(time.Second); got == nil && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		events, _ := hub.store.Events("job-1")
+		for _, e := range events {
+			if d, ok := e.Data.(SecurityScanData); ok {
+				got = &d
+			}
+		}
+	}
+	if got == nil || len(got.Findings) != 1 || got.AfterFix {
+		t.Errorf("security_scan event = %+v", got)
+	}
+
+	// The repo's empty list turns off the default scanners.
+	res, _ = o.securityScan(context.Background(), "job-2", "web", dir, "task", "plan", &RepoConfig{SecurityScanners: []string{}}, &verifyResult{})
+	if len(res.Scanners) != 0 {
+		t.Errorf("scanners ran despite security_scanners: []: %+v", res)
+	}
+}
+
+func TestBuiltinScanners(t *testing.T) {
+	files := []string{"cmd/it's/main.go", "main.go", "web/package.json", "README.md"}
+	for name, want := range map[string]string{
+		"gosec":     `gosec -quiet -fmt=text './cmd/it'\''s' './.'`,
+		"npm-audit": `(cd 'web' && npm audit --audit-level=high)`,
+		"trivy":     "trivy fs --quiet --exit-code 1 --scanners vuln,misconfig .",
+	} {
+		if got := builtinScanners[name](files); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if got := builtinScanners["trivy"]([]string{"README.md"}); got != "" {
+		t.Errorf("trivy ran for docs-only changes: %q", got)
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+const (
+	// maxSentryBodySize is the maximum request body size accepted from Sentry webhooks.
+	maxSentryBodySize = 5 << 20 // 5 MB
+	// maxSentryFrames caps the stack frames put in the task, innermost first.
+	maxSentryFrames = 15
+	// sentryChannel is the channel of Sentry jobs; the thread is the Sentry issue ID.
+	sentryChannel = "sentry"
+)
+
+// SentryPlatform reports on jobs started from Sentry issue alerts as comments
+// on the Sentry issue. Sentry can't send Bob replies, so plans are approved
+// from the web UI; ThreadMessages is always empty.
+type SentryPlatform struct {
+	apiURL string // e.g. https://sentry.io
+	token  string // auth token with the event:write scope
+	tag    string // event tag that marks an issue for Bob; its value names the repo
+}
+
+// NewSentryPlatform creates a SentryPlatform for the Sentry at apiURL
+// (sentry.io if empty).
+func NewSentryPlatform(apiURL, token, tag string) *SentryPlatform {
+	if apiURL == "" {
+		apiURL = "https://sentry.io"
+	}
+	if tag == "" {
+		tag = "bob"
+	}
+	return &SentryPlatform{apiURL: strings.TrimSuffix(apiURL, "/"), token: token, tag: tag}
+}
+
+// Name implements ChatPlatform.
+func (p *SentryPlatform) Name() string { return "sentry" }
+
+// Notify posts text as a comment on the Sentry issue in the context.
+func (p *SentryPlatform) Notify(ctx context.Context, text string) error {
+	issueID, _ := ctx.Value(ctxKeyThreadTS).(string)
+	if issueID == "" {
+		return fmt.Errorf("notify: no sentry issue in context")
+	}
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return fmt.Errorf("marshal comment: %w", err)
+	}
+	apiURL := fmt.Sprintf("%s/api/0/issues/%s/comments/", p.apiURL, issueID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sentry api: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("sentry api status %d: %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+// ThreadMessages implements ChatPlatform.
+func (p *SentryPlatform) ThreadMessages(ctx context.Context, channel, thread string) ([]Message, error) {
+	return nil, nil
+}
+
+// StripMention implements ChatPlatform.
+func (p *SentryPlatform) StripMention(text string) string { return strings.TrimSpace(text) }
+
+// MentionUser implements ChatPlatform.
+func (p *SentryPlatform) MentionUser(userID string) string { return userID }
+
+// sentryAlertEvent covers the fields we use from an event_alert webhook, sent
+// when an issue alert rule with the Bob integration as its action fires.
+type sentryAlertEvent struct {
+	Action string `json:"action"`
+	Data   struct {
+		Event struct {
+			IssueID   string      `json:"issue_id"`
+			Title     string      `json:"title"`
+			Culprit   string      `json:"culprit"`
+			WebURL    string      `json:"web_url"`
+			Tags      [][2]string `json:"tags"`
+			Exception struct {
+				Values []struct {
+					Type       string `json:"type"`
+					Value      string `json:"value"`
+					Stacktrace *struct {
+						Frames []sentryFrame `json:"frames"`
+					} `json:"stacktrace"`
+				} `json:"values"`
+			} `json:"exception"`
+		} `json:"event"`
+	} `json:"data"`
+}
+
+// sentryFrame is one stack frame, outermost first as Sentry sends them.
+type sentryFrame struct {
+	Filename    string `json:"filename"`
+	AbsPath     string `json:"abs_path"`
+	Function    string `json:"function"`
+	Module      string `json:"module"`
+	LineNo      int    `json:"lineno"`
+	InApp       bool   `json:"in_app"`
+	ContextLine string `json:"context_line"`
+}
+
+// sentryIssue is an issue alert Bob should work on.
+type sentryIssue struct {
+	ID      string
+	Repo    string // from the tag
+	Title   string
+	Culprit string // where Sentry attributes the error, e.g. a function
+	URL     string
+	Trace   string
+}
+
+// tagValue returns the value of the event tag key, or "".
+func (e *sentryAlertEvent) tagValue(key string) string {
+	for _, kv := range e.Data.Event.Tags {
+		if kv[0] == key {
+			return strings.TrimSpace(kv[1])
+		}
+	}
+	return ""
+}
+
+// issueFromEvent extracts the issue from an event alert, returning false for
+// alerts Bob should ignore: other actions and events without the tag.
+func (p *SentryPlatform) issueFromEvent(evt sentryAlertEvent) (sentryIssue, bool) {
+	ev := evt.Data.Event
+	repo := evt.tagValue(p.tag)
+	if evt.Action != "triggered" || ev.IssueID == "" || repo == "" {
+		return sentryIssue{}, false
+	}
+	return sentryIssue{
+		ID: ev.IssueID, Repo: repo, Title: ev.Title, Culprit: ev.Culprit, URL: ev.WebURL, Trace: formatSentryTrace(evt),
+	}, true
+}
+
+// formatSentryTrace renders the event's exceptions the way a Python-style
+// traceback reads: outermost frame first, the exception last. Frames outside
+// the application are marked, and long traces keep the innermost frames.
+func formatSentryTrace(evt sentryAlertEvent) string {
+	var b strings.Builder
+	for _, exc := range evt.Data.Event.Exception.Values {
+		if exc.Stacktrace != nil {
+			frames := exc.Stacktrace.Frames
+			if len(frames) > maxSentryFrames {
+				fmt.Fprintf(&b, "  ... %d earlier frame(s)\n", len(frames)-maxSentryFrames)
+				frames = frames[len(frames)-maxSentryFrames:]
+			}
+			for _, f := range frames {
+				file := f.Filename
+				if file == "" {
+					file = f.AbsPath
+				}
+				if file == "" {
+					file = f.Module
+				}
+				fmt.Fprintf(&b, "  %s:%d in %s", file, f.LineNo, f.Function)
+				if !f.InApp {
+					b.WriteString(" (library)")
+				}
+				b.WriteString("\n")
+				if line := strings.TrimSpace(f.ContextLine); line != "" {
+					fmt.Fprintf(&b, "    %s\n", line)
+				}
+			}
+		}
+		fmt.Fprintf(&b, "%s: %s\n", exc.Type, exc.Value)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// task is the job's task: the error, where it happened and its stack trace.
+// The first line doubles as the PR title.
+func (i sentryIssue) task() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Fix the Sentry error: %s\n\n", i.Title)
+	b.WriteString("Find the root cause of this error in production and fix it, adding a regression test where the repo has tests.")
+	if i.Culprit != "" {
+		fmt.Fprintf(&b, " Sentry attributes it to `%s`.", i.Culprit)
+	}
+	if i.Trace != "" {
+		fmt.Fprintf(&b, "\n\nStack trace:\n\n```\n%s\n```", i.Trace)
+	}
+	return b.String()
+}
+
+// verifySentrySignature checks the Sentry-Hook-Signature header, a hex
+// HMAC-SHA256 of the body keyed with the integration's client secret.
+func verifySentrySignature(secret string, body []byte, header string) bool {
+	want, err := hex.DecodeString(header)
+	if err != nil || len(want) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// NewSentryWebhookHandler handles webhooks from a Sentry internal integration.
+// An event alert for an issue tagged for Bob starts a planning job against the
+// repo the tag names, with the stack trace as context; the plan, and later the
+// PR, are posted as comments on the Sentry issue, and the PR links back to it.
+func NewSentryWebhookHandler(secret string, platform *SentryPlatform, orch *Orchestrator, hub *Hub, bobURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxSentryBodySize+1))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxSentryBodySize {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if !verifySentrySignature(secret, body, r.Header.Get("Sentry-Hook-Signature")) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Sentry-Hook-Resource") != "event_alert" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		var evt sentryAlertEvent
+		if err := json.Unmarshal(body, &evt); err != nil {
+			http.Error(w, "failed to parse event", http.StatusBadRequest)
+			return
+		}
+		issue, ok := platform.issueFromEvent(evt)
+		if !ok || hub.ActiveJobForThread(sentryChannel, issue.ID) != "" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		slog.Info("sentry: issue alert", "issue", issue.ID, "title", issue.Title, "repo", issue.Repo)
+		go handleSentryIssue(platform, orch, hub, bobURL, issue)
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// handleSentryIssue plans a fix for a Sentry issue and posts the plan on it.
+func handleSentryIssue(p *SentryPlatform, orch *Orchestrator, hub *Hub, bobURL string, issue sentryIssue) {
+	ctx := WithSlackThread(context.Background(), sentryChannel, issue.ID)
+	ctx = WithPlatform(ctx, p.Name())
+	ctx = WithHub(ctx, hub)
+	reply := func(text string) {
+		if err := p.Notify(ctx, text); err != nil {
+			slog.ErrorContext(ctx, "sentry: failed to comment on issue", "err", err)
+		}
+	}
+	defer recoverGoroutine("sentry issue", func() { reply(panicReply) })
+
+	hub.LockThread(sentryChannel, issue.ID)
+	defer hub.UnlockThread(sentryChannel, issue.ID)
+	if hub.ActiveJobForThread(sentryChannel, issue.ID) != "" {
+		return // a concurrent alert got here first
+	}
+
+	intent := IntentResult{Repo: issue.Repo, Task: issue.task()}
+	if issue.URL != "" {
+		intent.PRFooter = fmt.Sprintf("\n\nSentry issue: %s", issue.URL)
+	}
+	result, err := orch.startJob(ctx, intent, "", nil, func(jobID string) {
+		reply(workingMessage("Planning a fix for this issue.", bobURL, jobID, ""))
+	})
+	switch {
+	case err != nil:
+		slog.ErrorContext(ctx, "sentry: failed to plan a fix", "job_id", result.JobID, "err", err)
+		reply(errorReply(err))
+	case result.PlanText != "":
+		approve := "Approve it on the job page to implement it."
+		if bobURL != "" {
+			approve = fmt.Sprintf("Approve it at %s/jobs/%s to implement it.", bobURL, result.JobID)
+		}
+		reply(fmt.Sprintf("Here's my plan for *%s*:\n\n%s\n\n%s", issue.Repo, planMarkdown(hub, result), approve))
+	case result.Text != "":
+		reply(result.Text)
+	}
+}
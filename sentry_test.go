@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const sentryAlertPayload = `{
+  "action": "triggered",
+  "data": {
+    "event": {
+      "issue_id": "1117540176",
+      "title": "ZeroDivisionError: division by zero",
+      "culprit": "billing.invoice in total",
+      "web_url": "https://sentry.io/organizations/acme/issues/1117540176/events/abc/",
+      "tags": [["environment", "production"], ["bob", "billing"]],
+      "exception": {"values": [{
+        "type": "ZeroDivisionError",
+        "value": "division by zero",
+        "stacktrace": {"frames": [
+          {"filename": "django/core/handlers.py", "function": "inner", "lineno": 47, "in_app": false},
+          {"filename": "billing/invoice.py", "function": "total", "lineno": 12, "in_app": true, "context_line": "    return amount / count"}
+        ]}
+      }]}
+    }
+  }
+}`
+
+func TestSentryIssueFromEvent(t *testing.T) {
+	p := NewSentryPlatform("", "tok", "")
+	var evt sentryAlertEvent
+	if err := json.Unmarshal([]byte(sentryAlertPayload), &evt); err != nil {
+		t.Fatal(err)
+	}
+
+	issue, ok := p.issueFromEvent(evt)
+	if !ok || issue.ID != "1117540176" || issue.Repo != "billing" {
+		t.Fatalf("issue = %+v, %v", issue, ok)
+	}
+	want := "  django/core/handlers.py:47 in inner (library)\n  billing/invoice.py:12 in total\n    return amount / count\nZeroDivisionError: division by zero"
+	if issue.Trace != want {
+		t.Errorf("trace = %q, want %q", issue.Trace, want)
+	}
+	task := issue.task()
+	if first, _, _ := strings.Cut(task, "\n"); first != "Fix the Sentry error: ZeroDivisionError: division by zero" {
+		t.Errorf("task subject = %q", first)
+	}
+	if !strings.Contains(task, "`billing.invoice in total`") || !strings.Contains(task, want) {
+		t.Errorf("task = %q", task)
+	}
+
+	evt.Data.Event.Tags = [][2]string{{"environment", "production"}}
+	if _, ok := p.issueFromEvent(evt); ok {
+		t.Error("an untagged event should be ignored")
+	}
+}
+
+func TestSentryWebhookHandler(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	p := NewSentryPlatform("", "tok", "")
+	h := NewSentryWebhookHandler("s3cret", p, &Orchestrator{hub: hub}, hub, "")
+
+	send := func(resource, body, sig string) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/sentry", strings.NewReader(body))
+		req.Header.Set("Sentry-Hook-Resource", resource)
+		req.Header.Set("Sentry-Hook-Signature", sig)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte("s3cret"))
+		mac.Write([]byte(body))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	if code := send("event_alert", sentryAlertPayload, sign("other")); code != http.StatusUnauthorized {
+		t.Errorf("bad signature: status %d", code)
+	}
+	if code := send("issue", sentryAlertPayload, sign(sentryAlertPayload)); code != http.StatusNoContent {
+		t.Errorf("issue resource: status %d", code)
+	}
+	untagged := strings.Replace(sentryAlertPayload, `["bob", "billing"]`, `["team", "billing"]`, 1)
+	if code := send("event_alert", untagged, sign(untagged)); code != http.StatusNoContent {
+		t.Errorf("untagged event: status %d", code)
+	}
+
+	// An issue with an active job isn't picked up again.
+	hub.RegisterThreadJob(sentryChannel, "1117540176", "job-1")
+	if code := send("event_alert", sentryAlertPayload, sign(sentryAlertPayload)); code != http.StatusNoContent {
+		t.Errorf("active issue: status %d", code)
+	}
+}
+
+func TestSentryPlatform_Notify(t *testing.T) {
+	var gotPath, gotAuth, gotText string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		var c struct{ Text string }
+		json.Unmarshal(body, &c)
+		gotText = c.Text
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	p := NewSentryPlatform(srv.URL+"/", "tok", "")
+	ctx := WithSlackThread(context.Background(), sentryChannel, "42")
+	if err := p.Notify(ctx, "Opened https://github.com/acme/billing/pull/7"); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if gotPath != "/api/0/issues/42/comments/" || gotAuth != "Bearer tok" || !strings.Contains(gotText, "pull/7") {
+		t.Errorf("path = %q, auth = %q, text = %q", gotPath, gotAuth, gotText)
+	}
+	if err := p.Notify(context.Background(), "hi"); err == nil {
+		t.Error("expected an error without an issue in the context")
+	}
+}
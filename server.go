@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// defaultListenAddr is where Bob serves HTTP unless BOB_LISTEN_ADDR says otherwise.
+const defaultListenAddr = ":8080"
+
+// serverConfig is where and how Bob serves HTTP. Webhooks are always served
+// on Addr; the dashboard, API, event streams and metrics too, unless UIAddr
+// moves them to a listener of their own (e.g. one bound to an internal
+// interface).
+type serverConfig struct {
+	Addr   string // BOB_LISTEN_ADDR
+	UIAddr string // BOB_UI_LISTEN_ADDR; empty serves everything on Addr
+
+	// TLS on Addr: a certificate and key file, or certificates from Let's
+	// Encrypt for AutocertDomains, cached in AutocertDir. UIAddr is plain HTTP.
+	CertFile, KeyFile string
+	AutocertDomains   []string
+	AutocertDir       string
+}
+
+// serverConfigFromEnv reads the server settings. Autocert certificates are
+// cached under dataDir.
+func serverConfigFromEnv(dataDir string) (serverConfig, error) {
+	c := serverConfig{
+		Addr:        os.Getenv("BOB_LISTEN_ADDR"),
+		UIAddr:      os.Getenv("BOB_UI_LISTEN_ADDR"),
+		CertFile:    os.Getenv("BOB_TLS_CERT"),
+		KeyFile:     os.Getenv("BOB_TLS_KEY"),
+		AutocertDir: filepath.Join(dataDir, "autocert"),
+	}
+	if c.Addr == "" {
+		c.Addr = defaultListenAddr
+	}
+	for _, d := range strings.Split(os.Getenv("BOB_TLS_AUTOCERT_DOMAINS"), ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			c.AutocertDomains = append(c.AutocertDomains, d)
+		}
+	}
+	switch {
+	case (c.CertFile == "") != (c.KeyFile == ""):
+		return c, errors.New("BOB_TLS_CERT and BOB_TLS_KEY must be set together")
+	case c.CertFile != "" && len(c.AutocertDomains) > 0:
+		return c, errors.New("set either BOB_TLS_CERT/BOB_TLS_KEY or BOB_TLS_AUTOCERT_DOMAINS, not both")
+	case c.UIAddr != "" && c.UIAddr == c.Addr:
+		return c, fmt.Errorf("BOB_UI_LISTEN_ADDR must differ from BOB_LISTEN_ADDR (%s)", c.Addr)
+	}
+	return c, nil
+}
+
+// tlsConfig returns the TLS configuration for Addr, or nil for plain HTTP.
+func (c serverConfig) tlsConfig() (*tls.Config, error) {
+	switch {
+	case len(c.AutocertDomains) > 0:
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(c.AutocertDomains...),
+			Cache:      autocert.DirCache(c.AutocertDir),
+		}
+		// Includes the ACME TLS-ALPN protocol, so certificates can be issued
+		// through this listener as long as it is reachable on port 443.
+		return m.TLSConfig(), nil
+	case c.CertFile != "":
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load TLS certificate: %w", err)
+		}
+		return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+	}
+	return nil, nil
+}
+
+// serve serves public on Addr and, if UIAddr is set, ui on UIAddr, until one
+// of them fails.
+func (c serverConfig) serve(public, ui http.Handler) error {
+	tlsCfg, err := c.tlsConfig()
+	if err != nil {
+		return err
+	}
+	errc := make(chan error, 2)
+	if c.UIAddr != "" {
+		go func() {
+			slog.Info("Bob UI listening", "addr", c.UIAddr)
+			errc <- fmt.Errorf("UI listener: %w", http.ListenAndServe(c.UIAddr, ui))
+		}()
+	}
+	go func() {
+		srv := &http.Server{Addr: c.Addr, Handler: public, TLSConfig: tlsCfg}
+		slog.Info("Bob listening", "addr", c.Addr, "tls", tlsCfg != nil)
+		if tlsCfg != nil {
+			errc <- srv.ListenAndServeTLS("", "")
+			return
+		}
+		errc <- srv.ListenAndServe()
+	}()
+	return <-errc
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestServerConfigFromEnv(t *testing.T) {
+	for _, k := range []string{"BOB_LISTEN_ADDR", "BOB_UI_LISTEN_ADDR", "BOB_TLS_CERT", "BOB_TLS_KEY", "BOB_TLS_AUTOCERT_DOMAINS"} {
+		t.Setenv(k, "")
+	}
+	c, err := serverConfigFromEnv("/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Addr != ":8080" || c.UIAddr != "" || c.AutocertDomains != nil || c.AutocertDir != "/data/autocert" {
+		t.Errorf("defaults = %+v", c)
+	}
+
+	t.Setenv("BOB_LISTEN_ADDR", ":443")
+	t.Setenv("BOB_UI_LISTEN_ADDR", "127.0.0.1:8081")
+	t.Setenv("BOB_TLS_AUTOCERT_DOMAINS", " bob.example.com, ,bob2.example.com")
+	c, err = serverConfigFromEnv("/data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Addr != ":443" || c.UIAddr != "127.0.0.1:8081" || !slices.Equal(c.AutocertDomains, []string{"bob.example.com", "bob2.example.com"}) {
+		t.Errorf("config = %+v", c)
+	}
+	if cfg, err := c.tlsConfig(); err != nil || cfg == nil || cfg.GetCertificate == nil {
+		t.Errorf("autocert tlsConfig = %v, %v", cfg, err)
+	}
+
+	for name, env := range map[string]map[string]string{
+		"cert without key":      {"BOB_TLS_CERT": "c.pem"},
+		"key without cert":      {"BOB_TLS_KEY": "k.pem"},
+		"cert and autocert":     {"BOB_TLS_CERT": "c.pem", "BOB_TLS_KEY": "k.pem", "BOB_TLS_AUTOCERT_DOMAINS": "bob.example.com"},
+		"UI on the same listen": {"BOB_LISTEN_ADDR": ":9000", "BOB_UI_LISTEN_ADDR": ":9000"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, k := range []string{"BOB_LISTEN_ADDR", "BOB_UI_LISTEN_ADDR", "BOB_TLS_CERT", "BOB_TLS_KEY", "BOB_TLS_AUTOCERT_DOMAINS"} {
+				t.Setenv(k, env[k])
+			}
+			if _, err := serverConfigFromEnv("/data"); err == nil {
+				t.Error("want error")
+			}
+		})
+	}
+}
+
+func TestServerConfig_TLSConfig(t *testing.T) {
+	if cfg, err := (serverConfig{Addr: ":8080"}).tlsConfig(); cfg != nil || err != nil {
+		t.Errorf("plain HTTP tlsConfig = %v, %v", cfg, err)
+	}
+
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeTestCert(t, certFile, keyFile)
+	cfg, err := (serverConfig{CertFile: certFile, KeyFile: keyFile}).tlsConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Certificates) != 1 {
+		t.Errorf("certificates = %d, want 1", len(cfg.Certificates))
+	}
+
+	if _, err := (serverConfig{CertFile: keyFile, KeyFile: certFile}).tlsConfig(); err == nil {
+		t.Error("want error for mismatched files")
+	}
+}
+
+// writeTestCert writes a self-signed certificate and its key as PEM files.
+func writeTestCert(t *testing.T, certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"bob.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
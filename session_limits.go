@@ -0,0 +1,114 @@
+package main
+
+import (
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultSessionTimeout bounds a Claude Code run when no timeout is configured.
+const defaultSessionTimeout = 15 * time.Minute
+
+// SessionLimits are the per-run Claude Code settings. Zero values fall back to
+// defaultSessionTimeout and the CLI's own defaults.
+type SessionLimits struct {
+	Timeout  time.Duration
+	MaxTurns int    // --max-turns; 0 = unlimited
+	Model    string // --model; empty = CLI default
+
+	// FallbackModel is used when Model is overloaded (--fallback-model), and
+	// for all of a job's sessions once it has cost FallbackAfterUSD (0 = never).
+	FallbackModel    string
+	FallbackAfterUSD float64
+}
+
+// timeout returns the effective run timeout.
+func (l SessionLimits) timeout() time.Duration {
+	if l.Timeout <= 0 {
+		return defaultSessionTimeout
+	}
+	return l.Timeout
+}
+
+// SessionConfig holds the global session limits (BOB_CLAUDE_TIMEOUT,
+// BOB_CLAUDE_MAX_TURNS, BOB_CLAUDE_MODEL) and per-repo overrides.
+type SessionConfig struct {
+	mu       sync.RWMutex
+	defaults SessionLimits
+	repos    map[string]SessionLimits
+}
+
+// NewSessionConfig creates a SessionConfig. Non-zero fields of a repo override
+// replace the corresponding default.
+func NewSessionConfig(defaults SessionLimits, repos map[string]SessionLimits) *SessionConfig {
+	return &SessionConfig{defaults: defaults, repos: repos}
+}
+
+// update replaces the limits, e.g. on a config reload. Sessions already
+// running keep theirs.
+func (c *SessionConfig) update(defaults SessionLimits, repos map[string]SessionLimits) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.defaults, c.repos = defaults, repos
+}
+
+// forRepo returns the limits for a repo. A nil config yields the defaults.
+func (c *SessionConfig) forRepo(repo string) SessionLimits {
+	if c == nil {
+		return SessionLimits{}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	l := c.defaults
+	o, ok := c.repos[repo]
+	if !ok {
+		return l
+	}
+	if o.Timeout > 0 {
+		l.Timeout = o.Timeout
+	}
+	if o.MaxTurns > 0 {
+		l.MaxTurns = o.MaxTurns
+	}
+	if o.Model != "" {
+		l.Model = o.Model
+	}
+	return l
+}
+
+// parseRepoSessionLimits builds per-repo overrides from "repo=value,..." lists
+// of timeouts (Go durations), max turns and models. Invalid entries are logged
+// and skipped.
+func parseRepoSessionLimits(timeouts, maxTurns, models string) map[string]SessionLimits {
+	repos := make(map[string]SessionLimits)
+	for repo, v := range parseRepoMap(timeouts) {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			slog.Warn("ignoring invalid Claude Code timeout", "repo", repo, "value", v)
+			continue
+		}
+		l := repos[repo]
+		l.Timeout = d
+		repos[repo] = l
+	}
+	for repo, v := range parseRepoMap(maxTurns) {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			slog.Warn("ignoring invalid Claude Code max turns", "repo", repo, "value", v)
+			continue
+		}
+		l := repos[repo]
+		l.MaxTurns = n
+		repos[repo] = l
+	}
+	for repo, v := range parseRepoMap(models) {
+		l := repos[repo]
+		l.Model = v
+		repos[repo] = l
+	}
+	if len(repos) == 0 {
+		return nil
+	}
+	return repos
+}
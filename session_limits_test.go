@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionConfig_ForRepo(t *testing.T) {
+	c := NewSessionConfig(
+		SessionLimits{Timeout: 20 * time.Minute, MaxTurns: 50, Model: "sonnet"},
+		parseRepoSessionLimits("big=45m, small=5m,bad=soon", "big=200,small=-1", "big=opus"),
+	)
+
+	tests := []struct {
+		name string
+		repo string
+		want SessionLimits
+	}{
+		{"defaults", "other", SessionLimits{Timeout: 20 * time.Minute, MaxTurns: 50, Model: "sonnet"}},
+		{"full override", "big", SessionLimits{Timeout: 45 * time.Minute, MaxTurns: 200, Model: "opus"}},
+		{"partial override", "small", SessionLimits{Timeout: 5 * time.Minute, MaxTurns: 50, Model: "sonnet"}},
+		{"invalid override ignored", "bad", SessionLimits{Timeout: 20 * time.Minute, MaxTurns: 50, Model: "sonnet"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.forRepo(tt.repo); got != tt.want {
+				t.Errorf("forRepo(%q) = %+v, want %+v", tt.repo, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSessionConfig_NilUsesCLIDefaults(t *testing.T) {
+	var c *SessionConfig
+	l := c.forRepo("any")
+	if l != (SessionLimits{}) {
+		t.Errorf("forRepo on nil config = %+v, want zero", l)
+	}
+	if l.timeout() != defaultSessionTimeout {
+		t.Errorf("timeout() = %s, want %s", l.timeout(), defaultSessionTimeout)
+	}
+}
+
+func TestParseRepoSessionLimits_Empty(t *testing.T) {
+	if got := parseRepoSessionLimits("", "", ""); got != nil {
+		t.Errorf("got %v, want nil", got)
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"regexp"
 	"strings"
@@ -14,31 +14,38 @@ import (
 
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
-	"golang.org/x/time/rate"
 )
 
-// eventDedup tracks recently seen event timestamps to skip duplicate deliveries.
+// eventDedup tracks recently seen event keys to skip duplicate deliveries.
+// Slack retries an event up to three times over about five minutes when it
+// isn't acknowledged in time, so keys are kept for longer than that.
 type eventDedup struct {
 	mu   sync.Mutex
+	ttl  time.Duration
 	seen map[string]time.Time
 }
 
+func newEventDedup(ttl time.Duration) *eventDedup {
+	return &eventDedup{ttl: ttl, seen: make(map[string]time.Time)}
+}
+
 func (d *eventDedup) isDuplicate(key string) bool {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	if _, ok := d.seen[key]; ok {
-		return true
-	}
-	d.seen[key] = time.Now()
+	now := time.Now()
 	for k, t := range d.seen {
-		if time.Since(t) > 5*time.Minute {
+		if now.Sub(t) > d.ttl {
 			delete(d.seen, k)
 		}
 	}
+	if _, ok := d.seen[key]; ok {
+		return true
+	}
+	d.seen[key] = now
 	return false
 }
 
-var dedup = &eventDedup{seen: make(map[string]time.Time)}
+var dedup = newEventDedup(10 * time.Minute)
 
 // maxSlackBodySize is the maximum request body size accepted from Slack webhooks.
 // Slack events are typically small (< 100KB). 1 MB is generous.
@@ -61,9 +68,81 @@ func isApprovalText(text string) bool {
 	return approvalTexts[strings.ToLower(strings.TrimSpace(text))]
 }
 
-func NewSlackHandler(client *slack.Client, signingSecret string, orch *Orchestrator, hub *Hub, botUserID string, approver *Approver, bobURL string, apiToken string, maxPerMinute float64) http.Handler {
-	limiter := rate.NewLimiter(rate.Limit(maxPerMinute/60), int(maxPerMinute/60)+1)
+// cancelTexts is the set of messages that cancel the thread's active job.
+var cancelTexts = map[string]bool{
+	"cancel": true,
+	"stop":   true,
+	"abort":  true,
+}
+
+func isCancelText(text string) bool {
+	return cancelTexts[strings.ToLower(strings.TrimSpace(text))]
+}
+
+// slackDispatcher routes Slack events, interactions and slash commands to the
+// orchestrator. It is shared by the HTTP webhook handlers and Socket Mode, so
+// both transports behave identically once a payload has been authenticated.
+type slackDispatcher struct {
+	platform *SlackPlatform
+	client   *slack.Client
+	orch     *Orchestrator
+	hub      *Hub
+	approver *Approver
+	bobURL   string
+	apiToken string
+	ackText  string // posted to the thread immediately on a new request, before intent parsing
+	limits   *userLimits
+}
+
+func newSlackDispatcher(platform *SlackPlatform, orch *Orchestrator, hub *Hub, approver *Approver, bobURL string, apiToken string, limits *userLimits, ackText string) *slackDispatcher {
+	return &slackDispatcher{
+		platform: platform,
+		client:   platform.Client(),
+		orch:     orch,
+		hub:      hub,
+		approver: approver,
+		bobURL:   bobURL,
+		apiToken: apiToken,
+		ackText:  ackText,
+		limits:   limits,
+	}
+}
+
+// dispatchEvent handles an Events API callback. It returns immediately; work
+// happens in a goroutine so the transport can acknowledge within Slack's 3s limit.
+func (d *slackDispatcher) dispatchEvent(evt slackevents.EventsAPIEvent) {
+	if evt.Type != slackevents.CallbackEvent {
+		return
+	}
+	// Retried deliveries carry the original event_id.
+	if cb, ok := evt.Data.(*slackevents.EventsAPICallbackEvent); ok && cb.EventID != "" {
+		if dedup.isDuplicate("event:" + cb.EventID) {
+			slog.Debug("slack: duplicate event, skipping", "event_id", cb.EventID)
+			return
+		}
+	}
+	switch ev := evt.InnerEvent.Data.(type) {
+	case *slackevents.AppMentionEvent:
+		slog.Info("slack: app_mention", "user", ev.User, "channel", ev.Channel, "text", ev.Text)
 
+		// The same message can also arrive under a new event_id (e.g. edits).
+		if dedup.isDuplicate("msg:" + ev.Channel + ":" + ev.TimeStamp) {
+			slog.Debug("slack: duplicate app_mention, skipping", "ts", ev.TimeStamp)
+			return
+		}
+
+		if !d.limits.allow(d.platform.Name(), ev.User) {
+			slog.Warn("slack: rate limited app_mention", "user", ev.User, "channel", ev.Channel)
+			go replyRateLimited(d.client, ev)
+			return
+		}
+
+		go handleMention(d.platform, d.orch, d.hub, d.approver, d.limits, d.bobURL, d.apiToken, d.ackText, ev)
+	}
+}
+
+// NewSlackHandler handles Slack Events API callbacks.
+func NewSlackHandler(signingSecret string, d *slackDispatcher) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -112,24 +191,11 @@ func NewSlackHandler(client *slack.Client, signingSecret string, orch *Orchestra
 			fmt.Fprint(w, challenge.Challenge)
 
 		case slackevents.CallbackEvent:
-			innerEvent := evt.InnerEvent
-			switch ev := innerEvent.Data.(type) {
-			case *slackevents.AppMentionEvent:
-				log.Printf("app_mention from %s in %s: %s", ev.User, ev.Channel, ev.Text)
-
-				if !limiter.Allow() {
-					log.Printf("rate limited: app_mention from %s in %s", ev.User, ev.Channel)
-					go replyRateLimited(client, ev)
-					return
-				}
-
-				if dedup.isDuplicate(ev.TimeStamp) {
-					log.Printf("duplicate app_mention ts=%s, skipping", ev.TimeStamp)
-					return
-				}
-
-				go handleMention(client, orch, botUserID, hub, approver, bobURL, apiToken, ev)
+			// Acknowledge retries too; dispatchEvent drops already-seen events.
+			if n := r.Header.Get("X-Slack-Retry-Num"); n != "" {
+				slog.Info("slack: retried delivery", "retry", n, "reason", r.Header.Get("X-Slack-Retry-Reason"))
 			}
+			d.dispatchEvent(evt)
 		}
 	})
 }
@@ -141,23 +207,48 @@ func replyRateLimited(client *slack.Client, ev *slackevents.AppMentionEvent) {
 	}
 	_, _, err := client.PostMessage(ev.Channel,
 		slack.MsgOptionText(
-			fmt.Sprintf("<@%s> I'm receiving too many requests right now. Please try again in a moment.", ev.User),
+			fmt.Sprintf("<@%s> You're sending me requests faster than I can take them. Please try again in a moment.", ev.User),
 			false,
 		),
 		slack.MsgOptionTS(threadTS),
 	)
 	if err != nil {
-		log.Printf("failed to post rate-limited message: %v", err)
+		slog.Error("slack: failed to post rate-limited message", "channel", ev.Channel, "err", err)
 	}
 }
 
-func handleMention(client *slack.Client, orch *Orchestrator, botUserID string, hub *Hub, approver *Approver, bobURL string, apiToken string, ev *slackevents.AppMentionEvent) {
+// replyQuotaExceeded tells the user why their mention wasn't acted on.
+func replyQuotaExceeded(client *slack.Client, ev *slackevents.AppMentionEvent, threadTS, msg string) {
+	slog.Warn("slack: quota exceeded", "user", ev.User, "channel", ev.Channel)
+	removeReaction(client, ev.Channel, ev.TimeStamp)
+	if _, _, err := client.PostMessage(ev.Channel,
+		slack.MsgOptionText(fmt.Sprintf("<@%s> %s", ev.User, msg), false),
+		slack.MsgOptionTS(threadTS),
+	); err != nil {
+		slog.Error("slack: failed to post quota message", "channel", ev.Channel, "err", err)
+	}
+}
+
+func handleMention(p *SlackPlatform, orch *Orchestrator, hub *Hub, approver *Approver, limits *userLimits, bobURL string, apiToken string, ackText string, ev *slackevents.AppMentionEvent) {
+	client := p.Client()
+	defer recoverGoroutine("slack mention", func() {
+		threadTS := ev.ThreadTimeStamp
+		if threadTS == "" {
+			threadTS = ev.TimeStamp
+		}
+		removeReaction(client, ev.Channel, ev.TimeStamp)
+		_, _, _ = client.PostMessage(ev.Channel,
+			slack.MsgOptionText(fmt.Sprintf("<@%s> %s", ev.User, panicReply), false),
+			slack.MsgOptionTS(threadTS),
+		)
+	})
+
 	// Acknowledge the mention immediately.
 	if err := client.AddReaction("construction_worker", slack.ItemRef{
 		Channel:   ev.Channel,
 		Timestamp: ev.TimeStamp,
 	}); err != nil {
-		log.Printf("failed to add reaction: %v", err)
+		slog.Warn("slack: failed to add reaction", "channel", ev.Channel, "err", err)
 	}
 
 	// Determine thread timestamp for replies.
@@ -166,15 +257,50 @@ func handleMention(client *slack.Client, orch *Orchestrator, botUserID string, h
 		threadTS = ev.TimeStamp
 	}
 
+	userText := p.StripMention(ev.Text)
+
+	// Cancellation must not wait for the thread lock — the job being cancelled
+	// is usually the one holding it.
+	if isCancelText(userText) {
+		if jobID := hub.ActiveJobForThread(ev.Channel, threadTS); jobID != "" {
+			handleCancel(client, orch, ev, threadTS, jobID)
+			return
+		}
+	}
+
+	if filter, ok := helpQuery(userText); ok {
+		removeReaction(client, ev.Channel, ev.TimeStamp)
+		help := orch.HelpMessage(filter, hub.GetChannelRepo(ev.Channel))
+		if _, _, err := client.PostMessage(ev.Channel,
+			slack.MsgOptionText(markdownToMrkdwn(help), false),
+			slack.MsgOptionTS(threadTS),
+		); err != nil {
+			slog.Error("slack: failed to post help", "channel", ev.Channel, "err", err)
+		}
+		return
+	}
+
+	if v, ok := threadVerbosityQuery(userText); ok {
+		removeReaction(client, ev.Channel, ev.TimeStamp)
+		hub.SetThreadVerbosity(ev.Channel, threadTS, v)
+		if _, _, err := client.PostMessage(ev.Channel,
+			slack.MsgOptionText(threadVerbosityReply(v), false),
+			slack.MsgOptionTS(threadTS),
+		); err != nil {
+			slog.Error("slack: failed to confirm thread verbosity", "channel", ev.Channel, "err", err)
+		}
+		return
+	}
+
 	// Serialize processing per thread to prevent concurrent --resume calls.
 	hub.LockThread(ev.Channel, threadTS)
 	defer hub.UnlockThread(ev.Channel, threadTS)
 
-	userText := stripMention(ev.Text)
-
 	// Build context with Slack thread info.
 	ctx := WithSlackThread(context.Background(), ev.Channel, threadTS)
+	ctx = WithPlatform(ctx, p.Name())
 	ctx = WithMentionTS(ctx, ev.TimeStamp)
+	ctx = WithUser(ctx, ev.User)
 	ctx = WithHub(ctx, hub)
 
 	// Check for active job in this thread.
@@ -186,6 +312,11 @@ func handleMention(client *slack.Client, orch *Orchestrator, botUserID string, h
 	if activeJobID != "" {
 		state, hasState := hub.GetJobState(activeJobID)
 
+		if hasState && state.Phase == PhaseAwaitingPush && isPushText(userText) {
+			removeReaction(client, ev.Channel, ev.TimeStamp)
+			approver.Push(ctx, activeJobID, ev.Channel, threadTS, fmt.Sprintf("<@%s>", ev.User))
+			return
+		}
 		if hasState && state.Phase == PhaseAwaitingApproval && isApprovalText(userText) {
 			// Text-based approval — delegate to approver.
 			removeReaction(client, ev.Channel, ev.TimeStamp)
@@ -206,7 +337,7 @@ func handleMention(client *slack.Client, orch *Orchestrator, botUserID string, h
 					slack.MsgOptionBlocks(blocks...),
 				)
 				if updateErr != nil {
-					log.Printf("failed to update old plan message: %v", updateErr)
+					slog.WarnContext(ctx, "slack: failed to update old plan message", "job_id", activeJobID, "err", updateErr)
 				}
 				state.mu.Lock()
 				state.PlanMsgTS = "" // prevent post-session double-update
@@ -214,6 +345,11 @@ func handleMention(client *slack.Client, orch *Orchestrator, botUserID string, h
 			}
 		}
 
+		if msg := limits.quotaExceeded(p.Name(), ev.User, false); msg != "" {
+			replyQuotaExceeded(client, ev, threadTS, msg)
+			return
+		}
+
 		// Reply to active job (question answer or plan feedback).
 		// Post "Working on it..." status message.
 		msg := "Working on it..."
@@ -226,52 +362,111 @@ func handleMention(client *slack.Client, orch *Orchestrator, botUserID string, h
 		)
 
 		result, err = orch.HandleReply(ctx, activeJobID, userText)
+	} else if lastJobID := hub.LastThreadJob(ev.Channel, threadTS); lastJobID != "" && isRetryText(userText) {
+		if msg := limits.quotaExceeded(p.Name(), ev.User, true); msg != "" {
+			replyQuotaExceeded(client, ev, threadTS, msg)
+			return
+		}
+		result, err = approver.RerunThreadJob(ctx, lastJobID, fmt.Sprintf("<@%s>", ev.User), func(jobID string) {
+			limits.startJob(p.Name(), ev.User, jobID)
+			msg := "Retrying..."
+			if bobURL != "" {
+				msg = fmt.Sprintf("Retrying... Follow my progress here: <%s/jobs/%s?token=%s>", bobURL, jobID, apiToken)
+			}
+			_, _, _ = client.PostMessage(ev.Channel,
+				slack.MsgOptionText(msg, false),
+				slack.MsgOptionTS(threadTS),
+			)
+		})
 	} else {
-		// New request — parse intent and start planning.
+		if msg := limits.quotaExceeded(p.Name(), ev.User, true); msg != "" {
+			replyQuotaExceeded(client, ev, threadTS, msg)
+			return
+		}
+
+		// New request — post the quick textual acknowledgment before the slow
+		// intent parsing step. It is superseded by the first real message.
+		ackTS := postAck(client, ev.Channel, threadTS, ackText)
+
+		// Parse intent and start planning.
 		// Need full thread context for intent parsing.
 		var messages []Message
 		if ev.ThreadTimeStamp != "" {
-			replies, _, _, err := client.GetConversationReplies(&slack.GetConversationRepliesParameters{
-				ChannelID: ev.Channel,
-				Timestamp: ev.ThreadTimeStamp,
-			})
+			history, err := p.ThreadMessages(ctx, ev.Channel, ev.ThreadTimeStamp)
 			if err != nil {
-				log.Printf("failed to get thread replies: %v", err)
+				slog.WarnContext(ctx, "slack: failed to get thread replies", "err", err)
 				messages = []Message{{Role: RoleUser, Content: userText}}
 			} else {
-				messages = threadToMessages(replies, botUserID)
+				messages = history
 			}
 		} else {
 			messages = []Message{{Role: RoleUser, Content: userText}}
 		}
 
+		if name, err := p.UserName(ctx, ev.User); err == nil {
+			ctx = WithUserName(ctx, name)
+		} else {
+			slog.WarnContext(ctx, "slack: failed to look up user name", "user", ev.User, "err", err)
+		}
+		if orch.creditRequester {
+			if id, err := p.UserIdentity(ctx, ev.User); err == nil {
+				ctx = WithCoAuthor(ctx, id)
+			} else {
+				slog.WarnContext(ctx, "slack: failed to look up user for co-author credit", "user", ev.User, "err", err)
+			}
+		}
+
 		defaultRepo := hub.GetChannelRepo(ev.Channel)
 		result, err = orch.HandleNewRequest(ctx, messages, defaultRepo, func(jobID string) {
+			limits.startJob(p.Name(), ev.User, jobID)
 			msg := "Working on a plan..."
 			if bobURL != "" {
 				msg = fmt.Sprintf("Working on a plan... Follow my progress here: <%s/jobs/%s?token=%s>", bobURL, jobID, apiToken)
 			}
+			if ackTS != "" {
+				// Replace the acknowledgment in place.
+				if _, _, _, err := client.UpdateMessage(ev.Channel, ackTS, slack.MsgOptionText(msg, false)); err == nil {
+					ackTS = ""
+					return
+				}
+			}
 			_, _, _ = client.PostMessage(ev.Channel,
 				slack.MsgOptionText(msg, false),
 				slack.MsgOptionTS(threadTS),
 			)
 		})
+
+		// No job was started (clarification or early rejection) — the ack is stale.
+		if ackTS != "" {
+			if _, _, err := client.DeleteMessage(ev.Channel, ackTS); err != nil {
+				slog.WarnContext(ctx, "slack: failed to delete ack message", "err", err)
+			}
+		}
 	}
 
 	removeReaction(client, ev.Channel, ev.TimeStamp)
+	postResult(ctx, client, hub, ev.Channel, threadTS, ev.User, result, err)
+}
 
+// postResult posts an orchestrator result to the request's thread: the
+// error, the plan (remembering its message for approval), a question, or
+// the text reply, addressed to user.
+func postResult(ctx context.Context, client *slack.Client, hub *Hub, channel, threadTS, user string, result OrchestratorResult, err error) {
 	if err != nil {
-		log.Printf("orchestrator error: %v", err)
-		text := fmt.Sprintf("<@%s> Sorry, I hit an error trying to respond. Please try again.", ev.User)
-		_, _, err = client.PostMessage(ev.Channel,
+		slog.ErrorContext(ctx, "orchestrator error", "job_id", result.JobID, "err", err)
+		text := fmt.Sprintf("<@%s> %s", user, errorReply(err))
+		_, _, err = client.PostMessage(channel,
 			slack.MsgOptionText(text, false),
 			slack.MsgOptionTS(threadTS),
 		)
 		if err != nil {
-			log.Printf("failed to post message: %v", err)
+			slog.ErrorContext(ctx, "slack: failed to post message", "job_id", result.JobID, "err", err)
 		}
 		return
 	}
+	if result.Reported {
+		return
+	}
 
 	// Plan with Block Kit blocks.
 	if len(result.PlanBlocks) > 0 {
@@ -283,78 +478,118 @@ func handleMention(client *slack.Client, orch *Orchestrator, botUserID string, h
 			state.mu.Unlock()
 			if planMsgTS != "" {
 				updatedBlocks := formatApprovedPlanBlocks(planContent, "superseded by updated plan")
-				_, _, _, updateErr := client.UpdateMessage(ev.Channel, planMsgTS,
+				_, _, _, updateErr := client.UpdateMessage(channel, planMsgTS,
 					slack.MsgOptionText(result.PlanText, false),
 					slack.MsgOptionBlocks(updatedBlocks...),
 				)
 				if updateErr != nil {
-					log.Printf("failed to update old plan message: %v", updateErr)
+					slog.WarnContext(ctx, "slack: failed to update old plan message", "job_id", result.JobID, "err", updateErr)
 				}
 			}
 		}
 
-		planText := fmt.Sprintf("<@%s> %s", ev.User, result.PlanText)
-		_, msgTS, postErr := client.PostMessage(ev.Channel,
+		planText := fmt.Sprintf("<@%s> %s", user, result.PlanText)
+		_, msgTS, postErr := client.PostMessage(channel,
 			slack.MsgOptionText(planText, false),
 			slack.MsgOptionBlocks(result.PlanBlocks...),
 			slack.MsgOptionTS(threadTS),
 		)
 		if postErr != nil {
-			log.Printf("failed to post plan message: %v", postErr)
+			slog.ErrorContext(ctx, "slack: failed to post plan message", "job_id", result.JobID, "err", postErr)
 		} else if state, ok := hub.GetJobState(result.JobID); ok {
 			state.mu.Lock()
 			state.PlanMsgTS = msgTS
+			planContent := state.PlanContent
 			state.mu.Unlock()
+			_, snippets := extractLongCodeBlocks(planContent)
+			postCodeSnippets(ctx, client, channel, threadTS, snippets)
 		}
 		return
 	}
 
 	// Question with Block Kit blocks.
 	if len(result.QuestionBlocks) > 0 {
-		questionText := fmt.Sprintf("<@%s> %s", ev.User, result.Text)
-		_, _, postErr := client.PostMessage(ev.Channel,
+		questionText := fmt.Sprintf("<@%s> %s", user, result.Text)
+		_, _, postErr := client.PostMessage(channel,
 			slack.MsgOptionText(questionText, false),
 			slack.MsgOptionBlocks(result.QuestionBlocks...),
 			slack.MsgOptionTS(threadTS),
 		)
 		if postErr != nil {
-			log.Printf("failed to post question message: %v", postErr)
+			slog.ErrorContext(ctx, "slack: failed to post question message", "job_id", result.JobID, "err", postErr)
+			return
 		}
+		_, snippets := extractLongCodeBlocks(result.Text)
+		postCodeSnippets(ctx, client, channel, threadTS, snippets)
 		return
 	}
 
 	// Standard text reply.
 	var text string
+	requester := user
+	if r := hub.JobRequester(result.JobID); r != "" {
+		requester = r // the job's requester, not whoever in the thread spoke last
+	}
 	if result.IsJob && result.PRURL != "" {
-		text = fmt.Sprintf("<@%s> Done! %s", ev.User, result.PRURL)
+		text = fmt.Sprintf("<@%s> Done! %s", requester, result.PRURL)
 	} else if result.IsJob && result.Text != "" {
-		text = fmt.Sprintf("<@%s> %s", ev.User, result.Text)
+		text = fmt.Sprintf("<@%s> %s", user, result.Text)
 	} else if result.IsJob {
-		text = fmt.Sprintf("<@%s> Done!", ev.User)
+		text = fmt.Sprintf("<@%s> Done!", requester)
 	} else {
-		text = fmt.Sprintf("<@%s> %s", ev.User, result.Text)
+		text = fmt.Sprintf("<@%s> %s", user, result.Text)
 	}
 
-	_, _, err = client.PostMessage(ev.Channel,
+	if err := postSlackText(ctx, client, channel, threadTS, text); err != nil {
+		slog.ErrorContext(ctx, "slack: failed to post message", "job_id", result.JobID, "err", err)
+	}
+}
+
+// handleCancel cancels the thread's active job in response to a "cancel"/"stop" mention.
+func handleCancel(client *slack.Client, orch *Orchestrator, ev *slackevents.AppMentionEvent, threadTS, jobID string) {
+	ctx := WithSlackThread(context.Background(), ev.Channel, threadTS)
+	text := fmt.Sprintf("<@%s> Cancelled.", ev.User)
+	if err := orch.CancelJob(ctx, jobID, fmt.Sprintf("<@%s>", ev.User)); err != nil {
+		slog.InfoContext(ctx, "slack: cancel failed", "job_id", jobID, "err", err)
+		text = fmt.Sprintf("<@%s> There's nothing to cancel — the job has already finished.", ev.User)
+	}
+	removeReaction(client, ev.Channel, ev.TimeStamp)
+	if _, _, err := client.PostMessage(ev.Channel,
 		slack.MsgOptionText(text, false),
 		slack.MsgOptionTS(threadTS),
+	); err != nil {
+		slog.ErrorContext(ctx, "slack: failed to post cancel message", "job_id", jobID, "err", err)
+	}
+}
+
+// postAck posts the first-responder acknowledgment and returns its timestamp,
+// or empty string if acknowledgments are disabled or posting failed.
+func postAck(client *slack.Client, channel, threadTS, ackText string) string {
+	if ackText == "" {
+		return ""
+	}
+	_, ts, err := client.PostMessage(channel,
+		slack.MsgOptionText(ackText, false),
+		slack.MsgOptionTS(threadTS),
 	)
 	if err != nil {
-		log.Printf("failed to post message: %v", err)
+		slog.Warn("slack: failed to post ack message", "channel", channel, "err", err)
+		return ""
 	}
+	return ts
 }
 
 func removeReaction(client *slack.Client, channel, timestamp string) {
 	ref := slack.ItemRef{Channel: channel, Timestamp: timestamp}
 	reactions, err := client.GetReactions(ref, slack.NewGetReactionsParameters())
 	if err != nil {
-		log.Printf("failed to get reactions for removal: %v", err)
+		slog.Warn("slack: failed to get reactions for removal", "channel", channel, "err", err)
 		return
 	}
 	for _, r := range reactions {
 		if strings.Contains(r.Name, "construction") {
 			if err := client.RemoveReaction(r.Name, ref); err != nil {
-				log.Printf("failed to remove reaction %q: %v", r.Name, err)
+				slog.Warn("slack: failed to remove reaction", "channel", channel, "reaction", r.Name, "err", err)
 			}
 			return
 		}
@@ -381,8 +616,28 @@ func stripMention(text string) string {
 	return strings.TrimSpace(mentionRe.ReplaceAllString(text, ""))
 }
 
-// NewSlashCommandHandler handles the /bob-repo slash command for setting channel default repos.
-func NewSlashCommandHandler(signingSecret string, hub *Hub) http.Handler {
+// slashCommandResponse handles the /bob-repo slash command for setting channel
+// default repos and returns the ephemeral response text.
+func (d *slackDispatcher) slashCommandResponse(channelID, text string) string {
+	text = strings.TrimSpace(text)
+	switch {
+	case text == "":
+		repo := d.hub.GetChannelRepo(channelID)
+		if repo == "" {
+			return "No default repo set for this channel."
+		}
+		return fmt.Sprintf("Default repo for this channel: *%s*", repo)
+	case text == "clear":
+		d.hub.ClearChannelRepo(channelID)
+		return "Cleared default repo for this channel."
+	default:
+		d.hub.SetChannelRepo(channelID, text)
+		return fmt.Sprintf("Default repo for this channel set to *%s*.", text)
+	}
+}
+
+// NewSlashCommandHandler handles the /bob-repo slash command over HTTP.
+func NewSlashCommandHandler(signingSecret string, d *slackDispatcher) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -421,25 +676,7 @@ func NewSlashCommandHandler(signingSecret string, hub *Hub) http.Handler {
 			return
 		}
 
-		channelID := r.FormValue("channel_id")
-		text := strings.TrimSpace(r.FormValue("text"))
-
-		var respText string
-		switch {
-		case text == "":
-			repo := hub.GetChannelRepo(channelID)
-			if repo == "" {
-				respText = "No default repo set for this channel."
-			} else {
-				respText = fmt.Sprintf("Default repo for this channel: *%s*", repo)
-			}
-		case text == "clear":
-			hub.ClearChannelRepo(channelID)
-			respText = "Cleared default repo for this channel."
-		default:
-			hub.SetChannelRepo(channelID, text)
-			respText = fmt.Sprintf("Default repo for this channel set to *%s*.", text)
-		}
+		respText := d.slashCommandResponse(r.FormValue("channel_id"), r.FormValue("text"))
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
@@ -449,8 +686,9 @@ func NewSlashCommandHandler(signingSecret string, hub *Hub) http.Handler {
 	})
 }
 
-// NewSlackInteractionHandler handles Slack interactive component callbacks (button clicks).
-func NewSlackInteractionHandler(client *slack.Client, signingSecret string, approver *Approver) http.Handler {
+// NewSlackInteractionHandler handles Slack interactive component callbacks
+// (button clicks, the new job shortcut and modal) and select menu options loads.
+func NewSlackInteractionHandler(signingSecret string, d *slackDispatcher) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -501,31 +739,70 @@ func NewSlackInteractionHandler(client *slack.Client, signingSecret string, appr
 			return
 		}
 
-		if callback.Type != slack.InteractionTypeBlockActions {
-			w.WriteHeader(http.StatusOK)
+		// Respond immediately — Slack requires <3s response.
+		if resp := d.dispatchInteraction(callback); resp != nil {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(resp)
 			return
 		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
 
-		for _, action := range callback.ActionCallback.BlockActions {
-			if action.ActionID != "approve_plan" {
-				continue
-			}
-
-			jobID := action.Value
-			channel := callback.Channel.ID
-			threadTS := callback.Message.ThreadTimestamp
-			if threadTS == "" {
-				threadTS = callback.Message.Timestamp
-			}
-			approvedBy := fmt.Sprintf("<@%s>", callback.User.ID)
+// dispatchInteraction handles an interactive component callback. Slow work
+// happens in goroutines; the returned payload, if non-nil, is the response
+// Slack expects in the acknowledgement (select options, modal errors).
+func (d *slackDispatcher) dispatchInteraction(callback slack.InteractionCallback) any {
+	switch callback.Type {
+	case slack.InteractionTypeShortcut:
+		if callback.CallbackID == newJobShortcut {
+			go d.openNewJobModal(callback.TriggerID)
+		}
+		return nil
+	case slack.InteractionTypeBlockSuggestion:
+		if callback.ActionID != modalRepoAction {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		return d.repoOptions(ctx, callback.Value)
+	case slack.InteractionTypeViewSubmission:
+		if callback.View.CallbackID != newJobModal {
+			return nil
+		}
+		req, errs := parseNewJobModal(callback)
+		if errs != nil {
+			return slack.NewErrorsViewSubmissionResponse(errs)
+		}
+		if !d.limits.allow(d.platform.Name(), req.User) {
+			return slack.NewErrorsViewSubmissionResponse(map[string]string{
+				modalTaskBlock: "You're sending me requests faster than I can take them. Please try again in a moment.",
+			})
+		}
+		slog.Info("slack: new job modal", "user", req.User, "channel", req.Channel, "repo", req.Repo)
+		go d.startModalJob(req)
+		return nil
+	case slack.InteractionTypeBlockActions:
+		// Plan approval, below.
+	default:
+		return nil
+	}
 
-			// Return 200 immediately — Slack requires <3s response.
-			w.WriteHeader(http.StatusOK)
+	for _, action := range callback.ActionCallback.BlockActions {
+		if action.ActionID != "approve_plan" {
+			continue
+		}
 
-			go approver.Approve(context.Background(), jobID, channel, threadTS, approvedBy)
-			return
+		jobID := action.Value
+		channel := callback.Channel.ID
+		threadTS := callback.Message.ThreadTimestamp
+		if threadTS == "" {
+			threadTS = callback.Message.Timestamp
 		}
+		approvedBy := fmt.Sprintf("<@%s>", callback.User.ID)
 
-		w.WriteHeader(http.StatusOK)
-	})
+		go d.approver.Approve(context.Background(), jobID, channel, threadTS, approvedBy)
+		return nil
+	}
+	return nil
 }
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// The "New Bob job" global shortcut opens newJobModal; its submission starts
+// a job in a new thread of the chosen channel.
+const (
+	newJobShortcut = "bob_new_job"
+	newJobModal    = "bob_new_job_modal"
+)
+
+// Block and action IDs of newJobModal's inputs.
+const (
+	modalRepoBlock    = "repo"
+	modalRepoAction   = "repo_select"
+	modalChannelBlock = "channel"
+	modalChannelAct   = "channel_select"
+	modalTaskBlock    = "task"
+	modalTaskAction   = "task_input"
+	modalBaseBlock    = "base_branch"
+	modalBaseAction   = "base_branch_input"
+	modalPlanBlock    = "plan_first"
+	modalPlanAction   = "plan_first_check"
+)
+
+// maxRepoOptions is the most options Slack accepts for an external select.
+const maxRepoOptions = 100
+
+// repoLister is implemented by VCS providers that can list their repositories.
+type repoLister interface {
+	ListRepos(ctx context.Context) ([]repo, error)
+}
+
+// newJobModalView builds the modal the shortcut opens.
+func newJobModalView() slack.ModalViewRequest {
+	minQuery := 0
+	repoSelect := slack.NewOptionsSelectBlockElement(slack.OptTypeExternal,
+		slack.NewTextBlockObject(slack.PlainTextType, "Pick a repository", false, false), modalRepoAction)
+	repoSelect.MinQueryLength = &minQuery
+
+	channelSelect := slack.NewOptionsSelectBlockElement(slack.OptTypeConversations,
+		slack.NewTextBlockObject(slack.PlainTextType, "Where I'll post updates", false, false), modalChannelAct)
+	channelSelect.DefaultToCurrentConversation = true
+	channelSelect.Filter = &slack.SelectBlockElementFilter{Include: []string{"public", "private"}, ExcludeBotUsers: true}
+
+	task := slack.NewPlainTextInputBlockElement(
+		slack.NewTextBlockObject(slack.PlainTextType, "Add rate limiting to the login endpoint", false, false), modalTaskAction).
+		WithMultiline(true)
+	base := slack.NewPlainTextInputBlockElement(
+		slack.NewTextBlockObject(slack.PlainTextType, "The repo's default branch", false, false), modalBaseAction)
+
+	planOpt := slack.NewOptionBlockObject("plan_first",
+		slack.NewTextBlockObject(slack.PlainTextType, "Plan first", false, false),
+		slack.NewTextBlockObject(slack.PlainTextType, "Wait for my plan to be approved before implementing", false, false))
+	planFirst := slack.NewCheckboxGroupsBlockElement(modalPlanAction, planOpt)
+	planFirst.InitialOptions = []*slack.OptionBlockObject{planOpt}
+
+	label := func(text string) *slack.TextBlockObject {
+		return slack.NewTextBlockObject(slack.PlainTextType, text, false, false)
+	}
+	return slack.ModalViewRequest{
+		Type:       slack.VTModal,
+		CallbackID: newJobModal,
+		Title:      label("New Bob job"),
+		Submit:     label("Start"),
+		Close:      label("Cancel"),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewInputBlock(modalRepoBlock, label("Repository"), nil, repoSelect),
+			slack.NewInputBlock(modalChannelBlock, label("Channel"), nil, channelSelect),
+			slack.NewInputBlock(modalTaskBlock, label("Task"), nil, task),
+			slack.NewInputBlock(modalBaseBlock, label("Base branch"), nil, base).WithOptional(true),
+			slack.NewInputBlock(modalPlanBlock, label("Options"), nil, planFirst).WithOptional(true),
+		}},
+	}
+}
+
+// newJobRequest is a submitted newJobModal.
+type newJobRequest struct {
+	User       string
+	Channel    string
+	Repo       string
+	Task       string
+	BaseBranch string
+	PlanFirst  bool
+}
+
+// parseNewJobModal reads a newJobModal submission. It returns the errors to
+// show next to the offending inputs, if any.
+func parseNewJobModal(callback slack.InteractionCallback) (newJobRequest, map[string]string) {
+	values := callback.View.State.Values
+	req := newJobRequest{
+		User:       callback.User.ID,
+		Channel:    values[modalChannelBlock][modalChannelAct].SelectedConversation,
+		Repo:       values[modalRepoBlock][modalRepoAction].SelectedOption.Value,
+		Task:       strings.TrimSpace(values[modalTaskBlock][modalTaskAction].Value),
+		BaseBranch: strings.TrimSpace(values[modalBaseBlock][modalBaseAction].Value),
+	}
+	for _, opt := range values[modalPlanBlock][modalPlanAction].SelectedOptions {
+		if opt.Value == "plan_first" {
+			req.PlanFirst = true
+		}
+	}
+
+	errs := map[string]string{}
+	if req.Repo == "" {
+		errs[modalRepoBlock] = "Pick a repository."
+	}
+	if req.Channel == "" {
+		errs[modalChannelBlock] = "Pick a channel."
+	}
+	if req.Task == "" {
+		errs[modalTaskBlock] = "Describe the task."
+	}
+	if req.BaseBranch != "" && !isValidBranchName(req.BaseBranch) {
+		errs[modalBaseBlock] = "That isn't a valid branch name."
+	}
+	if len(errs) > 0 {
+		return req, errs
+	}
+	return req, nil
+}
+
+// repoChoices returns up to maxRepoOptions repos containing query, sorted:
+// the allowlist if there is one, otherwise every repo the providers list.
+// A query matching nothing is offered as is, so unlisted repos can be typed.
+func (o *Orchestrator) repoChoices(ctx context.Context, query string) []string {
+	query = strings.TrimSpace(query)
+	seen := map[string]bool{}
+	if len(o.allowedRepos) > 0 {
+		for r := range o.allowedRepos {
+			seen[r] = true
+		}
+	} else {
+		for _, p := range o.providers {
+			lister, ok := p.(repoLister)
+			if !ok {
+				continue
+			}
+			repos, err := lister.ListRepos(ctx)
+			if err != nil {
+				slog.WarnContext(ctx, "slack: failed to list repos", "provider", p.Name(), "err", err)
+				continue
+			}
+			for _, r := range repos {
+				seen[r.Name] = true
+			}
+		}
+	}
+
+	var matches []string
+	for r := range seen {
+		if strings.Contains(strings.ToLower(r), strings.ToLower(query)) {
+			matches = append(matches, r)
+		}
+	}
+	sort.Strings(matches)
+	if len(matches) == 0 && query != "" && len(o.allowedRepos) == 0 {
+		matches = []string{query}
+	}
+	if len(matches) > maxRepoOptions {
+		matches = matches[:maxRepoOptions]
+	}
+	return matches
+}
+
+// repoOptions answers the modal's repo select as the user types.
+func (d *slackDispatcher) repoOptions(ctx context.Context, query string) slack.OptionsResponse {
+	var opts []*slack.OptionBlockObject
+	for _, r := range d.orch.repoChoices(ctx, query) {
+		opts = append(opts, slack.NewOptionBlockObject(r, slack.NewTextBlockObject(slack.PlainTextType, truncate(r, 75), false, false), nil))
+	}
+	return slack.OptionsResponse{Options: opts}
+}
+
+// openNewJobModal opens newJobModal for a shortcut invocation.
+func (d *slackDispatcher) openNewJobModal(triggerID string) {
+	if _, err := d.client.OpenView(triggerID, newJobModalView()); err != nil {
+		slog.Error("slack: failed to open new job modal", "err", err)
+	}
+}
+
+// startModalJob posts the request to its channel and runs the job in that
+// message's thread, as a mention there would. With PlanFirst unchecked, the
+// plan is approved on the user's behalf as soon as it's ready, unless it
+// comes with suspected prompt injection.
+func (d *slackDispatcher) startModalJob(req newJobRequest) {
+	client := d.client
+	base := ""
+	if req.BaseBranch != "" {
+		base = fmt.Sprintf(" (off `%s`)", req.BaseBranch)
+	}
+	text := fmt.Sprintf("<@%s> asked me to work on *%s*%s:\n>%s", req.User, req.Repo, base, strings.ReplaceAll(req.Task, "\n", "\n>"))
+	_, threadTS, err := client.PostMessage(req.Channel, slack.MsgOptionText(text, false))
+	if err != nil {
+		slog.Error("slack: failed to post modal request", "channel", req.Channel, "err", err)
+		// Without a thread to work in, tell the user directly.
+		_, _, _ = client.PostMessage(req.User, slack.MsgOptionText(
+			fmt.Sprintf("I couldn't post in <#%s> (%s), so I didn't start your job. Is Bob a member of the channel?", req.Channel, err), false))
+		return
+	}
+
+	defer recoverGoroutine("slack modal job", func() {
+		_, _, _ = client.PostMessage(req.Channel,
+			slack.MsgOptionText(fmt.Sprintf("<@%s> %s", req.User, panicReply), false),
+			slack.MsgOptionTS(threadTS),
+		)
+	})
+
+	if msg := d.limits.quotaExceeded(d.platform.Name(), req.User, true); msg != "" {
+		slog.Warn("slack: quota exceeded", "user", req.User, "channel", req.Channel)
+		_, _, _ = client.PostMessage(req.Channel,
+			slack.MsgOptionText(fmt.Sprintf("<@%s> %s", req.User, msg), false),
+			slack.MsgOptionTS(threadTS),
+		)
+		return
+	}
+
+	d.hub.LockThread(req.Channel, threadTS)
+	defer d.hub.UnlockThread(req.Channel, threadTS)
+
+	ctx := WithSlackThread(context.Background(), req.Channel, threadTS)
+	ctx = WithPlatform(ctx, d.platform.Name())
+	ctx = WithUser(ctx, req.User)
+	ctx = WithHub(ctx, d.hub)
+	if name, err := d.platform.UserName(ctx, req.User); err == nil {
+		ctx = WithUserName(ctx, name)
+	} else {
+		slog.WarnContext(ctx, "slack: failed to look up user name", "user", req.User, "err", err)
+	}
+	if d.orch.creditRequester {
+		if id, err := d.platform.UserIdentity(ctx, req.User); err == nil {
+			ctx = WithCoAuthor(ctx, id)
+		} else {
+			slog.WarnContext(ctx, "slack: failed to look up user for co-author credit", "user", req.User, "err", err)
+		}
+	}
+
+	result, err := d.orch.HandleDirectRequest(ctx, IntentResult{Repo: req.Repo, Task: req.Task, BaseBranch: req.BaseBranch}, "", func(jobID string) {
+		d.limits.startJob(d.platform.Name(), req.User, jobID)
+		msg := "Working on a plan..."
+		if d.bobURL != "" {
+			msg = fmt.Sprintf("Working on a plan... Follow my progress here: <%s/jobs/%s?token=%s>", d.bobURL, jobID, d.apiToken)
+		}
+		_, _, _ = client.PostMessage(req.Channel, slack.MsgOptionText(msg, false), slack.MsgOptionTS(threadTS))
+	})
+	postResult(ctx, client, d.hub, req.Channel, threadTS, req.User, result, err)
+	if err == nil && !req.PlanFirst && len(result.PlanBlocks) > 0 && len(result.Injections) == 0 {
+		d.approver.Approve(ctx, result.JobID, req.Channel, threadTS, fmt.Sprintf("<@%s>", req.User))
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+func TestParseNewJobModal(t *testing.T) {
+	callback := slack.InteractionCallback{User: slack.User{ID: "U1"}}
+	callback.View.State = &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+		modalRepoBlock:    {modalRepoAction: {SelectedOption: slack.OptionBlockObject{Value: "web"}}},
+		modalChannelBlock: {modalChannelAct: {SelectedConversation: "C1"}},
+		modalTaskBlock:    {modalTaskAction: {Value: "  Fix the login page\n"}},
+		modalBaseBlock:    {modalBaseAction: {Value: "develop"}},
+		modalPlanBlock:    {modalPlanAction: {SelectedOptions: []slack.OptionBlockObject{{Value: "plan_first"}}}},
+	}}
+	req, errs := parseNewJobModal(callback)
+	if errs != nil {
+		t.Fatalf("errs = %v", errs)
+	}
+	want := newJobRequest{User: "U1", Channel: "C1", Repo: "web", Task: "Fix the login page", BaseBranch: "develop", PlanFirst: true}
+	if req != want {
+		t.Errorf("req = %+v, want %+v", req, want)
+	}
+
+	callback.View.State.Values[modalTaskBlock][modalTaskAction] = slack.BlockAction{Value: " "}
+	callback.View.State.Values[modalBaseBlock][modalBaseAction] = slack.BlockAction{Value: "bad..branch"}
+	delete(callback.View.State.Values, modalPlanBlock)
+	req, errs = parseNewJobModal(callback)
+	if len(errs) != 2 || errs[modalTaskBlock] == "" || errs[modalBaseBlock] == "" {
+		t.Errorf("errs = %v", errs)
+	}
+	if req.PlanFirst {
+		t.Error("plan first set with the box unchecked")
+	}
+}
+
+func TestRepoChoices(t *testing.T) {
+	ctx := context.Background()
+	o := &Orchestrator{allowedRepos: map[string]bool{"web": true, "api": true, "web-admin": true}}
+	if got := o.repoChoices(ctx, "WEB"); !slices.Equal(got, []string{"web", "web-admin"}) {
+		t.Errorf("allowlist = %v", got)
+	}
+	if got := o.repoChoices(ctx, "billing"); len(got) != 0 {
+		t.Errorf("allowlist miss = %v", got)
+	}
+
+	gh := &GitHubProvider{owner: "acme"}
+	gh.repoList.repos, gh.repoList.fetched = []repo{{Name: "web"}, {Name: "api"}}, time.Now()
+	o = &Orchestrator{providers: []VCSProvider{gh}}
+	if got := o.repoChoices(ctx, ""); !slices.Equal(got, []string{"api", "web"}) {
+		t.Errorf("listed = %v", got)
+	}
+	if got := o.repoChoices(ctx, "billing"); !slices.Equal(got, []string{"billing"}) {
+		t.Errorf("typed = %v", got)
+	}
+}
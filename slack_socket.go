@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/slack-go/slack/socketmode"
+)
+
+// RunSlackSocketMode receives Slack events, interactions and slash commands
+// over a Socket Mode websocket instead of the /webhooks/slack endpoints, so
+// Bob can run without a public URL. The client must be created with the
+// app-level token (slack.OptionAppLevelToken). Blocks until ctx is done.
+func RunSlackSocketMode(ctx context.Context, client *slack.Client, d *slackDispatcher) error {
+	sm := socketmode.New(client)
+
+	go func() {
+		for evt := range sm.Events {
+			switch evt.Type {
+			case socketmode.EventTypeConnecting:
+				slog.Info("slack: socket mode connecting")
+			case socketmode.EventTypeConnected:
+				slog.Info("slack: socket mode connected")
+			case socketmode.EventTypeConnectionError:
+				slog.Error("slack: socket mode connection error", "err", evt.Data)
+
+			case socketmode.EventTypeEventsAPI:
+				eventsAPIEvent, ok := evt.Data.(slackevents.EventsAPIEvent)
+				if !ok {
+					continue
+				}
+				sm.Ack(*evt.Request)
+				d.dispatchEvent(eventsAPIEvent)
+
+			case socketmode.EventTypeInteractive:
+				callback, ok := evt.Data.(slack.InteractionCallback)
+				if !ok {
+					continue
+				}
+				if resp := d.dispatchInteraction(callback); resp != nil {
+					sm.Ack(*evt.Request, resp)
+				} else {
+					sm.Ack(*evt.Request)
+				}
+
+			case socketmode.EventTypeSlashCommand:
+				cmd, ok := evt.Data.(slack.SlashCommand)
+				if !ok {
+					continue
+				}
+				sm.Ack(*evt.Request, map[string]string{
+					"response_type": "ephemeral",
+					"text":          d.slashCommandResponse(cmd.ChannelID, cmd.Text),
+				})
+			}
+		}
+	}()
+
+	return sm.RunContext(ctx)
+}
@@ -27,6 +27,19 @@ func TestIsApprovalText(t *testing.T) {
 	}
 }
 
+func TestIsCancelText(t *testing.T) {
+	for _, s := range []string{"cancel", "Stop", " abort "} {
+		if !isCancelText(s) {
+			t.Errorf("expected %q to be cancel text", s)
+		}
+	}
+	for _, s := range []string{"", "go", "stop the build step", "cancel?"} {
+		if isCancelText(s) {
+			t.Errorf("expected %q to NOT be cancel text", s)
+		}
+	}
+}
+
 func TestStripMention(t *testing.T) {
 	tests := []struct {
 		name string
@@ -97,7 +110,7 @@ func TestThreadToMessages(t *testing.T) {
 }
 
 func TestEventDedupIsDuplicate(t *testing.T) {
-	d := &eventDedup{seen: make(map[string]time.Time)}
+	d := newEventDedup(time.Minute)
 
 	t.Run("first call not duplicate", func(t *testing.T) {
 		if d.isDuplicate("ts1") {
@@ -116,4 +129,34 @@ func TestEventDedupIsDuplicate(t *testing.T) {
 			t.Error("different key should not be duplicate")
 		}
 	})
+
+	t.Run("key expires after ttl", func(t *testing.T) {
+		d.mu.Lock()
+		d.seen["ts1"] = time.Now().Add(-2 * time.Minute)
+		d.mu.Unlock()
+		if d.isDuplicate("ts1") {
+			t.Error("expired key should not be duplicate")
+		}
+	})
+}
+
+func TestSlashCommandResponse(t *testing.T) {
+	drainHub(t)
+	d := &slackDispatcher{hub: NewHub(t.TempDir())}
+
+	steps := []struct {
+		text string
+		want string
+	}{
+		{"", "No default repo set for this channel."},
+		{" myrepo ", "Default repo for this channel set to *myrepo*."},
+		{"", "Default repo for this channel: *myrepo*"},
+		{"clear", "Cleared default repo for this channel."},
+		{"", "No default repo set for this channel."},
+	}
+	for _, s := range steps {
+		if got := d.slashCommandResponse("C1", s.text); got != s.want {
+			t.Errorf("slashCommandResponse(%q) = %q, want %q", s.text, got, s.want)
+		}
+	}
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+const (
+	maxStepInput   = 2000 // bytes of a step's input recorded on tool_started
+	maxStepPreview = 300  // bytes of its result recorded on tool_completed
+)
+
+// defaultStepTimeouts bound the steps that don't run Claude Code, whose
+// sessions have their own limit (BOB_CLAUDE_TIMEOUT). BOB_STEP_TIMEOUTS
+// overrides them per step.
+var defaultStepTimeouts = map[string]time.Duration{
+	"clone_repo":          10 * time.Minute,
+	"create_pull_request": 5 * time.Minute,
+	"update_pull_request": 5 * time.Minute,
+	"fill_pr_template":    2 * time.Minute,
+	"enforce_scope":       time.Minute,
+}
+
+// parseStepTimeouts returns defaultStepTimeouts with the "step=duration,..."
+// overrides in s applied. Invalid entries are logged and skipped.
+func parseStepTimeouts(s string) map[string]time.Duration {
+	timeouts := make(map[string]time.Duration, len(defaultStepTimeouts))
+	for step, d := range defaultStepTimeouts {
+		timeouts[step] = d
+	}
+	for step, v := range parseRepoMap(s) {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			slog.Warn("ignoring invalid step timeout", "step", step, "value", v)
+			continue
+		}
+		timeouts[step] = d // 0 removes the limit
+	}
+	return timeouts
+}
+
+// runStep runs one job step, such as clone_repo or generate_plan, and reports
+// it: tool_started with the step's input, then tool_completed with fn's result
+// or error and the duration, which also feeds the step metrics. The step is
+// cut off after its timeout, and a panic in fn is returned as an error rather
+// than taking the job down with it.
+func (o *Orchestrator) runStep(ctx context.Context, jobID, name, input string, fn func(ctx context.Context) (string, error)) (result string, err error) {
+	o.hub.Emit(jobID, ToolStartedData{ToolName: name, Input: stepText(input, maxStepInput)})
+	timeout := o.stepTimeouts[name]
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	defer func() {
+		if rec := recover(); rec != nil {
+			slog.ErrorContext(ctx, "orchestrator: panic in step", "job_id", jobID, "step", name, "panic", rec, "stack", string(debug.Stack()))
+			err = fmt.Errorf("internal error in %s: %v", name, rec)
+		}
+		if err != nil && timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("%s timed out after %s: %w", name, timeout, err)
+		}
+		preview := result
+		if err != nil {
+			preview = err.Error()
+		}
+		o.hub.Emit(jobID, ToolCompletedData{
+			ToolName: name, IsError: err != nil,
+			ResultPreview: stepText(preview, maxStepPreview), DurationMs: time.Since(start).Milliseconds(),
+		})
+	}()
+	return fn(ctx)
+}
+
+// stepText makes s fit for a step event: valid UTF-8, credentials masked and
+// at most n bytes.
+func stepText(s string, n int) string {
+	return truncate(redactSecrets(strings.ToValidUTF8(s, "�")), n)
+}
+
+// sessionPreview returns the result of a Claude Code session for runStep.
+func sessionPreview(sr *SessionResult, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	return sr.ResultText, nil
+}
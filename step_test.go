@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunStep(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	o := &Orchestrator{hub: hub, stepTimeouts: map[string]time.Duration{"slow": 20 * time.Millisecond}}
+	ctx := context.Background()
+
+	// completed waits for the tool_completed events of job to be persisted.
+	completed := func(job string) []ToolCompletedData {
+		t.Helper()
+		var got []ToolCompletedData
+		for deadline := time.Now().Add(time.Second); len(got) == 0 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			events, _ := hub.store.Events(job)
+			for _, e := range events {
+				if d, ok := e.Data.(ToolCompletedData); ok {
+					got = append(got, d)
+				}
+			}
+		}
+		if len(got) != 1 {
+			t.Fatalf("%s: got %d tool_completed events, want 1", job, len(got))
+		}
+		return got
+	}
+
+	t.Run("result", func(t *testing.T) {
+		got, err := o.runStep(ctx, "job-ok", "clone_repo", "web", func(context.Context) (string, error) {
+			return strings.Repeat("é", 200), nil
+		})
+		if err != nil || got != strings.Repeat("é", 200) {
+			t.Fatalf("runStep = %q, %v", got, err)
+		}
+		d := completed("job-ok")[0]
+		if d.ToolName != "clone_repo" || d.IsError || len(d.ResultPreview) > maxStepPreview+3 || !strings.HasSuffix(d.ResultPreview, "é...") {
+			t.Errorf("tool_completed = %+v", d)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		_, err := o.runStep(ctx, "job-slow", "slow", "", func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		})
+		if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "slow timed out after 20ms") {
+			t.Fatalf("err = %v", err)
+		}
+		if d := completed("job-slow")[0]; !d.IsError {
+			t.Errorf("tool_completed = %+v, want an error", d)
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		_, err := o.runStep(ctx, "job-panic", "generate_plan", "", func(context.Context) (string, error) {
+			panic("boom")
+		})
+		if err == nil || !strings.Contains(err.Error(), "internal error in generate_plan: boom") {
+			t.Fatalf("err = %v", err)
+		}
+		if d := completed("job-panic")[0]; !d.IsError || !strings.Contains(d.ResultPreview, "boom") {
+			t.Errorf("tool_completed = %+v", d)
+		}
+	})
+}
+
+func TestParseStepTimeouts(t *testing.T) {
+	got := parseStepTimeouts("clone_repo=30m,enforce_scope=0,run_tests=bogus")
+	if got["clone_repo"] != 30*time.Minute || got["enforce_scope"] != 0 || got["create_pull_request"] != defaultStepTimeouts["create_pull_request"] {
+		t.Errorf("parseStepTimeouts = %v", got)
+	}
+	if _, ok := got["run_tests"]; ok {
+		t.Error("invalid entry was kept")
+	}
+}
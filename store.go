@@ -0,0 +1,541 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrJobNotFound is returned by EventStore.Events for an unknown job.
+var ErrJobNotFound = errors.New("job not found")
+
+// EventStore persists monitoring events and answers the job list and stats
+// queries. Append is only ever called from the Hub's run goroutine.
+type EventStore interface {
+	Append(e Event) error
+	Events(jobID string) ([]Event, error)
+	ListJobs(q jobQuery) (jobPage, error)
+	Stats() (statsResponse, error)
+	Close() error
+}
+
+// jobQuery filters and pages the job list. Zero fields don't filter.
+type jobQuery struct {
+	Statuses []string  // job statuses to include ("running", "completed", ...)
+	Repo     string    // exact repo name
+	User     string    // chat user ID of the requester
+	Since    time.Time // jobs started at or after
+	Limit    int       // page size; always set by parseJobQuery
+	Cursor   jobCursor // position after the last job of the previous page; zero for the first page
+	Archived bool      // list archived jobs instead of live ones
+}
+
+// jobPage is one page of the job list, newest first.
+type jobPage struct {
+	Jobs       []jobSummary `json:"jobs"`
+	Total      int          `json:"total"`                 // jobs matching the filters, across all pages
+	NextCursor string       `json:"next_cursor,omitempty"` // empty on the last page
+}
+
+// jobCursor identifies a job's position in the list order (started_at desc, id desc).
+type jobCursor struct {
+	StartedAt time.Time
+	ID        string
+}
+
+// defaultJobPageSize and maxJobPageSize bound jobQuery.Limit.
+const (
+	defaultJobPageSize = 50
+	maxJobPageSize     = 500
+)
+
+// String encodes the cursor as an opaque token.
+func (c jobCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.StartedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID))
+}
+
+// parseJobCursor decodes a token produced by jobCursor.String.
+func parseJobCursor(s string) (jobCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return jobCursor{}, fmt.Errorf("invalid cursor")
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if !ok || err != nil || id == "" {
+		return jobCursor{}, fmt.Errorf("invalid cursor")
+	}
+	return jobCursor{StartedAt: t, ID: id}, nil
+}
+
+// before reports whether a job at (startedAt, id) sorts before c in the list,
+// i.e. belongs to an earlier page.
+func (c jobCursor) before(startedAt time.Time, id string) bool {
+	if c.ID == "" {
+		return false
+	}
+	if !startedAt.Equal(c.StartedAt) {
+		return startedAt.After(c.StartedAt)
+	}
+	return id >= c.ID
+}
+
+// matches reports whether a job passes the query's filters (not the cursor).
+func (q jobQuery) matches(s jobSummary) bool {
+	if len(q.Statuses) > 0 {
+		ok := false
+		for _, st := range q.Statuses {
+			ok = ok || s.Status == st
+		}
+		if !ok {
+			return false
+		}
+	}
+	if q.Repo != "" && s.Repo != q.Repo {
+		return false
+	}
+	if q.User != "" && s.RequestedBy != q.User {
+		return false
+	}
+	return q.Since.IsZero() || !s.StartedAt.Before(q.Since)
+}
+
+// paginate filters jobs (sorted newest first) and cuts out the requested page.
+func (q jobQuery) paginate(jobs []jobSummary) jobPage {
+	page := jobPage{Jobs: []jobSummary{}}
+	for _, j := range jobs {
+		if !q.matches(j) {
+			continue
+		}
+		page.Total++
+		if q.Cursor.before(j.StartedAt, j.ID) {
+			continue
+		}
+		if q.Limit > 0 && len(page.Jobs) == q.Limit {
+			page.NextCursor = jobCursor{StartedAt: page.Jobs[len(page.Jobs)-1].StartedAt, ID: page.Jobs[len(page.Jobs)-1].ID}.String()
+			continue
+		}
+		page.Jobs = append(page.Jobs, j)
+	}
+	return page
+}
+
+// parseJobQuery reads the list filters from GET /api/jobs query parameters:
+// status (comma-separated), repo, requested_by, since (RFC 3339 time or a
+// duration such as "24h", relative to now), limit, cursor and archived.
+func parseJobQuery(values map[string][]string, now time.Time) (jobQuery, error) {
+	get := func(key string) string {
+		if v := values[key]; len(v) > 0 {
+			return strings.TrimSpace(v[0])
+		}
+		return ""
+	}
+	q := jobQuery{Repo: get("repo"), User: get("requested_by"), Limit: defaultJobPageSize, Archived: get("archived") == "true"}
+	if v := get("status"); v != "" {
+		for _, st := range strings.Split(v, ",") {
+			switch st = strings.TrimSpace(st); st {
+			case "running", "completed", "error", "cancelled", "expired":
+				q.Statuses = append(q.Statuses, st)
+			default:
+				return q, fmt.Errorf("invalid status %q", st)
+			}
+		}
+	}
+	if v := get("since"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			q.Since = t
+		} else if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			q.Since = now.Add(-d)
+		} else {
+			return q, fmt.Errorf("invalid since %q", v)
+		}
+	}
+	if v := get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return q, fmt.Errorf("invalid limit %q", v)
+		}
+		q.Limit = min(n, maxJobPageSize)
+	}
+	if v := get("cursor"); v != "" {
+		c, err := parseJobCursor(v)
+		if err != nil {
+			return q, err
+		}
+		q.Cursor = c
+	}
+	return q, nil
+}
+
+// jobAggregate is the per-job rollup derived from a job's events. Both stores
+// build it with apply, so list and stats semantics are identical across backends.
+type jobAggregate struct {
+	jobSummary
+	LLMCostUSD       float64 `json:"llm_cost_usd"` // sum of llm_response costs (stats semantics)
+	InputTokens      int64   `json:"input_tokens"`
+	OutputTokens     int64   `json:"output_tokens"`
+	CacheReadTokens  int64   `json:"cache_read_tokens"`
+	CacheWriteTokens int64   `json:"cache_write_tokens"`
+}
+
+// apply folds one event into the aggregate.
+func (a *jobAggregate) apply(e Event) {
+	if a.StartedAt.IsZero() {
+		a.ID = e.JobID
+		a.Status = "running"
+		a.StartedAt = e.Timestamp
+		if d, ok := e.Data.(JobStartedData); ok {
+			a.Task, a.Repo, a.RequestedBy, a.RequestedByName = d.Task, d.Repo, d.RequestedBy, d.RequestedByName
+			a.IntentModel, a.CLIVersion = d.IntentModel, d.CLIVersion
+		}
+	}
+	switch d := e.Data.(type) {
+	case JobMetadataData:
+		if d.Model != "" {
+			a.Model = d.Model
+		}
+		if d.CLIVersion != "" {
+			a.CLIVersion = d.CLIVersion
+		}
+	case LLMResponseData:
+		a.CostUSD += d.CostUSD
+		a.LLMCostUSD += d.CostUSD
+		a.InputTokens += d.InputTokens
+		a.OutputTokens += d.OutputTokens
+		a.CacheReadTokens += d.CacheReadTokens
+		a.CacheWriteTokens += d.CacheWriteTokens
+	case PhaseChangedData:
+		a.Phase = d.Phase
+	case JobCompletedData:
+		a.Status = "completed"
+		if d.TotalCostUSD != 0 {
+			a.CostUSD = d.TotalCostUSD // authoritative total
+		}
+	case JobErrorData:
+		a.Status = "error"
+		if d.TotalCostUSD != 0 {
+			a.CostUSD = d.TotalCostUSD
+		}
+	case JobCancelledData:
+		a.Status = "cancelled"
+	case JobExpiredData:
+		a.Status = "expired"
+	}
+}
+
+// summary returns the list view of the aggregate. Phase is only reported for
+// running jobs.
+func (a *jobAggregate) summary() jobSummary {
+	s := a.jobSummary
+	if s.Status != "running" {
+		s.Phase = ""
+	}
+	return s
+}
+
+// addTo accumulates the aggregate into a stats response.
+func (a *jobAggregate) addTo(stats *statsResponse) {
+	stats.TotalJobs++
+	stats.TotalCostUSD += a.LLMCostUSD
+	stats.TotalInputTokens += a.InputTokens
+	stats.TotalOutputTokens += a.OutputTokens
+	stats.TotalCacheReadTokens += a.CacheReadTokens
+	stats.TotalCacheWriteTokens += a.CacheWriteTokens
+	switch a.Status {
+	case "completed":
+		stats.CompletedJobs++
+	case "error":
+		stats.ErrorJobs++
+	case "cancelled":
+		stats.CancelledJobs++
+	case "expired":
+		stats.ExpiredJobs++
+	default:
+		stats.RunningJobs++
+	}
+}
+
+// jobIndexFile holds the aggregates of finished jobs, so listing and stats
+// only rescan the files of running jobs.
+const jobIndexFile = "job-index.json"
+
+// jobIndexVersion is bumped when jobAggregate.apply changes meaning; an index
+// with another version is discarded and rebuilt from the job files.
+const jobIndexVersion = 3
+
+// jobIndex is the on-disk form of the finished-job index.
+type jobIndex struct {
+	Version int                      `json:"version"`
+	Jobs    map[string]*jobAggregate `json:"jobs"`
+}
+
+// jsonlStore is the default EventStore: one append-only JSONL file per job
+// under dir. Finished jobs are aggregated once into job-index.json; listing
+// and stats rescan only the other files.
+type jsonlStore struct {
+	dir     string
+	filesMu sync.Mutex          // held by Append and while a job is archived
+	files   map[string]*os.File // open job files
+
+	archiveMu sync.Mutex // serializes archive index updates
+
+	indexMu sync.Mutex
+	index   map[string]*jobAggregate // finished jobs by ID, persisted in jobIndexFile
+}
+
+func newJSONLStore(dir string) *jsonlStore {
+	s := &jsonlStore{dir: dir, files: make(map[string]*os.File), index: make(map[string]*jobAggregate)}
+	s.loadIndex()
+	return s
+}
+
+func (s *jsonlStore) Append(e Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.filesMu.Lock()
+	f, err := s.openJobFile(e.JobID)
+	if err == nil {
+		_, err = f.Write(append(line, '\n'))
+	}
+	s.filesMu.Unlock()
+	if err != nil {
+		return err
+	}
+	s.updateIndex(e)
+	return nil
+}
+
+// updateIndex keeps the index in step with an appended event. A terminal event
+// indexes the job from its file; any other event for an indexed job (e.g. one
+// resumed after finishing) drops it, so it is rescanned until it finishes again.
+func (s *jsonlStore) updateIndex(e Event) {
+	var terminal bool
+	switch d := e.Data.(type) {
+	case JobCompletedData, JobErrorData, JobCancelledData, JobExpiredData:
+		terminal = true
+	case PhaseChangedData:
+		if JobPhase(d.Phase) == PhaseDone {
+			return // follows the terminal event when a job is closed
+		}
+	case ChecksCompletedData:
+		return // reported after the job closed; doesn't change its summary
+	}
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+	if !terminal {
+		if _, ok := s.index[e.JobID]; ok {
+			delete(s.index, e.JobID)
+			s.saveIndex()
+		}
+		return
+	}
+	a := &jobAggregate{jobSummary: jobSummary{ID: e.JobID, Status: "running"}}
+	if err := s.scan(filepath.Join(s.dir, e.JobID+".jsonl"), a.apply); err != nil {
+		slog.Error("store: failed to index job", "job_id", e.JobID, "err", err)
+		return
+	}
+	s.index[e.JobID] = a
+	s.saveIndex()
+}
+
+// openJobFile returns the job's file, opened for appending. Callers hold s.filesMu.
+func (s *jsonlStore) openJobFile(jobID string) (*os.File, error) {
+	if f, ok := s.files[jobID]; ok {
+		return f, nil
+	}
+	path := filepath.Join(s.dir, jobID+".jsonl")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s.files[jobID] = f
+	return f, nil
+}
+
+func (s *jsonlStore) Events(jobID string) ([]Event, error) {
+	var events []Event
+	err := s.scan(filepath.Join(s.dir, jobID+".jsonl"), func(e Event) {
+		events = append(events, e)
+	})
+	if os.IsNotExist(err) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []Event{}
+	}
+	return events, nil
+}
+
+func (s *jsonlStore) ListJobs(q jobQuery) (jobPage, error) {
+	aggs, err := s.aggregates()
+	if err != nil {
+		return jobPage{}, err
+	}
+	return q.paginate(sortedSummaries(aggs)), nil
+}
+
+// sortedSummaries returns the aggregates' summaries by started_at descending
+// (most recent first), then ID for a stable page order.
+func sortedSummaries(aggs []*jobAggregate) []jobSummary {
+	jobs := make([]jobSummary, 0, len(aggs))
+	for _, a := range aggs {
+		jobs = append(jobs, a.summary())
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if !jobs[i].StartedAt.Equal(jobs[j].StartedAt) {
+			return jobs[i].StartedAt.After(jobs[j].StartedAt)
+		}
+		return jobs[i].ID > jobs[j].ID
+	})
+	return jobs
+}
+
+func (s *jsonlStore) Stats() (statsResponse, error) {
+	var stats statsResponse
+	aggs, err := s.aggregates()
+	if err != nil {
+		return stats, err
+	}
+	for _, a := range aggs {
+		a.addTo(&stats)
+	}
+	return stats, nil
+}
+
+func (s *jsonlStore) Close() error {
+	s.filesMu.Lock()
+	defer s.filesMu.Unlock()
+	for id, f := range s.files {
+		f.Close()
+		delete(s.files, id)
+	}
+	return nil
+}
+
+// aggregates returns every job's aggregate: finished jobs from the index, the
+// rest by scanning their files. Finished jobs missing from the index (e.g.
+// from before it existed) are added, and jobs whose files are gone dropped.
+func (s *jsonlStore) aggregates() ([]*jobAggregate, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	// Held across the scans so a concurrent Append can't index a job between
+	// its scan here and the backfill below.
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+	var aggs []*jobAggregate
+	seen := make(map[string]bool, len(entries))
+	dirty := false
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".jsonl") {
+			continue
+		}
+		id := strings.TrimSuffix(entry.Name(), ".jsonl")
+		seen[id] = true
+		if a, ok := s.index[id]; ok {
+			aggs = append(aggs, a)
+			continue
+		}
+		a := &jobAggregate{jobSummary: jobSummary{ID: id, Status: "running"}}
+		if err := s.scan(filepath.Join(s.dir, entry.Name()), a.apply); err != nil {
+			continue
+		}
+		if a.Status != "running" {
+			s.index[id] = a
+			dirty = true
+		}
+		aggs = append(aggs, a)
+	}
+	for id := range s.index {
+		if !seen[id] {
+			delete(s.index, id)
+			dirty = true
+		}
+	}
+	if dirty {
+		s.saveIndex()
+	}
+	return aggs, nil
+}
+
+// loadIndex reads the finished-job index, if there is a current one.
+func (s *jsonlStore) loadIndex() {
+	data, err := os.ReadFile(filepath.Join(s.dir, jobIndexFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Error("store: failed to load job index", "err", err)
+		}
+		return
+	}
+	var idx jobIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		slog.Error("store: failed to parse job index", "err", err)
+		return
+	}
+	if idx.Version != jobIndexVersion || idx.Jobs == nil {
+		return // rebuilt from the job files on the next read
+	}
+	s.index = idx.Jobs
+}
+
+// saveIndex writes the index atomically. Callers hold s.indexMu.
+func (s *jsonlStore) saveIndex() {
+	data, err := json.Marshal(jobIndex{Version: jobIndexVersion, Jobs: s.index})
+	if err != nil {
+		slog.Error("store: failed to marshal job index", "err", err)
+		return
+	}
+	path := filepath.Join(s.dir, jobIndexFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		slog.Error("store: failed to write job index", "err", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		slog.Error("store: failed to rename job index", "err", err)
+	}
+}
+
+// scan calls fn for every decodable event line in a job file.
+func (s *jsonlStore) scan(path string, fn func(Event)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return scanEvents(f, fn)
+}
+
+// scanEvents calls fn for every decodable event line read from r.
+func scanEvents(r io.Reader, fn func(Event)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		fn(e)
+	}
+	return scanner.Err()
+}
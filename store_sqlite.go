@@ -0,0 +1,295 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS events (
+	seq       INTEGER PRIMARY KEY AUTOINCREMENT,
+	id        TEXT NOT NULL,
+	job_id    TEXT NOT NULL,
+	type      TEXT NOT NULL,
+	timestamp TEXT NOT NULL,
+	data      TEXT,
+	schema_version INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS events_job_id ON events(job_id, seq);
+
+CREATE TABLE IF NOT EXISTS jobs (
+	id                 TEXT PRIMARY KEY,
+	task               TEXT NOT NULL DEFAULT '',
+	repo               TEXT NOT NULL DEFAULT '',
+	started_at         TEXT NOT NULL,
+	status             TEXT NOT NULL,
+	phase              TEXT NOT NULL DEFAULT '',
+	cost_usd           REAL NOT NULL DEFAULT 0,
+	llm_cost_usd       REAL NOT NULL DEFAULT 0,
+	input_tokens       INTEGER NOT NULL DEFAULT 0,
+	output_tokens      INTEGER NOT NULL DEFAULT 0,
+	cache_read_tokens  INTEGER NOT NULL DEFAULT 0,
+	cache_write_tokens INTEGER NOT NULL DEFAULT 0,
+	intent_model       TEXT NOT NULL DEFAULT '',
+	model              TEXT NOT NULL DEFAULT '',
+	cli_version        TEXT NOT NULL DEFAULT '',
+	requested_by       TEXT NOT NULL DEFAULT '',
+	requested_by_name  TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS jobs_started_at ON jobs(started_at DESC);
+`
+
+// sqliteTimeFormat stores jobs.started_at in UTC with a fixed-width fraction,
+// so text order is time order and range queries can use the index.
+const sqliteTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// sqliteStore is an EventStore backed by a single SQLite database. Each Append
+// also updates the job's row in the jobs summary table, so listing and stats
+// are indexed queries instead of full rescans.
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// newSQLiteStore opens (or creates) the database at path.
+func newSQLiteStore(path string) (*sqliteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create data dir: %w", err)
+	}
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create schema: %w", err)
+	}
+	if err := addColumnIfMissing(db, "jobs", "repo", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate schema: %w", err)
+	}
+	if err := addColumnIfMissing(db, "jobs", "requested_by", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate schema: %w", err)
+	}
+	if err := addColumnIfMissing(db, "jobs", "requested_by_name", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate schema: %w", err)
+	}
+	if err := addColumnIfMissing(db, "events", "schema_version", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate schema: %w", err)
+	}
+	return &sqliteStore{db: db}, nil
+}
+
+// addColumnIfMissing adds a column to a table created by an older schema.
+func addColumnIfMissing(db *sql.DB, table, column, def string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, def))
+	return err
+}
+
+func (s *sqliteStore) Append(e Event) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO events (id, job_id, type, timestamp, data, schema_version) VALUES (?, ?, ?, ?, ?, ?)`,
+		e.ID, e.JobID, string(e.Type), e.Timestamp.Format(time.RFC3339Nano), string(data), e.SchemaVersion); err != nil {
+		return err
+	}
+
+	a, err := s.aggregate(tx, e.JobID)
+	if err != nil {
+		return err
+	}
+	a.apply(e)
+
+	if _, err := tx.Exec(`INSERT INTO jobs (id, task, repo, started_at, status, phase, cost_usd, llm_cost_usd,
+			input_tokens, output_tokens, cache_read_tokens, cache_write_tokens, intent_model, model, cli_version, requested_by, requested_by_name)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status = excluded.status, phase = excluded.phase,
+			cost_usd = excluded.cost_usd, llm_cost_usd = excluded.llm_cost_usd,
+			input_tokens = excluded.input_tokens, output_tokens = excluded.output_tokens,
+			cache_read_tokens = excluded.cache_read_tokens, cache_write_tokens = excluded.cache_write_tokens,
+			model = excluded.model, cli_version = excluded.cli_version`,
+		a.ID, a.Task, a.Repo, a.StartedAt.UTC().Format(sqliteTimeFormat), a.Status, a.Phase, a.CostUSD, a.LLMCostUSD,
+		a.InputTokens, a.OutputTokens, a.CacheReadTokens, a.CacheWriteTokens, a.IntentModel, a.Model, a.CLIVersion, a.RequestedBy, a.RequestedByName); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// aggregate loads a job's summary row, or an empty aggregate for a new job.
+func (s *sqliteStore) aggregate(tx *sql.Tx, jobID string) (*jobAggregate, error) {
+	a := &jobAggregate{}
+	var startedAt string
+	err := tx.QueryRow(`SELECT id, task, repo, started_at, status, phase, cost_usd, llm_cost_usd,
+			input_tokens, output_tokens, cache_read_tokens, cache_write_tokens, intent_model, model, cli_version, requested_by, requested_by_name
+		FROM jobs WHERE id = ?`, jobID).Scan(
+		&a.ID, &a.Task, &a.Repo, &startedAt, &a.Status, &a.Phase, &a.CostUSD, &a.LLMCostUSD,
+		&a.InputTokens, &a.OutputTokens, &a.CacheReadTokens, &a.CacheWriteTokens, &a.IntentModel, &a.Model, &a.CLIVersion, &a.RequestedBy, &a.RequestedByName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.StartedAt, _ = time.Parse(time.RFC3339Nano, startedAt)
+	return a, nil
+}
+
+func (s *sqliteStore) Events(jobID string) ([]Event, error) {
+	rows, err := s.db.Query(`SELECT id, job_id, type, timestamp, data, schema_version FROM events WHERE job_id = ? ORDER BY seq`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var e Event
+		var typ, ts, data string
+		if err := rows.Scan(&e.ID, &e.JobID, &typ, &ts, &data, &e.SchemaVersion); err != nil {
+			return nil, err
+		}
+		e.Type = EventType(typ)
+		e.Timestamp, _ = time.Parse(time.RFC3339Nano, ts)
+		e.Data, _ = decodeEventData(e.Type, json.RawMessage(data))
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, ErrJobNotFound
+	}
+	return events, nil
+}
+
+func (s *sqliteStore) ListJobs(q jobQuery) (jobPage, error) {
+	var where []string
+	var args []any
+	if len(q.Statuses) > 0 {
+		where = append(where, "status IN (?"+strings.Repeat(", ?", len(q.Statuses)-1)+")")
+		for _, st := range q.Statuses {
+			args = append(args, st)
+		}
+	}
+	if q.Repo != "" {
+		where = append(where, "repo = ?")
+		args = append(args, q.Repo)
+	}
+	if q.User != "" {
+		where = append(where, "requested_by = ?")
+		args = append(args, q.User)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "started_at >= ?")
+		args = append(args, q.Since.UTC().Format(sqliteTimeFormat))
+	}
+	filter := ""
+	if len(where) > 0 {
+		filter = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	page := jobPage{Jobs: []jobSummary{}}
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM jobs`+filter, args...).Scan(&page.Total); err != nil {
+		return jobPage{}, err
+	}
+
+	if q.Cursor.ID != "" {
+		where = append(where, "(started_at < ? OR (started_at = ? AND id < ?))")
+		ts := q.Cursor.StartedAt.UTC().Format(sqliteTimeFormat)
+		args = append(args, ts, ts, q.Cursor.ID)
+		filter = " WHERE " + strings.Join(where, " AND ")
+	}
+	limit := ""
+	if q.Limit > 0 {
+		limit = " LIMIT ?"
+		args = append(args, q.Limit+1) // one extra to tell whether there's a next page
+	}
+
+	rows, err := s.db.Query(`SELECT id, task, repo, started_at, status, phase, cost_usd, intent_model, model, cli_version, requested_by, requested_by_name
+		FROM jobs`+filter+` ORDER BY started_at DESC, id DESC`+limit, args...)
+	if err != nil {
+		return jobPage{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a jobAggregate
+		var startedAt string
+		if err := rows.Scan(&a.ID, &a.Task, &a.Repo, &startedAt, &a.Status, &a.Phase, &a.CostUSD, &a.IntentModel, &a.Model, &a.CLIVersion, &a.RequestedBy, &a.RequestedByName); err != nil {
+			return jobPage{}, err
+		}
+		a.StartedAt, _ = time.Parse(time.RFC3339Nano, startedAt)
+		page.Jobs = append(page.Jobs, a.summary())
+	}
+	if err := rows.Err(); err != nil {
+		return jobPage{}, err
+	}
+	if q.Limit > 0 && len(page.Jobs) > q.Limit {
+		page.Jobs = page.Jobs[:q.Limit]
+		last := page.Jobs[q.Limit-1]
+		page.NextCursor = jobCursor{StartedAt: last.StartedAt, ID: last.ID}.String()
+	}
+	return page, nil
+}
+
+func (s *sqliteStore) Stats() (statsResponse, error) {
+	var stats statsResponse
+	err := s.db.QueryRow(`SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'completed'),
+			COUNT(*) FILTER (WHERE status = 'error'),
+			COUNT(*) FILTER (WHERE status = 'cancelled'),
+			COUNT(*) FILTER (WHERE status = 'expired'),
+			COUNT(*) FILTER (WHERE status NOT IN ('completed', 'error', 'cancelled', 'expired')),
+			COALESCE(SUM(llm_cost_usd), 0),
+			COALESCE(SUM(input_tokens), 0),
+			COALESCE(SUM(output_tokens), 0),
+			COALESCE(SUM(cache_read_tokens), 0),
+			COALESCE(SUM(cache_write_tokens), 0)
+		FROM jobs`).Scan(
+		&stats.TotalJobs, &stats.CompletedJobs, &stats.ErrorJobs, &stats.CancelledJobs, &stats.ExpiredJobs, &stats.RunningJobs,
+		&stats.TotalCostUSD, &stats.TotalInputTokens, &stats.TotalOutputTokens,
+		&stats.TotalCacheReadTokens, &stats.TotalCacheWriteTokens)
+	return stats, err
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// storeFixtures returns a fresh store of each backend.
+func storeFixtures(t *testing.T) map[string]EventStore {
+	t.Helper()
+	sq, err := newSQLiteStore(filepath.Join(t.TempDir(), "events.db"))
+	if err != nil {
+		t.Fatalf("newSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { sq.Close() })
+	js := newJSONLStore(t.TempDir())
+	t.Cleanup(func() { js.Close() })
+	return map[string]EventStore{"jsonl": js, "sqlite": sq}
+}
+
+func TestEventStore(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []Event{
+		{ID: "1", JobID: "job-a", Type: EventJobStarted, Timestamp: t0, Data: JobStartedData{Task: "task a", Repo: "api", IntentModel: "haiku"}},
+		{ID: "2", JobID: "job-a", Type: EventLLMResponse, Timestamp: t0.Add(time.Second), Data: LLMResponseData{CostUSD: 0.5, InputTokens: 100, OutputTokens: 10}},
+		{ID: "3", JobID: "job-a", Type: EventJobCompleted, Timestamp: t0.Add(2 * time.Second), Data: JobCompletedData{TotalCostUSD: 1.25}},
+		{ID: "4", JobID: "job-b", Type: EventJobStarted, Timestamp: t0.Add(time.Minute), Data: JobStartedData{Task: "task b", Repo: "web", RequestedBy: "U1", RequestedByName: "Ada"}},
+		{ID: "5", JobID: "job-b", Type: EventPhaseChanged, Timestamp: t0.Add(time.Minute), Data: PhaseChangedData{Phase: "awaiting_approval"}},
+		{ID: "6", JobID: "job-c", Type: EventJobStarted, Timestamp: t0.Add(2 * time.Minute), Data: JobStartedData{Task: "task c", Repo: "api"}},
+		{ID: "7", JobID: "job-c", Type: EventJobCancelled, Timestamp: t0.Add(3 * time.Minute), Data: JobCancelledData{}},
+	}
+
+	for name, store := range storeFixtures(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, e := range events {
+				if err := store.Append(e); err != nil {
+					t.Fatalf("Append: %v", err)
+				}
+			}
+
+			t.Run("events in order", func(t *testing.T) {
+				got, err := store.Events("job-a")
+				if err != nil {
+					t.Fatalf("Events: %v", err)
+				}
+				if len(got) != 3 || got[0].ID != "1" || got[2].Type != EventJobCompleted {
+					t.Errorf("Events = %+v", got)
+				}
+			})
+
+			t.Run("unknown job", func(t *testing.T) {
+				if _, err := store.Events("nope"); !errors.Is(err, ErrJobNotFound) {
+					t.Errorf("err = %v, want ErrJobNotFound", err)
+				}
+			})
+
+			t.Run("list newest first", func(t *testing.T) {
+				page, err := store.ListJobs(jobQuery{})
+				if err != nil {
+					t.Fatalf("ListJobs: %v", err)
+				}
+				jobs := page.Jobs
+				if page.Total != 3 || page.NextCursor != "" {
+					t.Errorf("total = %d, next = %q", page.Total, page.NextCursor)
+				}
+				if len(jobs) != 3 {
+					t.Fatalf("len = %d, want 3", len(jobs))
+				}
+				if jobs[0].ID != "job-c" || jobs[0].Status != "cancelled" {
+					t.Errorf("jobs[0] = %+v", jobs[0])
+				}
+				if jobs[1].Phase != "awaiting_approval" || jobs[1].Status != "running" || jobs[1].RequestedBy != "U1" || jobs[1].RequestedByName != "Ada" {
+					t.Errorf("jobs[1] = %+v", jobs[1])
+				}
+				if jobs[2].Task != "task a" || jobs[2].CostUSD != 1.25 || jobs[2].IntentModel != "haiku" {
+					t.Errorf("jobs[2] = %+v", jobs[2])
+				}
+			})
+
+			t.Run("filters", func(t *testing.T) {
+				tests := []struct {
+					name string
+					q    jobQuery
+					want []string
+				}{
+					{"status", jobQuery{Statuses: []string{"running", "completed"}}, []string{"job-b", "job-a"}},
+					{"repo", jobQuery{Repo: "api"}, []string{"job-c", "job-a"}},
+					{"requester", jobQuery{User: "U1"}, []string{"job-b"}},
+					{"since", jobQuery{Since: t0.Add(time.Minute)}, []string{"job-c", "job-b"}},
+					{"combined", jobQuery{Repo: "api", Statuses: []string{"cancelled"}}, []string{"job-c"}},
+				}
+				for _, tt := range tests {
+					page, err := store.ListJobs(tt.q)
+					if err != nil {
+						t.Fatalf("%s: ListJobs: %v", tt.name, err)
+					}
+					if got := jobIDs(page.Jobs); !slices.Equal(got, tt.want) || page.Total != len(tt.want) {
+						t.Errorf("%s: got %v (total %d), want %v", tt.name, got, page.Total, tt.want)
+					}
+				}
+			})
+
+			t.Run("pages", func(t *testing.T) {
+				q := jobQuery{Limit: 2}
+				var got []string
+				for range 3 {
+					page, err := store.ListJobs(q)
+					if err != nil {
+						t.Fatalf("ListJobs: %v", err)
+					}
+					if page.Total != 3 {
+						t.Errorf("total = %d, want 3", page.Total)
+					}
+					got = append(got, jobIDs(page.Jobs)...)
+					if page.NextCursor == "" {
+						break
+					}
+					if q.Cursor, err = parseJobCursor(page.NextCursor); err != nil {
+						t.Fatalf("parseJobCursor: %v", err)
+					}
+				}
+				if want := []string{"job-c", "job-b", "job-a"}; !slices.Equal(got, want) {
+					t.Errorf("paged through %v, want %v", got, want)
+				}
+			})
+
+			t.Run("stats", func(t *testing.T) {
+				stats, err := store.Stats()
+				if err != nil {
+					t.Fatalf("Stats: %v", err)
+				}
+				if stats.TotalJobs != 3 || stats.CompletedJobs != 1 || stats.RunningJobs != 1 || stats.CancelledJobs != 1 {
+					t.Errorf("counts = %+v", stats)
+				}
+				if math.Abs(stats.TotalCostUSD-0.5) > 1e-9 || stats.TotalInputTokens != 100 || stats.TotalOutputTokens != 10 {
+					t.Errorf("totals = %+v", stats)
+				}
+			})
+		})
+	}
+}
+
+func TestJSONLStore_Index(t *testing.T) {
+	dir := t.TempDir()
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := newJSONLStore(dir)
+	for _, e := range []Event{
+		{ID: "1", JobID: "job-a", Type: EventJobStarted, Timestamp: t0, Data: JobStartedData{Task: "a"}},
+		{ID: "2", JobID: "job-a", Type: EventLLMResponse, Timestamp: t0, Data: LLMResponseData{CostUSD: 0.5}},
+		{ID: "3", JobID: "job-a", Type: EventJobCompleted, Timestamp: t0, Data: JobCompletedData{}},
+		{ID: "3b", JobID: "job-a", Type: EventPhaseChanged, Timestamp: t0, Data: PhaseChangedData{Phase: string(PhaseDone)}},
+		{ID: "4", JobID: "job-b", Type: EventJobStarted, Timestamp: t0, Data: JobStartedData{Task: "b"}},
+	} {
+		if err := s.Append(e); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	s.Close()
+	if _, ok := s.index["job-a"]; !ok || len(s.index) != 1 {
+		t.Fatalf("index = %v, want only the finished job", s.index)
+	}
+
+	// A new store serves finished jobs from the index without reading their
+	// files, and backfills jobs that finished without being indexed.
+	os.WriteFile(filepath.Join(dir, "job-a.jsonl"), nil, 0o644)
+	os.WriteFile(filepath.Join(dir, "job-c.jsonl"), []byte(`{"id":"5","job_id":"job-c","type":"job_cancelled","timestamp":"2025-01-01T12:00:00Z","data":{}}`+"\n"), 0o644)
+	s = newJSONLStore(dir)
+	defer s.Close()
+	stats, err := s.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.CompletedJobs != 1 || stats.CancelledJobs != 1 || stats.RunningJobs != 1 || stats.TotalCostUSD != 0.5 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if _, ok := s.index["job-c"]; !ok {
+		t.Error("finished job not backfilled into the index")
+	}
+
+	// A job resumed after finishing is rescanned, and removed files are dropped.
+	s.Append(Event{ID: "6", JobID: "job-c", Type: EventJobStarted, Timestamp: t0, Data: JobStartedData{Task: "c"}})
+	if _, ok := s.index["job-c"]; ok {
+		t.Error("resumed job still indexed")
+	}
+	os.Remove(filepath.Join(dir, "job-a.jsonl"))
+	page, err := s.ListJobs(jobQuery{})
+	if err != nil {
+		t.Fatalf("ListJobs: %v", err)
+	}
+	if got := jobIDs(page.Jobs); !slices.Equal(got, []string{"job-c", "job-b"}) && !slices.Equal(got, []string{"job-b", "job-c"}) {
+		t.Errorf("jobs = %v", got)
+	}
+	if _, ok := s.index["job-a"]; ok {
+		t.Error("deleted job still indexed")
+	}
+}
+
+func jobIDs(jobs []jobSummary) []string {
+	ids := make([]string, len(jobs))
+	for i, j := range jobs {
+		ids[i] = j.ID
+	}
+	return ids
+}
+
+func TestParseJobQuery(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cursor := jobCursor{StartedAt: now, ID: "job-x"}.String()
+
+	q, err := parseJobQuery(url.Values{
+		"status": {"running,error"}, "repo": {"web"}, "requested_by": {"U1"}, "since": {"24h"}, "limit": {"1000"}, "cursor": {cursor},
+	}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(q.Statuses, []string{"running", "error"}) || q.Repo != "web" || q.User != "U1" ||
+		!q.Since.Equal(now.Add(-24*time.Hour)) || q.Limit != maxJobPageSize || q.Cursor.ID != "job-x" || !q.Cursor.StartedAt.Equal(now) {
+		t.Errorf("q = %+v", q)
+	}
+
+	if q, _ := parseJobQuery(url.Values{"since": {"2025-05-01T00:00:00Z"}}, now); !q.Since.Equal(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)) || q.Limit != defaultJobPageSize {
+		t.Errorf("q = %+v", q)
+	}
+
+	for _, bad := range []url.Values{
+		{"status": {"done"}},
+		{"since": {"yesterday"}},
+		{"limit": {"0"}},
+		{"limit": {"10abc"}},
+		{"cursor": {"not-a-cursor"}},
+	} {
+		if _, err := parseJobQuery(bad, now); err == nil {
+			t.Errorf("parseJobQuery(%v): expected error", bad)
+		}
+	}
+}
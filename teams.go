@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	teamsMaxMessage  = 20000   // characters per card; Teams rejects payloads over ~28 KB
+	maxTeamsBodySize = 1 << 20 // 1 MB
+)
+
+var (
+	teamsMentionRe = regexp.MustCompile(`(?s)<at>.*?</at>\s*`)
+	teamsTagRe     = regexp.MustCompile(`<[^>]+>`)
+	teamsBreakRe   = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>`)
+)
+
+// TeamsPlatform is the Microsoft Teams ChatPlatform. Mentions arrive through an
+// outgoing webhook (a channel's "@Bob"); Bob answers through the channel's
+// incoming webhook. Incoming webhooks can't reply in a thread, so each message
+// is a new channel post addressed to the requester. The channel is the Teams
+// channel ID and the thread the ID of the conversation's root message.
+type TeamsPlatform struct {
+	secret     []byte // outgoing webhook security token, base64-decoded
+	webhookURL string // incoming webhook (or Workflows webhook) URL
+
+	users sync.Map // user ID → display name, learned from mentions
+}
+
+// NewTeamsPlatform creates a TeamsPlatform from the outgoing webhook's
+// security token (as shown by Teams, base64) and the incoming webhook URL.
+func NewTeamsPlatform(secret, webhookURL string) (*TeamsPlatform, error) {
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("teams outgoing webhook secret: %w", err)
+	}
+	return &TeamsPlatform{secret: key, webhookURL: webhookURL}, nil
+}
+
+// Name implements ChatPlatform.
+func (p *TeamsPlatform) Name() string { return "teams" }
+
+// Notify posts text to the channel as an Adaptive Card, which both incoming
+// webhooks and Workflows webhooks accept, split to fit Teams' size limit.
+func (p *TeamsPlatform) Notify(ctx context.Context, text string) error {
+	for _, chunk := range splitMessage(text, teamsMaxMessage) {
+		if err := p.post(ctx, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *TeamsPlatform) post(ctx context.Context, text string) error {
+	card := map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    []map[string]any{{"type": "TextBlock", "text": text, "wrap": true}},
+			},
+		}},
+	}
+	payload, err := json.Marshal(card)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("teams webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("teams webhook status %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// ThreadMessages implements ChatPlatform. Outgoing webhooks only see the
+// message that mentions Bob, so there is no history to read and the mention
+// alone is used.
+func (p *TeamsPlatform) ThreadMessages(ctx context.Context, channel, thread string) ([]Message, error) {
+	return nil, nil
+}
+
+// StripMention implements ChatPlatform. Teams sends message text as HTML with
+// mentions as <at>Name</at>; the result is plain text.
+func (p *TeamsPlatform) StripMention(text string) string {
+	text = teamsMentionRe.ReplaceAllString(text, "")
+	text = teamsBreakRe.ReplaceAllString(text, "\n")
+	text = html.UnescapeString(teamsTagRe.ReplaceAllString(text, ""))
+	return strings.TrimSpace(strings.ReplaceAll(text, "\u00a0", " "))
+}
+
+// MentionUser implements ChatPlatform. Cards posted through a webhook can't
+// mention users, so the requester is named instead.
+func (p *TeamsPlatform) MentionUser(userID string) string {
+	if name, ok := p.users.Load(userID); ok {
+		return "**" + name.(string) + "**"
+	}
+	return ""
+}
+
+// verify checks an outgoing webhook's "Authorization: HMAC <signature>" header
+// against the body.
+func (p *TeamsPlatform) verify(body []byte, header string) bool {
+	sig, ok := strings.CutPrefix(header, "HMAC ")
+	if !ok {
+		return false
+	}
+	want, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// teamsActivity covers the fields we use from an outgoing webhook activity.
+type teamsActivity struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	From struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"from"`
+	Conversation struct {
+		ID string `json:"id"` // "19:...@thread.tacv2;messageid=<root message ID>"
+	} `json:"conversation"`
+	ChannelData struct {
+		TeamsChannelID string `json:"teamsChannelId"`
+	} `json:"channelData"`
+}
+
+// mention converts an activity into a chat mention, keyed by channel and the
+// root message of its conversation.
+func (p *TeamsPlatform) mention(a teamsActivity) (chatMention, bool) {
+	if a.Type != "message" || a.From.ID == "" {
+		return chatMention{}, false
+	}
+	channel, thread, _ := strings.Cut(a.Conversation.ID, ";messageid=")
+	if a.ChannelData.TeamsChannelID != "" {
+		channel = a.ChannelData.TeamsChannelID
+	}
+	if thread == "" {
+		thread = a.ID
+	}
+	if channel == "" || thread == "" {
+		return chatMention{}, false
+	}
+	if a.From.Name != "" {
+		p.users.Store(a.From.ID, a.From.Name)
+	}
+	return chatMention{Channel: channel, Thread: thread, User: a.From.ID, UserName: a.From.Name, Text: a.Text}, true
+}
+
+// NewTeamsHandler handles the outgoing webhook. Teams waits at most five
+// seconds for the reply, so the request is acknowledged right away and handled
+// in the background, answering through the incoming webhook.
+func NewTeamsHandler(p *TeamsPlatform, orch *Orchestrator, hub *Hub, approver *Approver, limits *userLimits, bobURL, apiToken string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxTeamsBodySize+1))
+		if err != nil || len(body) > maxTeamsBodySize {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		if !p.verify(body, r.Header.Get("Authorization")) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		var a teamsActivity
+		if err := json.Unmarshal(body, &a); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		m, ok := p.mention(a)
+		if !ok {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		slog.Info("teams: mention", "user", m.User, "channel", m.Channel, "text", p.StripMention(m.Text))
+
+		reply := "On it."
+		if !limits.allow(p.Name(), m.User) {
+			slog.Warn("teams: rate limited mention", "user", m.User, "channel", m.Channel)
+			reply = "You're sending me requests faster than I can take them. Please try again in a moment."
+		} else {
+			go handleChatMention(p, orch, hub, approver, limits, bobURL, apiToken, m)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"type": "message", "text": reply})
+	})
+}
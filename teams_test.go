@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTeamsStripMention(t *testing.T) {
+	p := &TeamsPlatform{}
+	tests := []struct {
+		in, want string
+	}{
+		{"<at>Bob</at> fix the bug", "fix the bug"},
+		{"<p><at>Bob</at>&nbsp;fix the <b>login</b> bug</p>", "fix the login bug"},
+		{"<div>first<br>second</div>", "first\nsecond"},
+		{"no mention &amp; no tags", "no mention & no tags"},
+	}
+	for _, tt := range tests {
+		if got := p.StripMention(tt.in); got != tt.want {
+			t.Errorf("StripMention(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTeamsMention(t *testing.T) {
+	p := &TeamsPlatform{}
+	var a teamsActivity
+	json.Unmarshal([]byte(`{"type":"message","id":"1700000000002","text":"<at>Bob</at> go",
+		"from":{"id":"29:abc","name":"Alice"},
+		"conversation":{"id":"19:chan@thread.tacv2;messageid=1700000000001"},
+		"channelData":{"teamsChannelId":"19:chan@thread.tacv2"}}`), &a)
+
+	m, ok := p.mention(a)
+	if !ok {
+		t.Fatal("expected a mention")
+	}
+	if m.Channel != "19:chan@thread.tacv2" || m.Thread != "1700000000001" || m.User != "29:abc" {
+		t.Errorf("mention = %+v", m)
+	}
+	if got := p.MentionUser("29:abc"); got != "**Alice**" {
+		t.Errorf("MentionUser = %q", got)
+	}
+
+	a.Type = "conversationUpdate"
+	if _, ok := p.mention(a); ok {
+		t.Error("non-message activity should be ignored")
+	}
+}
+
+func TestTeamsHandler_Signature(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	p, err := NewTeamsPlatform(base64.StdEncoding.EncodeToString(key), "http://unused")
+	if err != nil {
+		t.Fatalf("NewTeamsPlatform: %v", err)
+	}
+	h := NewTeamsHandler(p, nil, nil, nil, nil, "", "")
+	body := `{"type":"conversationUpdate"}`
+	sign := func(b string) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(b))
+		return "HMAC " + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	for _, tt := range []struct {
+		name, auth string
+		want       int
+	}{
+		{"valid", sign(body), http.StatusOK},
+		{"wrong body", sign(body + " "), http.StatusUnauthorized},
+		{"missing", "", http.StatusUnauthorized},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/teams", strings.NewReader(body))
+			req.Header.Set("Authorization", tt.auth)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestTeamsNotify(t *testing.T) {
+	var texts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var card struct {
+			Attachments []struct {
+				ContentType string `json:"contentType"`
+				Content     struct {
+					Body []struct {
+						Text string `json:"text"`
+					} `json:"body"`
+				} `json:"content"`
+			} `json:"attachments"`
+		}
+		json.NewDecoder(r.Body).Decode(&card)
+		if len(card.Attachments) == 1 && card.Attachments[0].ContentType == "application/vnd.microsoft.card.adaptive" {
+			texts = append(texts, card.Attachments[0].Content.Body[0].Text)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	p := &TeamsPlatform{webhookURL: srv.URL}
+	if err := p.Notify(context.Background(), "Here's my plan"); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if len(texts) != 1 || texts[0] != "Here's my plan" {
+		t.Errorf("posted %q", texts)
+	}
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	p.webhookURL = missing.URL
+	if err := p.Notify(context.Background(), "x"); err == nil {
+		t.Error("expected an error for a failing webhook")
+	}
+}
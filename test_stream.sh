@@ -14,7 +14,7 @@ echo "---"
 START=$(python3 -c "import time; print(int(time.time()*1000))")
 claude -p "What is 2+2?" \
   --output-format stream-json \
-  --dangerously-skip-permissions \
+  --permission-mode plan \
   --verbose \
   2>&1 | \
 while IFS= read -r line; do
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	threadJobsFile = "thread-jobs.json"
+	threadPRsFile  = "thread-prs.json"
+)
+
+// persistedThreadJob is the on-disk form of an open thread's job: enough of
+// its JobState to answer, approve or cancel it after a restart.
+type persistedThreadJob struct {
+	JobID        string       `json:"job_id"`
+	Repo         string       `json:"repo,omitempty"`
+	Task         string       `json:"task,omitempty"`
+	Phase        string       `json:"phase,omitempty"`
+	SessionID    string       `json:"session_id,omitempty"`
+	PlanFilePath string       `json:"plan_file_path,omitempty"`
+	PlanContent  string       `json:"plan_content,omitempty"`
+	Channel      string       `json:"channel"`
+	ThreadTS     string       `json:"thread_ts"`
+	Platform     string       `json:"platform,omitempty"`
+	RequestedBy  string       `json:"requested_by,omitempty"`
+	RepoDir      string       `json:"repo_dir,omitempty"`
+	BaseDir      string       `json:"base_dir,omitempty"`
+	BaseBranch   string       `json:"base_branch,omitempty"`
+	VCS          string       `json:"vcs,omitempty"` // VCSProvider.Name()
+	PRURL        string       `json:"pr_url,omitempty"`
+	PRBranch     string       `json:"pr_branch,omitempty"`
+	PRHints      *PROptions   `json:"pr_hints,omitempty"`
+	CoAuthor     *gitIdentity `json:"co_author,omitempty"`
+	Paths        []string     `json:"paths,omitempty"`
+
+	PlanPrompt     string          `json:"plan_prompt,omitempty"`
+	Clarifications []Clarification `json:"clarifications,omitempty"`
+}
+
+// threadPR is the pull request a thread's last job opened or updated, so a
+// follow-up request in the same thread can push to it instead of opening
+// another.
+type threadPR struct {
+	JobID  string `json:"job_id"`
+	Repo   string `json:"repo"`
+	URL    string `json:"url"`
+	Branch string `json:"branch"`
+
+	// Who asked for the job, e.g. a GitHub login for a job from an issue.
+	Platform    string `json:"platform,omitempty"`
+	RequestedBy string `json:"requested_by,omitempty"`
+}
+
+// saveThreadJobs writes the thread→job mapping with a snapshot of each job's
+// state. Called whenever the mapping or a job's phase changes.
+func (h *Hub) saveThreadJobs() {
+	h.threadMu.Lock()
+	jobs := make(map[string]persistedThreadJob, len(h.threadJobs))
+	for key, jobID := range h.threadJobs {
+		jobs[key] = persistedThreadJob{JobID: jobID}
+	}
+	h.threadMu.Unlock()
+
+	for key, pj := range jobs {
+		state, ok := h.GetJobState(pj.JobID)
+		if !ok {
+			continue
+		}
+		state.mu.Lock()
+		pj.Repo, pj.Task, pj.Phase = state.Repo, state.Task, string(state.Phase)
+		pj.SessionID, pj.PlanFilePath, pj.PlanContent = state.SessionID, state.PlanFilePath, state.PlanContent
+		pj.Channel, pj.ThreadTS, pj.Platform, pj.RequestedBy = state.Channel, state.ThreadTS, state.Platform, state.RequestedBy
+		pj.RepoDir, pj.BaseDir, pj.BaseBranch = state.RepoDir, state.BaseDir, state.BaseBranch
+		pj.PRURL, pj.PRBranch = state.PRURL, state.PRBranch
+		if hints := state.PRHints; hints.Draft || hints.annotations() {
+			pj.PRHints = &hints
+		}
+		pj.CoAuthor, pj.Paths = state.CoAuthor, state.Paths
+		pj.PlanPrompt, pj.Clarifications = state.PlanPrompt, slices.Clone(state.Clarifications)
+		if state.vcs != nil {
+			pj.VCS = state.vcs.Name()
+		}
+		state.mu.Unlock()
+		jobs[key] = pj
+	}
+
+	data, err := json.Marshal(jobs)
+	if err != nil {
+		slog.Error("hub: failed to marshal thread jobs", "err", err)
+		return
+	}
+	h.threadJobsSaveMu.Lock()
+	defer h.threadJobsSaveMu.Unlock()
+	path := filepath.Join(h.dataDir, threadJobsFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		slog.Error("hub: failed to write thread jobs", "err", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		slog.Error("hub: failed to rename thread jobs", "err", err)
+	}
+}
+
+// loadThreadJobs rebuilds the thread→job mapping and job states from disk.
+// Jobs the event store shows as finished are dropped. Jobs that were waiting on
+// the user are restored; jobs that were mid-step died with the process and are
+// closed with an error. Their VCS provider is re-attached by the Orchestrator.
+func (h *Hub) loadThreadJobs() {
+	path := filepath.Join(h.dataDir, threadJobsFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Error("hub: failed to load thread jobs", "err", err)
+		}
+		return
+	}
+	var jobs map[string]persistedThreadJob
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		slog.Error("hub: failed to parse thread jobs", "err", err)
+		return
+	}
+
+	restored := 0
+	for key, pj := range jobs {
+		if h.jobFinished(pj.JobID) {
+			continue
+		}
+		phase := JobPhase(pj.Phase)
+		if phase != PhaseAwaitingApproval && phase != PhaseAwaitingQuestion {
+			h.Emit(pj.JobID, JobErrorData{
+				Error: "interrupted by a restart",
+			})
+			continue
+		}
+		h.threadJobs[key] = pj.JobID
+		state := &JobState{
+			SessionID:    pj.SessionID,
+			Repo:         pj.Repo,
+			Task:         pj.Task,
+			Phase:        phase,
+			PlanFilePath: pj.PlanFilePath,
+			PlanContent:  pj.PlanContent,
+			Channel:      pj.Channel,
+			ThreadTS:     pj.ThreadTS,
+			Platform:     pj.Platform,
+			RequestedBy:  pj.RequestedBy,
+			RepoDir:      pj.RepoDir,
+			BaseDir:      pj.BaseDir,
+			BaseBranch:   pj.BaseBranch,
+			PRURL:        pj.PRURL,
+			PRBranch:     pj.PRBranch,
+			CoAuthor:     pj.CoAuthor,
+			Paths:        pj.Paths,
+			vcsName:      pj.VCS,
+
+			PlanPrompt:     pj.PlanPrompt,
+			Clarifications: pj.Clarifications,
+		}
+		if pj.PRHints != nil {
+			state.PRHints = *pj.PRHints
+		}
+		h.SetJobState(pj.JobID, state)
+		restored++
+	}
+	if restored > 0 {
+		slog.Info("hub: restored open thread jobs", "jobs", restored)
+	}
+	h.saveThreadJobs()
+}
+
+// RecordThreadPR remembers the pull request a thread's job opened or updated.
+func (h *Hub) RecordThreadPR(channel, threadTS string, pr threadPR) {
+	if h == nil || channel == "" {
+		return
+	}
+	h.threadMu.Lock()
+	h.threadPRs[channel+":"+threadTS] = pr
+	data, err := json.Marshal(h.threadPRs)
+	h.threadMu.Unlock()
+	if err != nil {
+		slog.Error("hub: failed to marshal thread PRs", "err", err)
+		return
+	}
+	h.threadJobsSaveMu.Lock()
+	defer h.threadJobsSaveMu.Unlock()
+	path := filepath.Join(h.dataDir, threadPRsFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		slog.Error("hub: failed to write thread PRs", "err", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		slog.Error("hub: failed to rename thread PRs", "err", err)
+	}
+}
+
+// ThreadPR returns the pull request recorded for a thread, if any.
+func (h *Hub) ThreadPR(channel, threadTS string) (threadPR, bool) {
+	if h == nil {
+		return threadPR{}, false
+	}
+	h.threadMu.Lock()
+	defer h.threadMu.Unlock()
+	pr, ok := h.threadPRs[channel+":"+threadTS]
+	return pr, ok
+}
+
+// PRRequester returns the user who asked for the job that opened or last
+// updated the pull request at url, and the platform they asked on, or empty
+// strings if Bob has no record of it.
+func (h *Hub) PRRequester(url string) (platform, user string) {
+	if h == nil || url == "" {
+		return "", ""
+	}
+	h.threadMu.Lock()
+	defer h.threadMu.Unlock()
+	for _, pr := range h.threadPRs {
+		if pr.URL == url {
+			return pr.Platform, pr.RequestedBy
+		}
+	}
+	return "", ""
+}
+
+// pendingIntentTTL is how long a confirmation request stays answerable.
+const pendingIntentTTL = 24 * time.Hour
+
+// pendingIntent is a parsed request waiting for the user's confirmation.
+type pendingIntent struct {
+	intent IntentResult
+	at     time.Time
+}
+
+// SetPendingIntent records an intent awaiting confirmation in a thread. It is
+// kept in memory only; after a restart the thread is simply re-parsed.
+func (h *Hub) SetPendingIntent(channel, threadTS string, intent IntentResult) {
+	if h == nil {
+		return
+	}
+	h.pendingIntents.Store(channel+":"+threadTS, pendingIntent{intent: intent, at: time.Now()})
+}
+
+// TakePendingIntent removes and returns the thread's intent awaiting
+// confirmation, if there is a recent one.
+func (h *Hub) TakePendingIntent(channel, threadTS string) (IntentResult, bool) {
+	if h == nil {
+		return IntentResult{}, false
+	}
+	v, ok := h.pendingIntents.LoadAndDelete(channel + ":" + threadTS)
+	if !ok {
+		return IntentResult{}, false
+	}
+	p := v.(pendingIntent)
+	if time.Since(p.at) > pendingIntentTTL {
+		return IntentResult{}, false
+	}
+	return p.intent, true
+}
+
+// loadThreadPRs reads the thread→PR mapping from disk.
+func (h *Hub) loadThreadPRs() {
+	data, err := os.ReadFile(filepath.Join(h.dataDir, threadPRsFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Error("hub: failed to load thread PRs", "err", err)
+		}
+		return
+	}
+	var prs map[string]threadPR
+	if err := json.Unmarshal(data, &prs); err != nil || prs == nil {
+		slog.Error("hub: failed to parse thread PRs", "err", err)
+		return
+	}
+	h.threadPRs = prs
+}
+
+// jobFinished reports whether the store holds a terminal event for the job.
+func (h *Hub) jobFinished(jobID string) bool {
+	events, err := h.store.Events(jobID)
+	if err != nil {
+		return false
+	}
+	for _, e := range events {
+		switch e.Type {
+		case EventJobCompleted, EventJobError, EventJobCancelled, EventJobExpired:
+			return true
+		}
+	}
+	return false
+}
+
+// restoreProviders re-attaches the VCS provider to job states restored from
+// disk, matched by provider name.
+func (o *Orchestrator) restoreProviders() {
+	o.hub.jobStates.Range(func(_, v any) bool {
+		state := v.(*JobState)
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		if state.vcs != nil || state.vcsName == "" {
+			return true
+		}
+		for _, p := range o.providers {
+			if p.Name() == state.vcsName {
+				state.vcs = p
+				break
+			}
+		}
+		return true
+	})
+}
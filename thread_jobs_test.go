@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHub_ThreadJobsPersistence(t *testing.T) {
+	tests := []struct {
+		name     string
+		phase    JobPhase
+		finished bool
+		want     bool
+	}{
+		{"awaiting approval is restored", PhaseAwaitingApproval, false, true},
+		{"awaiting question is restored", PhaseAwaitingQuestion, false, true},
+		{"in-flight job is dropped", PhasePlanning, false, false},
+		{"finished job is dropped", PhaseAwaitingApproval, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drainHub(t)
+			dir := t.TempDir()
+			hub1 := NewHub(dir)
+			hub1.Emit("job-1", JobStartedData{Task: "t"})
+			hub1.SetJobState("job-1", &JobState{Repo: "repo", Task: "t", Channel: "C1", ThreadTS: "ts1", Platform: "slack", SessionID: "sess",
+				PlanPrompt: "## Task\n\nt", Clarifications: []Clarification{{Question: "Which env?"}}})
+			hub1.RegisterThreadJob("C1", "ts1", "job-1")
+			hub1.SetPhase("job-1", tt.phase)
+			if tt.finished {
+				hub1.Emit("job-1", JobCompletedData{})
+			}
+			time.Sleep(50 * time.Millisecond) // let hub1 persist events
+
+			hub2 := NewHub(dir)
+			got := hub2.ActiveJobForThread("C1", "ts1")
+			if (got == "job-1") != tt.want {
+				t.Fatalf("ActiveJobForThread = %q, want restored=%v", got, tt.want)
+			}
+			if !tt.want {
+				waitIndexed(t, dir, "job-1")
+				return
+			}
+			state, ok := hub2.GetJobState("job-1")
+			if !ok {
+				t.Fatal("job state not restored")
+			}
+			if state.Phase != tt.phase || state.SessionID != "sess" || state.Platform != "slack" ||
+				state.PlanPrompt != "## Task\n\nt" || len(state.Clarifications) != 1 || state.Clarifications[0].Question != "Which env?" {
+				t.Errorf("restored state = %+v", state)
+			}
+		})
+	}
+}
+
+func TestHub_ThreadJobsUnregisterPersists(t *testing.T) {
+	drainHub(t)
+	dir := t.TempDir()
+	hub1 := NewHub(dir)
+	hub1.SetJobState("job-1", &JobState{Channel: "C1", ThreadTS: "ts1", Phase: PhaseAwaitingApproval})
+	hub1.RegisterThreadJob("C1", "ts1", "job-1")
+	hub1.UnregisterThreadJob("C1", "ts1")
+
+	hub2 := NewHub(dir)
+	if got := hub2.ActiveJobForThread("C1", "ts1"); got != "" {
+		t.Errorf("ActiveJobForThread after unregister = %q, want empty", got)
+	}
+}
+
+func TestHub_ThreadPRPersistence(t *testing.T) {
+	drainHub(t)
+	dir := t.TempDir()
+	hub1 := NewHub(dir)
+	if _, ok := hub1.ThreadPR("C1", "ts1"); ok {
+		t.Fatal("ThreadPR on a fresh hub should be empty")
+	}
+	pr := threadPR{JobID: "job-1", Repo: "web", URL: "https://github.com/org/web/pull/7", Branch: "bob/fix-login-ab12"}
+	hub1.RecordThreadPR("C1", "ts1", pr)
+
+	hub2 := NewHub(dir)
+	if got, ok := hub2.ThreadPR("C1", "ts1"); !ok || got != pr {
+		t.Errorf("ThreadPR after restart = %+v, %v; want %+v", got, ok, pr)
+	}
+	if _, ok := hub2.ThreadPR("C1", "ts2"); ok {
+		t.Error("ThreadPR for another thread should be empty")
+	}
+}
+
+func TestHub_ThreadJobsPersistPRBranch(t *testing.T) {
+	drainHub(t)
+	dir := t.TempDir()
+	hub1 := NewHub(dir)
+	hub1.SetJobState("job-2", &JobState{Channel: "C1", ThreadTS: "ts1", PRURL: "https://github.com/org/web/pull/7", PRBranch: "bob/fix-login-ab12",
+		PRHints: PROptions{Draft: true, TeamReviewers: []string{"backend"}}})
+	hub1.RegisterThreadJob("C1", "ts1", "job-2")
+	hub1.SetPhase("job-2", PhaseAwaitingApproval)
+	// Wait for the phase event to be persisted so it can't land in the
+	// temp dir while it's being removed.
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if _, err := hub1.store.Events("job-2"); err == nil {
+			break
+		}
+	}
+
+	hub2 := NewHub(dir)
+	state, ok := hub2.GetJobState("job-2")
+	if !ok {
+		t.Fatal("job state not restored")
+	}
+	if state.PRURL != "https://github.com/org/web/pull/7" || state.PRBranch != "bob/fix-login-ab12" {
+		t.Errorf("restored PR = %q %q", state.PRURL, state.PRBranch)
+	}
+	if !state.PRHints.Draft || len(state.PRHints.TeamReviewers) != 1 {
+		t.Errorf("restored PR hints = %+v", state.PRHints)
+	}
+}
+
+// waitIndexed waits until the store has indexed a finished job, so its
+// writes are done before the TempDir is removed.
+func waitIndexed(t *testing.T, dir, jobID string) {
+	t.Helper()
+	for range 100 {
+		data, _ := os.ReadFile(filepath.Join(dir, jobIndexFile))
+		var idx jobIndex
+		if json.Unmarshal(data, &idx) == nil && idx.Jobs[jobID] != nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s never indexed", jobID)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const threadVerbosityFile = "thread-verbosity.json"
+
+// threadVerbosity is how much of a job's work Bob posts to its chat thread.
+type threadVerbosity string
+
+const (
+	// threadQuiet posts only what needs an answer (plans, questions) and
+	// results, without progress messages.
+	threadQuiet threadVerbosity = "quiet"
+	// threadNormal adds one live progress message per phase. The default.
+	threadNormal threadVerbosity = "normal"
+	// threadVerbose also forwards Claude Code's commentary as it works.
+	threadVerbose threadVerbosity = "verbose"
+)
+
+// maxNarrationLines caps the Claude Code text lines buffered for a verbose
+// thread between two posts; older lines are dropped.
+const maxNarrationLines = 40
+
+// threadVerbosityTexts are the messages that set a thread's verbosity.
+var threadVerbosityTexts = map[string]threadVerbosity{
+	"be quiet":       threadQuiet,
+	"quiet":          threadQuiet,
+	"quiet mode":     threadQuiet,
+	"shh":            threadQuiet,
+	"hush":           threadQuiet,
+	"fewer updates":  threadQuiet,
+	"normal":         threadNormal,
+	"normal mode":    threadNormal,
+	"normal updates": threadNormal,
+	"verbose":        threadVerbose,
+	"be verbose":     threadVerbose,
+	"verbose mode":   threadVerbose,
+	"play by play":   threadVerbose,
+	"play-by-play":   threadVerbose,
+	"more updates":   threadVerbose,
+}
+
+// threadVerbosityQuery reports whether text asks for a verbosity, and which.
+func threadVerbosityQuery(text string) (threadVerbosity, bool) {
+	v, ok := threadVerbosityTexts[strings.ToLower(strings.Trim(strings.TrimSpace(text), ".!"))]
+	return v, ok
+}
+
+// threadVerbosityReply confirms a verbosity change.
+func threadVerbosityReply(v threadVerbosity) string {
+	switch v {
+	case threadQuiet:
+		return "OK, I'll keep quiet in this thread: just plans, questions and results. Say \"normal\" for progress updates or \"verbose\" for a play-by-play."
+	case threadVerbose:
+		return "OK, I'll post what Claude Code says as it works, along with the progress updates. Say \"normal\" to stop, or \"be quiet\" for just plans, questions and results."
+	}
+	return "OK, back to one progress message per phase in this thread."
+}
+
+// SetThreadVerbosity sets how much Bob posts to a thread, for the jobs in it
+// now and later, and persists it.
+func (h *Hub) SetThreadVerbosity(channel, threadTS string, v threadVerbosity) {
+	if h == nil || channel == "" {
+		return
+	}
+	h.threadMu.Lock()
+	if v == threadNormal {
+		delete(h.threadVerbosity, channel+":"+threadTS)
+	} else {
+		h.threadVerbosity[channel+":"+threadTS] = v
+	}
+	data, err := json.Marshal(h.threadVerbosity)
+	h.threadMu.Unlock()
+	if err != nil {
+		slog.Error("hub: failed to marshal thread verbosity", "err", err)
+		return
+	}
+	h.threadJobsSaveMu.Lock()
+	defer h.threadJobsSaveMu.Unlock()
+	path := filepath.Join(h.dataDir, threadVerbosityFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		slog.Error("hub: failed to write thread verbosity", "err", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		slog.Error("hub: failed to rename thread verbosity", "err", err)
+	}
+}
+
+// ThreadVerbosity returns how much Bob posts to a thread; threadNormal unless
+// someone asked otherwise.
+func (h *Hub) ThreadVerbosity(channel, threadTS string) threadVerbosity {
+	if h == nil {
+		return threadNormal
+	}
+	h.threadMu.Lock()
+	defer h.threadMu.Unlock()
+	if v, ok := h.threadVerbosity[channel+":"+threadTS]; ok {
+		return v
+	}
+	return threadNormal
+}
+
+// loadThreadVerbosity reads the thread verbosity settings from disk.
+func (h *Hub) loadThreadVerbosity() {
+	data, err := os.ReadFile(filepath.Join(h.dataDir, threadVerbosityFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Error("hub: failed to load thread verbosity", "err", err)
+		}
+		return
+	}
+	var m map[string]threadVerbosity
+	if err := json.Unmarshal(data, &m); err != nil || m == nil {
+		slog.Error("hub: failed to parse thread verbosity", "err", err)
+		return
+	}
+	h.threadVerbosity = m
+}
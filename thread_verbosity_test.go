@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestThreadVerbosityQuery(t *testing.T) {
+	tests := []struct {
+		text string
+		want threadVerbosity
+		ok   bool
+	}{
+		{"be quiet", threadQuiet, true},
+		{"  Be quiet! ", threadQuiet, true},
+		{"shh", threadQuiet, true},
+		{"verbose", threadVerbose, true},
+		{"Play-by-play.", threadVerbose, true},
+		{"normal", threadNormal, true},
+		{"be quiet about the logging changes", "", false},
+		{"make the logs more verbose", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := threadVerbosityQuery(tt.text)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("threadVerbosityQuery(%q) = %q, %v; want %q, %v", tt.text, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHub_ThreadVerbosity(t *testing.T) {
+	drainHub(t)
+	dir := t.TempDir()
+	hub := NewHub(dir)
+
+	if got := hub.ThreadVerbosity("C1", "1.0"); got != threadNormal {
+		t.Errorf("default = %q", got)
+	}
+	hub.SetThreadVerbosity("C1", "1.0", threadQuiet)
+	hub.SetThreadVerbosity("C1", "2.0", threadVerbose)
+	hub.SetThreadVerbosity("C1", "2.0", threadNormal)
+	if got := hub.ThreadVerbosity("C1", "1.0"); got != threadQuiet {
+		t.Errorf("after set = %q", got)
+	}
+
+	// Settings survive a restart; normal isn't stored.
+	reloaded := NewHub(dir)
+	if got := reloaded.ThreadVerbosity("C1", "1.0"); got != threadQuiet {
+		t.Errorf("reloaded = %q", got)
+	}
+	if got := reloaded.ThreadVerbosity("C1", "2.0"); got != threadNormal {
+		t.Errorf("reset thread = %q", got)
+	}
+	if len(reloaded.threadVerbosity) != 1 {
+		t.Errorf("stored %v", reloaded.threadVerbosity)
+	}
+}
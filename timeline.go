@@ -0,0 +1,243 @@
+package main
+
+import (
+	"time"
+)
+
+// maxTimelineInput caps a span's input in the timeline.
+const maxTimelineInput = 200
+
+// timelineSpan is one bar of a job's timeline.
+type timelineSpan struct {
+	Name       string    `json:"name"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	DurationMs int64     `json:"duration_ms"`
+	Input      string    `json:"input,omitempty"`
+	IsError    bool      `json:"is_error,omitempty"`
+	Open       bool      `json:"open,omitempty"`  // still running; End is the time of the request
+	Step       string    `json:"step,omitempty"`  // tools and agents: the step whose session made the call
+	Agent      string    `json:"agent,omitempty"` // tools: the sub-agent that made the call, by ID
+}
+
+// agentSpan is a Claude Code sub-agent in the timeline, named by its
+// description.
+type agentSpan struct {
+	timelineSpan
+	ID         string         `json:"id"`
+	ParentID   string         `json:"parent_id,omitempty"`
+	AgentType  string         `json:"agent_type,omitempty"`
+	ToolCalls  map[string]int `json:"tool_calls"` // by tool name; matches Tools with this Agent
+	TotalCalls int            `json:"total_calls"`
+}
+
+// jobTimeline is the response of GET /api/jobs/{id}/timeline: where a job's
+// time went, computed from its events.
+type jobTimeline struct {
+	JobID      string    `json:"job_id"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	DurationMs int64     `json:"duration_ms"`
+	Running    bool      `json:"running,omitempty"`
+
+	// Phases covers the job's whole life, one span per phase it was in, in
+	// order: planning, awaiting_approval, implementing...
+	Phases []timelineSpan `json:"phases"`
+	// Steps are Bob's own steps: clone_repo, generate_plan,
+	// implement_changes, run_tests, create_pull_request...
+	Steps []timelineSpan `json:"steps"`
+	// Tools are Claude Code's tool calls. A call's span runs until the
+	// session's next output, so it is approximate, and calls batched into one
+	// claude_code_lines event (BOB_EVENT_COALESCE_MS) all but the last show
+	// as zero-length.
+	Tools []timelineSpan `json:"tools"`
+	// Agents are the sub-agents Claude Code started with its Task tool, in
+	// the order they started; several can run at once.
+	Agents []agentSpan `json:"agents"`
+
+	// StepTotalsMs and ToolTotalsMs sum the spans by name, e.g. the time spent
+	// cloning, planning, implementing and opening the PR, or in Bash.
+	StepTotalsMs map[string]int64 `json:"step_totals_ms"`
+	ToolTotalsMs map[string]int64 `json:"tool_totals_ms"`
+}
+
+// buildTimeline computes a job's timeline from its events, in order. Spans
+// still open at the last event end at now if the job is running.
+func buildTimeline(jobID string, events []Event, now time.Time) jobTimeline {
+	tl := jobTimeline{
+		JobID:        jobID,
+		Phases:       []timelineSpan{},
+		Steps:        []timelineSpan{},
+		Tools:        []timelineSpan{},
+		Agents:       []agentSpan{},
+		StepTotalsMs: map[string]int64{},
+		ToolTotalsMs: map[string]int64{},
+	}
+	if len(events) == 0 {
+		return tl
+	}
+	tl.Start = events[0].Timestamp
+	tl.Running = true
+
+	var phase *timelineSpan
+	startPhase := func(name string, at time.Time) {
+		if phase != nil {
+			if phase.Name == name {
+				return
+			}
+			tl.Phases = append(tl.Phases, closeSpan(*phase, at))
+		}
+		phase = &timelineSpan{Name: name, Start: at}
+	}
+	openSteps := map[string][]timelineSpan{} // name → started, innermost last
+	var stepOrder []string                   // names of open steps, innermost last
+	var tool *timelineSpan                   // the last Claude Code call, open until the next event
+	agents := map[string]int{}               // agent ID → index in tl.Agents, while running
+	countCall := func(line ClaudeCodeLineData) {
+		if i, ok := agents[line.AgentID]; ok && line.ToolName != "" {
+			tl.Agents[i].ToolCalls[line.ToolName]++
+			tl.Agents[i].TotalCalls++
+		}
+	}
+
+	for _, e := range events {
+		if tool != nil {
+			tl.Tools = append(tl.Tools, closeSpan(*tool, e.Timestamp))
+			tool = nil
+		}
+		switch d := e.Data.(type) {
+		case JobStartedData:
+			startPhase(d.Phase, e.Timestamp)
+		case PhaseChangedData:
+			startPhase(d.Phase, e.Timestamp)
+			tl.Running = true // a finished job can be picked up again
+		case ToolStartedData:
+			tl.Running = true
+			openSteps[d.ToolName] = append(openSteps[d.ToolName], timelineSpan{Name: d.ToolName, Start: e.Timestamp, Input: truncate(d.Input, maxTimelineInput)})
+			stepOrder = append(stepOrder, d.ToolName)
+		case ToolCompletedData:
+			span := timelineSpan{Name: d.ToolName, Start: e.Timestamp.Add(-time.Duration(d.DurationMs) * time.Millisecond)}
+			if started := openSteps[d.ToolName]; len(started) > 0 {
+				span = started[len(started)-1]
+				openSteps[d.ToolName] = started[:len(started)-1]
+				for i := len(stepOrder) - 1; i >= 0; i-- {
+					if stepOrder[i] == d.ToolName {
+						stepOrder = append(stepOrder[:i], stepOrder[i+1:]...)
+						break
+					}
+				}
+			}
+			span = closeSpan(span, e.Timestamp)
+			if d.DurationMs > 0 {
+				span.DurationMs = d.DurationMs // measured by the step itself
+			}
+			span.IsError = d.IsError
+			tl.Steps = append(tl.Steps, span)
+		case ClaudeCodeLineData:
+			tool = toolSpan(d, e.Timestamp, stepOrder)
+			countCall(d)
+		case ClaudeCodeLinesData:
+			for _, line := range d.Lines {
+				if tool != nil {
+					tl.Tools = append(tl.Tools, closeSpan(*tool, e.Timestamp))
+				}
+				tool = toolSpan(line, e.Timestamp, stepOrder)
+				countCall(line)
+			}
+		case AgentStartedData:
+			span := agentSpan{
+				timelineSpan: timelineSpan{Name: d.Description, Start: e.Timestamp},
+				ID:           d.AgentID,
+				ParentID:     d.ParentID,
+				AgentType:    d.AgentType,
+				ToolCalls:    map[string]int{},
+			}
+			if len(stepOrder) > 0 {
+				span.Step = stepOrder[len(stepOrder)-1]
+			}
+			agents[d.AgentID] = len(tl.Agents)
+			tl.Agents = append(tl.Agents, span)
+		case AgentFinishedData:
+			if i, ok := agents[d.AgentID]; ok {
+				delete(agents, d.AgentID)
+				a := &tl.Agents[i]
+				a.timelineSpan = closeSpan(a.timelineSpan, e.Timestamp)
+				if d.DurationMs > 0 {
+					a.DurationMs = d.DurationMs // measured by the parser
+				}
+				a.IsError = d.IsError
+				if d.ToolCalls != nil {
+					a.ToolCalls, a.TotalCalls = d.ToolCalls, d.totalToolCalls()
+				}
+			}
+		case JobCompletedData, JobErrorData, JobCancelledData, JobExpiredData:
+			if phase != nil {
+				tl.Phases = append(tl.Phases, closeSpan(*phase, e.Timestamp))
+				phase = nil
+			}
+			tl.Running = false
+			tl.End = e.Timestamp
+		}
+	}
+
+	// Events after the terminal one (e.g. checks_completed) don't extend the job.
+	end := tl.End
+	if tl.Running {
+		end = now
+	}
+	if tool != nil {
+		tl.Tools = append(tl.Tools, openSpan(*tool, end, tl.Running))
+	}
+	for _, i := range agents {
+		tl.Agents[i].timelineSpan = openSpan(tl.Agents[i].timelineSpan, end, tl.Running)
+	}
+	for _, name := range stepOrder {
+		started := openSteps[name]
+		tl.Steps = append(tl.Steps, openSpan(started[len(started)-1], end, tl.Running))
+		openSteps[name] = started[:len(started)-1]
+	}
+	if phase != nil {
+		tl.Phases = append(tl.Phases, openSpan(*phase, end, tl.Running))
+	}
+	tl.End = end
+	tl.DurationMs = end.Sub(tl.Start).Milliseconds()
+
+	for _, s := range tl.Steps {
+		tl.StepTotalsMs[s.Name] += s.DurationMs
+	}
+	for _, s := range tl.Tools {
+		tl.ToolTotalsMs[s.Name] += s.DurationMs
+	}
+	return tl
+}
+
+// toolSpan starts a span for a Claude Code tool call, or returns nil for
+// other lines. The call is attributed to the innermost open step.
+func toolSpan(line ClaudeCodeLineData, at time.Time, stepOrder []string) *timelineSpan {
+	if line.ToolName == "" {
+		return nil
+	}
+	span := &timelineSpan{Name: line.ToolName, Start: at, Input: truncate(line.ToolInput, maxTimelineInput), Agent: line.AgentID}
+	if len(stepOrder) > 0 {
+		span.Step = stepOrder[len(stepOrder)-1]
+	}
+	return span
+}
+
+// closeSpan ends s at end.
+func closeSpan(s timelineSpan, end time.Time) timelineSpan {
+	if end.Before(s.Start) {
+		end = s.Start
+	}
+	s.End = end
+	s.DurationMs = end.Sub(s.Start).Milliseconds()
+	return s
+}
+
+// openSpan ends a span that never finished at end, marking it open if the
+// job is still running.
+func openSpan(s timelineSpan, end time.Time, running bool) timelineSpan {
+	s = closeSpan(s, end)
+	s.Open = running
+	return s
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildTimeline(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(s int) time.Time { return t0.Add(time.Duration(s) * time.Second) }
+	events := []Event{
+		{Timestamp: at(0), Type: EventJobStarted, Data: JobStartedData{Phase: string(PhasePlanning)}},
+		{Timestamp: at(0), Type: EventToolStarted, Data: ToolStartedData{ToolName: "clone_repo", Input: "web"}},
+		{Timestamp: at(5), Type: EventToolCompleted, Data: ToolCompletedData{ToolName: "clone_repo", DurationMs: 5000}},
+		{Timestamp: at(5), Type: EventToolStarted, Data: ToolStartedData{ToolName: "generate_plan"}},
+		{Timestamp: at(6), Type: EventClaudeCodeLine, Data: ClaudeCodeLineData{ToolName: "Read", ToolInput: `{"file_path":"a.go"}`}},
+		{Timestamp: at(9), Type: EventClaudeCodeLines, Data: ClaudeCodeLinesData{Lines: []ClaudeCodeLineData{{Text: "thinking"}, {ToolName: "Bash"}}}},
+		{Timestamp: at(20), Type: EventToolCompleted, Data: ToolCompletedData{ToolName: "generate_plan"}},
+		{Timestamp: at(20), Type: EventPhaseChanged, Data: PhaseChangedData{Phase: string(PhaseAwaitingApproval)}},
+		{Timestamp: at(60), Type: EventPhaseChanged, Data: PhaseChangedData{Phase: string(PhaseImplementing)}},
+		{Timestamp: at(60), Type: EventToolStarted, Data: ToolStartedData{ToolName: "implement_changes"}},
+		{Timestamp: at(100), Type: EventToolCompleted, Data: ToolCompletedData{ToolName: "implement_changes", IsError: true}},
+		{Timestamp: at(101), Type: EventJobError, Data: JobErrorData{Error: "boom"}},
+		{Timestamp: at(500), Type: EventChecksCompleted, Data: ChecksCompletedData{}},
+	}
+	tl := buildTimeline("job-1", events, at(1000))
+
+	if tl.Running || tl.DurationMs != 101000 || !tl.End.Equal(at(101)) {
+		t.Errorf("running = %v, duration = %d, end = %v", tl.Running, tl.DurationMs, tl.End)
+	}
+	wantPhases := []struct {
+		name string
+		ms   int64
+	}{{string(PhasePlanning), 20000}, {string(PhaseAwaitingApproval), 40000}, {string(PhaseImplementing), 41000}}
+	if len(tl.Phases) != len(wantPhases) {
+		t.Fatalf("phases = %+v", tl.Phases)
+	}
+	for i, w := range wantPhases {
+		if tl.Phases[i].Name != w.name || tl.Phases[i].DurationMs != w.ms {
+			t.Errorf("phase %d = %s %dms, want %s %dms", i, tl.Phases[i].Name, tl.Phases[i].DurationMs, w.name, w.ms)
+		}
+	}
+	for name, ms := range map[string]int64{"clone_repo": 5000, "generate_plan": 15000, "implement_changes": 40000} {
+		if tl.StepTotalsMs[name] != ms {
+			t.Errorf("step %s = %dms, want %d", name, tl.StepTotalsMs[name], ms)
+		}
+	}
+	if s := tl.Steps[2]; s.Name != "implement_changes" || !s.IsError {
+		t.Errorf("step = %+v", s)
+	}
+	if len(tl.Tools) != 2 || tl.Tools[0].Name != "Read" || tl.Tools[0].DurationMs != 3000 || tl.Tools[0].Step != "generate_plan" {
+		t.Fatalf("tools = %+v", tl.Tools)
+	}
+	if tl.ToolTotalsMs["Bash"] != 11000 {
+		t.Errorf("Bash = %dms, want 11000", tl.ToolTotalsMs["Bash"])
+	}
+}
+
+func TestBuildTimeline_Running(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Timestamp: t0, Type: EventJobStarted, Data: JobStartedData{Phase: string(PhasePlanning)}},
+		{Timestamp: t0, Type: EventToolStarted, Data: ToolStartedData{ToolName: "generate_plan"}},
+	}
+	tl := buildTimeline("job-1", events, t0.Add(time.Minute))
+	if !tl.Running || tl.DurationMs != 60000 {
+		t.Errorf("running = %v, duration = %d", tl.Running, tl.DurationMs)
+	}
+	if len(tl.Steps) != 1 || !tl.Steps[0].Open || tl.Steps[0].DurationMs != 60000 {
+		t.Errorf("steps = %+v", tl.Steps)
+	}
+	if len(tl.Phases) != 1 || !tl.Phases[0].Open {
+		t.Errorf("phases = %+v", tl.Phases)
+	}
+}
+
+func TestBuildTimeline_Agents(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(s int) time.Time { return t0.Add(time.Duration(s) * time.Second) }
+	events := []Event{
+		{Timestamp: at(0), Type: EventJobStarted, Data: JobStartedData{Phase: string(PhasePlanning)}},
+		{Timestamp: at(0), Type: EventToolStarted, Data: ToolStartedData{ToolName: "generate_plan"}},
+		{Timestamp: at(1), Type: EventAgentStarted, Data: AgentStartedData{AgentID: "t1", Description: "find handlers", AgentType: "Explore"}},
+		{Timestamp: at(1), Type: EventAgentStarted, Data: AgentStartedData{AgentID: "t2", Description: "read tests"}},
+		{Timestamp: at(2), Type: EventClaudeCodeLines, Data: ClaudeCodeLinesData{Lines: []ClaudeCodeLineData{
+			{ToolName: "Grep", AgentID: "t1"}, {ToolName: "Read", AgentID: "t2"}, {Text: "looking", AgentID: "t1"},
+		}}},
+		{Timestamp: at(4), Type: EventClaudeCodeLine, Data: ClaudeCodeLineData{ToolName: "Read", AgentID: "t1"}},
+		{Timestamp: at(11), Type: EventAgentFinished, Data: AgentFinishedData{AgentID: "t1", DurationMs: 9500, ToolCalls: map[string]int{"Grep": 1, "Read": 1}}},
+	}
+	tl := buildTimeline("job-1", events, at(30))
+
+	if len(tl.Agents) != 2 {
+		t.Fatalf("agents = %+v", tl.Agents)
+	}
+	a := tl.Agents[0]
+	if a.ID != "t1" || a.Name != "find handlers" || a.AgentType != "Explore" || a.Step != "generate_plan" || a.Open ||
+		a.DurationMs != 9500 || a.TotalCalls != 2 || a.ToolCalls["Grep"] != 1 {
+		t.Errorf("finished agent = %+v", a)
+	}
+	if a := tl.Agents[1]; !a.Open || a.DurationMs != 29000 || a.TotalCalls != 1 || a.ToolCalls["Read"] != 1 {
+		t.Errorf("running agent = %+v", a)
+	}
+	if len(tl.Tools) != 3 || tl.Tools[0].Agent != "t1" || tl.Tools[1].Agent != "t2" {
+		t.Errorf("tools = %+v", tl.Tools)
+	}
+}
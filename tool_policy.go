@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Session phases, each with its own tool rules.
+const (
+	toolPhasePlan      = "plan"      // planning sessions; plan mode writes only the plan file
+	toolPhaseRead      = "read"      // PR reviews and questions about the code
+	toolPhaseImplement = "implement" // sessions that change files: implementation and fixes
+)
+
+// ToolRules are the Claude Code permission rules for a session, passed as
+// --allowedTools and --disallowedTools. Rules use the CLI's syntax, e.g.
+// "Read" or "Bash(go test:*)". A denied tool can't be used even if allowed.
+type ToolRules struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// defaultToolRules keep planning and reading sessions read-only, and
+// implementation sessions to file edits without network access or deletes.
+// Tools that are neither allowed nor denied would need a permission prompt,
+// which a non-interactive session refuses.
+var defaultToolRules = map[string]ToolRules{
+	toolPhasePlan: {
+		Allow: []string{"Read", "Glob", "Grep", "LS", "Task", "TodoWrite"},
+		Deny:  []string{"Edit", "MultiEdit", "NotebookEdit", "Bash"},
+	},
+	toolPhaseRead: {
+		Allow: []string{"Read", "Glob", "Grep", "LS", "Task", "TodoWrite"},
+		Deny:  []string{"Edit", "MultiEdit", "NotebookEdit", "Write", "Bash", "ExitPlanMode"},
+	},
+	toolPhaseImplement: {
+		Allow: []string{"Read", "Glob", "Grep", "LS", "Task", "TodoWrite", "Edit", "MultiEdit", "Write", "NotebookEdit"},
+		Deny:  []string{"WebFetch", "WebSearch", "Bash(rm:*)", "Bash(curl:*)", "Bash(wget:*)", "Bash(git push:*)"},
+	},
+}
+
+// toolPolicy is the BOB_TOOL_POLICY_FILE format: rules by phase, each
+// replacing that phase's defaults.
+//
+//	implement:
+//	  allow: [Read, Glob, Grep, Edit, Write, "Bash(go test:*)"]
+//	  deny: [WebFetch, WebSearch, "Bash(rm:*)"]
+type toolPolicy map[string]ToolRules
+
+// loadToolPolicy reads a tool policy file.
+func loadToolPolicy(path string) (toolPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p toolPolicy
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := checkToolPhases(p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// checkToolPhases rejects rules for phases that don't exist, which would
+// otherwise be ignored without a word.
+func checkToolPhases(rules map[string]ToolRules) error {
+	for phase := range rules {
+		if _, ok := defaultToolRules[phase]; !ok {
+			return fmt.Errorf("unknown tool phase %q (want plan, read or implement)", phase)
+		}
+	}
+	return nil
+}
+
+// rules returns the phase's rules, from the policy or the defaults.
+func (p toolPolicy) rules(phase string) ToolRules {
+	if r, ok := p[phase]; ok {
+		return r
+	}
+	return defaultToolRules[phase]
+}
+
+// toolRules returns the tool rules for a session in phase: the policy's, plus
+// any the repo's .bob.yml adds. Since denials win, a repo can allow more
+// tools but never one the policy denies.
+func (o *Orchestrator) toolRules(cfg *RepoConfig, phase string) ToolRules {
+	r := o.tools.rules(phase)
+	if cfg == nil {
+		return r
+	}
+	extra := cfg.Tools[phase]
+	return ToolRules{
+		Allow: slices.Concat(r.Allow, extra.Allow),
+		Deny:  slices.Concat(r.Deny, extra.Deny),
+	}
+}
+
+// args returns the CLI flags for the rules.
+func (r ToolRules) args() []string {
+	var args []string
+	if len(r.Allow) > 0 {
+		args = append(append(args, "--allowedTools"), r.Allow...)
+	}
+	if len(r.Deny) > 0 {
+		args = append(append(args, "--disallowedTools"), r.Deny...)
+	}
+	return args
+}
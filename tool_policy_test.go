@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadToolPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"phases", "implement:\n  allow: [Read, Edit]\n  deny: [\"Bash(rm:*)\"]\n", ""},
+		{"unknown phase", "deploy:\n  deny: [Bash]\n", `unknown tool phase "deploy"`},
+		{"misspelled field", "plan:\n  denied: [Bash]\n", "field denied not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tools.yml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := loadToolPolicy(path)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestToolRules(t *testing.T) {
+	o := &Orchestrator{tools: toolPolicy{
+		toolPhaseImplement: {Allow: []string{"Read", "Edit"}, Deny: []string{"Bash(rm:*)"}},
+	}}
+	cfg := &RepoConfig{Tools: map[string]ToolRules{
+		toolPhaseImplement: {Allow: []string{"Bash(make test:*)", "Bash(rm:*)"}},
+	}}
+
+	got := o.toolRules(cfg, toolPhaseImplement)
+	want := ToolRules{Allow: []string{"Read", "Edit", "Bash(make test:*)", "Bash(rm:*)"}, Deny: []string{"Bash(rm:*)"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("implement rules = %+v, want %+v", got, want)
+	}
+	// Phases the policy leaves out keep their defaults.
+	if got := o.toolRules(nil, toolPhasePlan); !reflect.DeepEqual(got, defaultToolRules[toolPhasePlan]) {
+		t.Errorf("plan rules = %+v, want the defaults", got)
+	}
+}
+
+func TestRunSession_ToolRules(t *testing.T) {
+	// A fake claude CLI that reports its arguments as the result.
+	bin := t.TempDir()
+	script := `#!/bin/sh
+args=""
+for a in "$@"; do
+  case "$a" in -p) skip=1; continue ;; esac
+  if [ -n "$skip" ]; then skip=""; continue; fi
+  args="$args|$a"
+done
+printf '{"type":"result","subtype":"success","result":"%s"}\n' "$args"
+`
+	if err := os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	sr, err := RunSession(context.Background(), "", nil, "", SessionOpts{
+		RepoDir: t.TempDir(), Prompt: "plan it", PermissionMode: "plan",
+		Tools: ToolRules{Allow: []string{"Read", "Grep"}, Deny: []string{"Bash(git diff:*)"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "|--permission-mode|plan|--allowedTools|Read|Grep|--disallowedTools|Bash(git diff:*)|"
+	if !strings.Contains(sr.ResultText+"|", want) {
+		t.Errorf("args = %q, want them to include %q", sr.ResultText, want)
+	}
+}
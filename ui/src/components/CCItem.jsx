@@ -13,6 +13,8 @@ export function CCItem({ item }) {
       return <div class="cc-error">&nbsp;<span>{item.text}</span></div>;
     case "agents":
       return <AgentsItem count={item.count} agents={item.agents} />;
+    case "agent":
+      return <AgentItem item={item} />;
     case "tool":
       return <ToolItem data={item.data} />;
     case "read-group":
@@ -71,6 +73,38 @@ function AgentsItem({ count, agents }) {
   );
 }
 
+function AgentItem({ item }) {
+  const calls = Object.values(item.toolCalls || {}).reduce((n, c) => n + c, 0);
+  let status = "running\u2026";
+  if (!item.running) {
+    status = (item.isError ? "failed after " : "finished in ") + fmtDuration(item.durationMs);
+  }
+  return (
+    <details class="cc-agents">
+      <summary>
+        Sub-agent{item.agentType ? " (" + item.agentType + ")" : ""}: {item.description}
+        <span class={item.isError ? "cc-agent-status cc-agent-err" : "cc-agent-status"}>
+          {calls} tool call{calls !== 1 ? "s" : ""} &middot; {status}
+        </span>
+      </summary>
+      <div class="cc-agents-body">
+        {item.activity.map((l, i) => (
+          <div key={i} class={l.kind === "error" ? "cc-agents-item cc-agent-err" : "cc-agents-item"}>
+            {l.kind === "tool" ? (
+              <>
+                <span class="cc-nm">{l.name}</span>
+                {l.arg && <span class="cc-arg"> {l.arg}</span>}
+              </>
+            ) : (
+              l.text
+            )}
+          </div>
+        ))}
+      </div>
+    </details>
+  );
+}
+
 function ToolItem({ data }) {
   const name = data.tool_name || "";
   if (name === "ExitPlanMode" || name === "EnterPlanMode") return null;
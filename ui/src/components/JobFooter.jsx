@@ -1,7 +1,8 @@
 import { h as esc } from "../lib/html.js";
 import { fmtCost, fmtDuration } from "../lib/format.js";
+import { artifactURL } from "../lib/api.js";
 
-export function JobFooter({ isError, data }) {
+export function JobFooter({ jobId, isError, data }) {
   const d = data || {};
   const icon = isError ? "\u2717" : "\u2713";
   const msg = isError ? d.error || "Job failed" : d.final_response || "Done";
@@ -18,6 +19,15 @@ export function JobFooter({ isError, data }) {
       <span class="job-footer-icon">{icon}</span>
       <span class="job-footer-msg">{msg}</span>
       {meta && <span class="job-footer-meta">{meta}</span>}
+      {jobId && d.artifacts && d.artifacts.length > 0 && (
+        <span class="job-footer-artifacts">
+          {d.artifacts.map((name) => (
+            <a key={name} href={artifactURL(jobId, name)} target="_blank" rel="noopener">
+              {name}
+            </a>
+          ))}
+        </span>
+      )}
     </div>
   );
 }
@@ -2,9 +2,12 @@ import { h } from "../lib/html.js";
 import { fmtCost } from "../lib/format.js";
 import { PhaseBadge } from "./PhaseBadge.jsx";
 
-export function JobHeader({ taskText, slackURL, prLink, jobCostUSD, currentPhase, isLive }) {
+export function JobHeader({ taskText, slackURL, requestedBy, prLink, jobCostUSD, currentPhase, isLive }) {
   const meta = [];
 
+  if (requestedBy) {
+    meta.push(<span>Requested by {requestedBy}</span>);
+  }
   if (slackURL) {
     meta.push(
       <a href={slackURL} target="_blank">
@@ -4,6 +4,7 @@ const LABELS = {
   planning: "Planning",
   awaiting_approval: "Awaiting Approval",
   awaiting_question: "Awaiting Question",
+  awaiting_push: "Awaiting Push",
   implementing: "Implementing",
   done: "Done",
 };
@@ -0,0 +1,52 @@
+import { useState } from "preact/hooks";
+import { pushJob, artifactURL } from "../lib/api.js";
+import "../styles/approve.css";
+import "../styles/cc.css";
+
+// PushDiff shows a dry run's changes with a button to push them.
+export function PushDiff({ jobId, status, approvedBy, diff, truncated }) {
+  const [submitting, setSubmitting] = useState(false);
+
+  const handleClick = () => {
+    if (!jobId) return;
+    setSubmitting(true);
+    pushJob(jobId).catch(() => setSubmitting(false));
+  };
+
+  return (
+    <div class="push-wrap">
+      <div class="push-hdr">
+        Dry run diff
+        {jobId && (
+          <a href={artifactURL(jobId, "changes.diff")} target="_blank" rel="noopener">
+            changes.diff
+          </a>
+        )}
+      </div>
+      <pre class="push-diff">
+        {(diff || "").split("\n").map((l, i) => (
+          <div
+            key={i}
+            class={
+              l.startsWith("+") && !l.startsWith("+++")
+                ? "dl dl-add"
+                : l.startsWith("-") && !l.startsWith("---")
+                ? "dl dl-del"
+                : "dl"
+            }
+          >
+            {l}
+          </div>
+        ))}
+        {truncated && <div class="dl dl-sep">&hellip; truncated &hellip;</div>}
+      </pre>
+      {status === "pending" && (
+        <button class="approve-btn" disabled={submitting} onClick={handleClick}>
+          {submitting ? "Pushing\u2026" : "Push Changes"}
+        </button>
+      )}
+      {status === "pushed" && <div class="approve-done">{"\u2713"} Pushed by {approvedBy}</div>}
+      {status === "superseded" && <div class="superseded-label">Revision requested</div>}
+    </div>
+  );
+}
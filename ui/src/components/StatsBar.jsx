@@ -1,8 +1,8 @@
 import { fmtCost, fmtTokens } from "../lib/format.js";
 
-export function StatsBar({ jobs, stats }) {
+export function StatsBar({ total, stats }) {
   const parts = [];
-  parts.push(jobs.length + " job" + (jobs.length !== 1 ? "s" : ""));
+  parts.push(total + " job" + (total !== 1 ? "s" : ""));
   if (stats) {
     if (stats.total_cost_usd)
       parts.push(fmtCost(stats.total_cost_usd) + " spent");
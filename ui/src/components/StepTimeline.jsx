@@ -2,10 +2,11 @@ import { StepRow } from "./StepRow.jsx";
 import { CCSection } from "./CCSection.jsx";
 import { QuestionCard } from "./QuestionCard.jsx";
 import { ApproveButton } from "./ApproveButton.jsx";
+import { PushDiff } from "./PushDiff.jsx";
 import { JobFooter } from "./JobFooter.jsx";
 import { slackURL } from "../state/job.js";
 
-export function StepTimeline({ items }) {
+export function StepTimeline({ items, jobId }) {
   const elements = [];
 
   for (let i = 0; i < items.length; i++) {
@@ -22,6 +23,7 @@ export function StepTimeline({ items }) {
             items[j].type === "cc-section" ||
             items[j].type === "step" ||
             items[j].type === "approve" ||
+            items[j].type === "push" ||
             items[j].type === "question" ||
             items[j].type === "footer"
           ) {
@@ -64,9 +66,29 @@ export function StepTimeline({ items }) {
           />
         );
         break;
+      case "push":
+        elements.push(
+          <PushDiff
+            key={"push-" + i}
+            jobId={jobId}
+            status={item.status}
+            approvedBy={item.approvedBy}
+            diff={item.diff}
+            truncated={item.truncated}
+          />
+        );
+        break;
       case "footer":
         elements.push(
-          <JobFooter key={"footer-" + i} isError={item.isError} data={item.data} />
+          <JobFooter key={"footer-" + i} jobId={jobId} isError={item.isError} data={item.data} />
+        );
+        break;
+      case "llm-thinking":
+        elements.push(
+          <details key={"think-" + i} class="cc-thinking">
+            <summary>Thinking &middot; {item.label || "LLM call"}</summary>
+            <pre class="cc-thinking-body">{item.text}</pre>
+          </details>
         );
         break;
       default:
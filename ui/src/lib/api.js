@@ -19,8 +19,16 @@ export function tokenQueryParam() {
   return "token=" + encodeURIComponent(apiToken);
 }
 
-export async function fetchJobs() {
-  const r = await fetch("/api/jobs", { headers: authHeaders() });
+// fetchJobs returns one page of jobs: { jobs, total, next_cursor }.
+// params may hold status, repo, since, limit and cursor.
+export async function fetchJobs(params = {}) {
+  const qs = new URLSearchParams();
+  for (const [k, v] of Object.entries(params)) {
+    if (v) qs.set(k, v);
+  }
+  const q = qs.toString();
+  const r = await fetch("/api/jobs" + (q ? "?" + q : ""), { headers: authHeaders() });
+  if (!r.ok) throw new Error("Failed to load jobs");
   return r.json();
 }
 
@@ -39,6 +47,12 @@ export async function fetchJobEvents(id) {
   return r.json();
 }
 
+// artifactURL links to one of a job's artifact files (diff, transcript, test log).
+export function artifactURL(id, name) {
+  const q = tokenQueryParam();
+  return "/api/jobs/" + encodeURIComponent(id) + "/artifacts/" + encodeURIComponent(name) + (q ? "?" + q : "");
+}
+
 export async function approveJob(id) {
   const r = await fetch("/api/jobs/" + encodeURIComponent(id) + "/approve", {
     method: "POST",
@@ -46,3 +60,11 @@ export async function approveJob(id) {
   });
   return r.json();
 }
+
+export async function pushJob(id) {
+  const r = await fetch("/api/jobs/" + encodeURIComponent(id) + "/push", {
+    method: "POST",
+    headers: authHeaders(),
+  });
+  return r.json();
+}
@@ -2,6 +2,7 @@ import {
   prLink,
   taskText,
   slackURL,
+  requestedBy,
   jobCostUSD,
   currentJobID,
   currentPhase,
@@ -19,6 +20,7 @@ let pendingThinking = []; // [{thinking, ts}] — duration resolved on next non-
 let codeBlock = null; // {lang, lines}
 let ccIdx = -1;
 let ccLabel = "Claude Code";
+let agentIdx = {}; // agent_id → index of its "agent" item
 
 export function resetEventState() {
   pendingReads = [];
@@ -27,6 +29,7 @@ export function resetEventState() {
   codeBlock = null;
   ccIdx = -1;
   ccLabel = "Claude Code";
+  agentIdx = {};
 }
 
 function flushReads() {
@@ -100,6 +103,17 @@ function pushItem(item) {
   items.value = [...items.value, item];
 }
 
+/** Replace the sub-agent item for id with update(item). */
+function updateAgent(id, update) {
+  const idx = agentIdx[id];
+  const cur = [...items.value];
+  cur[idx] = update(cur[idx]);
+  items.value = cur;
+}
+
+/** Maximum activity lines kept per sub-agent. */
+const maxAgentActivity = 200;
+
 /** Determine the short argument string for a tool call. */
 function toolArg(name, input) {
   const keys = ["file_path", "command", "pattern", "path", "glob", "query", "description"];
@@ -122,6 +136,14 @@ export { toolArg };
 export function addEvt(ev) {
   const d = ev.data || {};
 
+  // Coalesced output lines (BOB_EVENT_COALESCE_MS) replay as single lines.
+  if (ev.type === "claude_code_lines") {
+    for (const line of d.lines || []) {
+      addEvt({ ...ev, type: "claude_code_line", data: line });
+    }
+    return;
+  }
+
   // Flush pending buffers on non-cc-line events.
   if (ev.type !== "claude_code_line") {
     flushReads();
@@ -157,6 +179,7 @@ export function addEvt(ev) {
   if (ev.type === "job_started") {
     taskText.value = d.task || "";
     slackURL.value = d.slack_thread_url || "";
+    requestedBy.value = d.requested_by_name || d.requested_by || "";
     currentJobID.value = ev.job_id || "";
     return;
   }
@@ -181,11 +204,30 @@ export function addEvt(ev) {
     return;
   }
 
+  // diff_ready (dry run)
+  if (ev.type === "diff_ready") {
+    pushItem({ type: "push", status: "pending", diff: d.diff || "", truncated: !!d.truncated });
+    return;
+  }
+
+  // push_approved
+  if (ev.type === "push_approved") {
+    const cur = [...items.value];
+    for (let i = cur.length - 1; i >= 0; i--) {
+      if (cur[i].type === "push" && cur[i].status === "pending") {
+        cur[i] = { ...cur[i], status: "pushed", approvedBy: d.approved_by || "unknown" };
+        break;
+      }
+    }
+    items.value = cur;
+    return;
+  }
+
   // plan_superseded
   if (ev.type === "plan_superseded") {
     const cur = [...items.value];
     for (let i = cur.length - 1; i >= 0; i--) {
-      if (cur[i].type === "approve" && cur[i].status === "pending") {
+      if ((cur[i].type === "approve" || cur[i].type === "push") && cur[i].status === "pending") {
         cur[i] = { ...cur[i], status: "superseded" };
         break;
       }
@@ -211,6 +253,12 @@ export function addEvt(ev) {
     return;
   }
 
+  // Extended thinking of an orchestration LLM call (BOB_LLM_THINKING_BUDGET).
+  if (ev.type === "llm_response" && d.thinking) {
+    pushItem({ type: "llm-thinking", label: d.summary || "", text: d.thinking });
+    return;
+  }
+
   // Skip internal plumbing events.
   if (
     ev.type === "slack_notification" ||
@@ -220,8 +268,58 @@ export function addEvt(ev) {
     return;
   }
 
+  // Sub-agent (Task tool) started: its lines are grouped under one item.
+  if (ev.type === "agent_started") {
+    agentIdx[d.agent_id] = items.value.length;
+    pushCCItem({
+      type: "agent",
+      id: d.agent_id,
+      description: d.description || "",
+      agentType: d.agent_type || "",
+      running: true,
+      toolCalls: {},
+      activity: [],
+    });
+    return;
+  }
+
+  // Sub-agent finished.
+  if (ev.type === "agent_finished") {
+    if (agentIdx[d.agent_id] === undefined) return;
+    updateAgent(d.agent_id, (a) => ({
+      ...a,
+      running: false,
+      durationMs: d.duration_ms,
+      toolCalls: d.tool_calls || a.toolCalls,
+      isError: !!d.is_error,
+    }));
+    delete agentIdx[d.agent_id];
+    return;
+  }
+
   // Claude Code output line.
   if (ev.type === "claude_code_line") {
+    // A running sub-agent's line goes into its item.
+    if (d.agent_id && agentIdx[d.agent_id] !== undefined) {
+      updateAgent(d.agent_id, (a) => {
+        const toolCalls = { ...a.toolCalls };
+        let line = null;
+        if (d.tool_name) {
+          toolCalls[d.tool_name] = (toolCalls[d.tool_name] || 0) + 1;
+          let input = {};
+          try { input = JSON.parse(d.tool_input || "{}"); } catch {}
+          line = { kind: "tool", name: d.tool_name, arg: toolArg(d.tool_name, input) };
+        } else if (d.tool_error !== undefined) {
+          line = { kind: "error", text: d.tool_error };
+        } else if ((d.text || "").trim()) {
+          line = { kind: "text", text: d.text.trim() };
+        }
+        const activity = line ? [...a.activity, line].slice(-maxAgentActivity) : a.activity;
+        return { ...a, toolCalls, activity };
+      });
+      return;
+    }
+
     // AskUserQuestion renders as a top-level question card.
     if (d.tool_name === "AskUserQuestion") {
       let aqInput = {};
@@ -439,9 +537,9 @@ export function addEvt(ev) {
 
   // job_completed
   if (ev.type === "job_completed") {
-    // Remove pending approve buttons.
+    // Remove pending approve and push buttons.
     items.value = items.value.map((it) =>
-      it.type === "approve" && it.status === "pending"
+      (it.type === "approve" || it.type === "push") && it.status === "pending"
         ? { ...it, status: "removed" }
         : it
     );
@@ -452,7 +550,7 @@ export function addEvt(ev) {
   // job_error
   if (ev.type === "job_error") {
     items.value = items.value.map((it) =>
-      it.type === "approve" && it.status === "pending"
+      (it.type === "approve" || it.type === "push") && it.status === "pending"
         ? { ...it, status: "removed" }
         : it
     );
@@ -5,6 +5,7 @@ import {
   prLink,
   taskText,
   slackURL,
+  requestedBy,
   jobCostUSD,
   currentJobID,
   currentPhase,
@@ -69,14 +70,15 @@ describe("addEvt", () => {
 
   // — job_started —
 
-  it("job_started sets taskText, slackURL, currentJobID", () => {
+  it("job_started sets taskText, slackURL, requestedBy, currentJobID", () => {
     addEvt({
       type: "job_started",
       job_id: "j1",
-      data: { task: "fix bug", slack_thread_url: "https://slack/t" },
+      data: { task: "fix bug", slack_thread_url: "https://slack/t", requested_by: "U1", requested_by_name: "Ada" },
     });
     expect(taskText.value).toBe("fix bug");
     expect(slackURL.value).toBe("https://slack/t");
+    expect(requestedBy.value).toBe("Ada");
     expect(currentJobID.value).toBe("j1");
   });
 
@@ -243,6 +245,59 @@ describe("addEvt", () => {
     expect(textItems[0].text).toBe("Hello world");
   });
 
+  // — claude_code_lines —
+
+  it("claude_code_lines adds each batched line", () => {
+    addEvt({ type: "tool_started", data: { tool_name: "implement_changes" } });
+    addEvt({
+      type: "claude_code_lines",
+      data: { lines: [{ text: "First" }, { tool_name: "Edit", tool_input: '{"file_path":"/workspace/r/f.js"}' }] },
+    });
+    expect(items.value.filter((i) => i.type === "text").map((i) => i.text)).toEqual(["First"]);
+    expect(items.value.filter((i) => i.type === "tool")).toHaveLength(1);
+  });
+
+  // — llm_response with thinking —
+
+  it("llm_response with thinking pushes an llm-thinking item and still counts cost", () => {
+    addEvt({ type: "llm_response", data: { summary: "intent parsed", thinking: "The user wants…", cost_usd: 0.02 } });
+    expect(items.value).toEqual([{ type: "llm-thinking", label: "intent parsed", text: "The user wants…" }]);
+    expect(jobCostUSD.value).toBeCloseTo(0.02);
+  });
+
+  // — agent_started / agent_finished —
+
+  it("groups a sub-agent's lines under its agent item", () => {
+    addEvt({ type: "tool_started", data: { tool_name: "generate_plan" } });
+    addEvt({ type: "agent_started", data: { agent_id: "t1", description: "find handlers", agent_type: "Explore" } });
+    addEvt({
+      type: "claude_code_lines",
+      data: {
+        lines: [
+          { tool_name: "Grep", tool_input: '{"pattern":"handler"}', agent_id: "t1" },
+          { text: "Main session", agent_id: "" },
+          { tool_error: "no such file", agent_id: "t1" },
+        ],
+      },
+    });
+    addEvt({ type: "agent_finished", data: { agent_id: "t1", duration_ms: 4200, tool_calls: { Grep: 1 } } });
+
+    const agents = items.value.filter((i) => i.type === "agent");
+    expect(agents).toHaveLength(1);
+    expect(agents[0]).toMatchObject({ description: "find handlers", agentType: "Explore", running: false, durationMs: 4200, toolCalls: { Grep: 1 } });
+    expect(agents[0].activity.map((l) => l.kind)).toEqual(["tool", "error"]);
+    expect(items.value.filter((i) => i.type === "text").map((i) => i.text)).toEqual(["Main session"]);
+  });
+
+  // — diff_ready / push_approved —
+
+  it("diff_ready adds a pending push item that push_approved completes", () => {
+    addEvt({ type: "diff_ready", data: { diff: "+a" } });
+    expect(items.value[0]).toMatchObject({ type: "push", status: "pending", diff: "+a" });
+    addEvt({ type: "push_approved", data: { approved_by: "web UI" } });
+    expect(items.value[0]).toMatchObject({ status: "pushed", approvedBy: "web UI" });
+  });
+
   // — claude_code_line with tool_name —
 
   it("claude_code_line with tool_name pushes tool CC item", () => {
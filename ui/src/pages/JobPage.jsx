@@ -6,6 +6,7 @@ import {
   prLink,
   taskText,
   slackURL,
+  requestedBy,
   jobCostUSD,
   isLive,
   currentPhase,
@@ -32,7 +33,12 @@ export function JobPage({ id }) {
       .then((evts) => {
         (evts || []).forEach(addEvt);
 
+        // Resume the stream after the last fetched event so nothing emitted in
+        // between is missed. Reconnects resume via Last-Event-ID.
         let evtURL = "/events?job=" + encodeURIComponent(id);
+        if (evts && evts.length) {
+          evtURL += "&after=" + encodeURIComponent(evts[evts.length - 1].id);
+        }
         const tqp = tokenQueryParam();
         if (tqp) evtURL += "&" + tqp;
         const es = new EventSource(evtURL);
@@ -78,12 +84,13 @@ export function JobPage({ id }) {
       <JobHeader
         taskText={taskText.value}
         slackURL={slackURL.value}
+        requestedBy={requestedBy.value}
         prLink={prLink.value}
         jobCostUSD={jobCostUSD.value}
         currentPhase={currentPhase.value}
         isLive={isLive.value}
       />
-      <StepTimeline items={items.value} />
+      <StepTimeline items={items.value} jobId={id} />
       {prLink.value && (
         <a
           href={prLink.value}
@@ -10,18 +10,27 @@ const PHASE_LABELS = {
   planning: "Planning",
   awaiting_approval: "Awaiting Approval",
   awaiting_question: "Awaiting Question",
+  awaiting_push: "Awaiting Push",
   implementing: "Implementing",
 };
 
+const PAGE_SIZE = 50;
+
 const jobs = signal([]);
+const total = signal(0);
+const older = signal([]); // pages loaded with "Load more", kept across refreshes
+const nextCursor = signal("");
 const stats = signal(null);
 const loaded = signal(false);
 const error = signal(false);
 
+// loadOverview refreshes the first page; older pages stay as loaded.
 async function loadOverview() {
   try {
-    const [j, s] = await Promise.all([fetchJobs(), fetchStats()]);
-    jobs.value = j || [];
+    const [page, s] = await Promise.all([fetchJobs({ limit: PAGE_SIZE }), fetchStats()]);
+    jobs.value = page.jobs || [];
+    total.value = page.total || 0;
+    if (!older.value.length) nextCursor.value = page.next_cursor || "";
     stats.value = s;
     loaded.value = true;
     error.value = false;
@@ -30,6 +39,22 @@ async function loadOverview() {
   }
 }
 
+async function loadMore() {
+  try {
+    const page = await fetchJobs({ limit: PAGE_SIZE, cursor: nextCursor.value });
+    older.value = [...older.value, ...(page.jobs || [])];
+    nextCursor.value = page.next_cursor || "";
+  } catch {
+    error.value = true;
+  }
+}
+
+// allJobs merges the refreshed first page with older pages, without duplicates.
+function allJobs() {
+  const seen = new Set(jobs.value.map((j) => j.id));
+  return [...jobs.value, ...older.value.filter((j) => !seen.has(j.id))];
+}
+
 export function OverviewPage() {
   useEffect(() => {
     document.title = "Bob";
@@ -57,15 +82,16 @@ export function OverviewPage() {
       <div class="page-head">
         <h1 class="page-title">Jobs</h1>
       </div>
-      <StatsBar jobs={jobs.value} stats={stats.value} />
+      <StatsBar total={total.value} stats={stats.value} />
       <div class="job-list">
-        {jobs.value.map((j) => (
+        {allJobs().map((j) => (
           <a key={j.id} class="job-row" href={"/jobs/" + encodeURIComponent(j.id)}>
             <StatusPip status={j.status} phase={j.phase} />
             <span class="job-row-task">{j.task || "(untitled)"}</span>
             {j.status === "running" && j.phase && PHASE_LABELS[j.phase] && (
               <span class="job-row-phase">{PHASE_LABELS[j.phase]}</span>
             )}
+            {j.requested_by_name && <span class="job-row-user">{j.requested_by_name}</span>}
             {j.cost_usd ? (
               <span class="job-row-cost">{fmtCost(j.cost_usd)}</span>
             ) : null}
@@ -73,6 +99,11 @@ export function OverviewPage() {
           </a>
         ))}
       </div>
+      {nextCursor.value && (
+        <button class="load-more" onClick={loadMore}>
+          Load more
+        </button>
+      )}
     </div>
   );
 }
@@ -4,6 +4,7 @@ export const autoScroll = signal(true);
 export const prLink = signal(null);
 export const taskText = signal("");
 export const slackURL = signal("");
+export const requestedBy = signal(""); // display name of the user who asked for the job
 export const jobCostUSD = signal(0);
 export const isLive = signal(false);
 export const currentJobID = signal("");
@@ -20,6 +21,7 @@ export function resetJobState() {
   prLink.value = null;
   taskText.value = "";
   slackURL.value = "";
+  requestedBy.value = "";
   jobCostUSD.value = 0;
   isLive.value = false;
   currentJobID.value = "";
@@ -1,8 +1,43 @@
 package main
 
+import "unicode/utf8"
+
+// truncate shortens s to at most n bytes, marking the cut with "...". It never
+// splits a multibyte character.
 func truncate(s string, n int) string {
 	if len(s) <= n {
 		return s
 	}
-	return s[:n] + "..."
+	return prefix(s, n) + "..."
+}
+
+// prefix returns the longest prefix of s that is at most n bytes and ends on
+// a character boundary.
+func prefix(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// suffix returns the longest suffix of s that is at most n bytes and starts
+// on a character boundary.
+func suffix(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	start := len(s) - n
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return s[start:]
 }
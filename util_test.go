@@ -1,6 +1,9 @@
 package main
 
-import "testing"
+import (
+	"testing"
+	"unicode/utf8"
+)
 
 func TestTruncate(t *testing.T) {
 	tests := []struct {
@@ -14,6 +17,8 @@ func TestTruncate(t *testing.T) {
 		{"exactly n", "hello", 5, "hello"},
 		{"one over n", "hello!", 5, "hello..."},
 		{"n=0 non-empty", "hello", 0, "..."},
+		{"multibyte rune at the cut", "héllo", 2, "h..."},
+		{"emoji", "ok 👍👍", 5, "ok ..."},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -24,3 +29,29 @@ func TestTruncate(t *testing.T) {
 		})
 	}
 }
+
+func TestSuffix(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		n    int
+		want string
+	}{
+		{"shorter than n", "hello", 10, "hello"},
+		{"one over n", "hello!", 5, "ello!"},
+		{"n=0 non-empty", "hello", 0, ""},
+		{"multibyte rune at the cut", "olléh", 2, "h"},
+		{"emoji", "👍👍 ok", 6, " ok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := suffix(tt.s, tt.n)
+			if got != tt.want || !utf8.ValidString(got) {
+				t.Errorf("suffix(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+			}
+		})
+	}
+	if got := tailTruncate("FAIL: résumé", 6); got != "...\nsumé" {
+		t.Errorf("tailTruncate = %q, want the cut after the é", got)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ErrNoChanges means there is nothing to propose: no changed files to commit,
+// or no commits between the branch and its base.
+var ErrNoChanges = errors.New("no changes to propose")
+
+// VCSProvider abstracts a code hosting service: repository lookup, authenticated
+// git remotes, and opening pull (or merge) requests. Plain git operations stay
+// provider-agnostic in git.go.
+type VCSProvider interface {
+	// Name identifies the provider in logs and events (e.g. "github", "gitlab").
+	Name() string
+	// FindRepo checks whether the named repository exists.
+	FindRepo(ctx context.Context, name string) (repo, error)
+	// FetchURL returns a git remote URL with embedded credentials.
+	FetchURL(name string) string
+	// CleanURL returns the credential-free remote URL stored in .git/config.
+	CleanURL(name string) string
+	// Token returns the credential embedded in FetchURL, for redacting git output.
+	Token() string
+	// OpenPullRequest opens a pull/merge request from branch into base and returns its web URL.
+	// A draft request can't be merged until it is marked ready. If one is already open
+	// for branch, its title and body are updated and its URL returned instead. It returns
+	// ErrNoChanges if branch has no commits over base.
+	OpenPullRequest(ctx context.Context, name, branch, base, title, body string, draft bool) (string, error)
+}
+
+// PROptions control how a new pull request is opened, from the repo's
+// .bob.yml and hints in the request ("open as draft, tag @backend-team").
+type PROptions struct {
+	Draft         bool     `json:"draft,omitempty"`
+	Reviewers     []string `json:"reviewers,omitempty"`      // usernames; "org/team" requests a team (GitHub)
+	TeamReviewers []string `json:"team_reviewers,omitempty"` // team slugs (GitHub)
+	Labels        []string `json:"labels,omitempty"`
+	Assignees     []string `json:"assignees,omitempty"`
+}
+
+// maxPROptionNames caps each list, since intent hints come from free text.
+const maxPROptionNames = 10
+
+// merge returns o combined with other: draft if either is, and the union of
+// each list without duplicates.
+func (o PROptions) merge(other PROptions) PROptions {
+	return PROptions{
+		Draft:         o.Draft || other.Draft,
+		Reviewers:     mergeNames(o.Reviewers, other.Reviewers),
+		TeamReviewers: mergeNames(o.TeamReviewers, other.TeamReviewers),
+		Labels:        mergeNames(o.Labels, other.Labels),
+		Assignees:     mergeNames(o.Assignees, other.Assignees),
+	}
+}
+
+// annotations reports whether any option has to be set after the PR is open.
+func (o PROptions) annotations() bool {
+	return len(o.Reviewers) > 0 || len(o.TeamReviewers) > 0 || len(o.Labels) > 0 || len(o.Assignees) > 0
+}
+
+// mergeNames concatenates name lists, trimming a leading "@" from each name
+// and dropping blanks and duplicates, up to maxPROptionNames names.
+func mergeNames(lists ...[]string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		for _, n := range list {
+			n = strings.TrimPrefix(strings.TrimSpace(n), "@")
+			if n == "" || seen[n] || len(out) == maxPROptionNames {
+				continue
+			}
+			seen[n] = true
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// splitRepoName splits an "owner/repo" name. owner is empty for a bare name.
+func splitRepoName(name string) (owner, short string) {
+	name = strings.Trim(name, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return path.Base(name[:i]), name[i+1:]
+	}
+	return "", name
+}
+
+// findRepo looks the repository up in each provider in order and returns the
+// first provider that has it. This lets one Bob serve repos split across hosts.
+// Repos excluded by repoRules are never found.
+func findRepo(ctx context.Context, providers []VCSProvider, name string) (VCSProvider, repo, error) {
+	if !repoRules.Load().permits(name) {
+		return nil, repo{}, errRepoNotPermitted(name)
+	}
+	var lastErr error
+	for _, p := range providers {
+		r, err := p.FindRepo(ctx, name)
+		if err == nil && r.Name != "" && !repoRules.Load().permits(r.Name) {
+			err = errRepoNotPermitted(r.Name) // matched loosely to an excluded repo
+		}
+		if err == nil {
+			return p, r, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no VCS providers configured")
+	}
+	return nil, repo{}, lastErr
+}
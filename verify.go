@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// testTimeout bounds a single run of a repo's test command.
+const testTimeout = 10 * time.Minute
+
+// maxTestOutput is how much of the test output (from the end, where failures
+// are summarized) is kept for the fix prompt and the PR body.
+const maxTestOutput = 8000
+
+// detectTestCommand picks the repo's test command from its build files and
+// returns it with the file it was inferred from, or empty strings if none is
+// recognized.
+func detectTestCommand(repoDir string) (command, source string) {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(repoDir, name))
+		return err == nil
+	}
+	switch {
+	case hasMakeTarget(filepath.Join(repoDir, "Makefile"), "test"):
+		return "make test", "Makefile"
+	case exists("go.mod"):
+		return "go test ./...", "go.mod"
+	case exists("Cargo.toml"):
+		return "cargo test", "Cargo.toml"
+	case hasNPMTestScript(filepath.Join(repoDir, "package.json")):
+		switch {
+		case exists("pnpm-lock.yaml"):
+			return "pnpm test", "package.json"
+		case exists("yarn.lock"):
+			return "yarn test", "package.json"
+		case exists("bun.lock"), exists("bun.lockb"):
+			return "bun run test", "package.json"
+		}
+		return "npm test", "package.json"
+	case exists("pyproject.toml"):
+		switch {
+		case exists("uv.lock"):
+			return "uv run pytest", "pyproject.toml"
+		case exists("poetry.lock"):
+			return "poetry run pytest", "pyproject.toml"
+		}
+		return "python -m pytest", "pyproject.toml"
+	}
+	return "", ""
+}
+
+// testCommandFor returns the command that verifies changes in repoDir: cfg's
+// test_command, or the detected one. source says where it came from.
+func testCommandFor(repoDir string, cfg *RepoConfig) (command, source string) {
+	if cfg != nil && cfg.TestCommand != "" {
+		return cfg.TestCommand, repoConfigFile
+	}
+	return detectTestCommand(repoDir)
+}
+
+// testCommandPreamble tells Claude Code how the repo is tested, so it doesn't
+// have to guess.
+func testCommandPreamble(command string) string {
+	if command == "" {
+		return ""
+	}
+	return fmt.Sprintf("## Tests\n\nThis repo's tests run with `%s`; Bob runs it after you finish.\n\n", command)
+}
+
+// hasNPMTestScript reports whether the package.json at path defines a real
+// test script (not npm init's "no test specified" placeholder).
+func hasNPMTestScript(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return false
+	}
+	test := pkg.Scripts["test"]
+	return test != "" && !strings.Contains(test, "no test specified")
+}
+
+// hasMakeTarget reports whether the Makefile at path defines target.
+func hasMakeTarget(path, target string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.HasPrefix(scanner.Text(), target+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// runTests runs command in repoDir (inside sb's container, if set) and returns its combined output (tail-truncated, secrets redacted)
+// and whether it passed. err is only set if the command could not be run at all.
+// The untruncated output is also written to full, if set.
+func runTests(ctx context.Context, sb *sandboxSpec, repoDir, command string, full io.Writer) (output string, passed bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, testTimeout)
+	defer cancel()
+
+	cmd := sb.repoCommand(ctx, repoDir, []string{"CI=true"}, "sh", "-c", command)
+	out, runErr := cmd.CombinedOutput()
+	redacted := redactSecrets(string(out))
+	if full != nil {
+		fmt.Fprintf(full, "$ %s\n%s\n", command, redacted)
+	}
+	output = tailTruncate(redacted, maxTestOutput)
+
+	var exitErr *exec.ExitError
+	switch {
+	case runErr == nil:
+		return output, true, nil
+	case ctx.Err() == context.DeadlineExceeded:
+		return output + fmt.Sprintf("\n\n(test command timed out after %s)", testTimeout), false, nil
+	case sb != nil && errors.As(runErr, &exitErr) && exitErr.ExitCode() == dockerRunFailed:
+		// The container never ran (e.g. the image couldn't be pulled); that's
+		// not a test failure for Claude Code to fix.
+		return output, false, fmt.Errorf("docker run %s: %s", sb.image, truncate(strings.TrimSpace(redacted), 300))
+	case errors.As(runErr, &exitErr) && ctx.Err() == nil:
+		return output, false, nil
+	default:
+		return output, false, runErr
+	}
+}
+
+// tailTruncate keeps the end of s, at most max bytes of it, marking the cut
+// with "...". It never splits a multibyte character.
+func tailTruncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return "...\n" + suffix(s, max)
+}
+
+// verifyResult is the outcome of the test-and-fix loop.
+type verifyResult struct {
+	Command  string // test command; empty if none was detected (verification skipped)
+	Source   string // where Command came from: .bob.yml or the build file it was detected from
+	Passed   bool
+	Attempts int    // fix sessions run
+	Output   string // last test output when failing
+	Skipped  string // why the detected command wasn't run, if it wasn't
+}
+
+// verifyChanges runs the repo's tests (cfg's test_command, or the detected
+// one) after implementation. On failure, the output is fed to a fresh Claude
+// Code session to fix, up to o.testFixRetries times. It only returns an error if the tests or a fix session could not run.
+func (o *Orchestrator) verifyChanges(ctx context.Context, jobID, repo, repoDir, task, planContent string, cfg *RepoConfig) (verifyResult, error) {
+	var vr verifyResult
+	var testLog io.Writer
+	vr.Command, vr.Source = testCommandFor(repoDir, cfg)
+	sb, testSB := o.sandboxFor(repo, cfg), o.testSandboxFor(repo, cfg)
+	if vr.Command == "" {
+		slog.InfoContext(ctx, "orchestrator: no test command detected, skipping verification", "job_id", jobID)
+		return vr, nil
+	}
+	if testSB == nil && (!o.sandbox.allowsHostTests() || o.sandbox.repoNetwork() != "") {
+		// The command comes from the repo; on the host it would run with
+		// Bob's network, and without the opt-in, his tokens.
+		slog.WarnContext(ctx, "orchestrator: no sandbox for the tests, skipping verification", "job_id", jobID, "command", vr.Command)
+		vr.Skipped = fmt.Sprintf("`%s` was not run: without BOB_SANDBOX_IMAGE or a test_image, tests only run if BOB_ALLOW_HOST_TESTS is set.", vr.Command)
+		if o.sandbox.repoNetwork() != "" {
+			vr.Skipped = fmt.Sprintf("`%s` was not run: BOB_SANDBOX_TEST_NETWORK is set, and without BOB_SANDBOX_IMAGE or a test_image the tests would run on the host's network.", vr.Command)
+		}
+		o.hub.Emit(jobID, ToolStartedData{ToolName: "run_tests", Input: vr.Command, Source: vr.Source})
+		o.hub.Emit(jobID, ToolCompletedData{ToolName: "run_tests", ResultPreview: vr.Skipped})
+		vr.Command = ""
+		return vr, nil
+	}
+	slog.InfoContext(ctx, "orchestrator: testing", "job_id", jobID, "command", vr.Command, "source", vr.Source)
+	if f := o.hub.appendArtifact(jobID, artifactTestLog); f != nil {
+		defer f.Close()
+		testLog = f
+	}
+
+	for {
+		o.hub.Emit(jobID, ToolStartedData{ToolName: "run_tests", Input: vr.Command, Source: vr.Source})
+		testStart := time.Now()
+		output, passed, err := runTests(ctx, testSB, repoDir, vr.Command, testLog)
+		preview := "tests passed"
+		if err != nil {
+			preview = err.Error()
+		} else if !passed {
+			preview = truncate(tailTruncate(output, 300), 300)
+		}
+		o.hub.Emit(jobID, ToolCompletedData{
+			ToolName: "run_tests", IsError: err != nil || !passed,
+			ResultPreview: preview, DurationMs: time.Since(testStart).Milliseconds(),
+		})
+		if err != nil {
+			return vr, fmt.Errorf("run tests: %w", err)
+		}
+		vr.Passed, vr.Output = passed, output
+		if passed || vr.Attempts >= o.testFixRetries {
+			return vr, nil
+		}
+		if _, over := o.overBudget(jobID); over {
+			return vr, nil // the caller aborts the job
+		}
+
+		vr.Attempts++
+		slog.InfoContext(ctx, "orchestrator: tests failed, attempting a fix", "job_id", jobID, "attempt", vr.Attempts, "max_attempts", o.testFixRetries)
+		prompt := fmt.Sprintf("## Task\n\n%s\n\n## Approved Plan\n\n%s\n\n## Failing test output (`%s`)\n\n```\n%s\n```",
+			task, planContent, vr.Command, output)
+		o.hub.Emit(jobID, ToolStartedData{ToolName: "fix_tests", Input: fmt.Sprintf("attempt %d", vr.Attempts)})
+		fixStart := time.Now()
+		sr, err := RunSession(ctx, o.claudeCodeToken, o.hub, jobID, SessionOpts{
+			RepoDir:        repoDir,
+			Prompt:         prompt,
+			SystemPrompt:   fixTestsSystemPrompt,
+			PermissionMode: "acceptEdits",
+			Tools:          o.toolRules(cfg, toolPhaseImplement),
+			Sandbox:        sb,
+			Limits:         o.sessionLimits(ctx, jobID, repo),
+		})
+		isErr := err != nil || sr.IsError
+		preview = ""
+		if err != nil {
+			preview = err.Error()
+		} else {
+			preview = sr.ResultText
+		}
+		o.hub.Emit(jobID, ToolCompletedData{
+			ToolName: "fix_tests", IsError: isErr,
+			ResultPreview: truncate(preview, 300), DurationMs: time.Since(fixStart).Milliseconds(),
+		})
+		if err != nil {
+			return vr, fmt.Errorf("fix tests: %w", err)
+		}
+	}
+}
+
+// prBodyNote returns a note for the PR body when tests still fail after the
+// fix attempts, or empty string otherwise.
+func (vr verifyResult) prBodyNote() string {
+	if vr.Command == "" || vr.Passed {
+		return ""
+	}
+	return fmt.Sprintf("> **Warning:** `%s` still fails after %d fix attempt(s).\n\n<details><summary>Test output</summary>\n\n```\n%s\n```\n\n</details>\n\n",
+		vr.Command, vr.Attempts, vr.Output)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectTestCommand(t *testing.T) {
+	tests := []struct {
+		name       string
+		files      map[string]string
+		want, from string
+	}{
+		{"none", nil, "", ""},
+		{"go module", map[string]string{"go.mod": "module x\n"}, "go test ./...", "go.mod"},
+		{"makefile test target wins", map[string]string{"go.mod": "module x\n", "Makefile": "build:\n\tgo build\ntest:\n\tgo test -race ./...\n"}, "make test", "Makefile"},
+		{"makefile without test target", map[string]string{"Makefile": "build:\n\tgo build\n"}, "", ""},
+		{"cargo", map[string]string{"Cargo.toml": "[package]\n"}, "cargo test", "Cargo.toml"},
+		{"npm", map[string]string{"package.json": `{"scripts":{"test":"vitest run"}}`}, "npm test", "package.json"},
+		{"pnpm", map[string]string{"package.json": `{"scripts":{"test":"jest"}}`, "pnpm-lock.yaml": ""}, "pnpm test", "package.json"},
+		{"yarn", map[string]string{"package.json": `{"scripts":{"test":"jest"}}`, "yarn.lock": ""}, "yarn test", "package.json"},
+		{"npm init placeholder", map[string]string{"package.json": `{"scripts":{"test":"echo \"Error: no test specified\" && exit 1"}}`}, "", ""},
+		{"package.json without tests falls through", map[string]string{"package.json": `{}`, "pyproject.toml": ""}, "python -m pytest", "pyproject.toml"},
+		{"uv", map[string]string{"pyproject.toml": "", "uv.lock": ""}, "uv run pytest", "pyproject.toml"},
+		{"poetry", map[string]string{"pyproject.toml": "", "poetry.lock": ""}, "poetry run pytest", "pyproject.toml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got, from := detectTestCommand(dir); got != tt.want || from != tt.from {
+				t.Errorf("detectTestCommand = %q, %q; want %q, %q", got, from, tt.want, tt.from)
+			}
+		})
+	}
+}
+
+func TestTestCommandFor(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if cmd, from := testCommandFor(dir, nil); cmd != "go test ./..." || from != "go.mod" {
+		t.Errorf("detected = %q, %q", cmd, from)
+	}
+	if cmd, from := testCommandFor(dir, &RepoConfig{TestCommand: "make check"}); cmd != "make check" || from != repoConfigFile {
+		t.Errorf("configured = %q, %q", cmd, from)
+	}
+	if got := testCommandPreamble(""); got != "" {
+		t.Errorf("empty preamble = %q", got)
+	}
+	if got := testCommandPreamble("make check"); !strings.Contains(got, "`make check`") {
+		t.Errorf("preamble = %q", got)
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	dir := t.TempDir()
+
+	out, passed, err := runTests(context.Background(), nil, dir, "echo ok", nil)
+	if err != nil || !passed || !strings.Contains(out, "ok") {
+		t.Errorf("passing command: out=%q passed=%v err=%v", out, passed, err)
+	}
+
+	var full strings.Builder
+	out, passed, err = runTests(context.Background(), nil, dir, "echo FAIL: TestX; exit 1", &full)
+	if err != nil || passed || !strings.Contains(out, "FAIL: TestX") {
+		t.Errorf("failing command: out=%q passed=%v err=%v", out, passed, err)
+	}
+	if got := full.String(); got != "$ echo FAIL: TestX; exit 1\nFAIL: TestX\n\n" {
+		t.Errorf("full output = %q", got)
+	}
+}
+
+func TestRunTests_HostEnv(t *testing.T) {
+	t.Setenv("BOB_API_TOKEN", "hunter2")
+	t.Setenv("GOFLAGS", "-mod=mod")
+	out, passed, err := runTests(context.Background(), nil, t.TempDir(), `echo "token=[$BOB_API_TOKEN] goflags=[$GOFLAGS] ci=[$CI]"`, nil)
+	if err != nil || !passed {
+		t.Fatalf("passed=%v err=%v", passed, err)
+	}
+	if !strings.Contains(out, "token=[] goflags=[-mod=mod] ci=[true]") {
+		t.Errorf("out = %q, want no token but the toolchain's variables", out)
+	}
+}
+
+func TestVerifyChanges_NoHostTests(t *testing.T) {
+	drainHub(t)
+	dir := gitRepo(t, map[string]string{"go.mod": "module example.com/x\n\ngo 1.25\n"})
+	cfg := &RepoConfig{TestCommand: "touch ran"}
+
+	o := &Orchestrator{hub: NewHub(t.TempDir())}
+	vr, err := o.verifyChanges(context.Background(), "job-1", "api", dir, "task", "plan", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vr.Command != "" || !strings.Contains(vr.Skipped, "BOB_ALLOW_HOST_TESTS") {
+		t.Errorf("vr = %+v, want the tests skipped", vr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ran")); !os.IsNotExist(err) {
+		t.Errorf("test command ran on the host without opting in (err %v)", err)
+	}
+
+	// Opted in, but the network can't be restricted on the host.
+	o.sandbox = NewSandbox("", "ws", nil, true, "none")
+	vr, err = o.verifyChanges(context.Background(), "job-2", "api", dir, "task", "plan", cfg)
+	if err != nil || vr.Command != "" || !strings.Contains(vr.Skipped, "BOB_SANDBOX_TEST_NETWORK") {
+		t.Errorf("opted in with a test network: vr = %+v, err = %v; want the tests skipped", vr, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ran")); !os.IsNotExist(err) {
+		t.Errorf("test command ran on the host's network (err %v)", err)
+	}
+
+	o.sandbox = NewSandbox("", "ws", nil, true, "")
+	vr, err = o.verifyChanges(context.Background(), "job-3", "api", dir, "task", "plan", cfg)
+	if err != nil || !vr.Passed || vr.Skipped != "" {
+		t.Fatalf("opted in: vr = %+v, err = %v", vr, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ran")); err != nil {
+		t.Errorf("test command didn't run on the host after opting in: %v", err)
+	}
+}
+
+func TestRunTests_Container(t *testing.T) {
+	bin := t.TempDir()
+	// A fake docker that records its arguments and fails to pull "missing:1".
+	script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(bin, "args") + "\ncase \"$*\" in *missing:1*) echo 'Unable to find image' >&2; exit 125;; esac\necho ok\n"
+	if err := os.WriteFile(filepath.Join(bin, "docker"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	dir := t.TempDir()
+
+	out, passed, err := runTests(context.Background(), &sandboxSpec{image: "golang:1.23", volume: "bob_workspace"}, dir, "go test ./...", nil)
+	if err != nil || !passed || !strings.Contains(out, "ok") {
+		t.Errorf("out=%q passed=%v err=%v", out, passed, err)
+	}
+	args, _ := os.ReadFile(filepath.Join(bin, "args"))
+	if !strings.Contains(string(args), "-e CI golang:1.23 sh -c go test ./...") {
+		t.Errorf("docker args = %q", args)
+	}
+
+	if _, _, err := runTests(context.Background(), &sandboxSpec{image: "missing:1", volume: "bob_workspace"}, dir, "go test ./...", nil); err == nil || !strings.Contains(err.Error(), "Unable to find image") {
+		t.Errorf("image that can't be pulled: err = %v", err)
+	}
+}
+
+func TestTailTruncate(t *testing.T) {
+	if got := tailTruncate("abc", 5); got != "abc" {
+		t.Errorf("short = %q", got)
+	}
+	if got := tailTruncate("abcdefgh", 3); got != "...\nfgh" {
+		t.Errorf("long = %q", got)
+	}
+}
+
+func TestVerifyResultPRBodyNote(t *testing.T) {
+	if note := (verifyResult{}).prBodyNote(); note != "" {
+		t.Errorf("no command: note = %q", note)
+	}
+	if note := (verifyResult{Command: "go test ./...", Passed: true}).prBodyNote(); note != "" {
+		t.Errorf("passed: note = %q", note)
+	}
+	note := (verifyResult{Command: "go test ./...", Attempts: 2, Output: "FAIL"}).prBodyNote()
+	if !strings.Contains(note, "still fails after 2 fix attempt(s)") || !strings.Contains(note, "FAIL") {
+		t.Errorf("failing: note = %q", note)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// waitPolicy says when jobs waiting on the user (a question, plan approval or
+// a dry run's push) are nudged and closed. Zero fields don't apply.
+type waitPolicy struct {
+	RemindAfter time.Duration // post a reminder in the thread after this long without activity
+	ExpireAfter time.Duration // close the job with job_expired after this long
+}
+
+func (p waitPolicy) enabled() bool { return p.RemindAfter > 0 || p.ExpireAfter > 0 }
+
+// waitingFor describes what a job in phase is waiting for, or "" if it isn't
+// waiting on the user.
+func waitingFor(phase JobPhase) string {
+	switch phase {
+	case PhaseAwaitingQuestion:
+		return "an answer to my question"
+	case PhaseAwaitingApproval:
+		return "your approval of the plan (reply `go`) or feedback on it"
+	case PhaseAwaitingPush:
+		return "a `push it` to open the pull request, or feedback on the changes"
+	}
+	return ""
+}
+
+// RunWaitSweeper checks jobs waiting on the user per p every interval until
+// ctx is done.
+func (a *Approver) RunWaitSweeper(ctx context.Context, p waitPolicy, interval time.Duration) {
+	for {
+		a.sweepWaitingJobs(ctx, p, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// sweepWaitingJobs reminds and expires the jobs waiting on the user. How long a
+// job has waited, and whether it was reminded, is read from its events, so it
+// carries over restarts.
+func (a *Approver) sweepWaitingJobs(ctx context.Context, p waitPolicy, now time.Time) {
+	defer recoverGoroutine("wait sweeper", nil)
+	type waiting struct {
+		jobID string
+		state *JobState
+		phase JobPhase
+	}
+	var jobs []waiting
+	a.hub.jobStates.Range(func(k, v any) bool {
+		state := v.(*JobState)
+		state.mu.Lock()
+		phase, closed := state.Phase, state.closed
+		state.mu.Unlock()
+		if !closed && waitingFor(phase) != "" {
+			jobs = append(jobs, waiting{jobID: k.(string), state: state, phase: phase})
+		}
+		return true
+	})
+
+	for _, j := range jobs {
+		idleSince, reminded, err := a.hub.idleSince(j.jobID)
+		if err != nil {
+			slog.Warn("wait: failed to read job events", "job_id", j.jobID, "err", err)
+			continue
+		}
+		idle := now.Sub(idleSince)
+		jobCtx := j.state.ThreadContext(WithJobID(ctx, j.jobID))
+		switch {
+		case p.ExpireAfter > 0 && idle >= p.ExpireAfter:
+			a.expireJob(jobCtx, j.jobID, j.phase, idle)
+		case p.RemindAfter > 0 && idle >= p.RemindAfter && !reminded:
+			a.hub.Emit(j.jobID, JobRemindedData{Phase: string(j.phase), IdleMs: idle.Milliseconds()})
+			text := fmt.Sprintf("Just a reminder: this job is still waiting for %s.", waitingFor(j.phase))
+			if p.ExpireAfter > 0 {
+				text += fmt.Sprintf(" I'll close it if there's no reply in the next %s.", formatWait(p.ExpireAfter-idle))
+			}
+			if err := a.notifier.Notify(jobCtx, text); err != nil {
+				slog.WarnContext(jobCtx, "wait: failed to post reminder", "job_id", j.jobID, "err", err)
+			}
+		}
+	}
+}
+
+// expireJob closes a job that waited too long, unless it moved on meanwhile.
+func (a *Approver) expireJob(ctx context.Context, jobID string, phase JobPhase, idle time.Duration) {
+	if !a.hub.tryExpire(jobID, phase) {
+		return
+	}
+	slog.InfoContext(ctx, "wait: job expired", "job_id", jobID, "phase", phase, "idle", idle.Round(time.Minute))
+	a.orchestrator.closeJob(ctx, jobID, JobExpiredData{Phase: string(phase), IdleMs: idle.Milliseconds()})
+	text := fmt.Sprintf("I closed this job after %s without a reply. Mention me with the request again to start over.", formatWait(idle))
+	if err := a.notifier.Notify(ctx, text); err != nil {
+		slog.WarnContext(ctx, "wait: failed to post expiry", "job_id", jobID, "err", err)
+	}
+}
+
+// tryExpire atomically moves a job out of the waiting phase, so an approval or
+// push arriving at the same time either wins or finds the job gone.
+func (h *Hub) tryExpire(jobID string, phase JobPhase) bool {
+	state, ok := h.GetJobState(jobID)
+	if !ok {
+		return false
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.closed || state.Phase != phase {
+		return false
+	}
+	state.Phase = PhaseDone // closeJob emits the phase change
+	return true
+}
+
+// idleSince returns the time of the job's last activity (any event but a
+// reminder) and whether it has been reminded since.
+func (h *Hub) idleSince(jobID string) (time.Time, bool, error) {
+	events, err := h.store.Events(jobID)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	var last time.Time
+	reminded := false
+	for _, e := range events {
+		if e.Type == EventJobReminded {
+			reminded = true
+			continue
+		}
+		last, reminded = e.Timestamp, false
+	}
+	return last, reminded, nil
+}
+
+// formatWait renders a wait for the thread: "3 days", "5 hours" or "20 minutes".
+func formatWait(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%d days", int(d/(24*time.Hour)))
+	case d >= 2*time.Hour:
+		return fmt.Sprintf("%d hours", int(d/time.Hour))
+	case d >= time.Hour:
+		return "an hour"
+	case d >= 2*time.Minute:
+		return fmt.Sprintf("%d minutes", int(d/time.Minute))
+	}
+	return "a minute"
+}
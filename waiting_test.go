@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSweepWaitingJobs(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	platform := &fakePlatform{name: "slack"}
+	a := NewApprover(platform, hub, &Orchestrator{hub: hub}, false)
+	now := time.Now()
+
+	for id, idle := range map[string]time.Duration{"job-stale": 30 * time.Hour, "job-dead": 8 * 24 * time.Hour, "job-fresh": time.Hour} {
+		hub.SetJobState(id, &JobState{Phase: PhaseAwaitingApproval, Channel: "C1", ThreadTS: id, Platform: "slack"})
+		hub.RegisterThreadJob("C1", id, id)
+		if err := hub.store.Append(Event{ID: "1", JobID: id, Type: EventPhaseChanged, Timestamp: now.Add(-idle), Data: PhaseChangedData{Phase: string(PhaseAwaitingApproval)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A job that is working isn't waiting on anyone, however old its last event.
+	hub.SetJobState("job-busy", &JobState{Phase: PhaseImplementing})
+	hub.store.Append(Event{ID: "1", JobID: "job-busy", Type: EventPhaseChanged, Timestamp: now.Add(-30 * 24 * time.Hour), Data: PhaseChangedData{Phase: string(PhaseImplementing)}})
+
+	p := waitPolicy{RemindAfter: 24 * time.Hour, ExpireAfter: 7 * 24 * time.Hour}
+	a.sweepWaitingJobs(context.Background(), p, now)
+
+	if len(platform.sent) != 2 {
+		t.Fatalf("sent %q", platform.sent)
+	}
+	var reminder, expiry string
+	for _, s := range platform.sent {
+		if strings.HasPrefix(s, "Just a reminder") {
+			reminder = s
+		} else {
+			expiry = s
+		}
+	}
+	if !strings.Contains(reminder, "approval of the plan") || !strings.Contains(reminder, "next 5 days") {
+		t.Errorf("reminder = %q", reminder)
+	}
+	if !strings.HasPrefix(expiry, "I closed this job after 8 days") {
+		t.Errorf("expiry = %q", expiry)
+	}
+	if got := hub.ActiveJobForThread("C1", "job-dead"); got != "" {
+		t.Errorf("expired job still holds its thread: %q", got)
+	}
+	if state, _ := hub.GetJobState("job-dead"); !state.closed {
+		t.Error("expired job not closed")
+	}
+	if hub.ActiveJobForThread("C1", "job-stale") == "" || hub.ActiveJobForThread("C1", "job-fresh") == "" {
+		t.Error("waiting jobs closed early")
+	}
+
+	// Once the reminder is stored, the job isn't reminded again.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, reminded, _ := hub.idleSince("job-stale"); reminded {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("job_reminded not stored")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	platform.sent = nil
+	a.sweepWaitingJobs(context.Background(), p, now.Add(time.Hour))
+	if len(platform.sent) != 0 {
+		t.Errorf("sent again: %q", platform.sent)
+	}
+}
+
+func TestHubTryExpire(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	hub.SetJobState("job-1", &JobState{Phase: PhaseAwaitingApproval})
+	if hub.tryExpire("job-1", PhaseAwaitingQuestion) {
+		t.Error("expired from the wrong phase")
+	}
+	if !hub.tryExpire("job-1", PhaseAwaitingApproval) {
+		t.Fatal("tryExpire failed")
+	}
+	if hub.TryStartImplementation("job-1") {
+		t.Error("approval won after expiry")
+	}
+}
+
+func TestFormatWait(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		30 * time.Second: "a minute",
+		20 * time.Minute: "20 minutes",
+		90 * time.Minute: "an hour",
+		5 * time.Hour:    "5 hours",
+		50 * time.Hour:   "2 days",
+	} {
+		if got := formatWait(d); got != want {
+			t.Errorf("formatWait(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const webhooksFile = "webhooks.json"
+
+// webhookEvents are the job lifecycle events delivered to outbound webhooks.
+var webhookEvents = map[EventType]bool{
+	EventJobStarted:   true,
+	EventJobCompleted: true,
+	EventJobError:     true,
+	EventJobCancelled: true,
+	EventJobExpired:   true,
+}
+
+// webhookAttempts is how many times a delivery is tried before it is dropped.
+const webhookAttempts = 4
+
+// webhook is an operator-registered URL that receives job lifecycle events.
+type webhook struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events,omitempty"` // empty means every lifecycle event
+	Static bool     `json:"static,omitempty"` // from BOB_WEBHOOK_URLS; not removable via the API
+}
+
+// wants reports whether the webhook subscribes to t.
+func (wh webhook) wants(t EventType) bool {
+	if len(wh.Events) == 0 {
+		return true
+	}
+	for _, e := range wh.Events {
+		if EventType(e) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookPayload is the JSON body POSTed to webhooks.
+type webhookPayload struct {
+	ID        string    `json:"id"` // delivery ID; the same across retries
+	Event     EventType `json:"event"`
+	JobID     string    `json:"job_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Repo      string    `json:"repo,omitempty"`
+	Task      string    `json:"task,omitempty"`
+	JobURL    string    `json:"job_url,omitempty"`
+	Data      EventData `json:"data,omitempty"`
+}
+
+// webhookDispatcher delivers job lifecycle events to registered webhooks,
+// signing each body with HMAC-SHA256 and retrying failed deliveries with
+// exponential backoff. Hooks come from config (static) or the API (persisted
+// in webhooks.json).
+type webhookDispatcher struct {
+	hub     *Hub
+	secret  string // signs bodies in X-Bob-Signature; empty sends them unsigned
+	bobURL  string // for job links in payloads; may be empty
+	dataDir string
+	client  *http.Client
+	backoff time.Duration // delay before the first retry, doubled after each
+	queue   chan Event
+
+	mu    sync.Mutex
+	hooks []webhook
+}
+
+// newWebhookDispatcher creates a dispatcher with the static URLs plus any hooks
+// registered through the API, and starts its delivery loop, which runs until
+// ctx is done.
+func newWebhookDispatcher(ctx context.Context, hub *Hub, dataDir, secret, bobURL string, staticURLs []string) *webhookDispatcher {
+	d := &webhookDispatcher{
+		hub:     hub,
+		secret:  secret,
+		bobURL:  strings.TrimSuffix(bobURL, "/"),
+		dataDir: dataDir,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		backoff: time.Second,
+		queue:   make(chan Event, 1024),
+	}
+	for i, u := range staticURLs {
+		d.hooks = append(d.hooks, webhook{ID: fmt.Sprintf("static-%d", i+1), URL: u, Static: true})
+	}
+	d.load()
+	go d.run(ctx)
+	return d
+}
+
+// parseWebhookURLs parses a comma-separated list of webhook URLs, skipping
+// invalid ones.
+func parseWebhookURLs(raw string) []string {
+	var urls []string
+	for _, u := range strings.Split(raw, ",") {
+		u = strings.TrimSpace(u)
+		if u == "" {
+			continue
+		}
+		if err := validateWebhookURL(u); err != nil {
+			slog.Warn("webhooks: ignoring invalid URL", "url", u, "err", err)
+			continue
+		}
+		urls = append(urls, u)
+	}
+	return urls
+}
+
+// validateWebhookURL checks that u is an absolute http(s) URL.
+func validateWebhookURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("want an absolute http(s) URL")
+	}
+	return nil
+}
+
+// observe queues a lifecycle event for delivery. It is called from Hub.Emit,
+// possibly with the job's state locked, so it never blocks.
+func (d *webhookDispatcher) observe(e Event) {
+	if d == nil || !webhookEvents[e.Type] {
+		return
+	}
+	select {
+	case d.queue <- e:
+	default:
+		slog.Warn("webhooks: queue full, dropping event", "event", e.Type, "job_id", e.JobID)
+	}
+}
+
+func (d *webhookDispatcher) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-d.queue:
+			d.dispatch(ctx, e)
+		}
+	}
+}
+
+// dispatch sends one event to every hook subscribed to it. Each delivery
+// retries independently, so a slow receiver doesn't hold up the others.
+func (d *webhookDispatcher) dispatch(ctx context.Context, e Event) {
+	d.mu.Lock()
+	var targets []webhook
+	for _, wh := range d.hooks {
+		if wh.wants(e.Type) {
+			targets = append(targets, wh)
+		}
+	}
+	d.mu.Unlock()
+	if len(targets) == 0 {
+		return
+	}
+
+	p := d.payload(e)
+	body, err := json.Marshal(p)
+	if err != nil {
+		slog.Error("webhooks: failed to marshal event", "event", e.Type, "job_id", e.JobID, "err", err)
+		return
+	}
+	for _, wh := range targets {
+		go d.deliver(ctx, wh, p, body)
+	}
+}
+
+// payload builds the webhook body for e, adding the job's repo and task.
+func (d *webhookDispatcher) payload(e Event) webhookPayload {
+	p := webhookPayload{ID: uuid.New().String(), Event: e.Type, JobID: e.JobID, Timestamp: e.Timestamp, Data: e.Data}
+	if state, ok := d.hub.GetJobState(e.JobID); ok {
+		state.mu.Lock()
+		p.Repo, p.Task = state.Repo, state.Task
+		state.mu.Unlock()
+	} else if d, ok := e.Data.(JobStartedData); ok {
+		// job_started is emitted before the job's state exists.
+		p.Repo, p.Task = d.Repo, d.Task
+	}
+	if d.bobURL != "" {
+		p.JobURL = d.bobURL + "/jobs/" + e.JobID
+	}
+	return p
+}
+
+// deliver POSTs body to the hook, retrying network errors, 429s and 5xx
+// responses with exponential backoff.
+func (d *webhookDispatcher) deliver(ctx context.Context, wh webhook, p webhookPayload, body []byte) {
+	wait := d.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(ctx, wh.URL, p, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt == webhookAttempts {
+			slog.Error("webhooks: giving up on delivery", "event", p.Event, "url", wh.URL, "attempts", attempt, "job_id", p.JobID, "err", err)
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// post makes one delivery attempt and reports whether a failure is worth
+// retrying.
+func (d *webhookDispatcher) post(ctx context.Context, target string, p webhookPayload, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Bob-Webhooks")
+	req.Header.Set("X-Bob-Event", string(p.Event))
+	req.Header.Set("X-Bob-Delivery", p.ID)
+	if d.secret != "" {
+		req.Header.Set("X-Bob-Signature", "sha256="+signWebhook(d.secret, body))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("status %d", resp.StatusCode)
+}
+
+// signWebhook returns the hex HMAC-SHA256 of body, as sent in X-Bob-Signature.
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP serves the webhook registration API:
+//
+//	GET    /api/webhooks       list hooks
+//	POST   /api/webhooks       register {"url": "...", "events": ["job_completed"]}
+//	DELETE /api/webhooks/{id}  remove a hook registered through the API
+func (d *webhookDispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/webhooks"), "/")
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		d.mu.Lock()
+		hooks := append([]webhook{}, d.hooks...)
+		d.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"webhooks": hooks})
+	case r.Method == http.MethodPost && id == "":
+		var req struct {
+			URL    string   `json:"url"`
+			Events []string `json:"events"`
+		}
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxAPIBodySize)).Decode(&req); err != nil {
+			http.Error(w, `{"error":"invalid JSON"}`, http.StatusBadRequest)
+			return
+		}
+		if err := validateWebhookURL(req.URL); err != nil {
+			http.Error(w, `{"error":"url must be an absolute http(s) URL"}`, http.StatusBadRequest)
+			return
+		}
+		for _, e := range req.Events {
+			if !webhookEvents[EventType(e)] {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("unknown event %q", e)})
+				return
+			}
+		}
+		wh := webhook{ID: uuid.New().String(), URL: req.URL, Events: req.Events}
+		d.mu.Lock()
+		d.hooks = append(d.hooks, wh)
+		d.mu.Unlock()
+		d.save()
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(wh)
+	case r.Method == http.MethodDelete && id != "":
+		d.mu.Lock()
+		found := -1
+		for i, wh := range d.hooks {
+			if wh.ID == id && !wh.Static {
+				found = i
+			}
+		}
+		if found >= 0 {
+			d.hooks = append(d.hooks[:found], d.hooks[found+1:]...)
+		}
+		d.mu.Unlock()
+		if found < 0 {
+			http.Error(w, `{"error":"webhook not found"}`, http.StatusNotFound)
+			return
+		}
+		d.save()
+		w.Write([]byte(`{"ok":true}`))
+	default:
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+// load reads the hooks registered through the API.
+func (d *webhookDispatcher) load() {
+	data, err := os.ReadFile(filepath.Join(d.dataDir, webhooksFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			slog.Error("webhooks: failed to load", "err", err)
+		}
+		return
+	}
+	var hooks []webhook
+	if err := json.Unmarshal(data, &hooks); err != nil {
+		slog.Error("webhooks: failed to parse", "err", err)
+		return
+	}
+	d.hooks = append(d.hooks, hooks...)
+}
+
+// save persists the hooks registered through the API.
+func (d *webhookDispatcher) save() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var hooks []webhook
+	for _, wh := range d.hooks {
+		if !wh.Static {
+			hooks = append(hooks, wh)
+		}
+	}
+	data, err := json.Marshal(hooks)
+	if err != nil {
+		slog.Error("webhooks: failed to marshal", "err", err)
+		return
+	}
+	path := filepath.Join(d.dataDir, webhooksFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		slog.Error("webhooks: failed to write", "err", err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		slog.Error("webhooks: failed to rename", "err", err)
+	}
+}
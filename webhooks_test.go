@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver records deliveries and fails the first failures of them.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.attempts++
+	if rcv.failures > 0 {
+		rcv.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	rcv.bodies = append(rcv.bodies, body)
+	rcv.headers = append(rcv.headers, r.Header.Clone())
+}
+
+func (rcv *webhookReceiver) wait(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		rcv.mu.Lock()
+		got := len(rcv.bodies)
+		rcv.mu.Unlock()
+		if got >= n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d deliveries", n)
+}
+
+func TestWebhookDispatcher_Deliver(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	rcv := &webhookReceiver{failures: 2}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := newWebhookDispatcher(ctx, hub, t.TempDir(), "s3cret", "https://bob.example.com/", []string{srv.URL})
+	d.backoff = time.Millisecond
+
+	hub.SetJobState("job-1", &JobState{Repo: "web", Task: "Fix the login page"})
+	d.observe(Event{ID: "7", JobID: "job-1", Type: EventToolStarted})
+	d.observe(Event{ID: "8", JobID: "job-1", Type: EventJobCompleted, Timestamp: time.Now(), Data: JobCompletedData{PRURL: "https://github.com/org/web/pull/7"}})
+	rcv.wait(t, 1)
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	if rcv.attempts != 3 {
+		t.Errorf("attempts = %d, want 3 (two retries)", rcv.attempts)
+	}
+	if len(rcv.bodies) != 1 {
+		t.Fatalf("deliveries = %d, want only job_completed", len(rcv.bodies))
+	}
+	body, h := rcv.bodies[0], rcv.headers[0]
+	if got, want := h.Get("X-Bob-Signature"), "sha256="+signWebhook("s3cret", body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if h.Get("X-Bob-Event") != "job_completed" || h.Get("X-Bob-Delivery") == "" {
+		t.Errorf("headers = %v", h)
+	}
+	var p struct {
+		webhookPayload
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Event != EventJobCompleted || p.JobID != "job-1" || p.Repo != "web" || p.Task != "Fix the login page" ||
+		p.JobURL != "https://bob.example.com/jobs/job-1" || p.Data["pr_url"] != "https://github.com/org/web/pull/7" {
+		t.Errorf("payload = %+v", p)
+	}
+	if p.ID != h.Get("X-Bob-Delivery") {
+		t.Errorf("payload id %q != delivery header %q", p.ID, h.Get("X-Bob-Delivery"))
+	}
+}
+
+func TestWebhookDispatcher_NoRetryOnClientError(t *testing.T) {
+	var attempts int
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	d := &webhookDispatcher{client: srv.Client(), backoff: time.Millisecond}
+	d.deliver(context.Background(), webhook{URL: srv.URL}, webhookPayload{Event: EventJobError}, []byte(`{}`))
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestWebhook_Wants(t *testing.T) {
+	all := webhook{}
+	only := webhook{Events: []string{"job_error"}}
+	if !all.wants(EventJobStarted) || !only.wants(EventJobError) || only.wants(EventJobCompleted) {
+		t.Error("wants mismatch")
+	}
+}
+
+func TestWebhookDispatcher_API(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := newWebhookDispatcher(ctx, hub, dir, "", "", parseWebhookURLs("https://static.example.com/hook, ftp://nope"))
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		d.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	for _, body := range []string{`{"url":"not a url"}`, `{"url":"https://x.example.com","events":["tool_started"]}`, `{`} {
+		if w := do(http.MethodPost, "/api/webhooks", body); w.Code != http.StatusBadRequest {
+			t.Errorf("POST %s = %d, want 400", body, w.Code)
+		}
+	}
+
+	w := do(http.MethodPost, "/api/webhooks", `{"url":"https://dash.example.com/bob","events":["job_completed","job_error"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST = %d: %s", w.Code, w.Body)
+	}
+	var created webhook
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.ID == "" || created.URL != "https://dash.example.com/bob" {
+		t.Fatalf("created = %+v", created)
+	}
+
+	// Registered hooks survive a restart; static ones come from config.
+	d2 := newWebhookDispatcher(ctx, hub, dir, "", "", []string{"https://static.example.com/hook"})
+	var list struct{ Webhooks []webhook }
+	w2 := httptest.NewRecorder()
+	d2.ServeHTTP(w2, httptest.NewRequest(http.MethodGet, "/api/webhooks", nil))
+	json.Unmarshal(w2.Body.Bytes(), &list)
+	if len(list.Webhooks) != 2 || !list.Webhooks[0].Static || list.Webhooks[1].ID != created.ID {
+		t.Fatalf("list after restart = %+v", list.Webhooks)
+	}
+
+	if w := do(http.MethodDelete, "/api/webhooks/static-1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("DELETE static = %d, want 404", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/webhooks/"+created.ID, ""); w.Code != http.StatusOK {
+		t.Errorf("DELETE = %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/webhooks/"+created.ID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("second DELETE = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteTimeout   = 10 * time.Second
+	wsPingInterval   = 30 * time.Second
+	wsPongTimeout    = 60 * time.Second
+	wsMaxMessageSize = 4 << 10 // control messages are small JSON objects
+)
+
+// Stream verbosity levels. Summary drops the high-volume per-step events and
+// keeps job lifecycle, phase and plan events.
+const (
+	verbosityFull    = "full"
+	verbositySummary = "summary"
+)
+
+// detailEvents are the event types left out at summary verbosity.
+var detailEvents = map[EventType]bool{
+	EventLLMCall:         true,
+	EventLLMResponse:     true,
+	EventLLMRetry:        true,
+	EventToolStarted:     true,
+	EventToolCompleted:   true,
+	EventClaudeCodeLine:  true,
+	EventClaudeCodeLines: true,
+	EventAgentStarted:    true,
+	EventAgentFinished:   true,
+}
+
+// streamFilter is a WebSocket client's set of subscriptions. Unlike an SSE
+// stream's fixed job, it changes as the client sends control messages, so it
+// has its own lock; Hub.run reads it while fanning out.
+type streamFilter struct {
+	mu      sync.Mutex
+	all     bool            // subscribed to every job
+	jobs    map[string]bool // subscribed job IDs when not all
+	summary bool
+}
+
+func (f *streamFilter) wants(e Event) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.summary && detailEvents[e.Type] {
+		return false
+	}
+	return f.all || f.jobs[e.JobID]
+}
+
+// subscribe adds a job, or every job when jobID is empty.
+func (f *streamFilter) subscribe(jobID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if jobID == "" {
+		f.all = true
+		return
+	}
+	f.jobs[jobID] = true
+}
+
+// unsubscribe removes a job, or every subscription when jobID is empty.
+func (f *streamFilter) unsubscribe(jobID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if jobID == "" {
+		f.all = false
+		clear(f.jobs)
+		return
+	}
+	delete(f.jobs, jobID)
+}
+
+// wsRequest is a control message sent by a WebSocket client.
+type wsRequest struct {
+	Action    string `json:"action"` // subscribe, unsubscribe, cancel or verbosity
+	JobID     string `json:"job_id,omitempty"`
+	Verbosity string `json:"verbosity,omitempty"` // for verbosity: full or summary
+}
+
+// wsReply answers a control message. Events are sent as plain Event objects;
+// replies are told apart by reply_to.
+type wsReply struct {
+	ReplyTo string `json:"reply_to"`
+	JobID   string `json:"job_id,omitempty"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+}
+
+// NewWebSocketHandler serves GET /ws/events — a bidirectional alternative to
+// /events for networks whose proxies buffer SSE. It streams events like
+// /events (including ?job= and ?after= on connect) and accepts control
+// messages to change subscriptions, cancel jobs and set the verbosity.
+func NewWebSocketHandler(hub *Hub, orch *Orchestrator, notifier Notifier) http.Handler {
+	// The default origin check rejects cross-site pages, which matters because
+	// the auth cookie is sent with the upgrade request.
+	upgrader := websocket.Upgrader{}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		filter := &streamFilter{jobs: make(map[string]bool)}
+		jobID := r.URL.Query().Get("job")
+		filter.subscribe(jobID)
+		if r.URL.Query().Get("verbosity") == verbositySummary {
+			filter.summary = true
+		}
+
+		c := &sseClient{filter: filter, send: make(chan sseMessage, 64)}
+		if !hub.add(c) {
+			http.Error(w, "too many connections", http.StatusServiceUnavailable)
+			return
+		}
+		defer hub.remove(c)
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return // Upgrade has already replied
+		}
+		defer conn.Close()
+
+		// The connection allows one concurrent writer; the read loop hands
+		// its replies to this goroutine.
+		replies := make(chan wsReply, 16)
+		done, stop := make(chan struct{}), make(chan struct{})
+		defer close(stop)
+		go func() {
+			defer close(done)
+			readWebSocket(conn, filter, replies, stop, func(jobID string) error {
+				return cancelJob(hub, orch, notifier, jobID, "web UI")
+			})
+		}()
+
+		replayed := make(map[string]bool)
+		if after := r.URL.Query().Get("after"); after != "" && jobID != "" {
+			events, err := hub.store.Events(jobID)
+			if err != nil && !errors.Is(err, ErrJobNotFound) {
+				slog.ErrorContext(r.Context(), "ws: failed to replay events", "job_id", jobID, "err", err)
+			}
+			for _, e := range eventsAfter(events, after) {
+				if !filter.wants(e) {
+					continue
+				}
+				if err := writeWebSocket(conn, e); err != nil {
+					return
+				}
+				replayed[e.ID] = true
+			}
+		}
+
+		ping := time.NewTicker(wsPingInterval)
+		defer ping.Stop()
+		for {
+			select {
+			case msg, ok := <-c.send:
+				if !ok {
+					return
+				}
+				if replayed[msg.id] {
+					continue
+				}
+				conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+				if err := conn.WriteMessage(websocket.TextMessage, msg.data); err != nil {
+					return
+				}
+			case reply := <-replies:
+				if err := writeWebSocket(conn, reply); err != nil {
+					return
+				}
+			case <-ping.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+					return
+				}
+			case <-done:
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+}
+
+// readWebSocket applies control messages until the connection closes or stop
+// is closed.
+func readWebSocket(conn *websocket.Conn, filter *streamFilter, replies chan<- wsReply, stop <-chan struct{}, cancel func(jobID string) error) {
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	})
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+
+		reply := wsReply{Error: "invalid JSON"}
+		var req wsRequest
+		if err := json.Unmarshal(data, &req); err == nil {
+			reply = handleWebSocketRequest(req, filter, cancel)
+		}
+		select {
+		case replies <- reply:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// handleWebSocketRequest applies one control message and returns its reply.
+func handleWebSocketRequest(req wsRequest, filter *streamFilter, cancel func(jobID string) error) wsReply {
+	reply := wsReply{ReplyTo: req.Action, JobID: req.JobID, OK: true}
+	switch req.Action {
+	case "subscribe":
+		filter.subscribe(req.JobID)
+	case "unsubscribe":
+		filter.unsubscribe(req.JobID)
+	case "cancel":
+		switch err := cancel(req.JobID); {
+		case errors.Is(err, ErrJobNotFound):
+			reply.OK, reply.Error = false, "job not found"
+		case err != nil:
+			reply.OK, reply.Error = false, "job already finished"
+		}
+	case "verbosity":
+		if req.Verbosity != verbosityFull && req.Verbosity != verbositySummary {
+			reply.OK, reply.Error = false, "verbosity must be full or summary"
+			break
+		}
+		filter.mu.Lock()
+		filter.summary = req.Verbosity == verbositySummary
+		filter.mu.Unlock()
+	default:
+		reply.OK, reply.Error = false, "unknown action"
+	}
+	return reply
+}
+
+// writeWebSocket writes v as a JSON text message.
+func writeWebSocket(conn *websocket.Conn, v any) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return conn.WriteJSON(v)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestStreamFilter(t *testing.T) {
+	f := &streamFilter{jobs: make(map[string]bool)}
+	line := Event{JobID: "job-1", Type: EventClaudeCodeLine}
+	phase := Event{JobID: "job-1", Type: EventPhaseChanged}
+	if f.wants(phase) {
+		t.Error("empty filter should want nothing")
+	}
+	f.subscribe("job-1")
+	if !f.wants(line) || f.wants(Event{JobID: "job-2", Type: EventPhaseChanged}) {
+		t.Error("job subscription should match only that job")
+	}
+	f.summary = true
+	if f.wants(line) || !f.wants(phase) {
+		t.Error("summary should drop detail events only")
+	}
+	f.subscribe("")
+	if !f.wants(Event{JobID: "job-2", Type: EventPhaseChanged}) {
+		t.Error("subscribing to all should match every job")
+	}
+	f.unsubscribe("")
+	if f.wants(phase) {
+		t.Error("unsubscribing from all should clear every subscription")
+	}
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	hub.Emit("job-1", JobStartedData{Task: "t"})
+	hub.Emit("job-1", ToolStartedData{ToolName: "a"})
+	time.Sleep(50 * time.Millisecond) // let the hub persist
+
+	srv := httptest.NewServer(NewWebSocketHandler(hub, nil, nil))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "?job=job-1&after=1"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	// read returns the next message as a generic object.
+	read := func() map[string]any {
+		t.Helper()
+		var m map[string]any
+		if err := conn.ReadJSON(&m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	if m := read(); m["id"] != "2" {
+		t.Fatalf("replay = %v, want event 2", m)
+	}
+
+	send := func(req wsRequest) map[string]any {
+		t.Helper()
+		if err := conn.WriteJSON(req); err != nil {
+			t.Fatal(err)
+		}
+		return read()
+	}
+	if m := send(wsRequest{Action: "verbosity", Verbosity: "summary"}); m["ok"] != true {
+		t.Fatalf("verbosity reply = %v", m)
+	}
+	if m := send(wsRequest{Action: "subscribe", JobID: "job-2"}); m["ok"] != true || m["reply_to"] != "subscribe" {
+		t.Fatalf("subscribe reply = %v", m)
+	}
+	if m := send(wsRequest{Action: "cancel", JobID: "missing"}); m["ok"] != false || m["error"] != "job not found" {
+		t.Fatalf("cancel reply = %v", m)
+	}
+	if m := send(wsRequest{Action: "shout"}); m["error"] != "unknown action" {
+		t.Fatalf("unknown action reply = %v", m)
+	}
+
+	hub.Emit("job-2", ClaudeCodeLineData{Text: "noise"}) // dropped at summary verbosity
+	hub.Emit("job-3", PhaseChangedData{Phase: "planning"})
+	hub.Emit("job-2", PhaseChangedData{Phase: "planning"})
+	m := read()
+	if m["job_id"] != "job-2" || m["type"] != string(EventPhaseChanged) {
+		t.Errorf("live event = %v", m)
+	}
+	var data PhaseChangedData
+	raw, _ := json.Marshal(m["data"])
+	json.Unmarshal(raw, &data)
+	if data.Phase != "planning" {
+		t.Errorf("live event data = %+v", data)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultWorkspaceRoot is the workspace volume's mount point in the container.
+const defaultWorkspaceRoot = "/workspace"
+
+// workspaceRoot holds base clones (<root>/<repo>), their job worktrees, repo
+// mirrors (<root>/.cache) and Bob's data dir (<root>/.bob). It is set once at
+// startup from WORKSPACE_DIR.
+var workspaceRoot = defaultWorkspaceRoot
+
+// workspacePath returns a path under the workspace root.
+func workspacePath(elem ...string) string {
+	return filepath.Join(append([]string{workspaceRoot}, elem...)...)
+}
+
+const (
+	// defaultWorkspaceGCAfter is how long a finished job's worktree is kept
+	// unless BOB_WORKSPACE_GC_HOURS says otherwise.
+	defaultWorkspaceGCAfter = 24 * time.Hour
+	// workspaceUsageMaxAge is how long a measurement of the whole workspace
+	// is reused; walking a workspace full of node_modules takes a while.
+	workspaceUsageMaxAge = time.Minute
+)
+
+// workspaceQuota limits the workspace's disk use. Zero fields don't apply.
+type workspaceQuota struct {
+	MaxBytes    int64         // new jobs are refused while the workspace uses more than this
+	MaxJobBytes int64         // a job whose worktree grows past this is stopped
+	GCAfter     time.Duration // worktrees of finished jobs are removed this long after they last changed
+}
+
+// workspaceQuotaFromEnv reads BOB_WORKSPACE_MAX_GB, BOB_JOB_WORKSPACE_MAX_GB
+// and BOB_WORKSPACE_GC_HOURS (0 turns garbage collection off).
+func workspaceQuotaFromEnv() workspaceQuota {
+	q := workspaceQuota{GCAfter: defaultWorkspaceGCAfter}
+	if v := os.Getenv("BOB_WORKSPACE_MAX_GB"); v != "" {
+		if gb, err := strconv.ParseFloat(v, 64); err == nil && gb > 0 {
+			q.MaxBytes = int64(gb * (1 << 30))
+		}
+	}
+	if v := os.Getenv("BOB_JOB_WORKSPACE_MAX_GB"); v != "" {
+		if gb, err := strconv.ParseFloat(v, 64); err == nil && gb > 0 {
+			q.MaxJobBytes = int64(gb * (1 << 30))
+		}
+	}
+	if v := os.Getenv("BOB_WORKSPACE_GC_HOURS"); v != "" {
+		if h, err := strconv.ParseFloat(v, 64); err == nil && h >= 0 {
+			q.GCAfter = time.Duration(h * float64(time.Hour))
+		}
+	}
+	return q
+}
+
+// workspaceUsage caches the size of the whole workspace.
+type workspaceUsage struct {
+	mu    sync.Mutex
+	bytes int64
+	at    time.Time
+}
+
+// get returns the workspace's size, measuring it again if the last
+// measurement is older than maxAge.
+func (u *workspaceUsage) get(maxAge time.Duration) int64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.at.IsZero() || time.Since(u.at) > maxAge {
+		u.bytes, u.at = dirSize(workspaceRoot), time.Now()
+		metricWorkspaceBytes.Set(float64(u.bytes))
+	}
+	return u.bytes
+}
+
+// dirSize returns the total size of the regular files under dir. Files that
+// vanish or can't be read during the walk are skipped.
+func dirSize(dir string) int64 {
+	var total int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// formatBytes formats a size in GB, or MB below 1 GB.
+func formatBytes(n int64) string {
+	if n < 1<<30 {
+		return fmt.Sprintf("%.0f MB", float64(n)/(1<<20))
+	}
+	return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+}
+
+// workspaceFull returns a user-facing rejection if the workspace is over its
+// quota, or empty string if a new job may start. It collects garbage first
+// if that might make room.
+func (o *Orchestrator) workspaceFull(ctx context.Context) string {
+	if o.disk.MaxBytes <= 0 {
+		return ""
+	}
+	used := o.diskUsage.get(workspaceUsageMaxAge)
+	if used > o.disk.MaxBytes && o.collectWorkspaces(ctx) > 0 {
+		used = o.diskUsage.get(0)
+	}
+	if used <= o.disk.MaxBytes {
+		return ""
+	}
+	slog.WarnContext(ctx, "orchestrator: workspace over its disk quota, refusing new jobs", "bytes", used, "max_bytes", o.disk.MaxBytes)
+	return fmt.Sprintf("I can't start new jobs right now: my workspace uses %s of disk, over its %s quota. Old workspaces are removed as jobs finish, so please try again later.",
+		formatBytes(used), formatBytes(o.disk.MaxBytes))
+}
+
+// overDiskQuota measures the job's worktree, records it in a workspace_usage
+// event, and reports whether it exceeds the per-job quota.
+func (o *Orchestrator) overDiskQuota(jobID string) (int64, bool) {
+	state, ok := o.hub.GetJobState(jobID)
+	if !ok {
+		return 0, false
+	}
+	state.mu.Lock()
+	repoDir := state.RepoDir
+	state.mu.Unlock()
+	if repoDir == "" {
+		return 0, false
+	}
+	size := dirSize(repoDir)
+	o.hub.Emit(jobID, WorkspaceUsageData{Bytes: size, MaxBytes: o.disk.MaxJobBytes})
+	return size, o.disk.MaxJobBytes > 0 && size > o.disk.MaxJobBytes
+}
+
+// abortOverDiskQuota ends a job whose worktree outgrew the per-job quota and
+// returns the reply for the thread. Like the cost budget, the quota is checked
+// between steps.
+func (o *Orchestrator) abortOverDiskQuota(ctx context.Context, jobID string, size int64) OrchestratorResult {
+	msg := fmt.Sprintf("job workspace uses %s, over the %s disk quota", formatBytes(size), formatBytes(o.disk.MaxJobBytes))
+	slog.WarnContext(ctx, "orchestrator: job over its disk quota", "job_id", jobID, "bytes", size, "max_bytes", o.disk.MaxJobBytes)
+	o.closeJob(ctx, jobID, JobErrorData{
+		Error:             msg,
+		DiskQuotaExceeded: true,
+		TotalCostUSD:      o.hub.JobCost(jobID),
+	})
+	return OrchestratorResult{IsJob: true, JobID: jobID,
+		Text: fmt.Sprintf("I stopped this job because its workspace grew to %s of disk, over its %s quota.", formatBytes(size), formatBytes(o.disk.MaxJobBytes))}
+}
+
+// RunWorkspaceGC removes the worktrees of finished jobs every interval until
+// ctx is done.
+func (o *Orchestrator) RunWorkspaceGC(ctx context.Context, interval time.Duration) {
+	for {
+		if n := o.collectWorkspaces(ctx); n > 0 {
+			slog.Info("workspace gc: removed worktrees", "count", n)
+		}
+		o.diskUsage.get(0)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// collectWorkspaces removes worktrees that no running or waiting job uses and
+// that haven't changed for o.disk.GCAfter, and returns how many it removed.
+// Jobs normally remove their worktree when they finish; this catches those
+// left behind by a restart or a failed removal.
+func (o *Orchestrator) collectWorkspaces(ctx context.Context) int {
+	if o.disk.GCAfter <= 0 {
+		return 0
+	}
+	worktrees, err := filepath.Glob(workspacePath("*", "worktrees", "*"))
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, wtPath := range worktrees {
+		jobID := filepath.Base(wtPath)
+		if state, ok := o.hub.GetJobState(jobID); ok {
+			state.mu.Lock()
+			closed := state.closed
+			state.mu.Unlock()
+			if !closed {
+				continue
+			}
+		}
+		info, err := os.Stat(wtPath)
+		if err != nil || !info.IsDir() || time.Since(info.ModTime()) < o.disk.GCAfter {
+			continue
+		}
+		baseDir := filepath.Dir(filepath.Dir(wtPath))
+		unlock := lockBaseClone(filepath.Base(baseDir))
+		RemoveWorktree(ctx, baseDir, wtPath, jobID)
+		if _, err := os.Stat(wtPath); err == nil {
+			// Not a registered worktree (any more); remove what's left.
+			if err := os.RemoveAll(wtPath); err != nil {
+				slog.WarnContext(ctx, "workspace gc: failed to remove worktree", "job_id", jobID, "path", wtPath, "err", err)
+				unlock()
+				continue
+			}
+		}
+		unlock()
+		slog.InfoContext(ctx, "workspace gc: removed worktree", "job_id", jobID, "path", wtPath)
+		removed++
+	}
+	return removed
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorkspacePath(t *testing.T) {
+	old := workspaceRoot
+	t.Cleanup(func() { workspaceRoot = old })
+
+	if got := workspacePath("repo", "worktrees", "job-1"); got != "/workspace/repo/worktrees/job-1" {
+		t.Errorf("default root: %q", got)
+	}
+
+	workspaceRoot = "/srv/bob"
+	if got := mirrorPath("web"); got != "/srv/bob/.cache/web.git" {
+		t.Errorf("mirrorPath = %q", got)
+	}
+	if got := mirrorPath("other-org/web"); got != "/srv/bob/.cache/other-org@web.git" {
+		t.Errorf("mirrorPath = %q", got)
+	}
+	if got := sandboxClaudeConfigDir(); got != "/srv/bob/.bob/claude" {
+		t.Errorf("sandboxClaudeConfigDir = %q", got)
+	}
+}
+
+func TestWorkspaceQuotaFromEnv(t *testing.T) {
+	for _, k := range []string{"BOB_WORKSPACE_MAX_GB", "BOB_JOB_WORKSPACE_MAX_GB", "BOB_WORKSPACE_GC_HOURS"} {
+		t.Setenv(k, "")
+	}
+	if q := workspaceQuotaFromEnv(); q != (workspaceQuota{GCAfter: defaultWorkspaceGCAfter}) {
+		t.Errorf("defaults = %+v", q)
+	}
+	t.Setenv("BOB_WORKSPACE_MAX_GB", "50")
+	t.Setenv("BOB_JOB_WORKSPACE_MAX_GB", "0.5")
+	t.Setenv("BOB_WORKSPACE_GC_HOURS", "0")
+	want := workspaceQuota{MaxBytes: 50 << 30, MaxJobBytes: 512 << 20}
+	if q := workspaceQuotaFromEnv(); q != want {
+		t.Errorf("quota = %+v, want %+v", q, want)
+	}
+}
+
+// writeSizedFile writes size zero bytes to a new file at path, creating its directory.
+func writeSizedFile(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiskQuotaEnforcement(t *testing.T) {
+	old := workspaceRoot
+	t.Cleanup(func() { workspaceRoot = old })
+	workspaceRoot = t.TempDir()
+
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	o := &Orchestrator{hub: hub, disk: workspaceQuota{MaxBytes: 3000, MaxJobBytes: 2000}}
+	repoDir := workspacePath("web", "worktrees", "job-1")
+	writeSizedFile(t, filepath.Join(repoDir, "main.go"), 1000)
+	hub.SetJobState("job-1", &JobState{Phase: PhaseImplementing, RepoDir: repoDir})
+
+	if size, over := o.overDiskQuota("job-1"); over || size != 1000 {
+		t.Fatalf("overDiskQuota = %d, %v", size, over)
+	}
+	if msg := o.workspaceFull(context.Background()); msg != "" {
+		t.Errorf("workspaceFull at 1000 bytes: %q", msg)
+	}
+
+	writeSizedFile(t, filepath.Join(repoDir, "node_modules", "dep", "index.js"), 1500)
+	size, over := o.overDiskQuota("job-1")
+	if !over || size != 2500 {
+		t.Fatalf("overDiskQuota = %d, %v", size, over)
+	}
+	var usage []WorkspaceUsageData
+	for deadline := time.Now().Add(time.Second); len(usage) < 2 && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		events, _ := hub.store.Events("job-1")
+		usage = usage[:0]
+		for _, e := range events {
+			if d, ok := e.Data.(WorkspaceUsageData); ok {
+				usage = append(usage, d)
+			}
+		}
+	}
+	if len(usage) != 2 || usage[1] != (WorkspaceUsageData{Bytes: 2500, MaxBytes: 2000}) {
+		t.Errorf("workspace_usage events = %+v", usage)
+	}
+
+	result := o.abortOverDiskQuota(context.Background(), "job-1", size)
+	if !strings.Contains(result.Text, "quota") {
+		t.Errorf("Text = %q", result.Text)
+	}
+	state, _ := hub.GetJobState("job-1")
+	if state.Phase != PhaseDone || !state.closed {
+		t.Errorf("job not closed: phase=%q closed=%v", state.Phase, state.closed)
+	}
+
+	// The closed job's worktree is still there (it isn't a real worktree, so
+	// closeJob couldn't remove it) and pushes the workspace over its quota.
+	writeSizedFile(t, workspacePath(".cache", "web.git", "pack"), 1000)
+	o.diskUsage = workspaceUsage{}
+	o.disk.GCAfter = time.Hour
+	if msg := o.workspaceFull(context.Background()); !strings.Contains(msg, "quota") {
+		t.Errorf("workspaceFull over quota = %q", msg)
+	}
+	o.diskUsage = workspaceUsage{}
+	o.disk.GCAfter = time.Nanosecond
+	if msg := o.workspaceFull(context.Background()); msg != "" {
+		t.Errorf("workspaceFull after GC: %q", msg)
+	}
+	if _, err := os.Stat(repoDir); !os.IsNotExist(err) {
+		t.Errorf("worktree not removed: %v", err)
+	}
+}
+
+func TestCollectWorkspaces(t *testing.T) {
+	old := workspaceRoot
+	t.Cleanup(func() { workspaceRoot = old })
+	workspaceRoot = t.TempDir()
+
+	drainHub(t)
+	hub := NewHub(t.TempDir())
+	o := &Orchestrator{hub: hub, disk: workspaceQuota{GCAfter: time.Hour}}
+	stale := time.Now().Add(-2 * time.Hour)
+	for _, id := range []string{"job-running", "job-orphan", "job-recent"} {
+		dir := workspacePath("web", "worktrees", id)
+		writeSizedFile(t, filepath.Join(dir, "go.mod"), 10)
+		if id != "job-recent" {
+			if err := os.Chtimes(dir, stale, stale); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	hub.SetJobState("job-running", &JobState{Phase: PhaseAwaitingApproval})
+
+	if n := o.collectWorkspaces(context.Background()); n != 1 {
+		t.Errorf("removed %d worktrees, want 1", n)
+	}
+	for id, want := range map[string]bool{"job-running": true, "job-orphan": false, "job-recent": true} {
+		_, err := os.Stat(workspacePath("web", "worktrees", id))
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %v, want %v", id, exists, want)
+		}
+	}
+
+	o.disk.GCAfter = 0
+	hub.SetJobState("job-recent", &JobState{closed: true})
+	if n := o.collectWorkspaces(context.Background()); n != 0 {
+		t.Errorf("removed %d worktrees with GC off", n)
+	}
+}